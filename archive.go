@@ -0,0 +1,79 @@
+package darwin
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// ArchiveHistory writes the records of the migrations applied before the
+// time to w, as a JSON document like ExportHistory, then deletes them from
+// the history, which requires a RecordDeleter driver, and returns their
+// number. The record of the latest version archived is written but kept as
+// the baseline marker, so the migrations archived are never applied again;
+// Info reports the ones before it as ignored. Nothing is deleted when the
+// records can't be written.
+func ArchiveHistory(d Driver, before time.Time, w io.Writer) (int, error) {
+	deleter, err := recordDeleter(d)
+	if err != nil {
+		return 0, err
+	}
+
+	records, err := d.All()
+	if err != nil {
+		return 0, err
+	}
+
+	var archived []MigrationRecord
+	for _, r := range records {
+		if r.AppliedAt.Before(before) {
+			archived = append(archived, r)
+		}
+	}
+
+	if err := writeHistory(archived, w, FormatJSON); err != nil {
+		return 0, err
+	}
+
+	if len(archived) == 0 {
+		return 0, nil
+	}
+
+	// The records are sorted by version, the last is the baseline marker.
+	for _, r := range archived[:len(archived)-1] {
+		if err := deleter.Delete(r.Version); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(archived) - 1, nil
+}
+
+// ArchiveHistory writes the records of the migrations applied before the
+// time to w and deletes them like ArchiveHistory, holding the migration
+// lock, for the installations whose history table grew large.
+func (d Darwin) ArchiveHistory(ctx context.Context, before time.Time, w io.Writer) (archived int, err error) {
+	ctx, span := d.startSpan(ctx, "darwin.ArchiveHistory")
+	defer func() {
+		span.SetAttribute("darwin.archived", archived)
+		endSpan(span, err)
+	}()
+
+	_, release, err := d.acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if rerr := release(); err == nil {
+			err = rerr
+		}
+	}()
+
+	archived, err = ArchiveHistory(d.driver, before, w)
+	if err != nil {
+		return archived, err
+	}
+
+	d.logger().Info("darwin: history archived", "before", before, "records", archived)
+	return archived, nil
+}
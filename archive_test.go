@@ -0,0 +1,48 @@
+package darwin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func Test_Darwin_ArchiveHistory(t *testing.T) {
+	applied := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	driver := &downDriver{}
+	for i, r := range appliedRecords() {
+		r.AppliedAt = applied.AddDate(0, i, 0)
+		driver.records = append(driver.records, r)
+	}
+
+	var buf bytes.Buffer
+	archived, err := New(driver, nil).ArchiveHistory(context.Background(), applied.AddDate(0, 1, 1), &buf)
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	var docs []recordJSON
+	if err := json.Unmarshal(buf.Bytes(), &docs); err != nil {
+		t.Fatalf("Must write a JSON document, got %s", err)
+	}
+
+	if len(docs) != 2 || docs[0].Version != 1 || docs[1].Version != 2 {
+		t.Errorf("Must write the records applied before the time, got %#v", docs)
+	}
+
+	if archived != 1 || len(driver.records) != 2 || driver.records[0].Version != 2 || driver.records[1].Version != 3 {
+		t.Errorf("Must delete the archived records but the baseline marker, got %d and %#v", archived, driver.records)
+	}
+
+	buf.Reset()
+	if archived, err := ArchiveHistory(driver, applied, &buf); err != nil || archived != 0 || len(driver.records) != 2 {
+		t.Errorf("Must archive nothing without older records, got %d, %v", archived, err)
+	}
+}
+
+func Test_ArchiveHistory_unsupported(t *testing.T) {
+	if _, err := ArchiveHistory(&dummyDriver{}, time.Now(), &bytes.Buffer{}); err == nil {
+		t.Error("Must emit error when the driver can't delete records")
+	}
+}
@@ -0,0 +1,207 @@
+package darwin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// copyDirective bulk loads a data file into a table:
+//
+//	-- darwin:copy users.csv INTO users
+//
+// The file is resolved through the Files of the migration. Only the
+// directive is part of the checksum, not the content of the file.
+const copyDirective = "copy"
+
+// Attachment is a data file bulk loaded into a table by a migration.
+type Attachment struct {
+	File  string
+	Table string
+}
+
+// DataLoader is implemented by drivers able to stream the data files
+// attached to a migration into a table, for example using COPY or
+// LOAD DATA.
+type DataLoader interface {
+	LoadData(table string, r io.Reader) (time.Duration, error)
+}
+
+// AttachmentError is used to report when a data file attached to a migration
+// could not be loaded.
+type AttachmentError struct {
+	Version float64
+	File    string
+	Err     error
+}
+
+func (a AttachmentError) Error() string {
+	return fmt.Sprintf("darwin: unable to load file %s of migration %f: %s", a.File, a.Version, a.Err)
+}
+
+// errNoDataLoader is the error of the AttachmentErrors of the migrations
+// with data files when the driver isn't a DataLoader.
+var errNoDataLoader = errors.New("driver does not support data files")
+
+// checkAttachments returns an AttachmentError for the first data file of
+// the migrations, when d can't load them, so a plan is refused before any
+// of its migrations is executed.
+func checkAttachments(d Driver, migrations []Migration) error {
+	if _, ok := d.(DataLoader); ok {
+		return nil
+	}
+
+	for _, m := range migrations {
+		if streamed(d, m) {
+			continue
+		}
+
+		attachments, err := m.Attachments()
+		if err != nil {
+			return err
+		}
+		if len(attachments) > 0 {
+			return AttachmentError{Version: m.Version, File: attachments[0].File, Err: errNoDataLoader}
+		}
+	}
+
+	return nil
+}
+
+// Unwrap returns the underlying error.
+func (a AttachmentError) Unwrap() error {
+	return a.Err
+}
+
+// Attachments returns the data files referenced by the copy directives of
+// the migration, in order.
+func (m Migration) Attachments() ([]Attachment, error) {
+	var attachments []Attachment
+
+	for _, d := range Directives(m.Script) {
+		if d.Name != copyDirective {
+			continue
+		}
+
+		a, err := parseAttachment(d.Args)
+		if err != nil {
+			return nil, AttachmentError{Version: m.Version, Err: err}
+		}
+
+		attachments = append(attachments, a)
+	}
+
+	return attachments, nil
+}
+
+func parseAttachment(args string) (Attachment, error) {
+	fields := strings.Fields(args)
+	if len(fields) != 3 || !strings.EqualFold(fields[1], "INTO") {
+		return Attachment{}, fmt.Errorf("invalid copy directive %q, expected <file> INTO <table>", args)
+	}
+
+	return Attachment{File: fields[0], Table: fields[2]}, nil
+}
+
+// execMigration executes the migration script, streaming the attached data
+// files to the driver at the position of their copy directive, or streams
+// the script itself to an ExecReader driver.
+func execMigration(ctx context.Context, d Driver, m Migration) (Result, error) {
+	if streamed(d, m) {
+		return execStreamed(ctx, d, m)
+	}
+
+	result := Result{Migration: m}
+
+	attachments, err := m.Attachments()
+	if err != nil {
+		return result, err
+	}
+
+	if len(attachments) == 0 {
+		result.Statements, result.Duration, err = execScript(ctx, d, m.Script)
+		return result, err
+	}
+
+	loader, ok := d.(DataLoader)
+	if !ok {
+		return result, AttachmentError{Version: m.Version, File: attachments[0].File, Err: errNoDataLoader}
+	}
+
+	var script strings.Builder
+
+	flush := func() error {
+		if strings.TrimSpace(script.String()) == "" {
+			script.Reset()
+			return nil
+		}
+
+		statements, dur, err := execScript(ctx, d, script.String())
+		result.Statements = append(result.Statements, statements...)
+		result.Duration += dur
+		script.Reset()
+		return err
+	}
+
+	for _, line := range strings.SplitAfter(m.Script, "\n") {
+		directive, ok := parseDirective(line)
+		if !ok || directive.Name != copyDirective {
+			script.WriteString(line)
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return result, err
+		}
+
+		a, _ := parseAttachment(directive.Args)
+		dur, err := loadAttachment(loader, m, a)
+		result.Duration += dur
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, flush()
+}
+
+// execScript executes the script with ExecStatements when the driver is a
+// StatementExecer, or with ExecContext when it is a ContextDriver.
+func execScript(ctx context.Context, d Driver, script string) ([]StatementResult, time.Duration, error) {
+	if se, ok := d.(StatementExecer); ok {
+		statements, err := se.ExecStatements(ctx, script)
+		return statements, sumDurations(statements), err
+	}
+
+	ReportExecuting(ctx, script)
+
+	if cd, ok := d.(ContextDriver); ok {
+		dur, err := cd.ExecContext(ctx, script)
+		return nil, dur, err
+	}
+
+	dur, err := d.Exec(script)
+	return nil, dur, err
+}
+
+func loadAttachment(loader DataLoader, m Migration, a Attachment) (time.Duration, error) {
+	if m.Files == nil {
+		return 0, AttachmentError{Version: m.Version, File: a.File, Err: errors.New("migration has no files to resolve data files from")}
+	}
+
+	f, err := m.Files.Open(a.File)
+	if err != nil {
+		return 0, AttachmentError{Version: m.Version, File: a.File, Err: err}
+	}
+	defer f.Close()
+
+	dur, err := loader.LoadData(a.Table, f)
+	if err != nil {
+		return dur, AttachmentError{Version: m.Version, File: a.File, Err: err}
+	}
+
+	return dur, nil
+}
@@ -0,0 +1,195 @@
+package darwin
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+type loaderDriver struct {
+	dummyDriver
+	executed []string
+	loaded   map[string]string
+}
+
+func (d *loaderDriver) Exec(script string) (time.Duration, error) {
+	d.executed = append(d.executed, script)
+	return time.Millisecond, nil
+}
+
+func (d *loaderDriver) LoadData(table string, r io.Reader) (time.Duration, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+
+	if d.loaded == nil {
+		d.loaded = map[string]string{}
+	}
+	d.loaded[table] = string(b)
+
+	return time.Millisecond, nil
+}
+
+func Test_Migrate_with_attachments(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/001_schema.sql": {Data: []byte("-- Version: 1\n-- Description: Users\nCREATE TABLE users (id INT);\n-- darwin:copy data/users.csv INTO users\nANALYZE users;\n")},
+		"sql/data/users.csv": {Data: []byte("1\n2\n")},
+	}
+
+	migrations, err := NewFSSource(fsys, "sql").Migrations()
+	if err != nil {
+		t.Fatalf("unable to read migrations: %s", err)
+	}
+
+	driver := &loaderDriver{}
+	if err := Migrate(driver, migrations); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(driver.executed) != 2 || driver.executed[0] != "CREATE TABLE users (id INT);\n" || driver.executed[1] != "ANALYZE users;\n" {
+		t.Errorf("Must execute the script around the copy directive, got %q", driver.executed)
+	}
+
+	if driver.loaded["users"] != "1\n2\n" {
+		t.Errorf("Must stream the data file to the driver, got %q", driver.loaded["users"])
+	}
+
+	if len(driver.records) != 1 || driver.records[0].ExecutionTime != 3*time.Millisecond {
+		t.Errorf("Must record the migration with the total execution time, got %#v", driver.records)
+	}
+}
+
+func Test_Migrate_with_attachments_unsupported_driver(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{
+			Version: 2,
+			Script:  "-- darwin:copy users.csv INTO users\n",
+			Files:   fstest.MapFS{"users.csv": {Data: []byte("1\n")}},
+		},
+	}
+
+	driver := &dummyDriver{}
+	err := Migrate(driver, migrations)
+	if e, ok := err.(AttachmentError); !ok || e.Version != 2 || !strings.HasPrefix(err.Error(), "darwin: unable to load file users.csv") {
+		t.Errorf("Must return an AttachmentError, got %v", err)
+	}
+
+	if len(driver.records) != 0 {
+		t.Errorf("Must refuse the plan before executing it, got %d records", len(driver.records))
+	}
+}
+
+func Test_Migration_Attachments_invalid_directive(t *testing.T) {
+	m := Migration{Version: 1, Script: "-- darwin:copy users.csv users\n"}
+
+	if _, err := m.Attachments(); err == nil {
+		t.Error("Must emit error")
+	}
+}
+
+func Test_FSSource_parse_error(t *testing.T) {
+	fsys := fstest.MapFS{
+		"001.sql": {Data: []byte("-- Version: one\nSELECT 1;\n")},
+	}
+
+	if _, err := NewFSSource(fsys, "").Migrations(); err == nil {
+		t.Error("Must emit error")
+	}
+}
+
+func Test_FSSource_down_files(t *testing.T) {
+	fsys := fstest.MapFS{
+		"001_users.sql":      {Data: []byte("-- Version: 1\n-- Description: Users\nCREATE TABLE users (id INT);\n")},
+		"001_users.down.sql": {Data: []byte("-- Version: 1\n-- Description: Users\nDROP TABLE users;\n")},
+	}
+
+	migrations, err := NewFSSource(fsys, "").Migrations()
+	if err != nil || len(migrations) != 1 || migrations[0].Script != "CREATE TABLE users (id INT);\n" {
+		t.Errorf("Must skip the down files, got %#v %v", migrations, err)
+	}
+}
+
+func Test_FSSource_DownMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"001_users.sql":      {Data: []byte("-- Version: 1\n-- Description: Users\nCREATE TABLE users (id INT);\n")},
+		"001_users.down.sql": {Data: []byte("DROP TABLE users;\n")},
+		"002_more.sql":       {Data: []byte("-- Version: 2\nSELECT 2;\n-- Version: 3\nSELECT 3;\n")},
+		"002_more.down.sql":  {Data: []byte("-- Version: 3\nSELECT -3;\n")},
+	}
+
+	downs, err := NewFSSource(fsys, "").DownMigrations()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(downs) != 2 || downs[0].Version != 1 || downs[0].Description != "Users" || downs[0].Script != "DROP TABLE users;\n" {
+		t.Errorf("Must take the version of the single migration reverted, got %#v", downs)
+	}
+	if len(downs) == 2 && (downs[1].Version != 3 || downs[1].Script != "SELECT -3;\n") {
+		t.Errorf("Must parse the declared versions, got %#v", downs[1])
+	}
+
+	fsys["002_more.down.sql"] = &fstest.MapFile{Data: []byte("SELECT -2;\n")}
+	if _, err := NewFSSource(fsys, "").DownMigrations(); err == nil {
+		t.Error("Must emit error when the reverted migrations are ambiguous")
+	}
+}
+
+func Test_FSSource_Lazy(t *testing.T) {
+	fsys := fstest.MapFS{
+		"001_users.sql": {Data: []byte("-- Version: 1\n-- Description: Users\nCREATE TABLE users (id INT);\n")},
+		"002_more.sql":  {Data: []byte("-- Version: 2\nSELECT 2;\n-- Version: 3\nSELECT 3;\n")},
+	}
+
+	eager, _ := NewFSSource(fsys, "").Migrations()
+	migrations, err := FSSource{FS: fsys, Lazy: true}.Migrations()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	for i, m := range migrations {
+		if m.Script != "" || m.Checksum() != eager[i].Checksum() {
+			t.Errorf("Must leave the script out with its checksum, got %#v", m)
+		}
+	}
+
+	driver := &loaderDriver{}
+	driver.records = []MigrationRecord{{Version: 1, Checksum: eager[0].Checksum()}}
+
+	if err := New(driver, migrations).Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(driver.executed) != 2 || driver.executed[0] != "SELECT 2;\n" || driver.executed[1] != "SELECT 3;\n" {
+		t.Errorf("Must load the scripts of the pending migrations, got %#v", driver.executed)
+	}
+
+	delete(fsys, "002_more.sql")
+	if _, err := migrations[1].Load(); err == nil {
+		t.Error("Must emit error when the file can't be read again")
+	}
+}
+
+func Test_planMigration_loads_pending_scripts(t *testing.T) {
+	var loaded []float64
+	lazy := func(v float64) Migration {
+		return Migration{Version: v, ScriptChecksum: "sum", Loader: ScriptFunc(func() (string, error) {
+			loaded = append(loaded, v)
+			return "SELECT 1;", nil
+		})}
+	}
+
+	driver := &dummyDriver{records: []MigrationRecord{{Version: 1}}}
+	planned, err := planMigration(driver, []Migration{lazy(1), lazy(2)})
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(planned) != 1 || planned[0].Script != "SELECT 1;" || len(loaded) != 1 || loaded[0] != 2 {
+		t.Errorf("Must load the script of the pending migration only, got %#v and %v", planned, loaded)
+	}
+}
@@ -0,0 +1,190 @@
+package darwin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+)
+
+// Outcomes of a migration in an AuditRecord.
+const (
+	AuditApplied = "applied"
+	AuditFailed  = "failed"
+)
+
+// AuditRecord records a run of Migrate for change management: who ran it,
+// where, the plan and the outcome of each migration executed.
+type AuditRecord struct {
+	Time        time.Time        `json:"time"`
+	Identity    string           `json:"identity"`
+	Environment string           `json:"environment,omitempty"`
+	Plan        []AuditMigration `json:"plan"`
+	Outcomes    []AuditOutcome   `json:"outcomes"`
+	Error       string           `json:"error,omitempty"`
+}
+
+// AuditMigration is a migration of the plan of an AuditRecord.
+type AuditMigration struct {
+	Version     float64 `json:"version"`
+	Description string  `json:"description"`
+	Checksum    string  `json:"checksum"`
+}
+
+// AuditOutcome is the outcome of a migration executed, AuditApplied or
+// AuditFailed.
+type AuditOutcome struct {
+	AuditMigration
+	Outcome      string        `json:"outcome"`
+	Duration     time.Duration `json:"duration"`
+	RowsAffected int64         `json:"rows_affected"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// AuditSink receives an AuditRecord for each run of Migrate.
+type AuditSink interface {
+	Record(ctx context.Context, r AuditRecord) error
+}
+
+// WithAudit makes Migrate give an AuditRecord of each run to sink. Migrate
+// fails if the record can't be written.
+func WithAudit(sink AuditSink) Option {
+	return func(d *Darwin) {
+		d.audit = sink
+	}
+}
+
+// WithIdentity sets who runs the migrations in the audit records, the
+// current user and host by default.
+func WithIdentity(identity string) Option {
+	return func(d *Darwin) {
+		d.identity = identity
+	}
+}
+
+// WithEnvironment tags the runs with the environment migrated, like
+// "staging" or "production".
+func WithEnvironment(env string) Option {
+	return func(d *Darwin) {
+		d.environment = env
+	}
+}
+
+// JSONLinesSink is an AuditSink writing each record as a line of JSON.
+type JSONLinesSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLinesSink returns a sink writing the records to w.
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{w: w}
+}
+
+// OpenAuditFile returns a sink appending the records to the file at path,
+// created if necessary. The sink must be closed.
+func OpenAuditFile(path string) (*JSONLinesSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONLinesSink(f), nil
+}
+
+// Record writes the record.
+func (s *JSONLinesSink) Record(ctx context.Context, r AuditRecord) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.w.Write(append(b, '\n'))
+	return err
+}
+
+// Close closes the writer of the sink, if it is an io.Closer.
+func (s *JSONLinesSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// auditRun gives the record of a run of Migrate to the audit sink.
+func (d Darwin) auditRun(ctx context.Context, planned []Migration, report Report, err error, start time.Time) error {
+	if d.audit == nil {
+		return nil
+	}
+
+	r := AuditRecord{
+		Time:        start,
+		Identity:    d.identity,
+		Environment: d.environment,
+		Plan:        []AuditMigration{},
+		Outcomes:    []AuditOutcome{},
+	}
+
+	if r.Identity == "" {
+		r.Identity = defaultIdentity()
+	}
+
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	for _, m := range planned {
+		r.Plan = append(r.Plan, auditMigration(m))
+	}
+
+	for _, result := range report.Results {
+		r.Outcomes = append(r.Outcomes, AuditOutcome{
+			AuditMigration: auditMigration(result.Migration),
+			Outcome:        AuditApplied,
+			Duration:       result.Duration,
+			RowsAffected:   result.RowsAffected(),
+		})
+	}
+
+	var e ExecutionError
+	if errors.As(err, &e) {
+		for _, m := range planned {
+			if m.Version == e.Version {
+				r.Outcomes = append(r.Outcomes, AuditOutcome{
+					AuditMigration: auditMigration(m),
+					Outcome:        AuditFailed,
+					Error:          e.Err.Error(),
+				})
+			}
+		}
+	}
+
+	if err := d.audit.Record(ctx, r); err != nil {
+		return fmt.Errorf("darwin: unable to record the audit: %w", err)
+	}
+	return nil
+}
+
+func auditMigration(m Migration) AuditMigration {
+	return AuditMigration{Version: m.Version, Description: m.Description, Checksum: m.Checksum()}
+}
+
+// defaultIdentity returns the current user and host.
+func defaultIdentity() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+
+	if host, err := os.Hostname(); err == nil {
+		return name + "@" + host
+	}
+	return name
+}
@@ -0,0 +1,58 @@
+package darwin
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_WithAudit(t *testing.T) {
+	var buf bytes.Buffer
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+
+	d := New(&dummyDriver{}, migrations, WithAudit(NewJSONLinesSink(&buf)), WithIdentity("deploy-bot"), WithEnvironment("production"))
+	if err := d.Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	var r AuditRecord
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatalf("Must write a line of JSON, got %s", err)
+	}
+
+	if r.Identity != "deploy-bot" || r.Environment != "production" || len(r.Plan) != 2 || r.Plan[1].Checksum != migrations[1].Checksum() {
+		t.Errorf("Must record who ran which plan where, got %#v", r)
+	}
+
+	if len(r.Outcomes) != 2 || r.Outcomes[0].Outcome != AuditApplied || r.Error != "" {
+		t.Errorf("Must record the outcomes, got %#v", r.Outcomes)
+	}
+}
+
+func Test_WithAudit_failure(t *testing.T) {
+	var buf bytes.Buffer
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+	}
+
+	if err := New(&dummyDriver{ExecError: true}, migrations, WithAudit(NewJSONLinesSink(&buf))).Migrate(); err == nil {
+		t.Fatalf("Must return the error of Exec")
+	}
+
+	if strings.Count(buf.String(), "\n") != 1 {
+		t.Fatalf("Must write one line per run, got %q", buf.String())
+	}
+
+	var r AuditRecord
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatalf("Must write a line of JSON, got %s", err)
+	}
+
+	if len(r.Outcomes) != 1 || r.Outcomes[0].Outcome != AuditFailed || r.Outcomes[0].Error == "" || r.Error == "" || r.Identity == "" {
+		t.Errorf("Must record the failed migration, got %#v", r)
+	}
+}
@@ -0,0 +1,34 @@
+package darwin
+
+import (
+	"context"
+	"fmt"
+)
+
+// BackupFunc takes a backup of the database before the migrations of the
+// plan are executed, like running pg_dump or requesting a snapshot from the
+// API of the provider.
+type BackupFunc func(ctx context.Context, planned []Migration) error
+
+// WithBackup makes Migrate call f once the plan is approved and before any
+// of its migrations is executed, so every schema change has a recovery
+// point. f isn't called when nothing is pending. A failing backup stops
+// Migrate.
+func WithBackup(f BackupFunc) Option {
+	return func(d *Darwin) {
+		d.backup = f
+	}
+}
+
+// takeBackup calls the backup hook with the plan, unless it is empty.
+func (d Darwin) takeBackup(ctx context.Context, planned []Migration) error {
+	if d.backup == nil || len(planned) == 0 {
+		return nil
+	}
+
+	d.logger().Info("darwin: backup started", "pending", len(planned))
+	if err := d.backup(ctx, planned); err != nil {
+		return fmt.Errorf("darwin: backup: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package darwin
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func Test_WithBackup(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+
+	boom := errors.New("boom")
+	driver := &dummyDriver{}
+	err := New(driver, migrations, WithBackup(func(ctx context.Context, planned []Migration) error {
+		return boom
+	})).Migrate()
+	if !errors.Is(err, boom) {
+		t.Fatalf("Must return the error of the backup, got %v", err)
+	}
+
+	if all, _ := driver.All(); len(all) != 0 {
+		t.Errorf("Must not execute any migration, got %#v", all)
+	}
+
+	var backups []int
+	backup := func(ctx context.Context, planned []Migration) error {
+		backups = append(backups, len(planned))
+		return nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := New(driver, migrations, WithBackup(backup)).Migrate(); err != nil {
+			t.Fatalf("Must not return error, got %s", err)
+		}
+	}
+
+	if len(backups) != 1 || backups[0] != 2 {
+		t.Errorf("Must back up once before the pending migrations, got %v", backups)
+	}
+}
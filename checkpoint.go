@@ -0,0 +1,140 @@
+package darwin
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TransactionalDDLDialect is implemented by dialects reporting whether their
+// database can roll back schema changes. Dialects not implementing it are
+// assumed to.
+type TransactionalDDLDialect interface {
+	SupportsTransactionalDDL() bool
+}
+
+// CheckpointDialect is implemented by the dialects of databases committing
+// DDL implicitly, like MySQL and Oracle. The generic driver executes their
+// migrations one statement at a time and records each executed statement in
+// a checkpoint table, with its index and checksum, so running Migrate again
+// after a failure resumes the migration at the failed statement instead of
+// executing it from the start, even if the failed statement was fixed. The
+// checkpoints of a migration are deleted when it is recorded.
+type CheckpointDialect interface {
+	CreateCheckpointTableSQL() string
+	InsertCheckpointSQL() string
+	CheckpointsSQL() string
+	DeleteCheckpointsSQL() string
+}
+
+// supportsTransactionalDDL reports whether the database of the dialect can
+// roll back schema changes.
+func supportsTransactionalDDL(d Dialect) bool {
+	t, ok := d.(TransactionalDDLDialect)
+	return !ok || t.SupportsTransactionalDDL()
+}
+
+// checkpointDialect returns the dialect of the driver if its migrations are
+// executed with checkpoints.
+func (m *GenericDriver) checkpointDialect() (CheckpointDialect, bool) {
+	if supportsTransactionalDDL(m.Dialect) {
+		return nil, false
+	}
+	c, ok := m.Dialect.(CheckpointDialect)
+	return c, ok
+}
+
+// Checkpoint identifies an executed statement of a migration, by its
+// 0-based index in the script and its checksum.
+type Checkpoint struct {
+	Statement int
+	Checksum  string
+}
+
+// CheckpointStore keeps the checkpoints of a migration for
+// ExecCheckpointed.
+type CheckpointStore interface {
+	Checkpoints(ctx context.Context) (map[Checkpoint]bool, error)
+	SaveCheckpoint(ctx context.Context, cp Checkpoint) error
+}
+
+// ExecCheckpointed executes with exec the statements of the script, split
+// with the syntax, which have no checkpoint in s, saving one after each of
+// them. A fixed statement has another checksum and executes again, while
+// the statements before it are skipped. The error of a failed statement is
+// a StatementFailure. It is meant for the drivers of databases committing
+// DDL implicitly, the generic driver using it with a CheckpointDialect.
+func ExecCheckpointed(ctx context.Context, s CheckpointStore, script string, syntax Syntax, exec func(ctx context.Context, statement string) error) error {
+	done, err := s.Checkpoints(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i, stmt := range SplitStatements(script, syntax) {
+		cp := Checkpoint{Statement: i, Checksum: fmt.Sprintf("%x", md5.Sum([]byte(stmt)))}
+		if done[cp] {
+			continue
+		}
+
+		if err := exec(ctx, stmt); err != nil {
+			return statementError{index: i, statement: stmt, err: err}
+		}
+
+		if err := s.SaveCheckpoint(ctx, cp); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// execCheckpointed executes with e the statements of the script which have
+// no checkpoint in the checkpoint table of c.
+func (m *GenericDriver) execCheckpointed(ctx context.Context, e execer, c CheckpointDialect, script string) error {
+	return ExecCheckpointed(ctx, sqlCheckpoints{e, c}, script, syntaxOf(m.Dialect), func(ctx context.Context, stmt string) error {
+		_, err := e.ExecContext(ctx, stmt)
+		return err
+	})
+}
+
+// CheckpointTable returns the CheckpointStore of the checkpoint table of
+// the dialect, read and written with conn, for the drivers executing the
+// statements on a session of their own.
+func CheckpointTable(conn *sql.Conn, c CheckpointDialect) CheckpointStore {
+	return sqlCheckpoints{conn, c}
+}
+
+// sqlCheckpoints is the CheckpointStore of the checkpoint table of a
+// CheckpointDialect.
+type sqlCheckpoints struct {
+	e execer
+	c CheckpointDialect
+}
+
+// Checkpoints returns the statements already executed.
+func (s sqlCheckpoints) Checkpoints(ctx context.Context) (map[Checkpoint]bool, error) {
+	rows, err := s.e.QueryContext(ctx, s.c.CheckpointsSQL())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := make(map[Checkpoint]bool)
+	for rows.Next() {
+		var cp Checkpoint
+		if err := rows.Scan(&cp.Statement, &cp.Checksum); err != nil {
+			return nil, err
+		}
+		done[cp] = true
+	}
+
+	return done, rows.Err()
+}
+
+// SaveCheckpoint records the statement executed.
+func (s sqlCheckpoints) SaveCheckpoint(ctx context.Context, cp Checkpoint) error {
+	_, err := s.e.ExecContext(ctx, s.c.InsertCheckpointSQL(), cp.Statement, cp.Checksum, time.Now().Unix())
+	return err
+}
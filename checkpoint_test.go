@@ -0,0 +1,67 @@
+package darwin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func Test_GenericDriver_Exec_resumes_checkpoints(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	dialect := StandardDialect{NoTransactionalDDL: true}
+	d, _ := NewGenericDriver(db, dialect)
+
+	script := "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\nCREATE TABLE c (id INT);"
+	checksum := func(stmt string) string {
+		return Migration{Script: stmt}.Checksum()
+	}
+
+	// The first statement was executed and the second one fixed since the
+	// last run.
+	mock.ExpectQuery(escapeQuery(dialect.CheckpointsSQL())).
+		WillReturnRows(sqlmock.NewRows([]string{"statement", "checksum"}).
+			AddRow(0, checksum("CREATE TABLE a (id INT)")).
+			AddRow(1, checksum("CREATE TABLE b (id TEXT)")))
+	mock.ExpectExec(escapeQuery("CREATE TABLE b (id INT)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(escapeQuery(dialect.InsertCheckpointSQL())).
+		WithArgs(1, checksum("CREATE TABLE b (id INT)"), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(escapeQuery("CREATE TABLE c (id INT)")).WillReturnError(errors.New("Generic Error"))
+
+	if _, err := d.ExecContext(context.Background(), script); err == nil {
+		t.Errorf("Must return the error of the failed statement")
+	}
+
+	if _, err := d.BeginTx(context.Background()); err != ErrTransactionUnsupported {
+		t.Errorf("Expected ErrTransactionUnsupported, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_supportsTransactionalDDL(t *testing.T) {
+	expectations := []struct {
+		dialect  Dialect
+		expected bool
+	}{
+		{PostgresDialect{}, true},
+		{MySQLDialect{}, false},
+		{StandardDialect{NoTransactionalDDL: true}, false},
+		{QLDialect{}, true},
+	}
+
+	for _, expectation := range expectations {
+		if got := supportsTransactionalDDL(expectation.dialect); got != expectation.expected {
+			t.Errorf("supportsTransactionalDDL(%T) == %t, wants %t", expectation.dialect, got, expectation.expected)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package darwin
+
+import (
+	"bufio"
+	"crypto/md5"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChecksumCache keeps the versions, descriptions and checksums of the
+// migrations of the files a lazy FSSource read, by file identity: name,
+// size and modification time. A file unchanged since it was read isn't read
+// again. The files without a modification time, like those of an embed.FS,
+// aren't cached. A ChecksumCache is safe for concurrent use.
+type ChecksumCache struct {
+	mu    sync.Mutex
+	files map[fileIdentity][]scanned
+}
+
+// NewChecksumCache returns an empty ChecksumCache.
+func NewChecksumCache() *ChecksumCache {
+	return &ChecksumCache{files: map[fileIdentity][]scanned{}}
+}
+
+// fileIdentity identifies the content of a file without reading it.
+type fileIdentity struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+// scanned is a migration of a file, without its script.
+type scanned struct {
+	version     float64
+	description string
+	checksum    string
+	stream      bool
+}
+
+// checksums returns the migrations of the file name without their scripts,
+// from the cache of the source when the file didn't change.
+func (s FSSource) checksums(files fs.FS, name string) ([]scanned, error) {
+	if s.Checksums == nil {
+		return s.hash(files, name)
+	}
+
+	info, err := fs.Stat(files, name)
+	if err != nil {
+		return nil, err
+	}
+	if info.ModTime().IsZero() {
+		return s.hash(files, name)
+	}
+
+	id := fileIdentity{name: s.path(name), size: info.Size(), modTime: info.ModTime()}
+
+	s.Checksums.mu.Lock()
+	migs, ok := s.Checksums.files[id]
+	s.Checksums.mu.Unlock()
+	if ok {
+		return migs, nil
+	}
+
+	migs, err = s.hash(files, name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Checksums.mu.Lock()
+	if s.Checksums.files == nil {
+		s.Checksums.files = map[fileIdentity][]scanned{}
+	}
+	s.Checksums.files[id] = migs
+	s.Checksums.mu.Unlock()
+
+	return migs, nil
+}
+
+// hash reads the migrations of the file name like ParseMigrations, hashing
+// their scripts line by line instead of holding them in memory.
+func (s FSSource) hash(files fs.FS, name string) ([]scanned, error) {
+	f, err := files.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p := newParser(s.Options)
+
+	var (
+		migs    []scanned
+		current *scanned
+		h       hash.Hash
+	)
+
+	flush := func() {
+		if current != nil {
+			current.checksum = fmt.Sprintf("%x", h.Sum(nil))
+			migs = append(migs, *current)
+		}
+	}
+
+	r := bufio.NewReader(f)
+	for first := true; ; first = false {
+		line, err := r.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		if first {
+			line = strings.TrimPrefix(line, byteOrderMark)
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if value, ok := p.header(line, p.versionMarker); ok {
+			flush()
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("darwin: unable to parse migrations in %s", s.path(name))
+			}
+			current, h = &scanned{version: v}, md5.New()
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		if value, ok := p.header(line, p.descriptionMarker); ok {
+			current.description = value
+			continue
+		}
+
+		if d, ok := parseDirective(line); ok && d.Name == streamDirective {
+			current.stream = true
+		}
+		io.WriteString(h, line+"\n")
+	}
+
+	flush()
+	return migs, nil
+}
@@ -0,0 +1,58 @@
+package darwin
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func Test_FSSource_Lazy_checksums(t *testing.T) {
+	document := "\ufeff-- preamble\n-- Version: 1\r\n-- Description: Users\r\nCREATE TABLE users (id INT);\r\n\n-- Version: 2\n-- darwin:stream\nINSERT INTO users VALUES (1);"
+	fsys := fstest.MapFS{"001_users.sql": {Data: []byte(document)}}
+
+	eager := ParseMigrations(document)
+	migrations, err := FSSource{FS: fsys, Lazy: true}.Migrations()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(migrations) != 2 {
+		t.Fatalf("Must read the migrations, got %#v", migrations)
+	}
+
+	for i, m := range migrations {
+		if m.Version != eager[i].Version || m.Description != eager[i].Description || m.Checksum() != eager[i].Checksum() {
+			t.Errorf("Must hash the script as it is parsed, got %#v", m)
+		}
+	}
+
+	if _, ok := migrations[1].Loader.(ScriptOpener); !ok {
+		t.Errorf("Must stream the script with the stream directive, got %#v", migrations[1].Loader)
+	}
+
+	fsys["002_bad.sql"] = &fstest.MapFile{Data: []byte("-- Version: two\n")}
+	if _, err := (FSSource{FS: fsys, Lazy: true}).Migrations(); err == nil {
+		t.Error("Must emit error when a version doesn't parse")
+	}
+}
+
+func Test_ChecksumCache(t *testing.T) {
+	modified := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{"001_users.sql": {Data: []byte("-- Version: 1\nSELECT 1;\n"), ModTime: modified}}
+	source := FSSource{FS: fsys, Lazy: true, Checksums: NewChecksumCache()}
+
+	first, err := source.Migrations()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	fsys["001_users.sql"].Data = []byte("-- Version: 1\nSELECT 2;\n")
+	if cached, _ := source.Migrations(); cached[0].Checksum() != first[0].Checksum() {
+		t.Error("Must not read an unchanged file again")
+	}
+
+	fsys["001_users.sql"].ModTime = modified.Add(time.Second)
+	if changed, _ := source.Migrations(); changed[0].Checksum() == first[0].Checksum() {
+		t.Error("Must read a modified file again")
+	}
+}
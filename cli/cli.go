@@ -0,0 +1,566 @@
+// Package cli implements the darwin command, which runs the migrations of a
+// directory of .sql files against a database:
+//
+//	darwin -driver postgres -dsn "$DATABASE_URL" -dir migrations migrate
+//
+// The commands are migrate, status, validate and info. The database is
+// opened with database/sql, the history table is written in the darwin
+// dialect registered with the name of the driver, or the one given with
+// -dialect. The down command reverts the latest migration, or more, with
+// the scripts of their .down.sql files; in the production environment,
+// given with -env, it must be confirmed with -yes:
+//
+//	darwin -env production -driver postgres -dsn "$DATABASE_URL" down -steps 2 -yes
+//
+// The new command creates the file of a new migration, with the
+// next version, without opening the database:
+//
+//	darwin -dir migrations new -down "add users table"
+//
+// The -output flag selects the output of every command: table, for people,
+// json, a single document per command, or quiet. The exit code tells the
+// outcome: ExitPending for status -check when migrations are pending, which
+// lets deploy pipelines gate on the state of the database, ExitInvalid when
+// the applied migrations don't match the directory:
+//
+//	darwin -output json -driver postgres -dsn "$DATABASE_URL" status -check
+//
+// The squash command replaces the oldest migrations with a baseline, a
+// single migration of their scripts, and their records with its record.
+// The squashed files are moved to the archive directory. Run against the
+// other databases once the files are squashed, it replaces their records:
+//
+//	darwin -dsn "$STAGING_URL" squash -through 1200
+//
+// The lint command checks the files for CI without a database, reporting
+// each finding with its file, line and rule:
+//
+//	migrations/0003_users.sql:7: destructive: DROP TABLE loses data
+//
+// The settings can be given in a darwin.yaml or darwin.toml file in the
+// working directory, or the file of -config or DARWIN_CONFIG, and in DARWIN_
+// environment variables named after the flags, like DARWIN_DSN. A flag
+// overrides its variable, which overrides the file:
+//
+//	# darwin.yaml
+//	driver: postgres
+//	dir: db/migrations
+//	env: production
+//
+// The history keeps how long each migration took; info -slower-than lists
+// the migrations which took longer, the slowest first:
+//
+//	darwin info -slower-than 1m
+//
+// With -interactive, migrate shows each pending migration, its number of
+// statements and its size, and asks whether to apply it.
+//
+// In a Kubernetes init container, migrate waits for the database, holds
+// the advisory lock while the replicas of a deployment start together, and
+// reports the outcome as JSON, "nothing_to_do", "applied" or "failed", and,
+// with -detailed-exit-codes, as ExitOK, ExitApplied or ExitError:
+//
+//	darwin -output json migrate -wait -lock -timeout 10m
+//
+// The darwin binary of cmd/darwin only includes the ql database driver. A
+// binary for other databases imports their drivers and calls Main:
+//
+//	package main
+//
+//	import (
+//		"os"
+//
+//		"github.com/dustinevan/darwin/cli"
+//		_ "github.com/lib/pq"
+//	)
+//
+//	func main() {
+//		os.Exit(cli.Main(os.Args[1:], os.Stdout, os.Stderr))
+//	}
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Exit codes of Main. ExitPending is returned by status -check when
+// migrations are pending, ExitInvalid when the applied migrations don't
+// match the directory or lint has findings, and ExitApplied by migrate
+// -detailed-exit-codes when migrations were applied.
+const (
+	ExitOK      = 0
+	ExitError   = 1
+	ExitUsage   = 2
+	ExitPending = 3
+	ExitInvalid = 4
+	ExitApplied = 5
+)
+
+// errUsage reports invalid arguments, the usage being printed.
+var errUsage = errors.New("invalid usage")
+
+// errPending reports pending migrations to status -check, errApplied
+// applied migrations to migrate -detailed-exit-codes.
+var (
+	errPending = errors.New("migrations are pending")
+	errApplied = errors.New("migrations were applied")
+)
+
+// Modes of the commands.
+const (
+	online  = iota // the command opens the database
+	offline        // the command reads the migrations only
+	files          // the command reads the files of the directory itself
+)
+
+// commands are the commands of Main, with their help.
+var commands = []struct {
+	name string
+	help string
+	mode int
+	run  func(e *env, args []string) error
+}{
+	{"migrate", "apply the pending migrations: migrate [-interactive] [-wait] [-lock] [-timeout D] [-detailed-exit-codes]", online, migrate},
+	{"status", "tell if the database is up to date: status [-check]", online, status},
+	{"validate", "check the applied migrations against the directory", online, validate},
+	{"info", "list the migrations with their status", online, info},
+	{"down", "revert migrations: down [-steps N | -to VERSION] [-dry-run] [-yes]", online, down},
+	{"squash", "replace the migrations up to a version with a baseline: squash -through VERSION [-archive DIR]", online, squash},
+	{"new", "create the file of a new migration: new [-scheme numeric|timestamp] [-down] description", offline, newMigration},
+	{"lint", "check the migration files without a database: lint [-disable RULE,...]", files, lint},
+}
+
+// env is the environment of a command. Offline commands have no driver.
+type env struct {
+	stdout      io.Writer
+	stderr      io.Writer
+	dir         string
+	environment string
+	output      string
+	migrations  []darwin.Migration
+	driver      *darwin.GenericDriver
+	options     []darwin.Option
+	darwin      darwin.Darwin
+}
+
+// Main runs the darwin command with the arguments, without the program
+// name, and returns the exit code.
+func Main(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("darwin", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var c config
+	fs.StringVar(&c.driver, "driver", "", "name of the database/sql driver")
+	fs.StringVar(&c.dsn, "dsn", "", "data source name of the database")
+	fs.StringVar(&c.dialect, "dialect", "", "darwin dialect, the name of the driver by default")
+	fs.StringVar(&c.dir, "dir", "migrations", "directory of the .sql migration files")
+	fs.StringVar(&c.table, "table", "", "name of the history table, darwin_migrations by default")
+	fs.StringVar(&c.environment, "env", "", "environment of the database, down requires -yes in production")
+	fs.StringVar(&c.output, "output", OutputTable, "output format, table, json or quiet")
+	file := fs.String("config", "", "configuration file, darwin.yaml, darwin.yml or darwin.toml by default")
+
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: darwin [flags] command\n\nCommands:\n")
+		for _, c := range commands {
+			fmt.Fprintf(stderr, "  %-10s %s\n", c.name, c.help)
+		}
+		fmt.Fprintf(stderr, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+
+	if err := c.configure(fs, *file); err != nil {
+		fmt.Fprintf(stderr, "darwin: %s\n", err)
+		return ExitError
+	}
+
+	if fs.NArg() == 0 || !validOutput(c.output) {
+		fs.Usage()
+		return ExitUsage
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != fs.Arg(0) {
+			continue
+		}
+
+		err := run(cmd.run, cmd.mode, fs.Args()[1:], stdout, stderr, c)
+		switch {
+		case err == errUsage:
+			fs.Usage()
+			return ExitUsage
+		case err == errPending:
+			return ExitPending
+		case err == errApplied:
+			return ExitApplied
+		case err != nil:
+			fmt.Fprintf(stderr, "darwin: %s\n", strings.TrimPrefix(err.Error(), "darwin: "))
+			if invalid(err) {
+				return ExitInvalid
+			}
+			return ExitError
+		}
+		return ExitOK
+	}
+
+	fmt.Fprintf(stderr, "darwin: unknown command %q\n", fs.Arg(0))
+	fs.Usage()
+	return ExitUsage
+}
+
+// run reads the migrations and opens the database, as the mode of the
+// command requires, and runs the command.
+func run(f func(*env, []string) error, mode int, args []string, stdout, stderr io.Writer, c config) error {
+	e := &env{stdout: stdout, stderr: stderr, dir: c.dir, environment: c.environment, output: c.output}
+	if mode == files {
+		return f(e, args)
+	}
+
+	migrations, err := darwin.NewFSSource(os.DirFS(c.dir), ".").Migrations()
+	if err != nil {
+		return err
+	}
+
+	e.migrations = migrations
+	if mode == offline {
+		return f(e, args)
+	}
+
+	if c.driver == "" || c.dsn == "" {
+		return errUsage
+	}
+
+	dialect := c.dialect
+	if dialect == "" {
+		dialect = c.driver
+	}
+
+	if !registered(c.driver) {
+		return fmt.Errorf("unknown driver %q, this binary includes %s", c.driver, strings.Join(sql.Drivers(), ", "))
+	}
+
+	db, err := sql.Open(c.driver, c.dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	d, err := darwin.OpenDriver(dialect, db)
+	if err != nil {
+		return err
+	}
+
+	if c.table != "" {
+		if err := d.SetTableName(c.table); err != nil {
+			return err
+		}
+	}
+
+	e.driver = d
+	e.options = []darwin.Option{darwin.WithEnvironment(c.environment)}
+	e.darwin = darwin.New(d, migrations, e.options...)
+	return f(e, args)
+}
+
+// registered reports whether the database/sql driver name is linked in the
+// binary.
+func registered(name string) bool {
+	for _, d := range sql.Drivers() {
+		if d == name {
+			return true
+		}
+	}
+	return false
+}
+
+// create creates or upgrades the history table for the commands changing
+// the history. Migrate creates it itself, once the database is ready.
+func (e *env) create() error {
+	return e.driver.Create()
+}
+
+// reader returns the Darwin of the commands which only read the history,
+// which neither create nor upgrade the history table: a database without
+// it has no migration applied.
+func (e *env) reader() (darwin.Darwin, error) {
+	exists, err := e.driver.HistoryExists()
+	if err != nil || exists {
+		return e.darwin, err
+	}
+	return darwin.New(emptyHistory{e.driver}, e.migrations, e.options...), nil
+}
+
+// noLock is the lock of migrate without -lock, it excludes nothing.
+type noLock struct{}
+
+func (noLock) Lock(context.Context) error   { return nil }
+func (noLock) Unlock(context.Context) error { return nil }
+
+// emptyHistory is the driver of a database without history table.
+type emptyHistory struct {
+	darwin.Driver
+}
+
+func (emptyHistory) All() ([]darwin.MigrationRecord, error) {
+	return nil, nil
+}
+
+// invalid reports whether the error tells the applied migrations don't
+// match the directory, or the files have lint findings.
+func invalid(err error) bool {
+	switch err.(type) {
+	case darwin.IllegalMigrationVersionError, darwin.DuplicateMigrationVersionError,
+		darwin.RemovedMigrationError, darwin.InvalidChecksumError, lintError:
+		return true
+	}
+	return false
+}
+
+// Outcomes of migrate.
+const (
+	outcomeNothing = "nothing_to_do"
+	outcomeApplied = "applied"
+	outcomeFailed  = "failed"
+)
+
+// waitBackoff is the initial wait between the attempts of migrate -wait.
+const waitBackoff = time.Second
+
+// migrateJSON is the JSON output of migrate.
+type migrateJSON struct {
+	Outcome    string       `json:"outcome"`
+	Applied    []resultJSON `json:"applied"`
+	DurationMS int64        `json:"duration_ms"`
+	Error      string       `json:"error,omitempty"`
+}
+
+// migrate applies the pending migrations, asking before each one with
+// -interactive. For init containers, -wait waits for the database to accept
+// connections, -lock holds the advisory lock of the dialect for the whole
+// run, without it migrate takes no lock, and -timeout bounds the whole run; with -detailed-exit-codes the exit code
+// tells the outcome, ExitOK when there was nothing to do, ExitApplied when
+// migrations were applied.
+func migrate(e *env, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	wait := fs.Bool("wait", false, "wait for the database to accept connections")
+	lock := fs.Bool("lock", false, "hold the advisory lock of the dialect while migrating")
+	timeout := fs.Duration("timeout", 0, "maximum duration of the run, including the waits")
+	detailed := fs.Bool("detailed-exit-codes", false, "exit with ExitApplied when migrations were applied")
+	interactive := fs.Bool("interactive", false, "ask before applying each migration")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return errUsage
+	}
+
+	if *interactive && e.output != OutputTable {
+		return errUsage
+	}
+
+	opts := append([]darwin.Option{}, e.options...)
+	if *interactive {
+		opts = append(opts, darwin.WithConfirmation(e.confirmation()))
+	}
+	if !*lock {
+		opts = append(opts, darwin.WithLock(noLock{}))
+	}
+	d := darwin.New(e.driver, e.migrations, opts...)
+
+	start := time.Now()
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+		e.driver.LockTimeout = *timeout
+	}
+
+	report, err := func() (darwin.Report, error) {
+		if _, ok := e.driver.Dialect.(darwin.LockDialect); *lock && !ok {
+			return darwin.Report{}, errors.New("the dialect has no advisory lock")
+		}
+		if *wait {
+			if err := darwin.WaitForDriver(ctx, e.driver, waitBackoff); err != nil {
+				return darwin.Report{}, err
+			}
+		}
+		return d.MigrateReport(ctx)
+	}()
+
+	outcome := outcomeApplied
+	switch {
+	case err != nil:
+		outcome = outcomeFailed
+	case len(report.Results) == 0:
+		outcome = outcomeNothing
+	}
+
+	if e.output == OutputJSON {
+		doc := migrateJSON{
+			Outcome:    outcome,
+			Applied:    newResultsJSON(report),
+			DurationMS: time.Since(start).Milliseconds(),
+			Error:      errorString(err),
+		}
+		if jerr := e.json(doc); err == nil {
+			err = jerr
+		}
+	} else {
+		for _, r := range report.Results {
+			e.printf("Applied %s %s (%s)\n", version(r.Migration.Version), r.Migration.Description, r.Duration)
+		}
+		if outcome == outcomeNothing {
+			e.printf("Nothing to migrate\n")
+		}
+	}
+
+	if err == nil && *detailed && outcome == outcomeApplied {
+		return errApplied
+	}
+	return err
+}
+
+// statusJSON is the JSON output of status.
+type statusJSON struct {
+	UpToDate      bool      `json:"up_to_date"`
+	Pending       []float64 `json:"pending"`
+	LatestVersion *float64  `json:"latest_version"`
+}
+
+// status tells if the database is up to date. With -check it fails with
+// ExitPending when migrations are pending.
+func status(e *env, args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	check := fs.Bool("check", false, "exit with a non-zero code when migrations are pending")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return errUsage
+	}
+
+	d, err := e.reader()
+	if err != nil {
+		return err
+	}
+
+	infos, err := d.Info()
+	if err != nil {
+		return err
+	}
+
+	doc := statusJSON{Pending: []float64{}}
+	var pending []string
+	for _, i := range darwin.FilterInfo(infos, darwin.ByStatus(darwin.Pending)) {
+		doc.Pending = append(doc.Pending, i.Migration.Version)
+		pending = append(pending, version(i.Migration.Version))
+	}
+	doc.UpToDate = len(pending) == 0
+
+	last, ok, err := d.LatestApplied()
+	if err != nil {
+		return err
+	}
+	if ok {
+		doc.LatestVersion = &last.Version
+	}
+
+	switch {
+	case e.output == OutputJSON:
+		if err := e.json(doc); err != nil {
+			return err
+		}
+	case len(pending) > 0:
+		e.printf("%d pending migrations: %s\n", len(pending), strings.Join(pending, ", "))
+	case ok:
+		e.printf("Up to date at version %s\n", version(last.Version))
+	default:
+		e.printf("Up to date, no migration\n")
+	}
+
+	if *check && !doc.UpToDate {
+		return errPending
+	}
+	return nil
+}
+
+// validateJSON is the JSON output of validate.
+type validateJSON struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+func validate(e *env, args []string) error {
+	d, err := e.reader()
+	if err != nil {
+		return err
+	}
+
+	err = d.Validate()
+
+	if e.output == OutputJSON {
+		if jerr := e.json(validateJSON{Valid: err == nil, Error: errorString(err)}); err == nil {
+			err = jerr
+		}
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	e.printf("The applied migrations are valid\n")
+	return nil
+}
+
+func info(e *env, args []string) error {
+	fs := flag.NewFlagSet("info", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	slower := fs.Duration("slower-than", 0, "list only the applied migrations which took longer, the slowest first")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return errUsage
+	}
+
+	d, err := e.reader()
+	if err != nil {
+		return err
+	}
+
+	infos, err := d.Info()
+	if err != nil {
+		return err
+	}
+
+	if *slower > 0 {
+		infos = darwin.FilterInfo(infos, darwin.SlowerThan(*slower))
+		darwin.SortInfoBySlowest(infos)
+	}
+
+	switch e.output {
+	case OutputJSON:
+		return darwin.WriteInfoJSON(e.stdout, infos)
+	case OutputQuiet:
+		return nil
+	}
+
+	return darwin.RenderInfoTable(e.stdout, infos)
+}
+
+func version(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,185 @@
+package cli
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dustinevan/darwin"
+
+	_ "github.com/cznic/ql/driver"
+)
+
+// writeMigrations writes the migrations in a temporary directory.
+func writeMigrations(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// runCLI runs Main against a ql database in the directory.
+func runCLI(t *testing.T, dir string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	flags := []string{"-driver", "ql", "-dsn", filepath.Join(dir, "test.db"), "-dir", dir}
+	code := Main(append(flags, args...), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func Test_Main(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"1_posts.sql": "-- Version: 1\n-- Description: Posts\nCREATE TABLE posts (id int);\n",
+		"2_body.sql":  "-- Version: 1.1\n-- Description: Body\nALTER TABLE posts ADD body string;\n",
+	})
+
+	if code, out, _ := runCLI(t, dir, "status"); code != ExitOK || out != "2 pending migrations: 1, 1.1\n" {
+		t.Errorf("Must report the pending migrations, got %d %q", code, out)
+	}
+
+	code, out, stderr := runCLI(t, dir, "migrate")
+	if code != ExitOK || !strings.Contains(out, "Applied 1 Posts") || !strings.Contains(out, "Applied 1.1 Body") {
+		t.Fatalf("Must apply the migrations, got %d %q %q", code, out, stderr)
+	}
+
+	if code, out, _ := runCLI(t, dir, "status"); code != ExitOK || out != "Up to date at version 1.1\n" {
+		t.Errorf("Must report the database up to date, got %d %q", code, out)
+	}
+
+	if code, out, _ := runCLI(t, dir, "validate"); code != ExitOK || out != "The applied migrations are valid\n" {
+		t.Errorf("Must validate the migrations, got %d %q", code, out)
+	}
+
+	if code, out, _ := runCLI(t, dir, "info"); code != ExitOK || !strings.HasPrefix(out, "VERSION") || strings.Count(out, "APPLIED  ") != 2 {
+		t.Errorf("Must list the migrations, got %d %q", code, out)
+	}
+}
+
+func Test_Main_read_only(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"1_posts.sql": "-- Version: 1\n-- Description: Posts\nCREATE TABLE posts (id int);\n",
+	})
+
+	for _, command := range []string{"status", "validate", "info"} {
+		if code, _, stderr := runCLI(t, dir, command); code != ExitOK {
+			t.Errorf("%s: Must read a database without history, got %d %q", command, code, stderr)
+		}
+	}
+
+	db, err := sql.Open("ql", filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if exists, err := (&darwin.GenericDriver{DB: db, Dialect: darwin.QLDialect{}}).HistoryExists(); err != nil || exists {
+		t.Errorf("Must not create the history table, got %v %v", exists, err)
+	}
+}
+
+func Test_Main_errors(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"1_posts.sql": "-- Version: 1\n-- Description: Posts\nCREATE TABLE posts (id int);\n",
+	})
+
+	if code, _, stderr := runCLI(t, dir, "upgrade"); code != ExitUsage || !strings.Contains(stderr, `unknown command "upgrade"`) {
+		t.Errorf("Must refuse an unknown command, got %d %q", code, stderr)
+	}
+
+	var stderr bytes.Buffer
+	if code := Main([]string{"migrate"}, &bytes.Buffer{}, &stderr); code != ExitUsage || !strings.Contains(stderr.String(), "Usage: darwin") {
+		t.Errorf("Must require a driver and a DSN, got %d %q", code, stderr.String())
+	}
+
+	if code, out, stderr := runCLI(t, dir, "info", "-bogus"); code != ExitUsage || out != "" || !strings.Contains(stderr, "-bogus") {
+		t.Errorf("Must report the flag error on stderr, got %d %q %q", code, out, stderr)
+	}
+
+	stderr.Reset()
+	if code := Main([]string{"-driver", "nosuch", "-dsn", "x", "-dir", dir, "status"}, &bytes.Buffer{}, &stderr); code == ExitOK || !strings.Contains(stderr.String(), `unknown driver "nosuch"`) {
+		t.Errorf("Must refuse a driver missing from the binary, got %d %q", code, stderr.String())
+	}
+
+	runCLI(t, dir, "migrate")
+	os.WriteFile(filepath.Join(dir, "1_posts.sql"), []byte("-- Version: 1\n-- Description: Posts\nCREATE TABLE articles (id int);\n"), 0644)
+
+	if code, _, stderr := runCLI(t, dir, "validate"); code != ExitInvalid || !strings.HasPrefix(stderr, "darwin: ") {
+		t.Errorf("Must report the invalid checksum, got %d %q", code, stderr)
+	}
+}
+
+func Test_Main_migrate_init(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"1_posts.sql": "-- Version: 1\n-- Description: Posts\nCREATE TABLE posts (id int);\n",
+	})
+
+	if code, _, stderr := runCLI(t, dir, "migrate", "-lock"); code != ExitError || !strings.Contains(stderr, "advisory lock") {
+		t.Errorf("Must require an advisory lock, got %d %q", code, stderr)
+	}
+
+	code, out, stderr := runCLI(t, dir, "-output", "json", "migrate", "-wait", "-timeout", "10s", "-detailed-exit-codes")
+	if code != ExitApplied || !strings.Contains(out, `"outcome": "applied"`) {
+		t.Errorf("Must report the applied migrations, got %d %q %q", code, out, stderr)
+	}
+
+	code, out, _ = runCLI(t, dir, "-output", "json", "migrate", "-wait", "-timeout", "10s", "-detailed-exit-codes")
+	if code != ExitOK || !strings.Contains(out, `"outcome": "nothing_to_do"`) {
+		t.Errorf("Must report there was nothing to do, got %d %q", code, out)
+	}
+}
+
+func Test_Main_info_slower_than(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"1_posts.sql": "-- Version: 1\n-- Description: Posts\nCREATE TABLE posts (id int);\n",
+		"2_body.sql":  "-- Version: 2\n-- Description: Body\nALTER TABLE posts ADD body string;\n",
+	})
+
+	if code, out, stderr := runCLI(t, dir, "migrate"); code != ExitOK {
+		t.Fatalf("Must apply the migrations, got %d %q %q", code, out, stderr)
+	}
+
+	if code, out, _ := runCLI(t, dir, "info", "-slower-than", "1ns"); code != ExitOK || strings.Count(out, "APPLIED  ") != 2 {
+		t.Errorf("Must list the slow migrations, got %d %q", code, out)
+	}
+
+	if code, out, _ := runCLI(t, dir, "info", "-slower-than", "1h"); code != ExitOK || strings.Contains(out, "APPLIED  ") {
+		t.Errorf("Must leave out the fast migrations, got %d %q", code, out)
+	}
+}
+
+// lockingDialect is the ql dialect with an advisory lock counting its uses.
+type lockingDialect struct {
+	darwin.QLDialect
+	locks *int
+}
+
+func (l lockingDialect) LockSQL() string {
+	*l.locks++
+	return "SELECT * FROM __Table;"
+}
+
+func (l lockingDialect) UnlockSQL() string {
+	return "SELECT * FROM __Table;"
+}
+
+func Test_Main_migrate_lock(t *testing.T) {
+	locks := 0
+	darwin.RegisterDialect("ql-locking", lockingDialect{locks: &locks})
+
+	dir := writeMigrations(t, map[string]string{
+		"1_posts.sql": "-- Version: 1\n-- Description: Posts\nCREATE TABLE posts (id int);\n",
+	})
+
+	if code, _, stderr := runCLI(t, dir, "-dialect", "ql-locking", "migrate"); code != ExitOK || locks != 0 {
+		t.Errorf("Must not lock without -lock, got %d %q after %d locks", code, stderr, locks)
+	}
+
+	if code, _, stderr := runCLI(t, dir, "-dialect", "ql-locking", "migrate", "-lock"); code != ExitOK || locks != 1 {
+		t.Errorf("Must lock with -lock, got %d %q after %d locks", code, stderr, locks)
+	}
+}
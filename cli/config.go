@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ConfigFiles are the configuration files Main reads, the first found in
+// the working directory, when neither -config nor DARWIN_CONFIG is set.
+var ConfigFiles = []string{"darwin.yaml", "darwin.yml", "darwin.toml"}
+
+// lookupEnv reads the DARWIN_* variables.
+var lookupEnv = os.LookupEnv
+
+// config is the configuration of Main.
+type config struct {
+	driver      string
+	dsn         string
+	dialect     string
+	dir         string
+	table       string
+	environment string
+	output      string
+}
+
+// settings returns the settings of the configuration by name, the name of
+// their flag, their key in the configuration file and, in upper case, the
+// suffix of their DARWIN_ variable.
+func (c *config) settings() map[string]*string {
+	return map[string]*string{
+		"driver":  &c.driver,
+		"dsn":     &c.dsn,
+		"dialect": &c.dialect,
+		"dir":     &c.dir,
+		"table":   &c.table,
+		"env":     &c.environment,
+		"output":  &c.output,
+	}
+}
+
+// configure completes the configuration set with the flags of fs: a setting
+// without a flag takes its DARWIN_ variable, else its value in the
+// configuration file, else its default.
+func (c *config) configure(fs *flag.FlagSet, file string) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	if file == "" {
+		file, _ = lookupEnv("DARWIN_CONFIG")
+	}
+
+	values := map[string]string{}
+	if file != "" || findConfig(&file) {
+		v, err := readConfig(file)
+		if err != nil {
+			return err
+		}
+		values = v
+	}
+
+	for name, value := range c.settings() {
+		if set[name] {
+			continue
+		}
+		if v, ok := lookupEnv("DARWIN_" + strings.ToUpper(name)); ok {
+			*value = v
+		} else if v, ok := values[name]; ok {
+			*value = v
+		}
+	}
+
+	return nil
+}
+
+// findConfig sets file to the first of the ConfigFiles found.
+func findConfig(file *string) bool {
+	for _, name := range ConfigFiles {
+		if _, err := os.Stat(name); err == nil {
+			*file = name
+			return true
+		}
+	}
+	return false
+}
+
+// readConfig reads a configuration file. Both formats hold settings, one
+// per line, with # comments:
+//
+//	# darwin.yaml
+//	driver: postgres
+//	dsn: "postgres://localhost/app"
+//
+//	# darwin.toml
+//	driver = "postgres"
+//	dsn = "postgres://localhost/app"
+//
+// Only flat settings with string values are supported.
+func readConfig(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	separator := ":"
+	if filepath.Ext(path) == ".toml" {
+		separator = "="
+	}
+
+	known := (&config{}).settings()
+	values := map[string]string{}
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(uncomment(scanner.Text()))
+		if line == "" || line == "---" {
+			continue
+		}
+
+		i := strings.Index(line, separator)
+		if i < 0 {
+			return nil, fmt.Errorf("%s:%d: expected key %s value", path, n, separator)
+		}
+
+		key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		if _, ok := known[key]; !ok {
+			return nil, fmt.Errorf("%s:%d: unknown setting %q", path, n, key)
+		}
+
+		v, err := unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, n, err)
+		}
+		values[key] = v
+	}
+
+	return values, scanner.Err()
+}
+
+// uncomment returns the line without its # comment, outside of quotes.
+func uncomment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquote returns the string value, quoted or not.
+func unquote(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		return strconv.Unquote(value)
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return "", errors.New("unterminated string")
+		}
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	case strings.ContainsAny(value, "[]{}"):
+		return "", fmt.Errorf("unsupported value %s", value)
+	}
+	return value, nil
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_readConfig(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"darwin.yaml": "# migrations\n---\ndriver: postgres\ndsn: \"postgres://localhost/app#1\" # the database\nenv: 'it''s'\n",
+		"darwin.toml": "driver = \"postgres\"\ndir = db/migrations\n",
+		"bad.yaml":    "drivers: postgres\n",
+		"list.yaml":   "dir: [a, b]\n",
+	})
+
+	values, err := readConfig(filepath.Join(dir, "darwin.yaml"))
+	expected := map[string]string{"driver": "postgres", "dsn": "postgres://localhost/app#1", "env": "it's"}
+	if err != nil || !reflect.DeepEqual(values, expected) {
+		t.Errorf("Must read the YAML settings, got %v %v", values, err)
+	}
+
+	values, err = readConfig(filepath.Join(dir, "darwin.toml"))
+	expected = map[string]string{"driver": "postgres", "dir": "db/migrations"}
+	if err != nil || !reflect.DeepEqual(values, expected) {
+		t.Errorf("Must read the TOML settings, got %v %v", values, err)
+	}
+
+	if _, err := readConfig(filepath.Join(dir, "bad.yaml")); err == nil || !strings.Contains(err.Error(), `unknown setting "drivers"`) {
+		t.Errorf("Must refuse unknown settings, got %v", err)
+	}
+
+	if _, err := readConfig(filepath.Join(dir, "list.yaml")); err == nil {
+		t.Error("Must refuse values which aren't strings")
+	}
+}
+
+func Test_Main_config(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"1_posts.sql": "-- Version: 1\n-- Description: Posts\nCREATE TABLE posts (id int);\n",
+	})
+	config := filepath.Join(dir, "darwin.toml")
+	os.WriteFile(config, []byte("driver = \"ql\"\ndsn = \"unused.db\"\noutput = \"json\"\n"), 0644)
+
+	vars := map[string]string{
+		"DARWIN_CONFIG": config,
+		"DARWIN_DSN":    filepath.Join(dir, "test.db"),
+		"DARWIN_OUTPUT": "quiet",
+	}
+	lookupEnv = func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	}
+	defer func() { lookupEnv = os.LookupEnv }()
+
+	var stdout, stderr bytes.Buffer
+	code := Main([]string{"-dir", dir, "-output", "table", "status"}, &stdout, &stderr)
+	if code != ExitOK || stdout.String() != "1 pending migrations: 1\n" {
+		t.Errorf("Must combine the flags, variables and file, got %d %q %q", code, stdout.String(), stderr.String())
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "test.db")); err != nil {
+		t.Errorf("Must prefer the variable to the file, got %s", err)
+	}
+
+	stdout.Reset()
+	if code := Main([]string{"-dir", dir, "status"}, &stdout, &stderr); code != ExitOK || stdout.String() != "" {
+		t.Errorf("Must prefer the variable to the file, got %d %q", code, stdout.String())
+	}
+}
+
+func Test_Main_table(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"1_posts.sql": "-- Version: 1\n-- Description: Posts\nCREATE TABLE posts (id int);\n",
+	})
+
+	if code, out, stderr := runCLI(t, dir, "-table", "schema_migrations", "migrate"); code != ExitOK {
+		t.Fatalf("Must apply the migrations, got %d %q %q", code, out, stderr)
+	}
+
+	if code, out, _ := runCLI(t, dir, "-table", "schema_migrations", "status"); code != ExitOK || out != "Up to date at version 1\n" {
+		t.Errorf("Must read the history of the table, got %d %q", code, out)
+	}
+
+	if code, out, _ := runCLI(t, dir, "status"); code != ExitOK || out != "1 pending migrations: 1\n" {
+		t.Errorf("Must keep the default history apart, got %d %q", code, out)
+	}
+}
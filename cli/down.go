@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dustinevan/darwin"
+)
+
+// downJSON is the JSON output of down.
+type downJSON struct {
+	Reverted []resultJSON `json:"reverted"`
+	Error    string       `json:"error,omitempty"`
+}
+
+// dryRunJSON is the JSON output of down -dry-run.
+type dryRunJSON struct {
+	Planned []scriptJSON `json:"planned"`
+}
+
+// scriptJSON is the JSON document of a down migration.
+type scriptJSON struct {
+	Version     float64 `json:"version"`
+	Description string  `json:"description"`
+	Script      string  `json:"script"`
+}
+
+// production reports whether the environment is a production one, where
+// destructive commands must be confirmed.
+func production(environment string) bool {
+	switch strings.ToLower(environment) {
+	case "production", "prod":
+		return true
+	}
+	return false
+}
+
+// down reverts the latest migration, the latest steps ones or those newer
+// than a version with the scripts of their .down.sql files.
+func down(e *env, args []string) error {
+	fs := flag.NewFlagSet("down", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	steps := fs.Int("steps", 0, "number of migrations to revert, 1 by default")
+	to := fs.String("to", "", "revert the migrations newer than this version")
+	dryRun := fs.Bool("dry-run", false, "print the down scripts without executing them")
+	yes := fs.Bool("yes", false, "confirm reverting migrations in production")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return errUsage
+	}
+
+	if *steps < 0 || (*steps > 0 && *to != "") {
+		return errUsage
+	}
+
+	if err := e.create(); err != nil {
+		return err
+	}
+
+	target := 0.0
+	if *to != "" {
+		v, err := strconv.ParseFloat(*to, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", *to)
+		}
+		target = v
+	} else {
+		if *steps == 0 {
+			*steps = 1
+		}
+		v, err := e.darwin.DownTarget(*steps)
+		if err != nil {
+			return err
+		}
+		target = v
+	}
+
+	downs, err := darwin.NewFSSource(os.DirFS(e.dir), ".").DownMigrations()
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		planned, err := e.darwin.PlanDown(downs, target)
+		if err != nil {
+			return err
+		}
+
+		if e.output == OutputJSON {
+			doc := dryRunJSON{Planned: []scriptJSON{}}
+			for _, m := range planned {
+				doc.Planned = append(doc.Planned, scriptJSON{Version: m.Version, Description: m.Description, Script: m.Script})
+			}
+			return e.json(doc)
+		}
+
+		for _, m := range planned {
+			e.printf("-- Reverts version %s: %s\n%s\n", version(m.Version), m.Description, strings.TrimRight(m.Script, "\n"))
+		}
+		if len(planned) == 0 {
+			e.printf("Nothing to revert\n")
+		}
+		return nil
+	}
+
+	if production(e.environment) && !*yes {
+		return errors.New("reverting migrations in production requires -yes")
+	}
+
+	report, err := e.darwin.Down(context.Background(), downs, target)
+
+	if e.output == OutputJSON {
+		if jerr := e.json(downJSON{Reverted: newResultsJSON(report), Error: errorString(err)}); err == nil {
+			err = jerr
+		}
+		return err
+	}
+
+	for _, r := range report.Results {
+		e.printf("Reverted %s %s (%s)\n", version(r.Migration.Version), r.Migration.Description, r.Duration)
+	}
+
+	if err == nil && len(report.Results) == 0 {
+		e.printf("Nothing to revert\n")
+	}
+
+	return err
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Main_down(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"1_posts.sql":      "-- Version: 1\n-- Description: Posts\nCREATE TABLE posts (id int);\n",
+		"1_posts.down.sql": "DROP TABLE posts;\n",
+		"2_tags.sql":       "-- Version: 2\n-- Description: Tags\nCREATE TABLE tags (id int);\n",
+		"2_tags.down.sql":  "DROP TABLE tags;\n",
+	})
+
+	if code, out, stderr := runCLI(t, dir, "migrate"); code != ExitOK {
+		t.Fatalf("Must apply the migrations, got %d %q %q", code, out, stderr)
+	}
+
+	if code, out, _ := runCLI(t, dir, "down", "-dry-run", "-to", "0"); code != ExitOK || out != "-- Reverts version 2: Tags\nDROP TABLE tags;\n-- Reverts version 1: Posts\nDROP TABLE posts;\n" {
+		t.Errorf("Must print the down scripts, got %d %q", code, out)
+	}
+
+	if code, _, stderr := runCLI(t, dir, "-env", "production", "down"); code != ExitError || !strings.Contains(stderr, "-yes") {
+		t.Errorf("Must require a confirmation in production, got %d %q", code, stderr)
+	}
+
+	if code, out, stderr := runCLI(t, dir, "-env", "production", "down", "-yes"); code != ExitOK || !strings.HasPrefix(out, "Reverted 2 Tags") {
+		t.Errorf("Must revert the latest migration, got %d %q %q", code, out, stderr)
+	}
+
+	if code, out, _ := runCLI(t, dir, "status"); code != ExitOK || out != "1 pending migrations: 2\n" {
+		t.Errorf("Must report the reverted migration pending, got %d %q", code, out)
+	}
+
+	if code, _, _ := runCLI(t, dir, "down", "-steps", "1", "-to", "0"); code != ExitUsage {
+		t.Errorf("Must refuse -steps with -to, got %d", code)
+	}
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/dustinevan/darwin"
+)
+
+// stdin is read by migrate -interactive.
+var stdin io.Reader = os.Stdin
+
+// errAborted reports a run quit at a prompt.
+var errAborted = errors.New("migrate aborted")
+
+// confirmation returns the confirmation hook of migrate -interactive, which
+// shows each pending migration and asks whether to apply it: y applies it,
+// n stops, leaving it and the following ones pending, and q aborts.
+func (e *env) confirmation() darwin.ConfirmFunc {
+	in := bufio.NewReader(stdin)
+
+	syntax := darwin.StandardSyntax
+	if s, ok := e.driver.Dialect.(darwin.SyntaxDialect); ok {
+		syntax = s.Syntax()
+	}
+
+	return func(ctx context.Context, m darwin.Migration) (bool, error) {
+		statements := len(darwin.SplitStatements(m.Script, syntax))
+		fmt.Fprintf(e.stdout, "Migration %s %s: %d statements, %s\n", version(m.Version), m.Description, statements, size(estimateSize(m)))
+
+		for {
+			fmt.Fprint(e.stdout, "Apply? [y/n/q] ")
+
+			line, err := in.ReadString('\n')
+			switch strings.ToLower(strings.TrimSpace(line)) {
+			case "y", "yes":
+				return true, nil
+			case "n", "no":
+				return false, nil
+			case "q", "quit":
+				return false, errAborted
+			}
+
+			if err != nil {
+				fmt.Fprintln(e.stdout)
+				return false, errAborted
+			}
+		}
+	}
+}
+
+// estimateSize returns the size of the script of the migration and of its
+// data files.
+func estimateSize(m darwin.Migration) int64 {
+	total := int64(len(m.Script))
+
+	attachments, _ := m.Attachments()
+	for _, a := range attachments {
+		if m.Files == nil {
+			break
+		}
+		if info, err := fs.Stat(m.Files, a.File); err == nil {
+			total += info.Size()
+		}
+	}
+
+	return total
+}
+
+// size formats a number of bytes.
+func size(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
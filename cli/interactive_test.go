@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_Main_migrate_interactive(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"1_posts.sql": "-- Version: 1\n-- Description: Posts\nCREATE TABLE posts (id int);\nCREATE INDEX posts_id ON posts (id);\n",
+		"2_tags.sql":  "-- Version: 2\n-- Description: Tags\nCREATE TABLE tags (id int);\n",
+	})
+
+	defer func() { stdin = os.Stdin }()
+	stdin = strings.NewReader("maybe\ny\nn\n")
+	code, out, stderr := runCLI(t, dir, "migrate", "-interactive")
+	if code != ExitOK {
+		t.Fatalf("Must not fail, got %d %q", code, stderr)
+	}
+
+	if !strings.HasPrefix(out, "Migration 1 Posts: 2 statements, 66 B\nApply? [y/n/q] Apply? [y/n/q] ") {
+		t.Errorf("Must show the migration until answered, got %q", out)
+	}
+
+	if !strings.Contains(out, "Applied 1 Posts") || strings.Contains(out, "Applied 2") {
+		t.Errorf("Must stop at the declined migration, got %q", out)
+	}
+
+	stdin = strings.NewReader("q\n")
+	if code, _, stderr := runCLI(t, dir, "migrate", "-interactive"); code != ExitError || !strings.Contains(stderr, "aborted") {
+		t.Errorf("Must abort, got %d %q", code, stderr)
+	}
+
+	if size(1536) != "1.5 KB" {
+		t.Errorf("Must format the size, got %q", size(1536))
+	}
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dustinevan/darwin"
+)
+
+// findingJSON is the JSON document of a finding of lint.
+type findingJSON struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// lint checks the migration files with the default rules, but the disabled
+// ones, and fails with ExitInvalid when there are findings.
+func lint(e *env, args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	disable := fs.String("disable", "", "comma separated rules not to check")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return errUsage
+	}
+
+	disabled := map[string]bool{}
+	for _, name := range strings.Split(*disable, ",") {
+		disabled[strings.TrimSpace(name)] = true
+	}
+
+	var rules []darwin.LintRule
+	for _, rule := range darwin.DefaultLintRules {
+		if !disabled[rule.Name] {
+			rules = append(rules, rule)
+		}
+	}
+
+	findings, err := darwin.NewFSSource(os.DirFS(e.dir), ".").Lint(rules...)
+	if err != nil {
+		return err
+	}
+
+	doc := []findingJSON{}
+	for _, f := range findings {
+		f.File = filepath.Join(e.dir, f.File)
+		doc = append(doc, findingJSON{File: f.File, Line: f.Line, Rule: f.Rule, Message: f.Message})
+		e.printf("%s\n", f)
+	}
+
+	if e.output == OutputJSON {
+		if err := e.json(doc); err != nil {
+			return err
+		}
+	}
+
+	if len(findings) > 0 {
+		return lintError{len(findings)}
+	}
+	return nil
+}
+
+// lintError reports the findings of lint.
+type lintError struct {
+	findings int
+}
+
+func (l lintError) Error() string {
+	return fmt.Sprintf("%d findings", l.findings)
+}
@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Main_lint(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"1_posts.sql": "-- Version: 1\n-- Description: Posts\nCREATE TABLE posts (id int);\nDROP TABLE articles;\n",
+		"2_tags.sql":  "-- Version: 2\nCREATE TABLE tags (id int);\n",
+	})
+
+	var stdout, stderr bytes.Buffer
+	code := Main([]string{"-dir", dir, "lint"}, &stdout, &stderr)
+
+	expected := filepath.Join(dir, "1_posts.sql") + ":4: destructive: DROP TABLE loses data\n" +
+		filepath.Join(dir, "2_tags.sql") + ":1: description: the migration has no description\n"
+	if code != ExitInvalid || stdout.String() != expected || !strings.Contains(stderr.String(), "2 findings") {
+		t.Errorf("Must report the findings, got %d %q %q", code, stdout.String(), stderr.String())
+	}
+
+	stdout.Reset()
+	code = Main([]string{"-dir", dir, "-output", "json", "lint", "-disable", "destructive,description"}, &stdout, &stderr)
+	if code != ExitOK || stdout.String() != "[]\n" {
+		t.Errorf("Must skip the disabled rules, got %d %q", code, stdout.String())
+	}
+}
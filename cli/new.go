@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Version schemes of the new command.
+const (
+	SchemeNumeric   = "numeric"
+	SchemeTimestamp = "timestamp"
+)
+
+// now is the clock of the timestamp scheme.
+var now = time.Now
+
+// newJSON is the JSON output of new.
+type newJSON struct {
+	Version float64  `json:"version"`
+	Files   []string `json:"files"`
+}
+
+// newMigration creates the file of a new migration, with the next version
+// of the scheme, and its down file if asked.
+func newMigration(e *env, args []string) error {
+	fs := flag.NewFlagSet("new", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	scheme := fs.String("scheme", SchemeNumeric, "version scheme, numeric or timestamp")
+	down := fs.Bool("down", false, "create the down file too")
+
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	description := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if description == "" {
+		return errUsage
+	}
+
+	v, err := nextVersion(e.migrations, *scheme)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range e.migrations {
+		if m.Version == v {
+			return fmt.Errorf("version %s is already used by %q", version(v), m.Description)
+		}
+	}
+
+	name := fileVersion(v, *scheme) + "_" + slug(description)
+
+	doc := newJSON{Version: v}
+
+	path := filepath.Join(e.dir, name+".sql")
+	header := fmt.Sprintf("-- Version: %s\n-- Description: %s\n", version(v), description)
+	if err := create(path, header); err != nil {
+		return err
+	}
+	doc.Files = append(doc.Files, path)
+	e.printf("Created %s\n", path)
+
+	if *down {
+		path := filepath.Join(e.dir, name+darwin.DownSuffix)
+		if err := create(path, fmt.Sprintf("-- Reverts version %s: %s\n", version(v), description)); err != nil {
+			return err
+		}
+		doc.Files = append(doc.Files, path)
+		e.printf("Created %s\n", path)
+	}
+
+	if e.output == OutputJSON {
+		return e.json(doc)
+	}
+	return nil
+}
+
+// nextVersion returns the version of a new migration: the integer following
+// the latest version, or the current time as YYYYMMDDhhmmss.
+func nextVersion(migrations []darwin.Migration, scheme string) (float64, error) {
+	switch scheme {
+	case SchemeNumeric:
+		latest := 0.0
+		for _, m := range migrations {
+			latest = math.Max(latest, m.Version)
+		}
+		return math.Floor(latest) + 1, nil
+
+	case SchemeTimestamp:
+		return strconv.ParseFloat(now().UTC().Format("20060102150405"), 64)
+	}
+
+	return 0, fmt.Errorf("unknown version scheme %q", scheme)
+}
+
+// fileVersion returns the version in a file name, padded so the files of
+// numeric versions sort in order.
+func fileVersion(v float64, scheme string) string {
+	if scheme == SchemeNumeric {
+		return fmt.Sprintf("%04d", int64(v))
+	}
+	return version(v)
+}
+
+// slug returns the description in lower case, words joined with
+// underscores.
+func slug(description string) string {
+	words := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "_")
+}
+
+// create creates the file with the content, failing if it exists.
+func create(path, content string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
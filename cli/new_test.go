@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Main_new(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"0001_posts.sql": "-- Version: 1\n-- Description: Posts\nCREATE TABLE posts (id int);\n-- Version: 2.1\n-- Description: Body\nALTER TABLE posts ADD body string;\n",
+	})
+
+	code, out, stderr := runCLI(t, dir, "new", "-down", "Add users table!")
+	if code != ExitOK || !strings.Contains(out, "0003_add_users_table.sql") {
+		t.Fatalf("Must create the migration, got %d %q %q", code, out, stderr)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "0003_add_users_table.sql"))
+	if err != nil || string(content) != "-- Version: 3\n-- Description: Add users table!\n" {
+		t.Errorf("Must write the header, got %q %v", content, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "0003_add_users_table.down.sql")); err != nil {
+		t.Errorf("Must create the down file, got %s", err)
+	}
+
+	if code, _, _ := runCLI(t, dir, "migrate"); code != ExitOK {
+		t.Errorf("Must skip the down file, got %d", code)
+	}
+}
+
+func Test_Main_new_timestamp(t *testing.T) {
+	defer func(clock func() time.Time) { now = clock }(now)
+	now = func() time.Time { return time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC) }
+
+	dir := writeMigrations(t, nil)
+
+	if code, _, stderr := runCLI(t, dir, "new", "-scheme", "timestamp", "users"); code != ExitOK {
+		t.Fatalf("Must create the migration, got %d %q", code, stderr)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "20210304050607_users.sql")); err != nil {
+		t.Errorf("Must name the file after the timestamp, got %s", err)
+	}
+
+	if code, _, stderr := runCLI(t, dir, "new", "-scheme", "timestamp", "roles"); code != ExitError || !strings.Contains(stderr, "already used") {
+		t.Errorf("Must refuse a version collision, got %d %q", code, stderr)
+	}
+
+	if code, _, _ := runCLI(t, dir, "new"); code != ExitUsage {
+		t.Errorf("Must require a description, got %d", code)
+	}
+}
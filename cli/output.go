@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Output formats of the -output flag. The table format is meant for people,
+// the json one writes a single document per command, and quiet writes
+// nothing but errors, the exit code telling the outcome.
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputQuiet = "quiet"
+)
+
+func validOutput(output string) bool {
+	switch output {
+	case OutputTable, OutputJSON, OutputQuiet:
+		return true
+	}
+	return false
+}
+
+// resultJSON is the JSON document of a migration applied or reverted.
+type resultJSON struct {
+	Version      float64 `json:"version"`
+	Description  string  `json:"description"`
+	DurationMS   int64   `json:"duration_ms"`
+	RowsAffected int64   `json:"rows_affected"`
+}
+
+func newResultsJSON(report darwin.Report) []resultJSON {
+	results := []resultJSON{}
+	for _, r := range report.Results {
+		results = append(results, resultJSON{
+			Version:      r.Migration.Version,
+			Description:  r.Migration.Description,
+			DurationMS:   r.Duration.Milliseconds(),
+			RowsAffected: r.RowsAffected(),
+		})
+	}
+	return results
+}
+
+// errorString returns the message of err, empty when it is nil.
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// printf writes the text of the table output.
+func (e *env) printf(format string, args ...interface{}) {
+	if e.output == OutputTable {
+		fmt.Fprintf(e.stdout, format, args...)
+	}
+}
+
+// json writes the document of the json output.
+func (e *env) json(doc interface{}) error {
+	enc := json.NewEncoder(e.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_Main_output(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"1_posts.sql": "-- Version: 1\n-- Description: Posts\nCREATE TABLE posts (id int);\n",
+	})
+
+	if code, out, _ := runCLI(t, dir, "-output", "quiet", "status", "-check"); code != ExitPending || out != "" {
+		t.Errorf("Must exit with ExitPending without output, got %d %q", code, out)
+	}
+
+	code, out, _ := runCLI(t, dir, "-output", "json", "status")
+	if code != ExitOK || out != "{\n  \"up_to_date\": false,\n  \"pending\": [\n    1\n  ],\n  \"latest_version\": null\n}\n" {
+		t.Errorf("Must write the status as JSON, got %d %q", code, out)
+	}
+
+	code, out, _ = runCLI(t, dir, "-output", "json", "migrate")
+	var applied migrateJSON
+	if err := json.Unmarshal([]byte(out), &applied); err != nil || code != ExitOK || len(applied.Applied) != 1 || applied.Applied[0].Description != "Posts" {
+		t.Errorf("Must write the applied migrations as JSON, got %d %q", code, out)
+	}
+
+	if code, out, _ := runCLI(t, dir, "-output", "json", "status", "-check"); code != ExitOK || out != "{\n  \"up_to_date\": true,\n  \"pending\": [],\n  \"latest_version\": 1\n}\n" {
+		t.Errorf("Must report the database up to date, got %d %q", code, out)
+	}
+
+	if code, out, _ := runCLI(t, dir, "-output", "json", "validate"); code != ExitOK || out != "{\n  \"valid\": true\n}\n" {
+		t.Errorf("Must write the validation as JSON, got %d %q", code, out)
+	}
+
+	if code, _, _ := runCLI(t, dir, "-output", "yaml", "status"); code != ExitUsage {
+		t.Errorf("Must refuse an unknown output, got %d", code)
+	}
+}
@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dustinevan/darwin"
+)
+
+// squashJSON is the JSON output of squash.
+type squashJSON struct {
+	Baseline string   `json:"baseline,omitempty"`
+	Archived []string `json:"archived"`
+}
+
+// squash replaces the migrations up to a version with their baseline: it
+// writes the file of the baseline and moves the files of the squashed
+// migrations, with their down files, to the archive directory, then
+// squashes the history of the database. The files come first, so a failure
+// leaves the history of the original migrations, and squash run again with
+// the baseline file squashes it. Once the files are squashed, it squashes
+// the history of the other databases.
+func squash(e *env, args []string) error {
+	fs := flag.NewFlagSet("squash", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	through := fs.String("through", "", "version of the latest migration squashed")
+	archive := fs.String("archive", "", "directory of the squashed files, archive in the migrations directory by default")
+
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 || *through == "" {
+		return errUsage
+	}
+
+	v, err := strconv.ParseFloat(*through, 64)
+	if err != nil {
+		return fmt.Errorf("invalid version %q", *through)
+	}
+
+	if *archive == "" {
+		*archive = filepath.Join(e.dir, "archive")
+	}
+
+	files, err := squashedFiles(e.dir, v)
+	if err != nil {
+		return err
+	}
+
+	baseline, err := darwin.Squash(e.migrations, v)
+	if err != nil {
+		return err
+	}
+
+	if err := e.create(); err != nil {
+		return err
+	}
+
+	squashed := 0
+	for _, m := range e.migrations {
+		if m.Version <= v {
+			squashed++
+		}
+	}
+
+	doc := squashJSON{Archived: []string{}}
+
+	if squashed > 1 {
+		if err := e.darwin.Validate(); err != nil {
+			return err
+		}
+
+		doc.Baseline = filepath.Join(e.dir, baselineName(v))
+		header := fmt.Sprintf("-- Version: %s\n-- Description: %s\n", version(v), baseline.Description)
+		if err := create(doc.Baseline, header+baseline.Script); err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(*archive, 0755); err != nil {
+			return err
+		}
+
+		for _, name := range files {
+			for _, file := range []string{name, strings.TrimSuffix(name, ".sql") + darwin.DownSuffix} {
+				err := os.Rename(filepath.Join(e.dir, file), filepath.Join(*archive, file))
+				switch {
+				case os.IsNotExist(err):
+				case err != nil:
+					return err
+				default:
+					doc.Archived = append(doc.Archived, filepath.Join(*archive, file))
+				}
+			}
+		}
+	}
+
+	if _, err := e.darwin.Squash(context.Background(), v); err != nil {
+		return err
+	}
+
+	if e.output == OutputJSON {
+		return e.json(doc)
+	}
+
+	if doc.Baseline != "" {
+		e.printf("Created %s\n", doc.Baseline)
+	}
+	for _, file := range doc.Archived {
+		e.printf("Archived %s\n", file)
+	}
+	e.printf("History squashed through version %s\n", version(v))
+	return nil
+}
+
+// squashedFiles returns the names of the .sql files of dir holding the
+// migrations up to the version through.
+func squashedFiles(dir string, through float64) ([]string, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	var files []string
+	for _, path := range names {
+		name := filepath.Base(path)
+		if strings.HasSuffix(name, darwin.DownSuffix) {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		before, after := 0, 0
+		for _, m := range darwin.ParseMigrations(string(content)) {
+			if m.Version <= through {
+				before++
+			} else {
+				after++
+			}
+		}
+
+		switch {
+		case before > 0 && after > 0:
+			return nil, fmt.Errorf("%s holds migrations on both sides of version %s", name, version(through))
+		case before > 0:
+			files = append(files, name)
+		}
+	}
+
+	return files, nil
+}
+
+// baselineName returns the name of the file of the baseline of version v.
+func baselineName(v float64) string {
+	if v == math.Trunc(v) {
+		return fileVersion(v, SchemeNumeric) + "_baseline.sql"
+	}
+	return version(v) + "_baseline.sql"
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Main_squash(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"0001_posts.sql":      "-- Version: 1\n-- Description: Posts\nCREATE TABLE posts (id int);\n",
+		"0001_posts.down.sql": "DROP TABLE posts;\n",
+		"0002_tags.sql":       "-- Version: 2\n-- Description: Tags\nCREATE TABLE tags (id int);\n",
+		"0003_users.sql":      "-- Version: 3\n-- Description: Users\nCREATE TABLE users (id int);\n",
+	})
+
+	if code, out, stderr := runCLI(t, dir, "migrate"); code != ExitOK {
+		t.Fatalf("Must apply the migrations, got %d %q %q", code, out, stderr)
+	}
+
+	code, out, errs := runCLI(t, dir, "squash", "-through", "2")
+	if code != ExitOK || !strings.HasSuffix(out, "History squashed through version 2\n") {
+		t.Fatalf("Must squash the migrations, got %d %q %q", code, out, errs)
+	}
+
+	for _, name := range []string{"0001_posts.sql", "0001_posts.down.sql", "0002_tags.sql"} {
+		if _, err := os.Stat(filepath.Join(dir, "archive", name)); err != nil {
+			t.Errorf("Must archive %s, got %s", name, err)
+		}
+	}
+
+	if code, out, stderr := runCLI(t, dir, "validate"); code != ExitOK {
+		t.Errorf("Must leave a valid history, got %d %q %q", code, out, stderr)
+	}
+
+	if code, out, _ := runCLI(t, dir, "info"); code != ExitOK || !strings.Contains(out, "Baseline through version 2") || strings.Count(out, "APPLIED  ") != 2 {
+		t.Errorf("Must list the baseline applied, got %d %q", code, out)
+	}
+
+	if code, out, stderr := runCLI(t, dir, "squash", "-through", "2"); code != ExitOK || strings.Contains(out, "Archived") {
+		t.Errorf("Must only squash the history once the files are squashed, got %d %q %q", code, out, stderr)
+	}
+
+	other := t.TempDir()
+	flags := []string{"-driver", "ql", "-dsn", filepath.Join(other, "test.db"), "-dir", dir}
+	var stdout, stderr bytes.Buffer
+	if code := Main(append(flags, "migrate"), &stdout, &stderr); code != ExitOK || !strings.Contains(stdout.String(), "Applied 2 Baseline") {
+		t.Errorf("Must bootstrap a new database from the baseline, got %d %q %q", code, stdout.String(), stderr.String())
+	}
+}
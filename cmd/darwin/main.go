@@ -0,0 +1,31 @@
+// Command darwin runs the migrations of a directory of .sql files against a
+// database, see the cli package.
+//
+// This binary only links the ql database driver, so -driver ql is the only
+// driver it accepts. To run against another database, build your own binary
+// with the database/sql driver blank imported:
+//
+//	package main
+//
+//	import (
+//		"os"
+//
+//		_ "github.com/go-sql-driver/mysql"
+//		"github.com/dustinevan/darwin/cli"
+//	)
+//
+//	func main() {
+//		os.Exit(cli.Main(os.Args[1:], os.Stdout, os.Stderr))
+//	}
+package main
+
+import (
+	"os"
+
+	_ "github.com/cznic/ql/driver"
+	"github.com/dustinevan/darwin/cli"
+)
+
+func main() {
+	os.Exit(cli.Main(os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -0,0 +1,121 @@
+package darwin
+
+import (
+	"context"
+	"fmt"
+)
+
+// Component is a named set of migrations, like those of a service of a
+// monorepo, kept in its own history table of a database shared with other
+// components.
+type Component struct {
+	Name       string
+	Migrations []Migration
+
+	// Table is the history table of the component, darwin_migrations_ and
+	// its name by default.
+	Table string
+}
+
+// table returns the history table of the component.
+func (c Component) table() string {
+	if c.Table == "" {
+		return defaultTable + "_" + c.Name
+	}
+	return c.Table
+}
+
+// Components applies the migrations of the components sharing a database
+// in their declared order, each with its own history table, so the
+// migrations of a component can rely on those of the components before it:
+//
+//	components := darwin.Components{
+//		Components: []darwin.Component{
+//			{Name: "accounts", Migrations: accounts},
+//			{Name: "billing", Migrations: billing},
+//		},
+//		Driver: func() (darwin.Driver, error) {
+//			return darwin.NewGenericDriver(db, darwin.PostgresDialect{})
+//		},
+//	}
+//	report := components.MigrateAll(ctx)
+type Components struct {
+	Components []Component
+
+	// Driver returns a new driver of the database for each component, a
+	// TableNamer like the generic driver.
+	Driver func() (Driver, error)
+
+	// Options configure the Darwin migrating each component.
+	Options []Option
+}
+
+// MigrateAll executes the missing migrations of every component, in order.
+// A component failing stops the migration of the components after it,
+// which are reported with their pending migrations; the errors are in the
+// report.
+func (c Components) MigrateAll(ctx context.Context) FleetReport {
+	var failed string
+	return c.each(ctx, func(component Component, d Darwin) error {
+		if failed != "" {
+			return fmt.Errorf("darwin: not migrated, component %s failed", failed)
+		}
+		if err := d.MigrateContext(ctx); err != nil {
+			failed = component.Name
+			return err
+		}
+		return nil
+	})
+}
+
+// Info returns the state of every component without migrating them.
+func (c Components) Info(ctx context.Context) FleetReport {
+	return c.each(ctx, func(Component, Darwin) error {
+		return nil
+	})
+}
+
+// each runs do on every component in order, then reads its state.
+func (c Components) each(ctx context.Context, do func(Component, Darwin) error) FleetReport {
+	report := FleetReport{Results: make([]TargetResult, 0, len(c.Components))}
+
+	for _, component := range c.Components {
+		result := TargetResult{Name: component.Name, Pending: len(component.Migrations)}
+
+		d, err := c.darwin(component)
+		if err != nil {
+			result.Err = err
+			report.Results = append(report.Results, result)
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			result.Err = err
+		} else {
+			result.Err = do(component, d)
+		}
+
+		report.Results = append(report.Results, summarize(d, result))
+	}
+
+	return report
+}
+
+// darwin returns the Darwin of the component, with a driver keeping its
+// history table.
+func (c Components) darwin(component Component) (Darwin, error) {
+	driver, err := c.Driver()
+	if err != nil {
+		return Darwin{}, err
+	}
+
+	namer, ok := driver.(TableNamer)
+	if !ok {
+		return Darwin{}, fmt.Errorf("darwin: the driver of component %s can't change the name of the history table", component.Name)
+	}
+	if err := namer.SetTableName(component.table()); err != nil {
+		return Darwin{}, err
+	}
+
+	return New(driver, component.Migrations, c.Options...), nil
+}
@@ -0,0 +1,60 @@
+package darwin
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func Test_Components_MigrateAll(t *testing.T) {
+	db, err := sql.Open("ql-mem", "components.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	components := Components{
+		Components: []Component{
+			{Name: "accounts", Migrations: []Migration{
+				{Version: 1, Description: "Accounts", Script: "CREATE TABLE accounts (id int);"},
+			}},
+			{Name: "billing", Table: "billing_history", Migrations: []Migration{
+				{Version: 1, Description: "Invoices", Script: "CREATE TABLE invoices (account int);"},
+				{Version: 2, Description: "Broken", Script: "CREATE TABLE accounts (id int);"},
+			}},
+			{Name: "reports", Migrations: []Migration{
+				{Version: 1, Description: "Reports", Script: "CREATE TABLE reports (id int);"},
+			}},
+		},
+		Driver: func() (Driver, error) {
+			return NewGenericDriver(db, QLDialect{})
+		},
+	}
+
+	report := components.MigrateAll(context.Background())
+
+	if len(report.Results) != 3 {
+		t.Fatalf("Must report every component, got %#v", report.Results)
+	}
+
+	if a := report.Results[0]; a.Name != "accounts" || a.Err != nil || a.Version != 1 || a.Pending != 0 {
+		t.Errorf("Must migrate accounts, got %#v", a)
+	}
+
+	if b := report.Results[1]; b.Err == nil || b.Version != 1 || b.Pending != 1 {
+		t.Errorf("Must report the failure of billing, got %#v", b)
+	}
+
+	if r := report.Results[2]; r.Err == nil || r.Pending != 1 || hasTable(db, "reports", t) {
+		t.Errorf("Must not migrate the components after a failure, got %#v", r)
+	}
+
+	if !hasTable(db, "darwin_migrations_accounts", t) || !hasTable(db, "billing_history", t) || hasTable(db, "darwin_migrations", t) {
+		t.Errorf("Must keep the history of each component in its table")
+	}
+
+	info := components.Info(context.Background())
+	if info.Results[0].Err != nil || info.Results[1].Pending != 1 {
+		t.Errorf("Must report the state of the components, got %#v", info.Results)
+	}
+}
@@ -0,0 +1,127 @@
+package darwin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// nonTransactionalStatement matches the statements Postgres refuses to run
+// in a transaction.
+var nonTransactionalStatement = regexp.MustCompile(`(?is)^(CREATE\s+(UNIQUE\s+)?INDEX\s+CONCURRENTLY|DROP\s+INDEX\s+CONCURRENTLY|REINDEX\s.*\bCONCURRENTLY\b|VACUUM|CREATE\s+DATABASE|DROP\s+DATABASE|ALTER\s+SYSTEM|CREATE\s+TABLESPACE|DROP\s+TABLESPACE)\b`)
+
+// concurrentIndex matches a CREATE INDEX CONCURRENTLY statement, capturing
+// the name of the index and of its table.
+var concurrentIndex = regexp.MustCompile(`(?is)^CREATE\s+(?:UNIQUE\s+)?INDEX\s+CONCURRENTLY\s+(?:IF\s+NOT\s+EXISTS\s+)?("[^"]+"|\w+)\s+ON\s+(?:ONLY\s+)?((?:(?:"[^"]+"|\w+)\.)?(?:"[^"]+"|\w+))`)
+
+// indexPoll is the wait between two checks of the validity of an index.
+var indexPoll = time.Second
+
+// InvalidIndexError is used to report when an index created concurrently
+// is left invalid. It must be dropped before the migration runs again.
+type InvalidIndexError struct {
+	Index string
+}
+
+func (i InvalidIndexError) Error() string {
+	return fmt.Sprintf("darwin: index %s is invalid, drop it before running the migration again", i.Index)
+}
+
+// IndexDialect is implemented by the dialects of databases building indexes
+// concurrently, like Postgres. IndexValidSQL returns the SQL telling if the
+// index named by its single parameter is valid, without rows when it
+// doesn't exist.
+type IndexDialect interface {
+	IndexValidSQL() string
+}
+
+// nonTransactional reports whether the script has a statement which can't
+// run in a transaction, like CREATE INDEX CONCURRENTLY or VACUUM.
+func nonTransactional(script string) bool {
+	for _, stmt := range SplitStatements(script, PostgresSyntax) {
+		if nonTransactionalStatement.MatchString(stripComments(stmt)) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripComments returns the statement without its leading comments.
+func stripComments(stmt string) string {
+	for {
+		stmt = strings.TrimSpace(stmt)
+		switch {
+		case strings.HasPrefix(stmt, "--"):
+			stmt = stmt[skipTo(stmt, 0, "\n"):]
+		case strings.HasPrefix(stmt, "/*"):
+			stmt = stmt[skipComment(stmt, 0, true):]
+		default:
+			return stmt
+		}
+	}
+}
+
+// waitForIndex waits until the index created by the statement, if it is a
+// CREATE INDEX CONCURRENTLY, is valid.
+func (m *GenericDriver) waitForIndex(ctx context.Context, e execer, stmt string) error {
+	d, ok := m.Dialect.(IndexDialect)
+	if !ok {
+		return nil
+	}
+
+	match := concurrentIndex.FindStringSubmatch(stripComments(stmt))
+	if match == nil {
+		return nil
+	}
+
+	// The index is in the schema of its table.
+	index := match[1]
+	if i := strings.LastIndex(match[2], "."); i >= 0 {
+		index = match[2][:i+1] + index
+	}
+
+	timeout := m.IndexTimeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		valid, found, err := indexValid(ctx, e, d, index)
+		if err != nil || !found || valid {
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			return InvalidIndexError{Index: index}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(indexPoll):
+		}
+	}
+}
+
+// indexValid reports whether the index is valid, and if it exists.
+func indexValid(ctx context.Context, e execer, d IndexDialect, index string) (bool, bool, error) {
+	rows, err := e.QueryContext(ctx, d.IndexValidSQL(), index)
+	if err != nil {
+		return false, false, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return false, false, rows.Err()
+	}
+
+	var valid bool
+	if err := rows.Scan(&valid); err != nil {
+		return false, false, err
+	}
+
+	return valid, true, rows.Err()
+}
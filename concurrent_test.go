@@ -0,0 +1,86 @@
+package darwin
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func Test_nonTransactional(t *testing.T) {
+	tests := []struct {
+		script   string
+		expected bool
+	}{
+		{"CREATE INDEX CONCURRENTLY users_id ON users (id);", true},
+		{"-- an index\ncreate unique index concurrently users_id ON users (id);", true},
+		{"CREATE TABLE users (id INT);\nVACUUM ANALYZE users;", true},
+		{"REINDEX (VERBOSE) INDEX CONCURRENTLY users_id;", true},
+		{"DROP INDEX CONCURRENTLY users_id;", true},
+		{"CREATE INDEX users_id ON users (id);", false},
+		{"INSERT INTO notes VALUES ('VACUUM');", false},
+		{"-- CREATE INDEX CONCURRENTLY users_id ON users (id);\nSELECT 1;", false},
+	}
+
+	for _, test := range tests {
+		if got := nonTransactional(test.script); got != test.expected {
+			t.Errorf("nonTransactional(%q) = %t, expected %t", test.script, got, test.expected)
+		}
+	}
+
+	if transactional(Migration{Script: "VACUUM users;"}) {
+		t.Errorf("Must not run a VACUUM in a transaction")
+	}
+}
+
+func Test_GenericDriver_Exec_concurrent_index(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, _ := NewGenericDriver(db, PostgresDialect{})
+
+	mock.ExpectExec(escapeQuery("CREATE TABLE users (id INT)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(escapeQuery(`CREATE INDEX CONCURRENTLY IF NOT EXISTS users_id ON app.users (id)`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(escapeQuery(PostgresDialect{}.IndexValidSQL())).WithArgs("app.users_id").WillReturnRows(sqlmock.NewRows([]string{"indisvalid"}).AddRow(false))
+	mock.ExpectQuery(escapeQuery(PostgresDialect{}.IndexValidSQL())).WithArgs("app.users_id").WillReturnRows(sqlmock.NewRows([]string{"indisvalid"}).AddRow(true))
+
+	defer func(poll time.Duration) { indexPoll = poll }(indexPoll)
+	indexPoll = time.Millisecond
+
+	if _, err := d.Exec("CREATE TABLE users (id INT);\nCREATE INDEX CONCURRENTLY IF NOT EXISTS users_id ON app.users (id);"); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_GenericDriver_Exec_invalid_index(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, _ := NewGenericDriver(db, PostgresDialect{})
+	d.IndexTimeout = time.Nanosecond
+
+	mock.ExpectExec(escapeQuery(`CREATE INDEX CONCURRENTLY "Users_id" ON users (id)`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(escapeQuery(PostgresDialect{}.IndexValidSQL())).WithArgs(`"Users_id"`).WillReturnRows(sqlmock.NewRows([]string{"indisvalid"}).AddRow(false))
+
+	_, err = d.Exec(`CREATE INDEX CONCURRENTLY "Users_id" ON users (id);`)
+
+	var invalid InvalidIndexError
+	if !errors.As(err, &invalid) || invalid.Index != `"Users_id"` {
+		t.Fatalf("Must return an InvalidIndexError, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
@@ -1,11 +1,14 @@
 package darwin
 
 import (
-	"bufio"
+	"context"
 	"crypto/md5"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"math"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -50,89 +53,289 @@ type Migration struct {
 	Version     float64
 	Description string
 	Script      string
+
+	// Files resolves the data files attached to the migration with copy
+	// directives. It is set by sources reading migrations from a file system.
+	Files fs.FS
+
+	// Loader, when set, loads the script of a migration its source left
+	// empty, so the scripts of the migrations which aren't executed are never
+	// held in memory. ScriptChecksum is the checksum of the script when the
+	// source computed it beforehand.
+	Loader         ScriptLoader
+	ScriptChecksum string
+}
+
+// ScriptLoader loads the script of a migration.
+type ScriptLoader interface {
+	LoadScript() (string, error)
 }
 
-// Checksum calculate the Script md5.
+// ScriptFunc is a function loading the script of a migration. Migrations
+// holding one can't be compared.
+type ScriptFunc func() (string, error)
+
+// LoadScript implements the ScriptLoader interface.
+func (f ScriptFunc) LoadScript() (string, error) {
+	return f()
+}
+
+// Checksum calculate the Script md5, loading the script when the source
+// didn't compute its checksum.
 func (m Migration) Checksum() string {
+	if m.ScriptChecksum != "" {
+		return m.ScriptChecksum
+	}
+	if o, ok := m.Loader.(ScriptOpener); ok && m.Script == "" {
+		if sum, err := streamChecksum(o); err == nil {
+			return sum
+		}
+	}
+	if loaded, err := m.Load(); err == nil {
+		m = loaded
+	}
 	return fmt.Sprintf("%x", md5.Sum([]byte(m.Script)))
 }
 
+// Load returns the migration with its script, loaded with its Loader when
+// the source left it empty.
+func (m Migration) Load() (Migration, error) {
+	if m.Script != "" || m.Loader == nil {
+		return m, nil
+	}
+
+	script, err := m.Loader.LoadScript()
+	if err != nil {
+		return m, fmt.Errorf("darwin: unable to load migration %f: %w", m.Version, err)
+	}
+
+	m.Script = script
+	return m, nil
+}
+
+// loadScripts returns the migrations with their scripts, but those the
+// driver streams.
+func loadScripts(d Driver, migrations []Migration) ([]Migration, error) {
+	loaded := make([]Migration, len(migrations))
+	for i, m := range migrations {
+		if streamed(d, m) {
+			loaded[i] = m
+			continue
+		}
+
+		l, err := m.Load()
+		if err != nil {
+			return nil, err
+		}
+		loaded[i] = l
+	}
+	return loaded, nil
+}
+
 // MigrationInfo is a struct used in the infoChan to inform clients about
 // the migration being applied.
 type MigrationInfo struct {
 	Status    Status
 	Error     error
 	Migration Migration
+
+	// AppliedAt is when the migration was applied and ExecutionTime how
+	// long it took, zero unless it is applied.
+	AppliedAt     time.Time
+	ExecutionTime time.Duration
+
+	// StoredChecksum is the checksum recorded when the migration was
+	// applied, which differs from the checksum of the migration if its
+	// script was edited since.
+	StoredChecksum string
+
+	// Record is the record of the migration in the history, with the
+	// metadata stored by the driver, nil unless it is applied.
+	Record *MigrationRecord
 }
 
 // Darwin is a helper struct to access the Validate and migration functions.
 type Darwin struct {
 	driver     Driver
 	migrations []Migration
+	lock       DistributedLock
+	plan       PlanFunc
+	backup     BackupFunc
+	gates      []PlanFunc
+	policies   []Policy
+	seeds      []Seed
+	seedTable  string
+
+	waitTimeout time.Duration
+	waitBackoff time.Duration
+
+	singleTransaction bool
+	skip              SkipFunc
+
+	onStatement  StatementHook
+	log          Logger
+	tracer       Tracer
+	metrics      Metrics
+	last         *lastRun
+	beforeEach   func(Migration) error
+	afterEach    func(Migration, Result) error
+	confirmation ConfirmFunc
+	progress     func(context.Context, Event)
+
+	heartbeatInterval time.Duration
+	heartbeatFunc     HeartbeatFunc
+
+	notifiers []Notifier
+
+	audit       AuditSink
+	identity    string
+	environment string
+
+	metadata     RecordMetadata
+	metadataFunc MetadataFunc
+	scripts      *ScriptStorage
+	now          func() time.Time
+
+	echo      bool
+	redactors []Redactor
+
+	// err is the error of an option, returned by the methods using the
+	// driver.
+	err error
+}
+
+// Option configures a Darwin.
+type Option func(*Darwin)
+
+// setErr keeps the first error of the options.
+func (d *Darwin) setErr(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+// WithNow sets the clock giving the AppliedAt time of the records, so tests
+// can assert the content of the history. The default clock is time.Now in
+// UTC, consistent across the regions of a deployment.
+func WithNow(now func() time.Time) Option {
+	return func(d *Darwin) {
+		d.now = now
+	}
+}
+
+// appliedAt returns the time a migration applied now is recorded with.
+func (d Darwin) appliedAt() time.Time {
+	if d.now != nil {
+		return d.now()
+	}
+	return time.Now().UTC()
 }
 
 // Validate if the database migrations are applied and consistent.
 func (d Darwin) Validate() error {
+	return d.validate(context.Background())
+}
+
+// validate is Validate in a span.
+func (d Darwin) validate(ctx context.Context) (err error) {
+	_, span := d.startSpan(ctx, "darwin.Validate")
+	defer func() { endSpan(span, err) }()
+
+	if d.err != nil {
+		return d.err
+	}
 	return Validate(d.driver, d.migrations)
 }
 
 // Migrate executes the missing migrations in database.
 func (d Darwin) Migrate() error {
-	return Migrate(d.driver, d.migrations)
+	return d.MigrateContext(context.Background())
 }
 
-// Info returns the status of all migrations.
-func (d Darwin) Info() ([]MigrationInfo, error) {
-	return Info(d.driver, d.migrations)
+// MigrateContext executes the missing migrations in database, canceled with
+// ctx when the driver is a ContextDriver.
+func (d Darwin) MigrateContext(ctx context.Context) error {
+	_, err := d.migrate(ctx)
+	return err
 }
 
-// New returns a new Darwin struct
-func New(driver Driver, migrations []Migration) Darwin {
-	return Darwin{
-		driver:     driver,
-		migrations: migrations,
+// BreakLock releases the migration lock left by an applier which crashed.
+// It must only be used once the holder is known to be gone, LockHolder
+// tells who it is. Advisory locks, released with the session of a crashed
+// applier, can't be broken.
+func (d Darwin) BreakLock() error {
+	if d.err != nil {
+		return d.err
+	}
+
+	b, ok := d.driver.(LockBreaker)
+	if !ok {
+		return errors.New("darwin: the driver lock can't be broken")
 	}
+	return b.BreakLock()
 }
 
-// ParseMigrations takes a string that represents a text formatted set
-// of migrations and parse them for use.
-func ParseMigrations(s string) []Migration {
-	var migs []Migration
+// LockHolder returns the holder of the migration lock, if it is held.
+func (d Darwin) LockHolder() (LockHolder, bool, error) {
+	if d.err != nil {
+		return LockHolder{}, false, d.err
+	}
 
-	scanner := bufio.NewScanner(strings.NewReader(s))
-	scanner.Split(bufio.ScanLines)
+	b, ok := d.driver.(LockBreaker)
+	if !ok {
+		return LockHolder{}, false, errors.New("darwin: the driver doesn't record the lock holder")
+	}
+	return b.LockHolder()
+}
 
-	var mig Migration
-	var script string
-	for scanner.Scan() {
-		v := scanner.Text()
-		lower := strings.ToLower(v)
-		switch {
-		case len(v) >= 5 && (lower[:6] == "-- ver" || lower[:5] == "--ver"):
-			mig.Script = script
-			migs = append(migs, mig)
+// Close releases the resources of the driver, if it has a Close method.
+func (d Darwin) Close() error {
+	if c, ok := d.driver.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
 
-			mig = Migration{}
-			script = ""
+// Info returns the status of all migrations.
+func (d Darwin) Info() (info []MigrationInfo, err error) {
+	_, span := d.startSpan(context.Background(), "darwin.Info")
+	defer func() { endSpan(span, err) }()
 
-			f, err := strconv.ParseFloat(strings.TrimSpace(v[11:]), 64)
-			if err != nil {
-				return nil
-			}
-			mig.Version = f
+	if d.err != nil {
+		return nil, d.err
+	}
+	return Info(d.driver, d.migrations)
+}
 
-		case len(v) >= 5 && (lower[:6] == "-- des" || lower[:5] == "--des"):
-			mig.Description = strings.TrimSpace(v[15:])
+// LatestApplied returns the record of the newest migration applied, if any.
+func (d Darwin) LatestApplied() (MigrationRecord, bool, error) {
+	if d.err != nil {
+		return MigrationRecord{}, false, d.err
+	}
+	return LatestApplied(d.driver)
+}
 
-		default:
-			script += v + "\n"
-		}
+// ExportHistory writes the history of the migrations applied to w.
+func (d Darwin) ExportHistory(w io.Writer, format ExportFormat) error {
+	if d.err != nil {
+		return d.err
 	}
+	return ExportHistory(d.driver, w, format)
+}
 
-	mig.Script = script
-	migs = append(migs, mig)
+// New returns a new Darwin struct
+func New(driver Driver, migrations []Migration, opts ...Option) Darwin {
+	d := Darwin{
+		driver:     driver,
+		migrations: migrations,
+		last:       &lastRun{},
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
 
-	return migs[1:]
+	return d
 }
 
 // DuplicateMigrationVersionError is used to report when the migration list has
@@ -174,16 +377,97 @@ func (i InvalidChecksumError) Error() string {
 	return fmt.Sprintf("Invalid cheksum for migration %f", i.Version)
 }
 
-// Validate if the database migrations are applied and consistent.
-func Validate(d Driver, migrations []Migration) error {
-	sort.Sort(byMigrationVersion(migrations))
+// excerptLength is the maximum length, in characters, of the statement
+// excerpt of an ExecutionError.
+const excerptLength = 80
 
-	if version, invalid := isInvalidVersion(migrations); invalid {
-		return IllegalMigrationVersionError{Version: version}
+// StatementFailure is implemented by the errors of drivers knowing which
+// statement of a script failed, index is 0-based.
+type StatementFailure interface {
+	FailedStatement() (index int, statement string)
+}
+
+// ExecutionError is used to report when a migration fails, with the failed
+// statement when the driver returns a StatementFailure.
+type ExecutionError struct {
+	Version     float64
+	Description string
+
+	// Statement is the 0-based index of the failed statement, -1 when
+	// unknown.
+	Statement int
+
+	// SQL is the failed statement.
+	SQL string
+
+	Err error
+}
+
+func (e ExecutionError) Error() string {
+	if e.Statement < 0 {
+		return fmt.Sprintf("darwin: migration %f: %s", e.Version, e.Err)
 	}
+	return fmt.Sprintf("darwin: migration %f, statement %d %q: %s", e.Version, e.Statement+1, e.Excerpt(), e.Err)
+}
 
-	if version, dup := isDuplicated(migrations); dup {
-		return DuplicateMigrationVersionError{Version: version}
+// Unwrap returns the underlying error.
+func (e ExecutionError) Unwrap() error {
+	return e.Err
+}
+
+// Excerpt returns the failed statement on one line, truncated.
+func (e ExecutionError) Excerpt() string {
+	sql := []rune(strings.Join(strings.Fields(e.SQL), " "))
+	if len(sql) > excerptLength {
+		sql = append(sql[:excerptLength-3], []rune("...")...)
+	}
+	return string(sql)
+}
+
+// executionError wraps the error of the migration m in an ExecutionError.
+// An AttachmentError already tells which migration failed and is returned
+// as is.
+func executionError(m Migration, err error) error {
+	if _, ok := err.(AttachmentError); ok {
+		return err
+	}
+
+	e := ExecutionError{Version: m.Version, Description: m.Description, Statement: -1, Err: err}
+
+	var failure StatementFailure
+	if errors.As(err, &failure) {
+		e.Statement, e.SQL = failure.FailedStatement()
+	}
+
+	return e
+}
+
+// statementError is the StatementFailure of the generic driver.
+type statementError struct {
+	index     int
+	statement string
+	err       error
+}
+
+func (s statementError) Error() string {
+	return s.err.Error()
+}
+
+func (s statementError) Unwrap() error {
+	return s.err
+}
+
+func (s statementError) FailedStatement() (int, string) {
+	return s.index, s.statement
+}
+
+// Validate if the database migrations are applied and consistent. The
+// migrations are sorted in a copy, the slice of the caller is left as is.
+func Validate(d Driver, migrations []Migration) error {
+	migrations = sortedMigrations(migrations)
+
+	if err := checkVersions(migrations); err != nil {
+		return err
 	}
 
 	applied, err := d.All()
@@ -192,6 +476,26 @@ func Validate(d Driver, migrations []Migration) error {
 		return err
 	}
 
+	return validateRecords(applied, migrations)
+}
+
+// checkVersions checks that the versions of the sorted migrations are
+// legal and unique.
+func checkVersions(migrations []Migration) error {
+	if version, invalid := isInvalidVersion(migrations); invalid {
+		return IllegalMigrationVersionError{Version: version}
+	}
+
+	if version, dup := isDuplicated(migrations); dup {
+		return DuplicateMigrationVersionError{Version: version}
+	}
+
+	return nil
+}
+
+// validateRecords checks the sorted migrations against the records of the
+// history.
+func validateRecords(applied []MigrationRecord, migrations []Migration) error {
 	if version, removed := wasRemovedMigration(applied, migrations); removed {
 		return RemovedMigrationError{Version: version}
 	}
@@ -205,44 +509,67 @@ func Validate(d Driver, migrations []Migration) error {
 
 // Info returns the status of all migrations.
 func Info(d Driver, migrations []Migration) ([]MigrationInfo, error) {
-	info := []MigrationInfo{}
 	records, err := d.All()
 
 	if err != nil {
-		return info, err
+		return []MigrationInfo{}, err
 	}
 
-	sort.Sort(sort.Reverse(byMigrationRecordVersion(records)))
+	return infoRecords(records, migrations), nil
+}
+
+// infoRecords returns the status of the migrations from the records of the
+// history.
+func infoRecords(records []MigrationRecord, migrations []Migration) []MigrationInfo {
+	info := []MigrationInfo{}
+
+	// The records are looked up by version, so the status of thousands of
+	// migrations is known in linear time.
+	applied := map[float64]MigrationRecord{}
+	last := math.Inf(-1)
+	for _, record := range records {
+		applied[record.Version] = record
+		if record.Version > last {
+			last = record.Version
+		}
+	}
 
 	for _, migration := range migrations {
-		info = append(info, MigrationInfo{
-			Status:    getStatus(records, migration),
+		status := getStatus(applied, last, migration)
+
+		i := MigrationInfo{
+			Status:    status,
 			Error:     nil,
 			Migration: migration,
-		})
+		}
+
+		if record, ok := applied[migration.Version]; ok {
+			i.AppliedAt = record.AppliedAt
+			i.ExecutionTime = record.ExecutionTime
+			i.StoredChecksum = record.Checksum
+			i.Record = &record
+		}
+
+		info = append(info, i)
 	}
 
-	return info, nil
+	return info
 }
 
-func getStatus(inDatabase []MigrationRecord, migration Migration) Status {
-	last := inDatabase[0]
+// getStatus returns the status of the migration from the applied records
+// by version and the latest version applied.
+func getStatus(applied map[float64]MigrationRecord, last float64, migration Migration) Status {
+	if len(applied) == 0 {
+		return Pending
+	}
 
 	// Check if pending.
-	if migration.Version > last.Version {
+	if migration.Version > last {
 		return Pending
 	}
 
 	// Check if ignored.
-	found := false
-
-	for _, record := range inDatabase {
-		if record.Version == migration.Version {
-			found = true
-		}
-	}
-
-	if !found {
+	if _, found := applied[migration.Version]; !found {
 		return Ignored
 	}
 
@@ -251,46 +578,263 @@ func getStatus(inDatabase []MigrationRecord, migration Migration) Status {
 
 // Migrate executes the missing migrations in database.
 func Migrate(d Driver, migrations []Migration) error {
-	err := d.Create()
+	return MigrateContext(context.Background(), d, migrations)
+}
+
+// MigrateContext executes the missing migrations in database. When d is a
+// ContextDriver the connection is checked with Ping before the migrations
+// are planned, and they are executed with ExecContext. When d is a Locker
+// the lock is held from before the migrations are planned until the last one
+// is recorded.
+func MigrateContext(ctx context.Context, d Driver, migrations []Migration) error {
+	_, err := New(d, migrations).migrate(ctx)
+	return err
+}
+
+// migrate executes the missing migrations holding the lock given to
+// WithLock, or the lock of the driver, and reports on them.
+func (dw Darwin) migrate(ctx context.Context) (report Report, err error) {
+	d, migrations := dw.driver, dw.migrations
+
+	var planned []Migration
 
+	ctx, span := dw.startSpan(ctx, "darwin.Migrate")
+	defer func(start time.Time) {
+		if aerr := dw.auditRun(ctx, planned, report, err, start); err == nil {
+			err = aerr
+		}
+		span.SetAttribute("darwin.applied", len(report.Results))
+		endSpan(span, err)
+		dw.logOutcome(report, err, start)
+		if err != nil {
+			dw.emit(ctx, ErrorEvent{Err: err})
+		}
+		dw.recordRun(planned, report, err, start)
+		dw.notify(ctx, report, err)
+	}(time.Now())
+
+	if dw.err != nil {
+		return report, dw.err
+	}
+
+	if err := dw.checkMetadata(); err != nil {
+		return report, err
+	}
+
+	if dw.waitTimeout > 0 {
+		wctx, cancel := context.WithTimeout(ctx, dw.waitTimeout)
+		err := WaitForDriver(wctx, d, dw.waitBackoff)
+		cancel()
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if cd, ok := d.(ContextDriver); ok {
+		if err := cd.Ping(ctx); err != nil {
+			return report, err
+		}
+	}
+
+	lock, release, err := dw.acquire(ctx)
 	if err != nil {
-		return err
+		return report, err
 	}
+	defer func() {
+		if uerr := release(); err == nil {
+			err = uerr
+		}
+	}()
+
+	ctx, beat, stop := dw.heartbeat(ctx, lock)
+	defer func() {
+		if herr := stop(); herr != nil {
+			err = herr
+		}
+	}()
 
-	err = Validate(d, migrations)
+	err = d.Create()
 
 	if err != nil {
-		return err
+		return report, err
 	}
 
-	planned, err := planMigration(d, migrations)
+	// Validation and planning share a single read of the newest records.
+	sorted := sortedMigrations(migrations)
+	records, err := dw.validateHistory(ctx, sorted)
 
 	if err != nil {
-		return err
+		return report, err
 	}
 
-	for _, migration := range planned {
-		dur, err := d.Exec(migration.Script)
+	p, err := planRecords(d, records, sorted)
 
-		if err != nil {
-			return err
+	if err != nil {
+		return report, err
+	}
+
+	planned = p
+
+	if err := checkAttachments(d, planned); err != nil {
+		return report, err
+	}
+
+	dw.logger().Info("darwin: plan computed", "pending", len(planned))
+	span.SetAttribute("darwin.pending", len(planned))
+	dw.measures().MigrationsPending(len(planned))
+	dw.emit(ctx, PlanComputed{Planned: planned})
+
+	for _, gate := range dw.gates {
+		if err := gate(ctx, planned); err != nil {
+			return report, err
+		}
+	}
+
+	if err := PolicyGate(dw.environment, dw.policies...)(ctx, planned); err != nil {
+		return report, err
+	}
+
+	if dw.plan != nil {
+		if err := dw.plan(ctx, planned); err != nil {
+			return report, err
 		}
+	}
 
-		err = d.Insert(MigrationRecord{
-			Version:       migration.Version,
-			Description:   migration.Description,
-			Checksum:      migration.Checksum(),
-			AppliedAt:     time.Now(),
-			ExecutionTime: dur,
+	if err := dw.takeBackup(ctx, planned); err != nil {
+		return report, err
+	}
+
+	if dw.singleTransaction {
+		var confirmed []Migration
+		for _, migration := range planned {
+			ok, err := dw.confirm(ctx, migration)
+			if err != nil {
+				return report, err
+			}
+			if !ok {
+				break
+			}
+			confirmed = append(confirmed, migration)
+		}
+
+		if len(planned) == 0 {
+			return report, dw.seed(ctx, &report)
+		}
+		if len(confirmed) == 0 {
+			return report, nil
+		}
+
+		for _, migration := range confirmed {
+			if err := dw.before(migration); err != nil {
+				return report, err
+			}
+		}
+
+		var results []Result
+		tctx, tspan := dw.startSpan(ctx, "darwin.Transaction")
+		tspan.SetAttribute("darwin.migrations", len(confirmed))
+		beat.started(Migration{})
+		reported := map[float64]int{}
+		start := func(ctx context.Context, m Migration) context.Context {
+			dw.logger().Info("darwin: migration started", "version", m.Version, "description", m.Description)
+			dw.emit(ctx, MigrationStarted{Migration: m})
+			return dw.observe(ctx, m, reported)
+		}
+		err := execAllInTx(tctx, d, confirmed, dw.skip, dw.record, start, func(r Result) {
+			results = append(results, r)
+		}, func(s SkippedMigration) {
+			dw.logger().Error("darwin: migration skipped", "version", s.Migration.Version, "error", s.Err)
+			report.Skipped = append(report.Skipped, s)
 		})
+		beat.finished()
+		endSpan(tspan, err)
+		if err != nil {
+			dw.failed(confirmed, err)
+			return report, err
+		}
+		for _, r := range results {
+			dw.executed(ctx, &report, r, reported[r.Migration.Version])
+		}
+		dw.measures().MigrationsPending(len(planned) - len(confirmed))
+		for _, r := range results {
+			if err := dw.after(r); err != nil {
+				return report, err
+			}
+		}
+		return report, dw.seed(ctx, &report)
+	}
+
+	for _, migration := range planned {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		if ok, err := dw.confirm(ctx, migration); err != nil || !ok {
+			return report, err
+		}
+
+		if err := dw.before(migration); err != nil {
+			return report, err
+		}
+
+		dw.logger().Info("darwin: migration started", "version", migration.Version, "description", migration.Description)
+		dw.emit(ctx, MigrationStarted{Migration: migration})
 
+		reported := map[float64]int{}
+		beat.started(migration)
+		r, err := dw.apply(dw.observe(ctx, migration, reported), migration)
+		beat.finished()
 		if err != nil {
-			return err
+			dw.measures().MigrationFailed(migration, err)
+			return report, err
 		}
 
+		dw.executed(ctx, &report, r, reported[migration.Version])
+		dw.measures().MigrationsPending(len(planned) - len(report.Results))
+
+		if err := dw.after(r); err != nil {
+			return report, err
+		}
 	}
 
-	return nil
+	return report, dw.seed(ctx, &report)
+}
+
+// apply executes the migration and records it, in a transaction when the
+// driver and the migration allow it.
+func (dw Darwin) apply(ctx context.Context, migration Migration) (r Result, err error) {
+	d := dw.driver
+
+	ctx, span := dw.startSpan(ctx, "darwin.Migration")
+	span.SetAttribute("darwin.version", migration.Version)
+	span.SetAttribute("darwin.description", migration.Description)
+	defer func() {
+		if err == nil {
+			span.SetAttribute("darwin.statements", len(r.Statements))
+			span.SetAttribute("darwin.rows_affected", r.RowsAffected())
+		}
+		endSpan(span, err)
+	}()
+
+	if transactional(migration) {
+		r, err := execInTx(ctx, d, migration, dw.record)
+		if err == nil {
+			return r, nil
+		}
+		if err != ErrTransactionUnsupported {
+			return r, executionError(migration, err)
+		}
+	}
+
+	r, err = execMigration(ctx, d, migration)
+
+	if err != nil {
+		return r, executionError(migration, err)
+	}
+
+	err = d.Insert(dw.record(ctx, migration, r.Duration))
+
+	return r, err
 }
 
 func wasRemovedMigration(applied []MigrationRecord, migrations []Migration) (float64, bool) {
@@ -356,24 +900,30 @@ func isDuplicated(migrations []Migration) (float64, bool) {
 }
 
 func planMigration(d Driver, migrations []Migration) ([]Migration, error) {
-	records, err := d.All()
+	migrations = sortedMigrations(migrations)
+
+	records, err := readHistory(d, migrations)
 
 	if err != nil {
 		return []Migration{}, err
 	}
 
+	return planRecords(d, records, migrations)
+}
+
+// planRecords returns the sorted migrations newer than the newest of the
+// records, with their scripts.
+func planRecords(d Driver, records []MigrationRecord, migrations []Migration) ([]Migration, error) {
+	last, ok := lastRecord(records)
+
 	// Apply all migrations.
-	if len(records) == 0 {
-		return migrations, nil
+	if !ok {
+		return loadScripts(d, migrations)
 	}
 
 	// Which migrations needs to be applied.
 	planned := []Migration{}
 
-	// Make sure the order is correct. Do not trust the driver.
-	sort.Sort(sort.Reverse(byMigrationRecordVersion(records)))
-	last := records[0]
-
 	// Apply all migrations that are greater than the last migration.
 	for _, migration := range migrations {
 		if migration.Version > last.Version {
@@ -381,10 +931,15 @@ func planMigration(d Driver, migrations []Migration) ([]Migration, error) {
 		}
 	}
 
-	// Make sure the order is correct.
-	sort.Sort(byMigrationVersion(planned))
+	return loadScripts(d, planned)
+}
 
-	return planned, nil
+// sortedMigrations returns a copy of the migrations sorted by version.
+func sortedMigrations(migrations []Migration) []Migration {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Sort(byMigrationVersion(sorted))
+	return sorted
 }
 
 type byMigrationVersion []Migration
@@ -2,6 +2,7 @@ package darwin
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"sort"
@@ -275,6 +276,156 @@ func Test_Validate_invalid_checksum(t *testing.T) {
 	}
 }
 
+// contextDriver is a dummyDriver implementing ContextDriver.
+type contextDriver struct {
+	dummyDriver
+	pingError error
+	execs     int
+	closed    bool
+}
+
+func (d *contextDriver) ExecContext(ctx context.Context, script string) (time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	d.execs++
+	return d.Exec(script)
+}
+
+func (d *contextDriver) Ping(ctx context.Context) error { return d.pingError }
+
+func (d *contextDriver) Close() error {
+	d.closed = true
+	return nil
+}
+
+func Test_MigrateContext(t *testing.T) {
+	migrations := []Migration{{Version: 1, Description: "First Migration", Script: "does not matter!"}}
+
+	driver := &contextDriver{}
+	d := New(driver, migrations)
+
+	if err := d.MigrateContext(context.Background()); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if driver.execs != 1 {
+		t.Errorf("Must execute the migrations with ExecContext, got %d calls", driver.execs)
+	}
+
+	if err := d.Close(); err != nil || !driver.closed {
+		t.Errorf("Must close the driver")
+	}
+}
+
+func Test_MigrateContext_ping_error(t *testing.T) {
+	driver := &contextDriver{pingError: errors.New("connection refused")}
+
+	err := MigrateContext(context.Background(), driver, []Migration{{Version: 1, Script: "does not matter!"}})
+	if err != driver.pingError {
+		t.Errorf("Must return the error of Ping, got %v", err)
+	}
+
+	if all, _ := driver.All(); len(all) != 0 {
+		t.Errorf("Must not plan the migrations when the database is unreachable")
+	}
+}
+
+func Test_MigrateContext_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	driver := &contextDriver{}
+
+	err := MigrateContext(ctx, driver, []Migration{{Version: 1, Script: "does not matter!"}})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// lockingDriver is a dummyDriver implementing Locker.
+type lockingDriver struct {
+	dummyDriver
+	calls []string
+}
+
+func (d *lockingDriver) Lock() error {
+	d.calls = append(d.calls, "lock")
+	return nil
+}
+
+func (d *lockingDriver) Unlock() error {
+	d.calls = append(d.calls, "unlock")
+	return nil
+}
+
+func (d *lockingDriver) Insert(m MigrationRecord) error {
+	d.calls = append(d.calls, "insert")
+	return d.dummyDriver.Insert(m)
+}
+
+func Test_Migrate_lock(t *testing.T) {
+	driver := &lockingDriver{}
+
+	migrations := []Migration{{Version: 1, Description: "First Migration", Script: "does not matter!"}}
+	if err := Migrate(driver, migrations); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if fmt.Sprint(driver.calls) != "[lock insert unlock]" {
+		t.Errorf("Must hold the lock while migrating, got %v", driver.calls)
+	}
+
+	driver = &lockingDriver{dummyDriver: dummyDriver{ExecError: true}}
+	if err := Migrate(driver, migrations); err == nil {
+		t.Fatalf("Must return the error of Exec")
+	}
+
+	if fmt.Sprint(driver.calls) != "[lock unlock]" {
+		t.Errorf("Must release the lock when a migration fails, got %v", driver.calls)
+	}
+}
+
+// breakableDriver is a lockingDriver implementing LockBreaker.
+type breakableDriver struct {
+	lockingDriver
+	holder *LockHolder
+}
+
+func (d *breakableDriver) LockHolder() (LockHolder, bool, error) {
+	if d.holder == nil {
+		return LockHolder{}, false, nil
+	}
+	return *d.holder, true, nil
+}
+
+func (d *breakableDriver) BreakLock() error {
+	d.holder = nil
+	return nil
+}
+
+func Test_Darwin_BreakLock(t *testing.T) {
+	driver := &breakableDriver{holder: &LockHolder{Hostname: "pod-1", PID: 42}}
+	d := New(driver, nil)
+
+	holder, held, err := d.LockHolder()
+	if err != nil || !held || holder.Hostname != "pod-1" {
+		t.Fatalf("Must return the holder of the lock, got %#v, %v", holder, err)
+	}
+
+	if err := d.BreakLock(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if _, held, _ := d.LockHolder(); held {
+		t.Errorf("Must break the lock")
+	}
+
+	if err := New(&dummyDriver{}, nil).BreakLock(); err == nil {
+		t.Errorf("Must return an error when the driver lock can't be broken")
+	}
+}
+
 func Test_Migrate_migrate_all(t *testing.T) {
 	migrations := []Migration{
 		{
@@ -504,3 +655,71 @@ CREATE TABLE sales (
 ALTER TABLE products
 	ADD COLUMN user_id UUID DEFAULT '00000000-0000-0000-0000-000000000000'
 `
+
+// largeHistory returns n migrations, the first applied of them recorded.
+func largeHistory(n, applied int) ([]Migration, *dummyDriver) {
+	migrations := make([]Migration, n)
+	driver := &dummyDriver{}
+	for i := range migrations {
+		migrations[i] = Migration{Version: float64(i + 1), Description: "Migration", Script: fmt.Sprintf("SELECT %d;", i)}
+		if i < applied {
+			driver.records = append(driver.records, MigrationRecord{Version: migrations[i].Version, Checksum: migrations[i].Checksum()})
+		}
+	}
+	return migrations, driver
+}
+
+func BenchmarkInfo(b *testing.B) {
+	migrations, driver := largeHistory(10000, 9990)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := Info(driver, migrations); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkValidate(b *testing.B) {
+	migrations, driver := largeHistory(10000, 9990)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := Validate(driver, migrations); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPlan(b *testing.B) {
+	migrations, driver := largeHistory(10000, 9990)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if planned, err := planMigration(driver, migrations); err != nil || len(planned) != 10 {
+			b.Fatal(planned, err)
+		}
+	}
+}
+
+func Test_WithNow(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+	migrations := []Migration{{Version: 1, Script: "SELECT 1;"}, {Version: 2, Script: "SELECT 2;"}}
+
+	driver := &dummyDriver{}
+	if err := New(driver, migrations, WithNow(func() time.Time { return now })).Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	for _, r := range driver.records {
+		if !r.AppliedAt.Equal(now) {
+			t.Errorf("Must record the time of the clock, got %s", r.AppliedAt)
+		}
+	}
+
+	driver = &dummyDriver{}
+	New(driver, migrations).Migrate()
+	if len(driver.records) != 2 || driver.records[0].AppliedAt.Location() != time.UTC {
+		t.Errorf("Must record the time in UTC by default, got %#v", driver.records)
+	}
+}
@@ -0,0 +1,91 @@
+// Package darwinprom exports the metrics of darwin.Migrate to Prometheus.
+//
+// The package doesn't import the Prometheus client, the collectors of
+// github.com/prometheus/client_golang satisfy its interfaces:
+//
+//	metrics := darwinprom.Metrics{
+//		Applied: promauto.NewCounter(prometheus.CounterOpts{
+//			Name: "darwin_migrations_applied_total",
+//			Help: "Migrations applied.",
+//		}),
+//		Failed: promauto.NewCounter(prometheus.CounterOpts{
+//			Name: "darwin_migrations_failed_total",
+//			Help: "Migrations which failed.",
+//		}),
+//		Pending: promauto.NewGauge(prometheus.GaugeOpts{
+//			Name: "darwin_migrations_pending",
+//			Help: "Migrations left to apply.",
+//		}),
+//		Duration: promauto.NewHistogram(prometheus.HistogramOpts{
+//			Name:    "darwin_migration_duration_seconds",
+//			Help:    "Execution time of the migrations.",
+//			Buckets: prometheus.ExponentialBuckets(0.01, 4, 10),
+//		}),
+//	}
+//
+//	d := darwin.New(driver, migrations, darwin.WithMetrics(metrics))
+package darwinprom
+
+import (
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Counter is a Prometheus counter.
+type Counter interface {
+	Inc()
+}
+
+// Gauge is a Prometheus gauge.
+type Gauge interface {
+	Set(float64)
+}
+
+// Observer is a Prometheus histogram or summary.
+type Observer interface {
+	Observe(float64)
+}
+
+// Metrics is a darwin.Metrics updating Prometheus collectors. The nil ones
+// are skipped.
+type Metrics struct {
+	// Applied counts the migrations applied.
+	Applied Counter
+
+	// Failed counts the migrations which failed.
+	Failed Counter
+
+	// Pending is the number of migrations left to apply.
+	Pending Gauge
+
+	// Duration observes the execution time of the migrations, in seconds.
+	Duration Observer
+}
+
+var _ darwin.Metrics = Metrics{}
+
+// MigrationsPending sets the Pending gauge.
+func (m Metrics) MigrationsPending(n int) {
+	if m.Pending != nil {
+		m.Pending.Set(float64(n))
+	}
+}
+
+// MigrationApplied increments the Applied counter and observes the
+// duration.
+func (m Metrics) MigrationApplied(_ darwin.Migration, d time.Duration) {
+	if m.Applied != nil {
+		m.Applied.Inc()
+	}
+	if m.Duration != nil {
+		m.Duration.Observe(d.Seconds())
+	}
+}
+
+// MigrationFailed increments the Failed counter.
+func (m Metrics) MigrationFailed(darwin.Migration, error) {
+	if m.Failed != nil {
+		m.Failed.Inc()
+	}
+}
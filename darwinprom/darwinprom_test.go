@@ -0,0 +1,40 @@
+package darwinprom
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+type counter struct{ n int }
+
+func (c *counter) Inc() { c.n++ }
+
+type gauge struct{ v float64 }
+
+func (g *gauge) Set(v float64) { g.v = v }
+
+type observer struct{ values []float64 }
+
+func (o *observer) Observe(v float64) { o.values = append(o.values, v) }
+
+func Test_Metrics(t *testing.T) {
+	applied, failed, pending, duration := &counter{}, &counter{}, &gauge{}, &observer{}
+	m := Metrics{Applied: applied, Failed: failed, Pending: pending, Duration: duration}
+
+	m.MigrationsPending(3)
+	m.MigrationApplied(darwin.Migration{Version: 1}, 1500*time.Millisecond)
+	m.MigrationFailed(darwin.Migration{Version: 2}, errors.New("boom"))
+
+	if pending.v != 3 || applied.n != 1 || failed.n != 1 {
+		t.Errorf("Must update the collectors, got %v %d %d", pending.v, applied.n, failed.n)
+	}
+
+	if len(duration.values) != 1 || duration.values[0] != 1.5 {
+		t.Errorf("Must observe the duration in seconds, got %v", duration.values)
+	}
+
+	Metrics{}.MigrationApplied(darwin.Migration{}, time.Second)
+}
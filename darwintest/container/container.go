@@ -0,0 +1,156 @@
+// Package container runs migrations against real databases started in
+// containers, for the integration tests of darwin's drivers and of the
+// applications validating their migrations in CI:
+//
+//	func TestMigrations(t *testing.T) {
+//		driver := container.Migrate(t, container.Postgres, migrations)
+//		...
+//	}
+//
+// The package doesn't import testcontainers-go or a database/sql driver:
+// the containers are started with the docker command, the tests are skipped
+// where it isn't installed, and the test binary registers the driver named
+// by the Database, like github.com/lib/pq for Postgres.
+package container
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dustinevan/darwin"
+	"github.com/dustinevan/darwin/drivers/mysql"
+)
+
+// Database is a database server image and the way to migrate it.
+type Database struct {
+	Image string
+
+	// Port is the port of the server in the container, like "5432/tcp",
+	// published on a free port of the host.
+	Port string
+	Env  []string
+
+	// DriverName is the database/sql driver opening DSN, the data source
+	// of the server listening on the host:port address.
+	DriverName string
+	DSN        func(address string) string
+
+	// Driver returns the darwin driver of the database.
+	Driver func(db *sql.DB) (darwin.Driver, error)
+}
+
+// Databases of the built-in drivers.
+var (
+	Postgres = Database{
+		Image:      "postgres:16-alpine",
+		Port:       "5432/tcp",
+		Env:        []string{"POSTGRES_PASSWORD=darwin", "POSTGRES_DB=darwin"},
+		DriverName: "postgres",
+		DSN: func(address string) string {
+			return "postgres://postgres:darwin@" + address + "/darwin?sslmode=disable"
+		},
+		Driver: func(db *sql.DB) (darwin.Driver, error) {
+			return darwin.NewGenericDriver(db, darwin.PostgresDialect{})
+		},
+	}
+
+	MySQL = Database{
+		Image:      "mysql:8.0",
+		Port:       "3306/tcp",
+		Env:        []string{"MYSQL_ROOT_PASSWORD=darwin", "MYSQL_DATABASE=darwin"},
+		DriverName: "mysql",
+		DSN: func(address string) string {
+			return "root:darwin@tcp(" + address + ")/darwin?multiStatements=true"
+		},
+		Driver: func(db *sql.DB) (darwin.Driver, error) {
+			return mysql.New(db)
+		},
+	}
+)
+
+// StartTimeout is how long Start waits for a database to accept
+// connections.
+var StartTimeout = 2 * time.Minute
+
+// docker runs the docker command and returns its output.
+var docker = func(args ...string) (string, error) {
+	out, err := exec.Command("docker", args...).Output()
+	if ee, ok := err.(*exec.ExitError); ok {
+		return "", fmt.Errorf("docker %s: %s", args[0], strings.TrimSpace(string(ee.Stderr)))
+	}
+	return string(out), err
+}
+
+// lookPath reports where the docker command is.
+var lookPath = exec.LookPath
+
+// Start starts the database in a container removed once the test is over,
+// and returns its darwin driver once it accepts connections, with the
+// database/sql database. The test is skipped when docker isn't installed.
+func Start(t testing.TB, database Database) (darwin.Driver, *sql.DB) {
+	t.Helper()
+
+	if _, err := lookPath("docker"); err != nil {
+		t.Skip("container: docker isn't installed")
+	}
+
+	args := []string{"run", "--detach", "--publish", "127.0.0.1::" + database.Port}
+	for _, env := range database.Env {
+		args = append(args, "--env", env)
+	}
+
+	out, err := docker(append(args, database.Image)...)
+	if err != nil {
+		t.Fatalf("container: unable to start %s: %s", database.Image, err)
+	}
+
+	id := strings.TrimSpace(out)
+	t.Cleanup(func() {
+		if _, err := docker("rm", "--force", "--volumes", id); err != nil {
+			t.Errorf("container: unable to remove %s: %s", database.Image, err)
+		}
+	})
+
+	out, err = docker("port", id, database.Port)
+	if err != nil {
+		t.Fatalf("container: unable to find the port of %s: %s", database.Image, err)
+	}
+	address := strings.TrimSpace(strings.SplitN(out, "\n", 2)[0])
+
+	db, err := sql.Open(database.DriverName, database.DSN(address))
+	if err != nil {
+		t.Fatalf("container: unable to open %s: %s", database.Image, err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	driver, err := database.Driver(db)
+	if err != nil {
+		t.Fatalf("container: unable to create the driver of %s: %s", database.Image, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), StartTimeout)
+	defer cancel()
+	if err := darwin.WaitForDriver(ctx, driver, 500*time.Millisecond); err != nil {
+		t.Fatalf("container: %s doesn't accept connections: %s", database.Image, err)
+	}
+
+	return driver, db
+}
+
+// Migrate starts the database with Start and applies the migrations,
+// failing the test when they can't be.
+func Migrate(t testing.TB, database Database, migrations []darwin.Migration) darwin.Driver {
+	t.Helper()
+
+	driver, _ := Start(t, database)
+	if err := darwin.New(driver, migrations).Migrate(); err != nil {
+		t.Fatalf("container: unable to migrate %s: %s", database.Image, err)
+	}
+
+	return driver
+}
@@ -0,0 +1,108 @@
+// Package darwintest helps the integration tests of applications using
+// darwin. NewSchema gives each test a schema, or a database, of its own,
+// migrated and dropped once the test is over, so the tests can run in
+// parallel on a shared database server:
+//
+//	func newSchema(name string) (darwin.Driver, func() error, error) {
+//		if _, err := db.Exec("CREATE SCHEMA " + name); err != nil {
+//			return nil, nil, err
+//		}
+//		driver, err := darwin.NewGenericDriver(db, darwin.PostgresDialect{Table: name + ".darwin_migrations"})
+//		drop := func() error {
+//			_, err := db.Exec("DROP SCHEMA " + name + " CASCADE")
+//			return err
+//		}
+//		return driver, drop, err
+//	}
+//
+//	func TestUsers(t *testing.T) {
+//		t.Parallel()
+//		schema := darwintest.NewSchema(t, newSchema, migrations)
+//		// CREATE TABLE {{schema}}.users (...) was applied to schema.Name.
+//	}
+//
+// Code running migrations can be unit tested without a database with a
+// FakeDriver, failing on the migrations it is told to:
+//
+//	driver := darwintest.NewFakeDriver()
+//	driver.FailMigration(migrations[2], nil)
+//
+// The container package starts the database servers themselves, in
+// containers, and applies the migrations with the built-in drivers.
+package darwintest
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Factory creates the schema or database name and returns the driver
+// keeping its history there, with the function dropping it, nil if it
+// needn't be.
+type Factory func(name string) (driver darwin.Driver, drop func() error, err error)
+
+// Schema is a schema migrated for a test.
+type Schema struct {
+	Name   string
+	Driver darwin.Driver
+}
+
+// schemas counts the schemas created by the process.
+var schemas int64
+
+// maxPrefix is the length of the name of the test kept in the name of a
+// schema, short enough for the identifiers of every database.
+const maxPrefix = 32
+
+// NewSchema creates a schema with the factory, applies the migrations to it
+// with SchemaPlaceholder replaced by its name, and drops it when the test
+// and its subtests are over. The test fails when the schema can't be
+// created or migrated.
+func NewSchema(t testing.TB, factory Factory, migrations []darwin.Migration) *Schema {
+	t.Helper()
+
+	name := SchemaName(t)
+	driver, drop, err := factory(name)
+	if err != nil {
+		t.Fatalf("darwintest: unable to create schema %s: %s", name, err)
+	}
+
+	if drop != nil {
+		t.Cleanup(func() {
+			if err := drop(); err != nil {
+				t.Errorf("darwintest: unable to drop schema %s: %s", name, err)
+			}
+		})
+	}
+
+	if err := darwin.New(driver, darwin.RenderSchema(migrations, name)).Migrate(); err != nil {
+		t.Fatalf("darwintest: unable to migrate schema %s: %s", name, err)
+	}
+
+	return &Schema{Name: name, Driver: driver}
+}
+
+// SchemaName returns a name for a schema of the test, unique to the process
+// and to the processes of the other test packages: the name of the test in
+// lower case, then the process ID and a counter.
+func SchemaName(t testing.TB) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(t.Name()) {
+		if b.Len() == maxPrefix {
+			break
+		}
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+
+	return fmt.Sprintf("t_%s_%d_%d", b.String(), os.Getpid(), atomic.AddInt64(&schemas, 1))
+}
@@ -0,0 +1,69 @@
+package darwintest
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	_ "github.com/cznic/ql/driver"
+	"github.com/dustinevan/darwin"
+)
+
+var migrations = []darwin.Migration{
+	{Version: 1, Description: "Users", Script: "CREATE TABLE users (id int, schema string);"},
+	{Version: 2, Description: "Admin", Script: "INSERT INTO users VALUES (1, \"{{schema}}\");"},
+}
+
+// qlSchema creates each schema as an in memory ql database.
+func qlSchema(dbs map[string]*sql.DB) Factory {
+	return func(name string) (darwin.Driver, func() error, error) {
+		db, err := sql.Open("ql-mem", name)
+		if err != nil {
+			return nil, nil, err
+		}
+		dbs[name] = db
+
+		driver, err := darwin.NewGenericDriver(db, darwin.QLDialect{})
+		return driver, db.Close, err
+	}
+}
+
+func Test_NewSchema(t *testing.T) {
+	dbs := map[string]*sql.DB{}
+
+	var names []string
+	t.Run("tests", func(t *testing.T) {
+		for _, name := range []string{"first", "second"} {
+			t.Run(name, func(t *testing.T) {
+				schema := NewSchema(t, qlSchema(dbs), migrations)
+				names = append(names, schema.Name)
+
+				var count int
+				var owner string
+				if err := dbs[schema.Name].QueryRow("SELECT count(), max(schema) FROM users").Scan(&count, &owner); err != nil {
+					t.Fatal(err)
+				}
+				if count != 1 || owner != schema.Name {
+					t.Errorf("Must migrate a schema of its own, got %d rows of %s", count, owner)
+				}
+			})
+		}
+	})
+
+	if len(names) != 2 || names[0] == names[1] || !strings.HasPrefix(names[0], "t_test_newschema_tests_first_") {
+		t.Errorf("Must name the schemas after their tests, got %v", names)
+	}
+
+	for _, name := range names {
+		if err := dbs[name].Ping(); err == nil {
+			t.Errorf("Must drop the schema %s after the test", name)
+		}
+	}
+}
+
+func Test_SchemaName(t *testing.T) {
+	name := SchemaName(t)
+	if !strings.HasPrefix(name, "t_test_schemaname_") || strings.ContainsAny(name, "/ -") {
+		t.Errorf("Must return a valid identifier, got %s", name)
+	}
+}
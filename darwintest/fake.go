@@ -0,0 +1,164 @@
+package darwintest
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// ErrInjected is the error returned by a FakeDriver failing without a given
+// error.
+var ErrInjected = errors.New("darwintest: injected failure")
+
+// Call is a call of a method of a FakeDriver.
+type Call struct {
+	Method string
+
+	// Script is the script of Exec, Version the version of Insert and
+	// Delete.
+	Script  string
+	Version float64
+}
+
+// FakeDriver is an in memory darwin.Driver, to unit test the code running
+// migrations without a database. It records its calls and fails the way
+// it is told to. A FakeDriver is safe for concurrent use.
+type FakeDriver struct {
+	mu       sync.Mutex
+	records  []darwin.MigrationRecord
+	calls    []Call
+	create   error
+	all      error
+	execs    map[string]error
+	inserts  map[float64]error
+	duration time.Duration
+}
+
+// NewFakeDriver returns a FakeDriver with the records in its history.
+func NewFakeDriver(records ...darwin.MigrationRecord) *FakeDriver {
+	return &FakeDriver{
+		records:  append([]darwin.MigrationRecord(nil), records...),
+		execs:    map[string]error{},
+		inserts:  map[float64]error{},
+		duration: time.Millisecond,
+	}
+}
+
+// orInjected returns err, or ErrInjected when it is nil.
+func orInjected(err error) error {
+	if err == nil {
+		return ErrInjected
+	}
+	return err
+}
+
+// FailCreate makes Create return err, ErrInjected when nil.
+func (f *FakeDriver) FailCreate(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.create = orInjected(err)
+}
+
+// FailAll makes All return err, ErrInjected when nil.
+func (f *FakeDriver) FailAll(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.all = orInjected(err)
+}
+
+// FailMigration makes the execution of the script of the migration return
+// err, ErrInjected when nil, so Migrate fails on its version.
+func (f *FakeDriver) FailMigration(m darwin.Migration, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.execs[m.Script] = orInjected(err)
+}
+
+// FailInsert makes Insert return err, ErrInjected when nil, for the record
+// of the version, leaving the migration executed but not recorded.
+func (f *FakeDriver) FailInsert(version float64, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inserts[version] = orInjected(err)
+}
+
+// Create implements the darwin.Driver interface.
+func (f *FakeDriver) Create() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, Call{Method: "Create"})
+	return f.create
+}
+
+// Insert implements the darwin.Driver interface.
+func (f *FakeDriver) Insert(e darwin.MigrationRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, Call{Method: "Insert", Version: e.Version})
+	if err := f.inserts[e.Version]; err != nil {
+		return err
+	}
+	f.records = append(f.records, e)
+	return nil
+}
+
+// All implements the darwin.Driver interface.
+func (f *FakeDriver) All() ([]darwin.MigrationRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, Call{Method: "All"})
+	if f.all != nil {
+		return nil, f.all
+	}
+	return append([]darwin.MigrationRecord(nil), f.records...), nil
+}
+
+// Exec implements the darwin.Driver interface, taking a millisecond.
+func (f *FakeDriver) Exec(script string) (time.Duration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, Call{Method: "Exec", Script: script})
+	return f.duration, f.execs[script]
+}
+
+// Delete implements the darwin.RecordDeleter interface.
+func (f *FakeDriver) Delete(version float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, Call{Method: "Delete", Version: version})
+	for i, r := range f.records {
+		if r.Version == version {
+			f.records = append(f.records[:i], f.records[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Records returns the records of the history, in the order they were
+// inserted.
+func (f *FakeDriver) Records() []darwin.MigrationRecord {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]darwin.MigrationRecord(nil), f.records...)
+}
+
+// Calls returns the calls of the methods of the driver, in order.
+func (f *FakeDriver) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// Executed returns the scripts executed, in order, the failed ones too.
+func (f *FakeDriver) Executed() []string {
+	var scripts []string
+	for _, c := range f.Calls() {
+		if c.Method == "Exec" {
+			scripts = append(scripts, c.Script)
+		}
+	}
+	return scripts
+}
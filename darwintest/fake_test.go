@@ -0,0 +1,67 @@
+package darwintest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dustinevan/darwin"
+)
+
+var fakeMigrations = []darwin.Migration{
+	{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+	{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	{Version: 3, Description: "Teams", Script: "CREATE TABLE teams (id INT);"},
+}
+
+func Test_FakeDriver(t *testing.T) {
+	driver := NewFakeDriver(darwin.MigrationRecord{Version: 1, Checksum: fakeMigrations[0].Checksum()})
+
+	failure := errors.New("syntax error")
+	driver.FailMigration(fakeMigrations[2], failure)
+
+	err := darwin.New(driver, fakeMigrations).Migrate()
+	if !errors.Is(err, failure) {
+		t.Errorf("Must fail on the migration, got %v", err)
+	}
+
+	if records := driver.Records(); len(records) != 2 || records[1].Version != 2 {
+		t.Errorf("Must record the migrations applied before the failure, got %#v", records)
+	}
+
+	if executed := driver.Executed(); !reflect.DeepEqual(executed, []string{fakeMigrations[1].Script, fakeMigrations[2].Script}) {
+		t.Errorf("Must record the scripts executed, got %v", executed)
+	}
+
+	if calls := driver.Calls(); calls[0].Method != "Create" {
+		t.Errorf("Must record the calls, got %#v", calls)
+	}
+}
+
+func Test_FakeDriver_FailInsert(t *testing.T) {
+	driver := NewFakeDriver()
+	driver.FailInsert(2, nil)
+
+	if err := darwin.New(driver, fakeMigrations).Migrate(); !errors.Is(err, ErrInjected) {
+		t.Errorf("Must fail on the insert, got %v", err)
+	}
+
+	if records := driver.Records(); len(records) != 1 || len(driver.Executed()) != 2 {
+		t.Errorf("Must execute the migration without recording it, got %#v", records)
+	}
+}
+
+func Test_FakeDriver_FailCreate(t *testing.T) {
+	driver := NewFakeDriver()
+	driver.FailCreate(nil)
+
+	if err := darwin.New(driver, fakeMigrations).Migrate(); err != ErrInjected {
+		t.Errorf("Must fail on create, got %v", err)
+	}
+
+	driver = NewFakeDriver()
+	driver.FailAll(nil)
+	if _, err := darwin.New(driver, fakeMigrations).Info(); err != ErrInjected {
+		t.Errorf("Must fail on all, got %v", err)
+	}
+}
@@ -0,0 +1,319 @@
+package darwin
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PlaceholderStyle is the way a database names the bind parameters of a
+// statement.
+type PlaceholderStyle int
+
+// Placeholder styles of the common databases.
+const (
+	// PlaceholderQuestion is "?", used by MySQL and SQLite.
+	PlaceholderQuestion PlaceholderStyle = iota
+
+	// PlaceholderDollar is "$1", used by PostgreSQL.
+	PlaceholderDollar
+
+	// PlaceholderColon is ":1", used by Oracle.
+	PlaceholderColon
+
+	// PlaceholderAtP is "@p1", used by SQL Server.
+	PlaceholderAtP
+)
+
+// Placeholder returns the bind parameter for the nth (1-based) argument.
+func (p PlaceholderStyle) Placeholder(n int) string {
+	switch p {
+	case PlaceholderDollar:
+		return fmt.Sprintf("$%d", n)
+	case PlaceholderColon:
+		return fmt.Sprintf(":%d", n)
+	case PlaceholderAtP:
+		return fmt.Sprintf("@p%d", n)
+	default:
+		return "?"
+	}
+}
+
+// StandardDialect is a Dialect built from the few things that differ between
+// SQL databases, so a custom driver doesn't have to write the SQL of the
+// history table:
+//
+//	dialect := darwin.StandardDialect{
+//		Placeholder: darwin.PlaceholderColon,
+//		FloatType:   "BINARY_DOUBLE",
+//		StringType:  "VARCHAR2(%d)",
+//		IntegerType: "NUMBER(19)",
+//	}
+//	driver, err := darwin.NewGenericDriver(db, dialect)
+//
+// Empty fields use the SQL standard defaults.
+type StandardDialect struct {
+	// Table is the name of the history table, darwin_migrations by default.
+	Table string
+
+	// Placeholder is the style of the bind parameters of InsertSQL.
+	Placeholder PlaceholderStyle
+
+	// FloatType is the type of the version, DOUBLE PRECISION by default.
+	FloatType string
+
+	// StringType is the type of the description and checksum, a format
+	// with a %d verb for the length or a type without length, VARCHAR(%d)
+	// by default.
+	StringType string
+
+	// IntegerType is the type of applied_at and execution_time, BIGINT by
+	// default.
+	IntegerType string
+
+	// TextType is the type of the scripts stored with WithStoredScripts,
+	// CLOB by default.
+	TextType string
+
+	// AddColumn is the clause of ALTER TABLE adding a column, ADD by
+	// default.
+	AddColumn string
+
+	// NoIfNotExists is set for databases which don't understand CREATE TABLE
+	// IF NOT EXISTS; the driver must then ignore the error of an existing
+	// table.
+	NoIfNotExists bool
+
+	// NoTransactionalDDL is set for databases committing DDL implicitly, like
+	// Oracle; the generic driver then executes the migrations one statement
+	// at a time, with checkpoints.
+	NoTransactionalDDL bool
+
+	// StatementSyntax is the syntax used to split the scripts into their
+	// statements, the SQL standard by default.
+	StatementSyntax Syntax
+}
+
+func (s StandardDialect) table() string {
+	if s.Table == "" {
+		return defaultTable
+	}
+	return s.Table
+}
+
+func (s StandardDialect) stringType(length int) string {
+	if s.StringType == "" {
+		return fmt.Sprintf("VARCHAR(%d)", length)
+	}
+	if !strings.Contains(s.StringType, "%") {
+		return s.StringType
+	}
+	return fmt.Sprintf(s.StringType, length)
+}
+
+func (s StandardDialect) textType() string {
+	if s.TextType == "" {
+		return "CLOB"
+	}
+	return s.TextType
+}
+
+// CreateTableSQL returns the SQL to create the schema table.
+func (s StandardDialect) CreateTableSQL() string {
+	floatType, integerType := s.FloatType, s.IntegerType
+	if floatType == "" {
+		floatType = "DOUBLE PRECISION"
+	}
+	if integerType == "" {
+		integerType = "BIGINT"
+	}
+
+	ifNotExists := " IF NOT EXISTS"
+	if s.NoIfNotExists {
+		ifNotExists = ""
+	}
+
+	return fmt.Sprintf(`CREATE TABLE%s %s
+                (
+                    version        %s NOT NULL,
+                    description    %s NOT NULL,
+                    checksum       %s NOT NULL,
+                    applied_at     %s NOT NULL,
+                    execution_time %s NOT NULL,
+                    applied_by     %s,
+                    hostname       %s,
+                    app_version    %s,
+                    source         %s,
+                    script         %s,
+                    PRIMARY KEY    (version)
+                )`, ifNotExists, s.table(), floatType, s.stringType(255), s.stringType(32), integerType, integerType,
+		s.stringType(255), s.stringType(255), s.stringType(255), s.stringType(255), s.textType())
+}
+
+// InsertSQL returns the SQL to insert a new migration in the schema table.
+func (s StandardDialect) InsertSQL() string {
+	placeholders := make([]string, 5)
+	for i := range placeholders {
+		placeholders[i] = s.Placeholder.Placeholder(i + 1)
+	}
+
+	return fmt.Sprintf(`INSERT INTO %s
+                (
+                    version,
+                    description,
+                    checksum,
+                    applied_at,
+                    execution_time
+                )
+            VALUES (%s)`, s.table(), strings.Join(placeholders, ", "))
+}
+
+// AllSQL returns a SQL to get all entries in the table.
+func (s StandardDialect) AllSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time
+            FROM
+                %s
+            ORDER BY version ASC`, s.table())
+}
+
+// InsertMetadataSQL returns the SQL to insert a new migration in the schema
+// table with its metadata.
+func (s StandardDialect) InsertMetadataSQL() string {
+	placeholders := make([]string, 10)
+	for i := range placeholders {
+		placeholders[i] = s.Placeholder.Placeholder(i + 1)
+	}
+
+	return fmt.Sprintf(`INSERT INTO %s
+                (
+                    version,
+                    description,
+                    checksum,
+                    applied_at,
+                    execution_time,
+                    applied_by,
+                    hostname,
+                    app_version,
+                    source,
+                    script
+                )
+            VALUES (%s)`, s.table(), strings.Join(placeholders, ", "))
+}
+
+// AllMetadataSQL returns a SQL to get all entries in the table with their
+// metadata.
+func (s StandardDialect) AllMetadataSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time,
+                applied_by,
+                hostname,
+                app_version,
+                source
+            FROM
+                %s
+            ORDER BY version ASC`, s.table())
+}
+
+// AllSinceSQL returns a SQL to get the entries in the table from a version
+// on, with their metadata.
+func (s StandardDialect) AllSinceSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time,
+                applied_by,
+                hostname,
+                app_version,
+                source
+            FROM
+                %s
+            WHERE
+                version >= %s
+            ORDER BY version ASC`, s.table(), s.Placeholder.Placeholder(1))
+}
+
+// LatestVersionSQL returns a SQL to get the greatest version in the table,
+// NULL when it is empty.
+func (s StandardDialect) LatestVersionSQL() string {
+	return fmt.Sprintf("SELECT MAX(version) FROM %s", s.table())
+}
+
+// HistoryUpgrades returns the upgrades of the schema table to its latest
+// layout.
+func (s StandardDialect) HistoryUpgrades() []HistoryUpgrade {
+	add := s.AddColumn
+	if add == "" {
+		add = "ADD"
+	}
+	return historyUpgrades(s.table(), add, s.stringType(255), s.textType(), "1 = 0")
+}
+
+// ScriptSQL returns the SQL to get the script stored with a migration.
+func (s StandardDialect) ScriptSQL() string {
+	return fmt.Sprintf(`SELECT script FROM %s WHERE version = %s`, s.table(), s.Placeholder.Placeholder(1))
+}
+
+// DeleteSQL returns the SQL to delete the record of a migration.
+func (s StandardDialect) DeleteSQL() string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE version = %s`, s.table(), s.Placeholder.Placeholder(1))
+}
+
+// SupportsTransactionalDDL reports whether the database rolls back schema
+// changes.
+func (s StandardDialect) SupportsTransactionalDDL() bool {
+	return !s.NoTransactionalDDL
+}
+
+// Syntax returns the syntax of the statements.
+func (s StandardDialect) Syntax() Syntax {
+	return s.StatementSyntax
+}
+
+// CreateCheckpointTableSQL returns the SQL to create the checkpoint table,
+// named after the history table with a _statements suffix.
+func (s StandardDialect) CreateCheckpointTableSQL() string {
+	integerType := s.IntegerType
+	if integerType == "" {
+		integerType = "BIGINT"
+	}
+
+	ifNotExists := " IF NOT EXISTS"
+	if s.NoIfNotExists {
+		ifNotExists = ""
+	}
+
+	return fmt.Sprintf(`CREATE TABLE%s %s_statements
+                (
+                    statement   %s NOT NULL,
+                    checksum    %s NOT NULL,
+                    applied_at  %s NOT NULL,
+                    PRIMARY KEY (statement, checksum)
+                )`, ifNotExists, s.table(), integerType, s.stringType(32), integerType)
+}
+
+// InsertCheckpointSQL returns the SQL to record an executed statement.
+func (s StandardDialect) InsertCheckpointSQL() string {
+	return fmt.Sprintf(`INSERT INTO %s_statements (statement, checksum, applied_at) VALUES (%s, %s, %s)`,
+		s.table(), s.Placeholder.Placeholder(1), s.Placeholder.Placeholder(2), s.Placeholder.Placeholder(3))
+}
+
+// CheckpointsSQL returns the SQL to get the statements already executed.
+func (s StandardDialect) CheckpointsSQL() string {
+	return fmt.Sprintf(`SELECT statement, checksum FROM %s_statements`, s.table())
+}
+
+// DeleteCheckpointsSQL returns the SQL to delete the checkpoints once a
+// migration is recorded.
+func (s StandardDialect) DeleteCheckpointsSQL() string {
+	return fmt.Sprintf(`DELETE FROM %s_statements`, s.table())
+}
@@ -0,0 +1,65 @@
+package darwin
+
+import (
+	"bufio"
+	"strings"
+)
+
+// DirectivePrefix starts a script line holding an instruction to darwin
+// instead of SQL, for example "-- darwin:copy users.csv INTO users".
+const DirectivePrefix = "-- darwin:"
+
+// Directive is an instruction to darwin embedded in a migration script.
+type Directive struct {
+	Name string
+	Args string
+
+	// Line is the 1-based line number of the directive in the script.
+	Line int
+}
+
+// Directives returns all directives found in the script, in order.
+func Directives(script string) []Directive {
+	var directives []Directive
+
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), len(script)+1)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		if d, ok := parseDirective(scanner.Text()); ok {
+			d.Line = line
+			directives = append(directives, d)
+		}
+	}
+
+	return directives
+}
+
+// hasDirective reports if the script has a directive named name.
+func hasDirective(script string, name string) bool {
+	for _, d := range Directives(script) {
+		if d.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDirective reports if the line is a directive and returns it.
+func parseDirective(line string) (Directive, bool) {
+	line = strings.TrimSpace(line)
+	if len(line) < len(DirectivePrefix) || !strings.EqualFold(line[:len(DirectivePrefix)], DirectivePrefix) {
+		return Directive{}, false
+	}
+
+	rest := line[len(DirectivePrefix):]
+	name, args := rest, ""
+	if i := strings.IndexAny(rest, " \t"); i >= 0 {
+		name, args = rest[:i], strings.TrimSpace(rest[i:])
+	}
+
+	return Directive{Name: strings.ToLower(name), Args: args}, true
+}
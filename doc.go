@@ -76,5 +76,165 @@ You can write this code:
 			log.Println(err)
 		}
 	}
+
+Migrations can also be read from the .sql files of a directory with an
+FSSource. Large data sets don't need to be written as INSERT statements, a
+migration can reference a data file next to it that drivers implementing
+DataLoader stream into a table:
+
+	-- Version: 1.4
+	-- Description: Load countries
+	-- darwin:copy data/countries.csv INTO countries
+
+Only the pgx and mysql drivers are DataLoaders; with another driver,
+Migrate refuses a plan holding a copy directive before executing any of its
+migrations.
+
+A Lazy FSSource keeps only the checksums of the scripts in memory, hashed
+as the files are read, and reads a script again when its migration is
+executed. With a ChecksumCache, the files unchanged since they were read
+aren't read again. The script of a migration
+with the stream directive is never read in memory, drivers implementing
+ExecReader, like the generic driver, execute its statements as they read
+them, outside of any transaction:
+
+	-- Version: 1.6
+	-- Description: Seed the catalog
+	-- darwin:stream
+
+Drivers implementing Transactor, like the generic driver, execute each
+migration and record it in the same transaction. Statements which can't run
+in a transaction need the no-transaction directive:
+
+	-- Version: 1.5
+	-- Description: Index users by email
+	-- darwin:no-transaction
+	CREATE INDEX CONCURRENTLY users_email ON users (email);
+
+The statements of such a migration are executed one at a time. Scripts are
+split with SplitStatements, which follows the quoting rules of the dialect,
+so the dollar quoted body of a PL/pgSQL function stays in its statement.
+The directive can be left out for the statements Postgres refuses to run in
+a transaction, like CREATE INDEX CONCURRENTLY or VACUUM, which are detected.
+The generic driver then waits for the indexes created concurrently to be
+valid before recording the migration; an index left invalid is reported
+with an InvalidIndexError and must be dropped before trying again.
+
+Session settings, like lock and statement timeouts, are given with set
+directives. The generic driver applies them while the migration runs with
+the postgres and mysql dialects, and restores them after:
+
+	-- Version: 1.6
+	-- Description: Add users.active
+	-- darwin:set lock_timeout = '5s'
+	ALTER TABLE users ADD COLUMN active BOOL;
+
+Databases like MySQL and Oracle commit DDL implicitly, so a failed migration
+can't be rolled back. Their dialects report it with SupportsTransactionalDDL
+and the generic driver then executes the statements one at a time, recording
+a checkpoint after each of them: once the failed statement is fixed, the next
+run resumes the migration where it stopped.
+
+MigrateReport returns a Report of the migrations executed. Drivers
+implementing StatementExecer, like the generic driver in its transactions
+and the mysql driver, report the rows affected by each statement and how
+long it took, also given to the hook of WithStatementHook.
+
+Migrate is silent unless given a Logger with WithLogger, a *slog.Logger for
+example. It then logs the plan, each migration with its duration and the
+outcome, with the error of a failure. WithTracer similarly starts a span
+for Migrate, Validate, Info and each migration, through a Tracer adapting
+OpenTelemetry or any other tracing library. WithMetrics counts the applied
+and failed migrations, the pending ones and their durations; the darwinprom
+package exports them to Prometheus. LastRun returns the summary of the last
+run, which Publish exposes as an expvar variable. MigrateWithProgress
+streams the events of a long run, from the plan to each migration
+finished, for live progress in CLIs and deploy tools. WithHeartbeat calls a
+function periodically while a migration executes, and refreshes a lock
+which expires, so a long backfill isn't mistaken for a hung applier.
+WithNotifier announces the report of each run, for example in a Slack
+channel with the notify package. WithAudit gives an AuditRecord of each run,
+with who ran it, the environment, the plan and the outcomes, to an
+AuditSink such as the JSON lines file of OpenAuditFile.
+
+WithBackup calls a function taking a backup of the database, with pg_dump
+or the snapshots of a provider, before the migrations of a plan are
+executed, so each schema change has a recovery point; a failing backup
+stops Migrate. Nothing is backed up when no migration is pending.
+
+The history tells who and what applied each migration: the generic driver
+stores the RecordMetadata of the records, the user and host applying them
+by default, and the application version and source given with
+WithRecordMetadata or WithMetadataFunc. With WithStoredScripts, it stores
+the script of each migration as well, compressed or not, so AppliedScript
+recovers the SQL run even if the repository was rewritten.
+
+The layout of the history table changes as darwin records more: Create
+upgrades the tables of earlier versions with the HistoryUpgrades of their
+UpgradeDialect, adding the missing columns without losing the history.
+
+With a driver implementing HistorySince, Migrate reads only the newest
+records of a long history, those of the last hundred migrations applied,
+and validates and plans the migrations with them; Validate still checks the
+whole history.
+
+To debug a dialect, WithSQLEcho logs each statement executed. The
+passwords of statements like CREATE USER are masked, as are the secrets
+matched by the given redactors.
+
+Down reverts the latest migrations with down migrations, like the
+.down.sql files FSSource reads with DownMigrations, and deletes their
+records. It requires a driver implementing RecordDeleter, as the generic
+driver does with the dialects of this package and of the drivers, and
+fails before executing anything when the dialect can't delete records.
+
+The Lint method of FSSource checks the migration files without a database,
+for CI, reporting each Finding with its file, line and rule, and Lint the
+migrations of other sources. The DefaultLintRules catch the statements
+losing data, like DROP TABLE, the columns narrowed, and the indexes of
+large tables created without CONCURRENTLY; projects add their rules.
+
+WithPolicy enforces lint rules at Migrate time, in all the environments or
+in those given to WithEnvironment, like DestructiveRule in production or
+TicketReference everywhere: a plan violating them fails with a
+PolicyError before any migration is executed.
+
+Squash replaces the oldest migrations with a baseline, a single migration
+of their scripts, and SquashHistory their records with the record of the
+baseline, to keep the bootstrap of new databases short. ArchiveHistory
+writes the old records of a large history out and deletes them, keeping
+the latest as a baseline marker.
+
+Migrate holds the lock of drivers implementing Locker while it plans and
+executes the migrations, so replicas of an application starting together
+don't run the same migration twice. The generic driver takes an advisory
+lock with the postgres and mysql dialects.
+
+WithTableName keeps the history in another table than darwin_migrations,
+qualified by its schema or not, like "ops.schema_migrations", so several
+applications can share a database. The generic driver quotes the name as
+its dialect requires, and the advisory lock follows the table.
+
+Test suites reusing a database call MigrateCached, which compares the
+ManifestHash of the migrations, a hash of their versions and checksums, with
+the one of the history, and only resets and migrates the database when they
+differ. The darwintest package gives each test a schema of its own instead,
+migrated and dropped with the test.
+
+In a monorepo, the services sharing a database declare their migrations as
+Components, each with its own history table; MigrateAll applies them in
+order and stops at the first component failing.
+
+Seed data stays out of the versions of the schema: WithSeeds applies each
+Seed after the migrations, with its own versions and history in the
+darwin_seeds table, in the environments it is tagged with, once the schema
+version it requires is applied. A seed whose script changes is executed
+again, and Seed applies the seeds alone.
+
+Code selecting the database from its configuration can look the dialect up by
+name. The dialects of this package are registered as mysql, postgres, ql and
+sqlite3, other packages can add theirs with RegisterDialect:
+
+	driver, err := darwin.OpenDriver(cfg.Dialect, db)
 */
 package darwin
@@ -0,0 +1,228 @@
+package darwin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// RecordDeleter is implemented by drivers able to delete the record of a
+// migration from the history, which Down requires.
+type RecordDeleter interface {
+	Delete(version float64) error
+}
+
+// DeleteDialect is implemented by the dialects with the SQL deleting the
+// record of a migration, the version being its only argument.
+type DeleteDialect interface {
+	DeleteSQL() string
+}
+
+// DeleteChecker is implemented by RecordDeleters able to delete records in
+// some configurations only, like the generic driver which needs a
+// DeleteDialect. Down, Squash, ArchiveHistory and the seeds check it before
+// changing anything.
+type DeleteChecker interface {
+	CanDelete() bool
+}
+
+// recordDeleter returns d as a RecordDeleter, or an error when it can't
+// delete migration records.
+func recordDeleter(d Driver) (RecordDeleter, error) {
+	deleter, ok := d.(RecordDeleter)
+	if !ok {
+		return nil, errors.New("darwin: the driver can't delete migration records")
+	}
+
+	if c, ok := d.(DeleteChecker); ok && !c.CanDelete() {
+		return nil, errors.New("darwin: the dialect can't delete migration records")
+	}
+
+	return deleter, nil
+}
+
+// CanDelete reports whether the dialect is a DeleteDialect.
+func (m *GenericDriver) CanDelete() bool {
+	_, ok := m.Dialect.(DeleteDialect)
+	return ok
+}
+
+// Delete deletes the record of the migration version.
+func (m *GenericDriver) Delete(version float64) error {
+	dd, ok := m.Dialect.(DeleteDialect)
+	if !ok {
+		return errors.New("darwin: the dialect can't delete migration records")
+	}
+
+	return transaction(m.DB, func(tx *sql.Tx) error {
+		_, err := tx.Exec(dd.DeleteSQL(), version)
+		return err
+	})
+}
+
+// MissingDownError is used to report an applied migration to revert without
+// a down migration.
+type MissingDownError struct {
+	Version float64
+}
+
+func (m MissingDownError) Error() string {
+	return fmt.Sprintf("darwin: no down migration reverts migration %f", m.Version)
+}
+
+// DownTarget returns the version the database is at once its latest steps
+// applied migrations are reverted, -1 when none is left.
+func DownTarget(d Driver, steps int) (float64, error) {
+	records, err := d.All()
+	if err != nil {
+		return 0, err
+	}
+
+	sort.Sort(sort.Reverse(byMigrationRecordVersion(records)))
+
+	if steps < len(records) {
+		return records[steps].Version, nil
+	}
+	return -1, nil
+}
+
+// PlanDown returns the down migrations reverting the applied migrations
+// newer than the version to, newest first. downs are the down migrations,
+// each with the version of the migration it reverts.
+func PlanDown(d Driver, downs []Migration, to float64) ([]Migration, error) {
+	records, err := d.All()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(sort.Reverse(byMigrationRecordVersion(records)))
+
+	byVersion := map[float64]Migration{}
+	for _, m := range downs {
+		byVersion[m.Version] = m
+	}
+
+	var planned []Migration
+	for _, r := range records {
+		if r.Version <= to {
+			break
+		}
+
+		m, ok := byVersion[r.Version]
+		if !ok {
+			return nil, MissingDownError{Version: r.Version}
+		}
+		if m.Description == "" {
+			m.Description = r.Description
+		}
+		planned = append(planned, m)
+	}
+
+	return planned, nil
+}
+
+// PlanDown returns the down migrations Down would execute.
+func (d Darwin) PlanDown(downs []Migration, to float64) ([]Migration, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	return PlanDown(d.driver, downs, to)
+}
+
+// DownTarget returns the version the database is at once its latest steps
+// applied migrations are reverted.
+func (d Darwin) DownTarget(steps int) (float64, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	return DownTarget(d.driver, steps)
+}
+
+// Down reverts the applied migrations newer than the version to, newest
+// first, holding the migration lock like Migrate. The history must be valid
+// against the migrations of d. Each down migration is executed, then the
+// record of the migration it reverts is deleted, which requires a
+// RecordDeleter driver; both happen in a transaction when the driver is a
+// Transactor whose transactions are TxDeleters, as for Migrate. Nothing is
+// executed when a migration to revert has no down migration. The report
+// holds the down migrations executed before a failure.
+func (d Darwin) Down(ctx context.Context, downs []Migration, to float64) (report Report, err error) {
+	if d.err != nil {
+		return report, d.err
+	}
+
+	deleter, err := recordDeleter(d.driver)
+	if err != nil {
+		return report, err
+	}
+
+	ctx, span := d.startSpan(ctx, "darwin.Down")
+	defer func() {
+		span.SetAttribute("darwin.reverted", len(report.Results))
+		endSpan(span, err)
+	}()
+
+	if cd, ok := d.driver.(ContextDriver); ok {
+		if err := cd.Ping(ctx); err != nil {
+			return report, err
+		}
+	}
+
+	_, release, err := d.acquire(ctx)
+	if err != nil {
+		return report, err
+	}
+	defer func() {
+		if rerr := release(); err == nil {
+			err = rerr
+		}
+	}()
+
+	if err := d.validate(ctx); err != nil {
+		return report, err
+	}
+
+	planned, err := PlanDown(d.driver, downs, to)
+	if err != nil {
+		return report, err
+	}
+
+	for _, m := range planned {
+		d.logger().Info("darwin: migration reverting", "version", m.Version, "description", m.Description)
+
+		r, err := d.revert(d.observe(ctx, m, nil), deleter, m)
+		if err != nil {
+			return report, err
+		}
+
+		report.Results = append(report.Results, r)
+		d.echoResult(r)
+		d.logger().Info("darwin: migration reverted", "version", m.Version, "duration", r.Duration)
+	}
+
+	return report, nil
+}
+
+// revert executes the down migration and deletes the record of the
+// migration it reverts, in a transaction when the driver and the migration
+// allow it.
+func (d Darwin) revert(ctx context.Context, deleter RecordDeleter, m Migration) (Result, error) {
+	if transactional(m) {
+		r, err := revertInTx(ctx, d.driver, m)
+		if err == nil {
+			return r, nil
+		}
+		if err != ErrTransactionUnsupported {
+			return r, executionError(m, err)
+		}
+	}
+
+	r, err := execMigration(ctx, d.driver, m)
+	if err != nil {
+		return r, executionError(m, err)
+	}
+
+	return r, deleter.Delete(m.Version)
+}
@@ -0,0 +1,184 @@
+package darwin
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type downDriver struct {
+	dummyDriver
+	executed []string
+}
+
+func (d *downDriver) Exec(script string) (time.Duration, error) {
+	d.executed = append(d.executed, script)
+	return d.dummyDriver.Exec(script)
+}
+
+// txDownDriver is a txDriver able to delete records.
+type txDownDriver struct {
+	txDriver
+}
+
+func (d *txDownDriver) Delete(version float64) error {
+	d.calls = append(d.calls, "delete")
+	return nil
+}
+
+func (d *downDriver) Delete(version float64) error {
+	for i, r := range d.records {
+		if r.Version == version {
+			d.records = append(d.records[:i], d.records[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func appliedMigrations() []Migration {
+	return []Migration{
+		{Version: 1, Description: "First", Script: "CREATE TABLE one (id INT);"},
+		{Version: 2, Description: "Second", Script: "CREATE TABLE two (id INT);"},
+		{Version: 3, Description: "Third", Script: "CREATE TABLE three (id INT);"},
+	}
+}
+
+func appliedRecords() []MigrationRecord {
+	var records []MigrationRecord
+	for _, m := range appliedMigrations() {
+		records = append(records, MigrationRecord{Version: m.Version, Description: m.Description, Checksum: m.Checksum()})
+	}
+	return records
+}
+
+func Test_Darwin_Down(t *testing.T) {
+	driver := &downDriver{dummyDriver: dummyDriver{records: appliedRecords()}}
+	downs := []Migration{
+		{Version: 2, Script: "DROP TABLE two;"},
+		{Version: 3, Script: "DROP TABLE three;"},
+	}
+
+	report, err := New(driver, appliedMigrations()).Down(context.Background(), downs, 1)
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(driver.executed) != 2 || driver.executed[0] != "DROP TABLE three;" || driver.executed[1] != "DROP TABLE two;" {
+		t.Errorf("Must revert the newest migrations first, got %q", driver.executed)
+	}
+
+	if len(report.Results) != 2 || report.Results[1].Migration.Description != "Second" {
+		t.Errorf("Must report the reverted migrations, got %#v", report.Results)
+	}
+
+	if len(driver.records) != 1 || driver.records[0].Version != 1 {
+		t.Errorf("Must delete the records of the reverted migrations, got %#v", driver.records)
+	}
+}
+
+func Test_Darwin_Down_missing(t *testing.T) {
+	driver := &downDriver{dummyDriver: dummyDriver{records: appliedRecords()}}
+	downs := []Migration{{Version: 3, Script: "DROP TABLE three;"}}
+
+	_, err := New(driver, appliedMigrations()).Down(context.Background(), downs, 1)
+	if err != (MissingDownError{Version: 2}) {
+		t.Errorf("Must emit MissingDownError, got %v", err)
+	}
+
+	if len(driver.executed) != 0 {
+		t.Errorf("Must not execute anything, got %q", driver.executed)
+	}
+}
+
+func Test_Darwin_Down_transaction(t *testing.T) {
+	driver := &txDownDriver{txDriver: txDriver{dummyDriver: dummyDriver{records: appliedRecords()}}}
+	downs := []Migration{{Version: 3, Script: "DROP TABLE three;"}}
+
+	if _, err := New(driver, appliedMigrations()).Down(context.Background(), downs, 2); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if fmt.Sprint(driver.calls) != "[begin tx.exec tx.delete commit]" || len(driver.records) != 2 {
+		t.Errorf("Must revert the migration and delete its record in a transaction, got %v and %#v", driver.calls, driver.records)
+	}
+}
+
+func Test_Darwin_Down_invalid(t *testing.T) {
+	driver := &downDriver{dummyDriver: dummyDriver{records: appliedRecords()}}
+	downs := []Migration{{Version: 3, Script: "DROP TABLE three;"}}
+
+	migrations := appliedMigrations()
+	migrations[2].Script = "CREATE TABLE edited (id INT);"
+
+	if _, err := New(driver, migrations).Down(context.Background(), downs, 2); err != (InvalidChecksumError{Version: 3}) {
+		t.Errorf("Must validate the history before reverting, got %v", err)
+	}
+
+	if len(driver.executed) != 0 {
+		t.Errorf("Must not execute anything, got %q", driver.executed)
+	}
+}
+
+func Test_Darwin_Down_unsupported(t *testing.T) {
+	if _, err := New(&dummyDriver{}, nil).Down(context.Background(), nil, 0); err == nil {
+		t.Error("Must emit error when the driver can't delete records")
+	}
+}
+
+func Test_Darwin_Down_dialect_unsupported(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, _ := NewGenericDriver(db, plainDialect{})
+
+	downs := []Migration{{Version: 3, Script: "DROP TABLE three;"}}
+	if _, err := New(d, appliedMigrations()).Down(context.Background(), downs, 2); err == nil {
+		t.Error("Must emit error when the dialect can't delete records")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Must not query the database, got %s", err)
+	}
+}
+
+func Test_DownTarget(t *testing.T) {
+	d := &dummyDriver{records: appliedRecords()}
+
+	if to, err := DownTarget(d, 1); err != nil || to != 2 {
+		t.Errorf("Must return the version below the reverted steps, got %v %v", to, err)
+	}
+
+	if to, err := DownTarget(d, 5); err != nil || to != -1 {
+		t.Errorf("Must return -1 when every migration is reverted, got %v %v", to, err)
+	}
+}
+
+func Test_GenericDriver_Delete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	dialect := PostgresDialect{}
+	d, _ := NewGenericDriver(db, dialect)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(escapeQuery(dialect.DeleteSQL())).WithArgs(2.0).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := d.Delete(2); err != nil {
+		t.Errorf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
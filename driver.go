@@ -1,6 +1,7 @@
 package darwin
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -8,6 +9,8 @@ import (
 )
 
 // Dialect is used to support multiple databases by returning proper SQL.
+// StandardDialect builds one from the column types and the placeholder style
+// of a database.
 type Dialect interface {
 	CreateTableSQL() string
 	InsertSQL() string
@@ -22,6 +25,56 @@ type Driver interface {
 	Exec(string) (time.Duration, error)
 }
 
+// LockDialect is implemented by the dialects of databases with advisory
+// locks. The statements run on the same connection, held while the lock is.
+type LockDialect interface {
+	LockSQL() string
+	UnlockSQL() string
+}
+
+// Locker is implemented by drivers able to hold a lock excluding the other
+// appliers. Migrate holds it for the whole run, so replicas of an
+// application starting together don't execute the same migration twice.
+type Locker interface {
+	Lock() error
+	Unlock() error
+}
+
+// ErrLockTimeout is returned by the Lock method of the GenericDriver when the
+// lock is held by another applier for longer than the lock timeout.
+var ErrLockTimeout = errors.New("darwin: timeout waiting for the migration lock")
+
+// LockHolder describes the applier holding a lock.
+type LockHolder struct {
+	Owner      string
+	Hostname   string
+	PID        int
+	AcquiredAt time.Time
+}
+
+// LockBreaker is implemented by Lockers whose lock can outlive the applier
+// holding it, like a row in a table. BreakLock releases the lock whoever
+// holds it, to recover from an applier which crashed.
+type LockBreaker interface {
+	Locker
+	LockHolder() (LockHolder, bool, error)
+	BreakLock() error
+}
+
+// ContextDriver is a Driver accepting a context, able to check its
+// connection and to release its resources. Drivers implementing it are
+// pinged before the migrations are planned and execute them with
+// ExecContext.
+//
+// A driver overriding the Exec method of an embedded GenericDriver must
+// override ExecContext as well.
+type ContextDriver interface {
+	Driver
+	ExecContext(ctx context.Context, script string) (time.Duration, error)
+	Ping(ctx context.Context) error
+	Close() error
+}
+
 // MigrationRecord is the entry in schema table.
 type MigrationRecord struct {
 	Version       float64
@@ -29,12 +82,34 @@ type MigrationRecord struct {
 	Checksum      string
 	AppliedAt     time.Time
 	ExecutionTime time.Duration
+
+	// RecordMetadata is stored by the drivers able to, like the generic
+	// driver with a MetadataDialect.
+	RecordMetadata
+
+	// Script is the script applied, stored with WithStoredScripts, gzipped
+	// and encoded in base64 after a "gzip:" prefix when compressed. All
+	// leaves it empty, a ScriptReader reads it.
+	Script string
 }
 
 // GenericDriver is the default Driver, it can be configured to any database.
 type GenericDriver struct {
 	DB      *sql.DB
 	Dialect Dialect
+
+	// LockTimeout is how long Lock waits for the lock, forever when zero.
+	LockTimeout time.Duration
+
+	// IndexTimeout is how long Exec waits for an index created concurrently
+	// to become valid, a minute when zero.
+	IndexTimeout time.Duration
+
+	lockConn *sql.Conn
+
+	// metadata is set once the history table is known to have the metadata
+	// columns.
+	metadata bool
 }
 
 // NewGenericDriver creates a new GenericDriver configured with db and dialect.
@@ -44,7 +119,7 @@ func NewGenericDriver(db *sql.DB, dialect Dialect) (*GenericDriver, error) {
 	}
 
 	if dialect == nil {
-		return nil, errors.New("darwin: sql.DB is nil")
+		return nil, errors.New("darwin: dialect is nil")
 	}
 
 	return &GenericDriver{DB: db, Dialect: dialect}, nil
@@ -53,34 +128,98 @@ func NewGenericDriver(db *sql.DB, dialect Dialect) (*GenericDriver, error) {
 // Create create the table darwin_migrations if necessary.
 func (m *GenericDriver) Create() error {
 	f := func(tx *sql.Tx) error {
-		_, err := tx.Exec(m.Dialect.CreateTableSQL())
+		if _, err := tx.Exec(m.Dialect.CreateTableSQL()); err != nil {
+			return err
+		}
+		if c, ok := m.checkpointDialect(); ok {
+			_, err := tx.Exec(c.CreateCheckpointTableSQL())
+			return err
+		}
+		return nil
+	}
+	if err := transaction(m.DB, f); err != nil {
 		return err
 	}
-	return transaction(m.DB, f)
+
+	if u, ok := m.Dialect.(UpgradeDialect); ok {
+		return m.upgrade(u.HistoryUpgrades())
+	}
+	return nil
 }
 
-// Insert insert a migration entry into database.
+// HistoryExists reports whether the history table exists, without creating
+// nor upgrading it, for the commands which only read the history.
+func (m *GenericDriver) HistoryExists() (bool, error) {
+	if err := m.DB.Ping(); err != nil {
+		return false, err
+	}
+
+	query := m.Dialect.AllSQL()
+	if sd, ok := m.Dialect.(SinceDialect); ok {
+		query = sd.LatestVersionSQL()
+	}
+	return m.probe(query), nil
+}
+
+// Insert insert a migration entry into database, with its metadata if the
+// dialect is a MetadataDialect, and deletes the checkpoints of its
+// statements.
 func (m *GenericDriver) Insert(e MigrationRecord) error {
+	query, args := InsertArgs(m.Dialect, e)
 	f := func(tx *sql.Tx) error {
-		_, err := tx.Exec(m.Dialect.InsertSQL(),
-			e.Version,
-			e.Description,
-			e.Checksum,
-			e.AppliedAt.Unix(),
-			e.ExecutionTime,
-		)
+		_, err := tx.Exec(query, args...)
+		if err != nil {
+			return err
+		}
+		if c, ok := m.checkpointDialect(); ok {
+			_, err = tx.Exec(c.DeleteCheckpointsSQL())
+		}
 		return err
 	}
 	return transaction(m.DB, f)
 }
 
-// All returns all migrations applied.
+// All returns all migrations applied, with their metadata if the dialect is
+// a MetadataDialect and the history table has them.
 func (m *GenericDriver) All() ([]MigrationRecord, error) {
-	rows, err := m.DB.Query(m.Dialect.AllSQL())
+	query, metadata := m.Dialect.AllSQL(), m.hasMetadata()
+	if metadata {
+		query = m.Dialect.(MetadataDialect).AllMetadataSQL()
+	}
+
+	rows, err := m.DB.Query(query)
 	if err != nil {
 		return []MigrationRecord{}, err
 	}
 
+	return scanRecords(rows, metadata)
+}
+
+// hasMetadata reports whether the history table has the metadata columns
+// of a MetadataDialect, probing the tables of an UpgradeDialect for the
+// columns of its first upgrade until they have them. A failing probe means
+// a table of the first layout, read without metadata, the errors of the
+// database being returned by the query reading it.
+func (m *GenericDriver) hasMetadata() bool {
+	if _, ok := m.Dialect.(MetadataDialect); !ok {
+		return false
+	}
+
+	u, ok := m.Dialect.(UpgradeDialect)
+	if !ok || m.metadata {
+		return true
+	}
+
+	upgrades := u.HistoryUpgrades()
+	m.metadata = len(upgrades) == 0 || m.probe(upgrades[0].Probe)
+	return m.metadata
+}
+
+// scanRecords returns the records of the rows, with their metadata or not,
+// and closes them.
+func scanRecords(rows *sql.Rows, metadata bool) ([]MigrationRecord, error) {
+	defer rows.Close()
+
 	var entries []MigrationRecord
 	for rows.Next() {
 		var (
@@ -89,15 +228,23 @@ func (m *GenericDriver) All() ([]MigrationRecord, error) {
 			checksum      string
 			appliedAt     int64
 			executionTime float64
+
+			appliedBy, hostname, appVersion, source sql.NullString
 		)
 
-		rows.Scan(
+		dest := []interface{}{
 			&version,
 			&description,
 			&checksum,
 			&appliedAt,
 			&executionTime,
-		)
+		}
+		if metadata {
+			dest = append(dest, &appliedBy, &hostname, &appVersion, &source)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
 
 		entry := MigrationRecord{
 			Version:       version,
@@ -105,36 +252,154 @@ func (m *GenericDriver) All() ([]MigrationRecord, error) {
 			Checksum:      checksum,
 			AppliedAt:     time.Unix(appliedAt, 0),
 			ExecutionTime: time.Duration(executionTime),
+			RecordMetadata: RecordMetadata{
+				AppliedBy:  appliedBy.String,
+				Hostname:   hostname.String,
+				AppVersion: appVersion.String,
+				Source:     source.String,
+			},
 		}
 
 		entries = append(entries, entry)
 	}
 
-	rows.Close()
-
-	return entries, nil
+	return entries, rows.Err()
 }
 
 // Exec execute sql scripts into database.
 func (m *GenericDriver) Exec(script string) (time.Duration, error) {
+	return m.ExecContext(context.Background(), script)
+}
+
+// ExecContext executes the script in a transaction, canceled with ctx. The
+// statements of a script with the no-transaction directive, or with a
+// statement which can't run in a transaction like CREATE INDEX CONCURRENTLY,
+// are executed one at a time without, since databases like Postgres run the
+// statements sent together in an implicit transaction; with an IndexDialect
+// the indexes created concurrently are checked to be valid. With a
+// CheckpointDialect committing DDL implicitly, the statements are executed
+// one at a time as well and those already executed are skipped. The
+// settings of the set directives apply while the script runs.
+func (m *GenericDriver) ExecContext(ctx context.Context, script string) (time.Duration, error) {
 	start := time.Now()
 
+	c, checkpointed := m.checkpointDialect()
+	if checkpointed || hasDirective(script, noTransactionDirective) || nonTransactional(script) {
+		// The statements and the settings must share a session.
+		conn, err := m.DB.Conn(ctx)
+		if err != nil {
+			return time.Since(start), err
+		}
+		defer conn.Close()
+
+		err = withSettings(ctx, conn, m.Dialect, script, func() error {
+			if checkpointed {
+				return m.execCheckpointed(ctx, conn, c, script)
+			}
+
+			for i, stmt := range SplitStatements(script, syntaxOf(m.Dialect)) {
+				if _, err := conn.ExecContext(ctx, stmt); err != nil {
+					return statementError{index: i, statement: stmt, err: err}
+				}
+				if err := m.waitForIndex(ctx, conn, stmt); err != nil {
+					return statementError{index: i, statement: stmt, err: err}
+				}
+			}
+			return nil
+		})
+
+		return time.Since(start), err
+	}
+
 	f := func(tx *sql.Tx) error {
-		_, err := tx.Exec(script)
+		return withSettings(ctx, tx, m.Dialect, script, func() error {
+			_, err := tx.ExecContext(ctx, script)
+			return err
+		})
+	}
+
+	err := transactionContext(ctx, m.DB, f)
+	return time.Since(start), err
+}
+
+// Ping verifies the connection to the database.
+func (m *GenericDriver) Ping(ctx context.Context) error {
+	return m.DB.PingContext(ctx)
+}
+
+// Close closes the database.
+func (m *GenericDriver) Close() error {
+	return m.DB.Close()
+}
+
+// Lock acquires the migration lock with the statements of the dialect, it
+// does nothing when the dialect isn't a LockDialect. The lock belongs to the
+// session holding it, the database releases it when an applier crashes, so
+// it records no holder and can't be broken.
+func (m *GenericDriver) Lock() error {
+	dialect, ok := m.Dialect.(LockDialect)
+	if !ok {
+		return nil
+	}
+
+	ctx := context.Background()
+
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	lockCtx := ctx
+	if m.LockTimeout > 0 {
+		var cancel context.CancelFunc
+		lockCtx, cancel = context.WithTimeout(ctx, m.LockTimeout)
+		defer cancel()
+	}
+
+	if _, err := conn.ExecContext(lockCtx, dialect.LockSQL()); err != nil {
+		conn.Close()
+		if lockCtx.Err() == context.DeadlineExceeded {
+			return ErrLockTimeout
+		}
 		return err
 	}
 
-	return time.Since(start), transaction(m.DB, f)
+	m.lockConn = conn
+	return nil
+}
+
+// Unlock releases the lock acquired by Lock.
+func (m *GenericDriver) Unlock() error {
+	dialect, ok := m.Dialect.(LockDialect)
+	if !ok {
+		return nil
+	}
+
+	if m.lockConn == nil {
+		return errors.New("darwin: lock is not held")
+	}
+
+	conn := m.lockConn
+	m.lockConn = nil
+	defer conn.Close()
+
+	_, err := conn.ExecContext(context.Background(), dialect.UnlockSQL())
+	return err
 }
 
 // transaction is a utility function to execute the SQL inside a transaction.
 // see: http://stackoverflow.com/a/23502629
-func transaction(db *sql.DB, f func(*sql.Tx) error) (err error) {
+func transaction(db *sql.DB, f func(*sql.Tx) error) error {
+	return transactionContext(context.Background(), db, f)
+}
+
+// transactionContext is transaction with a context.
+func transactionContext(ctx context.Context, db *sql.DB, f func(*sql.Tx) error) (err error) {
 	if db == nil {
 		return errors.New("darwin: sql.DB is nil")
 	}
 
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return
 	}
@@ -1,6 +1,7 @@
 package darwin
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"regexp"
@@ -42,6 +43,7 @@ func Test_GenericDriver_Create(t *testing.T) {
 
 	mock.ExpectBegin()
 	mock.ExpectExec(escapeQuery(dialect.CreateTableSQL())).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(escapeQuery(dialect.CreateCheckpointTableSQL())).WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectCommit()
 
 	d, err := NewGenericDriver(db, dialect)
@@ -70,6 +72,10 @@ func Test_GenericDriver_Insert(t *testing.T) {
 		Checksum:      "7ebca1c6f05333a728a8db4629e8d543",
 		AppliedAt:     time.Now(),
 		ExecutionTime: time.Millisecond * 1,
+		RecordMetadata: RecordMetadata{
+			AppliedBy: "deploy",
+			Source:    "4f2a9c1",
+		},
 	}
 
 	dialect := MySQLDialect{}
@@ -80,15 +86,22 @@ func Test_GenericDriver_Insert(t *testing.T) {
 	}
 
 	mock.ExpectBegin()
-	mock.ExpectExec(escapeQuery(dialect.InsertSQL())).
+	mock.ExpectExec(escapeQuery(dialect.InsertMetadataSQL())).
 		WithArgs(
 			record.Version,
 			record.Description,
 			record.Checksum,
 			record.AppliedAt.Unix(),
 			record.ExecutionTime,
+			record.AppliedBy,
+			nil,
+			nil,
+			record.Source,
+			nil,
 		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(escapeQuery(dialect.DeleteCheckpointsSQL())).
+		WillReturnResult(sqlmock.NewResult(0, 2))
 
 	mock.ExpectCommit()
 
@@ -116,11 +129,11 @@ func Test_GenericDriver_All_success(t *testing.T) {
 	}
 
 	rows := sqlmock.NewRows([]string{
-		"version", "description", "checksum", "applied_at", "execution_time", "success",
+		"version", "description", "checksum", "applied_at", "execution_time",
 	}).AddRow(
 		1, "Description", "7ebca1c6f05333a728a8db4629e8d543",
 		time.Now().Unix(),
-		time.Millisecond*1, true,
+		time.Millisecond*1,
 	)
 
 	mock.ExpectQuery(escapeQuery(dialect.AllSQL())).
@@ -184,10 +197,13 @@ func Test_GenericDriver_Exec(t *testing.T) {
 		t.Errorf("unable to construct driver: %s", err)
 	}
 
-	mock.ExpectBegin()
-	mock.ExpectExec(escapeQuery(stmt)).
+	mock.ExpectQuery(escapeQuery(dialect.CheckpointsSQL())).
+		WillReturnRows(sqlmock.NewRows([]string{"statement", "checksum"}))
+	mock.ExpectExec(escapeQuery("CREATE TABLE HELLO (id INT)")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(escapeQuery(dialect.InsertCheckpointSQL())).
+		WithArgs(0, "2187dd1f2516f1fcbce5c10fa9e7b1f1", sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectCommit()
 
 	d.Exec(stmt)
 
@@ -213,10 +229,10 @@ func Test_GenericDriver_Exec_error(t *testing.T) {
 		t.Errorf("unable to construct driver: %s", err)
 	}
 
-	mock.ExpectBegin()
-	mock.ExpectExec(escapeQuery(stmt)).
+	mock.ExpectQuery(escapeQuery(dialect.CheckpointsSQL())).
+		WillReturnRows(sqlmock.NewRows([]string{"statement", "checksum"}))
+	mock.ExpectExec(escapeQuery("CREATE TABLE HELLO (id INT)")).
 		WillReturnError(errors.New("Generic Error"))
-	mock.ExpectRollback()
 
 	d.Exec(stmt)
 
@@ -337,3 +353,175 @@ func escapeQuery(s string) string {
 	s1 = strings.TrimSpace(re.ReplaceAllString(s1, " "))
 	return s1
 }
+
+func Test_PlaceholderStyle(t *testing.T) {
+	expectations := map[PlaceholderStyle]string{
+		PlaceholderQuestion: "?",
+		PlaceholderDollar:   "$2",
+		PlaceholderColon:    ":2",
+		PlaceholderAtP:      "@p2",
+	}
+
+	for style, expected := range expectations {
+		if got := style.Placeholder(2); got != expected {
+			t.Errorf("Expected %s, got %s", expected, got)
+		}
+	}
+}
+
+func Test_StandardDialect(t *testing.T) {
+	dialect := StandardDialect{
+		Table:       "schema_history",
+		Placeholder: PlaceholderColon,
+		FloatType:   "BINARY_DOUBLE",
+		StringType:  "VARCHAR2(%d)",
+	}
+
+	create := dialect.CreateTableSQL()
+	for _, expected := range []string{"CREATE TABLE IF NOT EXISTS schema_history", "BINARY_DOUBLE", "VARCHAR2(255)", "VARCHAR2(32)", "BIGINT"} {
+		if !strings.Contains(create, expected) {
+			t.Errorf("CreateTableSQL must contain %q, got %s", expected, create)
+		}
+	}
+
+	if insert := dialect.InsertSQL(); !strings.Contains(insert, "VALUES (:1, :2, :3, :4, :5)") {
+		t.Errorf("InsertSQL must use the placeholder style, got %s", insert)
+	}
+
+	if all := dialect.AllSQL(); !strings.Contains(all, "FROM\n                schema_history") {
+		t.Errorf("AllSQL must read the table, got %s", all)
+	}
+}
+
+func Test_GenericDriver_StandardDialect(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	dialect := StandardDialect{Placeholder: PlaceholderDollar}
+
+	d, err := NewGenericDriver(db, dialect)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	record := MigrationRecord{Version: 1, Description: "Users", Checksum: "abc", AppliedAt: time.Unix(1700000000, 0), ExecutionTime: 10}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(escapeQuery(dialect.InsertMetadataSQL())).
+		WithArgs(record.Version, record.Description, record.Checksum, record.AppliedAt.Unix(), record.ExecutionTime, nil, nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := d.Insert(record); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_GenericDriver_ContextDriver(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+
+	var d ContextDriver
+	d, _ = NewGenericDriver(db, PostgresDialect{})
+
+	mock.ExpectPing()
+	mock.ExpectBegin()
+	mock.ExpectExec(escapeQuery("CREATE TABLE users (id INT)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectClose()
+
+	if err := d.Ping(context.Background()); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if _, err := d.ExecContext(context.Background(), "CREATE TABLE users (id INT)"); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_GenericDriver_Lock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, _ := NewGenericDriver(db, PostgresDialect{})
+
+	mock.ExpectExec(escapeQuery(PostgresDialect{}.LockSQL())).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(escapeQuery(PostgresDialect{}.UnlockSQL())).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := d.Lock(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := d.Unlock(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := d.Unlock(); err == nil {
+		t.Errorf("Must return an error when the lock is not held")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_GenericDriver_Lock_unsupported(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, _ := NewGenericDriver(db, QLDialect{})
+
+	if err := d.Lock(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := d.Unlock(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_GenericDriver_Lock_timeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, _ := NewGenericDriver(db, MySQLDialect{})
+	d.LockTimeout = 10 * time.Millisecond
+
+	mock.ExpectExec(escapeQuery(MySQLDialect{}.LockSQL())).
+		WillDelayFor(time.Second).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := d.Lock(); err != ErrLockTimeout {
+		t.Errorf("Expected ErrLockTimeout, got %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package arangodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Error is an error returned by the ArangoDB API.
+type Error struct {
+	Code     int    `json:"code"`
+	ErrorNum int    `json:"errorNum"`
+	Message  string `json:"errorMessage"`
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("arangodb: %d (error %d): %s", e.Code, e.ErrorNum, e.Message)
+}
+
+// uniqueConstraintViolated is the error number of a duplicate document key.
+const uniqueConstraintViolated = 1210
+
+// request is a call to the API of the database.
+type request struct {
+	Method string
+	Path   string
+	Body   []byte
+
+	// Transaction is the id of the stream transaction the request is part
+	// of, if any.
+	Transaction string
+}
+
+// operations maps the name of an Operation to the request it sends.
+var operations = map[string]func(input json.RawMessage) (request, error){
+	"CreateCollection": post("/_api/collection"),
+	"DropCollection":   byName(http.MethodDelete, "/_api/collection/%s", "name"),
+	"UpdateCollection": byName(http.MethodPut, "/_api/collection/%s/properties", "name"),
+	"CreateIndex":      createIndex,
+	"DropIndex":        byName(http.MethodDelete, "/_api/index/%s", "id"),
+	"CreateView":       post("/_api/view"),
+	"DropView":         byName(http.MethodDelete, "/_api/view/%s", "name"),
+	"CreateGraph":      post("/_api/gharial"),
+	"DropGraph":        byName(http.MethodDelete, "/_api/gharial/%s", "name"),
+	"CreateAnalyzer":   post("/_api/analyzer"),
+	"DropAnalyzer":     byName(http.MethodDelete, "/_api/analyzer/%s", "name"),
+}
+
+// post sends the input to path.
+func post(path string) func(json.RawMessage) (request, error) {
+	return func(input json.RawMessage) (request, error) {
+		return request{Method: http.MethodPost, Path: path, Body: input}, nil
+	}
+}
+
+// byName sends the input to the path of the object named by the field of
+// the input.
+func byName(method string, path string, field string) func(json.RawMessage) (request, error) {
+	return func(input json.RawMessage) (request, error) {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(input, &fields); err != nil {
+			return request{}, err
+		}
+
+		name, _ := fields[field].(string)
+		if name == "" {
+			return request{}, fmt.Errorf("arangodb: missing %q in the input", field)
+		}
+
+		r := request{Method: method, Path: fmt.Sprintf(path, url.PathEscape(name))}
+		if method != http.MethodDelete {
+			r.Body = input
+		}
+		return r, nil
+	}
+}
+
+// createIndex sends the input to the index API of the collection named by
+// the collection field.
+func createIndex(input json.RawMessage) (request, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(input, &fields); err != nil {
+		return request{}, err
+	}
+
+	var collection string
+	json.Unmarshal(fields["collection"], &collection)
+	if collection == "" {
+		return request{}, fmt.Errorf(`arangodb: missing "collection" in the input`)
+	}
+	delete(fields, "collection")
+
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return request{}, err
+	}
+
+	return request{Method: http.MethodPost, Path: "/_api/index?collection=" + url.QueryEscape(collection), Body: body}, nil
+}
+
+// do sends a request to the database and decodes the response in v.
+func (d *Driver) do(ctx context.Context, r request, v interface{}) error {
+	var body io.Reader
+	if r.Body != nil {
+		body = bytes.NewReader(r.Body)
+	}
+
+	u := fmt.Sprintf("%s/_db/%s%s", d.endpoint, url.PathEscape(d.database), r.Path)
+	req, err := http.NewRequestWithContext(ctx, r.Method, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if d.username != "" {
+		req.SetBasicAuth(d.username, d.password)
+	}
+	if r.Transaction != "" {
+		req.Header.Set("x-arango-trx-id", r.Transaction)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		e := Error{Code: resp.StatusCode}
+		json.Unmarshal(b, &e)
+		return e
+	}
+
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(b, v)
+}
+
+// cursorResponse is a batch of results of an AQL query.
+type cursorResponse struct {
+	ID      string            `json:"id"`
+	Result  []json.RawMessage `json:"result"`
+	HasMore bool              `json:"hasMore"`
+}
+
+// query runs an AQL query and returns all its results.
+func (d *Driver) query(ctx context.Context, query string, bindVars map[string]interface{}, transaction string) ([]json.RawMessage, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     query,
+		"bindVars":  bindVars,
+		"batchSize": 1000,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var c cursorResponse
+	if err := d.do(ctx, request{Method: http.MethodPost, Path: "/_api/cursor", Body: body, Transaction: transaction}, &c); err != nil {
+		return nil, err
+	}
+
+	results := c.Result
+	for c.HasMore {
+		id := c.ID
+		c = cursorResponse{}
+		if err := d.do(ctx, request{Method: http.MethodPut, Path: "/_api/cursor/" + url.PathEscape(id), Transaction: transaction}, &c); err != nil {
+			return nil, err
+		}
+		results = append(results, c.Result...)
+	}
+
+	return results, nil
+}
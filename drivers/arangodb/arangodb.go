@@ -0,0 +1,327 @@
+// Package arangodb provides a darwin.Driver for ArangoDB.
+//
+// A migration script holds AQL queries terminated by semicolons and JSON
+// documents calling collection, index, view, graph and analyzer management
+// operations:
+//
+//	-- Version: 1.0
+//	-- Description: Index users by email
+//	{"Operation": "CreateCollection", "Input": {"name": "users"}}
+//	{"Operation": "CreateIndex", "Input": {"collection": "users", "type": "persistent", "fields": ["email"], "unique": true}}
+//	FOR u IN legacy_users INSERT {_key: u._key, email: LOWER(u.email)} INTO users;
+//
+// Consecutive AQL queries run in a stream transaction declaring the
+// collections they modify, so they are committed or aborted together.
+// Management operations can't be part of a transaction, they run on their
+// own. Stream transactions can be disabled with WithStreamTransactions for
+// deployments where they are not available.
+//
+// The history is stored in the darwin_migrations collection, the version is
+// the document key so two appliers can never record the same migration.
+//
+// The driver uses the HTTP API of ArangoDB, it doesn't import a client
+// library.
+package arangodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Collection is the default name of the history collection.
+const Collection = "darwin_migrations"
+
+// duplicateName is the error number of a collection which already exists.
+const duplicateName = 1207
+
+// AlreadyAppliedError is used to report when another applier recorded the
+// migration first.
+type AlreadyAppliedError struct {
+	Version float64
+}
+
+func (a AlreadyAppliedError) Error() string {
+	return fmt.Sprintf("arangodb: migration %f was recorded by another applier", a.Version)
+}
+
+// StepError is used to report the step of a migration which failed.
+type StepError struct {
+	Step Step
+	Err  error
+}
+
+func (s StepError) Error() string {
+	if s.Step.Operation != nil {
+		return fmt.Sprintf("arangodb: operation %s failed: %s", s.Step.Operation.Operation, s.Err)
+	}
+	return fmt.Sprintf("arangodb: query %q failed: %s", s.Step.Query, s.Err)
+}
+
+func (s StepError) Unwrap() error {
+	return s.Err
+}
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithBasicAuth sets the credentials sent with every request.
+func WithBasicAuth(username string, password string) Option {
+	return func(d *Driver) {
+		d.username = username
+		d.password = password
+	}
+}
+
+// WithStreamTransactions sets if consecutive AQL queries run in a stream
+// transaction, they do by default.
+func WithStreamTransactions(enabled bool) Option {
+	return func(d *Driver) {
+		d.transactions = enabled
+	}
+}
+
+// WithTimeout sets how long the driver waits for a migration.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.timeout = timeout
+	}
+}
+
+// Driver is a darwin.Driver for ArangoDB.
+type Driver struct {
+	client       *http.Client
+	endpoint     string
+	database     string
+	username     string
+	password     string
+	transactions bool
+	timeout      time.Duration
+	collection   string
+}
+
+// New creates a new Driver for the database of the server at endpoint, like
+// http://localhost:8529.
+func New(client *http.Client, endpoint string, database string, opts ...Option) (*Driver, error) {
+	if client == nil {
+		return nil, errors.New("arangodb: http client is nil")
+	}
+
+	if endpoint == "" || database == "" {
+		return nil, errors.New("arangodb: endpoint and database are required")
+	}
+
+	d := Driver{
+		client:       client,
+		endpoint:     strings.TrimSuffix(endpoint, "/"),
+		database:     database,
+		transactions: true,
+		timeout:      10 * time.Minute,
+		collection:   Collection,
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep the history in the collection name.
+func (d *Driver) SetTableName(name string) error {
+	if name == "" {
+		return errors.New("arangodb: the collection name is empty")
+	}
+
+	d.collection = name
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the collection
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	c := *d
+	if err := c.SetTableName(name); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Create creates the history collection if necessary.
+func (d *Driver) Create() error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	body, _ := json.Marshal(map[string]string{"name": d.collection})
+	err := d.do(ctx, request{Method: http.MethodPost, Path: "/_api/collection", Body: body}, nil)
+
+	var arangoErr Error
+	if errors.As(err, &arangoErr) && arangoErr.ErrorNum == duplicateName {
+		return nil
+	}
+	return err
+}
+
+// record is a history document.
+type record struct {
+	Key           string  `json:"_key,omitempty"`
+	Version       float64 `json:"version"`
+	Description   string  `json:"description"`
+	Checksum      string  `json:"checksum"`
+	AppliedAt     int64   `json:"applied_at"`
+	ExecutionTime int64   `json:"execution_time"`
+}
+
+// Insert inserts a migration entry into database. It returns an
+// AlreadyAppliedError when the version is already recorded.
+func (d *Driver) Insert(e darwin.MigrationRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	body, err := json.Marshal(record{
+		Key:           strconv.FormatFloat(e.Version, 'f', -1, 64),
+		Version:       e.Version,
+		Description:   e.Description,
+		Checksum:      e.Checksum,
+		AppliedAt:     e.AppliedAt.Unix(),
+		ExecutionTime: int64(e.ExecutionTime),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = d.do(ctx, request{Method: http.MethodPost, Path: "/_api/document/" + d.collection, Body: body}, nil)
+
+	var arangoErr Error
+	if errors.As(err, &arangoErr) && arangoErr.ErrorNum == uniqueConstraintViolated {
+		return AlreadyAppliedError{Version: e.Version}
+	}
+	return err
+}
+
+// All returns all migrations applied.
+func (d *Driver) All() ([]darwin.MigrationRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	results, err := d.query(ctx, "FOR m IN @@collection SORT m.version ASC RETURN m",
+		map[string]interface{}{"@collection": d.collection}, "")
+	if err != nil {
+		return []darwin.MigrationRecord{}, err
+	}
+
+	var records []darwin.MigrationRecord
+	for _, result := range results {
+		var r record
+		if err := json.Unmarshal(result, &r); err != nil {
+			return []darwin.MigrationRecord{}, fmt.Errorf("arangodb: invalid history entry: %w", err)
+		}
+
+		records = append(records, darwin.MigrationRecord{
+			Version:       r.Version,
+			Description:   r.Description,
+			Checksum:      r.Checksum,
+			AppliedAt:     time.Unix(r.AppliedAt, 0),
+			ExecutionTime: time.Duration(r.ExecutionTime),
+		})
+	}
+
+	return records, nil
+}
+
+// Exec runs the steps of the script in order, consecutive AQL queries in a
+// stream transaction.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	start := time.Now()
+
+	steps, err := ParseScript(script)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	for i := 0; i < len(steps); {
+		if op := steps[i].Operation; op != nil {
+			r, err := operations[op.Operation](op.Input)
+			if err == nil {
+				err = d.do(ctx, r, nil)
+			}
+			if err != nil {
+				return time.Since(start), StepError{Step: steps[i], Err: err}
+			}
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(steps) && steps[j].Operation == nil {
+			j++
+		}
+
+		if err := d.runQueries(ctx, steps[i:j]); err != nil {
+			return time.Since(start), err
+		}
+		i = j
+	}
+
+	return time.Since(start), nil
+}
+
+// runQueries runs AQL queries, in a stream transaction when enabled.
+func (d *Driver) runQueries(ctx context.Context, steps []Step) error {
+	if !d.transactions {
+		for _, step := range steps {
+			if _, err := d.query(ctx, step.Query, nil, ""); err != nil {
+				return StepError{Step: step, Err: err}
+			}
+		}
+		return nil
+	}
+
+	write := []string{}
+	seen := map[string]bool{}
+	for _, step := range steps {
+		for _, c := range WriteCollections(step.Query) {
+			if !seen[c] {
+				seen[c] = true
+				write = append(write, c)
+			}
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"collections": map[string][]string{"write": write},
+	})
+	if err != nil {
+		return err
+	}
+
+	var begin struct {
+		Result struct {
+			ID string `json:"id"`
+		} `json:"result"`
+	}
+	if err := d.do(ctx, request{Method: http.MethodPost, Path: "/_api/transaction/begin", Body: body}, &begin); err != nil {
+		return err
+	}
+	id := begin.Result.ID
+
+	for _, step := range steps {
+		if _, err := d.query(ctx, step.Query, nil, id); err != nil {
+			d.do(context.Background(), request{Method: http.MethodDelete, Path: "/_api/transaction/" + url.PathEscape(id)}, nil)
+			return StepError{Step: step, Err: err}
+		}
+	}
+
+	return d.do(ctx, request{Method: http.MethodPut, Path: "/_api/transaction/" + url.PathEscape(id)}, nil)
+}
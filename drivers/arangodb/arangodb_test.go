@@ -0,0 +1,188 @@
+package arangodb
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dustinevan/darwin"
+)
+
+// fakeServer records the requests it receives.
+type fakeServer struct {
+	requests []string
+	keys     map[string]bool
+	fail     string
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	path := strings.TrimPrefix(r.URL.RequestURI(), "/_db/app")
+	f.requests = append(f.requests, r.Method+" "+path+" "+r.Header.Get("x-arango-trx-id"))
+
+	switch {
+	case path == "/_api/transaction/begin":
+		var in struct {
+			Collections struct {
+				Write []string `json:"write"`
+			} `json:"collections"`
+		}
+		json.Unmarshal(body, &in)
+		f.requests[len(f.requests)-1] += " " + strings.Join(in.Collections.Write, ",")
+		w.Write([]byte(`{"result": {"id": "42"}}`))
+
+	case path == "/_api/cursor":
+		if f.fail != "" && strings.Contains(string(body), f.fail) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": true, "code": 400, "errorNum": 1501, "errorMessage": "syntax error"}`))
+			return
+		}
+		if strings.Contains(string(body), "@@collection") {
+			w.Write([]byte(`{"result": [{"_key": "1", "version": 1, "description": "Users", "checksum": "a", "applied_at": 1700000000, "execution_time": 10}], "hasMore": true, "id": "c1"}`))
+			return
+		}
+		w.Write([]byte(`{"result": [], "hasMore": false}`))
+
+	case path == "/_api/cursor/c1":
+		w.Write([]byte(`{"result": [{"_key": "2", "version": 2, "description": "Emails", "checksum": "b", "applied_at": 1700000100, "execution_time": 20}], "hasMore": false}`))
+
+	case path == "/_api/document/darwin_migrations":
+		var rec record
+		json.Unmarshal(body, &rec)
+		if f.keys[rec.Key] {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": true, "code": 409, "errorNum": 1210, "errorMessage": "unique constraint violated"}`))
+			return
+		}
+		f.keys[rec.Key] = true
+		w.Write([]byte(`{}`))
+
+	default:
+		w.Write([]byte(`{}`))
+	}
+}
+
+func newFakeDriver(t *testing.T, server *fakeServer) *Driver {
+	server.keys = map[string]bool{}
+
+	s := httptest.NewServer(server)
+	t.Cleanup(s.Close)
+
+	d, err := New(s.Client(), s.URL, "app")
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	return d
+}
+
+func Test_ParseScript(t *testing.T) {
+	script := `-- users
+{"Operation": "CreateCollection", "Input": {"name": "users"}}
+FOR u IN legacy FILTER u.name != ";" INSERT u INTO users;
+{"Operation": "CreateIndex", "Input": {"collection": "users", "type": "persistent", "fields": ["email"]}}
+`
+
+	steps, err := ParseScript(script)
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(steps) != 3 || steps[0].Operation == nil || steps[2].Operation == nil {
+		t.Fatalf("Unexpected steps %#v", steps)
+	}
+
+	if steps[1].Query != `FOR u IN legacy FILTER u.name != ";" INSERT u INTO users` {
+		t.Errorf("Unexpected query %q", steps[1].Query)
+	}
+
+	if _, err := ParseScript(`{"Operation": "Truncate", "Input": {}}`); err == nil {
+		t.Errorf("Must reject unknown operations")
+	}
+}
+
+func Test_WriteCollections(t *testing.T) {
+	query := `FOR u IN users // INSERT x INTO comments
+UPSERT {_key: u._key} INSERT u UPDATE {} IN ` + "`user-copies`" + `
+FOR o IN orders REMOVE o IN orders`
+
+	expected := []string{"user-copies", "orders"}
+	if got := WriteCollections(query); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func Test_Driver_Exec(t *testing.T) {
+	server := &fakeServer{}
+	d := newFakeDriver(t, server)
+
+	script := `{"Operation": "CreateIndex", "Input": {"collection": "users", "type": "persistent", "fields": ["email"]}}
+FOR u IN legacy INSERT u INTO users;
+FOR u IN users UPDATE u WITH {active: true} IN users;
+{"Operation": "DropCollection", "Input": {"name": "legacy"}}
+`
+
+	if _, err := d.Exec(script); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := []string{
+		"POST /_api/index?collection=users ",
+		"POST /_api/transaction/begin  users",
+		"POST /_api/cursor 42",
+		"POST /_api/cursor 42",
+		"PUT /_api/transaction/42 ",
+		"DELETE /_api/collection/legacy ",
+	}
+	if !reflect.DeepEqual(server.requests, expected) {
+		t.Errorf("Expected %q, got %q", expected, server.requests)
+	}
+}
+
+func Test_Driver_Exec_abort(t *testing.T) {
+	server := &fakeServer{fail: "BROKEN"}
+	d := newFakeDriver(t, server)
+
+	_, err := d.Exec("FOR u IN a INSERT u INTO b;\nBROKEN;\n")
+
+	var stepErr StepError
+	if !errors.As(err, &stepErr) || stepErr.Step.Query != "BROKEN" {
+		t.Fatalf("Must report the failed query, got %v", err)
+	}
+
+	var arangoErr Error
+	if !errors.As(err, &arangoErr) || arangoErr.ErrorNum != 1501 {
+		t.Errorf("Must wrap the server error, got %v", err)
+	}
+
+	if last := server.requests[len(server.requests)-1]; last != "DELETE /_api/transaction/42 " {
+		t.Errorf("Must abort the transaction, got %q", last)
+	}
+}
+
+func Test_Driver_history(t *testing.T) {
+	server := &fakeServer{}
+	d := newFakeDriver(t, server)
+
+	if err := d.Insert(darwin.MigrationRecord{Version: 1.5}); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := d.Insert(darwin.MigrationRecord{Version: 1.5}); !errors.As(err, &AlreadyAppliedError{}) {
+		t.Errorf("Expected AlreadyAppliedError, got %v", err)
+	}
+
+	records, err := d.All()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(records) != 2 || records[1].Description != "Emails" {
+		t.Errorf("Must read all the batches of the cursor, got %#v", records)
+	}
+}
@@ -0,0 +1,116 @@
+package arangodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Operation is a collection or index management call in a migration script:
+//
+//	{"Operation": "CreateIndex", "Input": {"collection": "users", "type": "persistent", "fields": ["email"], "unique": true}}
+//
+// See the operations variable for the supported operations.
+type Operation struct {
+	Operation string          `json:"Operation"`
+	Input     json.RawMessage `json:"Input"`
+}
+
+// Step is an AQL query or an Operation of a migration script.
+type Step struct {
+	Query     string
+	Operation *Operation
+}
+
+// writeCollection captures the collection modified by an AQL data
+// modification operation.
+var writeCollection = regexp.MustCompile(`(?is)\b(?:INSERT|UPDATE|REPLACE|REMOVE|UPSERT)\b.*?\b(?:INTO|IN)\s+` + "(`[^`]+`|[A-Za-z_][\\w-]*)")
+
+// aqlNoise matches the strings and comments of an AQL query.
+var aqlNoise = regexp.MustCompile(`'(?:[^'\\]|\\.)*'|"(?:[^"\\]|\\.)*"|//[^\n]*|/\*[\s\S]*?(?:\*/|$)`)
+
+// syntax is the syntax of AQL queries: strings escaped with a backslash,
+// backquoted names and // comments.
+var syntax = darwin.Syntax{BackslashEscapes: true, Backticks: true, SlashComments: true}
+
+// ParseScript returns the steps of a migration script: AQL queries
+// terminated by semicolons and JSON Operation documents, in any order.
+// Lines starting with "--" are comments.
+func ParseScript(script string) ([]Step, error) {
+	var steps []Step
+
+	for _, s := range darwin.SplitStatements(stripComments(script), syntax) {
+		for s != "" {
+			if s[0] != '{' {
+				steps = append(steps, Step{Query: s})
+				break
+			}
+
+			dec := json.NewDecoder(strings.NewReader(s))
+
+			var op Operation
+			if err := dec.Decode(&op); err != nil {
+				return nil, fmt.Errorf("arangodb: invalid operation: %w", err)
+			}
+
+			if _, ok := operations[op.Operation]; !ok {
+				return nil, fmt.Errorf("arangodb: unknown operation %q", op.Operation)
+			}
+
+			steps = append(steps, Step{Operation: &op})
+			s = strings.TrimSpace(s[dec.InputOffset():])
+		}
+	}
+
+	return steps, nil
+}
+
+// WriteCollections returns the collections modified by an AQL query, which
+// must be declared when the query runs in a stream transaction.
+func WriteCollections(query string) []string {
+	var collections []string
+
+	seen := map[string]bool{}
+	for _, m := range writeCollection.FindAllStringSubmatch(stripAQLComments(query), -1) {
+		name := strings.Trim(m[1], "`")
+		if !seen[name] {
+			seen[name] = true
+			collections = append(collections, name)
+		}
+	}
+
+	return collections
+}
+
+// stripComments removes the comment lines of a script.
+func stripComments(script string) string {
+	lines := strings.Split(script, "\n")
+	kept := lines[:0]
+
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	return strings.Join(kept, "\n")
+}
+
+// stripAQLComments removes the // and /* */ comments and the strings of an
+// AQL query.
+func stripAQLComments(query string) string {
+	return aqlNoise.ReplaceAllStringFunc(query, func(m string) string {
+		switch {
+		case strings.HasPrefix(m, "//"):
+			return ""
+		case strings.HasPrefix(m, "/*"):
+			return " "
+		default:
+			return `""`
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package athena
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// API calls an Athena operation using the JSON protocol of the Athena API:
+// input and output are the JSON documents described in the Athena API
+// reference.
+type API interface {
+	Call(ctx context.Context, operation string, input []byte) ([]byte, error)
+}
+
+// APIError is an error returned by the Athena API.
+type APIError struct {
+	Type    string
+	Message string
+}
+
+func (a APIError) Error() string {
+	return fmt.Sprintf("athena: %s: %s", a.Type, a.Message)
+}
+
+// HTTPAPI is an API sending requests to an Athena endpoint, like
+// https://athena.us-east-1.amazonaws.com.
+type HTTPAPI struct {
+	Endpoint string
+	Client   *http.Client
+
+	// Sign signs the request with AWS Signature Version 4, for example
+	// with the v4.Signer of github.com/aws/aws-sdk-go-v2.
+	Sign func(req *http.Request, body []byte) error
+}
+
+// Call implements the API interface.
+func (h HTTPAPI) Call(ctx context.Context, operation string, input []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.Endpoint, bytes.NewReader(input))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AmazonAthena."+operation)
+
+	if h.Sign != nil {
+		if err := h.Sign(req, input); err != nil {
+			return nil, err
+		}
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Type    string `json:"__type"`
+			Message string `json:"Message"`
+		}
+		json.Unmarshal(body, &e)
+
+		if i := strings.LastIndex(e.Type, "#"); i >= 0 {
+			e.Type = e.Type[i+1:]
+		}
+		return nil, APIError{Type: e.Type, Message: e.Message}
+	}
+
+	return body, nil
+}
+
+// call marshals input, calls operation and unmarshals its output in output.
+func (d *Driver) call(ctx context.Context, operation string, input interface{}, output interface{}) error {
+	b, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+
+	out, err := d.api.Call(ctx, operation, b)
+	if err != nil {
+		return err
+	}
+
+	if output == nil {
+		return nil
+	}
+
+	return json.Unmarshal(out, output)
+}
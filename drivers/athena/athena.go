@@ -0,0 +1,287 @@
+// Package athena provides a darwin.Driver for Amazon Athena, typically
+// managing the tables of a data lake with CREATE EXTERNAL TABLE, ALTER TABLE
+// ADD PARTITION or MSCK REPAIR TABLE statements.
+//
+// Athena runs a single statement per query execution, so the driver splits
+// migrations into statements, starts a query execution for each of them and
+// polls its state until it succeeds. Athena has no transactions: when a
+// statement fails, the statements before it stay applied.
+//
+// By default the history is stored in an Iceberg table of the database,
+// which requires an S3 location set with WithHistoryLocation. The history
+// can be stored elsewhere with WithHistory, for example in a DynamoDB table
+// with the driver of the dynamodb package:
+//
+//	history, err := dynamodb.New(dynamodb.HTTPAPI{...}, dynamodb.WithTable("lake_migrations"))
+//	driver, err := athena.New(api, "lake", athena.WithHistory(history))
+package athena
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// History stores the history of the migrations.
+type History interface {
+	Create() error
+	Insert(e darwin.MigrationRecord) error
+	All() ([]darwin.MigrationRecord, error)
+}
+
+// StatementError is used to report the statement of a migration which
+// failed, the statements before it are applied.
+type StatementError struct {
+	Statement string
+	Err       error
+}
+
+func (s StatementError) Error() string {
+	return fmt.Sprintf("athena: statement %q failed: %s", s.Statement, s.Err)
+}
+
+func (s StatementError) Unwrap() error {
+	return s.Err
+}
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithCatalog sets the data catalog of the database, "AwsDataCatalog" (the
+// Glue catalog) by default.
+func WithCatalog(catalog string) Option {
+	return func(d *Driver) {
+		d.catalog = catalog
+	}
+}
+
+// WithWorkGroup sets the work group queries run in.
+func WithWorkGroup(workGroup string) Option {
+	return func(d *Driver) {
+		d.workGroup = workGroup
+	}
+}
+
+// WithOutputLocation sets the S3 location of query results, required
+// unless the work group defines one.
+func WithOutputLocation(location string) Option {
+	return func(d *Driver) {
+		d.outputLocation = location
+	}
+}
+
+// WithHistoryLocation sets the S3 location of the Iceberg history table,
+// like s3://bucket/lake/darwin_migrations/.
+func WithHistoryLocation(location string) Option {
+	return func(d *Driver) {
+		d.historyLocation = location
+	}
+}
+
+// WithHistory stores the history in h instead of an Athena table.
+func WithHistory(h History) Option {
+	return func(d *Driver) {
+		d.history = h
+	}
+}
+
+// WithTimeout sets how long the driver waits for a query execution.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.timeout = timeout
+	}
+}
+
+// Driver is a darwin.Driver for Athena.
+type Driver struct {
+	api             API
+	database        string
+	catalog         string
+	workGroup       string
+	outputLocation  string
+	historyLocation string
+	history         History
+	timeout         time.Duration
+	poll            time.Duration
+	table           string
+}
+
+// New creates a new Driver running the migrations in database.
+func New(api API, database string, opts ...Option) (*Driver, error) {
+	if api == nil {
+		return nil, errors.New("athena: api is nil")
+	}
+
+	if database == "" {
+		return nil, errors.New("athena: database is required")
+	}
+
+	d := Driver{
+		api:      api,
+		database: database,
+		catalog:  "AwsDataCatalog",
+		table:    "darwin_migrations",
+		timeout:  time.Hour,
+		poll:     time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	if d.history == nil && d.historyLocation == "" {
+		return nil, errors.New("athena: a history location or a history is required")
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep the history in the table name of the
+// database, or makes the History of WithHistory do so when it is a
+// darwin.TableNamer. Athena table names are made of lowercase letters,
+// digits and underscores.
+func (d *Driver) SetTableName(name string) error {
+	if d.history != nil {
+		t, ok := d.history.(darwin.TableNamer)
+		if !ok {
+			return errors.New("athena: the history can't change the name of its table")
+		}
+		return t.SetTableName(name)
+	}
+
+	if name == "" || strings.TrimLeft(name, "abcdefghijklmnopqrstuvwxyz0123456789_") != "" {
+		return fmt.Errorf("athena: invalid table name %q", name)
+	}
+
+	d.table = name
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds. The History of WithHistory can't be copied.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	if d.history != nil {
+		return nil, errors.New("athena: the history can't be copied")
+	}
+
+	c := *d
+	if err := c.SetTableName(name); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Create creates the history table, darwin_migrations by default, if
+// necessary.
+func (d *Driver) Create() error {
+	if d.history != nil {
+		return d.history.Create()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	_, err := d.query(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+                (
+                    version        DOUBLE,
+                    description    STRING,
+                    checksum       STRING,
+                    applied_at     BIGINT,
+                    execution_time BIGINT
+                )
+            LOCATION %s
+            TBLPROPERTIES ('table_type' = 'ICEBERG')`, d.table, quoteString(d.historyLocation)))
+	return err
+}
+
+// Insert inserts a migration entry into database.
+func (d *Driver) Insert(e darwin.MigrationRecord) error {
+	if d.history != nil {
+		return d.history.Insert(e)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	_, err := d.query(ctx, fmt.Sprintf(`INSERT INTO %s
+                (version, description, checksum, applied_at, execution_time)
+            VALUES (CAST(%s AS DOUBLE), %s, %s, %d, %d)`,
+		d.table,
+		quoteString(strconv.FormatFloat(e.Version, 'f', -1, 64)),
+		quoteString(e.Description),
+		quoteString(e.Checksum),
+		e.AppliedAt.Unix(),
+		int64(e.ExecutionTime),
+	))
+	return err
+}
+
+// All returns all migrations applied.
+func (d *Driver) All() ([]darwin.MigrationRecord, error) {
+	if d.history != nil {
+		return d.history.All()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	id, err := d.query(ctx, fmt.Sprintf(`SELECT version, description, checksum, applied_at, execution_time
+            FROM %s
+            ORDER BY version ASC`, d.table))
+	if err != nil {
+		return []darwin.MigrationRecord{}, err
+	}
+
+	rows, err := d.rows(ctx, id)
+	if err != nil {
+		return []darwin.MigrationRecord{}, err
+	}
+
+	var records []darwin.MigrationRecord
+	for _, row := range rows {
+		if len(row) != 5 {
+			return []darwin.MigrationRecord{}, fmt.Errorf("athena: unexpected row %v", row)
+		}
+
+		version, _ := strconv.ParseFloat(row[0], 64)
+		appliedAt, _ := strconv.ParseInt(row[3], 10, 64)
+		executionTime, _ := strconv.ParseInt(row[4], 10, 64)
+
+		records = append(records, darwin.MigrationRecord{
+			Version:       version,
+			Description:   row[1],
+			Checksum:      row[2],
+			AppliedAt:     time.Unix(appliedAt, 0),
+			ExecutionTime: time.Duration(executionTime),
+		})
+	}
+
+	return records, nil
+}
+
+// Exec runs the statements of the script one at a time, waiting for each
+// query execution to succeed.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	for _, statement := range SplitStatements(script) {
+		if _, err := d.query(ctx, statement); err != nil {
+			return time.Since(start), StatementError{Statement: statement, Err: err}
+		}
+	}
+
+	return time.Since(start), nil
+}
+
+// quoteString returns s as a SQL string literal.
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
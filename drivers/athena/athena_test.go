@@ -0,0 +1,167 @@
+package athena
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// fakeAPI is an Athena API completing every query execution on the second
+// GetQueryExecution call.
+type fakeAPI struct {
+	queries []string
+	polls   map[string]int
+	fail    string
+	rows    string
+}
+
+func (f *fakeAPI) Call(ctx context.Context, operation string, input []byte) ([]byte, error) {
+	var in struct {
+		QueryString      string
+		QueryExecutionID string `json:"QueryExecutionId"`
+	}
+	json.Unmarshal(input, &in)
+
+	switch operation {
+	case "StartQueryExecution":
+		f.queries = append(f.queries, in.QueryString)
+		return []byte(`{"QueryExecutionId": "` + strconv.Itoa(len(f.queries)-1) + `"}`), nil
+
+	case "GetQueryExecution":
+		f.polls[in.QueryExecutionID]++
+		if f.polls[in.QueryExecutionID] == 1 {
+			return []byte(`{"QueryExecution": {"Status": {"State": "RUNNING"}}}`), nil
+		}
+
+		i, _ := strconv.Atoi(in.QueryExecutionID)
+		if f.fail != "" && strings.Contains(f.queries[i], f.fail) {
+			return []byte(`{"QueryExecution": {"Status": {"State": "FAILED", "StateChangeReason": "FAILED: SemanticException"}}}`), nil
+		}
+		return []byte(`{"QueryExecution": {"Status": {"State": "SUCCEEDED"}}}`), nil
+
+	case "GetQueryResults":
+		return []byte(f.rows), nil
+	}
+
+	return nil, APIError{Type: "InvalidRequestException", Message: operation}
+}
+
+func newFakeDriver(t *testing.T, api *fakeAPI, opts ...Option) *Driver {
+	api.polls = map[string]int{}
+
+	d, err := New(api, "lake", append([]Option{WithHistoryLocation("s3://bucket/darwin_migrations/")}, opts...)...)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+	d.poll = time.Millisecond
+
+	return d
+}
+
+func Test_Driver_Exec(t *testing.T) {
+	api := &fakeAPI{}
+	d := newFakeDriver(t, api)
+
+	script := "CREATE EXTERNAL TABLE `events` (id BIGINT) LOCATION 's3://bucket/events;v1/';\nMSCK REPAIR TABLE events;\n-- done\n"
+	if _, err := d.Exec(script); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := []string{
+		"CREATE EXTERNAL TABLE `events` (id BIGINT) LOCATION 's3://bucket/events;v1/'",
+		"MSCK REPAIR TABLE events",
+	}
+	if !reflect.DeepEqual(api.queries, expected) {
+		t.Errorf("Expected %q, got %q", expected, api.queries)
+	}
+}
+
+func Test_Driver_Exec_failed(t *testing.T) {
+	api := &fakeAPI{fail: "MSCK"}
+	d := newFakeDriver(t, api)
+
+	_, err := d.Exec("CREATE EXTERNAL TABLE events (id BIGINT) LOCATION 's3://bucket/events/';\nMSCK REPAIR TABLE events;\n")
+
+	var queryErr QueryError
+	if !errors.As(err, &queryErr) || queryErr.State != "FAILED" || queryErr.QueryExecutionID != "1" {
+		t.Fatalf("Must return the failed query execution, got %v", err)
+	}
+
+	var statementErr StatementError
+	if !errors.As(err, &statementErr) || statementErr.Statement != "MSCK REPAIR TABLE events" {
+		t.Errorf("Must report the failed statement, got %v", err)
+	}
+}
+
+func Test_Driver_All(t *testing.T) {
+	api := &fakeAPI{rows: `{"ResultSet": {"Rows": [
+		{"Data": [{"VarCharValue": "version"}, {"VarCharValue": "description"}, {"VarCharValue": "checksum"}, {"VarCharValue": "applied_at"}, {"VarCharValue": "execution_time"}]},
+		{"Data": [{"VarCharValue": "1.1"}, {"VarCharValue": "Create events"}, {"VarCharValue": "abc"}, {"VarCharValue": "1700000000"}, {"VarCharValue": "1500"}]}
+	]}}`}
+	d := newFakeDriver(t, api)
+
+	records, err := d.All()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("len(records) == %d, wants 1", len(records))
+	}
+
+	r := records[0]
+	if r.Version != 1.1 || r.Description != "Create events" || r.Checksum != "abc" || r.AppliedAt.Unix() != 1700000000 || r.ExecutionTime != 1500 {
+		t.Errorf("Unexpected record %#v", r)
+	}
+}
+
+// fakeHistory is a History kept in memory.
+type fakeHistory struct {
+	records []darwin.MigrationRecord
+}
+
+func (f *fakeHistory) Create() error { return nil }
+
+func (f *fakeHistory) Insert(e darwin.MigrationRecord) error {
+	f.records = append(f.records, e)
+	return nil
+}
+
+func (f *fakeHistory) All() ([]darwin.MigrationRecord, error) {
+	return f.records, nil
+}
+
+func Test_Driver_WithHistory(t *testing.T) {
+	api := &fakeAPI{polls: map[string]int{}}
+	history := &fakeHistory{}
+
+	d, err := New(api, "lake", WithHistory(history))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	if err := d.Create(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := d.Insert(darwin.MigrationRecord{Version: 1}); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(api.queries) != 0 || len(history.records) != 1 {
+		t.Errorf("Must store the history in the companion store, got queries %q", api.queries)
+	}
+}
+
+func Test_New_without_history(t *testing.T) {
+	if _, err := New(&fakeAPI{}, "lake"); err == nil {
+		t.Errorf("Must require a history location or a history")
+	}
+}
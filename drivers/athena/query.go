@@ -0,0 +1,124 @@
+package athena
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// QueryError is used to report a query execution which failed or was
+// cancelled.
+type QueryError struct {
+	QueryExecutionID string
+	State            string
+	Reason           string
+}
+
+func (q QueryError) Error() string {
+	return fmt.Sprintf("athena: query %s %s: %s", q.QueryExecutionID, strings.ToLower(q.State), q.Reason)
+}
+
+type queryExecution struct {
+	QueryExecution struct {
+		Status struct {
+			State             string `json:"State"`
+			StateChangeReason string `json:"StateChangeReason"`
+		} `json:"Status"`
+	} `json:"QueryExecution"`
+}
+
+type queryResults struct {
+	ResultSet struct {
+		Rows []struct {
+			Data []struct {
+				VarCharValue *string `json:"VarCharValue"`
+			} `json:"Data"`
+		} `json:"Rows"`
+	} `json:"ResultSet"`
+	NextToken string `json:"NextToken"`
+}
+
+// query starts the execution of a query and waits for its completion.
+func (d *Driver) query(ctx context.Context, query string) (string, error) {
+	input := map[string]interface{}{
+		"QueryString": query,
+		"QueryExecutionContext": map[string]string{
+			"Database": d.database,
+			"Catalog":  d.catalog,
+		},
+	}
+	if d.workGroup != "" {
+		input["WorkGroup"] = d.workGroup
+	}
+	if d.outputLocation != "" {
+		input["ResultConfiguration"] = map[string]string{"OutputLocation": d.outputLocation}
+	}
+
+	var started struct {
+		QueryExecutionID string `json:"QueryExecutionId"`
+	}
+	if err := d.call(ctx, "StartQueryExecution", input, &started); err != nil {
+		return "", err
+	}
+
+	for {
+		var qe queryExecution
+		err := d.call(ctx, "GetQueryExecution", map[string]string{"QueryExecutionId": started.QueryExecutionID}, &qe)
+		if err != nil {
+			return started.QueryExecutionID, err
+		}
+
+		switch status := qe.QueryExecution.Status; status.State {
+		case "SUCCEEDED":
+			return started.QueryExecutionID, nil
+		case "FAILED", "CANCELLED":
+			return started.QueryExecutionID, QueryError{
+				QueryExecutionID: started.QueryExecutionID,
+				State:            status.State,
+				Reason:           status.StateChangeReason,
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			d.call(context.Background(), "StopQueryExecution", map[string]string{"QueryExecutionId": started.QueryExecutionID}, nil)
+			return started.QueryExecutionID, ctx.Err()
+		case <-time.After(d.poll):
+		}
+	}
+}
+
+// rows returns the rows of a completed query, without the header row.
+func (d *Driver) rows(ctx context.Context, id string) ([][]string, error) {
+	var rows [][]string
+
+	input := map[string]interface{}{"QueryExecutionId": id}
+	for {
+		var results queryResults
+		if err := d.call(ctx, "GetQueryResults", input, &results); err != nil {
+			return nil, err
+		}
+
+		for _, row := range results.ResultSet.Rows {
+			values := make([]string, len(row.Data))
+			for i, v := range row.Data {
+				if v.VarCharValue != nil {
+					values[i] = *v.VarCharValue
+				}
+			}
+			rows = append(rows, values)
+		}
+
+		if results.NextToken == "" {
+			break
+		}
+		input["NextToken"] = results.NextToken
+	}
+
+	if len(rows) > 0 {
+		rows = rows[1:]
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,213 @@
+package bigquery
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Types of the BigQuery REST API used by the driver, see
+// https://cloud.google.com/bigquery/docs/reference/rest.
+
+type jobReference struct {
+	ProjectID string `json:"projectId"`
+	JobID     string `json:"jobId"`
+	Location  string `json:"location,omitempty"`
+}
+
+type queryParameter struct {
+	Name          string `json:"name"`
+	ParameterType struct {
+		Type string `json:"type"`
+	} `json:"parameterType"`
+	ParameterValue struct {
+		Value string `json:"value"`
+	} `json:"parameterValue"`
+}
+
+type job struct {
+	JobReference  jobReference `json:"jobReference"`
+	Configuration struct {
+		Labels map[string]string `json:"labels,omitempty"`
+		Query  struct {
+			Query           string           `json:"query"`
+			UseLegacySQL    bool             `json:"useLegacySql"`
+			ParameterMode   string           `json:"parameterMode,omitempty"`
+			QueryParameters []queryParameter `json:"queryParameters,omitempty"`
+		} `json:"query"`
+	} `json:"configuration"`
+	Status struct {
+		State       string     `json:"state"`
+		ErrorResult *JobError  `json:"errorResult"`
+		Errors      []JobError `json:"errors"`
+	} `json:"status"`
+}
+
+type queryResults struct {
+	JobComplete bool `json:"jobComplete"`
+	Rows        []struct {
+		F []struct {
+			V interface{} `json:"v"`
+		} `json:"f"`
+	} `json:"rows"`
+	PageToken string `json:"pageToken"`
+}
+
+// JobError is used to report a job which failed.
+type JobError struct {
+	Reason   string `json:"reason"`
+	Location string `json:"location"`
+	Message  string `json:"message"`
+}
+
+func (j JobError) Error() string {
+	return fmt.Sprintf("bigquery: %s: %s", j.Reason, j.Message)
+}
+
+// apiError is the error returned by the API for invalid requests.
+type apiError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// newJobID returns a unique job id, used to make job insertion idempotent.
+func newJobID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "darwin_" + hex.EncodeToString(b), nil
+}
+
+// do sends a request to the API and decodes the response in v.
+func (d *Driver) do(ctx context.Context, method string, path string, query url.Values, body interface{}, v interface{}) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	u := d.endpoint + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var apiErr apiError
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("bigquery: %s %s: %d %s", method, path, resp.StatusCode, apiErr.Error.Message)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// runJob inserts a query job and waits for its completion.
+func (d *Driver) runJob(ctx context.Context, query string, params []queryParameter) (jobReference, error) {
+	id, err := newJobID()
+	if err != nil {
+		return jobReference{}, err
+	}
+
+	var j job
+	j.JobReference = jobReference{ProjectID: d.project, JobID: id, Location: d.location}
+	j.Configuration.Labels = d.labels
+	j.Configuration.Query.Query = query
+	if len(params) > 0 {
+		j.Configuration.Query.ParameterMode = "NAMED"
+		j.Configuration.Query.QueryParameters = params
+	}
+
+	path := fmt.Sprintf("/projects/%s/jobs", url.PathEscape(d.project))
+	if err := d.do(ctx, http.MethodPost, path, nil, j, &j); err != nil {
+		return jobReference{}, err
+	}
+
+	for j.Status.State != "DONE" {
+		select {
+		case <-ctx.Done():
+			return j.JobReference, ctx.Err()
+		case <-time.After(d.poll):
+		}
+
+		path := fmt.Sprintf("/projects/%s/jobs/%s", url.PathEscape(d.project), url.PathEscape(j.JobReference.JobID))
+		if err := d.do(ctx, http.MethodGet, path, d.locationQuery(), nil, &j); err != nil {
+			return j.JobReference, err
+		}
+	}
+
+	if j.Status.ErrorResult != nil {
+		return j.JobReference, *j.Status.ErrorResult
+	}
+
+	return j.JobReference, nil
+}
+
+// rows returns all rows produced by a completed job.
+func (d *Driver) rows(ctx context.Context, ref jobReference) ([][]interface{}, error) {
+	var rows [][]interface{}
+
+	query := d.locationQuery()
+	path := fmt.Sprintf("/projects/%s/queries/%s", url.PathEscape(d.project), url.PathEscape(ref.JobID))
+
+	for {
+		var results queryResults
+		if err := d.do(ctx, http.MethodGet, path, query, nil, &results); err != nil {
+			return nil, err
+		}
+
+		for _, row := range results.Rows {
+			values := make([]interface{}, len(row.F))
+			for i, f := range row.F {
+				values[i] = f.V
+			}
+			rows = append(rows, values)
+		}
+
+		if results.PageToken == "" {
+			return rows, nil
+		}
+
+		query = d.locationQuery()
+		query.Set("pageToken", results.PageToken)
+	}
+}
+
+func (d *Driver) locationQuery() url.Values {
+	query := url.Values{}
+	if d.location != "" {
+		query.Set("location", d.location)
+	}
+	return query
+}
+
+func parameter(name string, typ string, value string) queryParameter {
+	var p queryParameter
+	p.Name = name
+	p.ParameterType.Type = typ
+	p.ParameterValue.Value = value
+	return p
+}
@@ -0,0 +1,218 @@
+// Package bigquery provides a darwin.Driver running DDL and DML migrations on
+// Google BigQuery through its REST API.
+//
+// Each migration is submitted as a single query job, so multi-statement
+// scripts and scripting blocks (DECLARE, BEGIN ... END, IF) work as in the
+// console. The driver waits for every job to complete. The history is stored
+// in the darwin_migrations table of a dataset.
+//
+// The driver expects an authenticated *http.Client, for example one returned
+// by google.DefaultClient from golang.org/x/oauth2/google with the
+// https://www.googleapis.com/auth/bigquery scope.
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// DefaultEndpoint is the base URL of the BigQuery API.
+const DefaultEndpoint = "https://bigquery.googleapis.com/bigquery/v2"
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithEndpoint sets the base URL of the API, for emulators and tests.
+func WithEndpoint(endpoint string) Option {
+	return func(d *Driver) {
+		d.endpoint = endpoint
+	}
+}
+
+// WithLocation sets the location jobs run in, like "EU" or "us-central1".
+func WithLocation(location string) Option {
+	return func(d *Driver) {
+		d.location = location
+	}
+}
+
+// WithLabels sets labels added to every job, for cost attribution.
+func WithLabels(labels map[string]string) Option {
+	return func(d *Driver) {
+		d.labels = labels
+	}
+}
+
+// WithTimeout sets how long the driver waits for a job to complete.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.timeout = timeout
+	}
+}
+
+// Driver is a darwin.Driver for BigQuery.
+type Driver struct {
+	client   *http.Client
+	endpoint string
+	project  string
+	dataset  string
+	location string
+	labels   map[string]string
+	timeout  time.Duration
+	poll     time.Duration
+	name     string
+}
+
+// New creates a new Driver running jobs in project and storing the history
+// in dataset.
+func New(client *http.Client, project string, dataset string, opts ...Option) (*Driver, error) {
+	if client == nil {
+		return nil, errors.New("bigquery: http client is nil")
+	}
+
+	if project == "" || dataset == "" {
+		return nil, errors.New("bigquery: project and dataset are required")
+	}
+
+	d := Driver{
+		client:   client,
+		endpoint: DefaultEndpoint,
+		project:  project,
+		dataset:  dataset,
+		name:     "darwin_migrations",
+		timeout:  30 * time.Minute,
+		poll:     time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep the history in the table name of the
+// dataset, which can't be qualified by another dataset.
+func (d *Driver) SetTableName(name string) error {
+	if name == "" || strings.ContainsAny(name, ".`") {
+		return fmt.Errorf("bigquery: invalid table name %q, it must be a table of the dataset", name)
+	}
+
+	d.name = name
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	c := *d
+	if err := c.SetTableName(name); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// table returns the quoted name of the history table.
+func (d *Driver) table() string {
+	return fmt.Sprintf("`%s.%s.%s`", d.project, d.dataset, d.name)
+}
+
+// Create creates the table darwin_migrations if necessary.
+func (d *Driver) Create() error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	_, err := d.runJob(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+                (
+                    version        FLOAT64 NOT NULL,
+                    description    STRING  NOT NULL,
+                    checksum       STRING  NOT NULL,
+                    applied_at     INT64   NOT NULL,
+                    execution_time INT64   NOT NULL
+                )`, d.table()), nil)
+	return err
+}
+
+// Insert inserts a migration entry into database.
+func (d *Driver) Insert(e darwin.MigrationRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf(`INSERT INTO %s
+                (version, description, checksum, applied_at, execution_time)
+            VALUES (@version, @description, @checksum, @applied_at, @execution_time)`, d.table())
+
+	_, err := d.runJob(ctx, query, []queryParameter{
+		parameter("version", "FLOAT64", strconv.FormatFloat(e.Version, 'g', -1, 64)),
+		parameter("description", "STRING", e.Description),
+		parameter("checksum", "STRING", e.Checksum),
+		parameter("applied_at", "INT64", strconv.FormatInt(e.AppliedAt.Unix(), 10)),
+		parameter("execution_time", "INT64", strconv.FormatInt(int64(e.ExecutionTime), 10)),
+	})
+	return err
+}
+
+// All returns all migrations applied.
+func (d *Driver) All() ([]darwin.MigrationRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	query := fmt.Sprintf(`SELECT version, description, checksum, applied_at, execution_time
+            FROM %s
+            ORDER BY version ASC`, d.table())
+
+	ref, err := d.runJob(ctx, query, nil)
+	if err != nil {
+		return []darwin.MigrationRecord{}, err
+	}
+
+	rows, err := d.rows(ctx, ref)
+	if err != nil {
+		return []darwin.MigrationRecord{}, err
+	}
+
+	var records []darwin.MigrationRecord
+	for _, row := range rows {
+		if len(row) != 5 {
+			return []darwin.MigrationRecord{}, fmt.Errorf("bigquery: unexpected row %v", row)
+		}
+
+		version, _ := strconv.ParseFloat(str(row[0]), 64)
+		appliedAt, _ := strconv.ParseInt(str(row[3]), 10, 64)
+		executionTime, _ := strconv.ParseInt(str(row[4]), 10, 64)
+
+		records = append(records, darwin.MigrationRecord{
+			Version:       version,
+			Description:   str(row[1]),
+			Checksum:      str(row[2]),
+			AppliedAt:     time.Unix(appliedAt, 0),
+			ExecutionTime: time.Duration(executionTime),
+		})
+	}
+
+	return records, nil
+}
+
+// Exec runs the script as a single query job and waits for its completion.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	_, err := d.runJob(ctx, script, nil)
+	return time.Since(start), err
+}
+
+// str returns a cell value, the API returns all scalars as strings.
+func str(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
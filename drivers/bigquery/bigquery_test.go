@@ -0,0 +1,113 @@
+package bigquery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// fakeAPI is a BigQuery API completing every job on the second poll and
+// answering every query with rows.
+type fakeAPI struct {
+	jobs   map[string]job
+	polls  map[string]int
+	rows   string
+	labels map[string]string
+	fail   string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/jobs"):
+		var j job
+		json.NewDecoder(r.Body).Decode(&j)
+		f.labels = j.Configuration.Labels
+		j.Status.State = "RUNNING"
+		f.jobs[j.JobReference.JobID] = j
+		json.NewEncoder(w).Encode(j)
+
+	case strings.Contains(r.URL.Path, "/jobs/"):
+		id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		j := f.jobs[id]
+		f.polls[id]++
+		if f.polls[id] > 1 {
+			j.Status.State = "DONE"
+			if f.fail != "" && strings.Contains(j.Configuration.Query.Query, f.fail) {
+				j.Status.ErrorResult = &JobError{Reason: "invalidQuery", Message: "Syntax error"}
+			}
+		}
+		json.NewEncoder(w).Encode(j)
+
+	case strings.Contains(r.URL.Path, "/queries/"):
+		w.Write([]byte(`{"jobComplete": true, "rows": ` + f.rows + `}`))
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newFakeDriver(t *testing.T, api *fakeAPI) *Driver {
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	d, err := New(server.Client(), "project", "dataset",
+		WithEndpoint(server.URL),
+		WithLabels(map[string]string{"team": "data"}),
+	)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+	d.poll = time.Millisecond
+
+	return d
+}
+
+func Test_Driver_Exec(t *testing.T) {
+	api := &fakeAPI{jobs: map[string]job{}, polls: map[string]int{}}
+	d := newFakeDriver(t, api)
+
+	if _, err := d.Exec("CREATE TABLE dataset.users (id INT64);"); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if api.labels["team"] != "data" {
+		t.Errorf("Must label the jobs, got %v", api.labels)
+	}
+
+	api.fail = "BROKEN"
+	_, err := d.Exec("BROKEN")
+	if jobErr, ok := err.(JobError); !ok || jobErr.Reason != "invalidQuery" {
+		t.Errorf("Must report the job error, got %v", err)
+	}
+}
+
+func Test_Driver_All(t *testing.T) {
+	api := &fakeAPI{
+		jobs:  map[string]job{},
+		polls: map[string]int{},
+		rows:  `[{"f": [{"v": "1.1"}, {"v": "Users"}, {"v": "abc"}, {"v": "1500000000"}, {"v": "1000"}]}]`,
+	}
+	d := newFakeDriver(t, api)
+
+	records, err := d.All()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := darwin.MigrationRecord{
+		Version:       1.1,
+		Description:   "Users",
+		Checksum:      "abc",
+		AppliedAt:     time.Unix(1500000000, 0),
+		ExecutionTime: time.Microsecond,
+	}
+
+	if len(records) != 1 || records[0] != expected {
+		t.Errorf("Expected %#v, got %#v", expected, records)
+	}
+}
@@ -0,0 +1,288 @@
+// Package cassandra provides a darwin.Driver executing CQL migrations on
+// Apache Cassandra and compatible databases like ScyllaDB.
+//
+// The driver works with any client through the Session interface. With
+// github.com/gocql/gocql it is a few lines:
+//
+//	type session struct{ s *gocql.Session }
+//
+//	func (s session) Exec(ctx context.Context, stmt string, values ...interface{}) error {
+//		return s.s.Query(stmt, values...).WithContext(ctx).Exec()
+//	}
+//
+//	func (s session) ExecCAS(ctx context.Context, stmt string, values ...interface{}) (bool, error) {
+//		return s.s.Query(stmt, values...).WithContext(ctx).MapScanCAS(map[string]interface{}{})
+//	}
+//
+//	func (s session) SliceMap(ctx context.Context, stmt string, values ...interface{}) ([]map[string]interface{}, error) {
+//		return s.s.Query(stmt, values...).WithContext(ctx).Iter().SliceMap()
+//	}
+//
+//	func (s session) AwaitSchemaAgreement(ctx context.Context) error {
+//		return s.s.AwaitSchemaAgreement(ctx)
+//	}
+//
+// History records are inserted with a lightweight transaction, so two
+// appliers can never record the same version.
+package cassandra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Session executes CQL statements.
+type Session interface {
+	Exec(ctx context.Context, stmt string, values ...interface{}) error
+	ExecCAS(ctx context.Context, stmt string, values ...interface{}) (applied bool, err error)
+	SliceMap(ctx context.Context, stmt string, values ...interface{}) ([]map[string]interface{}, error)
+	AwaitSchemaAgreement(ctx context.Context) error
+}
+
+// AlreadyAppliedError is used to report when another applier recorded the
+// migration first.
+type AlreadyAppliedError struct {
+	Version float64
+}
+
+func (a AlreadyAppliedError) Error() string {
+	return fmt.Sprintf("cassandra: migration %f was recorded by another applier", a.Version)
+}
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithSchemaAgreement makes the driver wait until all nodes of the cluster
+// agree on the schema after each migration, so following migrations and the
+// application don't see a stale schema.
+func WithSchemaAgreement() Option {
+	return func(d *Driver) {
+		d.schemaAgreement = true
+	}
+}
+
+// WithTimeout sets the timeout of each statement, including waiting for
+// schema agreement.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.timeout = timeout
+	}
+}
+
+// Driver is a darwin.Driver for Cassandra.
+type Driver struct {
+	session         Session
+	keyspace        string
+	schemaAgreement bool
+	timeout         time.Duration
+	tableName       string
+}
+
+// New creates a new Driver storing the history in the darwin_migrations
+// table of keyspace.
+func New(session Session, keyspace string, opts ...Option) (*Driver, error) {
+	if session == nil {
+		return nil, errors.New("cassandra: session is nil")
+	}
+
+	if keyspace == "" {
+		return nil, errors.New("cassandra: keyspace is empty")
+	}
+
+	d := Driver{
+		session:   session,
+		keyspace:  keyspace,
+		timeout:   time.Minute,
+		tableName: "darwin_migrations",
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep the history in the table name of the
+// keyspace, which can't be qualified by another keyspace.
+func (d *Driver) SetTableName(name string) error {
+	if name == "" || strings.Contains(name, ".") {
+		return fmt.Errorf("cassandra: invalid table name %q, it must be a table of the keyspace", name)
+	}
+
+	d.tableName = name
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	c := *d
+	if err := c.SetTableName(name); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (d *Driver) table() string {
+	return quoteIdentifier(d.keyspace) + "." + quoteIdentifier(d.tableName)
+}
+
+// Create creates the table darwin_migrations if necessary.
+func (d *Driver) Create() error {
+	stmt := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+                (
+                    version        double,
+                    description    text,
+                    checksum       text,
+                    applied_at     bigint,
+                    execution_time bigint,
+                    PRIMARY KEY    (version)
+                )`, d.table())
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	if err := d.session.Exec(ctx, stmt); err != nil {
+		return err
+	}
+
+	return d.awaitSchemaAgreement(ctx)
+}
+
+// Insert inserts a migration entry into database with a lightweight
+// transaction.
+func (d *Driver) Insert(e darwin.MigrationRecord) error {
+	stmt := fmt.Sprintf(`INSERT INTO %s
+                (version, description, checksum, applied_at, execution_time)
+            VALUES (?, ?, ?, ?, ?)
+            IF NOT EXISTS`, d.table())
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	applied, err := d.session.ExecCAS(ctx, stmt,
+		e.Version,
+		e.Description,
+		e.Checksum,
+		e.AppliedAt.Unix(),
+		int64(e.ExecutionTime),
+	)
+	if err != nil {
+		return err
+	}
+
+	if !applied {
+		return AlreadyAppliedError{Version: e.Version}
+	}
+
+	return nil
+}
+
+// All returns all migrations applied.
+func (d *Driver) All() ([]darwin.MigrationRecord, error) {
+	stmt := fmt.Sprintf(`SELECT version, description, checksum, applied_at, execution_time FROM %s`, d.table())
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	rows, err := d.session.SliceMap(ctx, stmt)
+	if err != nil {
+		return []darwin.MigrationRecord{}, err
+	}
+
+	var records []darwin.MigrationRecord
+	for _, row := range rows {
+		records = append(records, darwin.MigrationRecord{
+			Version:       toFloat(row["version"]),
+			Description:   toString(row["description"]),
+			Checksum:      toString(row["checksum"]),
+			AppliedAt:     time.Unix(toInt(row["applied_at"]), 0),
+			ExecutionTime: time.Duration(toInt(row["execution_time"])),
+		})
+	}
+
+	// Rows are returned in token order.
+	sort.Slice(records, func(i, j int) bool { return records[i].Version < records[j].Version })
+
+	return records, nil
+}
+
+// syntax is the syntax of CQL scripts: $$ quoted function bodies and
+// comments starting with -- or //.
+var syntax = darwin.Syntax{DollarQuotes: true, DashAndSlashComments: true}
+
+// SplitStatements splits a CQL script into its statements with the syntax
+// of darwin.SplitStatements. Semicolons in string literals, quoted
+// identifiers, $$ quoted function bodies and comments don't end a
+// statement. The statements are returned without their trailing semicolon;
+// statements holding only comments are dropped.
+func SplitStatements(script string) []string {
+	return darwin.SplitStatements(script, syntax)
+}
+
+// Exec executes the statements of the script one at a time, CQL has no
+// multi-statement queries.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	start := time.Now()
+
+	for i, stmt := range SplitStatements(script) {
+		ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+		err := d.session.Exec(ctx, stmt)
+		cancel()
+
+		if err != nil {
+			return time.Since(start), fmt.Errorf("cassandra: statement %d: %w", i+1, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	return time.Since(start), d.awaitSchemaAgreement(ctx)
+}
+
+func (d *Driver) awaitSchemaAgreement(ctx context.Context) error {
+	if !d.schemaAgreement {
+		return nil
+	}
+	return d.session.AwaitSchemaAgreement(ctx)
+}
+
+// quoteIdentifier quotes a CQL identifier.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	}
+	return 0
+}
+
+func toInt(v interface{}) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case int:
+		return int64(n)
+	case int32:
+		return int64(n)
+	}
+	return 0
+}
+
+func toString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
@@ -0,0 +1,116 @@
+package cassandra
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+type fakeSession struct {
+	executed   []string
+	versions   map[float64]bool
+	rows       []map[string]interface{}
+	agreements int
+}
+
+func (s *fakeSession) Exec(ctx context.Context, stmt string, values ...interface{}) error {
+	s.executed = append(s.executed, stmt)
+	return nil
+}
+
+func (s *fakeSession) ExecCAS(ctx context.Context, stmt string, values ...interface{}) (bool, error) {
+	if s.versions == nil {
+		s.versions = map[float64]bool{}
+	}
+
+	version := values[0].(float64)
+	if s.versions[version] {
+		return false, nil
+	}
+	s.versions[version] = true
+
+	s.rows = append(s.rows, map[string]interface{}{
+		"version":        version,
+		"description":    values[1],
+		"checksum":       values[2],
+		"applied_at":     values[3],
+		"execution_time": values[4],
+	})
+
+	return true, nil
+}
+
+func (s *fakeSession) SliceMap(ctx context.Context, stmt string, values ...interface{}) ([]map[string]interface{}, error) {
+	return s.rows, nil
+}
+
+func (s *fakeSession) AwaitSchemaAgreement(ctx context.Context) error {
+	s.agreements++
+	return nil
+}
+
+func Test_SplitStatements(t *testing.T) {
+	script := `CREATE TABLE a (id int PRIMARY KEY, name text); -- trailing; comment
+INSERT INTO a (id, name) VALUES (1, 'it''s; here');
+// slashes; comment
+CREATE FUNCTION f (x int) RETURNS NULL ON NULL INPUT RETURNS int LANGUAGE java AS $$ return x; $$;
+/* block; */`
+
+	expected := []string{
+		"CREATE TABLE a (id int PRIMARY KEY, name text)",
+		"-- trailing; comment\nINSERT INTO a (id, name) VALUES (1, 'it''s; here')",
+		"// slashes; comment\nCREATE FUNCTION f (x int) RETURNS NULL ON NULL INPUT RETURNS int LANGUAGE java AS $$ return x; $$",
+	}
+
+	if got := SplitStatements(script); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func Test_Driver_Migrate(t *testing.T) {
+	session := &fakeSession{}
+
+	d, err := New(session, "app", WithSchemaAgreement())
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	migrations := []darwin.Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id uuid PRIMARY KEY);\nCREATE INDEX ON users (id);"},
+		{Version: 2, Description: "Posts", Script: "CREATE TABLE posts (id uuid PRIMARY KEY);"},
+	}
+
+	if err := darwin.Migrate(d, migrations); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(session.executed) != 4 {
+		t.Errorf("Must execute each statement on its own, got %q", session.executed)
+	}
+
+	if session.agreements != 3 {
+		t.Errorf("Must wait for schema agreement after each change, got %d", session.agreements)
+	}
+
+	records, _ := d.All()
+	if len(records) != 2 || records[1].Description != "Posts" {
+		t.Errorf("Must record the migrations, got %#v", records)
+	}
+}
+
+func Test_Driver_Insert_already_applied(t *testing.T) {
+	session := &fakeSession{versions: map[float64]bool{1: true}}
+
+	d, err := New(session, "app")
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	err = d.Insert(darwin.MigrationRecord{Version: 1, AppliedAt: time.Now()})
+	if _, ok := err.(AlreadyAppliedError); !ok {
+		t.Errorf("Expected AlreadyAppliedError, got %v", err)
+	}
+}
@@ -0,0 +1,352 @@
+// Package cockroach provides a darwin.Driver for CockroachDB.
+//
+// A migration is executed and recorded in the same transaction. Transactions
+// aborted with a retry error (SQLSTATE 40001) are replayed with an
+// exponential backoff. CockroachDB has no advisory locks, Lock inserts a
+// row in the darwin_locks table instead. Schema changes run as background
+// jobs, a migration is only reported as executed once the jobs it started
+// have finished.
+//
+// The driver doesn't import a Postgres database/sql driver, use it with
+// github.com/jackc/pgx/v5/stdlib, github.com/lib/pq or any compatible driver.
+package cockroach
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dustinevan/darwin"
+	"github.com/dustinevan/darwin/drivers/internal/dbutil"
+)
+
+// Default values used by New.
+const (
+	DefaultAttempts            = 5
+	DefaultBackoff             = 50 * time.Millisecond
+	DefaultLockName            = "darwin_migrations"
+	DefaultLockTimeout         = time.Minute
+	DefaultSchemaChangeTimeout = 10 * time.Minute
+)
+
+// ErrLockTimeout is returned by Lock when the lock is held by another
+// applier for longer than the lock timeout.
+var ErrLockTimeout = dbutil.ErrLockTimeout
+
+// schemaChangeFailure is the SQLSTATE reported when a transaction committed
+// but the schema changes it started failed.
+const schemaChangeFailure = "XXA00"
+
+// SchemaChangeError is used to report a schema change job which failed after
+// the migration transaction committed.
+type SchemaChangeError struct {
+	JobID int64
+	Err   string
+}
+
+func (s SchemaChangeError) Error() string {
+	return fmt.Sprintf("cockroach: schema change job %d failed: %s", s.JobID, s.Err)
+}
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithRetries sets how many times a transaction is attempted and the initial
+// wait between attempts.
+func WithRetries(attempts int, backoff time.Duration) Option {
+	return func(d *Driver) {
+		d.attempts = attempts
+		d.backoff = backoff
+	}
+}
+
+// WithLockTimeout sets how long Lock waits for the lock. A zero or negative
+// timeout waits forever.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.lock.Timeout = timeout
+	}
+}
+
+// WithLockExpiry makes Lock take over a lock which wasn't refreshed for
+// expiry, left by an applier which crashed. Use it with darwin.WithHeartbeat
+// and an interval well under expiry, so a long migration keeps its lock.
+func WithLockExpiry(expiry time.Duration) Option {
+	return func(d *Driver) {
+		d.lock.Expiry = expiry
+	}
+}
+
+// WithSchemaChangeTimeout sets how long Exec waits for the schema change
+// jobs started by a migration.
+func WithSchemaChangeTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.schemaChangeTimeout = timeout
+	}
+}
+
+// Driver is a darwin.Driver for CockroachDB.
+type Driver struct {
+	*darwin.GenericDriver
+
+	attempts            int
+	backoff             time.Duration
+	schemaChangeTimeout time.Duration
+	lock                dbutil.TableLock
+}
+
+// New creates a new Driver for the CockroachDB database db.
+func New(db *sql.DB, opts ...Option) (*Driver, error) {
+	generic, err := darwin.NewGenericDriver(db, Dialect{})
+	if err != nil {
+		return nil, err
+	}
+
+	d := Driver{
+		GenericDriver:       generic,
+		attempts:            DefaultAttempts,
+		backoff:             DefaultBackoff,
+		schemaChangeTimeout: DefaultSchemaChangeTimeout,
+		lock: dbutil.TableLock{
+			DB:          db,
+			Table:       "darwin_locks",
+			Name:        DefaultLockName,
+			Placeholder: dbutil.Dollar,
+			Timeout:     DefaultLockTimeout,
+			Poll:        time.Second,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep the history in the table name, the
+// name of its lock in the darwin_locks table too.
+func (d *Driver) SetTableName(name string) error {
+	if err := d.GenericDriver.SetTableName(name); err != nil {
+		return err
+	}
+
+	d.lock.Name = name
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	generic, err := d.GenericDriver.WithTable(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *d
+	c.GenericDriver = generic
+	return &c, nil
+}
+
+// Create creates the table darwin_migrations if necessary.
+func (d *Driver) Create() error {
+	return d.retry(d.GenericDriver.Create)
+}
+
+// Insert inserts a migration entry into database.
+func (d *Driver) Insert(e darwin.MigrationRecord) error {
+	return d.retry(func() error {
+		return d.GenericDriver.Insert(e)
+	})
+}
+
+// Exec executes the script in a transaction, retried on retry errors, and
+// waits for the schema changes it started to complete. The statements of a
+// script with the no-transaction directive are executed one by one instead,
+// and the set directives are applied to the session while it runs.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	return d.ExecContext(context.Background(), script)
+}
+
+// ExecContext is Exec with a context.
+func (d *Driver) ExecContext(ctx context.Context, script string) (time.Duration, error) {
+	start := time.Now()
+
+	conn, err := d.DB.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	// The jobs are matched on the clock of the cluster, the local one may
+	// be ahead of it.
+	var since time.Time
+	if err := conn.QueryRowContext(ctx, "SELECT now()").Scan(&since); err != nil {
+		return time.Since(start), err
+	}
+
+	err = dbutil.WithSettings(ctx, conn, Dialect{}, script, func() error {
+		if dbutil.NoTransaction(script) {
+			for _, stmt := range darwin.SplitStatements(script, darwin.PostgresSyntax) {
+				if _, err := conn.ExecContext(ctx, stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		return d.retry(func() error {
+			tx, err := conn.BeginTx(ctx, nil)
+			if err != nil {
+				return err
+			}
+
+			if _, err := tx.ExecContext(ctx, script); err != nil {
+				tx.Rollback()
+				return err
+			}
+
+			return tx.Commit()
+		})
+	})
+
+	if err != nil {
+		if dbutil.SQLState(err) == schemaChangeFailure {
+			return time.Since(start), fmt.Errorf("cockroach: transaction committed but the schema change failed: %w", err)
+		}
+		return time.Since(start), err
+	}
+
+	return time.Since(start), d.waitForSchemaChanges(ctx, since)
+}
+
+// BeginTx starts a transaction executing migrations and recording them,
+// replayed on retry errors. Its commit waits for the schema changes it
+// started to complete.
+func (d *Driver) BeginTx(ctx context.Context) (darwin.Tx, error) {
+	var since time.Time
+	if err := d.DB.QueryRowContext(ctx, "SELECT now()").Scan(&since); err != nil {
+		return nil, err
+	}
+
+	tx, err := dbutil.BeginRetryTx(ctx, d.GenericDriver.BeginTx, d.retry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction{RetryTx: tx, d: d, ctx: ctx, since: since}, nil
+}
+
+// transaction is the darwin.Tx of the driver.
+type transaction struct {
+	*dbutil.RetryTx
+
+	d     *Driver
+	ctx   context.Context
+	since time.Time
+}
+
+// Commit commits the transaction and waits for the schema changes it
+// started.
+func (t *transaction) Commit() error {
+	if err := t.RetryTx.Commit(); err != nil {
+		if dbutil.SQLState(err) == schemaChangeFailure {
+			return fmt.Errorf("cockroach: transaction committed but the schema change failed: %w", err)
+		}
+		return err
+	}
+
+	return t.d.waitForSchemaChanges(t.ctx, t.since)
+}
+
+// waitForSchemaChanges waits for the schema change jobs created since the
+// cluster time since to finish, returning a SchemaChangeError for the first
+// failed one.
+func (d *Driver) waitForSchemaChanges(ctx context.Context, since time.Time) error {
+	const jobs = `SELECT job_id, status, COALESCE(error, '')
+            FROM [SHOW JOBS]
+            WHERE job_type IN ('SCHEMA CHANGE', 'NEW SCHEMA CHANGE') AND created >= $1`
+
+	deadline := time.Now().Add(d.schemaChangeTimeout)
+	for {
+		rows, err := d.DB.QueryContext(ctx, jobs, since)
+		if err != nil {
+			return err
+		}
+
+		running := false
+		for rows.Next() {
+			var (
+				id     int64
+				status string
+				msg    string
+			)
+
+			if err := rows.Scan(&id, &status, &msg); err != nil {
+				rows.Close()
+				return err
+			}
+
+			switch status {
+			case "succeeded":
+			case "failed", "canceled":
+				rows.Close()
+				return SchemaChangeError{JobID: id, Err: msg}
+			default:
+				running = true
+			}
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		if !running {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("cockroach: schema changes still running after %s", d.schemaChangeTimeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Lock acquires the migration lock by inserting a row in darwin_locks.
+func (d *Driver) Lock() error {
+	return d.lock.Lock()
+}
+
+// Unlock releases the lock acquired by Lock.
+func (d *Driver) Unlock() error {
+	return d.lock.Unlock()
+}
+
+// RefreshLock keeps the migration lock from expiring, it is called by the
+// heartbeat of darwin.WithHeartbeat.
+func (d *Driver) RefreshLock() error {
+	return d.lock.Refresh()
+}
+
+// LockHolder returns the holder of the migration lock, if it is held.
+func (d *Driver) LockHolder() (darwin.LockHolder, bool, error) {
+	return d.lock.Holder()
+}
+
+// BreakLock releases the migration lock whoever holds it, to recover from
+// an applier which crashed.
+func (d *Driver) BreakLock() error {
+	return d.lock.Break()
+}
+
+func (d *Driver) retry(f func() error) error {
+	return dbutil.Retry(d.attempts, d.backoff, dbutil.IsSerializationFailure, f)
+}
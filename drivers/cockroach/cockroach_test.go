@@ -0,0 +1,160 @@
+package cockroach
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dustinevan/darwin"
+)
+
+type stateError string
+
+func (s stateError) Error() string    { return "state " + string(s) }
+func (s stateError) SQLState() string { return string(s) }
+
+func Test_Driver_Exec_retry(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db, WithRetries(2, time.Millisecond))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	stmt := "CREATE TABLE a (id INT8);"
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT now()")).
+		WillReturnRows(sqlmock.NewRows([]string{"now"}).AddRow(time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(stmt)).WillReturnError(stateError("40001"))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(stmt)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM [SHOW JOBS]")).
+		WillReturnRows(sqlmock.NewRows([]string{"job_id", "status", "error"}).AddRow(1, "succeeded", ""))
+
+	if _, err := d.Exec(stmt); err != nil {
+		t.Fatalf("Must retry the transaction, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_Exec_schema_change_failed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	stmt := "CREATE UNIQUE INDEX ON a (name);"
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT now()")).
+		WillReturnRows(sqlmock.NewRows([]string{"now"}).AddRow(time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(stmt)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM [SHOW JOBS]")).
+		WillReturnRows(sqlmock.NewRows([]string{"job_id", "status", "error"}).AddRow(7, "failed", "duplicate key value"))
+
+	_, err = d.Exec(stmt)
+	if sc, ok := err.(SchemaChangeError); !ok || sc.JobID != 7 {
+		t.Errorf("Must report the failed schema change, got %v", err)
+	}
+}
+
+func Test_Driver_Exec_no_transaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	script := "-- darwin:no-transaction\n-- darwin:set sql_safe_updates = false\nCREATE INDEX ON a (name);\nDELETE FROM a;\n"
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT now()")).
+		WillReturnRows(sqlmock.NewRows([]string{"now"}).AddRow(now))
+	mock.ExpectExec(regexp.QuoteMeta("SET sql_safe_updates = false")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE INDEX ON a (name)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM a")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("RESET sql_safe_updates")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("FROM [SHOW JOBS]")).
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows([]string{"job_id", "status", "error"}).AddRow(1, "succeeded", ""))
+
+	if _, err := d.Exec(script); err != nil {
+		t.Fatalf("Must execute the statements outside of a transaction, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_BeginTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	stmt := "CREATE INDEX ON a (name);"
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT now()")).
+		WillReturnRows(sqlmock.NewRows([]string{"now"}).AddRow(now))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(stmt)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta("FROM [SHOW JOBS]")).
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows([]string{"job_id", "status", "error"}).AddRow(3, "failed", "duplicate key value"))
+
+	tx, err := d.BeginTx(context.Background())
+	if err != nil {
+		t.Fatalf("Must begin a transaction, got %s", err)
+	}
+
+	if _, err := tx.Exec(context.Background(), stmt); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := tx.Insert(context.Background(), darwin.MigrationRecord{Version: 1, Description: "A", AppliedAt: now}); err != nil {
+		t.Fatalf("Must record the migration, got %s", err)
+	}
+
+	err = tx.Commit()
+	if sc, ok := err.(SchemaChangeError); !ok || sc.JobID != 3 {
+		t.Errorf("Must wait for the schema changes of the transaction, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
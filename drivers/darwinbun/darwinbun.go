@@ -0,0 +1,203 @@
+// Package darwinbun runs darwin migrations over the connection of a
+// *bun.DB. Every statement goes through bun, so the query hooks of the
+// application, for tracing, logging or metrics, see the migrations as well.
+//
+// The package doesn't import bun. A *bun.DB implements DB, and the
+// transactions are started by a function returning a bun.Tx:
+//
+//	driver, err := darwinbun.New(db, darwin.PostgresDialect{}, func(ctx context.Context) (darwinbun.Tx, error) {
+//		return db.BeginTx(ctx, nil)
+//	})
+package darwinbun
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// DefaultTimeout is the timeout of each call to the database used by New.
+const DefaultTimeout = time.Hour
+
+// DB is the part of *bun.DB used by the driver.
+type DB interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Tx is the part of bun.Tx used by the driver.
+type Tx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	Commit() error
+	Rollback() error
+}
+
+// BeginFunc starts a transaction.
+type BeginFunc func(ctx context.Context) (Tx, error)
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithTimeout sets the timeout of each call to the database.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.timeout = timeout
+	}
+}
+
+// Driver is a darwin.Driver running on a bun database.
+type Driver struct {
+	db      DB
+	dialect darwin.Dialect
+	begin   BeginFunc
+	timeout time.Duration
+}
+
+// New creates a new Driver for the bun database db. The history table is
+// written with the SQL of dialect, and migrations run in the transactions
+// started by begin.
+func New(db DB, dialect darwin.Dialect, begin BeginFunc, opts ...Option) (*Driver, error) {
+	if db == nil {
+		return nil, errors.New("darwinbun: db is nil")
+	}
+
+	if dialect == nil {
+		return nil, errors.New("darwinbun: dialect is nil")
+	}
+
+	if begin == nil {
+		return nil, errors.New("darwinbun: begin is nil")
+	}
+
+	d := Driver{
+		db:      db,
+		dialect: dialect,
+		begin:   begin,
+		timeout: DefaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep the history in the table name, when
+// its dialect is a darwin.TableDialect.
+func (d *Driver) SetTableName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("darwinbun: the table name is empty")
+	}
+
+	t, ok := d.dialect.(darwin.TableDialect)
+	if !ok {
+		return errors.New("darwinbun: the dialect can't change the name of the history table")
+	}
+
+	d.dialect = t.WithTable(name)
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	c := *d
+	if err := c.SetTableName(name); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Create creates the table darwin_migrations if necessary.
+func (d *Driver) Create() error {
+	return d.transaction(func(ctx context.Context, tx Tx) error {
+		_, err := tx.ExecContext(ctx, d.dialect.CreateTableSQL())
+		return err
+	})
+}
+
+// Insert inserts a migration entry into database.
+func (d *Driver) Insert(e darwin.MigrationRecord) error {
+	return d.transaction(func(ctx context.Context, tx Tx) error {
+		_, err := tx.ExecContext(ctx, d.dialect.InsertSQL(),
+			e.Version,
+			e.Description,
+			e.Checksum,
+			e.AppliedAt.Unix(),
+			e.ExecutionTime,
+		)
+		return err
+	})
+}
+
+// All returns all migrations applied.
+func (d *Driver) All() ([]darwin.MigrationRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	rows, err := d.db.QueryContext(ctx, d.dialect.AllSQL())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []darwin.MigrationRecord
+	for rows.Next() {
+		var (
+			version       float64
+			description   string
+			checksum      string
+			appliedAt     int64
+			executionTime float64
+		)
+
+		if err := rows.Scan(&version, &description, &checksum, &appliedAt, &executionTime); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, darwin.MigrationRecord{
+			Version:       version,
+			Description:   description,
+			Checksum:      checksum,
+			AppliedAt:     time.Unix(appliedAt, 0),
+			ExecutionTime: time.Duration(executionTime),
+		})
+	}
+
+	return entries, rows.Err()
+}
+
+// Exec executes the script in a transaction.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	start := time.Now()
+
+	err := d.transaction(func(ctx context.Context, tx Tx) error {
+		_, err := tx.ExecContext(ctx, script)
+		return err
+	})
+
+	return time.Since(start), err
+}
+
+// transaction runs f in a transaction started by begin.
+func (d *Driver) transaction(f func(context.Context, Tx) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	tx, err := d.begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := f(ctx, tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,89 @@
+package darwinbun
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dustinevan/darwin"
+)
+
+// hookedDB counts the queries like a bun query hook would.
+type hookedDB struct {
+	*sql.DB
+	queries *int
+}
+
+func (h hookedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	*h.queries++
+	return h.DB.ExecContext(ctx, query, args...)
+}
+
+func (h hookedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	*h.queries++
+	return h.DB.QueryContext(ctx, query, args...)
+}
+
+type hookedTx struct {
+	*sql.Tx
+	queries *int
+}
+
+func (h hookedTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	*h.queries++
+	return h.Tx.ExecContext(ctx, query, args...)
+}
+
+func Test_Driver_Migrate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	queries := 0
+	dialect := darwin.PostgresDialect{}
+
+	d, err := New(hookedDB{DB: db, queries: &queries}, dialect, func(ctx context.Context) (Tx, error) {
+		tx, err := db.BeginTx(ctx, nil)
+		return hookedTx{Tx: tx, queries: &queries}, err
+	})
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	columns := []string{"version", "description", "checksum", "applied_at", "execution_time"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(dialect.CreateTableSQL())).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(dialect.AllSQL())).WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE users (id INT);")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(dialect.InsertSQL())).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	migrations := []darwin.Migration{{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"}}
+
+	if err := darwin.Migrate(d, migrations); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if queries != 4 {
+		t.Errorf("Must run every query through bun, got %d queries", queries)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_New_nil(t *testing.T) {
+	if _, err := New(nil, darwin.PostgresDialect{}, nil); err == nil {
+		t.Errorf("Must return an error when db is nil")
+	}
+}
@@ -0,0 +1,67 @@
+// Package darwinent bridges darwin and entgo.io/ent: it builds a
+// darwin.Driver from the SQL driver of an ent client and reads the versioned
+// migration directory generated by ent as darwin migrations, so ent users get
+// darwin's validation, Info and locking on their schema changes.
+//
+// The package doesn't import ent, the driver returned by entsql.Open or
+// entsql.OpenDB implements the Driver interface:
+//
+//	drv, err := entsql.Open(dialect.Postgres, dsn)
+//	if err != nil {
+//		return err
+//	}
+//
+//	driver, err := darwinent.New(drv)
+//	if err != nil {
+//		return err
+//	}
+//
+//	migrations, err := darwinent.NewMigrationDir(os.DirFS("ent/migrate"), "migrations").Migrations()
+//	if err != nil {
+//		return err
+//	}
+//
+//	if err := darwin.New(driver, migrations).Migrate(); err != nil {
+//		return err
+//	}
+//
+//	client := ent.NewClient(ent.Driver(drv))
+package darwinent
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/dustinevan/darwin"
+	"github.com/dustinevan/darwin/drivers/mysql"
+	"github.com/dustinevan/darwin/drivers/sqlite"
+)
+
+// Driver is the part of the ent SQL driver used by the package.
+type Driver interface {
+	DB() *sql.DB
+	Dialect() string
+}
+
+// New creates a darwin.Driver for the database of the ent driver drv: the
+// mysql and sqlite drivers of darwin for these databases and a generic
+// driver with darwin.PostgresDialect for postgres.
+func New(drv Driver) (darwin.Driver, error) {
+	if drv == nil {
+		return nil, errors.New("darwinent: driver is nil")
+	}
+
+	db := drv.DB()
+
+	switch name := drv.Dialect(); name {
+	case "mysql":
+		return mysql.New(db)
+	case "sqlite3":
+		return sqlite.New(db)
+	case "postgres":
+		return darwin.NewGenericDriver(db, darwin.PostgresDialect{})
+	default:
+		return nil, fmt.Errorf("darwinent: unsupported dialect %q", name)
+	}
+}
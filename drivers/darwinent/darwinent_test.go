@@ -0,0 +1,73 @@
+package darwinent
+
+import (
+	"database/sql"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dustinevan/darwin"
+	"github.com/dustinevan/darwin/drivers/sqlite"
+)
+
+type entDriver struct {
+	db      *sql.DB
+	dialect string
+}
+
+func (e entDriver) DB() *sql.DB     { return e.db }
+func (e entDriver) Dialect() string { return e.dialect }
+
+func Test_New(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	driver, err := New(entDriver{db: db, dialect: "sqlite3"})
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+	if _, ok := driver.(*sqlite.Driver); !ok {
+		t.Errorf("Expected the sqlite driver, got %T", driver)
+	}
+
+	if _, err := New(entDriver{db: db, dialect: "gremlin"}); err == nil {
+		t.Errorf("Must return an error for unsupported dialects")
+	}
+}
+
+func Test_MigrationDir_Migrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/20220318104614_create_users.sql":   {Data: []byte("CREATE TABLE users (id INT);")},
+		"migrations/20220320090000_add_email.up.sql":   {Data: []byte("ALTER TABLE users ADD email TEXT;")},
+		"migrations/20220320090000_add_email.down.sql": {Data: []byte("ALTER TABLE users DROP email;")},
+		"migrations/atlas.sum":                         {Data: []byte("h1:abc=")},
+	}
+
+	migrations, err := NewMigrationDir(fsys, "migrations").Migrations()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := []darwin.Migration{
+		{Version: 20220318104614, Description: "create users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 20220320090000, Description: "add email", Script: "ALTER TABLE users ADD email TEXT;"},
+	}
+
+	if len(migrations) != len(expected) {
+		t.Fatalf("Expected %d migrations, got %d", len(expected), len(migrations))
+	}
+
+	for i, m := range migrations {
+		if m.Version != expected[i].Version || m.Description != expected[i].Description || m.Script != expected[i].Script {
+			t.Errorf("Expected %#v, got %#v", expected[i], m)
+		}
+	}
+
+	fsys["migrations/latest_users.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	if _, err := NewMigrationDir(fsys, "migrations").Migrations(); err == nil {
+		t.Errorf("Must return an error for files without version")
+	}
+}
@@ -0,0 +1,90 @@
+package darwinent
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dustinevan/darwin"
+)
+
+// MigrationDir reads the versioned migration directory written by ent. Both
+// the Atlas format, 20220318104614_create_users.sql, and the golang-migrate
+// format, 20220318104614_create_users.up.sql, are supported. The version of
+// a migration is the timestamp of its file and the description the rest of
+// its name. Down files are ignored, and so is atlas.sum: darwin checksums
+// the migrations it applies.
+type MigrationDir struct {
+	FS  fs.FS
+	Dir string
+}
+
+// NewMigrationDir returns a MigrationDir reading dir in fsys.
+func NewMigrationDir(fsys fs.FS, dir string) MigrationDir {
+	return MigrationDir{FS: fsys, Dir: dir}
+}
+
+// Migrations implements the darwin.Source interface.
+func (m MigrationDir) Migrations() ([]darwin.Migration, error) {
+	dir := m.Dir
+	if dir == "" {
+		dir = "."
+	}
+
+	files, err := fs.Sub(m.FS, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	var migrations []darwin.Migration
+	for _, name := range names {
+		if strings.HasSuffix(name, ".down.sql") {
+			continue
+		}
+
+		version, description, err := parseName(name)
+		if err != nil {
+			return nil, fmt.Errorf("darwinent: %s: %w", path.Join(dir, name), err)
+		}
+
+		content, err := fs.ReadFile(files, name)
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, darwin.Migration{
+			Version:     version,
+			Description: description,
+			Script:      string(content),
+			Files:       files,
+		})
+	}
+
+	return migrations, nil
+}
+
+// parseName returns the version and the description of a migration file.
+func parseName(name string) (float64, string, error) {
+	base := strings.TrimSuffix(strings.TrimSuffix(name, ".sql"), ".up")
+
+	version, description := base, ""
+	if i := strings.Index(base, "_"); i >= 0 {
+		version, description = base[:i], base[i+1:]
+	}
+
+	v, err := strconv.ParseFloat(version, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid version %q", version)
+	}
+
+	return v, strings.Replace(description, "_", " ", -1), nil
+}
@@ -0,0 +1,126 @@
+// Package darwingorm runs darwin migrations on the database of a *gorm.DB,
+// for applications which want checksum verified SQL migrations instead of
+// AutoMigrate.
+//
+// The driver is chosen from the name of the GORM dialector: the mysql,
+// sqlite and sqlserver drivers of darwin for these databases, a generic
+// driver with darwin.PostgresDialect for postgres, and the dialect registered
+// in darwin under the name for the others.
+//
+// The package doesn't import GORM. Migrations are usually run right after
+// opening the database:
+//
+//	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+//	if err != nil {
+//		return err
+//	}
+//	if err := darwingorm.Migrate(db, migrations); err != nil {
+//		return err
+//	}
+//
+// or from a GORM plugin, so every db.Use of the plugin migrates before the
+// application serves traffic:
+//
+//	type migrate struct{ darwingorm.Plugin }
+//
+//	func (m migrate) Initialize(db *gorm.DB) error { return m.Migrate(db) }
+//
+//	db.Use(migrate{darwingorm.Plugin{Migrations: migrations}})
+package darwingorm
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/dustinevan/darwin"
+	"github.com/dustinevan/darwin/drivers/mysql"
+	"github.com/dustinevan/darwin/drivers/sqlite"
+	"github.com/dustinevan/darwin/drivers/sqlserver"
+)
+
+// DB is the part of *gorm.DB used by the package.
+type DB interface {
+	DB() (*sql.DB, error)
+	Name() string
+}
+
+// Option configures how the driver is built.
+type Option func(*options)
+
+type options struct {
+	dialect darwin.Dialect
+}
+
+// WithDialect makes New return a generic driver with the dialect, whatever
+// the dialector of the GORM database.
+func WithDialect(dialect darwin.Dialect) Option {
+	return func(o *options) {
+		o.dialect = dialect
+	}
+}
+
+// New creates a darwin.Driver for the database of the GORM handle db.
+func New(db DB, opts ...Option) (darwin.Driver, error) {
+	if db == nil {
+		return nil, errors.New("darwingorm: db is nil")
+	}
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("darwingorm: %w", err)
+	}
+
+	if o.dialect != nil {
+		return darwin.NewGenericDriver(sqlDB, o.dialect)
+	}
+
+	switch name := db.Name(); name {
+	case "mysql":
+		return mysql.New(sqlDB)
+	case "sqlite":
+		return sqlite.New(sqlDB)
+	case "sqlserver":
+		return sqlserver.New(sqlDB)
+	case "postgres":
+		return darwin.NewGenericDriver(sqlDB, darwin.PostgresDialect{})
+	default:
+		dialect, ok := darwin.LookupDialect(name)
+		if !ok {
+			return nil, fmt.Errorf("darwingorm: no dialect registered for %q, use WithDialect", name)
+		}
+		return darwin.NewGenericDriver(sqlDB, dialect)
+	}
+}
+
+// Migrate executes the missing migrations on the database of db.
+func Migrate(db DB, migrations []darwin.Migration, opts ...Option) error {
+	driver, err := New(db, opts...)
+	if err != nil {
+		return err
+	}
+
+	return darwin.New(driver, migrations).Migrate()
+}
+
+// Plugin migrates the database GORM is initializing the plugin with. It
+// becomes a gorm.Plugin with an Initialize method calling Migrate.
+type Plugin struct {
+	Migrations []darwin.Migration
+	Options    []Option
+}
+
+// Name returns the name the plugin is registered with in GORM.
+func (Plugin) Name() string {
+	return "darwin"
+}
+
+// Migrate executes the missing migrations on the database of db.
+func (p Plugin) Migrate(db DB) error {
+	return Migrate(db, p.Migrations, p.Options...)
+}
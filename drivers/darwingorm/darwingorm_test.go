@@ -0,0 +1,86 @@
+package darwingorm
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dustinevan/darwin"
+	"github.com/dustinevan/darwin/drivers/mysql"
+)
+
+// handle has the methods of *gorm.DB used by the package.
+type handle struct {
+	db   *sql.DB
+	name string
+	err  error
+}
+
+func (h handle) DB() (*sql.DB, error) { return h.db, h.err }
+func (h handle) Name() string         { return h.name }
+
+func Test_New(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	driver, err := New(handle{db: db, name: "mysql"})
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+	if _, ok := driver.(*mysql.Driver); !ok {
+		t.Errorf("Expected the mysql driver, got %T", driver)
+	}
+
+	driver, err = New(handle{db: db, name: "postgres"})
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+	if generic, ok := driver.(*darwin.GenericDriver); !ok || generic.Dialect != (darwin.PostgresDialect{}) {
+		t.Errorf("Expected a generic driver for postgres, got %#v", driver)
+	}
+
+	if _, err := New(handle{db: db, name: "clickhouse"}); err == nil {
+		t.Errorf("Must return an error for unknown dialectors")
+	}
+
+	if _, err := New(handle{name: "mysql", err: errors.New("closed")}); err == nil {
+		t.Errorf("Must return the error of DB")
+	}
+}
+
+func Test_Plugin_Migrate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	dialect := darwin.StandardDialect{}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(".*CREATE TABLE IF NOT EXISTS darwin_migrations.*").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(".*SELECT script.*").WillReturnRows(sqlmock.NewRows([]string{"script"}))
+	mock.ExpectQuery(".*SELECT MAX\\(version\\).*").WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE users").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(".*INSERT INTO darwin_migrations.*").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	plugin := Plugin{
+		Migrations: []darwin.Migration{{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"}},
+		Options:    []Option{WithDialect(dialect)},
+	}
+
+	if err := plugin.Migrate(handle{db: db, name: "oracle"}); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
@@ -0,0 +1,242 @@
+// Package databricks provides a darwin.Driver for Databricks SQL warehouses,
+// to manage Unity Catalog schemas and Delta tables.
+//
+// Databricks has no transactions spanning several statements, so the driver
+// splits migrations into statements and runs them one at a time; when a
+// statement fails, the statements before it stay applied. The history is
+// stored in a Delta table. Delta commits are optimistic, a write conflicting
+// with a concurrent one (like OPTIMIZE running on the same table) fails with
+// a DELTA_CONCURRENT_* error, such statements are retried.
+//
+// The driver doesn't import the Databricks database/sql driver, use it with
+// github.com/databricks/databricks-sql-go, selecting the warehouse with DSN:
+//
+//	db, err := sql.Open("databricks", databricks.DSN(host, warehouseID, token))
+//	driver, err := databricks.New(db, databricks.WithCatalog("main"), databricks.WithSchema("sales"))
+package databricks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+	"github.com/dustinevan/darwin/drivers/internal/dbutil"
+)
+
+// Default values used by New.
+const (
+	DefaultAttempts = 5
+	DefaultBackoff  = time.Second
+)
+
+// StatementError is used to report the statement of a migration which
+// failed, the statements before it are applied.
+type StatementError struct {
+	Statement string
+	Err       error
+}
+
+func (s StatementError) Error() string {
+	return fmt.Sprintf("databricks: statement %q failed: %s", s.Statement, s.Err)
+}
+
+func (s StatementError) Unwrap() error {
+	return s.Err
+}
+
+// DSN returns the data source name connecting the Databricks database/sql
+// driver to the SQL warehouse with the given id of the workspace at host,
+// authenticating with a personal access token.
+func DSN(host string, warehouseID string, token string) string {
+	return fmt.Sprintf("token:%s@%s:443/sql/1.0/warehouses/%s", url.PathEscape(token), host, url.PathEscape(warehouseID))
+}
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithCatalog sets the Unity Catalog catalog the migrations run in and the
+// history is stored in.
+func WithCatalog(catalog string) Option {
+	return func(d *Driver) {
+		d.dialect.Catalog = catalog
+	}
+}
+
+// WithSchema sets the schema the migrations run in and the history is stored
+// in.
+func WithSchema(schema string) Option {
+	return func(d *Driver) {
+		d.dialect.Schema = schema
+	}
+}
+
+// WithRetries sets how many times a statement failing with a Delta
+// concurrent modification error is attempted and the initial wait between
+// attempts.
+func WithRetries(attempts int, backoff time.Duration) Option {
+	return func(d *Driver) {
+		d.attempts = attempts
+		d.backoff = backoff
+	}
+}
+
+// Driver is a darwin.Driver for Databricks.
+type Driver struct {
+	*darwin.GenericDriver
+
+	dialect  Dialect
+	attempts int
+	backoff  time.Duration
+}
+
+// New creates a new Driver for the Databricks SQL warehouse db.
+func New(db *sql.DB, opts ...Option) (*Driver, error) {
+	d := Driver{
+		attempts: DefaultAttempts,
+		backoff:  DefaultBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	generic, err := darwin.NewGenericDriver(db, d.dialect)
+	if err != nil {
+		return nil, err
+	}
+	d.GenericDriver = generic
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep the history in the table name,
+// qualified by its schema, or its catalog and schema, or not.
+func (d *Driver) SetTableName(name string) error {
+	if err := d.GenericDriver.SetTableName(name); err != nil {
+		return err
+	}
+
+	d.dialect = d.Dialect.(Dialect)
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	generic, err := d.GenericDriver.WithTable(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *d
+	c.GenericDriver = generic
+	c.dialect = generic.Dialect.(Dialect)
+	return &c, nil
+}
+
+// Create creates the table darwin_migrations if necessary, and adds the
+// metadata columns to the tables created without them. Databricks doesn't
+// support transactions, the statements run on their own.
+func (d *Driver) Create() error {
+	err := d.retry(func() error {
+		_, err := d.DB.Exec(d.dialect.CreateTableSQL())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, u := range d.dialect.HistoryUpgrades() {
+		if rows, err := d.DB.Query(u.Probe); err == nil {
+			rows.Close()
+			continue
+		}
+
+		for _, stmt := range u.Statements {
+			err := d.retry(func() error {
+				_, err := d.DB.Exec(stmt)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("databricks: unable to upgrade the history table to layout %d: %w", u.Layout, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Insert inserts a migration entry into database, with its metadata.
+func (d *Driver) Insert(e darwin.MigrationRecord) error {
+	query, args := darwin.InsertArgs(d.dialect, e)
+	return d.retry(func() error {
+		_, err := d.DB.Exec(query, args...)
+		return err
+	})
+}
+
+// Exec executes the statements of the script one at a time, in the catalog
+// and schema of the driver.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	return d.ExecContext(context.Background(), script)
+}
+
+// ExecContext is Exec with a context.
+func (d *Driver) ExecContext(ctx context.Context, script string) (time.Duration, error) {
+	start := time.Now()
+
+	statements := SplitStatements(script)
+
+	var prelude []string
+	if d.dialect.Catalog != "" {
+		prelude = append(prelude, "USE CATALOG "+quoteIdentifier(d.dialect.Catalog))
+	}
+	if d.dialect.Schema != "" {
+		prelude = append(prelude, "USE SCHEMA "+quoteIdentifier(d.dialect.Schema))
+	}
+
+	// USE only changes the session of a connection.
+	conn, err := d.DB.Conn(ctx)
+	if err != nil {
+		return time.Since(start), err
+	}
+	defer conn.Close()
+
+	for _, statement := range prelude {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return time.Since(start), err
+		}
+	}
+
+	for _, statement := range statements {
+		err := d.retry(func() error {
+			_, err := conn.ExecContext(ctx, statement)
+			return err
+		})
+		if err != nil {
+			return time.Since(start), StatementError{Statement: statement, Err: err}
+		}
+	}
+
+	return time.Since(start), nil
+}
+
+// BeginTx returns darwin.ErrTransactionUnsupported, migrations are executed
+// with Exec and recorded with Insert.
+func (d *Driver) BeginTx(ctx context.Context) (darwin.Tx, error) {
+	return nil, darwin.ErrTransactionUnsupported
+}
+
+// retry calls f until it doesn't fail with a concurrent modification error.
+func (d *Driver) retry(f func() error) error {
+	return dbutil.Retry(d.attempts, d.backoff, isConcurrentModification, f)
+}
+
+// isConcurrentModification reports if err is a Delta write conflict, like
+// DELTA_CONCURRENT_APPEND or DELTA_CONCURRENT_DELETE_READ.
+func isConcurrentModification(err error) bool {
+	return strings.Contains(err.Error(), "DELTA_CONCURRENT_") || strings.Contains(err.Error(), "ConcurrentAppendException")
+}
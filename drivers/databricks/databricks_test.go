@@ -0,0 +1,136 @@
+package databricks
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dustinevan/darwin"
+)
+
+func Test_DSN(t *testing.T) {
+	dsn := DSN("adb-123.azuredatabricks.net", "abc123", "dapi-secret")
+	expected := "token:dapi-secret@adb-123.azuredatabricks.net:443/sql/1.0/warehouses/abc123"
+
+	if dsn != expected {
+		t.Errorf("Expected %s, got %s", expected, dsn)
+	}
+}
+
+func Test_Driver_Exec(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db, WithCatalog("main"), WithSchema("sales"), WithRetries(2, time.Millisecond))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("USE CATALOG `main`")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("USE SCHEMA `sales`")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE orders (id BIGINT) USING DELTA")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE orders ADD COLUMN note STRING")).WillReturnError(errors.New("[DELTA_CONCURRENT_APPEND] ConcurrentAppendException"))
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE orders ADD COLUMN note STRING")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if _, err := d.Exec("CREATE TABLE orders (id BIGINT) USING DELTA;\nALTER TABLE orders ADD COLUMN note STRING;\n"); err != nil {
+		t.Fatalf("Must retry concurrent modifications, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_Exec_error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("DROP TABLE orders")).WillReturnError(errors.New("[TABLE_OR_VIEW_NOT_FOUND]"))
+
+	_, err = d.Exec("DROP TABLE orders;")
+
+	var statementErr StatementError
+	if !errors.As(err, &statementErr) || statementErr.Statement != "DROP TABLE orders" {
+		t.Errorf("Must report the failed statement, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_Insert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db, WithCatalog("main"), WithSchema("sales"))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	record := darwin.MigrationRecord{Version: 1, Description: "Orders", Checksum: "abc", AppliedAt: time.Unix(1700000000, 0), ExecutionTime: 1500}
+	record.AppliedBy = "deploy"
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `main`.`sales`.darwin_migrations")).
+		WithArgs(1.0, "Orders", "abc", int64(1700000000), int64(1500), "deploy", nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if err := d.Insert(record); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_Create_upgrade(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	// A history table created without the metadata columns.
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS darwin_migrations")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT applied_by, hostname, app_version, source FROM darwin_migrations")).
+		WillReturnError(errors.New("UNRESOLVED_COLUMN"))
+	for _, column := range []string{"applied_by", "hostname", "app_version", "source"} {
+		mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE darwin_migrations ADD COLUMN " + column + " STRING")).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT script FROM darwin_migrations")).
+		WillReturnError(errors.New("UNRESOLVED_COLUMN"))
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE darwin_migrations ADD COLUMN script STRING")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := d.Create(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
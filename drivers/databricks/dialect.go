@@ -0,0 +1,151 @@
+package databricks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Dialect is the darwin.Dialect used by the Databricks driver. The history
+// is stored in a Delta table of Catalog.Schema, or of the current schema of
+// the session when they are empty.
+type Dialect struct {
+	Catalog string
+	Schema  string
+
+	// Table is the name of the history table, darwin_migrations by
+	// default.
+	Table string
+}
+
+// table returns the qualified name of the history table.
+func (d Dialect) table() string {
+	var parts []string
+	for _, p := range []string{d.Catalog, d.Schema} {
+		if p != "" {
+			parts = append(parts, quoteIdentifier(p))
+		}
+	}
+
+	table := "darwin_migrations"
+	if d.Table != "" {
+		table = quoteIdentifier(d.Table)
+	}
+	return strings.Join(append(parts, table), ".")
+}
+
+// WithTable returns the dialect using the table name, qualified by its
+// schema, or its catalog and schema, which then replace those of d.
+func (d Dialect) WithTable(name string) darwin.Dialect {
+	parts := strings.Split(name, ".")
+	switch len(parts) {
+	case 2:
+		d.Schema = parts[0]
+	case 3:
+		d.Catalog, d.Schema = parts[0], parts[1]
+	}
+	d.Table = parts[len(parts)-1]
+	return d
+}
+
+// CreateTableSQL returns the SQL to create the schema table.
+func (d Dialect) CreateTableSQL() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+                (
+                    version        DOUBLE NOT NULL,
+                    description    STRING NOT NULL,
+                    checksum       STRING NOT NULL,
+                    applied_at     BIGINT NOT NULL,
+                    execution_time BIGINT NOT NULL,
+                    applied_by     STRING,
+                    hostname       STRING,
+                    app_version    STRING,
+                    source         STRING,
+                    script         STRING
+                )
+            USING DELTA`, d.table())
+}
+
+// InsertSQL returns the SQL to insert a new migration in the schema table.
+func (d Dialect) InsertSQL() string {
+	return fmt.Sprintf(`INSERT INTO %s
+                (
+                    version,
+                    description,
+                    checksum,
+                    applied_at,
+                    execution_time
+                )
+            VALUES (?, ?, ?, ?, ?)`, d.table())
+}
+
+// AllSQL returns a SQL to get all entries in the table.
+func (d Dialect) AllSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time
+            FROM
+                %s
+            ORDER BY version ASC`, d.table())
+}
+
+// DeleteSQL returns the SQL to delete the record of a migration.
+func (d Dialect) DeleteSQL() string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE version = ?`, d.table())
+}
+
+// standard returns the darwin.StandardDialect of the history table, which
+// writes the SQL of the metadata columns.
+func (d Dialect) standard() darwin.StandardDialect {
+	return darwin.StandardDialect{
+		Table:       d.table(),
+		Placeholder: darwin.PlaceholderQuestion,
+		StringType:  "STRING",
+		TextType:    "STRING",
+		AddColumn:   "ADD COLUMN",
+	}
+}
+
+// InsertMetadataSQL returns the SQL to insert a new migration in the schema
+// table with its metadata.
+func (d Dialect) InsertMetadataSQL() string {
+	return d.standard().InsertMetadataSQL()
+}
+
+// AllMetadataSQL returns a SQL to get all entries in the table with their
+// metadata.
+func (d Dialect) AllMetadataSQL() string {
+	return d.standard().AllMetadataSQL()
+}
+
+// ScriptSQL returns the SQL to get the script stored with a migration.
+func (d Dialect) ScriptSQL() string {
+	return d.standard().ScriptSQL()
+}
+
+// AllSinceSQL returns a SQL to get the entries in the table from a version
+// on, with their metadata.
+func (d Dialect) AllSinceSQL() string {
+	return d.standard().AllSinceSQL()
+}
+
+// LatestVersionSQL returns a SQL to get the greatest version in the table,
+// NULL when it is empty.
+func (d Dialect) LatestVersionSQL() string {
+	return d.standard().LatestVersionSQL()
+}
+
+// HistoryUpgrades returns the upgrades adding the metadata columns to the
+// history tables created without them.
+func (d Dialect) HistoryUpgrades() []darwin.HistoryUpgrade {
+	return d.standard().HistoryUpgrades()
+}
+
+// quoteIdentifier returns name as a quoted identifier.
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
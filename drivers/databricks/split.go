@@ -0,0 +1,16 @@
+package databricks
+
+import "github.com/dustinevan/darwin"
+
+// syntax is the syntax of Databricks scripts, quoting identifiers with
+// double quotes or backticks.
+var syntax = darwin.Syntax{Backticks: true}
+
+// SplitStatements splits a script into its statements with the Databricks
+// syntax of darwin.SplitStatements. Semicolons in quoted strings, quoted
+// identifiers and comments don't end a statement. The statements are
+// returned without their trailing semicolon; statements holding only
+// comments are dropped, Databricks rejecting empty queries.
+func SplitStatements(script string) []string {
+	return darwin.SplitStatements(script, syntax)
+}
@@ -0,0 +1,126 @@
+package duckdb
+
+import (
+	"fmt"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Dialect is the darwin.Dialect used by the DuckDB driver.
+type Dialect struct {
+	// Table is the name of the history table, darwin_migrations by
+	// default, qualified by its schema or not.
+	Table string
+}
+
+func (d Dialect) table() string {
+	return darwin.QuoteTable(d.Table, `"`)
+}
+
+// WithTable returns the dialect using the table name.
+func (d Dialect) WithTable(name string) darwin.Dialect {
+	d.Table = name
+	return d
+}
+
+// CreateTableSQL returns the SQL to create the schema table.
+func (d Dialect) CreateTableSQL() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+                (
+                    version        DOUBLE  NOT NULL,
+                    description    VARCHAR NOT NULL,
+                    checksum       VARCHAR NOT NULL,
+                    applied_at     BIGINT  NOT NULL,
+                    execution_time BIGINT  NOT NULL,
+                    applied_by     VARCHAR,
+                    hostname       VARCHAR,
+                    app_version    VARCHAR,
+                    source         VARCHAR,
+                    script         VARCHAR,
+                    PRIMARY KEY    (version)
+                );`, d.table())
+}
+
+// InsertSQL returns the SQL to insert a new migration in the schema table.
+func (d Dialect) InsertSQL() string {
+	return fmt.Sprintf(`INSERT INTO %s
+                (
+                    version,
+                    description,
+                    checksum,
+                    applied_at,
+                    execution_time
+                )
+            VALUES (?, ?, ?, ?, ?);`, d.table())
+}
+
+// AllSQL returns a SQL to get all entries in the table.
+func (d Dialect) AllSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time
+            FROM
+                %s
+            ORDER BY version ASC;`, d.table())
+}
+
+// DeleteSQL returns the SQL to delete the record of a migration.
+func (d Dialect) DeleteSQL() string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE version = ?;`, d.table())
+}
+
+// standard returns the darwin.StandardDialect of the history table, which
+// writes the SQL of the metadata columns.
+func (d Dialect) standard() darwin.StandardDialect {
+	return darwin.StandardDialect{
+		Table:       d.table(),
+		Placeholder: darwin.PlaceholderQuestion,
+		StringType:  "VARCHAR",
+		TextType:    "VARCHAR",
+		AddColumn:   "ADD COLUMN",
+	}
+}
+
+// InsertMetadataSQL returns the SQL to insert a new migration in the schema
+// table with its metadata.
+func (d Dialect) InsertMetadataSQL() string {
+	return d.standard().InsertMetadataSQL()
+}
+
+// AllMetadataSQL returns a SQL to get all entries in the table with their
+// metadata.
+func (d Dialect) AllMetadataSQL() string {
+	return d.standard().AllMetadataSQL()
+}
+
+// ScriptSQL returns the SQL to get the script stored with a migration.
+func (d Dialect) ScriptSQL() string {
+	return d.standard().ScriptSQL()
+}
+
+// AllSinceSQL returns a SQL to get the entries in the table from a version
+// on, with their metadata.
+func (d Dialect) AllSinceSQL() string {
+	return d.standard().AllSinceSQL()
+}
+
+// LatestVersionSQL returns a SQL to get the greatest version in the table,
+// NULL when it is empty.
+func (d Dialect) LatestVersionSQL() string {
+	return d.standard().LatestVersionSQL()
+}
+
+// HistoryUpgrades returns the upgrades adding the metadata columns to the
+// history tables created without them.
+func (d Dialect) HistoryUpgrades() []darwin.HistoryUpgrade {
+	return d.standard().HistoryUpgrades()
+}
+
+// Syntax returns the syntax of the statements, quoting function bodies with
+// $$ like Postgres.
+func (d Dialect) Syntax() darwin.Syntax {
+	return darwin.Syntax{DollarQuotes: true}
+}
@@ -0,0 +1,275 @@
+// Package duckdb provides a darwin.Driver for DuckDB.
+//
+// A migration is executed and recorded in the same transaction. Extensions
+// are loaded on the connection running a migration before it starts,
+// either listed with WithExtensions or written in the script as INSTALL and
+// LOAD statements, which are executed before the rest of the script. A database file can only be opened by a single process,
+// WithLockWait makes the driver wait for another process to release it
+// instead of failing at once.
+//
+// Connections opened from the same sql.DB share the same database, so an
+// in-memory database (an empty DSN) can be migrated and used as usual as
+// long as the sql.DB is kept open.
+//
+// The driver doesn't import a DuckDB database/sql driver, use it with
+// github.com/marcboeker/go-duckdb or any compatible driver.
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// ErrDatabaseLocked is returned when the database file is held by another
+// process for longer than the lock wait.
+var ErrDatabaseLocked = errors.New("duckdb: database file is locked by another process")
+
+// extensionStatement matches the statements managing extensions.
+var extensionStatement = regexp.MustCompile(`(?i)^\s*(FORCE\s+)?(INSTALL|LOAD)\s+\S+(\s+FROM\s+\S+)?\s*;?\s*$`)
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithExtensions sets extensions installed and loaded before each migration.
+func WithExtensions(extensions ...string) Option {
+	return func(d *Driver) {
+		d.extensions = extensions
+	}
+}
+
+// WithLockWait sets how long the driver waits for another process to
+// release the database file.
+func WithLockWait(wait time.Duration) Option {
+	return func(d *Driver) {
+		d.lockWait = wait
+	}
+}
+
+// Driver is a darwin.Driver for DuckDB.
+type Driver struct {
+	*darwin.GenericDriver
+
+	extensions []string
+	lockWait   time.Duration
+}
+
+// New creates a new Driver for the DuckDB database db.
+func New(db *sql.DB, opts ...Option) (*Driver, error) {
+	generic, err := darwin.NewGenericDriver(db, Dialect{})
+	if err != nil {
+		return nil, err
+	}
+
+	d := Driver{GenericDriver: generic}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	generic, err := d.GenericDriver.WithTable(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *d
+	c.GenericDriver = generic
+	return &c, nil
+}
+
+// Create creates the table darwin_migrations if necessary, waiting for the
+// database file to be released by another process.
+func (d *Driver) Create() error {
+	deadline := time.Now().Add(d.lockWait)
+
+	for {
+		err := d.GenericDriver.Create()
+		if !isLockError(err) {
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w: %s", ErrDatabaseLocked, err)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// Exec loads the extensions needed by the script and executes the rest of
+// it in a transaction.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	return d.ExecContext(context.Background(), script)
+}
+
+// ExecContext is Exec with a context.
+func (d *Driver) ExecContext(ctx context.Context, script string) (time.Duration, error) {
+	start := time.Now()
+
+	conn, err := d.DB.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	statements, body := SplitExtensions(script)
+	for _, ext := range d.extensions {
+		statements = append([]string{"INSTALL " + ext, "LOAD " + ext}, statements...)
+	}
+
+	for _, stmt := range statements {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return time.Since(start), err
+		}
+	}
+
+	if strings.TrimSpace(body) == "" {
+		return time.Since(start), nil
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return time.Since(start), err
+	}
+
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		tx.Rollback()
+		return time.Since(start), err
+	}
+
+	return time.Since(start), tx.Commit()
+}
+
+// BeginTx starts a transaction executing migrations and recording them, on
+// a connection where the extensions of WithExtensions are loaded.
+func (d *Driver) BeginTx(ctx context.Context) (darwin.Tx, error) {
+	conn, err := d.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := d.begin(ctx, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &transaction{conn: conn, tx: tx, dialect: d.Dialect}, nil
+}
+
+// begin loads the extensions of WithExtensions on the connection and begins
+// a transaction.
+func (d *Driver) begin(ctx context.Context, conn *sql.Conn) (*sql.Tx, error) {
+	for _, ext := range d.extensions {
+		for _, stmt := range []string{"INSTALL " + ext, "LOAD " + ext} {
+			if _, err := conn.ExecContext(ctx, stmt); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return conn.BeginTx(ctx, nil)
+}
+
+// transaction is the darwin.Tx of the driver.
+type transaction struct {
+	conn    *sql.Conn
+	tx      *sql.Tx
+	dialect darwin.Dialect
+}
+
+// Exec executes the script, its INSTALL and LOAD statements first.
+func (t *transaction) Exec(ctx context.Context, script string) (time.Duration, error) {
+	start := time.Now()
+
+	body, err := t.loadExtensions(ctx, script)
+	if err == nil && strings.TrimSpace(body) != "" {
+		_, err = t.tx.ExecContext(ctx, body)
+	}
+	return time.Since(start), err
+}
+
+// ExecStatements executes the statements of the script one at a time, its
+// INSTALL and LOAD statements first.
+func (t *transaction) ExecStatements(ctx context.Context, script string) ([]darwin.StatementResult, error) {
+	body, err := t.loadExtensions(ctx, script)
+	if err != nil {
+		return nil, err
+	}
+	return darwin.ExecStatements(ctx, t.tx, body, Dialect{}.Syntax())
+}
+
+// loadExtensions executes the INSTALL and LOAD statements of the script and
+// returns the rest of it.
+func (t *transaction) loadExtensions(ctx context.Context, script string) (string, error) {
+	statements, body := SplitExtensions(script)
+	for _, stmt := range statements {
+		if _, err := t.tx.ExecContext(ctx, stmt); err != nil {
+			return body, err
+		}
+	}
+	return body, nil
+}
+
+// Insert records the migration.
+func (t *transaction) Insert(ctx context.Context, e darwin.MigrationRecord) error {
+	query, args := darwin.InsertArgs(t.dialect, e)
+	_, err := t.tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+// Delete deletes the record of the migration version.
+func (t *transaction) Delete(ctx context.Context, version float64) error {
+	dd, ok := t.dialect.(darwin.DeleteDialect)
+	if !ok {
+		return errors.New("duckdb: the dialect can't delete migration records")
+	}
+
+	_, err := t.tx.ExecContext(ctx, dd.DeleteSQL(), version)
+	return err
+}
+
+func (t *transaction) Commit() error {
+	defer t.conn.Close()
+	return t.tx.Commit()
+}
+
+func (t *transaction) Rollback() error {
+	defer t.conn.Close()
+	return t.tx.Rollback()
+}
+
+// SplitExtensions takes the INSTALL and LOAD statements, written on their
+// own line, out of a script. It returns them in order and the rest of the
+// script.
+func SplitExtensions(script string) ([]string, string) {
+	var statements []string
+	var body strings.Builder
+
+	for _, line := range strings.SplitAfter(script, "\n") {
+		if extensionStatement.MatchString(line) {
+			statements = append(statements, strings.TrimRight(strings.TrimSpace(line), ";"))
+			continue
+		}
+		body.WriteString(line)
+	}
+
+	return statements, body.String()
+}
+
+// isLockError reports if err is DuckDB failing to lock the database file.
+func isLockError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "Could not set lock on file")
+}
@@ -0,0 +1,118 @@
+package duckdb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dustinevan/darwin"
+)
+
+func Test_SplitExtensions(t *testing.T) {
+	script := "INSTALL spatial;\nload spatial;\nCREATE TABLE a (g GEOMETRY);\nFORCE INSTALL httpfs FROM core_nightly;\n"
+
+	statements, body := SplitExtensions(script)
+
+	expected := []string{"INSTALL spatial", "load spatial", "FORCE INSTALL httpfs FROM core_nightly"}
+	if !reflect.DeepEqual(statements, expected) {
+		t.Errorf("Expected %q, got %q", expected, statements)
+	}
+
+	if body != "CREATE TABLE a (g GEOMETRY);\n" {
+		t.Errorf("Must keep the rest of the script, got %q", body)
+	}
+}
+
+func Test_Driver_Exec(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db, WithExtensions("json"))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectExec("INSTALL json").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("LOAD json").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("LOAD spatial").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE a (id INT);")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	if _, err := d.Exec("LOAD spatial;\nCREATE TABLE a (id INT);\n"); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_Create_locked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db, WithLockWait(time.Millisecond))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectBegin().WillReturnError(errors.New(`IO Error: Could not set lock on file "app.db": Conflicting lock is held`))
+	mock.ExpectBegin().WillReturnError(errors.New(`IO Error: Could not set lock on file "app.db": Conflicting lock is held`))
+
+	if err := d.Create(); !errors.Is(err, ErrDatabaseLocked) {
+		t.Errorf("Expected ErrDatabaseLocked, got %v", err)
+	}
+}
+
+func Test_Driver_BeginTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db, WithExtensions("json"))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectExec("INSTALL json").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("LOAD json").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectExec("LOAD spatial").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE a (id INT);")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO darwin_migrations")).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	tx, err := d.BeginTx(context.Background())
+	if err != nil {
+		t.Fatalf("Must begin a transaction, got %s", err)
+	}
+
+	if _, err := tx.Exec(context.Background(), "LOAD spatial;\nCREATE TABLE a (id INT);\n"); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := tx.Insert(context.Background(), darwin.MigrationRecord{Version: 1, Description: "A", AppliedAt: time.Now()}); err != nil {
+		t.Fatalf("Must record the migration, got %s", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Must commit, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
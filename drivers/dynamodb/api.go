@@ -0,0 +1,117 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// API calls a DynamoDB operation using the JSON protocol of the DynamoDB
+// API: input and output are the JSON documents described in the DynamoDB
+// API reference, with attribute values like {"S": "text"}.
+type API interface {
+	Call(ctx context.Context, operation string, input []byte) ([]byte, error)
+}
+
+// APIError is an error returned by DynamoDB.
+type APIError struct {
+	Type    string
+	Message string
+}
+
+func (a APIError) Error() string {
+	return fmt.Sprintf("dynamodb: %s: %s", a.Type, a.Message)
+}
+
+// isError reports if err is the DynamoDB error named name, for example
+// "ConditionalCheckFailedException".
+func isError(err error, name string) bool {
+	var apiErr APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Type == name
+	}
+	return err != nil && strings.Contains(err.Error(), name)
+}
+
+// HTTPAPI is an API sending requests to a DynamoDB endpoint, like
+// https://dynamodb.us-east-1.amazonaws.com or a DynamoDB Local instance.
+type HTTPAPI struct {
+	Endpoint string
+	Client   *http.Client
+
+	// Sign signs the request with AWS Signature Version 4, for example
+	// with the v4.Signer of github.com/aws/aws-sdk-go-v2. It can be nil
+	// for DynamoDB Local.
+	Sign func(req *http.Request, body []byte) error
+}
+
+// Call implements the API interface.
+func (h HTTPAPI) Call(ctx context.Context, operation string, input []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.Endpoint, bytes.NewReader(input))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	req.Header.Set("X-Amz-Target", "DynamoDB_20120810."+operation)
+
+	if h.Sign != nil {
+		if err := h.Sign(req, input); err != nil {
+			return nil, err
+		}
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(body, &e)
+
+		if i := strings.LastIndex(e.Type, "#"); i >= 0 {
+			e.Type = e.Type[i+1:]
+		}
+		return nil, APIError{Type: e.Type, Message: e.Message}
+	}
+
+	return body, nil
+}
+
+// call marshals input, calls operation and unmarshals its output in output.
+func (d *Driver) call(ctx context.Context, operation string, input interface{}, output interface{}) error {
+	b, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+
+	out, err := d.api.Call(ctx, operation, b)
+	if err != nil {
+		return err
+	}
+
+	if output == nil {
+		return nil
+	}
+
+	return json.Unmarshal(out, output)
+}
@@ -0,0 +1,318 @@
+// Package dynamodb provides a darwin.Driver for Amazon DynamoDB.
+//
+// A migration script holds PartiQL statements terminated by semicolons and
+// JSON documents calling table management operations, typically to create
+// tables and global secondary indexes:
+//
+//	-- Version: 1.0
+//	-- Description: Index users by email
+//	{"Operation": "UpdateTable", "Input": {
+//		"TableName": "users",
+//		"AttributeDefinitions": [{"AttributeName": "email", "AttributeType": "S"}],
+//		"GlobalSecondaryIndexUpdates": [{"Create": {
+//			"IndexName": "email",
+//			"KeySchema": [{"AttributeName": "email", "KeyType": "HASH"}],
+//			"Projection": {"ProjectionType": "ALL"}
+//		}}]
+//	}}
+//	UPDATE users SET active = true WHERE id = 'admin';
+//
+// CreateTable, UpdateTable and DeleteTable are asynchronous, the driver waits
+// for the table and its indexes to be active (or gone) before running the
+// next step. The history is stored in the darwin_migrations table, records
+// are written with a conditional put so two appliers can never record the
+// same version.
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// DefaultTable is the name of the history table.
+const DefaultTable = "darwin_migrations"
+
+// AlreadyAppliedError is used to report when another applier recorded the
+// migration first.
+type AlreadyAppliedError struct {
+	Version float64
+}
+
+func (a AlreadyAppliedError) Error() string {
+	return fmt.Sprintf("dynamodb: migration %f was recorded by another applier", a.Version)
+}
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithTable sets the name of the history table.
+func WithTable(table string) Option {
+	return func(d *Driver) {
+		d.table = table
+	}
+}
+
+// WithTimeout sets how long the driver waits for a migration, including the
+// time tables and indexes take to become active.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.timeout = timeout
+	}
+}
+
+// Driver is a darwin.Driver for DynamoDB.
+type Driver struct {
+	api     API
+	table   string
+	timeout time.Duration
+	poll    time.Duration
+}
+
+// New creates a new Driver calling api.
+func New(api API, opts ...Option) (*Driver, error) {
+	if api == nil {
+		return nil, errors.New("dynamodb: api is nil")
+	}
+
+	d := Driver{
+		api:     api,
+		table:   DefaultTable,
+		timeout: time.Hour,
+		poll:    5 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep the history in the table name, like
+// WithTable.
+func (d *Driver) SetTableName(name string) error {
+	if name == "" {
+		return errors.New("dynamodb: the table name is empty")
+	}
+
+	d.table = name
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	c := *d
+	if err := c.SetTableName(name); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Create creates the history table if necessary.
+func (d *Driver) Create() error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	input := map[string]interface{}{
+		"TableName":            d.table,
+		"BillingMode":          "PAY_PER_REQUEST",
+		"AttributeDefinitions": []map[string]string{{"AttributeName": "version", "AttributeType": "N"}},
+		"KeySchema":            []map[string]string{{"AttributeName": "version", "KeyType": "HASH"}},
+	}
+
+	err := d.call(ctx, "CreateTable", input, nil)
+	if err != nil && !isError(err, "ResourceInUseException") {
+		return err
+	}
+
+	return d.waitForTable(ctx, d.table, false)
+}
+
+// attributeValue is a DynamoDB attribute value holding a string or a number.
+type attributeValue struct {
+	S *string `json:"S,omitempty"`
+	N *string `json:"N,omitempty"`
+}
+
+func stringValue(s string) attributeValue {
+	return attributeValue{S: &s}
+}
+
+func numberValue(n string) attributeValue {
+	return attributeValue{N: &n}
+}
+
+func (a attributeValue) String() string {
+	switch {
+	case a.S != nil:
+		return *a.S
+	case a.N != nil:
+		return *a.N
+	}
+	return ""
+}
+
+// Insert inserts a migration entry into database, failing with an
+// AlreadyAppliedError when the version is already recorded.
+func (d *Driver) Insert(e darwin.MigrationRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	input := map[string]interface{}{
+		"TableName": d.table,
+		"Item": map[string]attributeValue{
+			"version":        numberValue(strconv.FormatFloat(e.Version, 'g', -1, 64)),
+			"description":    stringValue(e.Description),
+			"checksum":       stringValue(e.Checksum),
+			"applied_at":     numberValue(strconv.FormatInt(e.AppliedAt.Unix(), 10)),
+			"execution_time": numberValue(strconv.FormatInt(int64(e.ExecutionTime), 10)),
+		},
+		"ConditionExpression": "attribute_not_exists(version)",
+	}
+
+	err := d.call(ctx, "PutItem", input, nil)
+	if isError(err, "ConditionalCheckFailedException") {
+		return AlreadyAppliedError{Version: e.Version}
+	}
+
+	return err
+}
+
+// All returns all migrations applied.
+func (d *Driver) All() ([]darwin.MigrationRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	var records []darwin.MigrationRecord
+	var start map[string]attributeValue
+
+	for {
+		input := map[string]interface{}{
+			"TableName":      d.table,
+			"ConsistentRead": true,
+		}
+		if start != nil {
+			input["ExclusiveStartKey"] = start
+		}
+
+		var output struct {
+			Items            []map[string]attributeValue `json:"Items"`
+			LastEvaluatedKey map[string]attributeValue   `json:"LastEvaluatedKey"`
+		}
+		if err := d.call(ctx, "Scan", input, &output); err != nil {
+			return []darwin.MigrationRecord{}, err
+		}
+
+		for _, item := range output.Items {
+			version, _ := strconv.ParseFloat(item["version"].String(), 64)
+			appliedAt, _ := strconv.ParseInt(item["applied_at"].String(), 10, 64)
+			executionTime, _ := strconv.ParseInt(item["execution_time"].String(), 10, 64)
+
+			records = append(records, darwin.MigrationRecord{
+				Version:       version,
+				Description:   item["description"].String(),
+				Checksum:      item["checksum"].String(),
+				AppliedAt:     time.Unix(appliedAt, 0),
+				ExecutionTime: time.Duration(executionTime),
+			})
+		}
+
+		if len(output.LastEvaluatedKey) == 0 {
+			return records, nil
+		}
+		start = output.LastEvaluatedKey
+	}
+}
+
+// Exec executes the steps of the script in order.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	start := time.Now()
+
+	steps, err := ParseScript(script)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	for i, step := range steps {
+		if err := d.execStep(ctx, step); err != nil {
+			return time.Since(start), fmt.Errorf("dynamodb: step %d: %w", i+1, err)
+		}
+	}
+
+	return time.Since(start), nil
+}
+
+func (d *Driver) execStep(ctx context.Context, step Step) error {
+	if step.Operation == nil {
+		input := map[string]string{"Statement": step.Statement}
+		return d.call(ctx, "ExecuteStatement", input, nil)
+	}
+
+	op := step.Operation
+	if _, err := d.api.Call(ctx, op.Operation, op.Input); err != nil {
+		return err
+	}
+
+	switch op.Operation {
+	case "CreateTable", "UpdateTable", "DeleteTable":
+		var input struct {
+			TableName string `json:"TableName"`
+		}
+		if err := json.Unmarshal(op.Input, &input); err != nil {
+			return err
+		}
+		return d.waitForTable(ctx, input.TableName, op.Operation == "DeleteTable")
+	}
+
+	return nil
+}
+
+// waitForTable waits until the table and all its global secondary indexes
+// are active, or until the table is deleted when deleted is true.
+func (d *Driver) waitForTable(ctx context.Context, table string, deleted bool) error {
+	for {
+		var output struct {
+			Table struct {
+				TableStatus            string `json:"TableStatus"`
+				GlobalSecondaryIndexes []struct {
+					IndexStatus string `json:"IndexStatus"`
+				} `json:"GlobalSecondaryIndexes"`
+			} `json:"Table"`
+		}
+
+		err := d.call(ctx, "DescribeTable", map[string]string{"TableName": table}, &output)
+		switch {
+		case deleted && isError(err, "ResourceNotFoundException"):
+			return nil
+		case err != nil:
+			return err
+		}
+
+		active := !deleted && output.Table.TableStatus == "ACTIVE"
+		for _, index := range output.Table.GlobalSecondaryIndexes {
+			if index.IndexStatus != "ACTIVE" {
+				active = false
+			}
+		}
+
+		if active {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("dynamodb: waiting for table %s: %w", table, ctx.Err())
+		case <-time.After(d.poll):
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// fakeAPI is an in-memory DynamoDB where tables become active on the second
+// DescribeTable call.
+type fakeAPI struct {
+	calls      []string
+	statements []string
+	items      []map[string]attributeValue
+	describes  int
+}
+
+func (f *fakeAPI) Call(ctx context.Context, operation string, input []byte) ([]byte, error) {
+	f.calls = append(f.calls, operation)
+
+	switch operation {
+	case "DescribeTable":
+		f.describes++
+		if f.describes%2 == 1 {
+			return []byte(`{"Table": {"TableStatus": "ACTIVE", "GlobalSecondaryIndexes": [{"IndexStatus": "CREATING"}]}}`), nil
+		}
+		return []byte(`{"Table": {"TableStatus": "ACTIVE", "GlobalSecondaryIndexes": [{"IndexStatus": "ACTIVE"}]}}`), nil
+
+	case "ExecuteStatement":
+		var in struct{ Statement string }
+		json.Unmarshal(input, &in)
+		f.statements = append(f.statements, in.Statement)
+
+	case "PutItem":
+		var in struct {
+			Item map[string]attributeValue
+		}
+		json.Unmarshal(input, &in)
+		for _, item := range f.items {
+			if item["version"].String() == in.Item["version"].String() {
+				return nil, APIError{Type: "ConditionalCheckFailedException", Message: "The conditional request failed"}
+			}
+		}
+		f.items = append(f.items, in.Item)
+
+	case "Scan":
+		b, _ := json.Marshal(map[string]interface{}{"Items": f.items})
+		return b, nil
+	}
+
+	return []byte(`{}`), nil
+}
+
+func Test_ParseScript(t *testing.T) {
+	script := `-- comment; with semicolon
+INSERT INTO users VALUE {'id': 'a;b'};
+{"Operation": "UpdateTable", "Input": {"TableName": "users"}}
+UPDATE users SET active = true WHERE id = 'a'`
+
+	steps, err := ParseScript(script)
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(steps) != 3 {
+		t.Fatalf("len(steps) == %d, wants 3", len(steps))
+	}
+
+	if steps[0].Statement != "INSERT INTO users VALUE {'id': 'a;b'}" {
+		t.Errorf("Must not split quoted semicolons, got %q", steps[0].Statement)
+	}
+
+	if steps[1].Operation == nil || steps[1].Operation.Operation != "UpdateTable" {
+		t.Errorf("Must parse operations, got %#v", steps[1])
+	}
+
+	if steps[2].Statement != "UPDATE users SET active = true WHERE id = 'a'" {
+		t.Errorf("Must parse the last statement, got %q", steps[2].Statement)
+	}
+}
+
+func Test_Driver_Migrate(t *testing.T) {
+	api := &fakeAPI{}
+
+	d, err := New(api)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+	d.poll = time.Millisecond
+
+	migrations := []darwin.Migration{
+		{Version: 1, Description: "Users", Script: `{"Operation": "CreateTable", "Input": {"TableName": "users"}}`},
+		{Version: 1.1, Description: "Admin", Script: `INSERT INTO users VALUE {'id': 'admin'};`},
+	}
+
+	if err := darwin.Migrate(d, migrations); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if api.describes != 4 {
+		t.Errorf("Must wait for the tables and indexes to be active, got %d calls to DescribeTable", api.describes)
+	}
+
+	if len(api.statements) != 1 {
+		t.Errorf("Must execute the statements, got %q", api.statements)
+	}
+
+	records, _ := d.All()
+	if len(records) != 2 || records[1].Version != 1.1 {
+		t.Errorf("Must record the migrations, got %#v", records)
+	}
+
+	if err := d.Insert(records[0]); !errors.As(err, &AlreadyAppliedError{}) {
+		t.Errorf("Expected AlreadyAppliedError, got %v", err)
+	}
+}
+
+func Test_HTTPAPI_Call(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "DynamoDB_20120810.PutItem" || r.Header.Get("Authorization") != "signed" {
+			t.Errorf("unexpected request headers %v", r.Header)
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"__type": "com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException", "message": "failed"}`))
+	}))
+	defer server.Close()
+
+	api := HTTPAPI{
+		Endpoint: server.URL,
+		Sign: func(req *http.Request, body []byte) error {
+			req.Header.Set("Authorization", "signed")
+			return nil
+		},
+	}
+
+	_, err := api.Call(context.Background(), "PutItem", []byte(`{}`))
+	if !isError(err, "ConditionalCheckFailedException") || !strings.Contains(err.Error(), "failed") {
+		t.Errorf("Must decode the error, got %v", err)
+	}
+}
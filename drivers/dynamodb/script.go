@@ -0,0 +1,100 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Operation is a DynamoDB API call in a migration script, used for table
+// and index management:
+//
+//	{"Operation": "UpdateTable", "Input": {"TableName": "users", ...}}
+type Operation struct {
+	Operation string          `json:"Operation"`
+	Input     json.RawMessage `json:"Input"`
+}
+
+// Step is a PartiQL statement or an Operation of a migration script.
+type Step struct {
+	Statement string
+	Operation *Operation
+}
+
+// ParseScript returns the steps of a migration script: PartiQL statements
+// terminated by semicolons and JSON Operation documents, in any order.
+// Lines starting with "--" are comments.
+func ParseScript(script string) ([]Step, error) {
+	var steps []Step
+
+	s := stripComments(script)
+	for {
+		s = strings.TrimLeft(s, " \t\r\n;")
+		if s == "" {
+			return steps, nil
+		}
+
+		if s[0] == '{' {
+			dec := json.NewDecoder(strings.NewReader(s))
+
+			var op Operation
+			if err := dec.Decode(&op); err != nil {
+				return nil, fmt.Errorf("dynamodb: invalid operation: %w", err)
+			}
+
+			if op.Operation == "" {
+				return nil, fmt.Errorf("dynamodb: operation without name")
+			}
+
+			steps = append(steps, Step{Operation: &op})
+			s = s[dec.InputOffset():]
+			continue
+		}
+
+		end := statementEnd(s)
+		steps = append(steps, Step{Statement: strings.TrimSpace(s[:end])})
+		s = s[end:]
+	}
+}
+
+// stripComments removes the comment lines of a script.
+func stripComments(script string) string {
+	lines := strings.Split(script, "\n")
+	kept := lines[:0]
+
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	return strings.Join(kept, "\n")
+}
+
+// statementEnd returns the index of the semicolon ending the statement at
+// the beginning of s, or len(s).
+func statementEnd(s string) int {
+	var quote byte
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case quote != 0:
+			if c == quote {
+				if i+1 < len(s) && s[i+1] == quote {
+					i++
+					continue
+				}
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ';':
+			return i
+		}
+	}
+
+	return len(s)
+}
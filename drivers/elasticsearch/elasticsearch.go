@@ -0,0 +1,350 @@
+// Package elasticsearch provides a darwin.Driver for Elasticsearch and
+// OpenSearch, to version index templates, mappings, settings and aliases
+// like SQL schemas.
+//
+// A migration script is a sequence of requests to the cluster API, written
+// like in the Kibana console:
+//
+//	-- Version: 1.0
+//	-- Description: Add the tags field to the logs
+//	PUT _index_template/logs
+//	{"index_patterns": ["logs-*"], "template": {"mappings": {"properties": {"tags": {"type": "keyword"}}}}}
+//
+//	PUT logs-000001/_mapping
+//	{"properties": {"tags": {"type": "keyword"}}}
+//
+// The requests run in order, a request failing or not acknowledged by the
+// cluster stops the migration; the requests before it stay applied. The
+// history is stored in the darwin_migrations index, one document per
+// version, created with the _create API so two appliers can never record
+// the same version.
+//
+// The driver uses the REST API directly, authentication is provided with
+// WithHeader or by the *http.Client.
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// DefaultIndex is the name of the history index.
+const DefaultIndex = "darwin_migrations"
+
+// AlreadyAppliedError is used to report when another applier recorded the
+// migration first.
+type AlreadyAppliedError struct {
+	Version float64
+}
+
+func (a AlreadyAppliedError) Error() string {
+	return fmt.Sprintf("elasticsearch: migration %f was recorded by another applier", a.Version)
+}
+
+// RequestError is used to report a request which failed.
+type RequestError struct {
+	Method string
+	Path   string
+	Status int
+	Type   string
+	Reason string
+}
+
+func (r RequestError) Error() string {
+	return fmt.Sprintf("elasticsearch: %s %s: %d %s: %s", r.Method, r.Path, r.Status, r.Type, r.Reason)
+}
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithIndex sets the name of the history index.
+func WithIndex(index string) Option {
+	return func(d *Driver) {
+		d.index = index
+	}
+}
+
+// WithHeader sets a header sent with every request, for example
+// "Authorization" with an "ApiKey ..." value.
+func WithHeader(name string, value string) Option {
+	return func(d *Driver) {
+		d.header.Set(name, value)
+	}
+}
+
+// WithTimeout sets how long the driver waits for a migration.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.timeout = timeout
+	}
+}
+
+// Driver is a darwin.Driver for Elasticsearch.
+type Driver struct {
+	client   *http.Client
+	endpoint string
+	index    string
+	header   http.Header
+	timeout  time.Duration
+}
+
+// New creates a new Driver sending requests to the cluster at endpoint,
+// like https://localhost:9200.
+func New(client *http.Client, endpoint string, opts ...Option) (*Driver, error) {
+	if client == nil {
+		return nil, errors.New("elasticsearch: http client is nil")
+	}
+
+	if endpoint == "" {
+		return nil, errors.New("elasticsearch: endpoint is required")
+	}
+
+	d := Driver{
+		client:   client,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		index:    DefaultIndex,
+		header:   http.Header{},
+		timeout:  10 * time.Minute,
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep the history in the index name, like
+// WithIndex.
+func (d *Driver) SetTableName(name string) error {
+	if name == "" {
+		return errors.New("elasticsearch: the index name is empty")
+	}
+
+	d.index = name
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the index
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	c := *d
+	if err := c.SetTableName(name); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Create creates the history index if necessary.
+func (d *Driver) Create() error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	body := []byte(`{"mappings": {"properties": {
+		"version": {"type": "double"},
+		"description": {"type": "text"},
+		"checksum": {"type": "keyword"},
+		"applied_at": {"type": "long"},
+		"execution_time": {"type": "long"}
+	}}}`)
+
+	_, err := d.do(ctx, Request{Method: http.MethodPut, Path: "/" + url.PathEscape(d.index), Body: body})
+
+	var requestErr RequestError
+	if errors.As(err, &requestErr) && requestErr.Type == "resource_already_exists_exception" {
+		return nil
+	}
+	return err
+}
+
+// record is a history document.
+type record struct {
+	Version       float64 `json:"version"`
+	Description   string  `json:"description"`
+	Checksum      string  `json:"checksum"`
+	AppliedAt     int64   `json:"applied_at"`
+	ExecutionTime int64   `json:"execution_time"`
+}
+
+// Insert inserts a migration entry into database. It returns an
+// AlreadyAppliedError when the version is already recorded.
+func (d *Driver) Insert(e darwin.MigrationRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	body, err := json.Marshal(record{
+		Version:       e.Version,
+		Description:   e.Description,
+		Checksum:      e.Checksum,
+		AppliedAt:     e.AppliedAt.Unix(),
+		ExecutionTime: int64(e.ExecutionTime),
+	})
+	if err != nil {
+		return err
+	}
+
+	id := strconv.FormatFloat(e.Version, 'f', -1, 64)
+	path := fmt.Sprintf("/%s/_create/%s?refresh=wait_for", url.PathEscape(d.index), url.PathEscape(id))
+
+	_, err = d.do(ctx, Request{Method: http.MethodPut, Path: path, Body: body})
+
+	var requestErr RequestError
+	if errors.As(err, &requestErr) && requestErr.Status == http.StatusConflict {
+		return AlreadyAppliedError{Version: e.Version}
+	}
+	return err
+}
+
+// All returns all migrations applied.
+func (d *Driver) All() ([]darwin.MigrationRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	path := fmt.Sprintf("/%s/_search", url.PathEscape(d.index))
+	body := []byte(`{"size": 10000, "sort": [{"version": "asc"}]}`)
+
+	b, err := d.do(ctx, Request{Method: http.MethodPost, Path: path, Body: body})
+	if err != nil {
+		return []darwin.MigrationRecord{}, err
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source record `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(b, &result); err != nil {
+		return []darwin.MigrationRecord{}, err
+	}
+
+	var records []darwin.MigrationRecord
+	for _, hit := range result.Hits.Hits {
+		records = append(records, darwin.MigrationRecord{
+			Version:       hit.Source.Version,
+			Description:   hit.Source.Description,
+			Checksum:      hit.Source.Checksum,
+			AppliedAt:     time.Unix(hit.Source.AppliedAt, 0),
+			ExecutionTime: time.Duration(hit.Source.ExecutionTime),
+		})
+	}
+
+	return records, nil
+}
+
+// Exec sends the requests of the script in order.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	start := time.Now()
+
+	requests, err := ParseScript(script)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	for _, r := range requests {
+		b, err := d.do(ctx, r)
+		if err != nil {
+			return time.Since(start), err
+		}
+
+		if err := checkResponse(r, b); err != nil {
+			return time.Since(start), err
+		}
+	}
+
+	return time.Since(start), nil
+}
+
+// checkResponse returns an error for successful responses reporting a
+// failure: an operation not acknowledged by the cluster or a _bulk request
+// with failed items.
+func checkResponse(r Request, b []byte) error {
+	var response struct {
+		Acknowledged *bool `json:"acknowledged"`
+		Errors       bool  `json:"errors"`
+	}
+	if json.Unmarshal(b, &response) != nil {
+		return nil
+	}
+
+	if response.Acknowledged != nil && !*response.Acknowledged {
+		return RequestError{Method: r.Method, Path: r.Path, Status: http.StatusOK, Type: "not_acknowledged", Reason: "the cluster didn't acknowledge the request in time"}
+	}
+
+	if response.Errors {
+		return RequestError{Method: r.Method, Path: r.Path, Status: http.StatusOK, Type: "bulk_errors", Reason: "some items of the bulk request failed"}
+	}
+
+	return nil
+}
+
+// do sends a request to the cluster and returns the response body.
+func (d *Driver) do(ctx context.Context, r Request) ([]byte, error) {
+	var body io.Reader
+	if r.Body != nil {
+		body = bytes.NewReader(r.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.Method, d.endpoint+r.Path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range d.header {
+		req.Header[name] = values
+	}
+
+	if r.Body != nil {
+		if isNDJSON(r.Path) {
+			req.Header.Set("Content-Type", "application/x-ndjson")
+		} else {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		}
+		json.Unmarshal(b, &e)
+
+		return nil, RequestError{
+			Method: r.Method,
+			Path:   r.Path,
+			Status: resp.StatusCode,
+			Type:   e.Error.Type,
+			Reason: e.Error.Reason,
+		}
+	}
+
+	return b, nil
+}
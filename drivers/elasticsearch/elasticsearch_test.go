@@ -0,0 +1,175 @@
+package elasticsearch
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// fakeCluster records the requests it receives and stores a single
+// history document.
+type fakeCluster struct {
+	requests []string
+	created  bool
+	auth     string
+}
+
+func (f *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.requests = append(f.requests, r.Method+" "+r.URL.RequestURI()+" "+strings.TrimSpace(string(body)))
+	f.auth = r.Header.Get("Authorization")
+
+	switch {
+	case strings.Contains(r.URL.Path, "/_create/"):
+		if f.created {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": {"type": "version_conflict_engine_exception", "reason": "document already exists"}, "status": 409}`))
+			return
+		}
+		f.created = true
+		w.Write([]byte(`{"result": "created"}`))
+
+	case r.Method == http.MethodPut && r.URL.Path == "/darwin_migrations":
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": {"type": "resource_already_exists_exception", "reason": "index already exists"}, "status": 400}`))
+
+	case strings.HasSuffix(r.URL.Path, "/_search"):
+		w.Write([]byte(`{"hits": {"hits": [{"_source": {"version": 1.1, "description": "Tags", "checksum": "abc", "applied_at": 1700000000, "execution_time": 1500}}]}}`))
+
+	case r.URL.Path == "/_aliases":
+		w.Write([]byte(`{"acknowledged": false}`))
+
+	case r.URL.Path == "/missing/_mapping":
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": {"type": "index_not_found_exception", "reason": "no such index [missing]"}, "status": 404}`))
+
+	default:
+		w.Write([]byte(`{"acknowledged": true}`))
+	}
+}
+
+func newFakeDriver(t *testing.T, cluster *fakeCluster) *Driver {
+	server := httptest.NewServer(cluster)
+	t.Cleanup(server.Close)
+
+	d, err := New(server.Client(), server.URL, WithHeader("Authorization", "ApiKey secret"))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	return d
+}
+
+func Test_ParseScript(t *testing.T) {
+	script := `# the template
+PUT _index_template/logs
+{
+  "index_patterns": ["logs-*"]
+}
+
+POST /_bulk
+{"index": {"_index": "logs-1"}}
+{"message": "hello"}
+DELETE logs-0
+`
+
+	requests, err := ParseScript(script)
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(requests) != 3 {
+		t.Fatalf("len(requests) == %d, wants 3", len(requests))
+	}
+
+	if requests[0].Method != "PUT" || requests[0].Path != "/_index_template/logs" || !strings.Contains(string(requests[0].Body), "logs-*") {
+		t.Errorf("Unexpected request %#v", requests[0])
+	}
+
+	if string(requests[1].Body) != "{\"index\": {\"_index\": \"logs-1\"}}\n{\"message\": \"hello\"}\n" {
+		t.Errorf("Must keep the newline delimited body of bulk requests, got %q", requests[1].Body)
+	}
+
+	if requests[2].Method != "DELETE" || requests[2].Body != nil {
+		t.Errorf("Unexpected request %#v", requests[2])
+	}
+}
+
+func Test_ParseScript_invalid_body(t *testing.T) {
+	if _, err := ParseScript("PUT logs/_mapping\n{\"properties\": \n"); err == nil {
+		t.Errorf("Must reject an invalid JSON body")
+	}
+}
+
+func Test_Driver_Exec(t *testing.T) {
+	cluster := &fakeCluster{}
+	d := newFakeDriver(t, cluster)
+
+	if _, err := d.Exec("PUT logs-1/_mapping\n{\"properties\": {\"tags\": {\"type\": \"keyword\"}}}\n"); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if cluster.auth != "ApiKey secret" {
+		t.Errorf("Must send the headers, got %q", cluster.auth)
+	}
+
+	expected := `PUT /logs-1/_mapping {"properties": {"tags": {"type": "keyword"}}}`
+	if len(cluster.requests) != 1 || cluster.requests[0] != expected {
+		t.Errorf("Expected %q, got %q", expected, cluster.requests)
+	}
+}
+
+func Test_Driver_Exec_errors(t *testing.T) {
+	cluster := &fakeCluster{}
+	d := newFakeDriver(t, cluster)
+
+	_, err := d.Exec("PUT missing/_mapping\n{}\nPUT logs-1/_mapping\n{}\n")
+
+	var requestErr RequestError
+	if !errors.As(err, &requestErr) || requestErr.Type != "index_not_found_exception" {
+		t.Fatalf("Must return the request error, got %v", err)
+	}
+
+	if len(cluster.requests) != 1 {
+		t.Errorf("Must stop at the failed request, got %q", cluster.requests)
+	}
+
+	_, err = d.Exec("POST _aliases\n{\"actions\": []}\n")
+	if !errors.As(err, &requestErr) || requestErr.Type != "not_acknowledged" {
+		t.Errorf("Must fail when the request isn't acknowledged, got %v", err)
+	}
+}
+
+func Test_Driver_history(t *testing.T) {
+	cluster := &fakeCluster{}
+	d := newFakeDriver(t, cluster)
+
+	if err := d.Create(); err != nil {
+		t.Fatalf("Must ignore an existing index, got %s", err)
+	}
+
+	record := darwin.MigrationRecord{Version: 1.1, Description: "Tags", Checksum: "abc", AppliedAt: time.Unix(1700000000, 0), ExecutionTime: 1500}
+
+	if err := d.Insert(record); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := d.Insert(record); !errors.As(err, &AlreadyAppliedError{}) {
+		t.Errorf("Expected AlreadyAppliedError, got %v", err)
+	}
+
+	records, err := d.All()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(records) != 1 || records[0] != record {
+		t.Errorf("Expected %#v, got %#v", record, records)
+	}
+}
@@ -0,0 +1,109 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Request is a call to the cluster API in a migration script.
+type Request struct {
+	Method string
+	Path   string
+	Body   []byte
+}
+
+// methods are the HTTP methods starting a request line.
+var methods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodHead:   true,
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodDelete: true,
+}
+
+// ParseScript returns the requests of a migration script, written like in
+// the Kibana and OpenSearch Dashboards consoles: a line with the method and
+// the path, followed by the JSON body of the request, if any. Bodies of
+// _bulk and _msearch requests are newline delimited JSON documents. Lines
+// starting with "--", "#" or "//" are comments.
+func ParseScript(script string) ([]Request, error) {
+	var requests []Request
+	var body bytes.Buffer
+
+	flush := func() error {
+		if len(requests) == 0 {
+			if strings.TrimSpace(body.String()) != "" {
+				return fmt.Errorf("elasticsearch: body without request: %.40q", body.String())
+			}
+			return nil
+		}
+
+		r := &requests[len(requests)-1]
+		b := append([]byte(nil), bytes.TrimSpace(body.Bytes())...)
+		body.Reset()
+
+		if len(b) == 0 {
+			return nil
+		}
+
+		if isNDJSON(r.Path) {
+			r.Body = append(b, '\n')
+			return nil
+		}
+
+		if !json.Valid(b) {
+			return fmt.Errorf("elasticsearch: invalid body for %s %s", r.Method, r.Path)
+		}
+		r.Body = b
+		return nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), len(script)+1)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "--") || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+
+		fields := strings.Fields(trimmed)
+		if len(fields) == 2 && methods[strings.ToUpper(fields[0])] {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+
+			path := fields[1]
+			if !strings.HasPrefix(path, "/") {
+				path = "/" + path
+			}
+
+			requests = append(requests, Request{Method: strings.ToUpper(fields[0]), Path: path})
+			continue
+		}
+
+		body.WriteString(line)
+		body.WriteByte('\n')
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// isNDJSON reports if the body of a request to path is newline delimited
+// JSON.
+func isNDJSON(path string) bool {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	return strings.HasSuffix(path, "/_bulk") || strings.HasSuffix(path, "/_msearch") || path == "/_bulk" || path == "/_msearch"
+}
@@ -0,0 +1,243 @@
+package dbutil
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type stateError string
+
+func (s stateError) Error() string    { return "state " + string(s) }
+func (s stateError) SQLState() string { return string(s) }
+
+func Test_IsSerializationFailure(t *testing.T) {
+	expectations := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{stateError("40001"), true},
+		{fmt.Errorf("wrapped: %w", stateError("40001")), true},
+		{stateError("23505"), false},
+		{errors.New("ERROR: restart transaction: TransactionRetryWithProtoRefreshError"), true},
+		{errors.New("Generic Error"), false},
+	}
+
+	for _, expectation := range expectations {
+		if got := IsSerializationFailure(expectation.err); got != expectation.expected {
+			t.Errorf("IsSerializationFailure(%v) == %t, wants %t", expectation.err, got, expectation.expected)
+		}
+	}
+}
+
+func Test_Retry(t *testing.T) {
+	calls := 0
+	err := Retry(3, time.Millisecond, IsSerializationFailure, func() error {
+		calls++
+		if calls < 3 {
+			return stateError("40001")
+		}
+		return nil
+	})
+
+	if err != nil || calls != 3 {
+		t.Errorf("Must retry until success, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = Retry(3, time.Millisecond, IsSerializationFailure, func() error {
+		calls++
+		return errors.New("Generic Error")
+	})
+
+	if err == nil || calls != 1 {
+		t.Errorf("Must not retry other errors, got %v after %d calls", err, calls)
+	}
+}
+
+func Test_TableLock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	l := TableLock{DB: db, Table: "darwin_locks", Name: "app", Placeholder: Dollar, Timeout: time.Minute, Poll: time.Millisecond}
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS darwin_locks").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO darwin_locks (name, owner, hostname, pid, acquired_at) VALUES ($1, $2, $3, $4, $5)")).
+		WillReturnError(errors.New("duplicate key"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT owner FROM darwin_locks WHERE name = $1")).
+		WithArgs("app").
+		WillReturnRows(sqlmock.NewRows([]string{"owner"}).AddRow("someone"))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO darwin_locks")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM darwin_locks WHERE name = $1 AND owner = $2")).
+		WithArgs("app", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := l.Lock(); err != nil {
+		t.Fatalf("Must acquire the lock once released, got %s", err)
+	}
+
+	if err := l.Unlock(); err != nil {
+		t.Fatalf("Must release the lock, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_TableLock_timeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	l := TableLock{DB: db, Table: "darwin_locks", Name: "app", Placeholder: Question, Timeout: time.Nanosecond, Poll: time.Millisecond}
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS darwin_locks").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO darwin_locks (name, owner, hostname, pid, acquired_at) VALUES (?, ?, ?, ?, ?)")).
+		WillReturnError(errors.New("duplicate key"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT owner FROM darwin_locks WHERE name = ?")).
+		WillReturnRows(sqlmock.NewRows([]string{"owner"}).AddRow("someone"))
+
+	if err := l.Lock(); err != ErrLockTimeout {
+		t.Errorf("Expected ErrLockTimeout, got %v", err)
+	}
+}
+
+func Test_TableLock_no_timeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	l := TableLock{DB: db, Table: "darwin_locks", Name: "app", Placeholder: Question, Timeout: 0, Poll: 5 * time.Millisecond}
+
+	// The lock is held for longer than a poll, a zero timeout waits for it.
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS darwin_locks").WillReturnResult(sqlmock.NewResult(0, 0))
+	for i := 0; i < 3; i++ {
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO darwin_locks")).
+			WillReturnError(errors.New("duplicate key"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT owner FROM darwin_locks WHERE name = ?")).
+			WillReturnRows(sqlmock.NewRows([]string{"owner"}).AddRow("someone"))
+	}
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO darwin_locks")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := l.Lock(); err != nil {
+		t.Fatalf("Must wait for the lock without timeout, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_TableLock_expired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	l := TableLock{DB: db, Table: "darwin_locks", Name: "app", Placeholder: Dollar, Timeout: time.Nanosecond, Poll: time.Millisecond, Expiry: time.Minute}
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS darwin_locks").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO darwin_locks")).
+		WillReturnError(errors.New("duplicate key"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT owner FROM darwin_locks WHERE name = $1")).
+		WillReturnRows(sqlmock.NewRows([]string{"owner"}).AddRow("someone"))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM darwin_locks WHERE name = $1 AND owner = $2 AND acquired_at < $3")).
+		WithArgs("app", "someone", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO darwin_locks")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE darwin_locks SET acquired_at = $1 WHERE name = $2 AND owner = $3")).
+		WithArgs(sqlmock.AnyArg(), "app", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE darwin_locks SET acquired_at = $1 WHERE name = $2 AND owner = $3")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := l.Lock(); err != nil {
+		t.Fatalf("Must take over the expired lock, got %s", err)
+	}
+
+	if err := l.Refresh(); err != nil {
+		t.Errorf("Must refresh the lock, got %s", err)
+	}
+
+	if err := l.Refresh(); err != ErrLockLost {
+		t.Errorf("Expected ErrLockLost, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_TableLock_not_expired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	l := TableLock{DB: db, Table: "darwin_locks", Name: "app", Placeholder: Question, Timeout: time.Nanosecond, Poll: time.Millisecond, Expiry: time.Minute}
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS darwin_locks").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO darwin_locks")).
+		WillReturnError(errors.New("duplicate key"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT owner FROM darwin_locks WHERE name = ?")).
+		WillReturnRows(sqlmock.NewRows([]string{"owner"}).AddRow("someone"))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM darwin_locks WHERE name = ? AND owner = ? AND acquired_at < ?")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := l.Lock(); err != ErrLockTimeout {
+		t.Errorf("Must not take over a lock which didn't expire, got %v", err)
+	}
+}
+
+func Test_TableLock_Holder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	l := TableLock{DB: db, Table: "darwin_locks", Name: "app", Placeholder: Dollar}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT owner, hostname, pid, acquired_at FROM darwin_locks WHERE name = $1")).
+		WithArgs("app").
+		WillReturnRows(sqlmock.NewRows([]string{"owner", "hostname", "pid", "acquired_at"}).AddRow("abc", "pod-1", 42, 1700000000))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS darwin_locks").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM darwin_locks WHERE name = $1")).
+		WithArgs("app").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	holder, held, err := l.Holder()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if !held || holder.Hostname != "pod-1" || holder.PID != 42 || holder.AcquiredAt.Unix() != 1700000000 {
+		t.Errorf("Must return the holder of the lock, got %#v", holder)
+	}
+
+	if err := l.Break(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
@@ -0,0 +1,222 @@
+package dbutil
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// ErrLockTimeout is returned by TableLock.Lock when the lock is held by
+// another owner for longer than the lock timeout.
+var ErrLockTimeout = errors.New("timeout waiting for the migration lock")
+
+// ErrLockLost is returned by TableLock.Refresh when the lock expired and was
+// taken over by another owner.
+var ErrLockLost = errors.New("the migration lock expired and was taken over")
+
+// TableLock is a mutual exclusion lock backed by a row in a table, for
+// databases without advisory locks. The lock is held by whoever inserted the
+// row named Name, the primary key prevents a second insert. The row records
+// the host and the process of the holder, so a lock left by a crashed
+// applier can be identified and broken, or taken over once expired.
+type TableLock struct {
+	DB    *sql.DB
+	Table string
+	Name  string
+
+	// Placeholder returns the bind parameter for the nth (1-based) argument.
+	Placeholder func(n int) string
+
+	// Timeout is how long Lock waits for the lock, forever when zero or
+	// negative, like the LockTimeout of darwin.GenericDriver.
+	Timeout time.Duration
+
+	// Poll is the wait between two attempts to acquire the lock.
+	Poll time.Duration
+
+	// Expiry is how long the lock is held without being refreshed, Lock
+	// takes over a lock acquired or refreshed longer ago. The lock never
+	// expires when zero.
+	Expiry time.Duration
+
+	owner string
+}
+
+// Dollar is the Placeholder of Postgres compatible databases.
+func Dollar(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+// Question is the Placeholder of MySQL compatible databases.
+func Question(int) string {
+	return "?"
+}
+
+// Create creates the lock table if necessary.
+func (l *TableLock) Create() error {
+	_, err := l.DB.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+                (
+                    name        VARCHAR(255) NOT NULL,
+                    owner       VARCHAR(64)  NOT NULL,
+                    hostname    VARCHAR(255) NOT NULL,
+                    pid         BIGINT       NOT NULL,
+                    acquired_at BIGINT       NOT NULL,
+                    PRIMARY KEY (name)
+                );`, l.Table))
+	return err
+}
+
+// Lock acquires the lock, waiting for the current owner to release it.
+func (l *TableLock) Lock() error {
+	if err := l.Create(); err != nil {
+		return err
+	}
+
+	owner, err := newOwner()
+	if err != nil {
+		return err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	insert := fmt.Sprintf("INSERT INTO %s (name, owner, hostname, pid, acquired_at) VALUES (%s, %s, %s, %s, %s)",
+		l.Table, l.Placeholder(1), l.Placeholder(2), l.Placeholder(3), l.Placeholder(4), l.Placeholder(5))
+	held := fmt.Sprintf("SELECT owner FROM %s WHERE name = %s", l.Table, l.Placeholder(1))
+
+	deadline := time.Now().Add(l.Timeout)
+	for {
+		_, err := l.DB.Exec(insert, l.Name, owner, hostname, os.Getpid(), time.Now().Unix())
+		if err == nil {
+			l.owner = owner
+			return nil
+		}
+
+		// The insert failed for another reason than the lock being held.
+		var current string
+		if qerr := l.DB.QueryRow(held, l.Name).Scan(&current); qerr != nil {
+			if qerr == sql.ErrNoRows {
+				return err
+			}
+			return qerr
+		}
+
+		if l.Expiry > 0 {
+			expired, err := l.expire(current)
+			if err != nil {
+				return err
+			}
+			if expired {
+				continue
+			}
+		}
+
+		if l.Timeout > 0 && time.Now().After(deadline) {
+			return ErrLockTimeout
+		}
+
+		time.Sleep(l.Poll)
+	}
+}
+
+// expire deletes the lock of owner if it expired, and reports if it did.
+// The owner is matched so a lock acquired again meanwhile is kept.
+func (l *TableLock) expire(owner string) (bool, error) {
+	remove := fmt.Sprintf("DELETE FROM %s WHERE name = %s AND owner = %s AND acquired_at < %s",
+		l.Table, l.Placeholder(1), l.Placeholder(2), l.Placeholder(3))
+
+	res, err := l.DB.Exec(remove, l.Name, owner, time.Now().Add(-l.Expiry).Unix())
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Refresh moves the acquisition time of the lock acquired by Lock to now,
+// so it doesn't expire. It returns ErrLockLost if the lock was taken over.
+func (l *TableLock) Refresh() error {
+	if l.owner == "" {
+		return errors.New("lock is not held")
+	}
+
+	update := fmt.Sprintf("UPDATE %s SET acquired_at = %s WHERE name = %s AND owner = %s",
+		l.Table, l.Placeholder(1), l.Placeholder(2), l.Placeholder(3))
+
+	res, err := l.DB.Exec(update, time.Now().Unix(), l.Name, l.owner)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Unlock releases the lock acquired by Lock.
+func (l *TableLock) Unlock() error {
+	if l.owner == "" {
+		return errors.New("lock is not held")
+	}
+
+	remove := fmt.Sprintf("DELETE FROM %s WHERE name = %s AND owner = %s",
+		l.Table, l.Placeholder(1), l.Placeholder(2))
+
+	_, err := l.DB.Exec(remove, l.Name, l.owner)
+	l.owner = ""
+	return err
+}
+
+// Holder returns the holder of the lock, if it is held.
+func (l *TableLock) Holder() (darwin.LockHolder, bool, error) {
+	query := fmt.Sprintf("SELECT owner, hostname, pid, acquired_at FROM %s WHERE name = %s", l.Table, l.Placeholder(1))
+
+	var (
+		holder     darwin.LockHolder
+		acquiredAt int64
+	)
+
+	err := l.DB.QueryRow(query, l.Name).Scan(&holder.Owner, &holder.Hostname, &holder.PID, &acquiredAt)
+	switch {
+	case err == sql.ErrNoRows:
+		return darwin.LockHolder{}, false, nil
+	case err != nil:
+		return darwin.LockHolder{}, false, err
+	}
+
+	holder.AcquiredAt = time.Unix(acquiredAt, 0)
+	return holder, true, nil
+}
+
+// Break releases the lock whoever holds it.
+func (l *TableLock) Break() error {
+	if err := l.Create(); err != nil {
+		return err
+	}
+
+	_, err := l.DB.Exec(fmt.Sprintf("DELETE FROM %s WHERE name = %s", l.Table, l.Placeholder(1)), l.Name)
+	return err
+}
+
+// newOwner returns a random identifier for the holder of a lock.
+func newOwner() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
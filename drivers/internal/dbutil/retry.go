@@ -0,0 +1,64 @@
+// Package dbutil holds the helpers shared by the database/sql based drivers.
+package dbutil
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// SerializationFailure is the SQLSTATE code reported when a transaction
+// must be retried.
+const SerializationFailure = "40001"
+
+// sqlState is implemented by the errors of github.com/lib/pq and
+// github.com/jackc/pgx.
+type sqlState interface {
+	SQLState() string
+}
+
+// SQLState returns the SQLSTATE code of err, or an empty string when the
+// database/sql driver doesn't expose it.
+func SQLState(err error) string {
+	var s sqlState
+	if errors.As(err, &s) {
+		return s.SQLState()
+	}
+	return ""
+}
+
+// IsSerializationFailure reports if err is a serialization failure after
+// which the transaction can be retried.
+func IsSerializationFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if code := SQLState(err); code != "" {
+		return code == SerializationFailure
+	}
+
+	msg := err.Error()
+	return strings.Contains(msg, "SQLSTATE "+SerializationFailure) ||
+		strings.Contains(msg, "restart transaction")
+}
+
+// Retry calls f until it succeeds, returns an error for which retryable is
+// false, or fails attempts times. The wait between attempts starts at
+// backoff and doubles after every attempt.
+func Retry(attempts int, backoff time.Duration, retryable func(error) bool, f func() error) error {
+	var err error
+
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		if err = f(); err == nil || !retryable(err) {
+			return err
+		}
+	}
+
+	return err
+}
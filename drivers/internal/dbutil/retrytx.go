@@ -0,0 +1,150 @@
+package dbutil
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// RetryTx is a darwin.Tx for databases asking to retry transactions, like
+// CockroachDB and YugabyteDB. When a statement or the commit fails with an
+// error Retry retries, the transaction is rolled back and what it executed
+// is replayed in a new one.
+type RetryTx struct {
+	begin  func(ctx context.Context) (darwin.Tx, error)
+	retry  func(f func() error) error
+	ctx    context.Context
+	tx     darwin.Tx
+	replay []func(tx darwin.Tx) error
+}
+
+// BeginRetryTx begins a RetryTx with begin, retrying with retry.
+func BeginRetryTx(ctx context.Context, begin func(ctx context.Context) (darwin.Tx, error), retry func(f func() error) error) (*RetryTx, error) {
+	var tx darwin.Tx
+	err := retry(func() error {
+		var err error
+		tx, err = begin(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RetryTx{begin: begin, retry: retry, ctx: ctx, tx: tx}, nil
+}
+
+// do calls f with the transaction, restarting it before each retry, and
+// keeps replay, f by default, to replay f once it succeeded.
+func (r *RetryTx) do(f, replay func(tx darwin.Tx) error) error {
+	first := true
+	err := r.retry(func() error {
+		if !first {
+			if err := r.restart(); err != nil {
+				return err
+			}
+		}
+		first = false
+		return f(r.tx)
+	})
+	if err == nil {
+		if replay == nil {
+			replay = f
+		}
+		r.replay = append(r.replay, replay)
+	}
+	return err
+}
+
+// restart rolls the transaction back and replays what it executed in a new
+// one.
+func (r *RetryTx) restart() error {
+	r.tx.Rollback()
+
+	tx, err := r.begin(r.ctx)
+	if err != nil {
+		return err
+	}
+	r.tx = tx
+
+	for _, f := range r.replay {
+		if err := f(tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Exec executes the script.
+func (r *RetryTx) Exec(ctx context.Context, script string) (time.Duration, error) {
+	var d time.Duration
+	err := r.do(func(tx darwin.Tx) error {
+		var err error
+		d, err = tx.Exec(ctx, script)
+		return err
+	}, nil)
+	return d, err
+}
+
+// ExecStatements executes the statements of the script one at a time, when
+// the transactions begun are darwin.StatementExecers.
+func (r *RetryTx) ExecStatements(ctx context.Context, script string) ([]darwin.StatementResult, error) {
+	var results []darwin.StatementResult
+	err := r.do(func(tx darwin.Tx) error {
+		se, ok := tx.(darwin.StatementExecer)
+		if !ok {
+			d, err := tx.Exec(ctx, script)
+			results = []darwin.StatementResult{{Statement: script, Duration: d}}
+			return err
+		}
+
+		var err error
+		results, err = se.ExecStatements(ctx, script)
+		return err
+	}, func(tx darwin.Tx) error {
+		// The statements were already reported.
+		_, err := tx.Exec(ctx, script)
+		return err
+	})
+	return results, err
+}
+
+// Insert records the migration.
+func (r *RetryTx) Insert(ctx context.Context, e darwin.MigrationRecord) error {
+	return r.do(func(tx darwin.Tx) error {
+		return tx.Insert(ctx, e)
+	}, nil)
+}
+
+// Delete deletes the record of the migration version, when the
+// transactions begun are darwin.TxDeleters.
+func (r *RetryTx) Delete(ctx context.Context, version float64) error {
+	return r.do(func(tx darwin.Tx) error {
+		deleter, ok := tx.(darwin.TxDeleter)
+		if !ok {
+			return errors.New("the transaction can't delete migration records")
+		}
+		return deleter.Delete(ctx, version)
+	}, nil)
+}
+
+// Commit commits the transaction, replaying it when the commit must be
+// retried.
+func (r *RetryTx) Commit() error {
+	first := true
+	return r.retry(func() error {
+		if !first {
+			if err := r.restart(); err != nil {
+				return err
+			}
+		}
+		first = false
+		return r.tx.Commit()
+	})
+}
+
+// Rollback rolls the transaction back.
+func (r *RetryTx) Rollback() error {
+	return r.tx.Rollback()
+}
@@ -0,0 +1,50 @@
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Execer is implemented by *sql.Conn and *sql.Tx.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// NoTransaction reports if the script has the no-transaction directive.
+func NoTransaction(script string) bool {
+	for _, d := range darwin.Directives(script) {
+		if d.Name == "no-transaction" {
+			return true
+		}
+	}
+	return false
+}
+
+// WithSettings calls f with the settings of the set directives of the
+// script applied to the session of e, and restores them after.
+func WithSettings(ctx context.Context, e Execer, sd darwin.SessionDialect, script string, f func() error) (err error) {
+	settings, err := darwin.Settings(script)
+	if err != nil {
+		return err
+	}
+
+	applied := 0
+	defer func() {
+		for _, s := range settings[:applied] {
+			if _, rerr := e.ExecContext(ctx, sd.ResetSQL(s.Name)); err == nil {
+				err = rerr
+			}
+		}
+	}()
+
+	for _, s := range settings {
+		if _, err := e.ExecContext(ctx, sd.SetSQL(s.Name, s.Value)); err != nil {
+			return err
+		}
+		applied++
+	}
+
+	return f()
+}
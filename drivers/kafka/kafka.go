@@ -0,0 +1,364 @@
+// Package kafka provides a darwin.Driver managing Kafka topics and the
+// schemas of the Schema Registry with migrations.
+//
+// A migration script is a sequence of JSON operation documents:
+//
+//	-- Version: 1.0
+//	-- Description: Orders topic
+//	{"Operation": "CreateTopic", "Input": {"name": "orders", "partitions": 12, "replicationFactor": 3, "configs": {"retention.ms": "604800000"}}}
+//	{"Operation": "SetCompatibility", "Input": {"subject": "orders-value", "compatibility": "BACKWARD"}}
+//	{"Operation": "RegisterSchema", "Input": {"subject": "orders-value", "schema": {"type": "record", "name": "Order", "fields": [{"name": "id", "type": "string"}]}}}
+//
+// The supported operations are CreateTopic, AlterTopicConfig,
+// CreatePartitions and DeleteTopic, run with the Admin, and RegisterSchema,
+// SetCompatibility and DeleteSubject, run with the Registry. They run in
+// order, when one fails the operations before it stay applied.
+//
+// The history is kept in the compacted darwin_migrations topic, keyed by
+// version.
+//
+// The driver works with any Kafka client through the Admin interface, with
+// github.com/twmb/franz-go the kadm package provides most methods.
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// DefaultTopic is the name of the history topic.
+const DefaultTopic = "darwin_migrations"
+
+// ErrTopicExists must be returned, or wrapped, by Admin.CreateTopic when the
+// topic already exists.
+var ErrTopicExists = errors.New("kafka: topic already exists")
+
+// Topic describes a topic to create.
+type Topic struct {
+	Name              string            `json:"name"`
+	Partitions        int               `json:"partitions"`
+	ReplicationFactor int               `json:"replicationFactor"`
+	Configs           map[string]string `json:"configs"`
+}
+
+// Message is a record of a topic. A nil Value is a tombstone.
+type Message struct {
+	Key   []byte
+	Value []byte
+}
+
+// Admin manages the topics of a Kafka cluster and produces and consumes the
+// history.
+type Admin interface {
+	CreateTopic(ctx context.Context, topic Topic) error
+	AlterTopicConfig(ctx context.Context, name string, configs map[string]string) error
+	CreatePartitions(ctx context.Context, name string, count int) error
+	DeleteTopic(ctx context.Context, name string) error
+
+	// Produce writes a message to a topic and waits for its
+	// acknowledgement by all in-sync replicas.
+	Produce(ctx context.Context, topic string, message Message) error
+
+	// Consume returns the messages of all partitions of a topic, from the
+	// earliest offset to the high watermark.
+	Consume(ctx context.Context, topic string) ([]Message, error)
+}
+
+// OperationError is used to report the operation of a migration which
+// failed.
+type OperationError struct {
+	Operation string
+	Err       error
+}
+
+func (o OperationError) Error() string {
+	return fmt.Sprintf("kafka: operation %s failed: %s", o.Operation, o.Err)
+}
+
+func (o OperationError) Unwrap() error {
+	return o.Err
+}
+
+// Operation is a call in a migration script.
+type Operation struct {
+	Operation string          `json:"Operation"`
+	Input     json.RawMessage `json:"Input"`
+}
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithTopic sets the name of the history topic.
+func WithTopic(topic string) Option {
+	return func(d *Driver) {
+		d.topic = topic
+	}
+}
+
+// WithReplicationFactor sets the replication factor of the history topic,
+// 3 by default.
+func WithReplicationFactor(factor int) Option {
+	return func(d *Driver) {
+		d.replicationFactor = factor
+	}
+}
+
+// WithRegistry sets the Schema Registry used by the schema operations.
+func WithRegistry(r Registry) Option {
+	return func(d *Driver) {
+		d.registry = &r
+	}
+}
+
+// WithTimeout sets how long the driver waits for a migration.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.timeout = timeout
+	}
+}
+
+// Driver is a darwin.Driver for Kafka.
+type Driver struct {
+	admin             Admin
+	registry          *Registry
+	topic             string
+	replicationFactor int
+	timeout           time.Duration
+}
+
+// New creates a new Driver managing the cluster with admin.
+func New(admin Admin, opts ...Option) (*Driver, error) {
+	if admin == nil {
+		return nil, errors.New("kafka: admin is nil")
+	}
+
+	d := Driver{
+		admin:             admin,
+		topic:             DefaultTopic,
+		replicationFactor: 3,
+		timeout:           10 * time.Minute,
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep the history in the topic name, like
+// WithTopic.
+func (d *Driver) SetTableName(name string) error {
+	if name == "" {
+		return errors.New("kafka: the topic name is empty")
+	}
+
+	d.topic = name
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the topic
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	c := *d
+	if err := c.SetTableName(name); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Create creates the compacted history topic if necessary.
+func (d *Driver) Create() error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	err := d.admin.CreateTopic(ctx, Topic{
+		Name:              d.topic,
+		Partitions:        1,
+		ReplicationFactor: d.replicationFactor,
+		Configs:           map[string]string{"cleanup.policy": "compact"},
+	})
+	if errors.Is(err, ErrTopicExists) {
+		return nil
+	}
+	return err
+}
+
+// record is a history entry, stored as JSON in the history topic.
+type record struct {
+	Version       float64 `json:"version"`
+	Description   string  `json:"description"`
+	Checksum      string  `json:"checksum"`
+	AppliedAt     int64   `json:"applied_at"`
+	ExecutionTime int64   `json:"execution_time"`
+}
+
+// Insert inserts a migration entry into database.
+func (d *Driver) Insert(e darwin.MigrationRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	value, err := json.Marshal(record{
+		Version:       e.Version,
+		Description:   e.Description,
+		Checksum:      e.Checksum,
+		AppliedAt:     e.AppliedAt.Unix(),
+		ExecutionTime: int64(e.ExecutionTime),
+	})
+	if err != nil {
+		return err
+	}
+
+	key := strconv.FormatFloat(e.Version, 'f', -1, 64)
+	return d.admin.Produce(ctx, d.topic, Message{Key: []byte(key), Value: value})
+}
+
+// All returns all migrations applied, the last message of each version
+// wins as it does after compaction.
+func (d *Driver) All() ([]darwin.MigrationRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	messages, err := d.admin.Consume(ctx, d.topic)
+	if err != nil {
+		return []darwin.MigrationRecord{}, err
+	}
+
+	latest := map[string]Message{}
+	for _, m := range messages {
+		latest[string(m.Key)] = m
+	}
+
+	var records []darwin.MigrationRecord
+	for _, m := range latest {
+		if m.Value == nil {
+			continue
+		}
+
+		var r record
+		if err := json.Unmarshal(m.Value, &r); err != nil {
+			return []darwin.MigrationRecord{}, fmt.Errorf("kafka: invalid history entry %s: %w", m.Key, err)
+		}
+
+		records = append(records, darwin.MigrationRecord{
+			Version:       r.Version,
+			Description:   r.Description,
+			Checksum:      r.Checksum,
+			AppliedAt:     time.Unix(r.AppliedAt, 0),
+			ExecutionTime: time.Duration(r.ExecutionTime),
+		})
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Version < records[j].Version
+	})
+
+	return records, nil
+}
+
+// Exec runs the operations of the script in order.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	start := time.Now()
+
+	ops, err := ParseScript(script)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	for _, op := range ops {
+		if err := d.run(ctx, op); err != nil {
+			return time.Since(start), OperationError{Operation: op.Operation, Err: err}
+		}
+	}
+
+	return time.Since(start), nil
+}
+
+// ParseScript returns the operations of a migration script. Lines starting
+// with "--" are comments.
+func ParseScript(script string) ([]Operation, error) {
+	var lines []string
+	for _, line := range strings.Split(script, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+
+	dec := json.NewDecoder(strings.NewReader(strings.Join(lines, "\n")))
+
+	var ops []Operation
+	for dec.More() {
+		var op Operation
+		if err := dec.Decode(&op); err != nil {
+			return nil, fmt.Errorf("kafka: invalid operation: %w", err)
+		}
+
+		if !validOperations[op.Operation] {
+			return nil, fmt.Errorf("kafka: unknown operation %q", op.Operation)
+		}
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
+
+// validOperations are the operations understood by the driver.
+var validOperations = map[string]bool{
+	"CreateTopic":      true,
+	"AlterTopicConfig": true,
+	"CreatePartitions": true,
+	"DeleteTopic":      true,
+	"RegisterSchema":   true,
+	"SetCompatibility": true,
+	"DeleteSubject":    true,
+}
+
+// run runs an operation.
+func (d *Driver) run(ctx context.Context, op Operation) error {
+	var in struct {
+		schemaInput
+		Topic
+
+		Count         int    `json:"count"`
+		Compatibility string `json:"compatibility"`
+		Permanent     bool   `json:"permanent"`
+	}
+	if err := json.Unmarshal(op.Input, &in); err != nil {
+		return err
+	}
+
+	switch op.Operation {
+	case "CreateTopic":
+		return d.admin.CreateTopic(ctx, in.Topic)
+	case "AlterTopicConfig":
+		return d.admin.AlterTopicConfig(ctx, in.Name, in.Configs)
+	case "CreatePartitions":
+		return d.admin.CreatePartitions(ctx, in.Name, in.Count)
+	case "DeleteTopic":
+		return d.admin.DeleteTopic(ctx, in.Name)
+	}
+
+	if d.registry == nil {
+		return errors.New("kafka: no schema registry configured")
+	}
+
+	switch op.Operation {
+	case "RegisterSchema":
+		return d.registry.register(ctx, in.schemaInput)
+	case "SetCompatibility":
+		return d.registry.setCompatibility(ctx, in.Subject, in.Compatibility)
+	default:
+		return d.registry.deleteSubject(ctx, in.Subject, in.Permanent)
+	}
+}
@@ -0,0 +1,172 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// fakeAdmin is an in-memory Kafka cluster.
+type fakeAdmin struct {
+	calls    []string
+	topics   map[string]Topic
+	messages map[string][]Message
+}
+
+func newFakeAdmin() *fakeAdmin {
+	return &fakeAdmin{topics: map[string]Topic{}, messages: map[string][]Message{}}
+}
+
+func (f *fakeAdmin) CreateTopic(ctx context.Context, topic Topic) error {
+	if _, ok := f.topics[topic.Name]; ok {
+		return fmt.Errorf("create %s: %w", topic.Name, ErrTopicExists)
+	}
+	f.topics[topic.Name] = topic
+	f.calls = append(f.calls, "CreateTopic "+topic.Name)
+	return nil
+}
+
+func (f *fakeAdmin) AlterTopicConfig(ctx context.Context, name string, configs map[string]string) error {
+	f.calls = append(f.calls, fmt.Sprintf("AlterTopicConfig %s %v", name, configs))
+	return nil
+}
+
+func (f *fakeAdmin) CreatePartitions(ctx context.Context, name string, count int) error {
+	f.calls = append(f.calls, fmt.Sprintf("CreatePartitions %s %d", name, count))
+	return nil
+}
+
+func (f *fakeAdmin) DeleteTopic(ctx context.Context, name string) error {
+	f.calls = append(f.calls, "DeleteTopic "+name)
+	return nil
+}
+
+func (f *fakeAdmin) Produce(ctx context.Context, topic string, message Message) error {
+	f.messages[topic] = append(f.messages[topic], message)
+	return nil
+}
+
+func (f *fakeAdmin) Consume(ctx context.Context, topic string) ([]Message, error) {
+	return f.messages[topic], nil
+}
+
+func Test_Driver_Exec(t *testing.T) {
+	var requests []string
+	var schema string
+
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		var body struct {
+			Schema string `json:"schema"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Schema != "" {
+			schema = body.Schema
+		}
+
+		w.Write([]byte(`{}`))
+	}))
+	defer registry.Close()
+
+	admin := newFakeAdmin()
+	d, err := New(admin, WithRegistry(Registry{URL: registry.URL, Client: registry.Client()}))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	script := `-- orders
+{"Operation": "CreateTopic", "Input": {"name": "orders", "partitions": 12, "replicationFactor": 3}}
+{"Operation": "CreatePartitions", "Input": {"name": "orders", "count": 24}}
+{"Operation": "SetCompatibility", "Input": {"subject": "orders-value", "compatibility": "BACKWARD"}}
+{"Operation": "RegisterSchema", "Input": {"subject": "orders-value", "schema": {"type": "record", "name": "Order", "fields": []}}}
+`
+
+	if _, err := d.Exec(script); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if expected := []string{"CreateTopic orders", "CreatePartitions orders 24"}; !reflect.DeepEqual(admin.calls, expected) {
+		t.Errorf("Expected %q, got %q", expected, admin.calls)
+	}
+
+	if expected := []string{"PUT /config/orders-value", "POST /subjects/orders-value/versions"}; !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Expected %q, got %q", expected, requests)
+	}
+
+	if schema != `{"type": "record", "name": "Order", "fields": []}` {
+		t.Errorf("Must send the schema as a string, got %q", schema)
+	}
+}
+
+func Test_Driver_Exec_incompatible_schema(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error_code": 409, "message": "Schema being registered is incompatible with an earlier schema"}`))
+	}))
+	defer registry.Close()
+
+	d, err := New(newFakeAdmin(), WithRegistry(Registry{URL: registry.URL}))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	_, err = d.Exec(`{"Operation": "RegisterSchema", "Input": {"subject": "orders-value", "schemaType": "PROTOBUF", "schema": "syntax = \"proto3\";"}}`)
+
+	var registryErr RegistryError
+	if !errors.As(err, &registryErr) || registryErr.Code != 409 {
+		t.Errorf("Must return the registry error, got %v", err)
+	}
+}
+
+func Test_ParseScript_unknown_operation(t *testing.T) {
+	if _, err := ParseScript(`{"Operation": "Truncate", "Input": {}}`); err == nil {
+		t.Errorf("Must reject unknown operations")
+	}
+}
+
+func Test_Driver_history(t *testing.T) {
+	admin := newFakeAdmin()
+	d, err := New(admin)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := d.Create(); err != nil {
+			t.Fatalf("Must ignore an existing topic, got %s", err)
+		}
+	}
+
+	if admin.topics[DefaultTopic].Configs["cleanup.policy"] != "compact" {
+		t.Errorf("Must create a compacted topic, got %#v", admin.topics[DefaultTopic])
+	}
+
+	records := []darwin.MigrationRecord{
+		{Version: 2, Description: "Second", Checksum: "b", AppliedAt: time.Unix(1700000100, 0), ExecutionTime: 20},
+		{Version: 1, Description: "First", Checksum: "a", AppliedAt: time.Unix(1700000000, 0), ExecutionTime: 10},
+	}
+	for _, r := range records {
+		if err := d.Insert(r); err != nil {
+			t.Fatalf("Must not return error, got %s", err)
+		}
+	}
+	admin.messages[DefaultTopic] = append(admin.messages[DefaultTopic], Message{Key: []byte("3")})
+
+	all, err := d.All()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if !reflect.DeepEqual(all, []darwin.MigrationRecord{records[1], records[0]}) {
+		t.Errorf("Must return the records sorted by version, got %#v", all)
+	}
+}
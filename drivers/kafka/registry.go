@@ -0,0 +1,133 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RegistryError is an error returned by the Schema Registry, for example
+// error 409 for a schema incompatible with the previous versions.
+type RegistryError struct {
+	Status  int
+	Code    int    `json:"error_code"`
+	Message string `json:"message"`
+}
+
+func (r RegistryError) Error() string {
+	return fmt.Sprintf("kafka: schema registry: %d %s", r.Code, r.Message)
+}
+
+// Registry is a Confluent compatible Schema Registry.
+type Registry struct {
+	URL    string
+	Client *http.Client
+
+	// Username and Password are sent with basic authentication when
+	// Username is set, for example an API key and its secret.
+	Username string
+	Password string
+}
+
+// schemaInput is the Input of a RegisterSchema operation.
+type schemaInput struct {
+	Subject    string          `json:"subject"`
+	SchemaType string          `json:"schemaType,omitempty"`
+	Schema     json.RawMessage `json:"schema"`
+	References json.RawMessage `json:"references,omitempty"`
+}
+
+// register registers a new version of the schema of a subject. A schema
+// written as a JSON document (Avro or JSON Schema) is sent as a string.
+func (r Registry) register(ctx context.Context, in schemaInput) error {
+	if in.Subject == "" || len(in.Schema) == 0 {
+		return fmt.Errorf("kafka: subject and schema are required")
+	}
+
+	schema := string(in.Schema)
+	var s string
+	if err := json.Unmarshal(in.Schema, &s); err == nil {
+		schema = s
+	}
+
+	body := map[string]interface{}{"schema": schema}
+	if in.SchemaType != "" && !strings.EqualFold(in.SchemaType, "AVRO") {
+		body["schemaType"] = strings.ToUpper(in.SchemaType)
+	}
+	if len(in.References) > 0 {
+		body["references"] = in.References
+	}
+
+	return r.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(in.Subject)+"/versions", body)
+}
+
+// setCompatibility sets the compatibility level of a subject.
+func (r Registry) setCompatibility(ctx context.Context, subject string, level string) error {
+	if subject == "" || level == "" {
+		return fmt.Errorf("kafka: subject and compatibility are required")
+	}
+	return r.do(ctx, http.MethodPut, "/config/"+url.PathEscape(subject), map[string]string{"compatibility": level})
+}
+
+// deleteSubject deletes a subject and its versions, permanently when
+// permanent is set.
+func (r Registry) deleteSubject(ctx context.Context, subject string, permanent bool) error {
+	if subject == "" {
+		return fmt.Errorf("kafka: subject is required")
+	}
+
+	path := "/subjects/" + url.PathEscape(subject)
+	if err := r.do(ctx, http.MethodDelete, path, nil); err != nil {
+		return err
+	}
+
+	if permanent {
+		return r.do(ctx, http.MethodDelete, path+"?permanent=true", nil)
+	}
+	return nil
+}
+
+// do sends a request to the registry.
+func (r Registry) do(ctx context.Context, method string, path string, body interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(r.URL, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	if r.Username != "" {
+		req.SetBasicAuth(r.Username, r.Password)
+	}
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		e := RegistryError{Status: resp.StatusCode}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return e
+	}
+
+	return nil
+}
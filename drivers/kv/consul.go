@@ -0,0 +1,219 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ConsulStore is a Store using the HTTP API of Consul.
+type ConsulStore struct {
+	// Endpoint is the URL of a Consul agent, like http://localhost:8500.
+	Endpoint string
+	Client   *http.Client
+
+	// Token is an ACL token, if ACLs are enabled.
+	Token string
+
+	// Poll is the wait between two attempts to acquire a lock, a second
+	// when zero.
+	Poll time.Duration
+}
+
+// List implements the Store interface.
+func (c ConsulStore) List(ctx context.Context, prefix string) ([]KeyValue, error) {
+	var out []struct {
+		Key   string  `json:"Key"`
+		Value *string `json:"Value"`
+	}
+
+	status, err := c.call(ctx, http.MethodGet, "/v1/kv/"+escapeKey(prefix)+"?recurse=true", nil, &out)
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	kvs := make([]KeyValue, len(out))
+	for i, kv := range out {
+		kvs[i] = KeyValue{Key: kv.Key, Value: base64Value(kv.Value)}
+	}
+	return kvs, nil
+}
+
+// Txn implements the Store interface. OpCreate operations use the cas verb
+// with index 0, which only succeeds if the key doesn't exist.
+func (c ConsulStore) Txn(ctx context.Context, ops []Op) error {
+	verbs := map[OpType]string{
+		OpPut:          "set",
+		OpDelete:       "delete",
+		OpDeletePrefix: "delete-tree",
+		OpCreate:       "cas",
+	}
+
+	var txn []interface{}
+	for _, op := range ops {
+		kv := map[string]interface{}{"Verb": verbs[op.Type], "Key": op.Key}
+		if op.Type == OpPut || op.Type == OpCreate {
+			kv["Value"] = op.Value
+		}
+		if op.Type == OpCreate {
+			kv["Index"] = 0
+		}
+		txn = append(txn, map[string]interface{}{"KV": kv})
+	}
+
+	var out struct {
+		Errors []struct {
+			OpIndex int    `json:"OpIndex"`
+			What    string `json:"What"`
+		} `json:"Errors"`
+	}
+
+	status, err := c.call(ctx, http.MethodPut, "/v1/txn", txn, &out)
+	if status == http.StatusConflict && len(out.Errors) > 0 {
+		e := out.Errors[0]
+		if e.OpIndex < len(ops) && ops[e.OpIndex].Type == OpCreate {
+			return ErrKeyExists
+		}
+		return fmt.Errorf("kv: consul transaction: %s", e.What)
+	}
+	return err
+}
+
+// Lock implements the Store interface with a session and the acquire
+// operation.
+func (c ConsulStore) Lock(ctx context.Context, key string, ttl time.Duration) (Session, error) {
+	var session struct {
+		ID string `json:"ID"`
+	}
+	body := map[string]string{"Name": key, "TTL": ttl.String(), "Behavior": "delete"}
+	if _, err := c.call(ctx, http.MethodPut, "/v1/session/create", body, &session); err != nil {
+		return nil, err
+	}
+
+	s := consulSession{store: c, id: session.ID, key: key}
+
+	poll := c.Poll
+	if poll == 0 {
+		poll = time.Second
+	}
+
+	for {
+		var acquired bool
+		_, err := c.call(ctx, http.MethodPut, "/v1/kv/"+escapeKey(key)+"?acquire="+url.QueryEscape(session.ID), nil, &acquired)
+		if err != nil {
+			s.destroy()
+			return nil, err
+		}
+
+		if acquired {
+			return s, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			s.destroy()
+			return nil, ctx.Err()
+		case <-time.After(poll):
+		}
+	}
+}
+
+// consulSession is a lock held with a session.
+type consulSession struct {
+	store ConsulStore
+	id    string
+	key   string
+}
+
+func (s consulSession) Renew(ctx context.Context) error {
+	_, err := s.store.call(ctx, http.MethodPut, "/v1/session/renew/"+url.PathEscape(s.id), nil, nil)
+	return err
+}
+
+func (s consulSession) Release(ctx context.Context) error {
+	_, err := s.store.call(ctx, http.MethodPut, "/v1/kv/"+escapeKey(s.key)+"?release="+url.QueryEscape(s.id), nil, nil)
+	if err != nil {
+		return err
+	}
+	_, err = s.store.call(ctx, http.MethodPut, "/v1/session/destroy/"+url.PathEscape(s.id), nil, nil)
+	return err
+}
+
+func (s consulSession) destroy() {
+	s.store.call(context.Background(), http.MethodPut, "/v1/session/destroy/"+url.PathEscape(s.id), nil, nil)
+}
+
+// call sends a request to the agent and returns the status code of the
+// response.
+func (c ConsulStore) call(ctx context.Context, method string, path string, in interface{}, out interface{}) (int, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return 0, err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.Endpoint, "/")+path, body)
+	if err != nil {
+		return 0, err
+	}
+	if c.Token != "" {
+		req.Header.Set("X-Consul-Token", c.Token)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+
+	if out != nil && len(b) > 0 {
+		json.Unmarshal(b, out)
+	}
+
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("kv: consul %s %s: %d %s", method, path, resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	return resp.StatusCode, nil
+}
+
+// escapeKey escapes the segments of a key for a URL path.
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// base64Value decodes a base64 encoded value of the Consul API.
+func base64Value(s *string) []byte {
+	if s == nil {
+		return nil
+	}
+	b, _ := base64.StdEncoding.DecodeString(*s)
+	return b
+}
@@ -0,0 +1,188 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EtcdStore is a Store using the JSON gateway of the etcd v3 API.
+type EtcdStore struct {
+	// Endpoint is the URL of an etcd member, like http://localhost:2379.
+	Endpoint string
+	Client   *http.Client
+
+	// Token is an authentication token obtained from /v3/auth/authenticate,
+	// if authentication is enabled.
+	Token string
+}
+
+// List implements the Store interface.
+func (e EtcdStore) List(ctx context.Context, prefix string) ([]KeyValue, error) {
+	var out struct {
+		Kvs []struct {
+			Key   []byte `json:"key"`
+			Value []byte `json:"value"`
+		} `json:"kvs"`
+	}
+
+	err := e.call(ctx, "/v3/kv/range", map[string]interface{}{
+		"key":       []byte(prefix),
+		"range_end": prefixEnd(prefix),
+	}, &out)
+	if err != nil {
+		return nil, err
+	}
+
+	kvs := make([]KeyValue, len(out.Kvs))
+	for i, kv := range out.Kvs {
+		kvs[i] = KeyValue{Key: string(kv.Key), Value: kv.Value}
+	}
+	return kvs, nil
+}
+
+// Txn implements the Store interface. OpCreate operations are guarded by a
+// comparison of the create revision of their key with 0.
+func (e EtcdStore) Txn(ctx context.Context, ops []Op) error {
+	compare := []interface{}{}
+	success := []interface{}{}
+
+	for _, op := range ops {
+		switch op.Type {
+		case OpPut, OpCreate:
+			success = append(success, map[string]interface{}{
+				"request_put": map[string]interface{}{"key": []byte(op.Key), "value": op.Value},
+			})
+			if op.Type == OpCreate {
+				compare = append(compare, map[string]interface{}{
+					"key": []byte(op.Key), "target": "CREATE", "result": "EQUAL", "create_revision": "0",
+				})
+			}
+		case OpDelete:
+			success = append(success, map[string]interface{}{
+				"request_delete_range": map[string]interface{}{"key": []byte(op.Key)},
+			})
+		case OpDeletePrefix:
+			success = append(success, map[string]interface{}{
+				"request_delete_range": map[string]interface{}{"key": []byte(op.Key), "range_end": prefixEnd(op.Key)},
+			})
+		}
+	}
+
+	var out struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	if err := e.call(ctx, "/v3/kv/txn", map[string]interface{}{"compare": compare, "success": success}, &out); err != nil {
+		return err
+	}
+
+	if !out.Succeeded {
+		return ErrKeyExists
+	}
+	return nil
+}
+
+// Lock implements the Store interface with a lease and the lock service.
+func (e EtcdStore) Lock(ctx context.Context, key string, ttl time.Duration) (Session, error) {
+	var lease struct {
+		ID string `json:"ID"`
+	}
+	err := e.call(ctx, "/v3/lease/grant", map[string]string{"TTL": strconv.Itoa(int(ttl / time.Second))}, &lease)
+	if err != nil {
+		return nil, err
+	}
+
+	var lock struct {
+		Key []byte `json:"key"`
+	}
+	if err := e.call(ctx, "/v3/lock/lock", map[string]interface{}{"name": []byte(key), "lease": lease.ID}, &lock); err != nil {
+		e.call(context.Background(), "/v3/lease/revoke", map[string]string{"ID": lease.ID}, nil)
+		return nil, err
+	}
+
+	return etcdSession{store: e, lease: lease.ID, key: lock.Key}, nil
+}
+
+// etcdSession is a lock held with a lease.
+type etcdSession struct {
+	store EtcdStore
+	lease string
+	key   []byte
+}
+
+func (s etcdSession) Renew(ctx context.Context) error {
+	return s.store.call(ctx, "/v3/lease/keepalive", map[string]string{"ID": s.lease}, nil)
+}
+
+func (s etcdSession) Release(ctx context.Context) error {
+	if err := s.store.call(ctx, "/v3/lock/unlock", map[string]interface{}{"key": s.key}, nil); err != nil {
+		return err
+	}
+	return s.store.call(ctx, "/v3/lease/revoke", map[string]string{"ID": s.lease}, nil)
+}
+
+// call sends a request to the gateway, []byte fields are base64 encoded as
+// the gateway expects.
+func (e EtcdStore) call(ctx context.Context, path string, in interface{}, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(e.Endpoint, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.Token != "" {
+		req.Header.Set("Authorization", e.Token)
+	}
+
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(b, &e)
+		return fmt.Errorf("kv: etcd %s: %d %s", path, resp.StatusCode, e.Message)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(b, out)
+}
+
+// prefixEnd returns the end of the range of the keys starting with prefix.
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// The prefix is all 0xff, the range ends with the last key.
+	return []byte{0}
+}
@@ -0,0 +1,345 @@
+// Package kv provides a darwin.Driver applying versioned configuration
+// changes to etcd or Consul.
+//
+// A migration script is a sequence of operations on keys, grouped in
+// transactions with BEGIN and COMMIT:
+//
+//	-- Version: 1.0
+//	-- Description: Feature flags
+//	BEGIN
+//	PUT config/flags/checkout {"enabled": true}
+//	DELETE PREFIX config/flags/legacy/
+//	COMMIT
+//	PUT config/motd "Welcome!\n"
+//
+// The history is stored under the darwin/migrations/ prefix, one key per
+// version created only if absent, so two appliers can never record the same
+// version. Lock acquires a lock bound to a session (a lease in etcd) which
+// is renewed while the lock is held and expires if the applier dies.
+//
+// The driver works with any store through the Store interface, EtcdStore
+// and ConsulStore implement it with the HTTP APIs of etcd and Consul.
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Default values used by New.
+const (
+	DefaultPrefix  = "darwin/"
+	DefaultLockTTL = 30 * time.Second
+)
+
+// ErrKeyExists must be returned, or wrapped, by Store.Txn when the key of
+// an OpCreate operation exists.
+var ErrKeyExists = errors.New("kv: key already exists")
+
+// KeyValue is a key and its value.
+type KeyValue struct {
+	Key   string
+	Value []byte
+}
+
+// Session keeps a lock alive.
+type Session interface {
+	// Renew extends the session before it expires.
+	Renew(ctx context.Context) error
+
+	// Release releases the lock and ends the session.
+	Release(ctx context.Context) error
+}
+
+// Store is a key value store.
+type Store interface {
+	// List returns the keys starting with prefix.
+	List(ctx context.Context, prefix string) ([]KeyValue, error)
+
+	// Txn applies the operations atomically.
+	Txn(ctx context.Context, ops []Op) error
+
+	// Lock acquires the lock named key, bound to a session expiring
+	// after ttl unless renewed. It waits until the lock is available or
+	// ctx is done.
+	Lock(ctx context.Context, key string, ttl time.Duration) (Session, error)
+}
+
+// AlreadyAppliedError is used to report when another applier recorded the
+// migration first.
+type AlreadyAppliedError struct {
+	Version float64
+}
+
+func (a AlreadyAppliedError) Error() string {
+	return fmt.Sprintf("kv: migration %f was recorded by another applier", a.Version)
+}
+
+// TxnError is used to report the transaction of a migration which failed,
+// the transactions before it are applied.
+type TxnError struct {
+	Ops []Op
+	Err error
+}
+
+func (t TxnError) Error() string {
+	return fmt.Sprintf("kv: transaction of %d operations on %s failed: %s", len(t.Ops), t.Ops[0].Key, t.Err)
+}
+
+func (t TxnError) Unwrap() error {
+	return t.Err
+}
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithPrefix sets the prefix of the keys of the driver.
+func WithPrefix(prefix string) Option {
+	return func(d *Driver) {
+		d.prefix = prefix
+	}
+}
+
+// WithLockTimeout sets how long Lock waits for the lock. A negative timeout
+// waits forever.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.lockTimeout = timeout
+	}
+}
+
+// WithLockTTL sets how long the lock survives an applier which stopped
+// renewing its session.
+func WithLockTTL(ttl time.Duration) Option {
+	return func(d *Driver) {
+		d.lockTTL = ttl
+	}
+}
+
+// WithTimeout sets how long the driver waits for a migration.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.timeout = timeout
+	}
+}
+
+// Driver is a darwin.Driver for key value stores.
+type Driver struct {
+	store       Store
+	prefix      string
+	lockTimeout time.Duration
+	lockTTL     time.Duration
+	timeout     time.Duration
+
+	session Session
+	stop    chan struct{}
+	renewed sync.WaitGroup
+}
+
+// New creates a new Driver applying migrations to store.
+func New(store Store, opts ...Option) (*Driver, error) {
+	if store == nil {
+		return nil, errors.New("kv: store is nil")
+	}
+
+	d := Driver{
+		store:       store,
+		prefix:      DefaultPrefix,
+		lockTimeout: time.Minute,
+		lockTTL:     DefaultLockTTL,
+		timeout:     10 * time.Minute,
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep its keys, the lock included, under the
+// name/ prefix.
+func (d *Driver) SetTableName(name string) error {
+	if name == "" {
+		return errors.New("kv: the table name is empty")
+	}
+
+	d.prefix = name + "/"
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping its keys under the prefix
+// of the name, like SetTableName, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	c := &Driver{store: d.store, lockTimeout: d.lockTimeout, lockTTL: d.lockTTL, timeout: d.timeout}
+	if err := c.SetTableName(name); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Create does nothing, keys don't need a schema.
+func (d *Driver) Create() error {
+	return nil
+}
+
+// record is a history entry, stored as JSON.
+type record struct {
+	Version       float64 `json:"version"`
+	Description   string  `json:"description"`
+	Checksum      string  `json:"checksum"`
+	AppliedAt     int64   `json:"applied_at"`
+	ExecutionTime int64   `json:"execution_time"`
+}
+
+// Insert inserts a migration entry into database. It returns an
+// AlreadyAppliedError when the version is already recorded.
+func (d *Driver) Insert(e darwin.MigrationRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	value, err := json.Marshal(record{
+		Version:       e.Version,
+		Description:   e.Description,
+		Checksum:      e.Checksum,
+		AppliedAt:     e.AppliedAt.Unix(),
+		ExecutionTime: int64(e.ExecutionTime),
+	})
+	if err != nil {
+		return err
+	}
+
+	key := d.prefix + "migrations/" + strconv.FormatFloat(e.Version, 'f', -1, 64)
+
+	err = d.store.Txn(ctx, []Op{{Type: OpCreate, Key: key, Value: value}})
+	if errors.Is(err, ErrKeyExists) {
+		return AlreadyAppliedError{Version: e.Version}
+	}
+	return err
+}
+
+// All returns all migrations applied.
+func (d *Driver) All() ([]darwin.MigrationRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	kvs, err := d.store.List(ctx, d.prefix+"migrations/")
+	if err != nil {
+		return []darwin.MigrationRecord{}, err
+	}
+
+	var records []darwin.MigrationRecord
+	for _, kv := range kvs {
+		var r record
+		if err := json.Unmarshal(kv.Value, &r); err != nil {
+			return []darwin.MigrationRecord{}, fmt.Errorf("kv: invalid history entry %s: %w", kv.Key, err)
+		}
+
+		records = append(records, darwin.MigrationRecord{
+			Version:       r.Version,
+			Description:   r.Description,
+			Checksum:      r.Checksum,
+			AppliedAt:     time.Unix(r.AppliedAt, 0),
+			ExecutionTime: time.Duration(r.ExecutionTime),
+		})
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Version < records[j].Version
+	})
+
+	return records, nil
+}
+
+// Exec applies the transactions of the script in order.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	start := time.Now()
+
+	txns, err := ParseScript(script)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	for _, ops := range txns {
+		for _, op := range ops {
+			if strings.HasPrefix(op.Key, d.prefix) {
+				return time.Since(start), fmt.Errorf("kv: key %s is reserved for darwin", op.Key)
+			}
+		}
+
+		if err := d.store.Txn(ctx, ops); err != nil {
+			return time.Since(start), TxnError{Ops: ops, Err: err}
+		}
+	}
+
+	return time.Since(start), nil
+}
+
+// Lock acquires the migration lock and renews its session until Unlock is
+// called.
+func (d *Driver) Lock() error {
+	ctx := context.Background()
+	if d.lockTimeout >= 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.lockTimeout)
+		defer cancel()
+	}
+
+	session, err := d.store.Lock(ctx, d.prefix+"lock", d.lockTTL)
+	if err != nil {
+		return err
+	}
+
+	d.session = session
+	d.stop = make(chan struct{})
+	d.renewed.Add(1)
+
+	go func(stop chan struct{}) {
+		defer d.renewed.Done()
+
+		ticker := time.NewTicker(d.lockTTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), d.lockTTL/3)
+				session.Renew(ctx)
+				cancel()
+			}
+		}
+	}(d.stop)
+
+	return nil
+}
+
+// Unlock releases the lock acquired by Lock.
+func (d *Driver) Unlock() error {
+	if d.session == nil {
+		return nil
+	}
+
+	close(d.stop)
+	d.renewed.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	err := d.session.Release(ctx)
+	d.session = nil
+	return err
+}
@@ -0,0 +1,258 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// fakeStore is an in-memory Store.
+type fakeStore struct {
+	mu     sync.Mutex
+	data   map[string][]byte
+	txns   [][]Op
+	renews int
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{data: map[string][]byte{}}
+}
+
+func (f *fakeStore) List(ctx context.Context, prefix string) ([]KeyValue, error) {
+	var kvs []KeyValue
+	for k, v := range f.data {
+		if strings.HasPrefix(k, prefix) {
+			kvs = append(kvs, KeyValue{Key: k, Value: v})
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	return kvs, nil
+}
+
+func (f *fakeStore) Txn(ctx context.Context, ops []Op) error {
+	for _, op := range ops {
+		if _, ok := f.data[op.Key]; ok && op.Type == OpCreate {
+			return ErrKeyExists
+		}
+	}
+
+	f.txns = append(f.txns, ops)
+	for _, op := range ops {
+		switch op.Type {
+		case OpPut, OpCreate:
+			f.data[op.Key] = op.Value
+		case OpDelete:
+			delete(f.data, op.Key)
+		case OpDeletePrefix:
+			for k := range f.data {
+				if strings.HasPrefix(k, op.Key) {
+					delete(f.data, k)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (f *fakeStore) Lock(ctx context.Context, key string, ttl time.Duration) (Session, error) {
+	return f, nil
+}
+
+func (f *fakeStore) Renew(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.renews++
+	return nil
+}
+
+func (f *fakeStore) Release(ctx context.Context) error {
+	return nil
+}
+
+func Test_ParseScript(t *testing.T) {
+	script := `# flags
+BEGIN
+PUT config/flags/checkout {"enabled": true}
+DELETE PREFIX config/flags/legacy/
+COMMIT
+PUT config/motd "Welcome!\n"
+DELETE config/old
+`
+
+	txns, err := ParseScript(script)
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := [][]Op{
+		{
+			{Type: OpPut, Key: "config/flags/checkout", Value: []byte(`{"enabled": true}`)},
+			{Type: OpDeletePrefix, Key: "config/flags/legacy/"},
+		},
+		{{Type: OpPut, Key: "config/motd", Value: []byte("Welcome!\n")}},
+		{{Type: OpDelete, Key: "config/old"}},
+	}
+
+	if !reflect.DeepEqual(txns, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, txns)
+	}
+}
+
+func Test_ParseScript_invalid(t *testing.T) {
+	scripts := []string{
+		"BEGIN\nPUT a b\n",
+		"COMMIT\n",
+		"SET a b\n",
+		"PUT a \"b\n",
+		"DELETE a b\n",
+	}
+
+	for _, script := range scripts {
+		if _, err := ParseScript(script); err == nil {
+			t.Errorf("Must not accept %q", script)
+		}
+	}
+}
+
+func Test_Driver_Exec(t *testing.T) {
+	store := newFakeStore()
+	d, err := New(store)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	if _, err := d.Exec("BEGIN\nPUT a 1\nPUT b 2\nCOMMIT\nPUT c 3\n"); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(store.txns) != 2 || len(store.txns[0]) != 2 {
+		t.Errorf("Must apply each transaction atomically, got %#v", store.txns)
+	}
+
+	if _, err := d.Exec("PUT darwin/migrations/9 {}\n"); err == nil {
+		t.Errorf("Must not let a migration write the keys of darwin")
+	}
+}
+
+func Test_Driver_history(t *testing.T) {
+	d, err := New(newFakeStore())
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	records := []darwin.MigrationRecord{
+		{Version: 10, Description: "Tenth", Checksum: "b", AppliedAt: time.Unix(1700000100, 0), ExecutionTime: 20},
+		{Version: 9, Description: "Ninth", Checksum: "a", AppliedAt: time.Unix(1700000000, 0), ExecutionTime: 10},
+	}
+	for _, r := range records {
+		if err := d.Insert(r); err != nil {
+			t.Fatalf("Must not return error, got %s", err)
+		}
+	}
+
+	if err := d.Insert(records[0]); !errors.As(err, &AlreadyAppliedError{}) {
+		t.Errorf("Expected AlreadyAppliedError, got %v", err)
+	}
+
+	all, err := d.All()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if !reflect.DeepEqual(all, []darwin.MigrationRecord{records[1], records[0]}) {
+		t.Errorf("Must return the records sorted by version, got %#v", all)
+	}
+}
+
+func Test_Driver_Lock_renews_session(t *testing.T) {
+	store := newFakeStore()
+	d, err := New(store, WithLockTTL(30*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	if err := d.Lock(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if err := d.Unlock(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.renews == 0 {
+		t.Errorf("Must renew the session while the lock is held")
+	}
+}
+
+func Test_EtcdStore_Txn(t *testing.T) {
+	var body map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"succeeded": false}`))
+	}))
+	defer server.Close()
+
+	store := EtcdStore{Endpoint: server.URL, Client: server.Client()}
+
+	err := store.Txn(context.Background(), []Op{{Type: OpCreate, Key: "a", Value: []byte("1")}})
+	if err != ErrKeyExists {
+		t.Errorf("Expected ErrKeyExists, got %v", err)
+	}
+
+	compare, _ := body["compare"].([]interface{})
+	if len(compare) != 1 || compare[0].(map[string]interface{})["key"] != "YQ==" {
+		t.Errorf("Must compare the base64 encoded key, got %v", body)
+	}
+}
+
+func Test_ConsulStore(t *testing.T) {
+	var txn string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/txn":
+			b, _ := io.ReadAll(r.Body)
+			txn = string(b)
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"Errors": [{"OpIndex": 1, "What": "failed to set key"}]}`))
+		case "/v1/kv/darwin/migrations/":
+			w.Write([]byte(`[{"Key": "darwin/migrations/1", "Value": "e30="}]`))
+		}
+	}))
+	defer server.Close()
+
+	store := ConsulStore{Endpoint: server.URL, Client: server.Client()}
+
+	err := store.Txn(context.Background(), []Op{{Type: OpPut, Key: "a", Value: []byte("1")}, {Type: OpCreate, Key: "b"}})
+	if err != ErrKeyExists {
+		t.Errorf("Expected ErrKeyExists, got %v", err)
+	}
+
+	if !strings.Contains(txn, `"Verb":"cas"`) || !strings.Contains(txn, `"Index":0`) {
+		t.Errorf("Must create keys with a check-and-set on index 0, got %s", txn)
+	}
+
+	kvs, err := store.List(context.Background(), "darwin/migrations/")
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(kvs) != 1 || string(kvs[0].Value) != "{}" {
+		t.Errorf("Must decode the values, got %#v", kvs)
+	}
+}
@@ -0,0 +1,175 @@
+package kv
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// OpType is the type of an Op.
+type OpType int
+
+// Types of operations.
+const (
+	// OpPut sets the value of a key.
+	OpPut OpType = iota
+
+	// OpDelete deletes a key.
+	OpDelete
+
+	// OpDeletePrefix deletes all keys starting with the key.
+	OpDeletePrefix
+
+	// OpCreate sets the value of a key which must not exist, the
+	// transaction fails with ErrKeyExists otherwise.
+	OpCreate
+)
+
+// Op is an operation on the store.
+type Op struct {
+	Type  OpType
+	Key   string
+	Value []byte
+}
+
+// ParseScript returns the transactions of a migration script. Each line is
+// an operation:
+//
+//	PUT key value
+//	DELETE key
+//	DELETE PREFIX key
+//
+// The value is the rest of the line, or a double quoted string with
+// backslash escapes. Operations between a BEGIN line and a COMMIT line form
+// a single transaction, other operations run on their own. Lines starting
+// with "--" or "#" are comments.
+func ParseScript(script string) ([][]Op, error) {
+	var txns [][]Op
+	var txn []Op
+	inTxn := false
+
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), len(script)+1)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+
+		if text == "" || strings.HasPrefix(text, "--") || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		verb, rest := cut(text)
+		switch strings.ToUpper(verb) {
+		case "BEGIN":
+			if inTxn {
+				return nil, fmt.Errorf("kv: line %d: nested BEGIN", line)
+			}
+			inTxn = true
+			continue
+
+		case "COMMIT":
+			if !inTxn {
+				return nil, fmt.Errorf("kv: line %d: COMMIT without BEGIN", line)
+			}
+			if len(txn) > 0 {
+				txns = append(txns, txn)
+			}
+			txn, inTxn = nil, false
+			continue
+		}
+
+		op, err := parseOp(verb, rest)
+		if err != nil {
+			return nil, fmt.Errorf("kv: line %d: %w", line, err)
+		}
+
+		if inTxn {
+			txn = append(txn, op)
+		} else {
+			txns = append(txns, []Op{op})
+		}
+	}
+
+	if inTxn {
+		return nil, fmt.Errorf("kv: BEGIN without COMMIT")
+	}
+
+	return txns, nil
+}
+
+// parseOp parses the operation of a line.
+func parseOp(verb string, rest string) (Op, error) {
+	switch strings.ToUpper(verb) {
+	case "PUT":
+		key, value := cut(rest)
+		if key == "" {
+			return Op{}, fmt.Errorf("PUT without key")
+		}
+
+		v, err := unquote(value)
+		if err != nil {
+			return Op{}, err
+		}
+		return Op{Type: OpPut, Key: key, Value: []byte(v)}, nil
+
+	case "DELETE":
+		key, extra := cut(rest)
+		if strings.EqualFold(key, "PREFIX") {
+			key, extra = cut(extra)
+			if key == "" || extra != "" {
+				return Op{}, fmt.Errorf("DELETE PREFIX expects a single key")
+			}
+			return Op{Type: OpDeletePrefix, Key: key}, nil
+		}
+
+		if key == "" || extra != "" {
+			return Op{}, fmt.Errorf("DELETE expects a single key")
+		}
+		return Op{Type: OpDelete, Key: key}, nil
+	}
+
+	return Op{}, fmt.Errorf("unknown operation %q", verb)
+}
+
+// cut returns the first word of s and the rest of s.
+func cut(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, " \t"); i >= 0 {
+		return s[:i], strings.TrimSpace(s[i:])
+	}
+	return s, ""
+}
+
+// unquote returns the value of a double quoted string, or s.
+func unquote(s string) (string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			if strings.TrimSpace(s[i+1:]) != "" {
+				return "", fmt.Errorf("unexpected text after the quoted value")
+			}
+			return b.String(), nil
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", fmt.Errorf("unterminated quoted value")
+}
@@ -0,0 +1,231 @@
+package libsql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Types of the Hrana over HTTP protocol used by the driver, see
+// https://github.com/tursodatabase/libsql/blob/main/docs/HRANA_3_SPEC.md.
+
+type pipelineRequest struct {
+	Baton    *string   `json:"baton"`
+	Requests []request `json:"requests"`
+}
+
+type request struct {
+	Type  string `json:"type"`
+	Stmt  *stmt  `json:"stmt,omitempty"`
+	Batch *batch `json:"batch,omitempty"`
+}
+
+type stmt struct {
+	SQL      string  `json:"sql"`
+	Args     []value `json:"args,omitempty"`
+	WantRows bool    `json:"want_rows"`
+}
+
+type batch struct {
+	Steps []batchStep `json:"steps"`
+}
+
+type batchStep struct {
+	Condition *condition `json:"condition,omitempty"`
+	Stmt      stmt       `json:"stmt"`
+}
+
+type condition struct {
+	Type string     `json:"type"`
+	Step *int       `json:"step,omitempty"`
+	Cond *condition `json:"cond,omitempty"`
+}
+
+// ok is the condition of a step executed if step succeeded.
+func ok(step int) *condition {
+	return &condition{Type: "ok", Step: &step}
+}
+
+// not negates c.
+func not(c *condition) *condition {
+	return &condition{Type: "not", Cond: c}
+}
+
+type value struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+type pipelineResponse struct {
+	Baton   *string `json:"baton"`
+	BaseURL *string `json:"base_url"`
+	Results []struct {
+		Type     string `json:"type"`
+		Response struct {
+			Type   string          `json:"type"`
+			Result json.RawMessage `json:"result"`
+		} `json:"response"`
+		Error *Error `json:"error"`
+	} `json:"results"`
+}
+
+type stmtResult struct {
+	Cols []struct {
+		Name string `json:"name"`
+	} `json:"cols"`
+	Rows             [][]value `json:"rows"`
+	AffectedRowCount int64     `json:"affected_row_count"`
+}
+
+type batchResult struct {
+	StepResults []*stmtResult `json:"step_results"`
+	StepErrors  []*Error      `json:"step_errors"`
+}
+
+// Error is an error returned by the server for a statement or a request.
+type Error struct {
+	Message string `json:"message"`
+	Code    string `json:"code"`
+}
+
+func (e Error) Error() string {
+	if e.Code == "" {
+		return "libsql: " + e.Message
+	}
+	return fmt.Sprintf("libsql: %s: %s", e.Code, e.Message)
+}
+
+// text returns a text argument.
+func text(s string) value {
+	return value{Type: "text", Value: s}
+}
+
+// integer returns an integer argument, the protocol encodes integers as
+// strings to keep their precision.
+func integer(i int64) value {
+	return value{Type: "integer", Value: strconv.FormatInt(i, 10)}
+}
+
+// float returns a float argument.
+func float(f float64) value {
+	return value{Type: "float", Value: f}
+}
+
+// String returns the value as text.
+func (v value) String() string {
+	switch x := v.Value.(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// Float returns the value as a number.
+func (v value) Float() float64 {
+	f, _ := strconv.ParseFloat(v.String(), 64)
+	return f
+}
+
+// Int returns the value as an integer.
+func (v value) Int() int64 {
+	if i, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+		return i
+	}
+	return int64(v.Float())
+}
+
+// httpURL converts the libsql:// URLs given by Turso to https:// URLs.
+func httpURL(u string) string {
+	if strings.HasPrefix(u, "libsql://") {
+		return "https://" + strings.TrimPrefix(u, "libsql://")
+	}
+	return strings.TrimSuffix(u, "/")
+}
+
+// pipeline sends requests to the server, followed by a close request, and
+// returns the responses.
+func (d *Driver) pipeline(ctx context.Context, requests ...request) ([]json.RawMessage, error) {
+	body, err := json.Marshal(pipelineRequest{
+		Requests: append(requests, request{Type: "close"}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url+"/v2/pipeline", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if d.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+d.authToken)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		var e Error
+		if json.Unmarshal(b, &e) != nil || e.Message == "" {
+			e.Message = strings.TrimSpace(string(b))
+		}
+		if e.Message == "" {
+			e.Message = resp.Status
+		}
+		return nil, e
+	}
+
+	var pr pipelineResponse
+	if err := json.Unmarshal(b, &pr); err != nil {
+		return nil, fmt.Errorf("libsql: invalid response: %w", err)
+	}
+
+	if len(pr.Results) < len(requests) {
+		return nil, fmt.Errorf("libsql: %d results for %d requests", len(pr.Results), len(requests))
+	}
+
+	results := make([]json.RawMessage, len(requests))
+	for i := range requests {
+		r := pr.Results[i]
+		if r.Type == "error" {
+			if r.Error == nil {
+				return nil, Error{Message: "unknown error"}
+			}
+			return nil, *r.Error
+		}
+		results[i] = r.Response.Result
+	}
+
+	return results, nil
+}
+
+// execute executes a single statement and returns its result.
+func (d *Driver) execute(ctx context.Context, s stmt) (stmtResult, error) {
+	results, err := d.pipeline(ctx, request{Type: "execute", Stmt: &s})
+	if err != nil {
+		return stmtResult{}, err
+	}
+
+	var r stmtResult
+	if err := json.Unmarshal(results[0], &r); err != nil {
+		return stmtResult{}, fmt.Errorf("libsql: invalid result: %w", err)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,233 @@
+// Package libsql provides a darwin.Driver for libSQL and Turso databases,
+// using the Hrana over HTTP protocol spoken by libSQL servers.
+//
+// Each migration is split into statements sent in a single batch request:
+// the batch starts a transaction, runs every statement on condition the
+// previous one succeeded and commits, or rolls back on the first failure.
+// A migration therefore costs one round trip regardless of its size, which
+// matters for databases deployed far from the machine running darwin.
+//
+// The driver doesn't depend on a libSQL client library. Use it with the
+// URL of the database, like libsql://my-db-my-org.turso.io, and an auth
+// token created with "turso db tokens create".
+package libsql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// StatementError is used to report the statement of a migration which
+// failed. The whole migration was rolled back.
+type StatementError struct {
+	Statement string
+	Err       error
+}
+
+func (s StatementError) Error() string {
+	return fmt.Sprintf("libsql: statement %q failed: %s", s.Statement, s.Err)
+}
+
+func (s StatementError) Unwrap() error {
+	return s.Err
+}
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithAuthToken sets the token sent as a bearer token with every request.
+func WithAuthToken(token string) Option {
+	return func(d *Driver) {
+		d.authToken = token
+	}
+}
+
+// WithTimeout sets how long the driver waits for a request to complete.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.timeout = timeout
+	}
+}
+
+// Driver is a darwin.Driver for libSQL.
+type Driver struct {
+	client    *http.Client
+	url       string
+	authToken string
+	timeout   time.Duration
+	table     string
+}
+
+// New creates a new Driver for the database at url, either a libsql://,
+// https:// or http:// URL.
+func New(client *http.Client, url string, opts ...Option) (*Driver, error) {
+	if client == nil {
+		return nil, errors.New("libsql: http client is nil")
+	}
+
+	if url == "" {
+		return nil, errors.New("libsql: url is required")
+	}
+
+	d := Driver{
+		client:  client,
+		url:     httpURL(url),
+		timeout: 5 * time.Minute,
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep the history in the table name,
+// qualified by its schema or not.
+func (d *Driver) SetTableName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("libsql: the table name is empty")
+	}
+
+	d.table = name
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	c := *d
+	if err := c.SetTableName(name); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Create creates the table darwin_migrations if necessary.
+func (d *Driver) Create() error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	_, err := d.execute(ctx, stmt{SQL: fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+                (
+                    id             INTEGER  PRIMARY KEY AUTOINCREMENT,
+                    version        REAL     NOT NULL,
+                    description    TEXT     NOT NULL,
+                    checksum       TEXT     NOT NULL,
+                    applied_at     DATETIME NOT NULL,
+                    execution_time REAL     NOT NULL,
+                    UNIQUE         (version)
+                );`, darwin.QuoteTable(d.table, `"`))})
+	return err
+}
+
+// Insert inserts a migration entry into database.
+func (d *Driver) Insert(e darwin.MigrationRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	_, err := d.execute(ctx, stmt{
+		SQL: fmt.Sprintf(`INSERT INTO %s
+                (version, description, checksum, applied_at, execution_time)
+            VALUES (?, ?, ?, ?, ?);`, darwin.QuoteTable(d.table, `"`)),
+		Args: []value{
+			float(e.Version),
+			text(e.Description),
+			text(e.Checksum),
+			integer(e.AppliedAt.Unix()),
+			float(float64(e.ExecutionTime)),
+		},
+	})
+	return err
+}
+
+// All returns all migrations applied.
+func (d *Driver) All() ([]darwin.MigrationRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	r, err := d.execute(ctx, stmt{
+		SQL: fmt.Sprintf(`SELECT version, description, checksum, applied_at, execution_time
+            FROM %s
+            ORDER BY version ASC;`, darwin.QuoteTable(d.table, `"`)),
+		WantRows: true,
+	})
+	if err != nil {
+		return []darwin.MigrationRecord{}, err
+	}
+
+	var records []darwin.MigrationRecord
+	for _, row := range r.Rows {
+		if len(row) != 5 {
+			return []darwin.MigrationRecord{}, fmt.Errorf("libsql: unexpected row %v", row)
+		}
+
+		records = append(records, darwin.MigrationRecord{
+			Version:       row[0].Float(),
+			Description:   row[1].String(),
+			Checksum:      row[2].String(),
+			AppliedAt:     time.Unix(row[3].Int(), 0),
+			ExecutionTime: time.Duration(row[4].Int()),
+		})
+	}
+
+	return records, nil
+}
+
+// Exec executes the statements of the script in a single transactional
+// batch.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	start := time.Now()
+
+	statements := SplitStatements(script)
+	if len(statements) == 0 {
+		return time.Since(start), nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	// BEGIN, the statements, COMMIT if the last statement succeeded and
+	// ROLLBACK if the COMMIT didn't happen.
+	steps := []batchStep{{Stmt: stmt{SQL: "BEGIN"}}}
+	for i, s := range statements {
+		steps = append(steps, batchStep{Condition: ok(i), Stmt: stmt{SQL: s}})
+	}
+	commit := len(steps)
+	steps = append(steps, batchStep{Condition: ok(commit - 1), Stmt: stmt{SQL: "COMMIT"}})
+	steps = append(steps, batchStep{Condition: not(ok(commit)), Stmt: stmt{SQL: "ROLLBACK"}})
+
+	results, err := d.pipeline(ctx, request{Type: "batch", Batch: &batch{Steps: steps}})
+	if err != nil {
+		return time.Since(start), err
+	}
+
+	var r batchResult
+	if err := json.Unmarshal(results[0], &r); err != nil {
+		return time.Since(start), fmt.Errorf("libsql: invalid result: %w", err)
+	}
+
+	for i, e := range r.StepErrors {
+		if e == nil || i > commit {
+			continue
+		}
+
+		if i == 0 || i == commit {
+			return time.Since(start), *e
+		}
+		return time.Since(start), StatementError{Statement: statements[i-1], Err: *e}
+	}
+
+	if len(r.StepResults) <= commit || r.StepResults[commit] == nil {
+		return time.Since(start), errors.New("libsql: migration was not committed")
+	}
+
+	return time.Since(start), nil
+}
@@ -0,0 +1,182 @@
+package libsql
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// fakeServer answers pipeline requests with response, recording the last
+// request.
+type fakeServer struct {
+	request       pipelineRequest
+	authorization string
+	response      string
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v2/pipeline" {
+		http.NotFound(w, r)
+		return
+	}
+
+	f.authorization = r.Header.Get("Authorization")
+	json.NewDecoder(r.Body).Decode(&f.request)
+	w.Write([]byte(f.response))
+}
+
+func newFakeDriver(t *testing.T, server *fakeServer) *Driver {
+	s := httptest.NewServer(server)
+	t.Cleanup(s.Close)
+
+	d, err := New(s.Client(), s.URL, WithAuthToken("secret"))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	return d
+}
+
+func Test_SplitStatements(t *testing.T) {
+	script := `CREATE TABLE users (id INTEGER, name TEXT DEFAULT 'a;b');
+-- a comment; with a semicolon
+CREATE TRIGGER users_audit AFTER INSERT ON users
+BEGIN
+    INSERT INTO audit VALUES (NEW.id, CASE WHEN NEW.name = '' THEN 'none' ELSE NEW.name END);
+    SELECT 1;
+END;
+/* ; */ INSERT INTO "weird;name" VALUES (1);
+;`
+
+	expected := []string{
+		"CREATE TABLE users (id INTEGER, name TEXT DEFAULT 'a;b')",
+		"-- a comment; with a semicolon\nCREATE TRIGGER users_audit AFTER INSERT ON users\nBEGIN\n    INSERT INTO audit VALUES (NEW.id, CASE WHEN NEW.name = '' THEN 'none' ELSE NEW.name END);\n    SELECT 1;\nEND",
+		`/* ; */ INSERT INTO "weird;name" VALUES (1)`,
+	}
+
+	if got := SplitStatements(script); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func Test_New_libsql_url(t *testing.T) {
+	d, err := New(http.DefaultClient, "libsql://db-org.turso.io")
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	if d.url != "https://db-org.turso.io" {
+		t.Errorf("Must use https for libsql URLs, got %s", d.url)
+	}
+}
+
+func Test_Driver_Exec(t *testing.T) {
+	server := &fakeServer{response: `{"baton": null, "results": [
+		{"type": "ok", "response": {"type": "batch", "result": {
+			"step_results": [{}, {}, {}, {}, null],
+			"step_errors": [null, null, null, null, null]
+		}}},
+		{"type": "ok", "response": {"type": "close"}}
+	]}`}
+	d := newFakeDriver(t, server)
+
+	if _, err := d.Exec("CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n"); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if server.authorization != "Bearer secret" {
+		t.Errorf("Must send the auth token, got %q", server.authorization)
+	}
+
+	requests := server.request.Requests
+	if len(requests) != 2 || requests[0].Type != "batch" || requests[1].Type != "close" {
+		t.Fatalf("Must send a batch and close the stream, got %#v", requests)
+	}
+
+	var sqls []string
+	for _, step := range requests[0].Batch.Steps {
+		sqls = append(sqls, step.Stmt.SQL)
+	}
+
+	expected := []string{"BEGIN", "CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)", "COMMIT", "ROLLBACK"}
+	if !reflect.DeepEqual(sqls, expected) {
+		t.Errorf("Expected steps %q, got %q", expected, sqls)
+	}
+
+	if c := requests[0].Batch.Steps[4].Condition; c == nil || c.Type != "not" || *c.Cond.Step != 3 {
+		t.Errorf("Must roll back unless committed, got %#v", c)
+	}
+}
+
+func Test_Driver_Exec_error(t *testing.T) {
+	server := &fakeServer{response: `{"baton": null, "results": [
+		{"type": "ok", "response": {"type": "batch", "result": {
+			"step_results": [{}, {}, null, null, {}],
+			"step_errors": [null, null, {"message": "no such table: c", "code": "SQLITE_ERROR"}, null, null]
+		}}},
+		{"type": "ok", "response": {"type": "close"}}
+	]}`}
+	d := newFakeDriver(t, server)
+
+	_, err := d.Exec("CREATE TABLE a (id INT);\nINSERT INTO c VALUES (1);\n")
+
+	var statementErr StatementError
+	if !errors.As(err, &statementErr) {
+		t.Fatalf("Must return a StatementError, got %v", err)
+	}
+
+	if statementErr.Statement != "INSERT INTO c VALUES (1)" {
+		t.Errorf("Must report the failed statement, got %q", statementErr.Statement)
+	}
+
+	var libsqlErr Error
+	if !errors.As(err, &libsqlErr) || libsqlErr.Code != "SQLITE_ERROR" {
+		t.Errorf("Must wrap the server error, got %v", err)
+	}
+}
+
+func Test_Driver_All(t *testing.T) {
+	server := &fakeServer{response: `{"baton": null, "results": [
+		{"type": "ok", "response": {"type": "execute", "result": {
+			"cols": [{"name": "version"}, {"name": "description"}, {"name": "checksum"}, {"name": "applied_at"}, {"name": "execution_time"}],
+			"rows": [[
+				{"type": "float", "value": 1.1},
+				{"type": "text", "value": "Create users"},
+				{"type": "text", "value": "abc"},
+				{"type": "integer", "value": "1700000000"},
+				{"type": "float", "value": 1500}
+			]]
+		}}},
+		{"type": "ok", "response": {"type": "close"}}
+	]}`}
+	d := newFakeDriver(t, server)
+
+	records, err := d.All()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("len(records) == %d, wants 1", len(records))
+	}
+
+	r := records[0]
+	if r.Version != 1.1 || r.Description != "Create users" || r.Checksum != "abc" || r.AppliedAt.Unix() != 1700000000 || r.ExecutionTime != 1500 {
+		t.Errorf("Unexpected record %#v", r)
+	}
+}
+
+func Test_Driver_request_error(t *testing.T) {
+	server := &fakeServer{response: `{"baton": null, "results": [
+		{"type": "error", "error": {"message": "permission denied", "code": "AUTH"}},
+		{"type": "ok", "response": {"type": "close"}}
+	]}`}
+	d := newFakeDriver(t, server)
+
+	if err := d.Create(); err == nil || err.Error() != "libsql: AUTH: permission denied" {
+		t.Errorf("Must return the request error, got %v", err)
+	}
+}
@@ -0,0 +1,12 @@
+package libsql
+
+import "github.com/dustinevan/darwin"
+
+// SplitStatements splits a SQLite script into its statements with the
+// SQLite syntax of darwin.SplitStatements. Semicolons in quoted strings and
+// identifiers, in comments and in the body of CREATE TRIGGER statements
+// don't end a statement. The statements are returned without their
+// trailing semicolon; statements holding only comments are dropped.
+func SplitStatements(script string) []string {
+	return darwin.SplitStatements(script, darwin.SqliteSyntax)
+}
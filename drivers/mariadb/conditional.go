@@ -0,0 +1,147 @@
+package mariadb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Conditional blocks include parts of a script depending on the server:
+//
+//	-- darwin:if mariadb >= 10.3
+//	CREATE SEQUENCE invoice_numbers;
+//	-- darwin:else
+//	CREATE TABLE invoice_numbers (id INT AUTO_INCREMENT PRIMARY KEY);
+//	-- darwin:end
+//
+// A condition is a product ("mariadb" or "mysql"), optionally compared to a
+// version with >=, >, <=, <, = or !=, or a feature like "sequences". Blocks
+// can be nested. The checksum of a migration covers all its blocks.
+const (
+	ifDirective   = "if"
+	elseDirective = "else"
+	endDirective  = "end"
+)
+
+// Preprocess returns the script with the conditional blocks which don't
+// apply to the server v removed.
+func Preprocess(script string, v Version) (string, error) {
+	type block struct {
+		parent bool
+		active bool
+		inElse bool
+	}
+
+	var (
+		out    strings.Builder
+		stack  []block
+		active = true
+	)
+
+	for n, line := range strings.SplitAfter(script, "\n") {
+		d, ok := directive(line)
+		if !ok {
+			if active {
+				out.WriteString(line)
+			}
+			continue
+		}
+
+		switch d.Name {
+		case ifDirective:
+			match, err := evaluate(d.Args, v)
+			if err != nil {
+				return "", fmt.Errorf("mariadb: line %d: %w", n+1, err)
+			}
+			stack = append(stack, block{parent: active, active: match})
+			active = active && match
+
+		case elseDirective:
+			if len(stack) == 0 || stack[len(stack)-1].inElse {
+				return "", fmt.Errorf("mariadb: line %d: unexpected else", n+1)
+			}
+			b := &stack[len(stack)-1]
+			b.inElse = true
+			active = b.parent && !b.active
+
+		case endDirective:
+			if len(stack) == 0 {
+				return "", fmt.Errorf("mariadb: line %d: unexpected end", n+1)
+			}
+			active = stack[len(stack)-1].parent
+			stack = stack[:len(stack)-1]
+
+		default:
+			if active {
+				out.WriteString(line)
+			}
+		}
+	}
+
+	if len(stack) > 0 {
+		return "", fmt.Errorf("mariadb: %d conditional blocks are not closed", len(stack))
+	}
+
+	return out.String(), nil
+}
+
+// directive returns the directive on the line, if any.
+func directive(line string) (darwin.Directive, bool) {
+	for _, d := range darwin.Directives(line) {
+		return d, true
+	}
+	return darwin.Directive{}, false
+}
+
+// evaluate evaluates a condition for the server v.
+func evaluate(condition string, v Version) (bool, error) {
+	fields := strings.Fields(strings.ToLower(condition))
+
+	switch {
+	case len(fields) == 1 && fields[0] == "mariadb":
+		return v.MariaDB, nil
+	case len(fields) == 1 && fields[0] == "mysql":
+		return !v.MariaDB, nil
+	case len(fields) == 1:
+		switch fields[0] {
+		case FeatureSequences, FeatureSystemVersioning, FeatureReturning, FeatureAtomicDDL:
+			return v.Has(fields[0]), nil
+		}
+		return false, fmt.Errorf("unknown condition %q", condition)
+	case len(fields) != 3 || (fields[0] != "mariadb" && fields[0] != "mysql"):
+		return false, fmt.Errorf("invalid condition %q", condition)
+	}
+
+	if (fields[0] == "mariadb") != v.MariaDB {
+		return false, nil
+	}
+
+	ver := fields[2]
+	for strings.Count(ver, ".") < 2 {
+		ver += ".0"
+	}
+
+	o, err := ParseVersion(ver)
+	if err != nil {
+		return false, err
+	}
+
+	c := v.compare(o)
+	switch fields[1] {
+	case ">=":
+		return c >= 0, nil
+	case ">":
+		return c > 0, nil
+	case "<=":
+		return c <= 0, nil
+	case "<":
+		return c < 0, nil
+	case "=", "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	}
+
+	return false, fmt.Errorf("invalid operator %q", fields[1])
+}
@@ -0,0 +1,108 @@
+// Package mariadb provides a darwin.Driver for MariaDB, based on the MySQL
+// driver.
+//
+// The driver detects the server version on first use. It gates the
+// conditional blocks of migration scripts, so a migration can use sequences
+// or system-versioned tables where available and fall back to plain tables
+// elsewhere. Since MariaDB 10.6 DDL statements are atomic: a failed ALTER
+// TABLE is rolled back entirely, so the number of applied statements
+// reported by a mysql.PartialMigrationError is exact.
+package mariadb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/dustinevan/darwin"
+	"github.com/dustinevan/darwin/drivers/mysql"
+)
+
+// Driver is a darwin.Driver for MariaDB.
+type Driver struct {
+	*mysql.Driver
+
+	db      *sql.DB
+	version *Version
+}
+
+// New creates a new Driver for the MariaDB database db, the options are
+// those of the MySQL driver.
+func New(db *sql.DB, opts ...mysql.Option) (*Driver, error) {
+	driver, err := mysql.New(db, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Driver{Driver: driver, db: db}, nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	driver, err := d.Driver.CloneTable(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *d
+	c.Driver = driver.(*mysql.Driver)
+	return &c, nil
+}
+
+// ServerVersion returns the version of the server, queried once.
+func (d *Driver) ServerVersion() (Version, error) {
+	if d.version != nil {
+		return *d.version, nil
+	}
+
+	var s string
+	if err := d.db.QueryRow("SELECT VERSION()").Scan(&s); err != nil {
+		return Version{}, err
+	}
+
+	v, err := ParseVersion(s)
+	if err != nil {
+		return Version{}, err
+	}
+
+	d.version = &v
+	return v, nil
+}
+
+// Exec removes the conditional blocks which don't apply to the server and
+// executes the statements of the script one at a time.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	return d.ExecContext(context.Background(), script)
+}
+
+// ExecContext is Exec with a context.
+func (d *Driver) ExecContext(ctx context.Context, script string) (time.Duration, error) {
+	v, err := d.ServerVersion()
+	if err != nil {
+		return 0, err
+	}
+
+	script, err = Preprocess(script, v)
+	if err != nil {
+		return 0, err
+	}
+
+	return d.Driver.ExecContext(ctx, script)
+}
+
+// ExecStatements is ExecContext reporting the rows affected by each
+// statement executed and how long it took.
+func (d *Driver) ExecStatements(ctx context.Context, script string) ([]darwin.StatementResult, error) {
+	v, err := d.ServerVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	script, err = Preprocess(script, v)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Driver.ExecStatements(ctx, script)
+}
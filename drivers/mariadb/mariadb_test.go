@@ -0,0 +1,101 @@
+package mariadb
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dustinevan/darwin/drivers/mysql"
+)
+
+const conditionalScript = `CREATE TABLE invoices (id INT);
+-- darwin:if sequences
+CREATE SEQUENCE invoice_numbers;
+-- darwin:if mariadb >= 10.6
+ALTER TABLE invoices ADD COLUMN number INT DEFAULT NEXT VALUE FOR invoice_numbers;
+-- darwin:end
+-- darwin:else
+CREATE TABLE invoice_numbers (id INT AUTO_INCREMENT PRIMARY KEY);
+-- darwin:end
+`
+
+func Test_Preprocess(t *testing.T) {
+	expectations := []struct {
+		version  string
+		expected string
+	}{
+		{
+			"10.11.6-MariaDB",
+			"CREATE TABLE invoices (id INT);\nCREATE SEQUENCE invoice_numbers;\nALTER TABLE invoices ADD COLUMN number INT DEFAULT NEXT VALUE FOR invoice_numbers;\n",
+		},
+		{
+			"10.4.2-MariaDB-log",
+			"CREATE TABLE invoices (id INT);\nCREATE SEQUENCE invoice_numbers;\n",
+		},
+		{
+			"8.0.36",
+			"CREATE TABLE invoices (id INT);\nCREATE TABLE invoice_numbers (id INT AUTO_INCREMENT PRIMARY KEY);\n",
+		},
+	}
+
+	for _, expectation := range expectations {
+		v, err := ParseVersion(expectation.version)
+		if err != nil {
+			t.Fatalf("unable to parse version: %s", err)
+		}
+
+		got, err := Preprocess(conditionalScript, v)
+		if err != nil {
+			t.Fatalf("Must not return error, got %s", err)
+		}
+
+		if got != expectation.expected {
+			t.Errorf("%s: expected %q, got %q", v, expectation.expected, got)
+		}
+	}
+}
+
+func Test_Preprocess_invalid(t *testing.T) {
+	v := Version{Major: 10, Minor: 6, MariaDB: true}
+
+	scripts := []string{
+		"-- darwin:if mariadb >= 10.6\nSELECT 1;\n",
+		"-- darwin:end\n",
+		"-- darwin:if postgres\n-- darwin:end\n",
+		"-- darwin:if mariadb ~ 10\n-- darwin:end\n",
+	}
+
+	for _, script := range scripts {
+		if _, err := Preprocess(script, v); err == nil {
+			t.Errorf("Must not accept %q", script)
+		}
+	}
+}
+
+func Test_Driver_Exec(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT VERSION()")).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("10.3.39-MariaDB"))
+	mock.ExpectQuery(regexp.QuoteMeta(mysql.Dialect{}.CheckpointsSQL())).WillReturnRows(sqlmock.NewRows([]string{"statement", "checksum"}))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE invoices (id INT)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(mysql.Dialect{}.InsertCheckpointSQL())).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE SEQUENCE invoice_numbers")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(mysql.Dialect{}.InsertCheckpointSQL())).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if _, err := d.Exec(conditionalScript); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
@@ -0,0 +1,93 @@
+package mariadb
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// versionPattern extracts the version number from the result of VERSION(),
+// like "10.11.6-MariaDB-1:10.11.6+maria~ubu2204".
+var versionPattern = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)`)
+
+// Version is the version of a MariaDB or MySQL server.
+type Version struct {
+	Major   int
+	Minor   int
+	Patch   int
+	MariaDB bool
+}
+
+// ParseVersion parses the result of SELECT VERSION().
+func ParseVersion(s string) (Version, error) {
+	m := versionPattern.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("mariadb: invalid server version %q", s)
+	}
+
+	var v Version
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	v.Patch, _ = strconv.Atoi(m[3])
+	v.MariaDB = strings.Contains(strings.ToLower(s), "mariadb")
+
+	return v, nil
+}
+
+// String implements the Stringer interface.
+func (v Version) String() string {
+	product := "MySQL"
+	if v.MariaDB {
+		product = "MariaDB"
+	}
+	return fmt.Sprintf("%s %d.%d.%d", product, v.Major, v.Minor, v.Patch)
+}
+
+// compare returns -1, 0 or 1 when v is lower, equal or greater than o.
+func (v Version) compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		switch {
+		case d < 0:
+			return -1
+		case d > 0:
+			return 1
+		}
+	}
+	return 0
+}
+
+// AtLeast reports if v is a MariaDB version greater or equal than
+// major.minor.
+func (v Version) AtLeast(major, minor int) bool {
+	return v.MariaDB && v.compare(Version{Major: major, Minor: minor}) >= 0
+}
+
+// Features reported by a server, usable in script conditions.
+const (
+	// FeatureSequences is CREATE SEQUENCE, MariaDB 10.3.
+	FeatureSequences = "sequences"
+
+	// FeatureSystemVersioning is system-versioned tables, MariaDB 10.3.
+	FeatureSystemVersioning = "system-versioning"
+
+	// FeatureReturning is INSERT and DELETE ... RETURNING, MariaDB 10.5.
+	FeatureReturning = "returning"
+
+	// FeatureAtomicDDL is crash safe DDL statements which are either
+	// completed or rolled back, MariaDB 10.6.
+	FeatureAtomicDDL = "atomic-ddl"
+)
+
+// Has reports if the server supports feature.
+func (v Version) Has(feature string) bool {
+	switch feature {
+	case FeatureSequences, FeatureSystemVersioning:
+		return v.AtLeast(10, 3)
+	case FeatureReturning:
+		return v.AtLeast(10, 5)
+	case FeatureAtomicDDL:
+		return v.AtLeast(10, 6)
+	}
+	return false
+}
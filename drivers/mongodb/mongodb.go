@@ -0,0 +1,378 @@
+// Package mongodb provides a darwin.Driver for MongoDB.
+//
+// A migration script is a sequence of database commands written as Extended
+// JSON documents, or a JSON array of them, which are run in order:
+//
+//	-- Version: 1.0
+//	-- Description: Create users
+//	{"create": "users"}
+//	{"createIndexes": "users", "indexes": [{"key": {"email": 1}, "name": "email_unique", "unique": true}]}
+//
+// Migrations needing logic can be written in Go with Driver.Func. The history
+// is stored in the darwin_migrations collection, a unique index on the
+// version prevents two appliers from recording the same migration.
+//
+// The driver works with any client through the Database interface. With
+// go.mongodb.org/mongo-driver it is a few lines:
+//
+//	type database struct{ db *mongo.Database }
+//
+//	func (d database) RunCommand(ctx context.Context, command []byte) ([]byte, error) {
+//		var doc bson.D
+//		if err := bson.UnmarshalExtJSON(command, false, &doc); err != nil {
+//			return nil, err
+//		}
+//		raw, err := d.db.RunCommand(ctx, doc).Raw()
+//		if err != nil {
+//			return nil, err
+//		}
+//		return bson.MarshalExtJSON(raw, false, false)
+//	}
+package mongodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Collection is the default name of the history collection.
+const Collection = "darwin_migrations"
+
+// duplicateKey is the error code of a unique index violation.
+const duplicateKey = 11000
+
+// Database runs commands, given and returned as Extended JSON documents.
+type Database interface {
+	RunCommand(ctx context.Context, command []byte) ([]byte, error)
+}
+
+// Func is a migration written in Go.
+type Func func(ctx context.Context) error
+
+// AlreadyAppliedError is used to report when another applier recorded the
+// migration first.
+type AlreadyAppliedError struct {
+	Version float64
+}
+
+func (a AlreadyAppliedError) Error() string {
+	return fmt.Sprintf("mongodb: migration %f was recorded by another applier", a.Version)
+}
+
+// CommandError is used to report a command which failed.
+type CommandError struct {
+	Command int
+	Code    int
+	Message string
+}
+
+func (c CommandError) Error() string {
+	return fmt.Sprintf("mongodb: command %d failed with code %d: %s", c.Command+1, c.Code, c.Message)
+}
+
+// goDirective is the script of a migration written in Go.
+const goDirective = "go"
+
+// Driver is a darwin.Driver for MongoDB.
+type Driver struct {
+	db         Database
+	funcs      map[string]Func
+	timeout    time.Duration
+	collection string
+}
+
+// New creates a new Driver for the database db.
+func New(db Database) (*Driver, error) {
+	if db == nil {
+		return nil, errors.New("mongodb: database is nil")
+	}
+
+	return &Driver{db: db, funcs: map[string]Func{}, timeout: time.Minute, collection: Collection}, nil
+}
+
+// SetTableName makes the driver keep the history in the collection name.
+func (d *Driver) SetTableName(name string) error {
+	if name == "" {
+		return errors.New("mongodb: the collection name is empty")
+	}
+
+	d.collection = name
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the collection
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	c := *d
+	if err := c.SetTableName(name); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Func registers f under name and returns the migration executing it. The
+// name is the script of the migration, so renaming it changes its checksum.
+func (d *Driver) Func(version float64, description string, name string, f Func) darwin.Migration {
+	d.funcs[name] = f
+
+	return darwin.Migration{
+		Version:     version,
+		Description: description,
+		Script:      fmt.Sprintf("%s%s %s\n", darwin.DirectivePrefix, goDirective, name),
+	}
+}
+
+// Create creates the history collection and its unique index if necessary.
+func (d *Driver) Create() error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	command := fmt.Sprintf(`{"createIndexes": %q, "indexes": [{"key": {"version": 1}, "name": "version_unique", "unique": true}]}`, d.collection)
+	_, err := d.run(ctx, 0, []byte(command))
+	return err
+}
+
+// Insert inserts a migration entry into database.
+func (d *Driver) Insert(e darwin.MigrationRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	document := fmt.Sprintf(`{"version": %s, "description": %s, "checksum": %q, "applied_at": {"$numberLong": "%d"}, "execution_time": {"$numberLong": "%d"}}`,
+		strconv.FormatFloat(e.Version, 'f', -1, 64), quote(e.Description), e.Checksum, e.AppliedAt.Unix(), int64(e.ExecutionTime))
+	command := fmt.Sprintf(`{"insert": %q, "documents": [%s]}`, d.collection, document)
+
+	reply, err := d.run(ctx, 0, []byte(command))
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		WriteErrors []struct {
+			Code   int    `json:"code"`
+			Errmsg string `json:"errmsg"`
+		} `json:"writeErrors"`
+	}
+	if err := json.Unmarshal(reply, &result); err != nil {
+		return err
+	}
+
+	if len(result.WriteErrors) > 0 {
+		if result.WriteErrors[0].Code == duplicateKey {
+			return AlreadyAppliedError{Version: e.Version}
+		}
+		return CommandError{Code: result.WriteErrors[0].Code, Message: result.WriteErrors[0].Errmsg}
+	}
+
+	return nil
+}
+
+// record is a history document.
+type record struct {
+	Version       number `json:"version"`
+	Description   string `json:"description"`
+	Checksum      string `json:"checksum"`
+	AppliedAt     number `json:"applied_at"`
+	ExecutionTime number `json:"execution_time"`
+}
+
+// number is a numeric value in relaxed or canonical Extended JSON.
+type number string
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (n *number) UnmarshalJSON(b []byte) error {
+	var wrapped map[string]string
+	if err := json.Unmarshal(b, &wrapped); err == nil {
+		for _, key := range []string{"$numberLong", "$numberInt", "$numberDouble"} {
+			if v, ok := wrapped[key]; ok {
+				*n = number(v)
+				return nil
+			}
+		}
+		return fmt.Errorf("mongodb: unexpected number %s", b)
+	}
+
+	var v json.Number
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*n = number(v)
+	return nil
+}
+
+// Float64 returns the number as a float64.
+func (n number) Float64() float64 {
+	f, _ := strconv.ParseFloat(string(n), 64)
+	return f
+}
+
+// Int64 returns the number as an int64.
+func (n number) Int64() int64 {
+	i, err := strconv.ParseInt(string(n), 10, 64)
+	if err != nil {
+		return int64(n.Float64())
+	}
+	return i
+}
+
+// cursorReply is the reply of the find and getMore commands.
+type cursorReply struct {
+	Cursor struct {
+		ID         number   `json:"id"`
+		FirstBatch []record `json:"firstBatch"`
+		NextBatch  []record `json:"nextBatch"`
+	} `json:"cursor"`
+}
+
+// All returns all migrations applied.
+func (d *Driver) All() ([]darwin.MigrationRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	command := fmt.Sprintf(`{"find": %q, "sort": {"version": 1}}`, d.collection)
+
+	var records []darwin.MigrationRecord
+	for {
+		reply, err := d.run(ctx, 0, []byte(command))
+		if err != nil {
+			return []darwin.MigrationRecord{}, err
+		}
+
+		var cursor cursorReply
+		if err := json.Unmarshal(reply, &cursor); err != nil {
+			return []darwin.MigrationRecord{}, err
+		}
+
+		for _, r := range append(cursor.Cursor.FirstBatch, cursor.Cursor.NextBatch...) {
+			records = append(records, darwin.MigrationRecord{
+				Version:       r.Version.Float64(),
+				Description:   r.Description,
+				Checksum:      r.Checksum,
+				AppliedAt:     time.Unix(r.AppliedAt.Int64(), 0),
+				ExecutionTime: time.Duration(r.ExecutionTime.Int64()),
+			})
+		}
+
+		id := string(cursor.Cursor.ID)
+		if id == "" || id == "0" {
+			return records, nil
+		}
+
+		command = fmt.Sprintf(`{"getMore": {"$numberLong": %q}, "collection": %q}`, id, d.collection)
+	}
+}
+
+// Exec runs the commands of the script, or the Go function it refers to.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	if name, ok := funcName(script); ok {
+		f, ok := d.funcs[name]
+		if !ok {
+			return 0, fmt.Errorf("mongodb: function %s is not registered", name)
+		}
+		return time.Since(start), f(ctx)
+	}
+
+	commands, err := Commands(script)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, command := range commands {
+		if _, err := d.run(ctx, i, command); err != nil {
+			return time.Since(start), err
+		}
+	}
+
+	return time.Since(start), nil
+}
+
+// Commands returns the command documents of a script. Comment lines starting
+// with "--" or "//" are ignored.
+func Commands(script string) ([]json.RawMessage, error) {
+	var lines []string
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "--") || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	var commands []json.RawMessage
+	dec := json.NewDecoder(strings.NewReader(strings.Join(lines, "\n")))
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			return commands, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("mongodb: invalid command document: %w", err)
+		}
+
+		raw = bytes.TrimSpace(raw)
+		switch {
+		case len(raw) > 0 && raw[0] == '[':
+			var batch []json.RawMessage
+			if err := json.Unmarshal(raw, &batch); err != nil {
+				return nil, err
+			}
+			commands = append(commands, batch...)
+		case len(raw) > 0 && raw[0] == '{':
+			commands = append(commands, raw)
+		default:
+			return nil, fmt.Errorf("mongodb: command must be a document, got %s", raw)
+		}
+	}
+}
+
+// run runs a command and returns its reply, or a CommandError when the reply
+// isn't ok.
+func (d *Driver) run(ctx context.Context, index int, command []byte) ([]byte, error) {
+	reply, err := d.db.RunCommand(ctx, command)
+	if err != nil {
+		return nil, fmt.Errorf("mongodb: command %d: %w", index+1, err)
+	}
+
+	var status struct {
+		OK     float64 `json:"ok"`
+		Code   int     `json:"code"`
+		Errmsg string  `json:"errmsg"`
+	}
+	if err := json.Unmarshal(reply, &status); err != nil {
+		return nil, err
+	}
+
+	if status.OK != 1 {
+		return nil, CommandError{Command: index, Code: status.Code, Message: status.Errmsg}
+	}
+
+	return reply, nil
+}
+
+// funcName returns the name of the Go function a script refers to.
+func funcName(script string) (string, bool) {
+	directives := darwin.Directives(script)
+	if len(directives) != 1 || directives[0].Name != goDirective {
+		return "", false
+	}
+	return directives[0].Args, true
+}
+
+func quote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
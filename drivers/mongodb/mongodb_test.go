@@ -0,0 +1,124 @@
+package mongodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/dustinevan/darwin"
+)
+
+type fakeDatabase struct {
+	commands  []map[string]interface{}
+	documents []json.RawMessage
+	versions  map[float64]bool
+}
+
+func (f *fakeDatabase) RunCommand(ctx context.Context, command []byte) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(command, &doc); err != nil {
+		return nil, err
+	}
+	f.commands = append(f.commands, doc)
+
+	switch {
+	case doc["insert"] != nil:
+		var insert struct {
+			Documents []json.RawMessage `json:"documents"`
+		}
+		json.Unmarshal(command, &insert)
+
+		var r record
+		json.Unmarshal(insert.Documents[0], &r)
+		if f.versions[r.Version.Float64()] {
+			return []byte(`{"n": 0, "writeErrors": [{"index": 0, "code": 11000, "errmsg": "E11000 duplicate key"}], "ok": 1.0}`), nil
+		}
+		if f.versions == nil {
+			f.versions = map[float64]bool{}
+		}
+		f.versions[r.Version.Float64()] = true
+		f.documents = append(f.documents, insert.Documents[0])
+		return []byte(`{"n": 1, "ok": 1.0}`), nil
+
+	case doc["find"] != nil:
+		batch, _ := json.Marshal(f.documents)
+		return []byte(`{"cursor": {"firstBatch": ` + string(batch) + `, "id": 0, "ns": "app.darwin_migrations"}, "ok": 1.0}`), nil
+
+	case doc["fail"] != nil:
+		return []byte(`{"ok": 0.0, "code": 2, "errmsg": "bad command"}`), nil
+	}
+
+	return []byte(`{"ok": 1.0}`), nil
+}
+
+func Test_Commands(t *testing.T) {
+	script := `-- a comment
+{"create": "users"}
+[{"create": "posts"}, {"drop": "tmp"}]
+// another comment
+{"createIndexes": "users", "indexes": []}`
+
+	commands, err := Commands(script)
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(commands) != 4 {
+		t.Errorf("len(commands) == %d, wants 4", len(commands))
+	}
+
+	if _, err := Commands(`"create"`); err == nil {
+		t.Error("Must not accept commands which are not documents")
+	}
+}
+
+func Test_Driver_Migrate(t *testing.T) {
+	db := &fakeDatabase{}
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	called := false
+	migrations := []darwin.Migration{
+		{Version: 1, Description: "Users", Script: `{"create": "users"}`},
+		d.Func(2, "Backfill", "backfill", func(ctx context.Context) error {
+			called = true
+			return nil
+		}),
+	}
+
+	if err := darwin.Migrate(d, migrations); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if !called {
+		t.Error("Must run the Go migration")
+	}
+
+	records, err := d.All()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(records) != 2 || records[1].Description != "Backfill" || records[1].Checksum != migrations[1].Checksum() {
+		t.Errorf("Must record the migrations, got %#v", records)
+	}
+
+	if err := d.Insert(records[0]); !errors.As(err, &AlreadyAppliedError{}) {
+		t.Errorf("Expected AlreadyAppliedError, got %v", err)
+	}
+}
+
+func Test_Driver_Exec_command_error(t *testing.T) {
+	d, _ := New(&fakeDatabase{})
+
+	_, err := d.Exec(`{"create": "users"} {"fail": 1}`)
+
+	var cmdErr CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.Command != 1 || cmdErr.Code != 2 {
+		t.Errorf("Must report the failed command, got %v", err)
+	}
+}
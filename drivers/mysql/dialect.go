@@ -0,0 +1,181 @@
+package mysql
+
+import (
+	"fmt"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Dialect is the darwin.Dialect used by the MySQL driver. Unlike
+// darwin.MySQLDialect it stores the version as a DOUBLE, so versions like
+// 1.1 are read back exactly, and uses the utf8mb4 character set.
+type Dialect struct {
+	// Table is the name of the history table, darwin_migrations by
+	// default, qualified by its schema or not.
+	Table string
+}
+
+func (d Dialect) table() string {
+	return darwin.QuoteTable(d.Table, "`")
+}
+
+// statementsTable returns the name of the checkpoint table, which follows
+// the name of the history table.
+func (d Dialect) statementsTable() string {
+	if d.Table == "" {
+		return "darwin_migration_statements"
+	}
+	return darwin.QuoteTable(d.Table+"_statements", "`")
+}
+
+// WithTable returns the dialect using the table name.
+func (d Dialect) WithTable(name string) darwin.Dialect {
+	d.Table = name
+	return d
+}
+
+// CreateTableSQL returns the SQL to create the schema table.
+func (d Dialect) CreateTableSQL() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+                (
+                    id             INT          NOT NULL AUTO_INCREMENT,
+                    version        DOUBLE       NOT NULL,
+                    description    VARCHAR(255) NOT NULL,
+                    checksum       CHAR(32)     NOT NULL,
+                    applied_at     BIGINT       NOT NULL,
+                    execution_time BIGINT       NOT NULL,
+                    applied_by     VARCHAR(255) NULL,
+                    hostname       VARCHAR(255) NULL,
+                    app_version    VARCHAR(255) NULL,
+                    source         VARCHAR(255) NULL,
+                    script         LONGTEXT     NULL,
+                    UNIQUE         (version),
+                    PRIMARY KEY    (id)
+                ) ENGINE=InnoDB DEFAULT CHARACTER SET=utf8mb4 COLLATE=utf8mb4_unicode_ci;`, d.table())
+}
+
+// InsertSQL returns the SQL to insert a new migration in the schema table.
+func (d Dialect) InsertSQL() string {
+	return fmt.Sprintf(`INSERT INTO %s
+                (
+                    version,
+                    description,
+                    checksum,
+                    applied_at,
+                    execution_time
+                )
+            VALUES (?, ?, ?, ?, ?);`, d.table())
+}
+
+// AllSQL returns a SQL to get all entries in the table.
+func (d Dialect) AllSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time
+            FROM
+                %s
+            ORDER BY version ASC;`, d.table())
+}
+
+// DeleteSQL returns the SQL to delete the record of a migration.
+func (d Dialect) DeleteSQL() string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE version = ?;`, d.table())
+}
+
+// standard returns the darwin.StandardDialect of the history table, which
+// writes the SQL of the metadata columns.
+func (d Dialect) standard() darwin.StandardDialect {
+	return darwin.StandardDialect{
+		Table:       d.table(),
+		Placeholder: darwin.PlaceholderQuestion,
+		StringType:  "VARCHAR(%d) NULL",
+		TextType:    "LONGTEXT NULL",
+		AddColumn:   "ADD COLUMN",
+	}
+}
+
+// InsertMetadataSQL returns the SQL to insert a new migration in the schema
+// table with its metadata.
+func (d Dialect) InsertMetadataSQL() string {
+	return d.standard().InsertMetadataSQL()
+}
+
+// AllMetadataSQL returns a SQL to get all entries in the table with their
+// metadata.
+func (d Dialect) AllMetadataSQL() string {
+	return d.standard().AllMetadataSQL()
+}
+
+// ScriptSQL returns the SQL to get the script stored with a migration.
+func (d Dialect) ScriptSQL() string {
+	return d.standard().ScriptSQL()
+}
+
+// AllSinceSQL returns a SQL to get the entries in the table from a version
+// on, with their metadata.
+func (d Dialect) AllSinceSQL() string {
+	return d.standard().AllSinceSQL()
+}
+
+// LatestVersionSQL returns a SQL to get the greatest version in the table,
+// NULL when it is empty.
+func (d Dialect) LatestVersionSQL() string {
+	return d.standard().LatestVersionSQL()
+}
+
+// HistoryUpgrades returns the upgrades adding the metadata columns to the
+// history tables created without them.
+func (d Dialect) HistoryUpgrades() []darwin.HistoryUpgrade {
+	return d.standard().HistoryUpgrades()
+}
+
+// Syntax returns the syntax of the statements, with DELIMITER lines.
+func (Dialect) Syntax() darwin.Syntax {
+	return darwin.MySQLSyntax
+}
+
+// SupportsTransactionalDDL returns false, MySQL commits DDL implicitly.
+func (Dialect) SupportsTransactionalDDL() bool {
+	return false
+}
+
+// CreateCheckpointTableSQL returns the SQL to create the table recording the
+// statements executed by a migration not recorded yet.
+func (d Dialect) CreateCheckpointTableSQL() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+                (
+                    statement   INT      NOT NULL,
+                    checksum    CHAR(32) NOT NULL,
+                    applied_at  BIGINT   NOT NULL,
+                    PRIMARY KEY (statement, checksum)
+                ) ENGINE=InnoDB DEFAULT CHARACTER SET=utf8mb4 COLLATE=utf8mb4_unicode_ci;`, d.statementsTable())
+}
+
+// InsertCheckpointSQL returns the SQL to record an executed statement.
+func (d Dialect) InsertCheckpointSQL() string {
+	return fmt.Sprintf("INSERT INTO %s (statement, checksum, applied_at) VALUES (?, ?, ?);", d.statementsTable())
+}
+
+// CheckpointsSQL returns the SQL to get the statements already executed.
+func (d Dialect) CheckpointsSQL() string {
+	return fmt.Sprintf("SELECT statement, checksum FROM %s;", d.statementsTable())
+}
+
+// DeleteCheckpointsSQL returns the SQL to delete the checkpoints once a
+// migration is recorded.
+func (d Dialect) DeleteCheckpointsSQL() string {
+	return fmt.Sprintf("DELETE FROM %s;", d.statementsTable())
+}
+
+// SetSQL returns the SQL to change a session setting.
+func (Dialect) SetSQL(name, value string) string {
+	return fmt.Sprintf("SET SESSION %s = %s", name, value)
+}
+
+// ResetSQL returns the SQL to restore a session setting to its global value.
+func (Dialect) ResetSQL(name string) string {
+	return fmt.Sprintf("SET SESSION %s = DEFAULT", name)
+}
@@ -0,0 +1,378 @@
+// Package mysql provides a darwin.Driver for MySQL.
+//
+// MySQL commits DDL statements implicitly, so a migration can't be rolled
+// back when one of its statements fails. The driver executes the statements
+// of a migration one at a time and reports how many of them were applied
+// with a PartialMigrationError. Each executed statement is recorded in the
+// darwin_migration_statements table with its index and checksum, so once the
+// failed statement is fixed the next run resumes the migration where it
+// stopped.
+//
+// Stored procedures and triggers are defined like with the mysql client,
+// changing the statement terminator with DELIMITER lines:
+//
+//	DELIMITER $$
+//	CREATE PROCEDURE archive() BEGIN ... END$$
+//	DELIMITER ;
+//
+// The driver doesn't import a MySQL database/sql driver, use it with
+// github.com/go-sql-driver/mysql or any compatible driver.
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dustinevan/darwin"
+	"github.com/dustinevan/darwin/drivers/internal/dbutil"
+)
+
+// Default values used by New.
+const (
+	DefaultLockName    = "darwin_migrations"
+	DefaultLockTimeout = time.Minute
+)
+
+// ErrLockTimeout is returned by Lock when the lock is held by another
+// session for longer than the lock timeout.
+var ErrLockTimeout = errors.New("mysql: timeout waiting for the migration lock")
+
+// PartialMigrationError is used to report when a statement of a migration
+// failed after previous statements were already committed.
+type PartialMigrationError struct {
+	// Statement is the 0-based index of the failed statement.
+	Statement int
+
+	// Applied is the number of statements committed before the failure.
+	Applied int
+
+	// SQL is the failed statement.
+	SQL string
+
+	Err error
+}
+
+func (p PartialMigrationError) Error() string {
+	return fmt.Sprintf("mysql: statement %d failed after %d statements were applied: %s", p.Statement+1, p.Applied, p.Err)
+}
+
+// Unwrap returns the underlying error.
+func (p PartialMigrationError) Unwrap() error {
+	return p.Err
+}
+
+// FailedStatement returns the failed statement, it implements
+// darwin.StatementFailure.
+func (p PartialMigrationError) FailedStatement() (int, string) {
+	return p.Statement, p.SQL
+}
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithLockName sets the name used with GET_LOCK, drivers sharing a lock name
+// never migrate concurrently.
+func WithLockName(name string) Option {
+	return func(d *Driver) {
+		d.lockName = name
+	}
+}
+
+// WithLockTimeout sets how long Lock waits for the lock. A negative timeout
+// waits forever.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.lockTimeout = timeout
+	}
+}
+
+// WithReaderHandler enables bulk loading of the data files attached to
+// migrations using LOAD DATA LOCAL INFILE. Pass mysql.RegisterReaderHandler
+// and mysql.DeregisterReaderHandler from github.com/go-sql-driver/mysql.
+func WithReaderHandler(register func(name string, handler func() io.Reader), deregister func(name string)) Option {
+	return func(d *Driver) {
+		d.registerReader = register
+		d.deregisterReader = deregister
+	}
+}
+
+// StatementHooks customize how ExecStatements executes the statements of a
+// script, for the drivers based on this one.
+type StatementHooks struct {
+	// Rewrite returns the statement to execute in place of stmt. The
+	// checkpoint keeps the checksum of stmt.
+	Rewrite func(stmt string) string
+
+	// After is called on the session once stmt is executed and
+	// checkpointed. Its error is reported with stmt applied.
+	After func(ctx context.Context, conn *sql.Conn, stmt string) error
+}
+
+// WithStatementHooks sets the hooks of ExecStatements.
+func WithStatementHooks(h StatementHooks) Option {
+	return func(d *Driver) {
+		d.hooks = h
+	}
+}
+
+// Driver is a darwin.Driver for MySQL.
+type Driver struct {
+	*darwin.GenericDriver
+
+	lockName         string
+	lockTimeout      time.Duration
+	lockConn         *sql.Conn
+	registerReader   func(name string, handler func() io.Reader)
+	deregisterReader func(name string)
+	loads            int
+	hooks            StatementHooks
+}
+
+// New creates a new Driver for the MySQL database db.
+func New(db *sql.DB, opts ...Option) (*Driver, error) {
+	generic, err := darwin.NewGenericDriver(db, Dialect{})
+	if err != nil {
+		return nil, err
+	}
+
+	d := Driver{
+		GenericDriver: generic,
+		lockName:      DefaultLockName,
+		lockTimeout:   DefaultLockTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep the history in the table name, and
+// its checkpoints in the table of the name followed by _statements. The lock
+// takes the name of the table too, unless WithLockName set another one.
+func (d *Driver) SetTableName(name string) error {
+	if err := d.GenericDriver.SetTableName(name); err != nil {
+		return err
+	}
+
+	if d.lockName == DefaultLockName {
+		d.lockName = name
+	}
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	generic, err := d.GenericDriver.WithTable(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *d
+	c.GenericDriver = generic
+	return &c, nil
+}
+
+// dialect returns the dialect of the driver, using its history table.
+func (d *Driver) dialect() Dialect {
+	dialect, _ := d.Dialect.(Dialect)
+	return dialect
+}
+
+// Exec executes the statements of the script one at a time, skipping those
+// executed by a previous run. Statements already executed when one fails are
+// not rolled back, since MySQL commits DDL implicitly, and a
+// PartialMigrationError is returned.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	return d.ExecContext(context.Background(), script)
+}
+
+// ExecContext is Exec with a context.
+func (d *Driver) ExecContext(ctx context.Context, script string) (time.Duration, error) {
+	start := time.Now()
+	_, err := d.ExecStatements(ctx, script)
+	return time.Since(start), err
+}
+
+// ExecStatements is ExecContext reporting the rows affected by each
+// statement executed and how long it took. The statements are executed
+// with darwin.ExecCheckpointed, checkpointed in the table of the dialect,
+// and the settings of the set directives of the script apply to the
+// session executing them.
+func (d *Driver) ExecStatements(ctx context.Context, script string) ([]darwin.StatementResult, error) {
+	conn, err := d.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var (
+		results []darwin.StatementResult
+		last    darwin.StatementResult
+	)
+
+	exec := func(ctx context.Context, stmt string) error {
+		if d.hooks.Rewrite != nil {
+			stmt = d.hooks.Rewrite(stmt)
+		}
+
+		darwin.ReportExecuting(ctx, stmt)
+		start := time.Now()
+
+		res, err := conn.ExecContext(ctx, stmt)
+		if err != nil {
+			return err
+		}
+
+		rows, _ := res.RowsAffected()
+		last = darwin.StatementResult{Statement: stmt, RowsAffected: rows, Duration: time.Since(start)}
+		return nil
+	}
+
+	after := func(ctx context.Context, i int) error {
+		if d.hooks.After != nil {
+			start := time.Now()
+			err := d.hooks.After(ctx, conn, last.Statement)
+			last.Duration += time.Since(start)
+			if err != nil {
+				return PartialMigrationError{Statement: i, Applied: i + 1, SQL: last.Statement, Err: err}
+			}
+		}
+
+		results = append(results, last)
+		darwin.ReportStatement(ctx, last)
+		return nil
+	}
+
+	store := checkpoints{darwin.CheckpointTable(conn, d.dialect()), after}
+	err = dbutil.WithSettings(ctx, conn, d.dialect(), script, func() error {
+		return darwin.ExecCheckpointed(ctx, store, script, d.dialect().Syntax(), exec)
+	})
+
+	var p PartialMigrationError
+	var failure darwin.StatementFailure
+	switch {
+	case errors.As(err, &p):
+		err = p
+	case errors.As(err, &failure):
+		i, stmt := failure.FailedStatement()
+		if d.hooks.Rewrite != nil {
+			stmt = d.hooks.Rewrite(stmt)
+		}
+		err = PartialMigrationError{Statement: i, Applied: i, SQL: stmt, Err: errors.Unwrap(err)}
+	}
+
+	return results, err
+}
+
+// checkpoints is the darwin.CheckpointStore of the driver, calling after
+// once a statement is checkpointed, with its index.
+type checkpoints struct {
+	darwin.CheckpointStore
+	after func(ctx context.Context, i int) error
+}
+
+// SaveCheckpoint records the statement executed and calls after.
+func (c checkpoints) SaveCheckpoint(ctx context.Context, cp darwin.Checkpoint) error {
+	if err := c.CheckpointStore.SaveCheckpoint(ctx, cp); err != nil {
+		return err
+	}
+	return c.after(ctx, cp.Statement)
+}
+
+// BeginTx returns darwin.ErrTransactionUnsupported, migrations are executed
+// with Exec and recorded with Insert.
+func (d *Driver) BeginTx(ctx context.Context) (darwin.Tx, error) {
+	return nil, darwin.ErrTransactionUnsupported
+}
+
+// Lock acquires a named lock with GET_LOCK. The lock belongs to a dedicated
+// connection which is kept until Unlock is called.
+func (d *Driver) Lock() error {
+	ctx := context.Background()
+
+	conn, err := d.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	timeout := -1
+	if d.lockTimeout >= 0 {
+		timeout = int(d.lockTimeout / time.Second)
+	}
+
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", d.lockName, timeout).Scan(&acquired); err != nil {
+		conn.Close()
+		return err
+	}
+
+	if acquired.Int64 != 1 {
+		conn.Close()
+		return ErrLockTimeout
+	}
+
+	d.lockConn = conn
+	return nil
+}
+
+// Unlock releases the lock acquired by Lock.
+func (d *Driver) Unlock() error {
+	if d.lockConn == nil {
+		return errors.New("mysql: lock is not held")
+	}
+
+	conn := d.lockConn
+	d.lockConn = nil
+	defer conn.Close()
+
+	_, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", d.lockName)
+	return err
+}
+
+// LoadData streams r into table with LOAD DATA LOCAL INFILE. The data must
+// be comma separated with optionally double quoted fields, one row per line.
+// It implements darwin.DataLoader when WithReaderHandler is used.
+func (d *Driver) LoadData(table string, r io.Reader) (time.Duration, error) {
+	if d.registerReader == nil {
+		return 0, errors.New("mysql: loading data requires WithReaderHandler")
+	}
+
+	d.loads++
+	name := fmt.Sprintf("darwin_%d_%d", time.Now().UnixNano(), d.loads)
+
+	d.registerReader(name, func() io.Reader { return r })
+	defer d.deregisterReader(name)
+
+	start := time.Now()
+
+	stmt := fmt.Sprintf(`LOAD DATA LOCAL INFILE 'Reader::%s' INTO TABLE %s
+            CHARACTER SET utf8mb4
+            FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '"'
+            LINES TERMINATED BY '\n'`, name, quoteIdentifier(table))
+
+	_, err := d.DB.Exec(stmt)
+	return time.Since(start), err
+}
+
+// quoteIdentifier quotes a possibly schema qualified identifier.
+func quoteIdentifier(name string) string {
+	quoted := "`"
+	for _, c := range name {
+		switch c {
+		case '.':
+			quoted += "`.`"
+		case '`':
+			quoted += "``"
+		default:
+			quoted += string(c)
+		}
+	}
+	return quoted + "`"
+}
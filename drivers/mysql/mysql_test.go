@@ -0,0 +1,238 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func Test_SplitStatements(t *testing.T) {
+	script := `CREATE TABLE a (name VARCHAR(10) DEFAULT ';');
+-- a comment; with a semicolon
+INSERT INTO a VALUES ('it''s; fine'), ("a \"; b");
+/* block; comment */
+# hash; comment
+UPDATE ` + "`a;b`" + ` SET x = 1
+`
+
+	expected := []string{
+		"CREATE TABLE a (name VARCHAR(10) DEFAULT ';')",
+		"-- a comment; with a semicolon\nINSERT INTO a VALUES ('it''s; fine'), (\"a \\\"; b\")",
+		"/* block; comment */\n# hash; comment\nUPDATE `a;b` SET x = 1",
+	}
+
+	if got := SplitStatements(script); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func Test_SplitStatements_delimiter(t *testing.T) {
+	script := `DELIMITER $$
+CREATE PROCEDURE fill()
+BEGIN
+	INSERT INTO a VALUES (1);
+END$$
+DELIMITER ;
+CALL fill();`
+
+	expected := []string{
+		"CREATE PROCEDURE fill()\nBEGIN\n\tINSERT INTO a VALUES (1);\nEND",
+		"CALL fill()",
+	}
+
+	if got := SplitStatements(script); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func Test_SplitStatements_only_comments(t *testing.T) {
+	if got := SplitStatements("-- nothing here\n;\n"); len(got) != 0 {
+		t.Errorf("Must drop empty statements, got %q", got)
+	}
+}
+
+func Test_Driver_Exec_partial(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(Dialect{}.CheckpointsSQL())).
+		WillReturnRows(sqlmock.NewRows([]string{"statement", "checksum"}))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE a (id INT)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(Dialect{}.InsertCheckpointSQL())).
+		WithArgs(0, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE b (id INT)")).WillReturnError(errors.New("Generic Error"))
+
+	_, err = d.Exec("CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\nCREATE TABLE c (id INT);")
+
+	var partial PartialMigrationError
+	if !errors.As(err, &partial) || partial.Statement != 1 || partial.Applied != 1 {
+		t.Errorf("Must report the failed statement, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_Lock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db, WithLockName("app"))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT GET_LOCK(?, ?)")).
+		WithArgs("app", 60).
+		WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT RELEASE_LOCK(?)")).
+		WithArgs("app").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := d.Lock(); err != nil {
+		t.Fatalf("Must acquire the lock, got %s", err)
+	}
+
+	if err := d.Unlock(); err != nil {
+		t.Fatalf("Must release the lock, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_SetTableName(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	if err := d.SetTableName("ops.schema_migrations"); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if sql := d.dialect().AllSQL(); !strings.Contains(sql, "`ops`.`schema_migrations`") {
+		t.Errorf("Must read the history from the table, got %s", sql)
+	}
+	if sql := d.dialect().CheckpointsSQL(); !strings.Contains(sql, "`ops`.`schema_migrations_statements`") {
+		t.Errorf("Must read the checkpoints from the table of the history, got %s", sql)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT GET_LOCK(?, ?)")).
+		WithArgs("ops.schema_migrations", 60).
+		WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+
+	if err := d.Lock(); err != nil {
+		t.Fatalf("Must take the lock of the table, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_Lock_timeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT GET_LOCK(?, ?)")).
+		WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(0))
+
+	if err := d.Lock(); err != ErrLockTimeout {
+		t.Errorf("Expected ErrLockTimeout, got %v", err)
+	}
+}
+
+func Test_Driver_Exec_resume(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(Dialect{}.CheckpointsSQL())).
+		WillReturnRows(sqlmock.NewRows([]string{"statement", "checksum"}).AddRow(0, "f8ae1c345a0e54bc801b56d4e0e01096"))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE b (id INT)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(Dialect{}.InsertCheckpointSQL())).
+		WithArgs(1, "efcd1b3bf83024e89dd5145dff5dce84", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if _, err := d.Exec("CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);"); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_ExecStatements_settings(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("SET SESSION lock_wait_timeout = 5")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(Dialect{}.CheckpointsSQL())).
+		WillReturnRows(sqlmock.NewRows([]string{"statement", "checksum"}))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE a SET x = 1")).WillReturnResult(sqlmock.NewResult(0, 42))
+	mock.ExpectExec(regexp.QuoteMeta(Dialect{}.InsertCheckpointSQL())).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("SET SESSION lock_wait_timeout = DEFAULT")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	results, err := d.ExecStatements(context.Background(), "-- darwin:set lock_wait_timeout = 5\nUPDATE a SET x = 1;")
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(results) != 1 || results[0].RowsAffected != 42 {
+		t.Errorf("Must report the rows affected, got %#v", results)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
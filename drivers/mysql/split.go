@@ -0,0 +1,13 @@
+package mysql
+
+import "github.com/dustinevan/darwin"
+
+// SplitStatements splits a script into its statements with the MySQL syntax
+// of darwin.SplitStatements. Semicolons inside quoted strings, quoted
+// identifiers, comments and the BEGIN ... END bodies of stored routines
+// don't end a statement, and DELIMITER lines change the terminator like in
+// the mysql client. The statements are returned without their terminator;
+// statements holding only whitespace and comments are dropped.
+func SplitStatements(script string) []string {
+	return darwin.SplitStatements(script, darwin.MySQLSyntax)
+}
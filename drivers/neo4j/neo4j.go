@@ -0,0 +1,374 @@
+// Package neo4j provides a darwin.Driver running Cypher migrations on
+// Neo4j: constraints, indexes and data refactorings.
+//
+// Neo4j doesn't allow schema changes and data writes in the same
+// transaction, so the driver groups consecutive statements of a migration
+// by kind and commits each group in its own transaction: a migration
+// creating a constraint and then backfilling data runs in two transactions.
+// When a group fails, the groups before it stay applied.
+//
+// The history is stored as :DarwinMigration nodes, a uniqueness constraint
+// on their version prevents two appliers from recording the same migration.
+//
+// The driver uses the HTTP transaction API of Neo4j 5, it doesn't import a
+// Neo4j client library.
+package neo4j
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// DefaultDatabase is the database migrations run in.
+const DefaultDatabase = "neo4j"
+
+// constraintViolation is the status code of a uniqueness constraint
+// violation.
+const constraintViolation = "Neo.ClientError.Schema.ConstraintValidationFailed"
+
+// schemaStatement matches a statement changing the schema.
+var schemaStatement = regexp.MustCompile(`(?i)^\s*(CREATE|DROP)\s+(OR\s+REPLACE\s+)?((RANGE|TEXT|POINT|LOOKUP|FULLTEXT|VECTOR|BTREE)\s+)?(CONSTRAINT|INDEX)\b`)
+
+// AlreadyAppliedError is used to report when another applier recorded the
+// migration first.
+type AlreadyAppliedError struct {
+	Version float64
+}
+
+func (a AlreadyAppliedError) Error() string {
+	return fmt.Sprintf("neo4j: migration %f was recorded by another applier", a.Version)
+}
+
+// Error is an error returned by the server for a statement.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("neo4j: %s: %s", e.Code, e.Message)
+}
+
+// StatementError is used to report the statement of a migration which
+// failed, its transaction was rolled back.
+type StatementError struct {
+	Statement string
+	Err       error
+}
+
+func (s StatementError) Error() string {
+	return fmt.Sprintf("neo4j: statement %q failed: %s", s.Statement, s.Err)
+}
+
+func (s StatementError) Unwrap() error {
+	return s.Err
+}
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithDatabase sets the database migrations run in.
+func WithDatabase(database string) Option {
+	return func(d *Driver) {
+		d.database = database
+	}
+}
+
+// WithBasicAuth sets the credentials sent with every request.
+func WithBasicAuth(username string, password string) Option {
+	return func(d *Driver) {
+		d.username = username
+		d.password = password
+	}
+}
+
+// WithTimeout sets how long the driver waits for a transaction.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.timeout = timeout
+	}
+}
+
+// Driver is a darwin.Driver for Neo4j.
+type Driver struct {
+	client   *http.Client
+	endpoint string
+	database string
+	username string
+	password string
+	timeout  time.Duration
+	label    string
+}
+
+// New creates a new Driver sending requests to the server at endpoint, like
+// http://localhost:7474.
+func New(client *http.Client, endpoint string, opts ...Option) (*Driver, error) {
+	if client == nil {
+		return nil, errors.New("neo4j: http client is nil")
+	}
+
+	if endpoint == "" {
+		return nil, errors.New("neo4j: endpoint is required")
+	}
+
+	d := Driver{
+		client:   client,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		database: DefaultDatabase,
+		timeout:  10 * time.Minute,
+		label:    "DarwinMigration",
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver store the history as nodes labeled name,
+// their uniqueness constraint named after the label.
+func (d *Driver) SetTableName(name string) error {
+	if name == "" {
+		return errors.New("neo4j: the label is empty")
+	}
+
+	d.label = name
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the label
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	c := *d
+	if err := c.SetTableName(name); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// quotedLabel returns the label of the history nodes between backticks.
+func (d *Driver) quotedLabel() string {
+	return "`" + strings.ReplaceAll(d.label, "`", "``") + "`"
+}
+
+// constraint returns the name of the uniqueness constraint of the history.
+func (d *Driver) constraint() string {
+	if d.label == "DarwinMigration" {
+		return "darwin_migration_version"
+	}
+	return "`" + strings.ReplaceAll(d.label, "`", "``") + "_version`"
+}
+
+// statement is a Cypher statement of a transaction.
+type statement struct {
+	Statement  string                 `json:"statement"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// Create creates the uniqueness constraint of the history if necessary.
+func (d *Driver) Create() error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	_, err := d.commit(ctx, statement{Statement: fmt.Sprintf(`CREATE CONSTRAINT %s IF NOT EXISTS
+            FOR (m:%s) REQUIRE m.version IS UNIQUE`, d.constraint(), d.quotedLabel())})
+	return err
+}
+
+// Insert inserts a migration entry into database. It returns an
+// AlreadyAppliedError when the version is already recorded.
+func (d *Driver) Insert(e darwin.MigrationRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	_, err := d.commit(ctx, statement{
+		Statement: fmt.Sprintf(`CREATE (:%s {
+                version: $version,
+                description: $description,
+                checksum: $checksum,
+                applied_at: $applied_at,
+                execution_time: $execution_time
+            })`, d.quotedLabel()),
+		Parameters: map[string]interface{}{
+			"version":        e.Version,
+			"description":    e.Description,
+			"checksum":       e.Checksum,
+			"applied_at":     e.AppliedAt.Unix(),
+			"execution_time": int64(e.ExecutionTime),
+		},
+	})
+
+	var neoErr Error
+	if errors.As(err, &neoErr) && neoErr.Code == constraintViolation {
+		return AlreadyAppliedError{Version: e.Version}
+	}
+	return err
+}
+
+// All returns all migrations applied.
+func (d *Driver) All() ([]darwin.MigrationRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	results, err := d.commit(ctx, statement{Statement: fmt.Sprintf(`MATCH (m:%s)
+            RETURN m.version, m.description, m.checksum, m.applied_at, m.execution_time
+            ORDER BY m.version ASC`, d.quotedLabel())})
+	if err != nil {
+		return []darwin.MigrationRecord{}, err
+	}
+
+	var records []darwin.MigrationRecord
+	for _, row := range results[0].Data {
+		var (
+			version       float64
+			description   string
+			checksum      string
+			appliedAt     int64
+			executionTime int64
+		)
+
+		fields := []interface{}{&version, &description, &checksum, &appliedAt, &executionTime}
+		if len(row.Row) != len(fields) {
+			return []darwin.MigrationRecord{}, fmt.Errorf("neo4j: unexpected row %s", row.Row)
+		}
+
+		for i, f := range fields {
+			if err := json.Unmarshal(row.Row[i], f); err != nil {
+				return []darwin.MigrationRecord{}, fmt.Errorf("neo4j: unexpected row: %w", err)
+			}
+		}
+
+		records = append(records, darwin.MigrationRecord{
+			Version:       version,
+			Description:   description,
+			Checksum:      checksum,
+			AppliedAt:     time.Unix(appliedAt, 0),
+			ExecutionTime: time.Duration(executionTime),
+		})
+	}
+
+	return records, nil
+}
+
+// Exec executes the statements of the script, committing schema changes
+// and data writes in separate transactions.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	for _, group := range Groups(script) {
+		statements := make([]statement, len(group))
+		for i, s := range group {
+			statements[i] = statement{Statement: s}
+		}
+
+		results, err := d.commit(ctx, statements...)
+
+		var neoErr Error
+		if errors.As(err, &neoErr) && len(results) < len(group) {
+			// Results are returned for the statements which succeeded
+			// before the failed one.
+			return time.Since(start), StatementError{Statement: group[len(results)], Err: err}
+		}
+
+		if err != nil {
+			return time.Since(start), err
+		}
+	}
+
+	return time.Since(start), nil
+}
+
+// Groups splits a script into groups of consecutive statements which can run
+// in the same transaction: schema changes and data writes are never grouped
+// together.
+func Groups(script string) [][]string {
+	var groups [][]string
+
+	previous := false
+	for i, s := range SplitStatements(script) {
+		schema := schemaStatement.MatchString(s)
+		if i == 0 || schema != previous {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], s)
+		previous = schema
+	}
+
+	return groups
+}
+
+type result struct {
+	Columns []string `json:"columns"`
+	Data    []struct {
+		Row []json.RawMessage `json:"row"`
+	} `json:"data"`
+}
+
+// commit runs the statements in a single transaction. When a statement
+// fails the server rolls the transaction back, the results of the
+// statements before it are returned with the error.
+func (d *Driver) commit(ctx context.Context, statements ...statement) ([]result, error) {
+	body, err := json.Marshal(map[string]interface{}{"statements": statements})
+	if err != nil {
+		return nil, err
+	}
+
+	u := fmt.Sprintf("%s/db/%s/tx/commit", d.endpoint, url.PathEscape(d.database))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if d.username != "" {
+		req.SetBasicAuth(d.username, d.password)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Results []result `json:"results"`
+		Errors  []Error  `json:"errors"`
+	}
+	if err := json.Unmarshal(b, &response); err != nil {
+		return nil, fmt.Errorf("neo4j: %d %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	if len(response.Errors) > 0 {
+		return response.Results, response.Errors[0]
+	}
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("neo4j: %d %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	if len(response.Results) < len(statements) {
+		return nil, fmt.Errorf("neo4j: %d results for %d statements", len(response.Results), len(statements))
+	}
+
+	return response.Results, nil
+}
@@ -0,0 +1,149 @@
+package neo4j
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// fakeServer records the transactions it receives and answers with
+// response.
+type fakeServer struct {
+	transactions [][]string
+	username     string
+	response     string
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/db/graph/tx/commit" {
+		http.NotFound(w, r)
+		return
+	}
+
+	f.username, _, _ = r.BasicAuth()
+
+	var body struct {
+		Statements []statement `json:"statements"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	var tx []string
+	for _, s := range body.Statements {
+		tx = append(tx, s.Statement)
+	}
+	f.transactions = append(f.transactions, tx)
+
+	if f.response != "" {
+		w.Write([]byte(f.response))
+		return
+	}
+
+	results := strings.Repeat(`{"columns": [], "data": []},`, len(tx))
+	w.Write([]byte(`{"results": [` + strings.TrimSuffix(results, ",") + `], "errors": []}`))
+}
+
+func newFakeDriver(t *testing.T, server *fakeServer) *Driver {
+	s := httptest.NewServer(server)
+	t.Cleanup(s.Close)
+
+	d, err := New(s.Client(), s.URL, WithDatabase("graph"), WithBasicAuth("neo4j", "secret"))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	return d
+}
+
+func Test_Groups(t *testing.T) {
+	script := `CREATE CONSTRAINT person_id FOR (p:Person) REQUIRE p.id IS UNIQUE;
+CREATE INDEX person_name IF NOT EXISTS FOR (p:Person) ON (p.name);
+// backfill; the ids
+MATCH (p:Person) WHERE p.id IS NULL SET p.id = randomUUID();
+MATCH (p:Person {name: 'a;b'}) DETACH DELETE p;
+DROP INDEX old_index;
+`
+
+	expected := [][]string{
+		{
+			"CREATE CONSTRAINT person_id FOR (p:Person) REQUIRE p.id IS UNIQUE",
+			"CREATE INDEX person_name IF NOT EXISTS FOR (p:Person) ON (p.name)",
+		},
+		{
+			"// backfill; the ids\nMATCH (p:Person) WHERE p.id IS NULL SET p.id = randomUUID()",
+			"MATCH (p:Person {name: 'a;b'}) DETACH DELETE p",
+		},
+		{
+			"DROP INDEX old_index",
+		},
+	}
+
+	if got := Groups(script); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func Test_Driver_Exec(t *testing.T) {
+	server := &fakeServer{}
+	d := newFakeDriver(t, server)
+
+	script := "CREATE INDEX a FOR (n:A) ON (n.x);\nMATCH (n:A) SET n.x = 1;\n"
+	if _, err := d.Exec(script); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(server.transactions) != 2 {
+		t.Errorf("Must run schema changes and writes in separate transactions, got %q", server.transactions)
+	}
+
+	if server.username != "neo4j" {
+		t.Errorf("Must authenticate, got %q", server.username)
+	}
+}
+
+func Test_Driver_Exec_error(t *testing.T) {
+	server := &fakeServer{response: `{"results": [{"columns": [], "data": []}], "errors": [{"code": "Neo.ClientError.Statement.SyntaxError", "message": "Invalid input"}]}`}
+	d := newFakeDriver(t, server)
+
+	_, err := d.Exec("MATCH (n) SET n.a = 1;\nMATCH (n) SETT n.b = 2;\n")
+
+	var statementErr StatementError
+	if !errors.As(err, &statementErr) || statementErr.Statement != "MATCH (n) SETT n.b = 2" {
+		t.Fatalf("Must report the failed statement, got %v", err)
+	}
+
+	var neoErr Error
+	if !errors.As(err, &neoErr) || neoErr.Code != "Neo.ClientError.Statement.SyntaxError" {
+		t.Errorf("Must wrap the server error, got %v", err)
+	}
+}
+
+func Test_Driver_Insert_already_applied(t *testing.T) {
+	server := &fakeServer{response: `{"results": [], "errors": [{"code": "Neo.ClientError.Schema.ConstraintValidationFailed", "message": "already exists"}]}`}
+	d := newFakeDriver(t, server)
+
+	if err := d.Insert(darwin.MigrationRecord{Version: 1}); !errors.As(err, &AlreadyAppliedError{}) {
+		t.Errorf("Expected AlreadyAppliedError, got %v", err)
+	}
+}
+
+func Test_Driver_All(t *testing.T) {
+	server := &fakeServer{response: `{"results": [{"columns": ["m.version"], "data": [{"row": [1.1, "People", "abc", 1700000000, 1500]}]}], "errors": []}`}
+	d := newFakeDriver(t, server)
+
+	records, err := d.All()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := darwin.MigrationRecord{Version: 1.1, Description: "People", Checksum: "abc", AppliedAt: time.Unix(1700000000, 0), ExecutionTime: 1500}
+	if len(records) != 1 || records[0] != expected {
+		t.Errorf("Expected %#v, got %#v", expected, records)
+	}
+}
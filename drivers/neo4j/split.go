@@ -0,0 +1,15 @@
+package neo4j
+
+import "github.com/dustinevan/darwin"
+
+// syntax is the syntax of Cypher scripts: strings escaped with a backslash,
+// backquoted names and // comments.
+var syntax = darwin.Syntax{BackslashEscapes: true, Backticks: true, SlashComments: true}
+
+// SplitStatements splits a Cypher script into its statements with the
+// syntax of darwin.SplitStatements. Semicolons in strings, backquoted names
+// and comments don't end a statement. The statements are returned without
+// their trailing semicolon; statements holding only comments are dropped.
+func SplitStatements(script string) []string {
+	return darwin.SplitStatements(script, syntax)
+}
@@ -0,0 +1,291 @@
+// Package pgx provides a darwin.Driver for PostgreSQL running on a pgx
+// connection pool instead of database/sql, so services reuse the pool they
+// already configured.
+//
+// A script is sent as a single Exec in its transaction, pgx uses the simple
+// protocol for statements without arguments and runs all the statements of
+// the script in one round trip. The data files attached to a migration are
+// streamed with COPY FROM STDIN. Errors keep the SQLSTATE reported by
+// Postgres, ErrorCode returns it and WithRetries retries the migrations
+// failing with a serialization failure or a deadlock.
+//
+// The history table is the one of darwin.PostgresDialect, a database
+// migrated with the database/sql driver can switch to this one.
+//
+// The driver doesn't import pgx, it works with the Pool interface. With
+// github.com/jackc/pgx/v5/pgxpool, and this package imported as pgxdriver,
+// it is a few lines:
+//
+//	type pool struct{ *pgxpool.Pool }
+//
+//	func (p pool) Begin(ctx context.Context) (pgxdriver.Tx, error) {
+//		tx, err := p.Pool.Begin(ctx)
+//		return txn{tx}, err
+//	}
+//
+//	func (p pool) Query(ctx context.Context, sql string, args ...interface{}) (pgxdriver.Rows, error) {
+//		return p.Pool.Query(ctx, sql, args...)
+//	}
+//
+//	type txn struct{ pgx.Tx }
+//
+//	func (t txn) Exec(ctx context.Context, sql string, args ...interface{}) error {
+//		_, err := t.Tx.Exec(ctx, sql, args...)
+//		return err
+//	}
+//
+//	func (t txn) CopyFrom(ctx context.Context, r io.Reader, sql string) (int64, error) {
+//		tag, err := t.Tx.Conn().PgConn().CopyFrom(ctx, r, sql)
+//		return tag.RowsAffected(), err
+//	}
+package pgx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+	"github.com/dustinevan/darwin/drivers/internal/dbutil"
+)
+
+// Default values used by New.
+const (
+	DefaultAttempts = 1
+	DefaultBackoff  = 50 * time.Millisecond
+	DefaultTimeout  = time.Hour
+)
+
+// deadlockDetected is the SQLSTATE reported when a transaction was chosen as
+// the victim of a deadlock.
+const deadlockDetected = "40P01"
+
+// Pool is the part of a pgx pool used by the driver.
+type Pool interface {
+	Begin(ctx context.Context) (Tx, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (Rows, error)
+}
+
+// Tx is a pgx transaction.
+type Tx interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) error
+	CopyFrom(ctx context.Context, r io.Reader, sql string) (int64, error)
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+// Rows is implemented by pgx.Rows.
+type Rows interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+	Close()
+}
+
+// ErrorCode returns the SQLSTATE code of err, or an empty string when err
+// wasn't reported by Postgres.
+func ErrorCode(err error) string {
+	return dbutil.SQLState(err)
+}
+
+// Retryable reports if the transaction failing with err can be retried.
+func Retryable(err error) bool {
+	switch ErrorCode(err) {
+	case dbutil.SerializationFailure, deadlockDetected:
+		return true
+	}
+	return false
+}
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithRetries sets how many times a migration failing with a retryable error
+// is attempted and the initial wait between attempts.
+func WithRetries(attempts int, backoff time.Duration) Option {
+	return func(d *Driver) {
+		d.attempts = attempts
+		d.backoff = backoff
+	}
+}
+
+// WithTimeout sets the timeout of each call to the database.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.timeout = timeout
+	}
+}
+
+// Driver is a darwin.Driver for PostgreSQL over a pgx pool.
+type Driver struct {
+	pool    Pool
+	dialect darwin.PostgresDialect
+
+	attempts int
+	backoff  time.Duration
+	timeout  time.Duration
+}
+
+// New creates a new Driver using the pgx pool.
+func New(pool Pool, opts ...Option) (*Driver, error) {
+	if pool == nil {
+		return nil, errors.New("pgx: pool is nil")
+	}
+
+	d := Driver{
+		pool:     pool,
+		attempts: DefaultAttempts,
+		backoff:  DefaultBackoff,
+		timeout:  DefaultTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep the history in the table name,
+// qualified by its schema or not.
+func (d *Driver) SetTableName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("pgx: the table name is empty")
+	}
+
+	d.dialect.Table = name
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	c := *d
+	if err := c.SetTableName(name); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Create creates the table darwin_migrations if necessary.
+func (d *Driver) Create() error {
+	return d.transaction(func(ctx context.Context, tx Tx) error {
+		return tx.Exec(ctx, d.dialect.CreateTableSQL())
+	})
+}
+
+// Insert inserts a migration entry into database.
+func (d *Driver) Insert(e darwin.MigrationRecord) error {
+	return d.transaction(func(ctx context.Context, tx Tx) error {
+		return tx.Exec(ctx, d.dialect.InsertSQL(),
+			e.Version,
+			e.Description,
+			e.Checksum,
+			e.AppliedAt.Unix(),
+			e.ExecutionTime,
+		)
+	})
+}
+
+// All returns all migrations applied.
+func (d *Driver) All() ([]darwin.MigrationRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	rows, err := d.pool.Query(ctx, d.dialect.AllSQL())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []darwin.MigrationRecord
+	for rows.Next() {
+		var (
+			version       float64
+			description   string
+			checksum      string
+			appliedAt     int64
+			executionTime float64
+		)
+
+		if err := rows.Scan(&version, &description, &checksum, &appliedAt, &executionTime); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, darwin.MigrationRecord{
+			Version:       version,
+			Description:   description,
+			Checksum:      checksum,
+			AppliedAt:     time.Unix(appliedAt, 0),
+			ExecutionTime: time.Duration(executionTime),
+		})
+	}
+
+	return entries, rows.Err()
+}
+
+// Exec executes the script in a transaction.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	start := time.Now()
+
+	err := d.transaction(func(ctx context.Context, tx Tx) error {
+		return tx.Exec(ctx, script)
+	})
+
+	return time.Since(start), err
+}
+
+// LoadData streams the CSV data of r into table with COPY FROM STDIN.
+func (d *Driver) LoadData(table string, r io.Reader) (time.Duration, error) {
+	start := time.Now()
+
+	stmt := fmt.Sprintf("COPY %s FROM STDIN WITH (FORMAT csv)", quoteIdentifier(table))
+
+	// The data can't be read twice, the copy is never retried.
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return time.Since(start), err
+	}
+
+	if _, err := tx.CopyFrom(ctx, r, stmt); err != nil {
+		tx.Rollback(ctx)
+		return time.Since(start), err
+	}
+
+	return time.Since(start), tx.Commit(ctx)
+}
+
+// transaction runs f in a transaction, retried on retryable errors.
+func (d *Driver) transaction(f func(context.Context, Tx) error) error {
+	return dbutil.Retry(d.attempts, d.backoff, Retryable, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+		defer cancel()
+
+		tx, err := d.pool.Begin(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := f(ctx, tx); err != nil {
+			tx.Rollback(ctx)
+			return err
+		}
+
+		return tx.Commit(ctx)
+	})
+}
+
+// quoteIdentifier quotes a possibly schema qualified identifier.
+func quoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = `"` + strings.Replace(part, `"`, `""`, -1) + `"`
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,156 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+type stateError string
+
+func (s stateError) Error() string    { return "state " + string(s) }
+func (s stateError) SQLState() string { return string(s) }
+
+// fakePool records the statements of the committed transactions.
+type fakePool struct {
+	committed []string
+	copied    string
+	failures  []error
+	records   [][]interface{}
+}
+
+func (f *fakePool) Begin(ctx context.Context) (Tx, error) {
+	return &fakeTx{pool: f}, nil
+}
+
+func (f *fakePool) Query(ctx context.Context, sql string, args ...interface{}) (Rows, error) {
+	return &fakeRows{records: f.records}, nil
+}
+
+type fakeTx struct {
+	pool       *fakePool
+	statements []string
+	records    [][]interface{}
+}
+
+func (t *fakeTx) Exec(ctx context.Context, sql string, args ...interface{}) error {
+	if len(t.pool.failures) > 0 {
+		err := t.pool.failures[0]
+		t.pool.failures = t.pool.failures[1:]
+		return err
+	}
+	t.statements = append(t.statements, sql)
+	if len(args) > 0 {
+		t.records = append(t.records, args)
+	}
+	return nil
+}
+
+func (t *fakeTx) CopyFrom(ctx context.Context, r io.Reader, sql string) (int64, error) {
+	b, err := io.ReadAll(r)
+	t.pool.copied = sql + "\n" + string(b)
+	return 1, err
+}
+
+func (t *fakeTx) Commit(ctx context.Context) error {
+	t.pool.committed = append(t.pool.committed, t.statements...)
+	t.pool.records = append(t.pool.records, t.records...)
+	return nil
+}
+
+func (t *fakeTx) Rollback(ctx context.Context) error { return nil }
+
+type fakeRows struct {
+	records [][]interface{}
+	current []interface{}
+}
+
+func (r *fakeRows) Next() bool {
+	if len(r.records) == 0 {
+		return false
+	}
+	r.current, r.records = r.records[0], r.records[1:]
+	return true
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	*dest[0].(*float64) = r.current[0].(float64)
+	*dest[1].(*string) = r.current[1].(string)
+	*dest[2].(*string) = r.current[2].(string)
+	*dest[3].(*int64) = r.current[3].(int64)
+	*dest[4].(*float64) = float64(r.current[4].(time.Duration))
+	return nil
+}
+
+func (r *fakeRows) Err() error { return nil }
+func (r *fakeRows) Close()     {}
+
+func Test_Driver_Migrate(t *testing.T) {
+	pool := &fakePool{}
+
+	d, err := New(pool)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	migrations := []darwin.Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT); CREATE INDEX ON users (id);"},
+	}
+
+	if err := darwin.Migrate(d, migrations); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(pool.committed) != 3 || pool.committed[1] != migrations[0].Script {
+		t.Errorf("Must execute the script in a single Exec, got %q", pool.committed)
+	}
+
+	records, err := d.All()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(records) != 1 || records[0].Version != 1 || records[0].Checksum != migrations[0].Checksum() {
+		t.Errorf("Must record the migration, got %#v", records)
+	}
+}
+
+func Test_Driver_Exec_retry(t *testing.T) {
+	pool := &fakePool{failures: []error{stateError("40P01")}}
+
+	d, _ := New(pool, WithRetries(3, time.Millisecond))
+
+	if _, err := d.Exec("UPDATE users SET id = 1;"); err != nil {
+		t.Fatalf("Must retry the deadlocked transaction, got %s", err)
+	}
+
+	pool.failures = []error{stateError("42P01")}
+	_, err := d.Exec("UPDATE missing SET id = 1;")
+	if ErrorCode(err) != "42P01" {
+		t.Errorf("Must return the Postgres error, got %v", err)
+	}
+
+	if !Retryable(stateError("40001")) || Retryable(errors.New("state 40001")) {
+		t.Errorf("Must classify the errors by SQLSTATE")
+	}
+}
+
+func Test_Driver_LoadData(t *testing.T) {
+	pool := &fakePool{}
+	d, _ := New(pool)
+
+	loader := darwin.DataLoader(d)
+	if _, err := loader.LoadData("public.countries", strings.NewReader("fr,France\n")); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := "COPY \"public\".\"countries\" FROM STDIN WITH (FORMAT csv)\nfr,France\n"
+	if pool.copied != expected {
+		t.Errorf("Expected %q, got %q", expected, pool.copied)
+	}
+}
@@ -0,0 +1,372 @@
+// Package redis provides a darwin.Driver running migrations on Redis, for
+// key renames, RediSearch index definitions, ACL setup and the like.
+//
+// A migration script is a sequence of commands, one per line, and Lua
+// scripts:
+//
+//	-- Version: 1.0
+//	-- Description: Index the users
+//	FT.CREATE users ON HASH PREFIX 1 user: SCHEMA email TAG name TEXT
+//	ACL SETUSER reporting on >secret ~report:* +@read
+//	-- darwin:lua 0
+//	for _, key in ipairs(redis.call('KEYS', 'session:*')) do
+//	    redis.call('RENAME', key, 'sess:' .. string.sub(key, 9))
+//	end
+//	-- darwin:end
+//
+// The history is stored in the darwin:migrations hash, one field per
+// version written with HSETNX so two appliers can never record the same
+// version. Lock sets the darwin:lock key with SET NX and an expiration, so
+// a crashed applier doesn't hold the lock forever; RefreshLock extends it
+// with the heartbeat of darwin.WithHeartbeat during long migrations.
+//
+// The driver works with any client through the Client interface. With
+// github.com/redis/go-redis it is a few lines:
+//
+//	type client struct{ rdb *redis.Client }
+//
+//	func (c client) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+//		v, err := c.rdb.Do(ctx, args...).Result()
+//		if err == redis.Nil {
+//			return nil, nil
+//		}
+//		return v, err
+//	}
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Default values used by New.
+const (
+	DefaultPrefix      = "darwin:"
+	DefaultLockTimeout = time.Minute
+	DefaultLockTTL     = 10 * time.Minute
+)
+
+// ErrLockTimeout is returned by Lock when the lock is held by another
+// applier for longer than the lock timeout.
+var ErrLockTimeout = errors.New("redis: timeout waiting for the migration lock")
+
+// ErrLockLost is returned by RefreshLock when the lock expired and may have
+// been acquired by another applier.
+var ErrLockLost = errors.New("redis: the migration lock expired")
+
+// unlockScript deletes the lock key if it is still held by the owner.
+const unlockScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('DEL', KEYS[1]) end return 0`
+
+// refreshScript resets the expiration of the lock key if it is still held
+// by the owner.
+const refreshScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('PEXPIRE', KEYS[1], ARGV[2]) end return 0`
+
+// Client sends commands to Redis. A nil reply is returned as nil, without
+// error.
+type Client interface {
+	Do(ctx context.Context, args ...interface{}) (interface{}, error)
+}
+
+// AlreadyAppliedError is used to report when another applier recorded the
+// migration first.
+type AlreadyAppliedError struct {
+	Version float64
+}
+
+func (a AlreadyAppliedError) Error() string {
+	return fmt.Sprintf("redis: migration %f was recorded by another applier", a.Version)
+}
+
+// CommandError is used to report a command which failed, the commands
+// before it are applied.
+type CommandError struct {
+	Command Command
+	Err     error
+}
+
+func (c CommandError) Error() string {
+	name := ""
+	if len(c.Command) > 0 {
+		name = c.Command[0]
+	}
+	return fmt.Sprintf("redis: command %s failed: %s", name, c.Err)
+}
+
+func (c CommandError) Unwrap() error {
+	return c.Err
+}
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithPrefix sets the prefix of the keys of the driver.
+func WithPrefix(prefix string) Option {
+	return func(d *Driver) {
+		d.prefix = prefix
+	}
+}
+
+// WithLockTimeout sets how long Lock waits for the lock. A negative timeout
+// waits forever.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.lockTimeout = timeout
+	}
+}
+
+// WithLockTTL sets when the lock expires if it isn't released.
+func WithLockTTL(ttl time.Duration) Option {
+	return func(d *Driver) {
+		d.lockTTL = ttl
+	}
+}
+
+// WithTimeout sets how long the driver waits for a migration.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.timeout = timeout
+	}
+}
+
+// Driver is a darwin.Driver for Redis.
+type Driver struct {
+	client      Client
+	prefix      string
+	lockTimeout time.Duration
+	lockTTL     time.Duration
+	timeout     time.Duration
+	poll        time.Duration
+	owner       string
+}
+
+// New creates a new Driver sending commands with client.
+func New(client Client, opts ...Option) (*Driver, error) {
+	if client == nil {
+		return nil, errors.New("redis: client is nil")
+	}
+
+	d := Driver{
+		client:      client,
+		prefix:      DefaultPrefix,
+		lockTimeout: DefaultLockTimeout,
+		lockTTL:     DefaultLockTTL,
+		timeout:     10 * time.Minute,
+		poll:        time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep its keys, the lock included, under the
+// name: prefix.
+func (d *Driver) SetTableName(name string) error {
+	if name == "" {
+		return errors.New("redis: the table name is empty")
+	}
+
+	d.prefix = name + ":"
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping its keys under the prefix
+// of the name, like SetTableName, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	c := *d
+	if err := c.SetTableName(name); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Create does nothing, the history hash is created by the first insert.
+func (d *Driver) Create() error {
+	return nil
+}
+
+// record is a history entry, stored as JSON in the history hash.
+type record struct {
+	Version       float64 `json:"version"`
+	Description   string  `json:"description"`
+	Checksum      string  `json:"checksum"`
+	AppliedAt     int64   `json:"applied_at"`
+	ExecutionTime int64   `json:"execution_time"`
+}
+
+// Insert inserts a migration entry into database. It returns an
+// AlreadyAppliedError when the version is already recorded.
+func (d *Driver) Insert(e darwin.MigrationRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	b, err := json.Marshal(record{
+		Version:       e.Version,
+		Description:   e.Description,
+		Checksum:      e.Checksum,
+		AppliedAt:     e.AppliedAt.Unix(),
+		ExecutionTime: int64(e.ExecutionTime),
+	})
+	if err != nil {
+		return err
+	}
+
+	field := strconv.FormatFloat(e.Version, 'f', -1, 64)
+	reply, err := d.client.Do(ctx, "HSETNX", d.prefix+"migrations", field, string(b))
+	if err != nil {
+		return err
+	}
+
+	if n, _ := reply.(int64); n == 0 {
+		return AlreadyAppliedError{Version: e.Version}
+	}
+
+	return nil
+}
+
+// All returns all migrations applied.
+func (d *Driver) All() ([]darwin.MigrationRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	reply, err := d.client.Do(ctx, "HVALS", d.prefix+"migrations")
+	if err != nil {
+		return []darwin.MigrationRecord{}, err
+	}
+
+	values, _ := reply.([]interface{})
+
+	var records []darwin.MigrationRecord
+	for _, v := range values {
+		var r record
+		if err := json.Unmarshal([]byte(str(v)), &r); err != nil {
+			return []darwin.MigrationRecord{}, fmt.Errorf("redis: invalid history entry: %w", err)
+		}
+
+		records = append(records, darwin.MigrationRecord{
+			Version:       r.Version,
+			Description:   r.Description,
+			Checksum:      r.Checksum,
+			AppliedAt:     time.Unix(r.AppliedAt, 0),
+			ExecutionTime: time.Duration(r.ExecutionTime),
+		})
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Version < records[j].Version
+	})
+
+	return records, nil
+}
+
+// Exec sends the commands of the script in order.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	start := time.Now()
+
+	commands, err := ParseScript(script)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	for _, command := range commands {
+		args := make([]interface{}, len(command))
+		for i, arg := range command {
+			args[i] = arg
+		}
+
+		if _, err := d.client.Do(ctx, args...); err != nil {
+			return time.Since(start), CommandError{Command: command, Err: err}
+		}
+	}
+
+	return time.Since(start), nil
+}
+
+// Lock acquires the migration lock by setting the lock key, waiting for the
+// current owner to release it or for the key to expire.
+func (d *Driver) Lock() error {
+	ctx := context.Background()
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	owner := hex.EncodeToString(b)
+
+	deadline := time.Now().Add(d.lockTimeout)
+	for {
+		reply, err := d.client.Do(ctx, "SET", d.prefix+"lock", owner, "NX", "PX", strconv.FormatInt(int64(d.lockTTL/time.Millisecond), 10))
+		if err != nil {
+			return err
+		}
+
+		if reply != nil {
+			d.owner = owner
+			return nil
+		}
+
+		if d.lockTimeout >= 0 && time.Now().After(deadline) {
+			return ErrLockTimeout
+		}
+
+		time.Sleep(d.poll)
+	}
+}
+
+// Unlock releases the lock acquired by Lock, unless it expired and was
+// acquired by another applier.
+func (d *Driver) Unlock() error {
+	if d.owner == "" {
+		return nil
+	}
+
+	_, err := d.client.Do(context.Background(), "EVAL", unlockScript, "1", d.prefix+"lock", d.owner)
+	d.owner = ""
+	return err
+}
+
+// RefreshLock resets the expiration of the lock acquired by Lock, called by
+// darwin with the heartbeat of long migrations.
+func (d *Driver) RefreshLock() error {
+	if d.owner == "" {
+		return ErrLockLost
+	}
+
+	reply, err := d.client.Do(context.Background(), "EVAL", refreshScript, "1", d.prefix+"lock", d.owner, strconv.FormatInt(int64(d.lockTTL/time.Millisecond), 10))
+	if err != nil {
+		return err
+	}
+
+	if n, ok := reply.(int64); ok && n == 0 {
+		return ErrLockLost
+	}
+
+	return nil
+}
+
+// str returns a reply as a string, clients return bulk strings as string or
+// []byte.
+func str(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,210 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// fakeClient is an in-memory Redis supporting the commands used by the
+// driver and recording the others.
+type fakeClient struct {
+	commands [][]string
+	hash     map[string]string
+	keys     map[string]string
+	fail     string
+}
+
+func (f *fakeClient) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	command := make([]string, len(args))
+	for i, arg := range args {
+		command[i] = fmt.Sprint(arg)
+	}
+
+	switch command[0] {
+	case "HSETNX":
+		if _, ok := f.hash[command[2]]; ok {
+			return int64(0), nil
+		}
+		f.hash[command[2]] = command[3]
+		return int64(1), nil
+
+	case "HVALS":
+		var values []interface{}
+		for _, v := range f.hash {
+			values = append(values, v)
+		}
+		return values, nil
+
+	case "SET":
+		if _, ok := f.keys[command[1]]; ok {
+			return nil, nil
+		}
+		f.keys[command[1]] = command[2]
+		return "OK", nil
+	}
+
+	f.commands = append(f.commands, command)
+	if command[0] == f.fail {
+		return nil, errors.New("ERR unknown command")
+	}
+	return "OK", nil
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{hash: map[string]string{}, keys: map[string]string{}}
+}
+
+func Test_ParseScript(t *testing.T) {
+	script := `# rename the keys
+RENAME "user:1" 'user:one'
+SET greeting "hello \"world\"\n"
+-- darwin:lua 1 counter 10
+-- a Lua comment
+return redis.call('INCRBY', KEYS[1], ARGV[1])
+-- darwin:end
+`
+
+	commands, err := ParseScript(script)
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := []Command{
+		{"RENAME", "user:1", "user:one"},
+		{"SET", "greeting", "hello \"world\"\n"},
+		{"EVAL", "-- a Lua comment\nreturn redis.call('INCRBY', KEYS[1], ARGV[1])\n", "1", "counter", "10"},
+	}
+
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("Expected %q, got %q", expected, commands)
+	}
+}
+
+func Test_ParseScript_invalid(t *testing.T) {
+	scripts := []string{
+		"SET a \"b\n",
+		"-- darwin:lua 0\nreturn 1\n",
+		"-- darwin:end\n",
+		"-- darwin:lua\nreturn 1\n-- darwin:end\n",
+	}
+
+	for _, script := range scripts {
+		if _, err := ParseScript(script); err == nil {
+			t.Errorf("Must not accept %q", script)
+		}
+	}
+}
+
+func Test_Driver_Exec(t *testing.T) {
+	client := newFakeClient()
+	client.fail = "FT.CREATE"
+
+	d, err := New(client)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	_, err = d.Exec("ACL SETUSER reporting on\nFT.CREATE idx ON HASH SCHEMA name TEXT\nDEL old\n")
+
+	var commandErr CommandError
+	if !errors.As(err, &commandErr) || commandErr.Command[0] != "FT.CREATE" {
+		t.Fatalf("Must report the failed command, got %v", err)
+	}
+
+	if len(client.commands) != 2 {
+		t.Errorf("Must stop at the failed command, got %q", client.commands)
+	}
+}
+
+func Test_Driver_history(t *testing.T) {
+	d, err := New(newFakeClient())
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	records := []darwin.MigrationRecord{
+		{Version: 2, Description: "Second", Checksum: "b", AppliedAt: time.Unix(1700000100, 0), ExecutionTime: 20},
+		{Version: 1, Description: "First", Checksum: "a", AppliedAt: time.Unix(1700000000, 0), ExecutionTime: 10},
+	}
+
+	for _, r := range records {
+		if err := d.Insert(r); err != nil {
+			t.Fatalf("Must not return error, got %s", err)
+		}
+	}
+
+	if err := d.Insert(records[0]); !errors.As(err, &AlreadyAppliedError{}) {
+		t.Errorf("Expected AlreadyAppliedError, got %v", err)
+	}
+
+	all, err := d.All()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if !reflect.DeepEqual(all, []darwin.MigrationRecord{records[1], records[0]}) {
+		t.Errorf("Must return the records sorted by version, got %#v", all)
+	}
+}
+
+func Test_Driver_Lock(t *testing.T) {
+	client := newFakeClient()
+
+	d, err := New(client, WithLockTimeout(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+	d.poll = time.Millisecond
+
+	if err := d.Lock(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	other, _ := New(client, WithLockTimeout(10*time.Millisecond))
+	other.poll = time.Millisecond
+
+	if err := other.Lock(); err != ErrLockTimeout {
+		t.Errorf("Expected ErrLockTimeout, got %v", err)
+	}
+
+	if err := d.Unlock(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	last := client.commands[len(client.commands)-1]
+	if last[0] != "EVAL" || last[3] != "darwin:lock" || last[4] != client.keys["darwin:lock"] {
+		t.Errorf("Must release the lock it owns, got %q", last)
+	}
+}
+
+func Test_Driver_RefreshLock(t *testing.T) {
+	client := newFakeClient()
+
+	d, err := New(client, WithLockTTL(time.Minute))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	if err := d.RefreshLock(); err != ErrLockLost {
+		t.Errorf("Expected ErrLockLost before Lock, got %v", err)
+	}
+
+	if err := d.Lock(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := d.RefreshLock(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	last := client.commands[len(client.commands)-1]
+	if last[0] != "EVAL" || last[1] != refreshScript || last[4] != client.keys["darwin:lock"] || last[5] != "60000" {
+		t.Errorf("Must extend the lock it owns, got %q", last)
+	}
+}
@@ -0,0 +1,149 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/dustinevan/darwin"
+)
+
+// luaDirective starts a Lua script block: "-- darwin:lua numkeys key... arg...".
+const luaDirective = "lua"
+
+// endDirective ends a Lua script block.
+const endDirective = "end"
+
+// Command is a Redis command of a migration script.
+type Command []string
+
+// ParseScript returns the commands of a migration script. Each line is a
+// command with its arguments separated by spaces, arguments holding spaces
+// are written in double quotes (with backslash escapes) or single quotes,
+// like in redis-cli. A Lua script is written between a
+// "-- darwin:lua numkeys key... arg..." line and a "-- darwin:end" line, it
+// is run with EVAL. Other lines starting with "--" or "#" are comments.
+func ParseScript(script string) ([]Command, error) {
+	var commands []Command
+
+	var lua *strings.Builder
+	var eval Command
+
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), len(script)+1)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+
+		if d, ok := directive(text); ok {
+			switch {
+			case d.Name == luaDirective && lua == nil:
+				args, err := splitArgs(d.Args)
+				if err != nil {
+					return nil, fmt.Errorf("redis: line %d: %w", line, err)
+				}
+				if len(args) == 0 {
+					return nil, fmt.Errorf("redis: line %d: missing number of keys", line)
+				}
+				lua = &strings.Builder{}
+				eval = append(Command{"EVAL", ""}, args...)
+			case d.Name == endDirective && lua != nil:
+				eval[1] = lua.String()
+				commands = append(commands, eval)
+				lua, eval = nil, nil
+			default:
+				return nil, fmt.Errorf("redis: line %d: unexpected directive %q", line, d.Name)
+			}
+			continue
+		}
+
+		if lua != nil {
+			lua.WriteString(text)
+			lua.WriteByte('\n')
+			continue
+		}
+
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		args, err := splitArgs(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("redis: line %d: %w", line, err)
+		}
+		commands = append(commands, args)
+	}
+
+	if lua != nil {
+		return nil, fmt.Errorf("redis: Lua script without %s%s", darwin.DirectivePrefix, endDirective)
+	}
+
+	return commands, nil
+}
+
+// directive returns the directive on the line, if any.
+func directive(line string) (darwin.Directive, bool) {
+	for _, d := range darwin.Directives(line) {
+		return d, true
+	}
+	return darwin.Directive{}, false
+}
+
+// splitArgs splits a command line into arguments.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+
+	for i := 0; i < len(line); {
+		switch c := line[i]; {
+		case c == ' ' || c == '\t':
+			i++
+
+		case c == '"':
+			var arg strings.Builder
+			j := i + 1
+			for ; j < len(line) && line[j] != '"'; j++ {
+				if line[j] == '\\' && j+1 < len(line) {
+					j++
+					switch line[j] {
+					case 'n':
+						arg.WriteByte('\n')
+					case 'r':
+						arg.WriteByte('\r')
+					case 't':
+						arg.WriteByte('\t')
+					default:
+						arg.WriteByte(line[j])
+					}
+					continue
+				}
+				arg.WriteByte(line[j])
+			}
+			if j == len(line) {
+				return nil, fmt.Errorf("unterminated quoted argument")
+			}
+			args = append(args, arg.String())
+			i = j + 1
+
+		case c == '\'':
+			j := strings.IndexByte(line[i+1:], '\'')
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated quoted argument")
+			}
+			args = append(args, line[i+1:i+1+j])
+			i += j + 2
+
+		default:
+			j := strings.IndexAny(line[i:], " \t")
+			if j < 0 {
+				j = len(line) - i
+			}
+			args = append(args, line[i:i+j])
+			i += j
+		}
+	}
+
+	return args, nil
+}
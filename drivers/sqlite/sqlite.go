@@ -0,0 +1,404 @@
+// Package sqlite provides a darwin.Driver for SQLite.
+//
+// SQLite allows a single writer at a time, so every migration runs in a
+// BEGIN IMMEDIATE transaction which takes the write lock up front instead of
+// failing halfway with SQLITE_BUSY, and is recorded in it. Foreign keys are disabled while a
+// migration runs, following the procedure recommended by SQLite to rebuild
+// tables, and checked with PRAGMA foreign_key_check before committing.
+//
+// Scripts with the no-transaction directive, for VACUUM or pragmas which
+// can't change inside a transaction, are executed one statement at a time
+// with the foreign keys left as they are. The set directive sets a pragma
+// for the migration, restored to its previous value after it:
+//
+//	-- darwin:set cache_size = 10000
+//
+// The driver doesn't import a SQLite database/sql driver, use it with
+// modernc.org/sqlite, github.com/mattn/go-sqlite3 or any compatible driver.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/dustinevan/darwin"
+	"github.com/dustinevan/darwin/drivers/internal/dbutil"
+)
+
+// DefaultBusyTimeout is how long a connection waits for the write lock held
+// by another connection.
+const DefaultBusyTimeout = 5 * time.Second
+
+// ForeignKeyViolationError is used to report when a migration leaves rows
+// violating a foreign key constraint.
+type ForeignKeyViolationError struct {
+	Table string
+	RowID int64
+}
+
+func (f ForeignKeyViolationError) Error() string {
+	return fmt.Sprintf("sqlite: foreign key violation in table %s, rowid %d", f.Table, f.RowID)
+}
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithForeignKeys sets if foreign keys are enforced on the connections of the
+// driver, they are by default.
+func WithForeignKeys(enabled bool) Option {
+	return func(d *Driver) {
+		d.foreignKeys = enabled
+	}
+}
+
+// WithBusyTimeout sets how long a statement waits for the write lock held by
+// another connection.
+func WithBusyTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.busyTimeout = timeout
+	}
+}
+
+// Driver is a darwin.Driver for SQLite.
+type Driver struct {
+	*darwin.GenericDriver
+
+	foreignKeys bool
+	busyTimeout time.Duration
+}
+
+// New creates a new Driver for the SQLite database db.
+func New(db *sql.DB, opts ...Option) (*Driver, error) {
+	generic, err := darwin.NewGenericDriver(db, Dialect{})
+	if err != nil {
+		return nil, err
+	}
+
+	d := Driver{
+		GenericDriver: generic,
+		foreignKeys:   true,
+		busyTimeout:   DefaultBusyTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	generic, err := d.GenericDriver.WithTable(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *d
+	c.GenericDriver = generic
+	return &c, nil
+}
+
+var memoryDatabases int64
+
+// OpenMemory opens a new private in-memory database using the database/sql
+// driver registered as driverName. The pool is limited to a single
+// connection which is never closed, since every connection to ":memory:"
+// would see a different empty database. It is meant for tests.
+func OpenMemory(driverName string) (*sql.DB, error) {
+	n := atomic.AddInt64(&memoryDatabases, 1)
+
+	db, err := sql.Open(driverName, fmt.Sprintf("file:darwin_memory_%d?mode=memory&cache=shared", n))
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Exec executes the script in a BEGIN IMMEDIATE transaction, or one
+// statement at a time without a transaction when it has the no-transaction
+// directive.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	return d.ExecContext(context.Background(), script)
+}
+
+// ExecContext is Exec with a context.
+func (d *Driver) ExecContext(ctx context.Context, script string) (time.Duration, error) {
+	start := time.Now()
+
+	conn, err := d.DB.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	if err := d.prepare(ctx, conn); err != nil {
+		return time.Since(start), err
+	}
+
+	err = withPragmas(ctx, conn, script, func() error {
+		// VACUUM and some pragmas can't run in a transaction.
+		if dbutil.NoTransaction(script) {
+			for _, stmt := range darwin.SplitStatements(script, darwin.SqliteSyntax) {
+				if _, err := conn.ExecContext(ctx, stmt); err != nil {
+					return err
+				}
+			}
+			return d.check(ctx, conn)
+		}
+
+		if err := d.immediate(ctx, conn); err != nil {
+			return err
+		}
+
+		err := d.exec(ctx, conn, script)
+		if err == nil {
+			_, err = conn.ExecContext(ctx, "COMMIT")
+		}
+		if err != nil {
+			conn.ExecContext(context.Background(), "ROLLBACK")
+		}
+
+		if ferr := d.end(conn); err == nil {
+			err = ferr
+		}
+		return err
+	})
+
+	return time.Since(start), err
+}
+
+// BeginTx starts a BEGIN IMMEDIATE transaction executing migrations and
+// recording them, with the foreign keys disabled until it ends.
+func (d *Driver) BeginTx(ctx context.Context) (darwin.Tx, error) {
+	conn, err := d.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.begin(ctx, conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &transaction{conn: conn, d: d}, nil
+}
+
+// begin prepares the connection and begins a BEGIN IMMEDIATE transaction,
+// with the foreign keys disabled since they can't be toggled inside it.
+func (d *Driver) begin(ctx context.Context, conn *sql.Conn) error {
+	if err := d.prepare(ctx, conn); err != nil {
+		return err
+	}
+
+	return d.immediate(ctx, conn)
+}
+
+// immediate begins a BEGIN IMMEDIATE transaction, with the foreign keys
+// disabled since they can't be toggled inside it.
+func (d *Driver) immediate(ctx context.Context, conn *sql.Conn) error {
+	if d.foreignKeys {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		d.end(conn)
+		return err
+	}
+	return nil
+}
+
+// end enables the foreign keys again once the transaction ended, even when
+// the context of the migration is canceled.
+func (d *Driver) end(conn *sql.Conn) error {
+	if !d.foreignKeys {
+		return nil
+	}
+	_, err := conn.ExecContext(context.Background(), "PRAGMA foreign_keys = ON")
+	return err
+}
+
+// transaction is the darwin.Tx of the driver, on a connection where BEGIN
+// IMMEDIATE was executed.
+type transaction struct {
+	conn *sql.Conn
+	d    *Driver
+}
+
+// Exec executes the script, with the pragmas of its set directives.
+func (t *transaction) Exec(ctx context.Context, script string) (time.Duration, error) {
+	start := time.Now()
+	err := withPragmas(ctx, t.conn, script, func() error {
+		_, err := t.conn.ExecContext(ctx, script)
+		return err
+	})
+	return time.Since(start), err
+}
+
+// ExecStatements executes the statements of the script one at a time, with
+// the pragmas of its set directives.
+func (t *transaction) ExecStatements(ctx context.Context, script string) ([]darwin.StatementResult, error) {
+	var results []darwin.StatementResult
+	err := withPragmas(ctx, t.conn, script, func() error {
+		var err error
+		results, err = darwin.ExecStatements(ctx, t.conn, script, darwin.SqliteSyntax)
+		return err
+	})
+	return results, err
+}
+
+// Insert records the migration.
+func (t *transaction) Insert(ctx context.Context, e darwin.MigrationRecord) error {
+	query, args := darwin.InsertArgs(t.d.Dialect, e)
+	_, err := t.conn.ExecContext(ctx, query, args...)
+	return err
+}
+
+// Delete deletes the record of the migration version.
+func (t *transaction) Delete(ctx context.Context, version float64) error {
+	dd, ok := t.d.Dialect.(darwin.DeleteDialect)
+	if !ok {
+		return errors.New("sqlite: the dialect can't delete migration records")
+	}
+
+	_, err := t.conn.ExecContext(ctx, dd.DeleteSQL(), version)
+	return err
+}
+
+// Commit checks the foreign keys and commits the transaction.
+func (t *transaction) Commit() error {
+	ctx := context.Background()
+
+	err := t.d.check(ctx, t.conn)
+	if err == nil {
+		_, err = t.conn.ExecContext(ctx, "COMMIT")
+	}
+	if err != nil {
+		t.conn.ExecContext(ctx, "ROLLBACK")
+	}
+
+	return t.close(err)
+}
+
+// Rollback rolls the transaction back.
+func (t *transaction) Rollback() error {
+	_, err := t.conn.ExecContext(context.Background(), "ROLLBACK")
+	return t.close(err)
+}
+
+// close ends the transaction, returning err or the error enabling the
+// foreign keys again.
+func (t *transaction) close(err error) error {
+	if ferr := t.d.end(t.conn); err == nil {
+		err = ferr
+	}
+	t.conn.Close()
+	return err
+}
+
+// prepare applies the connection settings of the driver.
+func (d *Driver) prepare(ctx context.Context, conn *sql.Conn) error {
+	timeout := fmt.Sprintf("PRAGMA busy_timeout = %d", d.busyTimeout/time.Millisecond)
+	_, err := conn.ExecContext(ctx, timeout)
+	return err
+}
+
+// withPragmas calls f with the pragmas of the set directives of the script
+// applied to the connection, and restores their previous values after, even
+// when the context of the migration is canceled.
+func withPragmas(ctx context.Context, conn *sql.Conn, script string, f func() error) (err error) {
+	settings, err := darwin.Settings(script)
+	if err != nil {
+		return err
+	}
+
+	var previous []darwin.Setting
+	defer func() {
+		for i := len(previous) - 1; i >= 0; i-- {
+			p := previous[i]
+			if _, rerr := conn.ExecContext(context.Background(), pragmaSQL(p.Name, p.Value)); err == nil {
+				err = rerr
+			}
+		}
+	}()
+
+	for _, s := range settings {
+		var value string
+		if err := conn.QueryRowContext(ctx, "PRAGMA "+s.Name).Scan(&value); err != nil {
+			return fmt.Errorf("sqlite: reading pragma %s: %w", s.Name, err)
+		}
+
+		if _, err := conn.ExecContext(ctx, pragmaSQL(s.Name, s.Value)); err != nil {
+			return err
+		}
+		previous = append(previous, darwin.Setting{Name: s.Name, Value: value})
+	}
+
+	return f()
+}
+
+// pragmaSQL returns the statement setting the pragma.
+func pragmaSQL(name, value string) string {
+	return fmt.Sprintf("PRAGMA %s = %s", name, value)
+}
+
+func (d *Driver) exec(ctx context.Context, conn *sql.Conn, script string) error {
+	if _, err := conn.ExecContext(ctx, script); err != nil {
+		return err
+	}
+
+	return d.check(ctx, conn)
+}
+
+// check checks the foreign keys when they are enforced.
+func (d *Driver) check(ctx context.Context, conn *sql.Conn) error {
+	if !d.foreignKeys {
+		return nil
+	}
+
+	return foreignKeyCheck(ctx, conn)
+}
+
+// foreignKeyCheck returns a ForeignKeyViolationError for the first row
+// violating a foreign key constraint.
+func foreignKeyCheck(ctx context.Context, conn *sql.Conn) error {
+	rows, err := conn.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return rows.Err()
+	}
+
+	var (
+		table  string
+		rowID  sql.NullInt64
+		parent string
+		fkid   int64
+	)
+
+	if err := rows.Scan(&table, &rowID, &parent, &fkid); err != nil {
+		return err
+	}
+
+	return ForeignKeyViolationError{Table: table, RowID: rowID.Int64}
+}
@@ -0,0 +1,220 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dustinevan/darwin"
+)
+
+func Test_Driver_Exec(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	stmt := "CREATE TABLE posts (id INTEGER);"
+
+	mock.ExpectExec(regexp.QuoteMeta("PRAGMA busy_timeout = 5000")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("PRAGMA foreign_keys = OFF")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("BEGIN IMMEDIATE")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(stmt)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("PRAGMA foreign_key_check")).
+		WillReturnRows(sqlmock.NewRows([]string{"table", "rowid", "parent", "fkid"}))
+	mock.ExpectExec(regexp.QuoteMeta("COMMIT")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("PRAGMA foreign_keys = ON")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if _, err := d.Exec(stmt); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_Exec_foreign_key_violation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db, WithBusyTimeout(0))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	stmt := "DELETE FROM users;"
+
+	mock.ExpectExec(regexp.QuoteMeta("PRAGMA busy_timeout = 0")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("PRAGMA foreign_keys = OFF")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("BEGIN IMMEDIATE")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(stmt)).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectQuery(regexp.QuoteMeta("PRAGMA foreign_key_check")).
+		WillReturnRows(sqlmock.NewRows([]string{"table", "rowid", "parent", "fkid"}).AddRow("posts", 7, "users", 0))
+	mock.ExpectExec(regexp.QuoteMeta("ROLLBACK")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("PRAGMA foreign_keys = ON")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err = d.Exec(stmt)
+	if fk, ok := err.(ForeignKeyViolationError); !ok || fk.Table != "posts" || fk.RowID != 7 {
+		t.Errorf("Must report the foreign key violation, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_Exec_no_transaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	script := "-- darwin:no-transaction\nPRAGMA journal_mode = WAL;\nVACUUM;"
+
+	mock.ExpectExec(regexp.QuoteMeta("PRAGMA busy_timeout = 5000")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("PRAGMA journal_mode = WAL")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("VACUUM")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("PRAGMA foreign_key_check")).
+		WillReturnRows(sqlmock.NewRows([]string{"table", "rowid", "parent", "fkid"}))
+
+	if _, err := d.Exec(script); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_Exec_set(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db, WithForeignKeys(false))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	script := "-- darwin:set cache_size = 10000\nCREATE TABLE posts (id INTEGER);"
+
+	mock.ExpectExec(regexp.QuoteMeta("PRAGMA busy_timeout = 5000")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("PRAGMA cache_size")).
+		WillReturnRows(sqlmock.NewRows([]string{"cache_size"}).AddRow("-2000"))
+	mock.ExpectExec(regexp.QuoteMeta("PRAGMA cache_size = 10000")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("BEGIN IMMEDIATE")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(script)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("COMMIT")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("PRAGMA cache_size = -2000")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if _, err := d.Exec(script); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_Exec_foreign_keys_restore_error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	stmt := "CREATE TABLE posts (id INTEGER);"
+	restore := errors.New("disk I/O error")
+
+	mock.ExpectExec(regexp.QuoteMeta("PRAGMA busy_timeout = 5000")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("PRAGMA foreign_keys = OFF")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("BEGIN IMMEDIATE")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(stmt)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("PRAGMA foreign_key_check")).
+		WillReturnRows(sqlmock.NewRows([]string{"table", "rowid", "parent", "fkid"}))
+	mock.ExpectExec(regexp.QuoteMeta("COMMIT")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("PRAGMA foreign_keys = ON")).WillReturnError(restore)
+
+	if _, err := d.Exec(stmt); !errors.Is(err, restore) {
+		t.Errorf("Must return the error enabling the foreign keys, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_BeginTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	stmt := "CREATE TABLE posts (id INTEGER);"
+
+	mock.ExpectExec(regexp.QuoteMeta("PRAGMA busy_timeout = 5000")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("PRAGMA foreign_keys = OFF")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("BEGIN IMMEDIATE")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(stmt)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO darwin_migrations")).
+		WithArgs(1.0, "Posts", "abc", int64(0), time.Second, nil, nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("PRAGMA foreign_key_check")).
+		WillReturnRows(sqlmock.NewRows([]string{"table", "rowid", "parent", "fkid"}))
+	mock.ExpectExec(regexp.QuoteMeta("COMMIT")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("PRAGMA foreign_keys = ON")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	tx, err := d.BeginTx(context.Background())
+	if err != nil {
+		t.Fatalf("Must begin a transaction, got %s", err)
+	}
+
+	if _, err := tx.Exec(context.Background(), stmt); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	record := darwin.MigrationRecord{Version: 1, Description: "Posts", Checksum: "abc", AppliedAt: time.Unix(0, 0), ExecutionTime: time.Second}
+	if err := tx.Insert(context.Background(), record); err != nil {
+		t.Fatalf("Must record the migration, got %s", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Must commit, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
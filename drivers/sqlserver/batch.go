@@ -0,0 +1,127 @@
+package sqlserver
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Batch is a part of a script delimited by GO separators.
+type Batch struct {
+	SQL string
+
+	// Count is the number of times the batch is executed, as in "GO 5".
+	Count int
+}
+
+// SplitBatches splits a T-SQL script into the batches delimited by lines
+// holding only the GO separator, like sqlcmd and SSMS do. Separators inside
+// comments and string literals are ignored. Empty batches are dropped.
+func SplitBatches(script string) []Batch {
+	var (
+		batches []Batch
+		current strings.Builder
+		state   scanState
+	)
+
+	for _, line := range strings.SplitAfter(script, "\n") {
+		if state == code {
+			if count, ok := separator(line); ok {
+				if sql := strings.TrimSpace(current.String()); sql != "" {
+					batches = append(batches, Batch{SQL: sql, Count: count})
+				}
+				current.Reset()
+				continue
+			}
+		}
+
+		current.WriteString(line)
+		state = state.scan(line)
+	}
+
+	if sql := strings.TrimSpace(current.String()); sql != "" {
+		batches = append(batches, Batch{SQL: sql, Count: 1})
+	}
+
+	return batches
+}
+
+// separator reports if the line is a GO separator and returns its count.
+func separator(line string) (int, bool) {
+	if i := strings.Index(line, "--"); i >= 0 {
+		line = line[:i]
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 || len(fields) > 2 || !strings.EqualFold(fields[0], "GO") {
+		return 0, false
+	}
+
+	if len(fields) == 1 {
+		return 1, true
+	}
+
+	count, err := strconv.Atoi(fields[1])
+	if err != nil || count < 1 {
+		return 0, false
+	}
+
+	return count, true
+}
+
+// scanState tracks the lexical context at the end of a line.
+type scanState int
+
+const (
+	code scanState = iota
+	blockComment
+	stringLiteral
+	quotedIdentifier
+)
+
+// scan returns the state at the end of line when it starts in state s.
+func (s scanState) scan(line string) scanState {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+
+		switch s {
+		case code:
+			switch {
+			case c == '-' && i+1 < len(line) && line[i+1] == '-':
+				return code
+			case c == '/' && i+1 < len(line) && line[i+1] == '*':
+				s = blockComment
+				i++
+			case c == '\'':
+				s = stringLiteral
+			case c == '[':
+				s = quotedIdentifier
+			}
+
+		case blockComment:
+			if c == '*' && i+1 < len(line) && line[i+1] == '/' {
+				s = code
+				i++
+			}
+
+		case stringLiteral:
+			if c == '\'' {
+				if i+1 < len(line) && line[i+1] == '\'' {
+					i++
+					continue
+				}
+				s = code
+			}
+
+		case quotedIdentifier:
+			if c == ']' {
+				if i+1 < len(line) && line[i+1] == ']' {
+					i++
+					continue
+				}
+				s = code
+			}
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,182 @@
+package sqlserver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Dialect is the darwin.Dialect used by the SQL Server driver. The history
+// table is created in Schema, "dbo" when empty.
+type Dialect struct {
+	Schema string
+
+	// Table is the name of the history table, darwin_migrations by
+	// default.
+	Table string
+}
+
+// table returns the quoted schema qualified name of the history table.
+func (d Dialect) table() string {
+	table := d.Table
+	if table == "" {
+		table = "darwin_migrations"
+	}
+	return quoteIdentifier(d.schema()) + "." + quoteIdentifier(table)
+}
+
+// WithTable returns the dialect using the table name, whose schema, if
+// qualified, replaces Schema.
+func (d Dialect) WithTable(name string) darwin.Dialect {
+	if i := strings.Index(name, "."); i >= 0 {
+		d.Schema, name = name[:i], name[i+1:]
+	}
+	d.Table = name
+	return d
+}
+
+func (d Dialect) schema() string {
+	if d.Schema == "" {
+		return "dbo"
+	}
+	return d.Schema
+}
+
+// CreateTableSQL returns the SQL to create the schema and the schema table.
+func (d Dialect) CreateTableSQL() string {
+	return fmt.Sprintf(`IF SCHEMA_ID(%[1]s) IS NULL
+                EXEC('CREATE SCHEMA %[2]s');
+            IF OBJECT_ID(%[3]s, N'U') IS NULL
+                CREATE TABLE %[4]s
+                (
+                    id             INT           IDENTITY(1,1) NOT NULL,
+                    version        FLOAT(53)     NOT NULL,
+                    description    NVARCHAR(255) NOT NULL,
+                    checksum       CHAR(32)      NOT NULL,
+                    applied_at     BIGINT        NOT NULL,
+                    execution_time BIGINT        NOT NULL,
+                    applied_by     NVARCHAR(255),
+                    hostname       NVARCHAR(255),
+                    app_version    NVARCHAR(255),
+                    source         NVARCHAR(255),
+                    script         NVARCHAR(MAX),
+                    UNIQUE         (version),
+                    PRIMARY KEY    (id)
+                );`,
+		quoteString(d.schema()),
+		strings.ReplaceAll(quoteIdentifier(d.schema()), "'", "''"),
+		quoteString(d.table()),
+		d.table(),
+	)
+}
+
+// InsertSQL returns the SQL to insert a new migration in the schema table.
+func (d Dialect) InsertSQL() string {
+	return fmt.Sprintf(`INSERT INTO %s
+                (
+                    version,
+                    description,
+                    checksum,
+                    applied_at,
+                    execution_time
+                )
+            VALUES (@p1, @p2, @p3, @p4, @p5);`, d.table())
+}
+
+// AllSQL returns a SQL to get all entries in the table.
+func (d Dialect) AllSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time
+            FROM
+                %s
+            ORDER BY version ASC;`, d.table())
+}
+
+// DeleteSQL returns the SQL to delete the record of a migration.
+func (d Dialect) DeleteSQL() string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE version = @p1;`, d.table())
+}
+
+// standard returns the darwin.StandardDialect of the history table, which
+// writes the SQL of the metadata columns.
+func (d Dialect) standard() darwin.StandardDialect {
+	return darwin.StandardDialect{
+		Table:       d.table(),
+		Placeholder: darwin.PlaceholderAtP,
+		StringType:  "NVARCHAR(%d)",
+		TextType:    "NVARCHAR(MAX)",
+	}
+}
+
+// InsertMetadataSQL returns the SQL to insert a new migration in the schema
+// table with its metadata.
+func (d Dialect) InsertMetadataSQL() string {
+	return d.standard().InsertMetadataSQL()
+}
+
+// AllMetadataSQL returns a SQL to get all entries in the table with their
+// metadata.
+func (d Dialect) AllMetadataSQL() string {
+	return d.standard().AllMetadataSQL()
+}
+
+// ScriptSQL returns the SQL to get the script stored with a migration.
+func (d Dialect) ScriptSQL() string {
+	return d.standard().ScriptSQL()
+}
+
+// AllSinceSQL returns a SQL to get the entries in the table from a version
+// on, with their metadata.
+func (d Dialect) AllSinceSQL() string {
+	return d.standard().AllSinceSQL()
+}
+
+// LatestVersionSQL returns a SQL to get the greatest version in the table,
+// NULL when it is empty.
+func (d Dialect) LatestVersionSQL() string {
+	return d.standard().LatestVersionSQL()
+}
+
+// HistoryUpgrades returns the upgrades adding the metadata columns to the
+// history tables created without them.
+func (d Dialect) HistoryUpgrades() []darwin.HistoryUpgrade {
+	return d.standard().HistoryUpgrades()
+}
+
+// defaults are the default values of the SET options supported by the set
+// directive.
+var defaults = map[string]string{
+	"DEADLOCK_PRIORITY":         "NORMAL",
+	"LOCK_TIMEOUT":              "-1",
+	"NOCOUNT":                   "OFF",
+	"QUERY_GOVERNOR_COST_LIMIT": "0",
+	"ROWCOUNT":                  "0",
+	"XACT_ABORT":                "OFF",
+}
+
+// SetSQL returns the SQL to change a SET option of the session.
+func (Dialect) SetSQL(name, value string) string {
+	return fmt.Sprintf("SET %s %s", strings.ToUpper(name), value)
+}
+
+// ResetSQL returns the SQL to restore the default of a SET option of the
+// session.
+func (Dialect) ResetSQL(name string) string {
+	name = strings.ToUpper(name)
+	return fmt.Sprintf("SET %s %s", name, defaults[name])
+}
+
+// quoteIdentifier quotes an identifier with brackets.
+func quoteIdentifier(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+// quoteString quotes s as a unicode string literal.
+func quoteString(s string) string {
+	return "N'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
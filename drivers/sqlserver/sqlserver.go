@@ -0,0 +1,298 @@
+// Package sqlserver provides a darwin.Driver for Microsoft SQL Server.
+//
+// Scripts are split into batches on GO separator lines, as sqlcmd does, and
+// all batches of a migration are executed and recorded in a single
+// transaction, unless the migration has the no-transaction directive.
+// Statements like CREATE PROCEDURE, which must be the first statement of a
+// batch, can be written in migrations as usual.
+//
+// The set directive applies the SET options with a known default, like
+// LOCK_TIMEOUT and DEADLOCK_PRIORITY, restored once the migration is
+// executed:
+//
+//	-- darwin:set LOCK_TIMEOUT = 5000
+//
+// The driver doesn't import a SQL Server database/sql driver, use it with
+// github.com/microsoft/go-mssqldb or any compatible driver.
+package sqlserver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+	"github.com/dustinevan/darwin/drivers/internal/dbutil"
+)
+
+// Default values used by New.
+const (
+	DefaultLockName    = "darwin_migrations"
+	DefaultLockTimeout = time.Minute
+)
+
+// ErrLockTimeout is returned by Lock when the lock is held by another
+// session for longer than the lock timeout.
+var ErrLockTimeout = errors.New("sqlserver: timeout waiting for the migration lock")
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithSchema sets the schema of the history table, it is created when
+// missing.
+func WithSchema(schema string) Option {
+	return func(d *Driver) {
+		dialect, _ := d.Dialect.(Dialect)
+		dialect.Schema = schema
+		d.Dialect = dialect
+	}
+}
+
+// WithLockName sets the resource name used with sp_getapplock, drivers
+// sharing a lock name never migrate concurrently.
+func WithLockName(name string) Option {
+	return func(d *Driver) {
+		d.lockName = name
+	}
+}
+
+// WithLockTimeout sets how long Lock waits for the lock. A negative timeout
+// waits forever.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.lockTimeout = timeout
+	}
+}
+
+// Driver is a darwin.Driver for SQL Server.
+type Driver struct {
+	*darwin.GenericDriver
+
+	lockName    string
+	lockTimeout time.Duration
+	lockConn    *sql.Conn
+}
+
+// New creates a new Driver for the SQL Server database db.
+func New(db *sql.DB, opts ...Option) (*Driver, error) {
+	generic, err := darwin.NewGenericDriver(db, Dialect{})
+	if err != nil {
+		return nil, err
+	}
+
+	d := Driver{
+		GenericDriver: generic,
+		lockName:      DefaultLockName,
+		lockTimeout:   DefaultLockTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep the history in the table name,
+// qualified by its schema or not. The lock takes the name of the table too,
+// unless WithLockName set another one.
+func (d *Driver) SetTableName(name string) error {
+	if err := d.GenericDriver.SetTableName(name); err != nil {
+		return err
+	}
+
+	if d.lockName == DefaultLockName {
+		d.lockName = name
+	}
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	generic, err := d.GenericDriver.WithTable(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *d
+	c.GenericDriver = generic
+	return &c, nil
+}
+
+// Exec executes the batches of the script in a transaction, or outside of
+// any with the no-transaction directive. The settings of the set directives
+// of the script apply to the session executing it.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	return d.ExecContext(context.Background(), script)
+}
+
+// ExecContext is Exec with a context.
+func (d *Driver) ExecContext(ctx context.Context, script string) (time.Duration, error) {
+	start := time.Now()
+
+	conn, err := d.DB.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	if dbutil.NoTransaction(script) {
+		err := withSettings(ctx, conn, script, func() error {
+			return execBatches(ctx, conn, script)
+		})
+		return time.Since(start), err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	err = withSettings(ctx, tx, script, func() error {
+		return execBatches(ctx, tx, script)
+	})
+	if err != nil {
+		tx.Rollback()
+		return time.Since(start), err
+	}
+
+	return time.Since(start), tx.Commit()
+}
+
+// BeginTx starts a transaction executing the batches of migrations and
+// recording them.
+func (d *Driver) BeginTx(ctx context.Context) (darwin.Tx, error) {
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{tx: tx, dialect: d.Dialect}, nil
+}
+
+// transaction is the darwin.Tx of the driver.
+type transaction struct {
+	tx      *sql.Tx
+	dialect darwin.Dialect
+}
+
+// Exec executes the batches of the script, with the settings of its set
+// directives.
+func (t *transaction) Exec(ctx context.Context, script string) (time.Duration, error) {
+	start := time.Now()
+	err := withSettings(ctx, t.tx, script, func() error {
+		return execBatches(ctx, t.tx, script)
+	})
+	return time.Since(start), err
+}
+
+// Insert records the migration.
+func (t *transaction) Insert(ctx context.Context, e darwin.MigrationRecord) error {
+	query, args := darwin.InsertArgs(t.dialect, e)
+	_, err := t.tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+// Delete deletes the record of the migration version.
+func (t *transaction) Delete(ctx context.Context, version float64) error {
+	dd, ok := t.dialect.(darwin.DeleteDialect)
+	if !ok {
+		return errors.New("sqlserver: the dialect can't delete migration records")
+	}
+
+	_, err := t.tx.ExecContext(ctx, dd.DeleteSQL(), version)
+	return err
+}
+
+func (t *transaction) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *transaction) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// execBatches executes the batches of the script, each as many times as its
+// count.
+func execBatches(ctx context.Context, e dbutil.Execer, script string) error {
+	for i, batch := range SplitBatches(script) {
+		for n := 0; n < batch.Count; n++ {
+			if _, err := e.ExecContext(ctx, batch.SQL); err != nil {
+				return fmt.Errorf("sqlserver: batch %d: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// withSettings calls f with the settings of the set directives of the script
+// applied to the session of e, and restores their defaults after.
+func withSettings(ctx context.Context, e dbutil.Execer, script string, f func() error) error {
+	settings, err := darwin.Settings(script)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range settings {
+		if _, ok := defaults[strings.ToUpper(s.Name)]; !ok {
+			return fmt.Errorf("sqlserver: unsupported setting %s", s.Name)
+		}
+	}
+
+	return dbutil.WithSettings(ctx, e, Dialect{}, script, f)
+}
+
+// Lock acquires an exclusive application lock with sp_getapplock. The lock
+// is owned by a dedicated session which is kept until Unlock is called.
+func (d *Driver) Lock() error {
+	ctx := context.Background()
+
+	conn, err := d.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	timeout := int64(-1)
+	if d.lockTimeout >= 0 {
+		timeout = int64(d.lockTimeout / time.Millisecond)
+	}
+
+	const getLock = `DECLARE @result INT;
+            EXEC @result = sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = @p2;
+            SELECT @result;`
+
+	var result int
+	if err := conn.QueryRowContext(ctx, getLock, d.lockName, timeout).Scan(&result); err != nil {
+		conn.Close()
+		return err
+	}
+
+	switch {
+	case result >= 0:
+		d.lockConn = conn
+		return nil
+	case result == -1:
+		conn.Close()
+		return ErrLockTimeout
+	default:
+		conn.Close()
+		return fmt.Errorf("sqlserver: sp_getapplock failed with code %d", result)
+	}
+}
+
+// Unlock releases the lock acquired by Lock.
+func (d *Driver) Unlock() error {
+	if d.lockConn == nil {
+		return errors.New("sqlserver: lock is not held")
+	}
+
+	conn := d.lockConn
+	d.lockConn = nil
+	defer conn.Close()
+
+	_, err := conn.ExecContext(context.Background(), "EXEC sp_releaseapplock @Resource = @p1, @LockOwner = 'Session';", d.lockName)
+	return err
+}
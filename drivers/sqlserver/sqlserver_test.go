@@ -0,0 +1,210 @@
+package sqlserver
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dustinevan/darwin"
+)
+
+func Test_SplitBatches(t *testing.T) {
+	script := `CREATE TABLE a (id INT);
+GO
+CREATE PROCEDURE p AS
+BEGIN
+	/* a comment
+GO
+	*/
+	SELECT 'a string
+GO
+';
+END
+go -- end of procedure
+INSERT INTO a VALUES (1);
+GO 3
+GO
+`
+
+	expected := []Batch{
+		{SQL: "CREATE TABLE a (id INT);", Count: 1},
+		{SQL: "CREATE PROCEDURE p AS\nBEGIN\n\t/* a comment\nGO\n\t*/\n\tSELECT 'a string\nGO\n';\nEND", Count: 1},
+		{SQL: "INSERT INTO a VALUES (1);", Count: 3},
+	}
+
+	if got := SplitBatches(script); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, got)
+	}
+}
+
+func Test_Dialect_schema(t *testing.T) {
+	d := Dialect{Schema: "ops"}
+
+	if !strings.Contains(d.CreateTableSQL(), "CREATE TABLE [ops].[darwin_migrations]") {
+		t.Errorf("Must create the table in the schema, got %s", d.CreateTableSQL())
+	}
+
+	if !strings.Contains(d.AllSQL(), "[ops].[darwin_migrations]") {
+		t.Errorf("Must read the table in the schema, got %s", d.AllSQL())
+	}
+}
+
+func Test_Dialect_WithTable(t *testing.T) {
+	d := Dialect{Schema: "ops"}.WithTable("schema_migrations")
+	if sql := d.AllSQL(); !strings.Contains(sql, "[ops].[schema_migrations]") {
+		t.Errorf("Must keep the schema, got %s", sql)
+	}
+
+	d = d.(Dialect).WithTable("app.history")
+	if sql := d.InsertSQL(); !strings.Contains(sql, "[app].[history]") {
+		t.Errorf("Must use the schema of the name, got %s", sql)
+	}
+}
+
+func Test_Driver_Exec(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE a (id INT);")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE VIEW v AS SELECT id FROM a;")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	if _, err := d.Exec("CREATE TABLE a (id INT);\nGO\nCREATE VIEW v AS SELECT id FROM a;\n"); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_Exec_no_transaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("SET LOCK_TIMEOUT 5000")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("ALTER DATABASE CURRENT SET READ_COMMITTED_SNAPSHOT ON;")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("SET LOCK_TIMEOUT -1")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	script := "-- darwin:no-transaction\n-- darwin:set lock_timeout = 5000\nALTER DATABASE CURRENT SET READ_COMMITTED_SNAPSHOT ON;\n"
+	if _, err := d.Exec(script); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if _, err := d.Exec("-- darwin:set ANSI_NULLS = OFF\nSELECT 1;"); err == nil {
+		t.Errorf("Must refuse a setting without a known default")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_BeginTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE a (id INT);")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO \\[dbo\\]\\.\\[darwin_migrations\\]").
+		WithArgs(1.0, "A", "abc", int64(0), time.Second, nil, nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM \\[dbo\\]\\.\\[darwin_migrations\\] WHERE version = @p1").
+		WithArgs(0.5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tx, err := d.BeginTx(context.Background())
+	if err != nil {
+		t.Fatalf("Must begin a transaction, got %s", err)
+	}
+
+	if _, err := tx.Exec(context.Background(), "CREATE TABLE a (id INT);"); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	record := darwin.MigrationRecord{Version: 1, Description: "A", Checksum: "abc", AppliedAt: time.Unix(0, 0), ExecutionTime: time.Second}
+	if err := tx.Insert(context.Background(), record); err != nil {
+		t.Fatalf("Must record the migration, got %s", err)
+	}
+
+	if err := tx.(darwin.TxDeleter).Delete(context.Background(), 0.5); err != nil {
+		t.Fatalf("Must delete the record, got %s", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Must commit, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_Lock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectQuery("sp_getapplock").
+		WithArgs(DefaultLockName, int64(60000)).
+		WillReturnRows(sqlmock.NewRows([]string{"result"}).AddRow(-1))
+
+	if err := d.Lock(); err != ErrLockTimeout {
+		t.Errorf("Expected ErrLockTimeout, got %v", err)
+	}
+
+	mock.ExpectQuery("sp_getapplock").
+		WillReturnRows(sqlmock.NewRows([]string{"result"}).AddRow(0))
+	mock.ExpectExec("sp_releaseapplock").
+		WithArgs(DefaultLockName).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := d.Lock(); err != nil {
+		t.Fatalf("Must acquire the lock, got %s", err)
+	}
+
+	if err := d.Unlock(); err != nil {
+		t.Fatalf("Must release the lock, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
@@ -0,0 +1,130 @@
+// Package sqlx builds a darwin.Driver from an *sqlx.DB, so services already
+// holding an sqlx handle migrate with its connection pool and configuration.
+//
+// The dialect is looked up in the darwin dialect registry by the name of the
+// database/sql driver the handle was opened with, common aliases like pgx or
+// sqlite are mapped to the registered dialects. Other databases are supported
+// with WithDialect, a darwin.StandardDialect is usually enough.
+//
+// The package doesn't import github.com/jmoiron/sqlx, it accepts any handle
+// embedding an *sql.DB and reporting its driver name like *sqlx.DB does.
+package sqlx
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/dustinevan/darwin"
+)
+
+// aliases maps the names of common database/sql drivers to the dialects
+// registered in darwin.
+var aliases = map[string]string{
+	"pgx":              "postgres",
+	"pgx/v5":           "postgres",
+	"pq-timeouts":      "postgres",
+	"cloudsqlpostgres": "postgres",
+	"nrpostgres":       "postgres",
+	"cockroach":        "postgres",
+	"nrmysql":          "mysql",
+	"sqlite":           "sqlite3",
+	"nrsqlite3":        "sqlite3",
+}
+
+// DB is the part of *sqlx.DB used by the adapter. The handle must also embed
+// the *sql.DB it wraps as its DB field.
+type DB interface {
+	DriverName() string
+}
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithDialect sets the dialect instead of looking it up by driver name.
+func WithDialect(dialect darwin.Dialect) Option {
+	return func(d *Driver) {
+		d.Dialect = dialect
+	}
+}
+
+// Driver is a darwin.Driver for the database of an sqlx handle.
+type Driver struct {
+	*darwin.GenericDriver
+}
+
+// New creates a new Driver for the database of the sqlx handle db.
+func New(db DB, opts ...Option) (*Driver, error) {
+	if db == nil {
+		return nil, errors.New("sqlx: db is nil")
+	}
+
+	sqlDB, ok := unwrap(db)
+	if !ok {
+		return nil, fmt.Errorf("sqlx: %T doesn't embed an *sql.DB", db)
+	}
+
+	d := Driver{GenericDriver: &darwin.GenericDriver{DB: sqlDB}}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	if d.Dialect == nil {
+		name := db.DriverName()
+		if alias, ok := aliases[name]; ok {
+			name = alias
+		}
+
+		dialect, ok := darwin.LookupDialect(name)
+		if !ok {
+			return nil, fmt.Errorf("sqlx: no dialect registered for driver %q, use WithDialect", db.DriverName())
+		}
+		d.Dialect = dialect
+	}
+
+	generic, err := darwin.NewGenericDriver(sqlDB, d.Dialect)
+	if err != nil {
+		return nil, err
+	}
+	d.GenericDriver = generic
+
+	return &d, nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	generic, err := d.GenericDriver.WithTable(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *d
+	c.GenericDriver = generic
+	return &c, nil
+}
+
+// unwrap returns the *sql.DB embedded in the handle db.
+func unwrap(db DB) (*sql.DB, bool) {
+	v := reflect.ValueOf(db)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	f := v.FieldByName("DB")
+	if !f.IsValid() || !f.CanInterface() {
+		return nil, false
+	}
+
+	sqlDB, ok := f.Interface().(*sql.DB)
+	return sqlDB, ok && sqlDB != nil
+}
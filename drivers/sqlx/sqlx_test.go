@@ -0,0 +1,77 @@
+package sqlx
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dustinevan/darwin"
+)
+
+// handle has the shape of *sqlx.DB.
+type handle struct {
+	*sql.DB
+	driverName string
+}
+
+func (h *handle) DriverName() string { return h.driverName }
+
+func Test_New_alias(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(&handle{DB: db, driverName: "pgx"})
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	if _, ok := d.Dialect.(darwin.PostgresDialect); !ok {
+		t.Errorf("Expected PostgresDialect, got %T", d.Dialect)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(darwin.PostgresDialect{}.CreateTableSQL())).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	upgrades := darwin.PostgresDialect{}.HistoryUpgrades()
+	mock.ExpectQuery(regexp.QuoteMeta(upgrades[len(upgrades)-1].Probe)).WillReturnRows(sqlmock.NewRows([]string{"script"}))
+
+	if err := d.Create(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_New_unknown_driver(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	if _, err := New(&handle{DB: db, driverName: "godror"}); err == nil {
+		t.Errorf("Must return an error for unknown drivers")
+	}
+
+	dialect := darwin.StandardDialect{Placeholder: darwin.PlaceholderColon}
+	d, err := New(&handle{DB: db, driverName: "godror"}, WithDialect(dialect))
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if d.Dialect != dialect {
+		t.Errorf("Must use the dialect of WithDialect, got %#v", d.Dialect)
+	}
+}
+
+func Test_New_no_sql_DB(t *testing.T) {
+	if _, err := New(&handle{driverName: "postgres"}); err == nil {
+		t.Errorf("Must return an error when the handle has no *sql.DB")
+	}
+}
@@ -0,0 +1,313 @@
+// Package tidb provides a darwin.Driver for TiDB, based on the MySQL driver.
+//
+// TiDB runs schema changes as online DDL jobs. After each DDL statement the
+// driver waits for the DDL jobs of the database to be synced, so following
+// statements and the application see the new schema everywhere. Statements
+// are checkpointed like with the MySQL driver, so a failed migration resumes
+// where it stopped. GET_LOCK is only implemented since TiDB 5.3, older
+// versions are locked with a row in the darwin_locks table instead.
+//
+// A single transaction can't exceed the txn-total-size-limit of the cluster.
+// WithBatchDML turns UPDATE and DELETE statements into non-transactional
+// BATCH statements so backfills touching millions of rows don't fail.
+package tidb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+	"github.com/dustinevan/darwin/drivers/internal/dbutil"
+	"github.com/dustinevan/darwin/drivers/mysql"
+)
+
+// Default values used by New.
+const (
+	DefaultLockName   = mysql.DefaultLockName
+	DefaultDDLTimeout = time.Hour
+)
+
+// transactionTooLarge is the error code reported by TiDB when a transaction
+// exceeds the txn-total-size-limit.
+const transactionTooLarge = "8004"
+
+var (
+	// ddlStatement matches the statements creating DDL jobs.
+	ddlStatement = regexp.MustCompile(`(?is)^(\s*(--[^\n]*\n|#[^\n]*\n|/\*.*?\*/))*\s*(CREATE|ALTER|DROP|TRUNCATE|RENAME)\s`)
+
+	// dmlStatement matches the statements which can be batched.
+	dmlStatement = regexp.MustCompile(`(?is)^(\s*(--[^\n]*\n|#[^\n]*\n|/\*.*?\*/))*\s*(UPDATE|DELETE)\s`)
+
+	// tidbVersion extracts the TiDB version from the result of VERSION().
+	tidbVersion = regexp.MustCompile(`TiDB-v(\d+)\.(\d+)`)
+)
+
+// TransactionTooLargeError is used to report a statement exceeding the
+// transaction size limit of the cluster.
+type TransactionTooLargeError struct {
+	Statement int
+	Err       error
+}
+
+func (t TransactionTooLargeError) Error() string {
+	return fmt.Sprintf("tidb: statement %d exceeds the transaction size limit, split it or use WithBatchDML: %s", t.Statement+1, t.Err)
+}
+
+// Unwrap returns the underlying error.
+func (t TransactionTooLargeError) Unwrap() error {
+	return t.Err
+}
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithBatchDML rewrites UPDATE and DELETE statements as non-transactional
+// BATCH statements of size rows. Only supported since TiDB 6.5.
+func WithBatchDML(size int) Option {
+	return func(d *Driver) {
+		d.batchSize = size
+	}
+}
+
+// WithDDLTimeout sets how long the driver waits for DDL jobs to be synced.
+func WithDDLTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.ddlTimeout = timeout
+	}
+}
+
+// WithLockExpiry makes Lock take over a darwin_locks lock which wasn't
+// refreshed for expiry, left by an applier which crashed. Use it with darwin.WithHeartbeat
+// and an interval well under expiry, so a long migration keeps its lock.
+func WithLockExpiry(expiry time.Duration) Option {
+	return func(d *Driver) {
+		d.lockExpiry = expiry
+	}
+}
+
+// WithMySQLOptions sets the options of the underlying MySQL driver.
+func WithMySQLOptions(opts ...mysql.Option) Option {
+	return func(d *Driver) {
+		d.mysqlOptions = opts
+	}
+}
+
+// Driver is a darwin.Driver for TiDB.
+type Driver struct {
+	*mysql.Driver
+
+	db           *sql.DB
+	batchSize    int
+	ddlTimeout   time.Duration
+	mysqlOptions []mysql.Option
+	tableLock    *dbutil.TableLock
+	lockExpiry   time.Duration
+	lockName     string
+}
+
+// New creates a new Driver for the TiDB database db.
+func New(db *sql.DB, opts ...Option) (*Driver, error) {
+	d := Driver{
+		db:         db,
+		ddlTimeout: DefaultDDLTimeout,
+		lockName:   DefaultLockName,
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	hooks := mysql.WithStatementHooks(mysql.StatementHooks{Rewrite: d.batch, After: d.waitForDDL})
+	driver, err := mysql.New(db, append(append([]mysql.Option{}, d.mysqlOptions...), hooks)...)
+	if err != nil {
+		return nil, err
+	}
+	d.Driver = driver
+
+	return &d, nil
+}
+
+// Exec executes the statements of the script one at a time, waiting for the
+// DDL jobs to be synced after each DDL statement.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	return d.ExecContext(context.Background(), script)
+}
+
+// ExecContext is Exec with a context.
+func (d *Driver) ExecContext(ctx context.Context, script string) (time.Duration, error) {
+	start := time.Now()
+	_, err := d.ExecStatements(ctx, script)
+	return time.Since(start), err
+}
+
+// ExecStatements is ExecContext reporting the rows affected by each
+// statement executed and how long it took, including the wait for its DDL
+// jobs. The statements run on one session like with the MySQL driver, with
+// its settings and checkpoints.
+func (d *Driver) ExecStatements(ctx context.Context, script string) ([]darwin.StatementResult, error) {
+	results, err := d.Driver.ExecStatements(ctx, script)
+
+	var p mysql.PartialMigrationError
+	if errors.As(err, &p) && strings.Contains(p.Err.Error(), transactionTooLarge) {
+		p.Err = TransactionTooLargeError{Statement: p.Statement, Err: p.Err}
+		err = p
+	}
+
+	return results, err
+}
+
+// batch rewrites UPDATE and DELETE statements as BATCH statements, with
+// WithBatchDML.
+func (d *Driver) batch(stmt string) string {
+	if d.batchSize > 0 && dmlStatement.MatchString(stmt) && !strings.HasPrefix(strings.ToUpper(stmt), "BATCH") {
+		return fmt.Sprintf("BATCH LIMIT %d %s", d.batchSize, stmt)
+	}
+	return stmt
+}
+
+// waitForDDL waits until no DDL job of the database of the session is
+// running, after a DDL statement.
+func (d *Driver) waitForDDL(ctx context.Context, conn *sql.Conn, stmt string) error {
+	const running = `SELECT COUNT(*)
+            FROM information_schema.ddl_jobs
+            WHERE db_name = DATABASE() AND state NOT IN ('synced', 'cancelled', 'rollback done')`
+
+	if !ddlStatement.MatchString(stmt) {
+		return nil
+	}
+
+	deadline := time.Now().Add(d.ddlTimeout)
+	for {
+		var count int
+		if err := conn.QueryRowContext(ctx, running).Scan(&count); err != nil {
+			return err
+		}
+
+		if count == 0 {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("tidb: DDL jobs still running after %s", d.ddlTimeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// Lock acquires the migration lock with GET_LOCK, or with a row in the
+// darwin_locks table on TiDB versions without a real GET_LOCK.
+func (d *Driver) Lock() error {
+	supported, err := d.supportsGetLock()
+	if err != nil {
+		return err
+	}
+
+	if supported {
+		return d.Driver.Lock()
+	}
+
+	d.tableLock = d.newTableLock()
+	return d.tableLock.Lock()
+}
+
+// Unlock releases the lock acquired by Lock.
+func (d *Driver) Unlock() error {
+	if d.tableLock != nil {
+		l := d.tableLock
+		d.tableLock = nil
+		return l.Unlock()
+	}
+
+	return d.Driver.Unlock()
+}
+
+// RefreshLock keeps the darwin_locks lock from expiring, it is called by
+// the heartbeat of darwin.WithHeartbeat. Locks taken with GET_LOCK don't
+// expire.
+func (d *Driver) RefreshLock() error {
+	if d.tableLock == nil {
+		return nil
+	}
+	return d.tableLock.Refresh()
+}
+
+// LockHolder returns the holder of the darwin_locks lock, if it is held.
+// Locks taken with GET_LOCK are released with the session of their holder
+// and aren't reported.
+func (d *Driver) LockHolder() (darwin.LockHolder, bool, error) {
+	return d.newTableLock().Holder()
+}
+
+// BreakLock releases the darwin_locks lock whoever holds it, to recover from
+// an applier which crashed.
+func (d *Driver) BreakLock() error {
+	return d.newTableLock().Break()
+}
+
+// SetTableName makes the driver keep the history in the table name, the
+// name of its lock in the darwin_locks table too.
+func (d *Driver) SetTableName(name string) error {
+	if err := d.Driver.SetTableName(name); err != nil {
+		return err
+	}
+
+	d.lockName = name
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	driver, err := d.Driver.CloneTable(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *d
+	c.Driver = driver.(*mysql.Driver)
+	c.tableLock = nil
+	return &c, nil
+}
+
+// newTableLock returns the lock used when GET_LOCK isn't supported.
+func (d *Driver) newTableLock() *dbutil.TableLock {
+	return &dbutil.TableLock{
+		DB:          d.db,
+		Table:       "darwin_locks",
+		Name:        d.lockName,
+		Placeholder: dbutil.Question,
+		Timeout:     mysql.DefaultLockTimeout,
+		Poll:        time.Second,
+		Expiry:      d.lockExpiry,
+	}
+}
+
+// supportsGetLock reports if the server implements GET_LOCK, TiDB before 5.3
+// only had a noop implementation.
+func (d *Driver) supportsGetLock() (bool, error) {
+	var version string
+	if err := d.db.QueryRow("SELECT VERSION()").Scan(&version); err != nil {
+		return false, err
+	}
+
+	m := tidbVersion.FindStringSubmatch(version)
+	if m == nil {
+		return false, errors.New("tidb: server is not TiDB: " + version)
+	}
+
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+
+	return major > 5 || (major == 5 && minor >= 3), nil
+}
@@ -0,0 +1,124 @@
+package tidb
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dustinevan/darwin/drivers/mysql"
+)
+
+func Test_Driver_Exec(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db, WithBatchDML(1000))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(mysql.Dialect{}.CheckpointsSQL())).
+		WillReturnRows(sqlmock.NewRows([]string{"statement", "checksum"}))
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE users ADD INDEX (email)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(mysql.Dialect{}.InsertCheckpointSQL())).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("information_schema.ddl_jobs").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(regexp.QuoteMeta("BATCH LIMIT 1000 UPDATE users SET active = 1")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(mysql.Dialect{}.InsertCheckpointSQL())).WillReturnResult(sqlmock.NewResult(2, 1))
+
+	if _, err := d.Exec("ALTER TABLE users ADD INDEX (email);\nUPDATE users SET active = 1;"); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_Lock_fallback(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT VERSION()")).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("5.7.25-TiDB-v5.2.1"))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS darwin_locks").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO darwin_locks").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM darwin_locks").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := d.Lock(); err != nil {
+		t.Fatalf("Must acquire the lock, got %s", err)
+	}
+
+	if err := d.Unlock(); err != nil {
+		t.Fatalf("Must release the lock, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_Lock_get_lock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT VERSION()")).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("8.0.11-TiDB-v7.5.0"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT GET_LOCK(?, ?)")).WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+
+	if err := d.Lock(); err != nil {
+		t.Fatalf("Must acquire the lock, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_ExecStatements_ddl_canceled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(mysql.Dialect{}.CheckpointsSQL())).
+		WillReturnRows(sqlmock.NewRows([]string{"statement", "checksum"}))
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE users ADD INDEX (email)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(mysql.Dialect{}.InsertCheckpointSQL())).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("information_schema.ddl_jobs").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = d.ExecStatements(ctx, "ALTER TABLE users ADD INDEX (email);")
+
+	var p mysql.PartialMigrationError
+	if !errors.As(err, &p) || p.Applied != 1 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Must stop waiting for the DDL jobs with the context, got %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package trino
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Types of the Trino client protocol used by the driver, see
+// https://trino.io/docs/current/develop/client-protocol.html.
+
+type queryResults struct {
+	ID      string          `json:"id"`
+	NextURI string          `json:"nextUri"`
+	Data    [][]interface{} `json:"data"`
+	Stats   struct {
+		State string `json:"state"`
+	} `json:"stats"`
+	Error *QueryError `json:"error"`
+}
+
+// QueryError is used to report a query which failed.
+type QueryError struct {
+	Message   string `json:"message"`
+	ErrorCode int    `json:"errorCode"`
+	ErrorName string `json:"errorName"`
+	ErrorType string `json:"errorType"`
+}
+
+func (q QueryError) Error() string {
+	return fmt.Sprintf("trino: %s: %s", q.ErrorName, q.Message)
+}
+
+// query runs a statement, follows the nextUri links until it completes
+// and returns the rows it produced.
+func (d *Driver) query(ctx context.Context, statement string) ([][]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.endpoint+"/v1/statement", strings.NewReader(statement))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(d.header("User"), d.user)
+	req.Header.Set(d.header("Source"), d.source)
+	req.Header.Set(d.header("Catalog"), d.catalog)
+	req.Header.Set(d.header("Schema"), d.schema)
+
+	var rows [][]interface{}
+	for {
+		var results queryResults
+		if err := d.do(req, &results); err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, results.Data...)
+
+		if results.Error != nil {
+			return nil, *results.Error
+		}
+
+		if results.NextURI == "" {
+			return rows, nil
+		}
+
+		// The server asks to wait when the next results aren't ready.
+		if len(results.Data) == 0 && results.Stats.State == "QUEUED" {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(d.poll):
+			}
+		}
+
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, results.NextURI, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(d.header("User"), d.user)
+	}
+}
+
+// do sends a request and decodes the response in v, retrying when the
+// server is overloaded as the protocol requires.
+func (d *Driver) do(req *http.Request, v interface{}) error {
+	for {
+		resp, err := d.client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusServiceUnavailable ||
+			resp.StatusCode == http.StatusBadGateway ||
+			resp.StatusCode == http.StatusGatewayTimeout:
+			select {
+			case <-req.Context().Done():
+				return req.Context().Err()
+			case <-time.After(d.poll):
+			}
+
+			if req.GetBody != nil {
+				if req.Body, err = req.GetBody(); err != nil {
+					return err
+				}
+			}
+			continue
+
+		case resp.StatusCode >= 300:
+			return fmt.Errorf("trino: %s %s: %d %s", req.Method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+
+		return json.Unmarshal(body, v)
+	}
+}
+
+// header returns the name of a protocol header, Presto uses X-Presto-
+// instead of X-Trino-.
+func (d *Driver) header(name string) string {
+	if d.presto {
+		return "X-Presto-" + name
+	}
+	return "X-Trino-" + name
+}
@@ -0,0 +1,12 @@
+package trino
+
+import "github.com/dustinevan/darwin"
+
+// SplitStatements splits a script into its statements with the standard
+// syntax of darwin.SplitStatements. Semicolons in quoted strings, quoted
+// identifiers and comments don't end a statement. The statements are
+// returned without their trailing semicolon; statements holding only
+// comments are dropped, Trino rejecting empty queries.
+func SplitStatements(script string) []string {
+	return darwin.SplitStatements(script, darwin.StandardSyntax)
+}
@@ -0,0 +1,337 @@
+// Package trino provides a darwin.Driver for Trino and Presto, typically
+// migrating Iceberg or Hive tables.
+//
+// Trino has no transactions spanning several statements, so a migration
+// which fails halfway leaves its first statements applied. The driver
+// records every successful statement of a migration in the
+// darwin_migration_statements table, keyed by the version of the migration
+// and the index and checksum of the statement, with
+// darwin.ExecCheckpointed; when the migration is retried, the statements
+// already applied are skipped and the migration resumes where it stopped,
+// even if the failed statement was fixed. A statement which failed is run
+// again, so a statement having partial effects, like an INSERT into a Hive
+// table, should be written to be rerun safely. The statements of a
+// migration are deleted once it is recorded, so the history schema must
+// support DELETE, as Iceberg and Delta Lake tables do.
+//
+// The driver talks to the coordinator with the Trino client protocol, it
+// doesn't import a Trino client library. Authentication, when required, is
+// provided by the *http.Client.
+package trino
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithUser sets the user running the queries, "darwin" by default.
+func WithUser(user string) Option {
+	return func(d *Driver) {
+		d.user = user
+	}
+}
+
+// WithPresto makes the driver use the X-Presto- headers understood by
+// Presto instead of the X-Trino- headers.
+func WithPresto() Option {
+	return func(d *Driver) {
+		d.presto = true
+	}
+}
+
+// WithTimeout sets how long the driver waits for a query to complete.
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.timeout = timeout
+	}
+}
+
+// Driver is a darwin.Driver for Trino.
+type Driver struct {
+	client   *http.Client
+	endpoint string
+	catalog  string
+	schema   string
+	user     string
+	source   string
+	presto   bool
+	timeout  time.Duration
+	poll     time.Duration
+	history  string
+}
+
+// New creates a new Driver sending queries to the coordinator at endpoint,
+// like http://trino:8080, and storing the history in catalog.schema.
+func New(client *http.Client, endpoint string, catalog string, schema string, opts ...Option) (*Driver, error) {
+	if client == nil {
+		return nil, errors.New("trino: http client is nil")
+	}
+
+	if endpoint == "" || catalog == "" || schema == "" {
+		return nil, errors.New("trino: endpoint, catalog and schema are required")
+	}
+
+	d := Driver{
+		client:   client,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		catalog:  catalog,
+		schema:   schema,
+		user:     "darwin",
+		source:   "darwin",
+		history:  "darwin_migrations",
+		timeout:  time.Hour,
+		poll:     100 * time.Millisecond,
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep the history in the table name of
+// catalog.schema, which can't be qualified by another schema, and the
+// statements executed in the table of the name followed by _statements.
+func (d *Driver) SetTableName(name string) error {
+	if name == "" || strings.Contains(name, ".") {
+		return fmt.Errorf("trino: invalid table name %q, it must be a table of the schema", name)
+	}
+
+	d.history = name
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	c := *d
+	if err := c.SetTableName(name); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// statements returns the name of the table of the statements executed.
+func (d *Driver) statements() string {
+	if d.history == "darwin_migrations" {
+		return "darwin_migration_statements"
+	}
+	return d.history + "_statements"
+}
+
+// table returns the qualified name of a table of the driver.
+func (d *Driver) table(name string) string {
+	return fmt.Sprintf("%s.%s.%s", quoteIdentifier(d.catalog), quoteIdentifier(d.schema), quoteIdentifier(name))
+}
+
+// Create creates the history table, darwin_migrations by default, and the
+// table of the statements executed if necessary.
+func (d *Driver) Create() error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	_, err := d.query(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+                (
+                    version        DOUBLE  NOT NULL,
+                    description    VARCHAR NOT NULL,
+                    checksum       VARCHAR NOT NULL,
+                    applied_at     BIGINT  NOT NULL,
+                    execution_time BIGINT  NOT NULL
+                )`, d.table(d.history)))
+	if err != nil {
+		return err
+	}
+
+	_, err = d.query(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+                (
+                    version    DOUBLE  NOT NULL,
+                    statement  INTEGER NOT NULL,
+                    checksum   VARCHAR NOT NULL,
+                    applied_at BIGINT  NOT NULL
+                )`, d.table(d.statements())))
+	return err
+}
+
+// Insert inserts a migration entry into database, and deletes the
+// statements of the migration recorded while it was executed.
+func (d *Driver) Insert(e darwin.MigrationRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	_, err := d.query(ctx, fmt.Sprintf(`INSERT INTO %s
+                (version, description, checksum, applied_at, execution_time)
+            VALUES (%s, %s, %s, %d, %d)`,
+		d.table(d.history),
+		version(e.Version),
+		quoteString(e.Description),
+		quoteString(e.Checksum),
+		e.AppliedAt.Unix(),
+		int64(e.ExecutionTime),
+	))
+	if err != nil {
+		return err
+	}
+
+	_, err = d.query(ctx, fmt.Sprintf(`DELETE FROM %s WHERE version = %s`, d.table(d.statements()), version(e.Version)))
+	return err
+}
+
+// All returns all migrations applied.
+func (d *Driver) All() ([]darwin.MigrationRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	rows, err := d.query(ctx, fmt.Sprintf(`SELECT version, description, checksum, applied_at, execution_time
+            FROM %s
+            ORDER BY version ASC`, d.table(d.history)))
+	if err != nil {
+		return []darwin.MigrationRecord{}, err
+	}
+
+	var records []darwin.MigrationRecord
+	for _, row := range rows {
+		if len(row) != 5 {
+			return []darwin.MigrationRecord{}, fmt.Errorf("trino: unexpected row %v", row)
+		}
+
+		records = append(records, darwin.MigrationRecord{
+			Version:       number(row[0]),
+			Description:   str(row[1]),
+			Checksum:      str(row[2]),
+			AppliedAt:     time.Unix(int64(number(row[3])), 0),
+			ExecutionTime: time.Duration(number(row[4])),
+		})
+	}
+
+	return records, nil
+}
+
+// Exec executes the statements of the script one at a time.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	start := time.Now()
+	_, err := d.ExecStatements(context.Background(), script)
+	return time.Since(start), err
+}
+
+// ExecStatements executes the statements of the script one at a time. The
+// statements of the migration Migrate executes are recorded, and those
+// recorded by a previous attempt are skipped. The error of a failed
+// statement is a darwin.StatementFailure.
+func (d *Driver) ExecStatements(ctx context.Context, script string) ([]darwin.StatementResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	var results []darwin.StatementResult
+	exec := func(ctx context.Context, statement string) error {
+		darwin.ReportExecuting(ctx, statement)
+
+		start := time.Now()
+		if _, err := d.query(ctx, statement); err != nil {
+			return err
+		}
+
+		result := darwin.StatementResult{Statement: statement, Duration: time.Since(start)}
+		results = append(results, result)
+		darwin.ReportStatement(ctx, result)
+		return nil
+	}
+
+	var store darwin.CheckpointStore = noProgress{}
+	if m, ok := darwin.ExecutingMigration(ctx); ok {
+		store = progress{d, m.Version}
+	}
+
+	err := darwin.ExecCheckpointed(ctx, store, script, darwin.StandardSyntax, exec)
+	return results, err
+}
+
+// noProgress is the darwin.CheckpointStore of the scripts executed outside
+// of Migrate, whose statements aren't recorded.
+type noProgress struct{}
+
+func (noProgress) Checkpoints(context.Context) (map[darwin.Checkpoint]bool, error) {
+	return nil, nil
+}
+
+func (noProgress) SaveCheckpoint(context.Context, darwin.Checkpoint) error {
+	return nil
+}
+
+// progress is the darwin.CheckpointStore of the statements of a migration
+// version.
+type progress struct {
+	d       *Driver
+	version float64
+}
+
+// Checkpoints returns the statements of the migration applied by previous
+// attempts.
+func (p progress) Checkpoints(ctx context.Context) (map[darwin.Checkpoint]bool, error) {
+	rows, err := p.d.query(ctx, fmt.Sprintf(`SELECT statement, checksum FROM %s WHERE version = %s`,
+		p.d.table(p.d.statements()), version(p.version)))
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(map[darwin.Checkpoint]bool, len(rows))
+	for _, row := range rows {
+		if len(row) == 2 {
+			done[darwin.Checkpoint{Statement: int(number(row[0])), Checksum: str(row[1])}] = true
+		}
+	}
+
+	return done, nil
+}
+
+// SaveCheckpoint records the statement of the migration applied.
+func (p progress) SaveCheckpoint(ctx context.Context, cp darwin.Checkpoint) error {
+	_, err := p.d.query(ctx, fmt.Sprintf(`INSERT INTO %s (version, statement, checksum, applied_at)
+            VALUES (%s, %d, %s, %d)`, p.d.table(p.d.statements()), version(p.version), cp.Statement, quoteString(cp.Checksum), time.Now().Unix()))
+	return err
+}
+
+// version returns the migration version as a DOUBLE literal.
+func version(v float64) string {
+	return fmt.Sprintf("CAST(%s AS DOUBLE)", quoteString(strconv.FormatFloat(v, 'f', -1, 64)))
+}
+
+// quoteString returns s as a SQL string literal.
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// quoteIdentifier returns name as a quoted identifier.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// str returns a cell value as a string.
+func str(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+// number returns a cell value as a number, DOUBLE values like NaN are
+// returned as strings by the protocol.
+func number(v interface{}) float64 {
+	switch x := v.(type) {
+	case float64:
+		return x
+	case string:
+		f, _ := strconv.ParseFloat(x, 64)
+		return f
+	default:
+		return 0
+	}
+}
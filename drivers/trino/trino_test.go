@@ -0,0 +1,211 @@
+package trino
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// fakeCoordinator is a Trino coordinator recording the statements it runs
+// and the progress of the migrations.
+type fakeCoordinator struct {
+	mu         sync.Mutex
+	url        string
+	statements []string
+	progress   map[string][]string
+	results    map[string]string
+	fail       string
+	user       string
+}
+
+var (
+	progressInsert = regexp.MustCompile(`VALUES \((CAST\('[^']*' AS DOUBLE\)), (\d+), ('[0-9a-f]+'),`)
+	progressQuery  = regexp.MustCompile(`^SELECT statement, checksum .* WHERE version = (.*)$`)
+	progressDelete = regexp.MustCompile(`^DELETE FROM .* WHERE version = (.*)$`)
+)
+
+func (f *fakeCoordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/statement":
+		b, _ := io.ReadAll(r.Body)
+		statement := string(b)
+		f.user = r.Header.Get("X-Trino-User")
+
+		id := string(rune('a' + len(f.results)))
+		if f.fail != "" && strings.Contains(statement, f.fail) {
+			f.results[id] = `{"id": "` + id + `", "stats": {"state": "FAILED"}, "error": {"message": "Table not found", "errorName": "TABLE_NOT_FOUND"}}`
+		} else if m := progressQuery.FindStringSubmatch(statement); m != nil {
+			data := strings.Join(f.progress[m[1]], ",")
+			f.results[id] = `{"id": "` + id + `", "data": [` + strings.ReplaceAll(data, "'", `"`) + `], "stats": {"state": "FINISHED"}}`
+		} else {
+			if m := progressInsert.FindStringSubmatch(statement); m != nil {
+				f.progress[m[1]] = append(f.progress[m[1]], "["+m[2]+", "+m[3]+"]")
+			} else if m := progressDelete.FindStringSubmatch(statement); m != nil {
+				delete(f.progress, m[1])
+			}
+			f.statements = append(f.statements, statement)
+			f.results[id] = `{"id": "` + id + `", "stats": {"state": "FINISHED"}}`
+		}
+
+		w.Write([]byte(`{"id": "` + id + `", "nextUri": "` + f.url + `/v1/statement/queued/` + id + `", "stats": {"state": "QUEUED"}}`))
+
+	case strings.HasPrefix(r.URL.Path, "/v1/statement/queued/"):
+		w.Write([]byte(f.results[strings.TrimPrefix(r.URL.Path, "/v1/statement/queued/")]))
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// migrated returns the statements run on other tables than those of the
+// driver.
+func (f *fakeCoordinator) migrated() []string {
+	var statements []string
+	for _, s := range f.statements {
+		if !strings.Contains(s, `"iceberg"."analytics"`) {
+			statements = append(statements, s)
+		}
+	}
+	return statements
+}
+
+func newFakeDriver(t *testing.T, coordinator *fakeCoordinator) *Driver {
+	server := httptest.NewServer(coordinator)
+	t.Cleanup(server.Close)
+	coordinator.url = server.URL
+	coordinator.progress = map[string][]string{}
+	coordinator.results = map[string]string{}
+
+	d, err := New(server.Client(), server.URL, "iceberg", "analytics", WithUser("migrator"))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+	d.poll = time.Millisecond
+
+	return d
+}
+
+func Test_SplitStatements(t *testing.T) {
+	script := `-- create the table
+CREATE TABLE events (id BIGINT, name VARCHAR);
+INSERT INTO events VALUES (1, 'a;b'); /* ; */
+ALTER TABLE "weird;table" ADD COLUMN x INT;
+-- trailing comment`
+
+	expected := []string{
+		"-- create the table\nCREATE TABLE events (id BIGINT, name VARCHAR)",
+		"INSERT INTO events VALUES (1, 'a;b')",
+		"/* ; */\nALTER TABLE \"weird;table\" ADD COLUMN x INT",
+	}
+
+	if got := SplitStatements(script); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func Test_Driver_Exec_resume(t *testing.T) {
+	coordinator := &fakeCoordinator{}
+	d := newFakeDriver(t, coordinator)
+
+	migrations := []darwin.Migration{{
+		Version:     1,
+		Description: "Events",
+		Script:      "CREATE TABLE a (id INT);\nINSERT INTO b SELECT * FROM a;\nDROP TABLE c;\n",
+	}}
+
+	coordinator.fail = "INSERT INTO b"
+	err := darwin.New(d, migrations).Migrate()
+
+	var execErr darwin.ExecutionError
+	if !errors.As(err, &execErr) || execErr.Statement != 1 {
+		t.Fatalf("Must report the failed statement, got %v", err)
+	}
+
+	var queryErr QueryError
+	if !errors.As(err, &queryErr) || queryErr.ErrorName != "TABLE_NOT_FOUND" {
+		t.Errorf("Must wrap the query error, got %v", err)
+	}
+
+	// The failed statement is fixed, which changes the checksum of the
+	// script but not of the statements before it.
+	coordinator.fail = ""
+	migrations[0].Script = "CREATE TABLE a (id INT);\nINSERT INTO b SELECT id FROM a;\nDROP TABLE c;\n"
+	if err := darwin.New(d, migrations).Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := []string{"CREATE TABLE a (id INT)", "INSERT INTO b SELECT id FROM a", "DROP TABLE c"}
+	if got := coordinator.migrated(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Must resume after the applied statements, expected %q, got %q", expected, got)
+	}
+
+	if len(coordinator.progress) != 0 {
+		t.Errorf("Must delete the progress of the migration recorded, got %v", coordinator.progress)
+	}
+
+	if coordinator.user != "migrator" {
+		t.Errorf("Must send the user, got %q", coordinator.user)
+	}
+}
+
+func Test_Driver_All(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": "x", "data": [[1.1, "Create events", "abc", 1700000000, 1500]], "stats": {"state": "FINISHED"}}`))
+	}))
+	defer server.Close()
+
+	d, err := New(server.Client(), server.URL, "iceberg", "analytics")
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	records, err := d.All()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("len(records) == %d, wants 1", len(records))
+	}
+
+	r := records[0]
+	if r.Version != 1.1 || r.Description != "Create events" || r.Checksum != "abc" || r.AppliedAt.Unix() != 1700000000 || r.ExecutionTime != 1500 {
+		t.Errorf("Unexpected record %#v", r)
+	}
+}
+
+func Test_Driver_SetTableName(t *testing.T) {
+	coordinator := &fakeCoordinator{}
+	d := newFakeDriver(t, coordinator)
+
+	if err := d.SetTableName("other.schema_migrations"); err == nil {
+		t.Error("Must refuse a table of another schema")
+	}
+
+	if err := d.SetTableName("schema_migrations"); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := d.Create(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	tables := []string{`"iceberg"."analytics"."schema_migrations"`, `"iceberg"."analytics"."schema_migrations_statements"`}
+	for i, table := range tables {
+		if !strings.Contains(coordinator.statements[i], table) {
+			t.Errorf("Must create the table %s, got %s", table, coordinator.statements[i])
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package vertica
+
+import "github.com/dustinevan/darwin"
+
+// syntax is the syntax of Vertica scripts, quoting the bodies of stored
+// procedures with dollars.
+var syntax = darwin.Syntax{DollarQuotes: true}
+
+// SplitStatements splits a script into its statements with the Vertica
+// syntax of darwin.SplitStatements. Semicolons in quoted strings, quoted
+// identifiers, comments and dollar quoted bodies of stored procedures
+// ($$ ... $$ or $tag$ ... $tag$) don't end a statement. The statements are
+// returned without their trailing semicolon; statements holding only
+// comments are dropped.
+func SplitStatements(script string) []string {
+	return darwin.SplitStatements(script, syntax)
+}
@@ -0,0 +1,248 @@
+// Package vertica provides a darwin.Driver for Vertica, to version tables
+// and projections alongside application schemas.
+//
+// Vertica commits the current transaction before most DDL statements, so a
+// migration can't be rolled back: the driver runs the statements one at a
+// time and reports the failed one, the statements before it stay applied.
+// A projection created on a table holding data can't be used until it is
+// refreshed, after a migration creating projections the driver refreshes
+// their anchor tables with REFRESH so the migration is complete when it is
+// recorded.
+//
+// Vertica has no advisory locks and doesn't enforce primary keys by default,
+// Lock inserts a row in a darwin_locks table with an ENABLED primary key.
+//
+// The driver doesn't import a Vertica database/sql driver, use it with
+// github.com/vertica/vertica-sql-go or any compatible driver.
+package vertica
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+	"github.com/dustinevan/darwin/drivers/internal/dbutil"
+)
+
+// Default values used by New.
+const (
+	DefaultLockName    = "darwin_migrations"
+	DefaultLockTimeout = time.Minute
+)
+
+// ErrLockTimeout is returned by Lock when the lock is held by another
+// applier for longer than the lock timeout.
+var ErrLockTimeout = dbutil.ErrLockTimeout
+
+// createProjection matches a CREATE PROJECTION statement and captures its
+// anchor table.
+var createProjection = regexp.MustCompile(`(?is)^\s*CREATE\s+PROJECTION\b.*?\bFROM\s+((?:"[^"]+"|[\w$]+)(?:\.(?:"[^"]+"|[\w$]+))?)`)
+
+// StatementError is used to report the statement of a migration which
+// failed, the statements before it are applied.
+type StatementError struct {
+	Statement string
+	Err       error
+}
+
+func (s StatementError) Error() string {
+	return fmt.Sprintf("vertica: statement %q failed: %s", s.Statement, s.Err)
+}
+
+func (s StatementError) Unwrap() error {
+	return s.Err
+}
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithProjectionRefresh sets if the anchor tables of the projections created
+// by a migration are refreshed, they are by default.
+func WithProjectionRefresh(enabled bool) Option {
+	return func(d *Driver) {
+		d.refresh = enabled
+	}
+}
+
+// WithLockTimeout sets how long Lock waits for the lock. A zero or negative
+// timeout waits forever.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.lock.Timeout = timeout
+	}
+}
+
+// WithLockExpiry makes Lock take over a lock which wasn't refreshed for
+// expiry, left by an applier which crashed. Use it with darwin.WithHeartbeat
+// and an interval well under expiry, so a long migration keeps its lock.
+func WithLockExpiry(expiry time.Duration) Option {
+	return func(d *Driver) {
+		d.lock.Expiry = expiry
+	}
+}
+
+// Driver is a darwin.Driver for Vertica.
+type Driver struct {
+	*darwin.GenericDriver
+
+	refresh bool
+	lock    dbutil.TableLock
+}
+
+// New creates a new Driver for the Vertica database db.
+func New(db *sql.DB, opts ...Option) (*Driver, error) {
+	generic, err := darwin.NewGenericDriver(db, Dialect{})
+	if err != nil {
+		return nil, err
+	}
+
+	d := Driver{
+		GenericDriver: generic,
+		refresh:       true,
+		lock: dbutil.TableLock{
+			DB:          db,
+			Table:       "darwin_locks",
+			Name:        DefaultLockName,
+			Placeholder: dbutil.Question,
+			Timeout:     DefaultLockTimeout,
+			Poll:        time.Second,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep the history in the table name, the
+// name of its lock in the darwin_locks table too.
+func (d *Driver) SetTableName(name string) error {
+	if err := d.GenericDriver.SetTableName(name); err != nil {
+		return err
+	}
+
+	d.lock.Name = name
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	generic, err := d.GenericDriver.WithTable(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *d
+	c.GenericDriver = generic
+	return &c, nil
+}
+
+// Exec executes the statements of the script one at a time and refreshes
+// the projections it created.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	return d.ExecContext(context.Background(), script)
+}
+
+// ExecContext is Exec with a context.
+func (d *Driver) ExecContext(ctx context.Context, script string) (time.Duration, error) {
+	start := time.Now()
+
+	// Session settings of a statement must apply to the next ones.
+	conn, err := d.DB.Conn(ctx)
+	if err != nil {
+		return time.Since(start), err
+	}
+	defer conn.Close()
+
+	var anchors []string
+	for _, statement := range SplitStatements(script) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return time.Since(start), StatementError{Statement: statement, Err: err}
+		}
+
+		if m := createProjection.FindStringSubmatch(statement); m != nil && !contains(anchors, m[1]) {
+			anchors = append(anchors, m[1])
+		}
+	}
+
+	if d.refresh && len(anchors) > 0 {
+		refresh := fmt.Sprintf("SELECT REFRESH('%s')", strings.ReplaceAll(strings.Join(anchors, ","), "'", "''"))
+		if _, err := conn.ExecContext(ctx, refresh); err != nil {
+			return time.Since(start), StatementError{Statement: refresh, Err: err}
+		}
+	}
+
+	return time.Since(start), nil
+}
+
+// BeginTx returns darwin.ErrTransactionUnsupported, migrations are executed
+// with Exec and recorded with Insert.
+func (d *Driver) BeginTx(ctx context.Context) (darwin.Tx, error) {
+	return nil, darwin.ErrTransactionUnsupported
+}
+
+// Lock acquires the migration lock by inserting a row in darwin_locks.
+func (d *Driver) Lock() error {
+	if err := d.createLockTable(); err != nil {
+		return err
+	}
+
+	return d.lock.Lock()
+}
+
+// Unlock releases the lock acquired by Lock.
+func (d *Driver) Unlock() error {
+	return d.lock.Unlock()
+}
+
+// RefreshLock keeps the migration lock from expiring, it is called by the
+// heartbeat of darwin.WithHeartbeat.
+func (d *Driver) RefreshLock() error {
+	return d.lock.Refresh()
+}
+
+// LockHolder returns the holder of the migration lock, if it is held.
+func (d *Driver) LockHolder() (darwin.LockHolder, bool, error) {
+	return d.lock.Holder()
+}
+
+// BreakLock releases the migration lock whoever holds it, to recover from
+// an applier which crashed.
+func (d *Driver) BreakLock() error {
+	if err := d.createLockTable(); err != nil {
+		return err
+	}
+
+	return d.lock.Break()
+}
+
+// createLockTable creates darwin_locks with an enforced primary key, Vertica
+// doesn't check constraints which aren't ENABLED.
+func (d *Driver) createLockTable() error {
+	_, err := d.DB.Exec(`CREATE TABLE IF NOT EXISTS darwin_locks
+                (
+                    name        VARCHAR(255) NOT NULL,
+                    owner       VARCHAR(64)  NOT NULL,
+                    hostname    VARCHAR(255) NOT NULL,
+                    pid         INT          NOT NULL,
+                    acquired_at INT          NOT NULL,
+                    PRIMARY KEY (name) ENABLED
+                );`)
+	return err
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package vertica
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func Test_SplitStatements(t *testing.T) {
+	script := `CREATE TABLE sales (id INT, note VARCHAR(20) DEFAULT 'a;b');
+CREATE PROCEDURE raise() LANGUAGE PLvSQL AS $$
+BEGIN
+    PERFORM 1;
+END;
+$$;
+-- done;
+`
+
+	expected := []string{
+		"CREATE TABLE sales (id INT, note VARCHAR(20) DEFAULT 'a;b')",
+		"CREATE PROCEDURE raise() LANGUAGE PLvSQL AS $$\nBEGIN\n    PERFORM 1;\nEND;\n$$",
+	}
+
+	if got := SplitStatements(script); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func Test_Driver_Exec_projection_refresh(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	projection := "CREATE PROJECTION sales_by_date AS SELECT * FROM store.sales ORDER BY sold_at SEGMENTED BY HASH(id) ALL NODES"
+
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE store.sales ADD COLUMN sold_at DATE")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(projection)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("SELECT REFRESH('store.sales')")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if _, err := d.Exec("ALTER TABLE store.sales ADD COLUMN sold_at DATE;\n" + projection + ";\n"); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_Exec_error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE a (id INT)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE SEQUENCE s CACHE 0")).WillReturnError(errors.New("Invalid cache value"))
+
+	_, err = d.Exec("CREATE TABLE a (id INT);\nCREATE SEQUENCE s CACHE 0;\nCREATE TABLE b (id INT);\n")
+
+	var statementErr StatementError
+	if !errors.As(err, &statementErr) || statementErr.Statement != "CREATE SEQUENCE s CACHE 0" {
+		t.Errorf("Must report the failed statement, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Driver_Lock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db)
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	mock.ExpectExec(`PRIMARY KEY \(name\) ENABLED`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS darwin_locks")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO darwin_locks")).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if err := d.Lock(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
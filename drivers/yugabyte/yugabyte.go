@@ -0,0 +1,278 @@
+// Package yugabyte provides a darwin.Driver for YugabyteDB (YSQL).
+//
+// Transactions aborted with a serialization failure (SQLSTATE 40001), which
+// YugabyteDB reports much more often than Postgres, are retried with an
+// exponential backoff, and a migration executed and recorded in the same
+// transaction is replayed. Advisory locks are not available on every version,
+// Lock inserts a row in the darwin_locks table instead. Catalog changes take
+// some time to reach every tablet server, after a migration changing the
+// schema the driver waits for the propagation delay before reporting it as
+// executed.
+//
+// The driver doesn't import a Postgres database/sql driver, use it with
+// github.com/yugabyte/pgx/v5/stdlib, github.com/lib/pq or any compatible
+// driver.
+package yugabyte
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"time"
+
+	"github.com/dustinevan/darwin"
+	"github.com/dustinevan/darwin/drivers/internal/dbutil"
+)
+
+// Default values used by New.
+const (
+	DefaultAttempts       = 10
+	DefaultBackoff        = 100 * time.Millisecond
+	DefaultLockName       = "darwin_migrations"
+	DefaultLockTimeout    = time.Minute
+	DefaultDDLPropagation = 2 * time.Second
+)
+
+// ErrLockTimeout is returned by Lock when the lock is held by another
+// applier for longer than the lock timeout.
+var ErrLockTimeout = dbutil.ErrLockTimeout
+
+// ddlStatement matches a statement changing the catalog.
+var ddlStatement = regexp.MustCompile(`(?im)(^|;)\s*(CREATE|ALTER|DROP|TRUNCATE|COMMENT\s+ON|GRANT|REVOKE)\s`)
+
+// Option configures the Driver.
+type Option func(*Driver)
+
+// WithRetries sets how many times a transaction is attempted and the initial
+// wait between attempts.
+func WithRetries(attempts int, backoff time.Duration) Option {
+	return func(d *Driver) {
+		d.attempts = attempts
+		d.backoff = backoff
+	}
+}
+
+// WithLockTimeout sets how long Lock waits for the lock. A zero or negative
+// timeout waits forever.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(d *Driver) {
+		d.lock.Timeout = timeout
+	}
+}
+
+// WithLockExpiry makes Lock take over a lock which wasn't refreshed for
+// expiry, left by an applier which crashed. Use it with darwin.WithHeartbeat
+// and an interval well under expiry, so a long migration keeps its lock.
+func WithLockExpiry(expiry time.Duration) Option {
+	return func(d *Driver) {
+		d.lock.Expiry = expiry
+	}
+}
+
+// WithDDLPropagation sets how long the driver waits after a migration
+// changing the schema, it should exceed the heartbeat interval of the
+// tablet servers.
+func WithDDLPropagation(wait time.Duration) Option {
+	return func(d *Driver) {
+		d.ddlPropagation = wait
+	}
+}
+
+// Driver is a darwin.Driver for YugabyteDB.
+type Driver struct {
+	*darwin.GenericDriver
+
+	attempts       int
+	backoff        time.Duration
+	ddlPropagation time.Duration
+	lock           dbutil.TableLock
+}
+
+// New creates a new Driver for the YugabyteDB database db.
+func New(db *sql.DB, opts ...Option) (*Driver, error) {
+	generic, err := darwin.NewGenericDriver(db, Dialect{})
+	if err != nil {
+		return nil, err
+	}
+
+	d := Driver{
+		GenericDriver:  generic,
+		attempts:       DefaultAttempts,
+		backoff:        DefaultBackoff,
+		ddlPropagation: DefaultDDLPropagation,
+		lock: dbutil.TableLock{
+			DB:          db,
+			Table:       "darwin_locks",
+			Name:        DefaultLockName,
+			Placeholder: dbutil.Dollar,
+			Timeout:     DefaultLockTimeout,
+			Poll:        time.Second,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d, nil
+}
+
+// SetTableName makes the driver keep the history in the table name, the
+// name of its lock in the darwin_locks table too.
+func (d *Driver) SetTableName(name string) error {
+	if err := d.GenericDriver.SetTableName(name); err != nil {
+		return err
+	}
+
+	d.lock.Name = name
+	return nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name, for the seeds.
+func (d *Driver) CloneTable(name string) (darwin.Driver, error) {
+	generic, err := d.GenericDriver.WithTable(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := *d
+	c.GenericDriver = generic
+	return &c, nil
+}
+
+// Create creates the table darwin_migrations if necessary.
+func (d *Driver) Create() error {
+	return d.retry(d.GenericDriver.Create)
+}
+
+// Insert inserts a migration entry into database.
+func (d *Driver) Insert(e darwin.MigrationRecord) error {
+	return d.retry(func() error {
+		return d.GenericDriver.Insert(e)
+	})
+}
+
+// All returns all migrations applied.
+func (d *Driver) All() ([]darwin.MigrationRecord, error) {
+	var records []darwin.MigrationRecord
+
+	err := d.retry(func() error {
+		var err error
+		records, err = d.GenericDriver.All()
+		return err
+	})
+
+	return records, err
+}
+
+// Exec executes the script in a transaction, retried on serialization
+// failures, and waits for schema changes to propagate.
+func (d *Driver) Exec(script string) (time.Duration, error) {
+	return d.ExecContext(context.Background(), script)
+}
+
+// ExecContext is Exec with a context.
+func (d *Driver) ExecContext(ctx context.Context, script string) (time.Duration, error) {
+	start := time.Now()
+
+	err := d.retry(func() error {
+		tx, err := d.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, script); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		return tx.Commit()
+	})
+
+	if err != nil {
+		return time.Since(start), err
+	}
+
+	if ddlStatement.MatchString(script) {
+		time.Sleep(d.ddlPropagation)
+	}
+
+	return time.Since(start), nil
+}
+
+// BeginTx starts a transaction executing migrations and recording them,
+// replayed on serialization failures. Its commit waits for the schema
+// changes to propagate.
+func (d *Driver) BeginTx(ctx context.Context) (darwin.Tx, error) {
+	tx, err := dbutil.BeginRetryTx(ctx, d.GenericDriver.BeginTx, d.retry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction{RetryTx: tx, d: d}, nil
+}
+
+// transaction is the darwin.Tx of the driver.
+type transaction struct {
+	*dbutil.RetryTx
+
+	d   *Driver
+	ddl bool
+}
+
+// Exec executes the script.
+func (t *transaction) Exec(ctx context.Context, script string) (time.Duration, error) {
+	t.ddl = t.ddl || ddlStatement.MatchString(script)
+	return t.RetryTx.Exec(ctx, script)
+}
+
+// ExecStatements executes the statements of the script one at a time.
+func (t *transaction) ExecStatements(ctx context.Context, script string) ([]darwin.StatementResult, error) {
+	t.ddl = t.ddl || ddlStatement.MatchString(script)
+	return t.RetryTx.ExecStatements(ctx, script)
+}
+
+// Commit commits the transaction and waits for the schema changes it made
+// to propagate.
+func (t *transaction) Commit() error {
+	if err := t.RetryTx.Commit(); err != nil {
+		return err
+	}
+
+	if t.ddl {
+		time.Sleep(t.d.ddlPropagation)
+	}
+	return nil
+}
+
+// Lock acquires the migration lock by inserting a row in darwin_locks.
+func (d *Driver) Lock() error {
+	return d.lock.Lock()
+}
+
+// Unlock releases the lock acquired by Lock.
+func (d *Driver) Unlock() error {
+	return d.lock.Unlock()
+}
+
+// RefreshLock keeps the migration lock from expiring, it is called by the
+// heartbeat of darwin.WithHeartbeat.
+func (d *Driver) RefreshLock() error {
+	return d.lock.Refresh()
+}
+
+// LockHolder returns the holder of the migration lock, if it is held.
+func (d *Driver) LockHolder() (darwin.LockHolder, bool, error) {
+	return d.lock.Holder()
+}
+
+// BreakLock releases the migration lock whoever holds it, to recover from
+// an applier which crashed.
+func (d *Driver) BreakLock() error {
+	return d.lock.Break()
+}
+
+func (d *Driver) retry(f func() error) error {
+	return dbutil.Retry(d.attempts, d.backoff, dbutil.IsSerializationFailure, f)
+}
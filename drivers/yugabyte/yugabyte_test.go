@@ -0,0 +1,114 @@
+package yugabyte
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dustinevan/darwin"
+)
+
+type stateError string
+
+func (s stateError) Error() string    { return "state " + string(s) }
+func (s stateError) SQLState() string { return string(s) }
+
+func Test_Driver_Exec_retry(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db, WithRetries(3, time.Millisecond), WithDDLPropagation(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	stmt := "CREATE TABLE a (id INT);"
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(stmt)).WillReturnError(stateError("40001"))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(stmt)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	dur, err := d.Exec(stmt)
+	if err != nil {
+		t.Fatalf("Must retry the transaction, got %s", err)
+	}
+
+	if dur < 10*time.Millisecond {
+		t.Errorf("Must wait for the schema change to propagate, took %s", dur)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_ddlStatement(t *testing.T) {
+	expectations := []struct {
+		script   string
+		expected bool
+	}{
+		{"CREATE TABLE a (id INT);", true},
+		{"INSERT INTO a VALUES (1);\n  alter table a add column b int;", true},
+		{"INSERT INTO a VALUES (1); GRANT SELECT ON a TO app;", true},
+		{"UPDATE a SET created = now();", false},
+	}
+
+	for _, expectation := range expectations {
+		if got := ddlStatement.MatchString(expectation.script); got != expectation.expected {
+			t.Errorf("ddlStatement.MatchString(%q) == %t, wants %t", expectation.script, got, expectation.expected)
+		}
+	}
+}
+
+func Test_Driver_BeginTx_retry(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := New(db, WithRetries(3, time.Millisecond), WithDDLPropagation(0))
+	if err != nil {
+		t.Fatalf("unable to construct driver: %s", err)
+	}
+
+	stmt := "CREATE TABLE a (id INT);"
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(stmt)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit().WillReturnError(stateError("40001"))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(stmt)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	tx, err := d.BeginTx(context.Background())
+	if err != nil {
+		t.Fatalf("Must begin a transaction, got %s", err)
+	}
+
+	if _, err := tx.Exec(context.Background(), stmt); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := tx.Insert(context.Background(), darwin.MigrationRecord{Version: 1, Description: "A", AppliedAt: time.Now()}); err != nil {
+		t.Fatalf("Must record the migration, got %s", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Must replay the transaction, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
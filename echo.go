@@ -0,0 +1,98 @@
+package darwin
+
+import "regexp"
+
+// Redactor returns a statement with its secrets masked, before it is
+// logged.
+type Redactor func(statement string) string
+
+// passwords matches the passwords of statements like CREATE USER, ALTER
+// ROLE and SET PASSWORD, quoted after PASSWORD or IDENTIFIED BY.
+var passwords = regexp.MustCompile(`(?i)(\b(?:PASSWORD|IDENTIFIED\s+(?:BY|WITH\s+\w+\s+(?:BY|AS)))\s*(?:=\s*)?(?:\(\s*)?)('(?:[^']|'')*'|"(?:[^"]|"")*"|\$(\w*)\$[\s\S]*?\$\w*\$)`)
+
+// redacted replaces the secrets.
+const redacted = "***"
+
+// RedactPasswords masks the quoted passwords of statements like CREATE USER
+// and ALTER ROLE. WithSQLEcho always applies it.
+func RedactPasswords(statement string) string {
+	return passwords.ReplaceAllString(statement, "${1}'"+redacted+"'")
+}
+
+// RedactPattern returns a Redactor replacing the text matched by re, or by
+// its first group if it has one, with ***.
+func RedactPattern(re *regexp.Regexp) Redactor {
+	return func(statement string) string {
+		if re.NumSubexp() == 0 {
+			return re.ReplaceAllString(statement, redacted)
+		}
+
+		var out []byte
+		last := 0
+		for _, m := range re.FindAllStringSubmatchIndex(statement, -1) {
+			if m[2] < 0 {
+				continue
+			}
+			out = append(out, statement[last:m[2]]...)
+			out = append(out, redacted...)
+			last = m[3]
+		}
+		return string(append(out, statement[last:]...))
+	}
+}
+
+// WithSQLEcho makes Migrate log each statement before and once it is
+// executed, or each script when the driver doesn't report statements, with
+// the Logger of WithLogger.
+// The statements are redacted with RedactPasswords then the redactors, as
+// are the errors logged.
+func WithSQLEcho(redactors ...Redactor) Option {
+	return func(d *Darwin) {
+		d.echo = true
+		d.redactors = redactors
+	}
+}
+
+// redact returns the statement redacted for logs.
+func (d Darwin) redact(statement string) string {
+	statement = RedactPasswords(statement)
+	for _, r := range d.redactors {
+		statement = r(statement)
+	}
+	return statement
+}
+
+// echoExecuting logs the statement of the migration about to execute.
+func (d Darwin) echoExecuting(m Migration, statement string) {
+	if !d.echo {
+		return
+	}
+
+	d.logger().Info("darwin: statement executing",
+		"version", m.Version,
+		"sql", d.redact(statement))
+}
+
+// echoResult logs the statements of an executed migration.
+func (d Darwin) echoResult(r Result) {
+	if !d.echo {
+		return
+	}
+
+	if len(r.Statements) == 0 {
+		d.logger().Info("darwin: script executed",
+			"version", r.Migration.Version,
+			"script", d.redact(r.Migration.Script),
+			"duration", r.Duration)
+		return
+	}
+
+	for i, s := range r.Statements {
+		d.logger().Info("darwin: statement executed",
+			"version", r.Migration.Version,
+			"statement", i,
+			"sql", d.redact(s.Statement),
+			"rows_affected", s.RowsAffected,
+			"duration", s.Duration)
+	}
+}
@@ -0,0 +1,79 @@
+package darwin
+
+import (
+	"regexp"
+	"testing"
+)
+
+func Test_RedactPasswords(t *testing.T) {
+	tests := map[string]string{
+		"CREATE USER app WITH PASSWORD 's3cr''et'":                    "CREATE USER app WITH PASSWORD '***'",
+		"CREATE USER 'app'@'%' IDENTIFIED BY 'hunter2'":               "CREATE USER 'app'@'%' IDENTIFIED BY '***'",
+		"ALTER USER app IDENTIFIED WITH mysql_native_password BY 'x'": "ALTER USER app IDENTIFIED WITH mysql_native_password BY '***'",
+		"SET PASSWORD FOR app = PASSWORD('x')":                        "SET PASSWORD FOR app = PASSWORD('***')",
+		"ALTER ROLE app PASSWORD = \"x\"":                             "ALTER ROLE app PASSWORD = '***'",
+		"UPDATE users SET password_hash = 'abc'":                      "UPDATE users SET password_hash = 'abc'",
+	}
+
+	for statement, expected := range tests {
+		if got := RedactPasswords(statement); got != expected {
+			t.Errorf("RedactPasswords(%q) = %q, expected %q", statement, got, expected)
+		}
+	}
+}
+
+func Test_RedactPattern(t *testing.T) {
+	token := RedactPattern(regexp.MustCompile(`token = '([^']*)'`))
+	if got := token("UPDATE hooks SET token = 'abc' WHERE token = 'def'"); got != "UPDATE hooks SET token = '***' WHERE token = '***'" {
+		t.Errorf("Must mask the group, got %q", got)
+	}
+
+	secret := RedactPattern(regexp.MustCompile(`sk_live_\w+`))
+	if got := secret("INSERT INTO keys VALUES ('sk_live_123')"); got != "INSERT INTO keys VALUES ('***')" {
+		t.Errorf("Must mask the match, got %q", got)
+	}
+}
+
+func Test_WithSQLEcho(t *testing.T) {
+	logger := &recordLogger{}
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE USER app WITH PASSWORD 'hunter2';"},
+	}
+
+	if err := New(&dummyDriver{}, migrations, WithLogger(logger), WithSQLEcho()).Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	for i, event := range logger.events {
+		if event == "darwin: script executed" {
+			if script := logger.args[i][3]; script != "CREATE USER app WITH PASSWORD '***';" {
+				t.Errorf("Must redact the script, got %q", script)
+			}
+			return
+		}
+	}
+
+	t.Errorf("Must echo the script, got %q", logger.events)
+}
+
+func Test_WithSQLEcho_before(t *testing.T) {
+	logger := &recordLogger{}
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+	}
+
+	if err := New(&dummyDriver{ExecError: true}, migrations, WithLogger(logger), WithSQLEcho()).Migrate(); err == nil {
+		t.Fatal("Must return the execution error")
+	}
+
+	for i, event := range logger.events {
+		if event == "darwin: statement executing" {
+			if sql := logger.args[i][3]; sql != "CREATE TABLE users (id INT);" {
+				t.Errorf("Must echo the script, got %q", sql)
+			}
+			return
+		}
+	}
+
+	t.Errorf("Must echo the script before executing it, got %q", logger.events)
+}
@@ -0,0 +1,175 @@
+package darwin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Target is a database of a Fleet.
+type Target struct {
+	Name   string
+	Driver Driver
+
+	// Migrations replace the migrations of the Fleet for the target.
+	Migrations []Migration
+}
+
+// Fleet applies the same migrations to many databases, like the regional
+// databases of an application.
+type Fleet struct {
+	Targets    []Target
+	Migrations []Migration
+
+	// Concurrency is how many targets are migrated at the same time, all of
+	// them when zero.
+	Concurrency int
+
+	// Options configure the Darwin migrating each target.
+	Options []Option
+}
+
+// TargetResult is the outcome of migrating a target.
+type TargetResult struct {
+	Name string
+
+	// Err is the error which stopped the migration of the target.
+	Err error
+
+	// Version is the last version applied to the target, 0 when none is.
+	Version float64
+
+	// Pending is the number of migrations not applied to the target.
+	Pending int
+}
+
+// FleetReport is the outcome of migrating a Fleet, with a result per
+// target in the order of the targets.
+type FleetReport struct {
+	Results []TargetResult
+}
+
+// Behind returns the results of the targets with pending migrations.
+func (r FleetReport) Behind() []TargetResult {
+	var behind []TargetResult
+	for _, result := range r.Results {
+		if result.Pending > 0 {
+			behind = append(behind, result)
+		}
+	}
+	return behind
+}
+
+// Err returns a FleetError when the migration of a target failed.
+func (r FleetReport) Err() error {
+	var failed []TargetResult
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+	return FleetError{Failed: failed}
+}
+
+// FleetError is used to report the targets of a Fleet which failed.
+type FleetError struct {
+	Failed []TargetResult
+}
+
+func (f FleetError) Error() string {
+	messages := make([]string, len(f.Failed))
+	for i, result := range f.Failed {
+		messages[i] = fmt.Sprintf("%s: %s", result.Name, result.Err)
+	}
+	return fmt.Sprintf("darwin: migration failed on %d targets: %s", len(f.Failed), strings.Join(messages, "; "))
+}
+
+// Migrate executes the missing migrations on every target. A target failing
+// doesn't stop the others, the errors are in the report.
+func (f Fleet) Migrate(ctx context.Context) FleetReport {
+	return f.each(ctx, func(d Darwin) error {
+		return d.MigrateContext(ctx)
+	})
+}
+
+// Info returns the state of every target without migrating them.
+func (f Fleet) Info(ctx context.Context) FleetReport {
+	return f.each(ctx, func(Darwin) error {
+		return nil
+	})
+}
+
+// each runs do on every target, then reads its state.
+func (f Fleet) each(ctx context.Context, do func(Darwin) error) FleetReport {
+	report := FleetReport{Results: make([]TargetResult, len(f.Targets))}
+
+	concurrency := f.Concurrency
+	if concurrency <= 0 {
+		concurrency = len(f.Targets)
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+	for i, target := range f.Targets {
+		wg.Add(1)
+		go func(i int, target Target) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result := TargetResult{Name: target.Name}
+			if err := ctx.Err(); err != nil {
+				result.Err = err
+				report.Results[i] = result
+				return
+			}
+
+			// Each target gets its own slice, the targets being migrated
+			// concurrently.
+			migrations := f.Migrations
+			if target.Migrations != nil {
+				migrations = target.Migrations
+			}
+			migrations = sortedMigrations(migrations)
+
+			d := New(target.Driver, migrations, f.Options...)
+			result.Err = do(d)
+
+			report.Results[i] = summarize(d, result)
+		}(i, target)
+	}
+
+	wg.Wait()
+	return report
+}
+
+// summarize returns the result with the last version applied and the
+// number of pending migrations of the database of d.
+func summarize(d Darwin, result TargetResult) TargetResult {
+	info, err := d.Info()
+	if err != nil {
+		if result.Err == nil {
+			result.Err = err
+		}
+		return result
+	}
+
+	result.Version, result.Pending = 0, 0
+	for _, m := range info {
+		switch {
+		case m.Status == Pending:
+			result.Pending++
+		case m.Status == Applied && m.Migration.Version > result.Version:
+			result.Version = m.Migration.Version
+		}
+	}
+
+	return result
+}
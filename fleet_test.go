@@ -0,0 +1,76 @@
+package darwin
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func Test_Fleet_Migrate(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Description: "First Migration", Script: "does not matter!"},
+		{Version: 2, Description: "Second Migration", Script: "does not matter!"},
+	}
+
+	fleet := Fleet{
+		Targets: []Target{
+			{Name: "eu", Driver: &dummyDriver{}},
+			{Name: "us", Driver: &dummyDriver{ExecError: true}},
+		},
+		Migrations:  migrations,
+		Concurrency: 1,
+	}
+
+	report := fleet.Info(context.Background())
+	if len(report.Behind()) != 2 || report.Results[0].Pending != 2 {
+		t.Errorf("Must report every target as behind, got %#v", report.Results)
+	}
+
+	report = fleet.Migrate(context.Background())
+
+	eu, us := report.Results[0], report.Results[1]
+	if eu.Err != nil || eu.Version != 2 || eu.Pending != 0 {
+		t.Errorf("Must migrate eu, got %#v", eu)
+	}
+
+	if us.Err == nil || us.Pending != 2 {
+		t.Errorf("Must report the failure of us, got %#v", us)
+	}
+
+	var fleetErr FleetError
+	if err := report.Err(); !errors.As(err, &fleetErr) || len(fleetErr.Failed) != 1 || fleetErr.Failed[0].Name != "us" {
+		t.Errorf("Must return a FleetError for us, got %v", err)
+	}
+
+	if behind := report.Behind(); len(behind) != 1 || behind[0].Name != "us" {
+		t.Errorf("Must report us as behind, got %#v", behind)
+	}
+}
+
+func Test_Fleet_Migrate_unsorted(t *testing.T) {
+	migrations := []Migration{
+		{Version: 3, Description: "Third Migration", Script: "does not matter!"},
+		{Version: 1, Description: "First Migration", Script: "does not matter!"},
+		{Version: 2, Description: "Second Migration", Script: "does not matter!"},
+	}
+
+	var targets []Target
+	for _, name := range []string{"eu", "us", "ap", "sa"} {
+		targets = append(targets, Target{Name: name, Driver: &dummyDriver{}})
+	}
+
+	report := Fleet{Targets: targets, Migrations: migrations}.Migrate(context.Background())
+	if err := report.Err(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	for _, result := range report.Results {
+		if result.Version != 3 || result.Pending != 0 {
+			t.Errorf("Must migrate %s, got %#v", result.Name, result)
+		}
+	}
+
+	if migrations[0].Version != 3 {
+		t.Errorf("Must not sort the migrations of the caller, got %#v", migrations)
+	}
+}
@@ -0,0 +1,149 @@
+package darwin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// HistoryScripter is implemented by drivers able to write the SQL of their
+// history table, like the generic driver, for GenerateScript.
+type HistoryScripter interface {
+	// CreateHistorySQL returns the SQL creating the history table if it
+	// doesn't exist.
+	CreateHistorySQL() string
+
+	// InsertRecordSQL returns the SQL recording the migration, without bind
+	// parameters.
+	InsertRecordSQL(r MigrationRecord) string
+}
+
+// CreateHistorySQL returns the SQL of the dialect creating the history
+// table.
+func (m *GenericDriver) CreateHistorySQL() string {
+	return m.Dialect.CreateTableSQL()
+}
+
+// InsertRecordSQL returns the SQL of the dialect recording the migration,
+// with the values of the record inlined.
+func (m *GenericDriver) InsertRecordSQL(r MigrationRecord) string {
+	return inlineArgs(m.Dialect.InsertSQL(),
+		strconv.FormatFloat(r.Version, 'f', -1, 64),
+		quoteLiteral(r.Description),
+		quoteLiteral(r.Checksum),
+		strconv.FormatInt(r.AppliedAt.Unix(), 10),
+		strconv.FormatInt(int64(r.ExecutionTime), 10),
+	)
+}
+
+// GenerateScript writes the SQL of the pending migrations to w, in order,
+// each followed by the statement recording it in the history, for DBAs
+// running the changes through their own change control. The migrations are
+// recorded as applied when the script is generated, in no time.
+func (d Darwin) GenerateScript(w io.Writer) error {
+	if d.err != nil {
+		return d.err
+	}
+
+	h, ok := d.driver.(HistoryScripter)
+	if !ok {
+		return errors.New("darwin: the driver can't write the SQL of its history")
+	}
+
+	migrations := sortedMigrations(d.migrations)
+	records, err := d.validateHistory(context.Background(), migrations)
+	if err != nil {
+		return err
+	}
+
+	planned, err := planRecords(d.driver, records, migrations)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString(terminate(h.CreateHistorySQL()))
+
+	now := d.appliedAt()
+	for _, m := range planned {
+		fmt.Fprintf(&b, "\n-- Version: %s\n-- Description: %s\n", strconv.FormatFloat(m.Version, 'f', -1, 64), m.Description)
+		b.WriteString(terminate(m.Script))
+		b.WriteString(terminate(h.InsertRecordSQL(MigrationRecord{
+			Version:     m.Version,
+			Description: m.Description,
+			Checksum:    m.Checksum(),
+			AppliedAt:   now,
+		})))
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// terminate returns the statements ended with a semicolon and a new line.
+func terminate(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if !strings.HasSuffix(sql, ";") {
+		sql += ";"
+	}
+	return sql + "\n"
+}
+
+// quoteLiteral returns s as a SQL string literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// inlineArgs replaces the bind parameters of the query, in any placeholder
+// style, with the values.
+func inlineArgs(query string, values ...string) string {
+	var (
+		b strings.Builder
+		n int
+	)
+
+	for i := 0; i < len(query); {
+		c := query[i]
+
+		switch {
+		case c == '\'' || c == '"':
+			j := skipQuoted(query, i, false)
+			b.WriteString(query[i:j])
+			i = j
+			continue
+
+		case c == '?':
+			if n < len(values) {
+				b.WriteString(values[n])
+			}
+			n++
+			i++
+			continue
+
+		case c == '$' || c == ':' || (c == '@' && i+1 < len(query) && query[i+1] == 'p'):
+			j := i + 1
+			if c == '@' {
+				j++
+			}
+			k := j
+			for k < len(query) && query[k] >= '0' && query[k] <= '9' {
+				k++
+			}
+			if k > j {
+				if index, _ := strconv.Atoi(query[j:k]); index >= 1 && index <= len(values) {
+					b.WriteString(values[index-1])
+					i = k
+					continue
+				}
+			}
+		}
+
+		b.WriteByte(c)
+		i++
+	}
+
+	return b.String()
+}
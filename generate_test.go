@@ -0,0 +1,70 @@
+package darwin
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func Test_inlineArgs(t *testing.T) {
+	tests := map[string]string{
+		"VALUES (?, ?, '?')":           "VALUES (1, 'a''b', '?')",
+		"VALUES ($2, $1, '$1')":        "VALUES ('a''b', 1, '$1')",
+		"VALUES (:1, :2)":              "VALUES (1, 'a''b')",
+		"VALUES (@p1, @p2) -- @people": "VALUES (1, 'a''b') -- @people",
+	}
+
+	for query, expected := range tests {
+		if got := inlineArgs(query, "1", quoteLiteral("a'b")); got != expected {
+			t.Errorf("inlineArgs(%q) = %q, expected %q", query, got, expected)
+		}
+	}
+}
+
+func Test_Darwin_GenerateScript(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	dialect := MySQLDialect{}
+	d, _ := NewGenericDriver(db, dialect)
+
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 1.1, Description: "Owner's roles", Script: "CREATE TABLE roles (id INT)"},
+	}
+
+	columns := []string{"version", "description", "checksum", "applied_at", "execution_time", "applied_by", "hostname", "app_version", "source"}
+	mock.ExpectQuery(escapeQuery(dialect.LatestVersionSQL())).WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(1.0))
+	mock.ExpectQuery(escapeQuery(dialect.AllSinceSQL())).WithArgs(1.0).WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "Users", migrations[0].Checksum(), 0, 0, nil, nil, nil, nil))
+
+	var buf bytes.Buffer
+	if err := New(d, migrations).GenerateScript(&buf); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	script := buf.String()
+	if !strings.HasPrefix(script, strings.TrimSpace(dialect.CreateTableSQL())) || strings.Contains(script, "CREATE TABLE users") {
+		t.Errorf("Must create the history table and skip the applied migrations, got\n%s", script)
+	}
+
+	if !strings.Contains(script, "-- Version: 1.1\n-- Description: Owner's roles\nCREATE TABLE roles (id INT);\nINSERT INTO darwin_migrations") {
+		t.Errorf("Must write the pending migration, got\n%s", script)
+	}
+
+	if !strings.Contains(script, "VALUES (1.1, 'Owner''s roles', '"+migrations[1].Checksum()+"', ") {
+		t.Errorf("Must record the pending migration, got\n%s", script)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+
+	if err := New(&dummyDriver{}, migrations).GenerateScript(&buf); err == nil {
+		t.Errorf("Must refuse a driver which can't script its history")
+	}
+}
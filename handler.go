@@ -0,0 +1,82 @@
+package darwin
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// Errors of the document served by Handler. The errors themselves, which
+// may hold SQL or connection details, are logged instead of served.
+const (
+	statusUnavailable = "the history can't be read"
+	statusInvalid     = "the migrations don't match the history"
+)
+
+// statusJSON is the document served by Handler.
+type statusJSON struct {
+	UpToDate    bool        `json:"up_to_date"`
+	Pending     []float64   `json:"pending"`
+	LastApplied *recordJSON `json:"last_applied,omitempty"`
+	Valid       bool        `json:"valid"`
+	Error       string      `json:"error,omitempty"`
+}
+
+// Handler returns an http.Handler serving the status of the migrations as
+// JSON, for readiness probes and dashboards: whether the database is up to
+// date, the pending versions, the last migration applied and the result of
+// Validate. It answers 503 Service Unavailable unless the database is up to
+// date and valid. Each request reads the history once; the errors are
+// logged with the logger of d and served as a generic message.
+func Handler(d *Darwin) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := d.status()
+
+		w.Header().Set("Content-Type", "application/json")
+		if !status.UpToDate || !status.Valid {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(status)
+	})
+}
+
+// status returns the status of the migrations from a single read of the
+// history.
+func (d Darwin) status() statusJSON {
+	status := statusJSON{Pending: []float64{}}
+
+	err := d.err
+	var records []MigrationRecord
+	if err == nil {
+		records, err = d.driver.All()
+	}
+	if err != nil {
+		d.logger().Error("darwin: status unavailable", "error", d.redact(err.Error()))
+		status.Error = statusUnavailable
+		return status
+	}
+
+	migrations := sortedMigrations(d.migrations)
+	if err := checkVersions(migrations); err != nil {
+		d.logger().Error("darwin: migrations invalid", "error", d.redact(err.Error()))
+		status.Error = statusInvalid
+	} else if err := validateRecords(records, migrations); err != nil {
+		d.logger().Error("darwin: migrations invalid", "error", d.redact(err.Error()))
+		status.Error = statusInvalid
+	} else {
+		status.Valid = true
+	}
+
+	for _, i := range FilterInfo(infoRecords(records, migrations), ByStatus(Pending, Error)) {
+		status.Pending = append(status.Pending, i.Migration.Version)
+	}
+
+	if len(records) > 0 {
+		sort.Sort(sort.Reverse(byMigrationRecordVersion(records)))
+		doc := newRecordJSON(records[0])
+		status.LastApplied = &doc
+	}
+
+	status.UpToDate = status.Error == "" && len(status.Pending) == 0
+	return status
+}
@@ -0,0 +1,77 @@
+package darwin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func Test_Handler(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+	driver := &dummyDriver{records: []MigrationRecord{{Version: 1, Description: "Users", Checksum: migrations[0].Checksum()}}}
+	d := New(driver, migrations)
+
+	serve := func() (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		Handler(&d).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/migrations", nil))
+
+		var doc map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+			t.Fatalf("Must serve JSON, got %s", err)
+		}
+		return rec.Code, doc
+	}
+
+	code, doc := serve()
+	if code != http.StatusServiceUnavailable || doc["up_to_date"] != false || doc["valid"] != true || !reflect.DeepEqual(doc["pending"], []interface{}{2.0}) {
+		t.Errorf("Must report the pending migration, got %d %v", code, doc)
+	}
+
+	if last, _ := doc["last_applied"].(map[string]interface{}); last["version"] != 1.0 {
+		t.Errorf("Must report the last migration applied, got %v", doc["last_applied"])
+	}
+
+	if err := d.Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	code, doc = serve()
+	if code != http.StatusOK || doc["up_to_date"] != true || len(doc["pending"].([]interface{})) != 0 {
+		t.Errorf("Must report the database up to date, got %d %v", code, doc)
+	}
+
+	driver.AllError = true
+	code, doc = serve()
+	if code != http.StatusServiceUnavailable || doc["valid"] != false || doc["error"] != statusUnavailable {
+		t.Errorf("Must report the error without its details, got %d %v", code, doc)
+	}
+}
+
+func Test_Handler_concurrent(t *testing.T) {
+	migrations := []Migration{
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+	}
+	d := New(&dummyDriver{}, migrations)
+	handler := Handler(&d)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/migrations", nil))
+		}()
+	}
+	wg.Wait()
+
+	if migrations[0].Version != 2 {
+		t.Errorf("Must not sort the migrations of d, got %#v", migrations)
+	}
+}
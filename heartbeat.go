@@ -0,0 +1,135 @@
+package darwin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// HeartbeatFunc is called periodically while a migration executes, with the
+// time elapsed since it started. With WithSingleTransaction, m is the zero
+// Migration, the heartbeat covering the whole transaction.
+type HeartbeatFunc func(ctx context.Context, m Migration, elapsed time.Duration)
+
+// LeaseRefresher is implemented by the DistributedLocks expiring unless
+// refreshed, like a lease. Migrate refreshes them with the heartbeat.
+type LeaseRefresher interface {
+	Refresh(ctx context.Context) error
+}
+
+// LockRefresher is implemented by the Lockers of drivers whose lock expires,
+// like the redis driver. Migrate refreshes it with the heartbeat.
+type LockRefresher interface {
+	RefreshLock() error
+}
+
+// WithHeartbeat makes Migrate call f every interval while a migration
+// executes, so orchestrators can tell a long backfill from a hung one, and
+// refresh the migration lock when it expires for as long as it is held,
+// backup, validation and seeds included. A nil f only refreshes the lock.
+// When a refresh fails, the lock may be taken by another process: Migrate
+// cancels the context of the migration executing, applies no other, and
+// returns the error of the refresh.
+func WithHeartbeat(interval time.Duration, f HeartbeatFunc) Option {
+	return func(d *Darwin) {
+		d.heartbeatInterval = interval
+		d.heartbeatFunc = f
+	}
+}
+
+// Refresh refreshes the lock of the Locker, if it is a LockRefresher.
+func (l lockerLock) Refresh(ctx context.Context) error {
+	if r, ok := l.l.(LockRefresher); ok {
+		return r.RefreshLock()
+	}
+	return nil
+}
+
+// beat is the migration executing, for the heartbeat function.
+type beat struct {
+	mu      sync.Mutex
+	m       Migration
+	start   time.Time
+	running bool
+}
+
+// started sets the migration executing.
+func (b *beat) started(m Migration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.m, b.start, b.running = m, time.Now(), true
+}
+
+// finished clears the migration executing.
+func (b *beat) finished() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.running = false
+}
+
+// current returns the migration executing and the time elapsed since it
+// started.
+func (b *beat) current() (Migration, time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.m, time.Since(b.start), b.running
+}
+
+// heartbeat refreshes the lock every interval until the returned function
+// is called, and calls the heartbeat function while a migration started
+// with the beat executes. The returned context is canceled when a refresh
+// fails, stop then returning the error of the refresh.
+func (d Darwin) heartbeat(ctx context.Context, lock DistributedLock) (hctx context.Context, b *beat, stop func() error) {
+	b = &beat{}
+	if d.heartbeatInterval <= 0 {
+		return ctx, b, func() error { return nil }
+	}
+
+	var (
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+		lost error
+	)
+	hctx, cancel := context.WithCancel(ctx)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(d.heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			m, elapsed, running := b.current()
+
+			if r, ok := lock.(LeaseRefresher); ok {
+				if err := r.Refresh(ctx); err != nil {
+					d.logger().Error("darwin: lock refresh failed", "error", err, "version", m.Version)
+					lost = fmt.Errorf("darwin: the migration lock was lost: %w", err)
+					cancel()
+					return
+				}
+			}
+
+			if running && d.heartbeatFunc != nil {
+				d.heartbeatFunc(ctx, m, elapsed)
+			}
+		}
+	}()
+
+	return hctx, b, func() error {
+		close(done)
+		wg.Wait()
+		cancel()
+		return lost
+	}
+}
@@ -0,0 +1,126 @@
+package darwin
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type leaseLock struct {
+	mu        sync.Mutex
+	refreshed int
+	err       error
+}
+
+func (l *leaseLock) Lock(ctx context.Context) error   { return nil }
+func (l *leaseLock) Unlock(ctx context.Context) error { return nil }
+
+func (l *leaseLock) Refresh(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refreshed++
+	return l.err
+}
+
+type slowDriver struct {
+	dummyDriver
+}
+
+func (s *slowDriver) Exec(script string) (time.Duration, error) {
+	time.Sleep(30 * time.Millisecond)
+	return s.dummyDriver.Exec(script)
+}
+
+func Test_WithHeartbeat(t *testing.T) {
+	lock := &leaseLock{}
+
+	var (
+		mu      sync.Mutex
+		elapsed []time.Duration
+	)
+	beat := func(ctx context.Context, m Migration, d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		if m.Version != 1 {
+			t.Errorf("Must report the running migration, got %#v", m)
+		}
+		elapsed = append(elapsed, d)
+	}
+
+	migrations := []Migration{
+		{Version: 1, Description: "Backfill", Script: "UPDATE users SET active = true;"},
+	}
+
+	d := New(&slowDriver{}, migrations, WithLock(lock), WithHeartbeat(5*time.Millisecond, beat))
+	if err := d.Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	count := len(elapsed)
+	if count == 0 || lock.refreshed < count {
+		t.Fatalf("Must beat and refresh the lock while the migration runs, got %d beats and %d refreshes", count, lock.refreshed)
+	}
+
+	if elapsed[count-1] < elapsed[0] {
+		t.Errorf("Must report the elapsed time, got %v", elapsed)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if len(elapsed) != count {
+		t.Errorf("Must stop beating once the migration is executed")
+	}
+}
+
+func Test_WithHeartbeat_backup(t *testing.T) {
+	lock := &leaseLock{}
+
+	beats := 0
+	beat := func(ctx context.Context, m Migration, d time.Duration) {
+		beats++
+	}
+
+	backup := func(ctx context.Context, planned []Migration) error {
+		time.Sleep(30 * time.Millisecond)
+		return nil
+	}
+
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+	}
+
+	d := New(&dummyDriver{}, migrations, WithLock(lock), WithBackup(backup), WithHeartbeat(5*time.Millisecond, beat))
+	if err := d.Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	lock.mu.Lock()
+	defer lock.mu.Unlock()
+	if lock.refreshed == 0 {
+		t.Errorf("Must refresh the lock during the backup")
+	}
+	if beats != 0 {
+		t.Errorf("Must only beat while a migration executes, got %d beats", beats)
+	}
+}
+
+func Test_WithHeartbeat_lock_lost(t *testing.T) {
+	lost := errors.New("lease expired")
+	lock := &leaseLock{err: lost}
+
+	migrations := []Migration{
+		{Version: 1, Description: "Backfill", Script: "UPDATE users SET active = true;"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+
+	driver := &slowDriver{}
+	d := New(driver, migrations, WithLock(lock), WithHeartbeat(5*time.Millisecond, nil))
+	if err := d.Migrate(); !errors.Is(err, lost) {
+		t.Fatalf("Must return the error of the refresh, got %v", err)
+	}
+
+	if len(driver.records) != 1 {
+		t.Errorf("Must not apply a migration once the lock is lost, got %d records", len(driver.records))
+	}
+}
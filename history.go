@@ -0,0 +1,210 @@
+package darwin
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ExportFormat is a format of ExportHistory.
+type ExportFormat string
+
+// Formats of ExportHistory.
+const (
+	FormatCSV  ExportFormat = "csv"
+	FormatJSON ExportFormat = "json"
+)
+
+// recordJSON is the JSON document of a MigrationRecord.
+type recordJSON struct {
+	Version         float64   `json:"version"`
+	Description     string    `json:"description"`
+	Checksum        string    `json:"checksum"`
+	AppliedAt       time.Time `json:"applied_at"`
+	ExecutionTimeMS int64     `json:"execution_time_ms"`
+	AppliedBy       string    `json:"applied_by,omitempty"`
+	Hostname        string    `json:"hostname,omitempty"`
+	AppVersion      string    `json:"app_version,omitempty"`
+	Source          string    `json:"source,omitempty"`
+}
+
+func newRecordJSON(r MigrationRecord) recordJSON {
+	return recordJSON{
+		Version:         r.Version,
+		Description:     r.Description,
+		Checksum:        r.Checksum,
+		AppliedAt:       r.AppliedAt.UTC(),
+		ExecutionTimeMS: r.ExecutionTime.Milliseconds(),
+		AppliedBy:       r.AppliedBy,
+		Hostname:        r.Hostname,
+		AppVersion:      r.AppVersion,
+		Source:          r.Source,
+	}
+}
+
+// historyColumns are the columns of the CSV of ExportHistory.
+var historyColumns = []string{"version", "description", "checksum", "applied_at", "execution_time_ms", "applied_by", "hostname", "app_version", "source"}
+
+// ExportHistory writes the history of the migrations applied with the
+// driver to w, ordered by version, for archiving and compliance reports.
+func ExportHistory(d Driver, w io.Writer, format ExportFormat) error {
+	records, err := d.All()
+	if err != nil {
+		return err
+	}
+
+	return writeHistory(records, w, format)
+}
+
+// writeHistory writes the records to w in the format, ordered by version.
+func writeHistory(records []MigrationRecord, w io.Writer, format ExportFormat) error {
+	sort.Sort(byMigrationRecordVersion(records))
+
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write(historyColumns)
+		for _, r := range records {
+			cw.Write([]string{
+				strconv.FormatFloat(r.Version, 'f', -1, 64),
+				r.Description,
+				r.Checksum,
+				r.AppliedAt.UTC().Format(time.RFC3339Nano),
+				strconv.FormatInt(r.ExecutionTime.Milliseconds(), 10),
+				r.AppliedBy,
+				r.Hostname,
+				r.AppVersion,
+				r.Source,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case FormatJSON:
+		docs := []recordJSON{}
+		for _, r := range records {
+			docs = append(docs, newRecordJSON(r))
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(docs)
+	}
+
+	return fmt.Errorf("darwin: unknown export format %q", format)
+}
+
+// HistoryDiff is the difference between the histories of two databases.
+type HistoryDiff struct {
+	// OnlyInA and OnlyInB are the migrations applied to one database only,
+	// ordered by version.
+	OnlyInA []MigrationRecord
+	OnlyInB []MigrationRecord
+
+	// ChecksumMismatches are the migrations applied to both databases with
+	// different scripts.
+	ChecksumMismatches []ChecksumMismatch
+
+	// OutOfOrder are the versions applied to both databases in a different
+	// order relative to the others, like a hotfix applied before a later
+	// version in one database only.
+	OutOfOrder []float64
+}
+
+// ChecksumMismatch is a migration applied to two databases with different
+// checksums.
+type ChecksumMismatch struct {
+	Version   float64
+	ChecksumA string
+	ChecksumB string
+}
+
+// Equal reports whether the histories are the same.
+func (h HistoryDiff) Equal() bool {
+	return len(h.OnlyInA) == 0 && len(h.OnlyInB) == 0 && len(h.ChecksumMismatches) == 0 && len(h.OutOfOrder) == 0
+}
+
+// Ahead reports whether database a applied all the migrations of b, the
+// same way, and more.
+func (h HistoryDiff) Ahead() bool {
+	return len(h.OnlyInA) > 0 && len(h.OnlyInB) == 0 && len(h.ChecksumMismatches) == 0
+}
+
+// CompareHistories compares the histories of the databases of the drivers,
+// for example to tell if staging is ahead of production.
+func CompareHistories(a, b Driver) (HistoryDiff, error) {
+	var diff HistoryDiff
+
+	recordsA, err := a.All()
+	if err != nil {
+		return diff, err
+	}
+
+	recordsB, err := b.All()
+	if err != nil {
+		return diff, err
+	}
+
+	sort.Sort(byMigrationRecordVersion(recordsA))
+	sort.Sort(byMigrationRecordVersion(recordsB))
+
+	inA := map[float64]MigrationRecord{}
+	for _, r := range recordsA {
+		inA[r.Version] = r
+	}
+
+	inB := map[float64]MigrationRecord{}
+	for _, r := range recordsB {
+		inB[r.Version] = r
+	}
+
+	var commonA, commonB []MigrationRecord
+
+	for _, r := range recordsA {
+		other, ok := inB[r.Version]
+		if !ok {
+			diff.OnlyInA = append(diff.OnlyInA, r)
+			continue
+		}
+
+		commonA = append(commonA, r)
+		if r.Checksum != other.Checksum {
+			diff.ChecksumMismatches = append(diff.ChecksumMismatches, ChecksumMismatch{
+				Version:   r.Version,
+				ChecksumA: r.Checksum,
+				ChecksumB: other.Checksum,
+			})
+		}
+	}
+
+	for _, r := range recordsB {
+		if _, ok := inA[r.Version]; !ok {
+			diff.OnlyInB = append(diff.OnlyInB, r)
+			continue
+		}
+		commonB = append(commonB, r)
+	}
+
+	// The versions applied to both databases, in the order of application.
+	sortByAppliedAt(commonA)
+	sortByAppliedAt(commonB)
+
+	for i := range commonA {
+		if commonA[i].Version != commonB[i].Version {
+			diff.OutOfOrder = append(diff.OutOfOrder, commonA[i].Version)
+		}
+	}
+	sort.Float64s(diff.OutOfOrder)
+
+	return diff, nil
+}
+
+// sortByAppliedAt sorts the records by time of application, then version.
+func sortByAppliedAt(records []MigrationRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].AppliedAt.Before(records[j].AppliedAt)
+	})
+}
@@ -0,0 +1,97 @@
+package darwin
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func history() []MigrationRecord {
+	return []MigrationRecord{
+		{Version: 2, Description: "Roles, and grants", Checksum: "b", AppliedAt: time.Date(2021, 3, 4, 5, 6, 8, 0, time.UTC), ExecutionTime: 20 * time.Millisecond, RecordMetadata: RecordMetadata{AppliedBy: "deploy", Hostname: "ci-1", AppVersion: "1.4.0", Source: "4f2a9c1"}},
+		{Version: 1, Description: "Users", Checksum: "a", AppliedAt: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC), ExecutionTime: 1500 * time.Millisecond},
+	}
+}
+
+func Test_ExportHistory_csv(t *testing.T) {
+	var buf bytes.Buffer
+	if err := New(&dummyDriver{records: history()}, nil).ExportHistory(&buf, FormatCSV); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := `version,description,checksum,applied_at,execution_time_ms,applied_by,hostname,app_version,source
+1,Users,a,2021-03-04T05:06:07Z,1500,,,,
+2,"Roles, and grants",b,2021-03-04T05:06:08Z,20,deploy,ci-1,1.4.0,4f2a9c1
+`
+	if buf.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func Test_ExportHistory_json(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportHistory(&dummyDriver{records: history()[1:]}, &buf, FormatJSON); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := `[
+  {
+    "version": 1,
+    "description": "Users",
+    "checksum": "a",
+    "applied_at": "2021-03-04T05:06:07Z",
+    "execution_time_ms": 1500
+  }
+]
+`
+	if buf.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	if err := ExportHistory(&dummyDriver{}, &buf, "xml"); err == nil {
+		t.Errorf("Must refuse an unknown format")
+	}
+}
+
+func Test_CompareHistories(t *testing.T) {
+	at := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	staging := []MigrationRecord{
+		{Version: 1, Checksum: "a", AppliedAt: at},
+		{Version: 2, Checksum: "b", AppliedAt: at.Add(time.Minute)},
+		{Version: 2.1, Checksum: "c", AppliedAt: at.Add(2 * time.Minute)},
+		{Version: 3, Checksum: "d", AppliedAt: at.Add(3 * time.Minute)},
+	}
+	production := []MigrationRecord{
+		{Version: 1, Checksum: "a", AppliedAt: at},
+		{Version: 2.1, Checksum: "c", AppliedAt: at.Add(time.Minute)},
+		{Version: 2, Checksum: "x", AppliedAt: at.Add(2 * time.Minute)},
+		{Version: 1.5, Checksum: "e", AppliedAt: at.Add(3 * time.Minute)},
+	}
+
+	diff, err := CompareHistories(&dummyDriver{records: staging}, &dummyDriver{records: production})
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(diff.OnlyInA) != 1 || diff.OnlyInA[0].Version != 3 || len(diff.OnlyInB) != 1 || diff.OnlyInB[0].Version != 1.5 {
+		t.Errorf("Must report the migrations applied to one database, got %#v and %#v", diff.OnlyInA, diff.OnlyInB)
+	}
+
+	if !reflect.DeepEqual(diff.ChecksumMismatches, []ChecksumMismatch{{Version: 2, ChecksumA: "b", ChecksumB: "x"}}) {
+		t.Errorf("Must report the checksum mismatches, got %#v", diff.ChecksumMismatches)
+	}
+
+	if !reflect.DeepEqual(diff.OutOfOrder, []float64{2, 2.1}) {
+		t.Errorf("Must report the ordering differences, got %v", diff.OutOfOrder)
+	}
+
+	if diff.Equal() || diff.Ahead() {
+		t.Errorf("Must not be equal or ahead, got %#v", diff)
+	}
+
+	diff, _ = CompareHistories(&dummyDriver{records: staging}, &dummyDriver{records: staging[:2]})
+	if !diff.Ahead() || diff.Equal() {
+		t.Errorf("Must be ahead, got %#v", diff)
+	}
+}
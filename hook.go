@@ -0,0 +1,80 @@
+package darwin
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithBeforeEach makes Migrate call f before executing each migration. An
+// error stops Migrate before the migration is executed. With
+// WithSingleTransaction, f is called for all the migrations before the
+// transaction begins.
+func WithBeforeEach(f func(Migration) error) Option {
+	return func(d *Darwin) {
+		d.beforeEach = f
+	}
+}
+
+// WithAfterEach makes Migrate call f after each migration is executed and
+// recorded, to emit audit events or warm caches after a schema change. An
+// error stops Migrate, the migration staying applied. With
+// WithSingleTransaction, f is called once the transaction is committed.
+func WithAfterEach(f func(Migration, Result) error) Option {
+	return func(d *Darwin) {
+		d.afterEach = f
+	}
+}
+
+// ConfirmFunc is asked if a migration is executed. Declining it stops
+// Migrate without error, the migration and the following ones staying
+// pending since migrations are applied in order.
+type ConfirmFunc func(ctx context.Context, m Migration) (bool, error)
+
+// WithConfirmation makes Migrate ask f before executing each migration, and
+// before calling the before hook, for interactive tools. An error stops
+// Migrate. With WithSingleTransaction, f is asked for all the migrations
+// before the transaction begins, those preceding the first declined one
+// being executed.
+func WithConfirmation(f ConfirmFunc) Option {
+	return func(d *Darwin) {
+		d.confirmation = f
+	}
+}
+
+// confirm asks the confirmation hook if the migration is executed.
+func (d Darwin) confirm(ctx context.Context, m Migration) (bool, error) {
+	if d.confirmation == nil {
+		return true, nil
+	}
+
+	ok, err := d.confirmation(ctx, m)
+	if err != nil {
+		return false, fmt.Errorf("darwin: confirm migration %f: %w", m.Version, err)
+	}
+	if !ok {
+		d.logger().Info("darwin: migration declined", "version", m.Version, "description", m.Description)
+	}
+	return ok, nil
+}
+
+// before calls the before hook with the migration.
+func (d Darwin) before(m Migration) error {
+	if d.beforeEach == nil {
+		return nil
+	}
+	if err := d.beforeEach(m); err != nil {
+		return fmt.Errorf("darwin: before migration %f: %w", m.Version, err)
+	}
+	return nil
+}
+
+// after calls the after hook with the result of a migration.
+func (d Darwin) after(r Result) error {
+	if d.afterEach == nil {
+		return nil
+	}
+	if err := d.afterEach(r.Migration, r); err != nil {
+		return fmt.Errorf("darwin: after migration %f: %w", r.Migration.Version, err)
+	}
+	return nil
+}
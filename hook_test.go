@@ -0,0 +1,119 @@
+package darwin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func Test_WithBeforeEach_WithAfterEach(t *testing.T) {
+	var calls []string
+	before := func(m Migration) error {
+		calls = append(calls, fmt.Sprintf("before %g", m.Version))
+		return nil
+	}
+	after := func(m Migration, r Result) error {
+		calls = append(calls, fmt.Sprintf("after %g", r.Migration.Version))
+		return nil
+	}
+
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+
+	if err := New(&dummyDriver{}, migrations, WithBeforeEach(before), WithAfterEach(after)).Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := []string{"before 1", "after 1", "before 2", "after 2"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected %q, got %q", expected, calls)
+	}
+}
+
+func Test_WithBeforeEach_error(t *testing.T) {
+	boom := errors.New("boom")
+	before := func(m Migration) error {
+		if m.Version == 2 {
+			return boom
+		}
+		return nil
+	}
+
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+
+	driver := &dummyDriver{}
+	err := New(driver, migrations, WithBeforeEach(before)).Migrate()
+	if !errors.Is(err, boom) {
+		t.Fatalf("Must return the error of the hook, got %v", err)
+	}
+
+	if all, _ := driver.All(); len(all) != 1 {
+		t.Errorf("Must stop before the second migration, got %#v", all)
+	}
+}
+
+func Test_WithAfterEach_single_transaction(t *testing.T) {
+	driver := &txDriver{}
+	after := func(m Migration, r Result) error {
+		driver.calls = append(driver.calls, fmt.Sprintf("after %g", m.Version))
+		return nil
+	}
+
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+
+	if err := New(driver, migrations, WithSingleTransaction(), WithAfterEach(after)).Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if fmt.Sprint(driver.calls) != "[begin tx.exec tx.insert tx.exec tx.insert commit after 1 after 2]" {
+		t.Errorf("Must call the hook once committed, got %v", driver.calls)
+	}
+}
+
+func Test_WithConfirmation(t *testing.T) {
+	confirm := func(ctx context.Context, m Migration) (bool, error) {
+		return m.Version < 2, nil
+	}
+
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+		{Version: 3, Description: "Teams", Script: "CREATE TABLE teams (id INT);"},
+	}
+
+	driver := &dummyDriver{}
+	if err := New(driver, migrations, WithConfirmation(confirm)).Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if all, _ := driver.All(); len(all) != 1 || all[0].Version != 1 {
+		t.Errorf("Must stop before the declined migration, got %#v", all)
+	}
+
+	tx := &txDriver{}
+	if err := New(tx, migrations, WithSingleTransaction(), WithConfirmation(confirm)).Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if fmt.Sprint(tx.calls) != "[begin tx.exec tx.insert commit]" {
+		t.Errorf("Must execute the migrations confirmed in the transaction, got %v", tx.calls)
+	}
+
+	boom := errors.New("boom")
+	fail := func(ctx context.Context, m Migration) (bool, error) {
+		return false, boom
+	}
+	if err := New(&dummyDriver{}, migrations, WithConfirmation(fail)).Migrate(); !errors.Is(err, boom) {
+		t.Errorf("Must return the error of the hook, got %v", err)
+	}
+}
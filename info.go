@@ -0,0 +1,152 @@
+package darwin
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+)
+
+// infoJSON is the JSON document of a MigrationInfo.
+type infoJSON struct {
+	Version     float64    `json:"version"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+	Checksum    string     `json:"checksum"`
+	Error       string     `json:"error,omitempty"`
+
+	StoredChecksum  string `json:"stored_checksum,omitempty"`
+	ExecutionTimeMS *int64 `json:"execution_time_ms,omitempty"`
+	AppliedBy       string `json:"applied_by,omitempty"`
+	Hostname        string `json:"hostname,omitempty"`
+	AppVersion      string `json:"app_version,omitempty"`
+	Source          string `json:"source,omitempty"`
+}
+
+// MarshalJSON returns the status of the migration as a JSON object with
+// its version, description, status, applied_at time if applied, checksum
+// and error if any. An applied migration also has the stored_checksum and
+// execution_time_ms of its record, and its applied_by, hostname,
+// app_version and source when recorded.
+func (i MigrationInfo) MarshalJSON() ([]byte, error) {
+	doc := infoJSON{
+		Version:     i.Migration.Version,
+		Description: i.Migration.Description,
+		Status:      i.Status.String(),
+		Checksum:    i.Migration.Checksum(),
+	}
+
+	if !i.AppliedAt.IsZero() {
+		at := i.AppliedAt.UTC()
+		doc.AppliedAt = &at
+	}
+
+	if i.Error != nil {
+		doc.Error = i.Error.Error()
+	}
+
+	if i.Record != nil {
+		ms := i.ExecutionTime.Milliseconds()
+		doc.StoredChecksum = i.StoredChecksum
+		doc.ExecutionTimeMS = &ms
+		doc.AppliedBy = i.Record.AppliedBy
+		doc.Hostname = i.Record.Hostname
+		doc.AppVersion = i.Record.AppVersion
+		doc.Source = i.Record.Source
+	}
+
+	return json.Marshal(doc)
+}
+
+// WriteInfoJSON writes the status of the migrations to w as an indented JSON
+// array, for CI pipelines.
+func WriteInfoJSON(w io.Writer, infos []MigrationInfo) error {
+	if infos == nil {
+		infos = []MigrationInfo{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(infos)
+}
+
+// InfoFilter selects the migrations kept by FilterInfo.
+type InfoFilter func(MigrationInfo) bool
+
+// ByStatus keeps the migrations with one of the statuses.
+func ByStatus(statuses ...Status) InfoFilter {
+	return func(i MigrationInfo) bool {
+		for _, s := range statuses {
+			if i.Status == s {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ByVersion keeps the migrations with a version between min and max,
+// included.
+func ByVersion(min, max float64) InfoFilter {
+	return func(i MigrationInfo) bool {
+		return i.Migration.Version >= min && i.Migration.Version <= max
+	}
+}
+
+// SlowerThan keeps the applied migrations which took longer than d to
+// execute, to find the slow migrations of the history.
+func SlowerThan(d time.Duration) InfoFilter {
+	return func(i MigrationInfo) bool {
+		return i.Record != nil && i.ExecutionTime > d
+	}
+}
+
+// FilterInfo returns the migrations kept by all the filters, in order:
+//
+//	pending := darwin.FilterInfo(infos, darwin.ByStatus(darwin.Pending, darwin.Error))
+func FilterInfo(infos []MigrationInfo, filters ...InfoFilter) []MigrationInfo {
+	kept := []MigrationInfo{}
+
+	for _, i := range infos {
+		keep := true
+		for _, f := range filters {
+			if !f(i) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			kept = append(kept, i)
+		}
+	}
+
+	return kept
+}
+
+// SortInfoDescending sorts the migrations from the newest version to the
+// oldest.
+func SortInfoDescending(infos []MigrationInfo) {
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].Migration.Version > infos[j].Migration.Version
+	})
+}
+
+// SortInfoBySlowest sorts the migrations from the slowest to execute to the
+// fastest, those not applied last.
+func SortInfoBySlowest(infos []MigrationInfo) {
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].ExecutionTime > infos[j].ExecutionTime
+	})
+}
+
+// LatestApplied returns the record of the newest migration applied, if any.
+func LatestApplied(d Driver) (MigrationRecord, bool, error) {
+	records, err := d.All()
+	if err != nil || len(records) == 0 {
+		return MigrationRecord{}, false, err
+	}
+
+	sort.Sort(sort.Reverse(byMigrationRecordVersion(records)))
+	return records[0], true, nil
+}
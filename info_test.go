@@ -0,0 +1,157 @@
+package darwin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_MigrationInfo_MarshalJSON(t *testing.T) {
+	m := Migration{Version: 1.1, Description: "Users", Script: "CREATE TABLE users (id INT);"}
+	at := time.Date(2021, 3, 4, 5, 6, 7, 0, time.FixedZone("CET", 3600))
+
+	b, err := json.Marshal(MigrationInfo{Status: Applied, Migration: m, AppliedAt: at})
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := `{"version":1.1,"description":"Users","status":"APPLIED","applied_at":"2021-03-04T04:06:07Z","checksum":"` + m.Checksum() + `"}`
+	if string(b) != expected {
+		t.Errorf("Expected %s, got %s", expected, b)
+	}
+
+	b, _ = json.Marshal(MigrationInfo{Status: Error, Migration: m, Error: errors.New("boom")})
+	expected = `{"version":1.1,"description":"Users","status":"ERROR","checksum":"` + m.Checksum() + `","error":"boom"}`
+	if string(b) != expected {
+		t.Errorf("Expected %s, got %s", expected, b)
+	}
+}
+
+func Test_WriteInfoJSON(t *testing.T) {
+	at := time.Now()
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+	records := []MigrationRecord{{Version: 1, Checksum: migrations[0].Checksum(), AppliedAt: at}}
+
+	infos, err := Info(&dummyDriver{records: records}, migrations)
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteInfoJSON(&buf, infos); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	var docs []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &docs); err != nil {
+		t.Fatalf("Must write a JSON array, got %s", err)
+	}
+
+	if len(docs) != 2 || docs[0]["status"] != "APPLIED" || docs[0]["applied_at"] == nil || docs[1]["status"] != "PENDING" || docs[1]["applied_at"] != nil {
+		t.Errorf("Must write the status of each migration, got %v", docs)
+	}
+
+	buf.Reset()
+	WriteInfoJSON(&buf, nil)
+	if buf.String() != "[]\n" {
+		t.Errorf("Must write an empty array, got %q", buf.String())
+	}
+}
+
+func Test_FilterInfo(t *testing.T) {
+	infos := []MigrationInfo{
+		{Status: Applied, Migration: Migration{Version: 1}},
+		{Status: Applied, Migration: Migration{Version: 2}},
+		{Status: Pending, Migration: Migration{Version: 3}},
+		{Status: Error, Migration: Migration{Version: 4}},
+	}
+
+	versions := func(infos []MigrationInfo) []float64 {
+		v := []float64{}
+		for _, i := range infos {
+			v = append(v, i.Migration.Version)
+		}
+		return v
+	}
+
+	if got := versions(FilterInfo(infos, ByStatus(Pending, Error))); !reflect.DeepEqual(got, []float64{3, 4}) {
+		t.Errorf("Must keep the pending and failed migrations, got %v", got)
+	}
+
+	if got := versions(FilterInfo(infos, ByStatus(Applied, Pending), ByVersion(2, 4))); !reflect.DeepEqual(got, []float64{2, 3}) {
+		t.Errorf("Must apply all the filters, got %v", got)
+	}
+
+	SortInfoDescending(infos)
+	if got := versions(infos); !reflect.DeepEqual(got, []float64{4, 3, 2, 1}) {
+		t.Errorf("Must sort from the newest version, got %v", got)
+	}
+}
+
+func Test_SlowerThan(t *testing.T) {
+	infos := []MigrationInfo{
+		{Status: Applied, Migration: Migration{Version: 1}, ExecutionTime: time.Second, Record: &MigrationRecord{}},
+		{Status: Applied, Migration: Migration{Version: 2}, ExecutionTime: time.Minute, Record: &MigrationRecord{}},
+		{Status: Applied, Migration: Migration{Version: 3}, ExecutionTime: 3 * time.Minute, Record: &MigrationRecord{}},
+		{Status: Pending, Migration: Migration{Version: 4}},
+	}
+
+	slow := FilterInfo(infos, SlowerThan(30*time.Second))
+	SortInfoBySlowest(slow)
+
+	if len(slow) != 2 || slow[0].Migration.Version != 3 || slow[1].Migration.Version != 2 {
+		t.Errorf("Must keep the slow migrations, the slowest first, got %#v", slow)
+	}
+}
+
+func Test_LatestApplied(t *testing.T) {
+	if _, ok, err := New(&dummyDriver{}, nil).LatestApplied(); ok || err != nil {
+		t.Errorf("Must not find a record, got %t %v", ok, err)
+	}
+
+	records := []MigrationRecord{{Version: 2}, {Version: 10}, {Version: 3}}
+	r, ok, err := New(&dummyDriver{records: records}, nil).LatestApplied()
+	if !ok || err != nil || r.Version != 10 {
+		t.Errorf("Must return the newest record, got %#v %t %v", r, ok, err)
+	}
+
+	if _, _, err := LatestApplied(&dummyDriver{AllError: true}); err == nil {
+		t.Errorf("Must return the error of All")
+	}
+}
+
+func Test_Info_record(t *testing.T) {
+	at := time.Now()
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+	records := []MigrationRecord{{Version: 1, Checksum: "edited", AppliedAt: at, ExecutionTime: 2 * time.Second}}
+
+	infos, err := Info(&dummyDriver{records: records}, migrations)
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	i := infos[0]
+	if i.Record == nil || i.Record.Version != 1 || i.StoredChecksum != "edited" || !i.AppliedAt.Equal(at) || i.ExecutionTime != 2*time.Second {
+		t.Errorf("Must include the record of the applied migration, got %#v", i)
+	}
+
+	if infos[1].Record != nil || infos[1].StoredChecksum != "" {
+		t.Errorf("Must not include a record for the pending migration, got %#v", infos[1])
+	}
+
+	b, _ := json.Marshal(i)
+	var doc map[string]interface{}
+	json.Unmarshal(b, &doc)
+	if doc["stored_checksum"] != "edited" || doc["execution_time_ms"] != 2000.0 {
+		t.Errorf("Must marshal the record, got %s", b)
+	}
+}
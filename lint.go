@@ -0,0 +1,602 @@
+package darwin
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Finding is a problem found in a migration by Lint.
+type Finding struct {
+	// File is the file of the migration, empty for the migrations which
+	// don't come from files.
+	File string
+
+	// Version is the version of the migration the rule found the problem
+	// in.
+	Version float64
+
+	// Line is the 1-based line of the problem in the file, the line of the
+	// version header for the problems of a whole migration.
+	Line int
+
+	Rule    string
+	Message string
+}
+
+func (f Finding) String() string {
+	if f.File == "" {
+		return fmt.Sprintf("version %s:%d: %s: %s", formatVersion(f.Version), f.Line, f.Rule, f.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s", f.File, f.Line, f.Rule, f.Message)
+}
+
+// LintRule is a rule Lint checks the migrations against. Check reports the
+// findings of a migration with the line in its script, 0 for the whole
+// migration; Lint sets their file, line in the file and rule.
+type LintRule struct {
+	Name  string
+	Check func(m Migration) []Finding
+
+	// History, when set in place of Check, checks a migration knowing the
+	// migrations of lower versions, in order, for the rules comparing it
+	// with the schema they leave.
+	History func(m Migration, before []Migration) []Finding
+}
+
+// check returns the findings of the rule for the migration m of all.
+func (r LintRule) check(m Migration, all []Migration) []Finding {
+	if r.History == nil {
+		return r.Check(m)
+	}
+
+	var before []Migration
+	for _, b := range sortedMigrations(all) {
+		if b.Version < m.Version {
+			before = append(before, b)
+		}
+	}
+	return r.History(m, before)
+}
+
+// Rules of the validation of Lint, which can't be disabled.
+const (
+	RuleParse     = "parse"
+	RuleVersion   = "version"
+	RuleDuplicate = "duplicate"
+	RuleCopy      = "copy"
+	RuleDown      = "down"
+)
+
+// destructiveStatement matches a statement losing data.
+var destructiveStatement = regexp.MustCompile(`(?i)\b(DROP\s+(TABLE|COLUMN|SCHEMA|DATABASE)|TRUNCATE)\b`)
+
+// alterType matches the change of the type of a column, the table, the
+// column of ALTER COLUMN or MODIFY and the type being its groups.
+var alterType = regexp.MustCompile(`(?is)\bALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?(\S+)\s+(?:ALTER\s+(?:COLUMN\s+)?(\S+)\s+(?:SET\s+DATA\s+)?TYPE|MODIFY\s+(?:COLUMN\s+)?(\S+))\s+(\w+(?:\s*\([^)]*\))?)`)
+
+// addColumn matches the addition of a column, the table, the column and its
+// type being its groups.
+var addColumn = regexp.MustCompile(`(?is)\bALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?(\S+)\s+ADD\s+(?:COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(\S+)\s+(\w+(?:\s*\([^)]*\))?)`)
+
+// createTable matches the creation of a table, the table and its
+// definitions being its groups.
+var createTable = regexp.MustCompile(`(?is)^(?:\s*(?:--[^\n]*\n|/\*.*?\*/))*\s*CREATE\s+(?:\w+\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?([^\s(]+)\s*\((.*)\)`)
+
+// columnType matches the name and type of a column definition.
+var columnType = regexp.MustCompile(`^(\S+)\s+(\w+(?:\s*\([^)]*\))?)`)
+
+// typeSize is the family of a type and its size: in bytes for numbers, in
+// characters for text, -1 when unbounded, 0 when given by its length or
+// precision.
+type typeSize struct {
+	family string
+	size   int
+}
+
+// typeSizes are the sizes of the types compared by the narrowing rule.
+var typeSizes = map[string]typeSize{
+	"TINYINT":     {"int", 1},
+	"INT1":        {"int", 1},
+	"SMALLINT":    {"int", 2},
+	"INT2":        {"int", 2},
+	"SMALLSERIAL": {"int", 2},
+	"MEDIUMINT":   {"int", 3},
+	"INT":         {"int", 4},
+	"INTEGER":     {"int", 4},
+	"INT4":        {"int", 4},
+	"SERIAL":      {"int", 4},
+	"BIGINT":      {"int", 8},
+	"INT8":        {"int", 8},
+	"BIGSERIAL":   {"int", 8},
+	"REAL":        {"float", 4},
+	"FLOAT4":      {"float", 4},
+	"DOUBLE":      {"float", 8},
+	"FLOAT8":      {"float", 8},
+	"FLOAT":       {"float", 8},
+	"CHAR":        {"text", 0},
+	"CHARACTER":   {"text", 0},
+	"VARCHAR":     {"text", 0},
+	"NCHAR":       {"text", 0},
+	"NVARCHAR":    {"text", 0},
+	"TINYTEXT":    {"text", 255},
+	"TEXT":        {"text", -1},
+	"MEDIUMTEXT":  {"text", -1},
+	"LONGTEXT":    {"text", -1},
+	"DECIMAL":     {"decimal", 0},
+	"NUMERIC":     {"decimal", 0},
+}
+
+// createIndex matches the creation of an index, the CONCURRENTLY keyword
+// and the table being its groups.
+var createIndex = regexp.MustCompile(`(?i)\bCREATE\s+(?:UNIQUE\s+)?INDEX\s+(CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(?:\S+\s+)?ON\s+(?:ONLY\s+)?([^\s(]+)`)
+
+// largeTableDirective declares a table large enough for the migration to
+// create its indexes concurrently, for the large-table-index rule:
+//
+//	-- darwin:large-table orders
+const largeTableDirective = "large-table"
+
+// scriptLines calls f with each line of the script which isn't a comment
+// and its 1-based number.
+func scriptLines(script string, f func(n int, code string)) {
+	for i, line := range strings.Split(script, "\n") {
+		code := strings.TrimSpace(line)
+		if code == "" || strings.HasPrefix(code, "--") {
+			continue
+		}
+		f(i+1, code)
+	}
+}
+
+// LargeTableIndexes returns the large-table-index rule: the indexes of the
+// tables, and of those declared with the large-table directive, must be
+// created concurrently, since creating them locks the writes to the table
+// until the index is built. DefaultLintRules checks the tables declared
+// with the directive, replace its rule to give the tables.
+func LargeTableIndexes(tables ...string) LintRule {
+	return LintRule{Name: "large-table-index", Check: func(m Migration) []Finding {
+		large := map[string]bool{}
+		for _, t := range tables {
+			large[tableName(t)] = true
+		}
+		for _, d := range Directives(m.Script) {
+			if d.Name == largeTableDirective {
+				for _, t := range strings.Fields(d.Args) {
+					large[tableName(t)] = true
+				}
+			}
+		}
+		if len(large) == 0 {
+			return nil
+		}
+
+		var findings []Finding
+		scriptLines(m.Script, func(n int, code string) {
+			match := createIndex.FindStringSubmatch(code)
+			if match == nil || match[1] != "" || !large[tableName(match[2])] {
+				return
+			}
+			findings = append(findings, Finding{Line: n, Message: fmt.Sprintf("the index of the large table %s must be created concurrently", tableName(match[2]))})
+		})
+		return findings
+	}}
+}
+
+// tableName returns the name of a table, without schema and quotes, in
+// lower case.
+func tableName(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.ToLower(strings.Trim(name, "\"`[];"))
+}
+
+// DestructiveRule is the destructive rule: the migrations don't drop or
+// truncate tables, columns, schemas or databases.
+var DestructiveRule = LintRule{Name: "destructive", Check: func(m Migration) []Finding {
+	var findings []Finding
+	for i, line := range strings.Split(m.Script, "\n") {
+		code := strings.TrimSpace(line)
+		if strings.HasPrefix(code, "--") {
+			continue
+		}
+		if match := destructiveStatement.FindString(code); match != "" {
+			findings = append(findings, Finding{Line: i + 1, Message: fmt.Sprintf("%s loses data", strings.ToUpper(strings.Join(strings.Fields(match), " ")))})
+		}
+	}
+	return findings
+}}
+
+// NarrowingRule is the narrowing rule: the migrations don't change the type
+// of a column to a smaller one of the same family, like BIGINT to INT or
+// VARCHAR(100) to VARCHAR(20), or from text to another family. The types of
+// the columns are those declared by the CREATE TABLE and ALTER TABLE
+// statements of the migrations; the changes of the columns declared
+// elsewhere aren't reported.
+var NarrowingRule = LintRule{Name: "narrowing", History: func(m Migration, before []Migration) []Finding {
+	columns := map[string]string{}
+	for _, b := range before {
+		declareColumns(columns, b.Script, nil)
+	}
+
+	var findings []Finding
+	declareColumns(columns, m.Script, func(line int, column, from, to string) {
+		if narrower(from, to) {
+			findings = append(findings, Finding{Line: line, Message: fmt.Sprintf("changing the type of %s from %s to %s may truncate its values", column, from, to)})
+		}
+	})
+	return findings
+}}
+
+// declareColumns records the types of the columns declared by the script,
+// by table.column, calling changed with the line of each change of the type
+// of a column whose type is known.
+func declareColumns(columns map[string]string, script string, changed func(line int, column, from, to string)) {
+	offset := 0
+	for _, stmt := range SplitStatements(script, StandardSyntax) {
+		start := strings.Index(script[offset:], stmt) + offset
+		offset = start + len(stmt)
+
+		if match := createTable.FindStringSubmatch(stmt); match != nil {
+			for _, def := range splitDefinitions(match[2]) {
+				if c := columnType.FindStringSubmatch(def); c != nil {
+					columns[columnKey(match[1], c[1])] = normalizeType(c[2])
+				}
+			}
+			continue
+		}
+
+		for _, match := range addColumn.FindAllStringSubmatch(stmt, -1) {
+			columns[columnKey(match[1], match[2])] = normalizeType(match[3])
+		}
+
+		for _, match := range alterType.FindAllStringSubmatchIndex(stmt, -1) {
+			table, column, to := stmt[match[2]:match[3]], "", normalizeType(stmt[match[8]:match[9]])
+			if match[4] >= 0 {
+				column = stmt[match[4]:match[5]]
+			} else {
+				column = stmt[match[6]:match[7]]
+			}
+
+			key := columnKey(table, column)
+			if from, ok := columns[key]; ok && changed != nil {
+				changed(strings.Count(script[:start+match[0]], "\n")+1, key, from, to)
+			}
+			columns[key] = to
+		}
+	}
+}
+
+// splitDefinitions splits the definitions of a CREATE TABLE statement on
+// the commas outside of parentheses.
+func splitDefinitions(defs string) []string {
+	var (
+		parts []string
+		depth int
+		start int
+	)
+	for i, c := range defs {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(defs[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(defs[start:]))
+}
+
+// columnKey returns the table.column key of a column.
+func columnKey(table, column string) string {
+	return tableName(table) + "." + tableName(column)
+}
+
+// normalizeType returns the type in upper case without spaces.
+func normalizeType(t string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(t), ""))
+}
+
+// narrower reports whether the type to can't hold all the values of the
+// type from: a smaller type, length or precision of the same family, or a
+// change from text to another family. The changes involving unknown types
+// aren't reported.
+func narrower(from, to string) bool {
+	fromName, fromArgs := splitType(from)
+	toName, toArgs := splitType(to)
+
+	f, ok := typeSizes[fromName]
+	if !ok {
+		return false
+	}
+	t, ok := typeSizes[toName]
+	if !ok {
+		return f.family == "text"
+	}
+
+	if f.family != t.family {
+		return f.family == "text"
+	}
+
+	fromSize, toSize := f.size, t.size
+	if fromSize == 0 && len(fromArgs) > 0 {
+		fromSize = fromArgs[0]
+	}
+	if toSize == 0 && len(toArgs) > 0 {
+		toSize = toArgs[0]
+	}
+
+	switch {
+	case fromSize == 0 || toSize == 0:
+		// A length or precision left to the default of the database.
+		return false
+	case toSize < 0:
+		return false
+	case fromSize < 0, toSize < fromSize:
+		return true
+	}
+
+	// The scale of decimals.
+	return f.family == "decimal" && len(fromArgs) > 1 && len(toArgs) > 1 && toArgs[1] < fromArgs[1]
+}
+
+// splitType returns the name of the type and its numeric arguments, like
+// VARCHAR and [20] for VARCHAR(20).
+func splitType(t string) (string, []int) {
+	i := strings.IndexByte(t, '(')
+	if i < 0 {
+		return t, nil
+	}
+
+	var args []int
+	for _, a := range strings.Split(strings.TrimSuffix(t[i+1:], ")"), ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(a)); err == nil {
+			args = append(args, n)
+		}
+	}
+	return t[:i], args
+}
+
+// DefaultLintRules are the rules of the darwin lint command: migrations
+// have a description, have statements, and don't drop or truncate tables,
+// columns, schemas or databases, which their down migrations may, don't
+// narrow the type of columns, and create the indexes of the tables declared
+// large with the large-table directive concurrently.
+var DefaultLintRules = []LintRule{
+	{Name: "description", Check: func(m Migration) []Finding {
+		if strings.TrimSpace(m.Description) == "" {
+			return []Finding{{Message: "the migration has no description"}}
+		}
+		return nil
+	}},
+	{Name: "empty", Check: func(m Migration) []Finding {
+		if len(SplitStatements(m.Script, StandardSyntax)) == 0 {
+			return []Finding{{Message: "the migration has no statement"}}
+		}
+		return nil
+	}},
+	DestructiveRule,
+	NarrowingRule,
+	LargeTableIndexes(),
+}
+
+// Lint checks the migrations against the rules, for the migrations which
+// don't come from files, like those of ParseMigrations; the findings have
+// the line of the problem in the script and are ordered by version and
+// line. FSSource.Lint checks migration files.
+func Lint(migrations []Migration, rules ...LintRule) []Finding {
+	var findings []Finding
+	for _, m := range migrations {
+		for _, rule := range rules {
+			for _, f := range rule.check(m, migrations) {
+				f.Version, f.Rule = m.Version, rule.Name
+				findings = append(findings, f)
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Version != findings[j].Version {
+			return findings[i].Version < findings[j].Version
+		}
+		return findings[i].Line < findings[j].Line
+	})
+
+	return findings
+}
+
+// linted is a migration of a file with the lines of its script in the file.
+type linted struct {
+	Migration
+	file   string
+	header int
+	lines  []int
+}
+
+// position returns the line in the file of the line of the script.
+func (l linted) position(line int) int {
+	if line < 1 || line > len(l.lines) {
+		return l.header
+	}
+	return l.lines[line-1]
+}
+
+// Lint checks the migrations of the source without a database, for CI: the
+// files must parse, with valid copy directives, versions must be positive
+// and unique, down files must revert a migration, and the migrations must
+// follow the rules. The findings are ordered by file and line.
+func (s FSSource) Lint(rules ...LintRule) ([]Finding, error) {
+	files, names, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		findings []Finding
+		ups      []linted
+		downs    []linted
+		versions = map[float64]bool{}
+	)
+
+	for _, name := range names {
+		content, err := fs.ReadFile(files, name)
+		if err != nil {
+			return nil, err
+		}
+
+		migs, problems := s.scan(s.path(name), string(content))
+		findings = append(findings, problems...)
+
+		if strings.HasSuffix(name, DownSuffix) {
+			downs = append(downs, migs...)
+			continue
+		}
+
+		if len(migs) == 0 && len(problems) == 0 {
+			findings = append(findings, Finding{File: s.path(name), Line: 1, Rule: RuleParse, Message: "the file holds no migration"})
+		}
+		ups = append(ups, migs...)
+	}
+
+	all := make([]Migration, len(ups))
+	for i, m := range ups {
+		all[i] = m.Migration
+	}
+
+	for _, m := range ups {
+		switch {
+		case m.Version < 0:
+			findings = append(findings, Finding{File: m.file, Line: m.header, Rule: RuleVersion, Message: fmt.Sprintf("version %s is negative", formatVersion(m.Version))})
+		case versions[m.Version]:
+			findings = append(findings, Finding{File: m.file, Line: m.header, Rule: RuleDuplicate, Message: fmt.Sprintf("version %s is already used", formatVersion(m.Version))})
+		}
+		versions[m.Version] = true
+
+		for _, d := range Directives(m.Script) {
+			if d.Name != copyDirective {
+				continue
+			}
+			if _, err := parseAttachment(d.Args); err != nil {
+				findings = append(findings, Finding{File: m.file, Line: m.position(d.Line), Rule: RuleCopy, Message: err.Error()})
+			}
+		}
+
+		for _, rule := range rules {
+			for _, f := range rule.check(m.Migration, all) {
+				f.File, f.Line, f.Rule, f.Version = m.file, m.position(f.Line), rule.Name, m.Version
+				findings = append(findings, f)
+			}
+		}
+	}
+
+	for _, d := range downs {
+		if !versions[d.Version] {
+			findings = append(findings, Finding{File: d.file, Line: d.header, Rule: RuleDown, Message: fmt.Sprintf("no migration has the version %s", formatVersion(d.Version))})
+		}
+	}
+
+	findings = append(findings, s.lintDownFiles(files, names)...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+
+	return findings, nil
+}
+
+// lintDownFiles checks that the down files without version headers revert
+// the single migration of their .sql file.
+func (s FSSource) lintDownFiles(files fs.FS, names []string) []Finding {
+	var findings []Finding
+
+	for _, name := range names {
+		if !strings.HasSuffix(name, DownSuffix) {
+			continue
+		}
+
+		content, err := fs.ReadFile(files, name)
+		if err != nil || len(ParseMigrations(string(content), s.Options...)) > 0 {
+			continue
+		}
+
+		up, err := fs.ReadFile(files, strings.TrimSuffix(name, DownSuffix)+".sql")
+		if err != nil {
+			findings = append(findings, Finding{File: s.path(name), Line: 1, Rule: RuleDown, Message: "the file reverts no migration"})
+			continue
+		}
+
+		if n := len(ParseMigrations(string(up), s.Options...)); n != 1 {
+			findings = append(findings, Finding{File: s.path(name), Line: 1, Rule: RuleDown, Message: fmt.Sprintf("the file reverts %d migrations, versions must be declared", n)})
+		}
+	}
+
+	return findings
+}
+
+// scan parses the migrations of a file like ParseMigrations, keeping the
+// lines of their scripts, and reports the headers which don't parse.
+func (s FSSource) scan(file, content string) ([]linted, []Finding) {
+	p := newParser(s.Options)
+
+	content = strings.TrimPrefix(content, byteOrderMark)
+	if p.normalize {
+		content = normalizeDocument(content)
+	}
+
+	var (
+		migs     []linted
+		findings []Finding
+		current  = -1
+	)
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), len(content)+1)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+
+		if value, ok := p.header(line, p.versionMarker); ok {
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				findings = append(findings, Finding{File: file, Line: n, Rule: RuleParse, Message: fmt.Sprintf("invalid version %q", value)})
+				current = -1
+				continue
+			}
+			migs = append(migs, linted{Migration: Migration{Version: v}, file: file, header: n})
+			current = len(migs) - 1
+			continue
+		}
+
+		if current < 0 {
+			continue
+		}
+
+		m := &migs[current]
+		if value, ok := p.header(line, p.descriptionMarker); ok {
+			m.Description = value
+			continue
+		}
+
+		m.Script += line + "\n"
+		m.lines = append(m.lines, n)
+	}
+
+	return migs, findings
+}
+
+// formatVersion returns the shortest representation of the version.
+func formatVersion(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
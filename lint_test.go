@@ -0,0 +1,129 @@
+package darwin
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func Test_FSSource_Lint(t *testing.T) {
+	fsys := fstest.MapFS{
+		"001_users.sql":      {Data: []byte("-- Version: 1\n-- Description: Users\nCREATE TABLE users (id INT);\n-- Version: 2\n\n-- Cleanup\nDROP TABLE legacy;\n")},
+		"001_users.down.sql": {Data: []byte("DROP TABLE users;\n")},
+		"002_roles.sql":      {Data: []byte("-- Version: 1\n-- Description: Roles\n-- darwin:copy roles.csv roles\n")},
+		"003_bad.sql":        {Data: []byte("-- Version: three\nSELECT 1;\n")},
+		"004_none.sql":       {Data: []byte("SELECT 1;\n")},
+		"005_gone.down.sql":  {Data: []byte("-- Version: 5\nDROP TABLE gone;\n")},
+	}
+
+	findings, err := NewFSSource(fsys, "").Lint(DefaultLintRules...)
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := []string{
+		"001_users.down.sql:1: down: the file reverts 2 migrations, versions must be declared",
+		"001_users.sql:4: description: the migration has no description",
+		"001_users.sql:7: destructive: DROP TABLE loses data",
+		"002_roles.sql:1: duplicate: version 1 is already used",
+		"002_roles.sql:1: empty: the migration has no statement",
+		`002_roles.sql:3: copy: invalid copy directive "roles.csv roles", expected <file> INTO <table>`,
+		`003_bad.sql:1: parse: invalid version "three"`,
+		"004_none.sql:1: parse: the file holds no migration",
+		"005_gone.down.sql:1: down: no migration has the version 5",
+	}
+
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %q", len(expected), findings)
+	}
+	for i, f := range findings {
+		if f.String() != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], f.String())
+		}
+	}
+}
+
+func Test_Lint(t *testing.T) {
+	migrations := ParseMigrations(`-- Version: 1
+-- Description: Orders
+-- darwin:large-table orders
+CREATE TABLE orders (id INT, total NUMERIC(12, 2), note TEXT);
+CREATE INDEX orders_total ON public."Orders" (total);
+CREATE INDEX CONCURRENTLY orders_note ON orders (note);
+CREATE INDEX users_email ON users (email);
+-- Version: 2
+-- Description: Narrow
+ALTER TABLE orders ALTER COLUMN note TYPE varchar(20);
+ALTER TABLE orders ALTER COLUMN id TYPE bigint;
+ALTER TABLE users MODIFY COLUMN age smallint;
+ALTER TABLE users DROP COLUMN age;
+-- Version: 1.5
+-- Description: Users
+CREATE INDEX users_name ON app.users (name);
+CREATE TABLE users (name TEXT, age INT);
+`)
+
+	findings := Lint(migrations, DefaultLintRules...)
+
+	expected := []string{
+		"version 1:3: large-table-index: the index of the large table orders must be created concurrently",
+		"version 2:1: narrowing: changing the type of orders.note from TEXT to VARCHAR(20) may truncate its values",
+		"version 2:3: narrowing: changing the type of users.age from INT to SMALLINT may truncate its values",
+		"version 2:4: destructive: DROP COLUMN loses data",
+	}
+
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %q", len(expected), findings)
+	}
+	for i, f := range findings {
+		if f.String() != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], f.String())
+		}
+	}
+
+	findings = Lint(migrations, LargeTableIndexes("app.users"))
+	if len(findings) != 3 || findings[1].Line != 5 || findings[2].Version != 1.5 {
+		t.Errorf("Must check the indexes of the tables given, got %q", findings)
+	}
+}
+
+func Test_NarrowingRule(t *testing.T) {
+	migrations := ParseMigrations(`-- Version: 1
+-- Description: Accounts
+CREATE TABLE accounts (
+	id INT PRIMARY KEY,
+	name VARCHAR(50) NOT NULL,
+	balance NUMERIC(12, 2),
+	CONSTRAINT accounts_name UNIQUE (name)
+);
+ALTER TABLE accounts ADD COLUMN note VARCHAR(100);
+-- Version: 2
+-- Description: Widen
+ALTER TABLE accounts ALTER COLUMN id TYPE BIGINT;
+ALTER TABLE accounts ALTER COLUMN name TYPE VARCHAR(200);
+ALTER TABLE accounts ALTER COLUMN balance TYPE NUMERIC(18, 4);
+ALTER TABLE accounts ALTER COLUMN note TYPE TEXT;
+ALTER TABLE ledger ALTER COLUMN total TYPE SMALLINT;
+-- Version: 3
+-- Description: Shrink
+ALTER TABLE accounts
+	ALTER COLUMN id TYPE INT;
+ALTER TABLE accounts MODIFY COLUMN name VARCHAR(100);
+ALTER TABLE accounts ALTER COLUMN balance TYPE NUMERIC(18, 2);
+`)
+
+	expected := []string{
+		"version 3:1: narrowing: changing the type of accounts.id from BIGINT to INT may truncate its values",
+		"version 3:3: narrowing: changing the type of accounts.name from VARCHAR(200) to VARCHAR(100) may truncate its values",
+		"version 3:4: narrowing: changing the type of accounts.balance from NUMERIC(18,4) to NUMERIC(18,2) may truncate its values",
+	}
+
+	findings := Lint(migrations, NarrowingRule)
+	if len(findings) != len(expected) {
+		t.Fatalf("Must only report the shrinking changes, expected %d findings, got %q", len(expected), findings)
+	}
+	for i, f := range findings {
+		if f.String() != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], f.String())
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package darwin
+
+import "context"
+
+// DistributedLock is a mutual exclusion mechanism outside of the database,
+// like a Redis key set with SETNX, an etcd lease or a Consul session, for
+// databases without locks of their own such as some cloud warehouses. It
+// replaces the lock of the driver when given to WithLock.
+type DistributedLock interface {
+	Lock(ctx context.Context) error
+	Unlock(ctx context.Context) error
+}
+
+// WithLock makes Migrate hold l instead of the lock of the driver.
+func WithLock(l DistributedLock) Option {
+	return func(d *Darwin) {
+		d.lock = l
+	}
+}
+
+// FromLocker returns a DistributedLock using the Lock and Unlock methods of
+// l, for example to lock the migrations of a warehouse with the redis or kv
+// drivers:
+//
+//	d := darwin.New(warehouse, migrations, darwin.WithLock(darwin.FromLocker(redisDriver)))
+func FromLocker(l Locker) DistributedLock {
+	return lockerLock{l}
+}
+
+type lockerLock struct {
+	l Locker
+}
+
+func (l lockerLock) Lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return l.l.Lock()
+}
+
+func (l lockerLock) Unlock(ctx context.Context) error {
+	return l.l.Unlock()
+}
+
+// acquire takes the lock given to WithLock, or the lock of the driver when
+// it is a Locker, and returns it with the function releasing it. The lock is
+// nil when there is none.
+func (d Darwin) acquire(ctx context.Context) (DistributedLock, func() error, error) {
+	if d.err != nil {
+		return nil, nil, d.err
+	}
+
+	lock := d.lock
+	if l, ok := d.driver.(Locker); ok && lock == nil {
+		lock = FromLocker(l)
+	}
+
+	if lock == nil {
+		return nil, func() error { return nil }, nil
+	}
+
+	if err := lock.Lock(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	return lock, func() error { return lock.Unlock(context.Background()) }, nil
+}
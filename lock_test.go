@@ -0,0 +1,59 @@
+package darwin
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+type fakeLock struct {
+	calls []string
+}
+
+func (f *fakeLock) Lock(ctx context.Context) error {
+	f.calls = append(f.calls, "lock")
+	return nil
+}
+
+func (f *fakeLock) Unlock(ctx context.Context) error {
+	f.calls = append(f.calls, "unlock")
+	return nil
+}
+
+func Test_WithLock(t *testing.T) {
+	lock := &fakeLock{}
+	driver := &lockingDriver{}
+
+	migrations := []Migration{{Version: 1, Description: "First Migration", Script: "does not matter!"}}
+
+	if err := New(driver, migrations, WithLock(lock)).Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if fmt.Sprint(lock.calls) != "[lock unlock]" {
+		t.Errorf("Must hold the distributed lock, got %v", lock.calls)
+	}
+
+	if fmt.Sprint(driver.calls) != "[insert]" {
+		t.Errorf("Must not take the lock of the driver, got %v", driver.calls)
+	}
+}
+
+func Test_FromLocker(t *testing.T) {
+	driver := &lockingDriver{}
+	lock := FromLocker(driver)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := lock.Lock(ctx); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	lock.Lock(context.Background())
+	lock.Unlock(context.Background())
+
+	if fmt.Sprint(driver.calls) != "[lock unlock]" {
+		t.Errorf("Must use the methods of the Locker, got %v", driver.calls)
+	}
+}
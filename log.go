@@ -0,0 +1,54 @@
+package darwin
+
+import (
+	"errors"
+	"time"
+)
+
+// Logger receives the structured events of Migrate: a message followed by
+// alternating keys and values. A *slog.Logger is a Logger.
+type Logger interface {
+	Info(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// WithLogger makes Migrate log the plan, the start and end of each
+// migration with its duration, and the outcome.
+func WithLogger(l Logger) Option {
+	return func(d *Darwin) {
+		d.log = l
+	}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+
+// logger returns the logger of d, discarding the events when there is none.
+func (d Darwin) logger() Logger {
+	if d.log == nil {
+		return nopLogger{}
+	}
+	return d.log
+}
+
+// logOutcome logs the outcome of Migrate, started at start.
+func (d Darwin) logOutcome(report Report, err error, start time.Time) {
+	if err == nil {
+		d.logger().Info("darwin: migrate finished",
+			"migrations", len(report.Results),
+			"rows_affected", report.RowsAffected(),
+			"duration", time.Since(start))
+		return
+	}
+
+	args := []interface{}{"error", d.redact(err.Error()), "migrations", len(report.Results), "duration", time.Since(start)}
+
+	var e ExecutionError
+	if errors.As(err, &e) {
+		args = append(args, "version", e.Version, "description", e.Description)
+	}
+
+	d.logger().Error("darwin: migrate failed", args...)
+}
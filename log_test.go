@@ -0,0 +1,56 @@
+package darwin
+
+import (
+	"reflect"
+	"testing"
+)
+
+type recordLogger struct {
+	events []string
+	args   [][]interface{}
+}
+
+func (l *recordLogger) Info(msg string, args ...interface{}) {
+	l.events = append(l.events, msg)
+	l.args = append(l.args, args)
+}
+
+func (l *recordLogger) Error(msg string, args ...interface{}) {
+	l.Info(msg, args...)
+}
+
+func Test_WithLogger(t *testing.T) {
+	logger := &recordLogger{}
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+	}
+
+	if err := New(&dummyDriver{}, migrations, WithLogger(logger)).Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := []string{"darwin: plan computed", "darwin: migration started", "darwin: migration finished", "darwin: migrate finished"}
+	if !reflect.DeepEqual(logger.events, expected) {
+		t.Fatalf("Expected %q, got %q", expected, logger.events)
+	}
+
+	if logger.args[1][0] != "version" || logger.args[1][1] != 1.0 {
+		t.Errorf("Must log the version of the migration, got %v", logger.args[1])
+	}
+}
+
+func Test_WithLogger_failure(t *testing.T) {
+	logger := &recordLogger{}
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+	}
+
+	if err := New(&dummyDriver{ExecError: true}, migrations, WithLogger(logger)).Migrate(); err == nil {
+		t.Fatalf("Must return the error of Exec")
+	}
+
+	last := logger.args[len(logger.args)-1]
+	if logger.events[len(logger.events)-1] != "darwin: migrate failed" || last[len(last)-3] != 1.0 {
+		t.Errorf("Must log the failed migration, got %q %v", logger.events, last)
+	}
+}
@@ -0,0 +1,78 @@
+package darwin
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+)
+
+// ManifestHash returns the hash of the versions and checksums of the
+// migrations, which changes when a migration is added, removed or edited.
+func ManifestHash(migrations []Migration) string {
+	records := make([]MigrationRecord, len(migrations))
+	for i, m := range migrations {
+		records[i] = MigrationRecord{Version: m.Version, Checksum: m.Checksum()}
+	}
+	return manifestHash(records)
+}
+
+// HistoryManifestHash returns the manifest hash of the migrations applied
+// with the driver, the ManifestHash of the migrations once they are all
+// applied.
+func HistoryManifestHash(d Driver) (string, error) {
+	records, err := d.All()
+	if err != nil {
+		return "", err
+	}
+	return manifestHash(records), nil
+}
+
+// manifestHash returns the hash of the versions and checksums of the
+// records, in version order.
+func manifestHash(records []MigrationRecord) string {
+	sorted := make([]MigrationRecord, len(records))
+	copy(sorted, records)
+	sort.Sort(byMigrationRecordVersion(sorted))
+
+	h := sha256.New()
+	for _, r := range sorted {
+		fmt.Fprintf(h, "%s %s\n", formatVersion(r.Version), r.Checksum)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// ResetFunc empties a database, dropping its tables and the history.
+type ResetFunc func(ctx context.Context) error
+
+// MigrateCached migrates a database reused between test runs: when the
+// manifest hash of its history is the manifest hash of the migrations, the
+// schema is up to date and nothing is done; else the database is emptied
+// with reset and the migrations are applied. It reports whether the
+// migrations were applied.
+func (d Darwin) MigrateCached(ctx context.Context, reset ResetFunc) (bool, error) {
+	if d.err != nil {
+		return false, d.err
+	}
+
+	if err := d.driver.Create(); err != nil {
+		return false, err
+	}
+
+	applied, err := HistoryManifestHash(d.driver)
+	if err != nil {
+		return false, err
+	}
+
+	if applied == ManifestHash(d.migrations) {
+		d.logger().Info("darwin: schema up to date", "manifest", applied)
+		return false, nil
+	}
+
+	if err := reset(ctx); err != nil {
+		return false, err
+	}
+
+	_, err = d.migrate(ctx)
+	return true, err
+}
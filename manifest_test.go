@@ -0,0 +1,70 @@
+package darwin
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func Test_ManifestHash(t *testing.T) {
+	migrations := []Migration{
+		{Version: 2, Script: "CREATE TABLE roles (id int);"},
+		{Version: 1, Script: "CREATE TABLE users (id int);"},
+	}
+
+	hash := ManifestHash(migrations)
+	if again := ManifestHash([]Migration{migrations[1], migrations[0]}); again != hash {
+		t.Errorf("Must not depend on the order of the migrations, got %s and %s", hash, again)
+	}
+
+	migrations[0].Script = "CREATE TABLE roles (id int, name string);"
+	if ManifestHash(migrations) == hash {
+		t.Error("Must change when a migration is edited")
+	}
+}
+
+func Test_Darwin_MigrateCached(t *testing.T) {
+	db, err := sql.Open("ql-mem", "cached.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	driver, _ := NewGenericDriver(db, QLDialect{})
+	migrations := []Migration{{Version: 1, Description: "Users", Script: "CREATE TABLE users (id int);"}}
+
+	resets := 0
+	reset := func(ctx context.Context) error {
+		resets++
+		var tables []string
+		for _, table := range []string{"users", "roles", "darwin_migrations"} {
+			if hasTable(db, table, t) {
+				tables = append(tables, table)
+			}
+		}
+		return transaction(db, func(tx *sql.Tx) error {
+			for _, table := range tables {
+				if _, err := tx.Exec("DROP TABLE " + table); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	migrated, err := New(driver, migrations).MigrateCached(context.Background(), reset)
+	if err != nil || !migrated || !hasTable(db, "users", t) {
+		t.Fatalf("Must migrate an empty database, got %t, %v", migrated, err)
+	}
+
+	migrated, err = New(driver, migrations).MigrateCached(context.Background(), reset)
+	if err != nil || migrated || resets != 1 {
+		t.Errorf("Must skip an up to date database, got %t, %v with %d resets", migrated, err, resets)
+	}
+
+	migrations = append(migrations, Migration{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id int);"})
+	migrated, err = New(driver, migrations).MigrateCached(context.Background(), reset)
+	if err != nil || !migrated || resets != 2 || !hasTable(db, "roles", t) {
+		t.Errorf("Must reset and migrate a database out of date, got %t, %v with %d resets", migrated, err, resets)
+	}
+}
@@ -0,0 +1,159 @@
+package darwin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/user"
+)
+
+// RecordMetadata tells who and what applied a migration, so the history
+// answers audits asking who applied a version to production. Its fields
+// are optional.
+type RecordMetadata struct {
+	// AppliedBy is the user applying the migration, the current user by
+	// default.
+	AppliedBy string
+
+	// Hostname is the host applying the migration, the current host by
+	// default.
+	Hostname string
+
+	// AppVersion is the version of the application applying the migration.
+	AppVersion string
+
+	// Source identifies the source of the migrations, like a git SHA.
+	Source string
+}
+
+// merge returns the metadata with the fields set in o replaced.
+func (r RecordMetadata) merge(o RecordMetadata) RecordMetadata {
+	if o.AppliedBy != "" {
+		r.AppliedBy = o.AppliedBy
+	}
+	if o.Hostname != "" {
+		r.Hostname = o.Hostname
+	}
+	if o.AppVersion != "" {
+		r.AppVersion = o.AppVersion
+	}
+	if o.Source != "" {
+		r.Source = o.Source
+	}
+	return r
+}
+
+// MetadataFunc returns the metadata recorded with a migration applied.
+type MetadataFunc func(ctx context.Context, m Migration) RecordMetadata
+
+// WithRecordMetadata records the metadata with each migration applied, its
+// fields set replacing the defaults:
+//
+//	darwin.New(driver, migrations, darwin.WithRecordMetadata(darwin.RecordMetadata{
+//		AppVersion: version,
+//		Source:     gitSHA,
+//	}))
+func WithRecordMetadata(md RecordMetadata) Option {
+	return func(d *Darwin) {
+		d.metadata = d.metadata.merge(md)
+	}
+}
+
+// WithMetadataFunc makes Migrate call f for the metadata of each migration
+// applied, its fields set replacing those of WithRecordMetadata.
+func WithMetadataFunc(f MetadataFunc) Option {
+	return func(d *Darwin) {
+		d.metadataFunc = f
+	}
+}
+
+// MetadataRecorder is implemented by the drivers storing the metadata and
+// the scripts of the records. Migrate refuses WithRecordMetadata,
+// WithMetadataFunc and WithStoredScripts with the drivers which aren't, or
+// whose RecordsMetadata returns false, rather than drop them.
+type MetadataRecorder interface {
+	RecordsMetadata() bool
+}
+
+// RecordsMetadata reports whether the dialect is a MetadataDialect.
+func (m *GenericDriver) RecordsMetadata() bool {
+	_, ok := m.Dialect.(MetadataDialect)
+	return ok
+}
+
+// checkMetadata returns an error when the metadata or the scripts are to be
+// recorded by a driver which can't.
+func (d Darwin) checkMetadata() error {
+	if d.metadata == (RecordMetadata{}) && d.metadataFunc == nil && d.scripts == nil {
+		return nil
+	}
+
+	if r, ok := d.driver.(MetadataRecorder); ok && r.RecordsMetadata() {
+		return nil
+	}
+	return errors.New("darwin: the driver can't record the metadata nor the scripts of the migrations")
+}
+
+// recordMetadata returns the metadata of the migration.
+func (d Darwin) recordMetadata(ctx context.Context, m Migration) RecordMetadata {
+	md := RecordMetadata{}
+	if u, err := user.Current(); err == nil {
+		md.AppliedBy = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		md.Hostname = host
+	}
+
+	md = md.merge(d.metadata)
+	if d.metadataFunc != nil {
+		md = md.merge(d.metadataFunc(ctx, m))
+	}
+	return md
+}
+
+// MetadataDialect is implemented by the dialects storing the metadata of
+// the records, as the dialects of this package do. InsertMetadataSQL takes
+// the arguments of InsertSQL followed by applied_by, hostname, app_version,
+// source and script; AllMetadataSQL selects the same columns but the
+// script, after the columns of AllSQL, and ScriptSQL the script of a
+// version. The dialect must be an UpgradeDialect adding the metadata
+// columns to the history tables created without them.
+type MetadataDialect interface {
+	InsertMetadataSQL() string
+	AllMetadataSQL() string
+	ScriptSQL() string
+}
+
+// InsertArgs returns the SQL of the dialect inserting the record, with its
+// metadata if the dialect stores them, and its arguments, for the drivers
+// recording migrations in transactions of their own.
+func InsertArgs(d Dialect, e MigrationRecord) (string, []interface{}) {
+	args := []interface{}{
+		e.Version,
+		e.Description,
+		e.Checksum,
+		e.AppliedAt.Unix(),
+		e.ExecutionTime,
+	}
+
+	md, ok := d.(MetadataDialect)
+	if !ok {
+		return d.InsertSQL(), args
+	}
+
+	return md.InsertMetadataSQL(), append(args,
+		nullString(e.AppliedBy),
+		nullString(e.Hostname),
+		nullString(e.AppVersion),
+		nullString(e.Source),
+		nullString(e.Script),
+	)
+}
+
+// nullString returns s, or NULL when it is empty.
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
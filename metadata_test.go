@@ -0,0 +1,106 @@
+package darwin
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+// metadataDriver is a dummyDriver recording the metadata.
+type metadataDriver struct {
+	*dummyDriver
+}
+
+func (metadataDriver) RecordsMetadata() bool {
+	return true
+}
+
+func Test_WithRecordMetadata(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+
+	source := func(ctx context.Context, m Migration) RecordMetadata {
+		if m.Version == 2 {
+			return RecordMetadata{Source: "hotfix"}
+		}
+		return RecordMetadata{}
+	}
+
+	driver := &dummyDriver{}
+	d := New(metadataDriver{driver}, migrations,
+		WithRecordMetadata(RecordMetadata{AppliedBy: "deploy", AppVersion: "1.4.0", Source: "4f2a9c1"}),
+		WithMetadataFunc(source))
+
+	if err := d.Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	first, second := driver.records[0], driver.records[1]
+	if first.AppliedBy != "deploy" || first.AppVersion != "1.4.0" || first.Source != "4f2a9c1" || first.Hostname == "" {
+		t.Errorf("Must record the metadata with the host by default, got %#v", first.RecordMetadata)
+	}
+
+	if second.Source != "hotfix" || second.AppliedBy != "deploy" {
+		t.Errorf("Must prefer the metadata of the function, got %#v", second.RecordMetadata)
+	}
+}
+
+func Test_WithRecordMetadata_unsupported(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+	}
+
+	for _, opt := range []Option{
+		WithRecordMetadata(RecordMetadata{Source: "4f2a9c1"}),
+		WithStoredScripts(ScriptStorage{}),
+	} {
+		driver := &dummyDriver{}
+		if err := New(driver, migrations, opt).Migrate(); err == nil {
+			t.Errorf("Must return an error when the driver can't record the metadata")
+		}
+
+		if len(driver.records) != 0 {
+			t.Errorf("Must not apply the migrations, got %d records", len(driver.records))
+		}
+	}
+}
+
+func Test_GenericDriver_metadata(t *testing.T) {
+	db, err := sql.Open("ql-mem", "metadata.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// A history table created before the metadata columns.
+	tx, _ := db.Begin()
+	if _, err := tx.Exec("CREATE TABLE darwin_migrations (version float, description string, checksum string, applied_at int64, execution_time int64);"); err != nil {
+		t.Fatal(err)
+	}
+	tx.Commit()
+
+	d, _ := NewGenericDriver(db, QLDialect{})
+	if err := d.Create(); err != nil {
+		t.Fatalf("Must add the metadata columns, got %s", err)
+	}
+
+	record := MigrationRecord{Version: 1, Description: "Users", Checksum: "a", RecordMetadata: RecordMetadata{AppliedBy: "deploy", Source: "4f2a9c1"}}
+	if err := d.Insert(record); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	records, err := d.All()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(records) != 1 || records[0].RecordMetadata != record.RecordMetadata {
+		t.Errorf("Must read the metadata back, got %#v", records)
+	}
+
+	if err := d.Create(); err != nil {
+		t.Errorf("Must leave an upgraded table as is, got %s", err)
+	}
+}
@@ -0,0 +1,59 @@
+package darwin
+
+import (
+	"errors"
+	"time"
+)
+
+// Metrics receives the measures of Migrate, for dashboards alerting on
+// drift and slow migrations. The darwinprom package exports them to
+// Prometheus.
+type Metrics interface {
+	// MigrationsPending is called with the number of migrations left to
+	// execute, once planned and after each migration.
+	MigrationsPending(n int)
+
+	// MigrationApplied is called after each migration executed.
+	MigrationApplied(m Migration, d time.Duration)
+
+	// MigrationFailed is called when a migration fails.
+	MigrationFailed(m Migration, err error)
+}
+
+// WithMetrics makes Migrate report to m.
+func WithMetrics(m Metrics) Option {
+	return func(d *Darwin) {
+		d.metrics = m
+	}
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) MigrationsPending(n int)                       {}
+func (nopMetrics) MigrationApplied(m Migration, d time.Duration) {}
+func (nopMetrics) MigrationFailed(m Migration, err error)        {}
+
+// measures returns the metrics of d, discarding the measures when there are
+// none.
+func (d Darwin) measures() Metrics {
+	if d.metrics == nil {
+		return nopMetrics{}
+	}
+	return d.metrics
+}
+
+// failed reports the migration of a single transaction which failed with
+// err, if known.
+func (d Darwin) failed(planned []Migration, err error) {
+	var e ExecutionError
+	if !errors.As(err, &e) {
+		return
+	}
+
+	for _, m := range planned {
+		if m.Version == e.Version {
+			d.measures().MigrationFailed(m, err)
+			return
+		}
+	}
+}
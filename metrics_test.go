@@ -0,0 +1,57 @@
+package darwin
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type recordMetrics struct {
+	calls []string
+}
+
+func (r *recordMetrics) MigrationsPending(n int) {
+	r.calls = append(r.calls, fmt.Sprintf("pending %d", n))
+}
+
+func (r *recordMetrics) MigrationApplied(m Migration, d time.Duration) {
+	r.calls = append(r.calls, fmt.Sprintf("applied %g", m.Version))
+}
+
+func (r *recordMetrics) MigrationFailed(m Migration, err error) {
+	r.calls = append(r.calls, fmt.Sprintf("failed %g", m.Version))
+}
+
+func Test_WithMetrics(t *testing.T) {
+	metrics := &recordMetrics{}
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+
+	if err := New(&dummyDriver{}, migrations, WithMetrics(metrics)).Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := []string{"pending 2", "applied 1", "pending 1", "applied 2", "pending 0"}
+	if !reflect.DeepEqual(metrics.calls, expected) {
+		t.Errorf("Expected %q, got %q", expected, metrics.calls)
+	}
+}
+
+func Test_WithMetrics_failure(t *testing.T) {
+	metrics := &recordMetrics{}
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+	}
+
+	if err := New(&dummyDriver{ExecError: true}, migrations, WithMetrics(metrics)).Migrate(); err == nil {
+		t.Fatalf("Must return the error of Exec")
+	}
+
+	expected := []string{"pending 1", "failed 1"}
+	if !reflect.DeepEqual(metrics.calls, expected) {
+		t.Errorf("Expected %q, got %q", expected, metrics.calls)
+	}
+}
@@ -1,11 +1,17 @@
 package darwin
 
+import "fmt"
+
 // MySQLDialect a Dialect configured for MySQL.
-type MySQLDialect struct{}
+type MySQLDialect struct {
+	// Table is the name of the history table, qualified by its database or
+	// not, darwin_migrations by default.
+	Table string
+}
 
 // CreateTableSQL returns the SQL to create the schema table.
 func (m MySQLDialect) CreateTableSQL() string {
-	return `CREATE TABLE IF NOT EXISTS darwin_migrations
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
                 (
                     id             INT          auto_increment,
                     version        FLOAT        NOT NULL,
@@ -13,14 +19,19 @@ func (m MySQLDialect) CreateTableSQL() string {
                     checksum       VARCHAR(32)  NOT NULL,
                     applied_at     INT          NOT NULL,
                     execution_time FLOAT        NOT NULL,
+                    applied_by     VARCHAR(255) NULL,
+                    hostname       VARCHAR(255) NULL,
+                    app_version    VARCHAR(255) NULL,
+                    source         VARCHAR(255) NULL,
+                    script         LONGTEXT     NULL,
                     UNIQUE         (version),
                     PRIMARY KEY    (id)
-                ) ENGINE=InnoDB CHARACTER SET=utf8;`
+                ) ENGINE=InnoDB CHARACTER SET=utf8;`, m.table())
 }
 
 // InsertSQL returns the SQL to insert a new migration in the schema table.
 func (m MySQLDialect) InsertSQL() string {
-	return `INSERT INTO darwin_migrations
+	return fmt.Sprintf(`INSERT INTO %s
                 (
                     version,
                     description,
@@ -28,18 +39,154 @@ func (m MySQLDialect) InsertSQL() string {
                     applied_at,
                     execution_time
                 )
-            VALUES (?, ?, ?, ?, ?);`
+            VALUES (?, ?, ?, ?, ?);`, m.table())
 }
 
 // AllSQL returns a SQL to get all entries in the table.
 func (m MySQLDialect) AllSQL() string {
-	return `SELECT 
+	return fmt.Sprintf(`SELECT 
                 version,
                 description,
                 checksum,
                 applied_at,
                 execution_time
             FROM 
-                darwin_migrations
-            ORDER BY version ASC;`
+                %s
+            ORDER BY version ASC;`, m.table())
+}
+
+// DeleteSQL returns the SQL to delete the record of a migration.
+func (m MySQLDialect) DeleteSQL() string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE version = ?;`, m.table())
+}
+
+// InsertMetadataSQL returns the SQL to insert a new migration in the schema
+// table with its metadata.
+func (m MySQLDialect) InsertMetadataSQL() string {
+	return fmt.Sprintf(`INSERT INTO %s
+                (
+                    version,
+                    description,
+                    checksum,
+                    applied_at,
+                    execution_time,
+                    applied_by,
+                    hostname,
+                    app_version,
+                    source,
+                    script
+                )
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`, m.table())
+}
+
+// AllMetadataSQL returns a SQL to get all entries in the table with their
+// metadata.
+func (m MySQLDialect) AllMetadataSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time,
+                applied_by,
+                hostname,
+                app_version,
+                source
+            FROM
+                %s
+            ORDER BY version ASC;`, m.table())
+}
+
+// AllSinceSQL returns a SQL to get the entries in the table from a version
+// on, with their metadata.
+func (m MySQLDialect) AllSinceSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time,
+                applied_by,
+                hostname,
+                app_version,
+                source
+            FROM
+                %s
+            WHERE
+                version >= ?
+            ORDER BY version ASC;`, m.table())
+}
+
+// LatestVersionSQL returns a SQL to get the greatest version in the table,
+// NULL when it is empty.
+func (m MySQLDialect) LatestVersionSQL() string {
+	return fmt.Sprintf("SELECT MAX(version) FROM %s;", m.table())
+}
+
+// HistoryUpgrades returns the upgrades of the schema table to its latest
+// layout.
+func (m MySQLDialect) HistoryUpgrades() []HistoryUpgrade {
+	return historyUpgrades(m.table(), "ADD COLUMN", "VARCHAR(255) NULL", "LONGTEXT NULL", "1 = 0")
+}
+
+// ScriptSQL returns the SQL to get the script stored with a migration.
+func (m MySQLDialect) ScriptSQL() string {
+	return fmt.Sprintf(`SELECT script FROM %s WHERE version = ?;`, m.table())
+}
+
+// LockSQL returns the SQL to acquire the migration lock.
+func (m MySQLDialect) LockSQL() string {
+	return fmt.Sprintf(`SELECT GET_LOCK(%s, -1);`, lockKey(m.Table))
+}
+
+// UnlockSQL returns the SQL to release the migration lock.
+func (m MySQLDialect) UnlockSQL() string {
+	return fmt.Sprintf(`SELECT RELEASE_LOCK(%s);`, lockKey(m.Table))
+}
+
+// SupportsTransactionalDDL returns false, MySQL commits DDL implicitly.
+func (m MySQLDialect) SupportsTransactionalDDL() bool {
+	return false
+}
+
+// CreateCheckpointTableSQL returns the SQL to create the checkpoint table.
+func (m MySQLDialect) CreateCheckpointTableSQL() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+                (
+                    statement   INT         NOT NULL,
+                    checksum    VARCHAR(32) NOT NULL,
+                    applied_at  INT         NOT NULL,
+                    PRIMARY KEY (statement, checksum)
+                ) ENGINE=InnoDB CHARACTER SET=utf8;`, m.statementsTable())
+}
+
+// InsertCheckpointSQL returns the SQL to record an executed statement.
+func (m MySQLDialect) InsertCheckpointSQL() string {
+	return fmt.Sprintf(`INSERT INTO %s (statement, checksum, applied_at) VALUES (?, ?, ?);`, m.statementsTable())
+}
+
+// CheckpointsSQL returns the SQL to get the statements already executed.
+func (m MySQLDialect) CheckpointsSQL() string {
+	return fmt.Sprintf(`SELECT statement, checksum FROM %s;`, m.statementsTable())
+}
+
+// DeleteCheckpointsSQL returns the SQL to delete the checkpoints once a
+// migration is recorded.
+func (m MySQLDialect) DeleteCheckpointsSQL() string {
+	return fmt.Sprintf(`DELETE FROM %s;`, m.statementsTable())
+}
+
+// Syntax returns the syntax of the statements.
+func (m MySQLDialect) Syntax() Syntax {
+	return MySQLSyntax
+}
+
+// SetSQL returns the SQL to change a session setting.
+func (m MySQLDialect) SetSQL(name, value string) string {
+	return fmt.Sprintf("SET SESSION %s = %s", name, value)
+}
+
+// ResetSQL returns the SQL to restore a session setting.
+func (m MySQLDialect) ResetSQL(name string) string {
+	return fmt.Sprintf("SET SESSION %s = DEFAULT", name)
 }
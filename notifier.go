@@ -0,0 +1,27 @@
+package darwin
+
+import "context"
+
+// Notifier is told of the outcome of Migrate, with the report of the
+// migrations executed and the error of a failure. The notify package
+// announces it on a webhook or in a Slack channel.
+type Notifier interface {
+	Notify(ctx context.Context, report Report, err error) error
+}
+
+// WithNotifier makes Migrate call the notifiers once it returns, the lock
+// released. Their errors are logged and don't fail Migrate.
+func WithNotifier(notifiers ...Notifier) Option {
+	return func(d *Darwin) {
+		d.notifiers = append(d.notifiers, notifiers...)
+	}
+}
+
+// notify calls the notifiers with the outcome of Migrate.
+func (d Darwin) notify(ctx context.Context, report Report, err error) {
+	for _, n := range d.notifiers {
+		if nerr := n.Notify(ctx, report, err); nerr != nil {
+			d.logger().Error("darwin: notification failed", "error", nerr)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package darwin
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type recordNotifier struct {
+	report Report
+	err    error
+	calls  int
+}
+
+func (r *recordNotifier) Notify(ctx context.Context, report Report, err error) error {
+	r.report, r.err = report, err
+	r.calls++
+	return errors.New("unreachable")
+}
+
+func Test_WithNotifier(t *testing.T) {
+	notifier := &recordNotifier{}
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+	}
+
+	if err := New(&dummyDriver{}, migrations, WithNotifier(notifier)).Migrate(); err != nil {
+		t.Fatalf("Must not fail with the notifier, got %s", err)
+	}
+
+	if notifier.calls != 1 || len(notifier.report.Results) != 1 || notifier.err != nil {
+		t.Errorf("Must notify the report, got %#v", notifier)
+	}
+
+	if err := New(&dummyDriver{ExecError: true}, migrations, WithNotifier(notifier)).Migrate(); err == nil {
+		t.Fatalf("Must return the error of Exec")
+	}
+
+	if notifier.calls != 2 || notifier.err == nil {
+		t.Errorf("Must notify the error, got %#v", notifier)
+	}
+}
@@ -0,0 +1,267 @@
+// Package notify provides darwin.Notifiers announcing the outcome of
+// Migrate, on a generic webhook or in a Slack channel:
+//
+//	d := darwin.New(driver, migrations, darwin.WithNotifier(
+//		notify.Slack{WebhookURL: os.Getenv("SLACK_WEBHOOK_URL"), Source: "billing"},
+//	))
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+// Status of a run in the payload of the Webhook.
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// DefaultTimeout bounds the requests sent with the default client.
+const DefaultTimeout = 10 * time.Second
+
+// defaultClient sends the requests of the notifiers without a client.
+var defaultClient = &http.Client{Timeout: DefaultTimeout}
+
+// Payload is the JSON document posted by the Webhook.
+type Payload struct {
+	Source     string      `json:"source,omitempty"`
+	Status     string      `json:"status"`
+	Error      *Failure    `json:"error,omitempty"`
+	Migrations []Migration `json:"migrations"`
+}
+
+// Failure describes the error of a failed run. The message of the error,
+// which can quote the data or the secrets of a statement, is only sent
+// through the Redact function of the notifier.
+type Failure struct {
+	// Version and Description are those of the failed migration, if known.
+	Version     float64 `json:"version,omitempty"`
+	Description string  `json:"description,omitempty"`
+
+	// Type is the type of the cause of the error, like *pq.Error.
+	Type string `json:"type"`
+
+	// Message is the redacted message of the error.
+	Message string `json:"message,omitempty"`
+}
+
+// NewFailure returns the failure describing err, without its message.
+func NewFailure(err error) *Failure {
+	f := &Failure{}
+
+	var exec darwin.ExecutionError
+	var seed darwin.SeedError
+	switch {
+	case errors.As(err, &exec):
+		f.Version, f.Description = exec.Version, exec.Description
+	case errors.As(err, &seed):
+		f.Version, f.Description = seed.Version, seed.Description
+	}
+
+	cause := err
+	for errors.Unwrap(cause) != nil {
+		cause = errors.Unwrap(cause)
+	}
+	f.Type = fmt.Sprintf("%T", cause)
+
+	return f
+}
+
+// String returns the description of the failure.
+func (f Failure) String() string {
+	var b strings.Builder
+	if f.Description != "" {
+		fmt.Fprintf(&b, "migration %g %s: ", f.Version, f.Description)
+	}
+	b.WriteString(f.Type)
+	if f.Message != "" {
+		fmt.Fprintf(&b, ": %s", f.Message)
+	}
+	return b.String()
+}
+
+// Migration is an executed migration in the Payload.
+type Migration struct {
+	Version      float64 `json:"version"`
+	Description  string  `json:"description"`
+	DurationMS   int64   `json:"duration_ms"`
+	RowsAffected int64   `json:"rows_affected"`
+}
+
+// StatusError is used to report a notification refused by the server.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (s StatusError) Error() string {
+	return fmt.Sprintf("notify: %s answered %d: %s", s.URL, s.StatusCode, s.Body)
+}
+
+// NewPayload returns the payload describing the outcome of Migrate, the
+// error without its message.
+func NewPayload(source string, report darwin.Report, err error) Payload {
+	p := Payload{
+		Source:     source,
+		Status:     StatusSucceeded,
+		Migrations: []Migration{},
+	}
+
+	if err != nil {
+		p.Status = StatusFailed
+		p.Error = NewFailure(err)
+	}
+
+	for _, r := range report.Results {
+		p.Migrations = append(p.Migrations, Migration{
+			Version:      r.Migration.Version,
+			Description:  r.Migration.Description,
+			DurationMS:   r.Duration.Milliseconds(),
+			RowsAffected: r.RowsAffected(),
+		})
+	}
+
+	return p
+}
+
+// Webhook posts the Payload of each run to URL.
+type Webhook struct {
+	URL string
+
+	// Source names the application or database migrated.
+	Source string
+
+	// Header is added to the requests, for example for authorization.
+	Header http.Header
+
+	// Client sends the requests, a client with the DefaultTimeout when nil.
+	Client *http.Client
+
+	// Redact, when set, adds the message of the error to the payload,
+	// passed through darwin.RedactPasswords and then Redact.
+	Redact darwin.Redactor
+}
+
+// Notify posts the payload of the run.
+func (w Webhook) Notify(ctx context.Context, report darwin.Report, err error) error {
+	p := NewPayload(w.Source, report, err)
+	if p.Error != nil {
+		p.Error.Message = redact(w.Redact, err)
+	}
+	return post(ctx, w.Client, w.URL, w.Header, p)
+}
+
+// Slack posts a message about each run to an incoming webhook of Slack.
+type Slack struct {
+	WebhookURL string
+
+	// Source names the application or database migrated.
+	Source string
+
+	// Channel overrides the channel of the webhook, if allowed.
+	Channel string
+
+	// Client sends the requests, a client with the DefaultTimeout when nil.
+	Client *http.Client
+
+	// Redact, when set, adds the message of the error to the message,
+	// passed through darwin.RedactPasswords and then Redact.
+	Redact darwin.Redactor
+}
+
+// Notify posts the message about the run.
+func (s Slack) Notify(ctx context.Context, report darwin.Report, err error) error {
+	message := struct {
+		Channel string `json:"channel,omitempty"`
+		Text    string `json:"text"`
+	}{s.Channel, message(s.Source, report, err, s.Redact)}
+
+	return post(ctx, s.Client, s.WebhookURL, nil, message)
+}
+
+// Message returns the text announcing the outcome of Migrate, the error
+// without its message.
+func Message(source string, report darwin.Report, err error) string {
+	return message(source, report, err, nil)
+}
+
+func message(source string, report darwin.Report, err error, r darwin.Redactor) string {
+	var b strings.Builder
+
+	target := "the database"
+	if source != "" {
+		target = source
+	}
+
+	switch {
+	case err != nil:
+		f := NewFailure(err)
+		f.Message = redact(r, err)
+		fmt.Fprintf(&b, ":x: Migrating %s failed after %d migrations: %s", target, len(report.Results), f)
+	case len(report.Results) == 0:
+		fmt.Fprintf(&b, ":white_check_mark: %s is up to date", target)
+	default:
+		fmt.Fprintf(&b, ":white_check_mark: Applied %d migrations to %s", len(report.Results), target)
+	}
+
+	for _, r := range report.Results {
+		fmt.Fprintf(&b, "\n• %g %s (%s, %d rows)", r.Migration.Version, r.Migration.Description, r.Duration, r.RowsAffected())
+	}
+
+	return b.String()
+}
+
+// redact returns the message of err redacted by r, none when r is nil.
+func redact(r darwin.Redactor, err error) string {
+	if r == nil {
+		return ""
+	}
+	return r(darwin.RedactPasswords(err.Error()))
+}
+
+func post(ctx context.Context, client *http.Client, url string, header http.Header, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	for k, values := range header {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if client == nil {
+		client = defaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return StatusError{URL: url, StatusCode: resp.StatusCode, Body: string(msg)}
+	}
+
+	return nil
+}
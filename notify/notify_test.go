@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dustinevan/darwin"
+)
+
+var report = darwin.Report{Results: []darwin.Result{{
+	Migration:  darwin.Migration{Version: 1.1, Description: "Backfill"},
+	Duration:   1500 * time.Millisecond,
+	Statements: []darwin.StatementResult{{RowsAffected: 42}},
+}}}
+
+func Test_Webhook_Notify(t *testing.T) {
+	var payload Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Must send the header, got %q", r.Header)
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer server.Close()
+
+	w := Webhook{URL: server.URL, Source: "billing", Header: http.Header{"Authorization": {"Bearer token"}}}
+	if err := w.Notify(context.Background(), report, errors.New("boom")); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := Migration{Version: 1.1, Description: "Backfill", DurationMS: 1500, RowsAffected: 42}
+	if payload.Source != "billing" || payload.Status != StatusFailed || len(payload.Migrations) != 1 || payload.Migrations[0] != expected {
+		t.Errorf("Must post the payload, got %#v", payload)
+	}
+
+	if payload.Error == nil || *payload.Error != (Failure{Type: "*errors.errorString"}) {
+		t.Errorf("Must post the type of the error without its message, got %#v", payload.Error)
+	}
+}
+
+func Test_Webhook_Notify_redact(t *testing.T) {
+	var payload Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer server.Close()
+
+	err := darwin.ExecutionError{Version: 2, Description: "Users", Err: errors.New("duplicate key (email)=(jane@example.com)")}
+	w := Webhook{URL: server.URL, Redact: func(string) string { return "duplicate key" }}
+	if err := w.Notify(context.Background(), darwin.Report{}, err); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := Failure{Version: 2, Description: "Users", Type: "*errors.errorString", Message: "duplicate key"}
+	if payload.Error == nil || *payload.Error != expected {
+		t.Errorf("Must post the failed migration and the redacted message, got %#v", payload.Error)
+	}
+}
+
+func Test_Slack_Notify(t *testing.T) {
+	var message struct {
+		Channel string `json:"channel"`
+		Text    string `json:"text"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&message)
+	}))
+	defer server.Close()
+
+	s := Slack{WebhookURL: server.URL, Source: "billing", Channel: "#deploys"}
+	if err := s.Notify(context.Background(), report, nil); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if message.Channel != "#deploys" || !strings.HasPrefix(message.Text, ":white_check_mark: Applied 1 migrations to billing") || !strings.Contains(message.Text, "1.1 Backfill (1.5s, 42 rows)") {
+		t.Errorf("Must post the message, got %#v", message)
+	}
+}
+
+func Test_Webhook_Notify_status(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such hook", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	err := Webhook{URL: server.URL}.Notify(context.Background(), darwin.Report{}, nil)
+
+	var status StatusError
+	if !errors.As(err, &status) || status.StatusCode != http.StatusNotFound {
+		t.Errorf("Must return a StatusError, got %v", err)
+	}
+}
+
+func Test_Message_error(t *testing.T) {
+	err := darwin.ExecutionError{Version: 2, Description: "Users", Err: errors.New("password 'hunter2' rejected")}
+
+	if got := Message("billing", report, err); strings.Contains(got, "hunter2") || !strings.Contains(got, "failed after 1 migrations: migration 2 Users: *errors.errorString") {
+		t.Errorf("Must describe the error without its message, got %s", got)
+	}
+}
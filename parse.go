@@ -0,0 +1,167 @@
+package darwin
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+)
+
+// Default markers used by ParseMigrations to recognize the header lines of a
+// migration.
+const (
+	DefaultCommentPrefix     = "--"
+	DefaultVersionMarker     = "Version:"
+	DefaultDescriptionMarker = "Description:"
+)
+
+// ParseOption customizes the way ParseMigrations reads a migration document.
+type ParseOption func(*parser)
+
+// WithCommentPrefix sets the line comment prefix that starts a header line,
+// for example "#" for dialects that do not understand "--".
+func WithCommentPrefix(prefix string) ParseOption {
+	return func(p *parser) {
+		p.commentPrefix = prefix
+	}
+}
+
+// WithVersionMarker sets the marker that follows the comment prefix on the
+// line holding the version number, for example "migrate:version". Markers
+// are matched case insensitively.
+func WithVersionMarker(marker string) ParseOption {
+	return func(p *parser) {
+		p.versionMarker = marker
+	}
+}
+
+// WithDescriptionMarker sets the marker that follows the comment prefix on
+// the line holding the description, for example "migrate:description".
+// Markers are matched case insensitively.
+func WithDescriptionMarker(marker string) ParseOption {
+	return func(p *parser) {
+		p.descriptionMarker = marker
+	}
+}
+
+// WithNormalization normalizes the document before it is parsed, so the
+// checksum of a migration does not depend on the editor used to write it:
+// line endings are converted to "\n", invalid UTF-8 sequences are replaced
+// by the Unicode replacement character and trailing whitespace, NUL and
+// EOF (Ctrl-Z) bytes at the end of the document are reduced to a single
+// newline.
+func WithNormalization() ParseOption {
+	return func(p *parser) {
+		p.normalize = true
+	}
+}
+
+// parser holds the configuration used to parse a migration document.
+type parser struct {
+	commentPrefix     string
+	versionMarker     string
+	descriptionMarker string
+	normalize         bool
+}
+
+func newParser(opts []ParseOption) parser {
+	p := parser{
+		commentPrefix:     DefaultCommentPrefix,
+		versionMarker:     DefaultVersionMarker,
+		descriptionMarker: DefaultDescriptionMarker,
+	}
+
+	for _, opt := range opts {
+		opt(&p)
+	}
+
+	return p
+}
+
+// header reports if the line is a header line with the given marker and
+// returns the value following the marker.
+func (p parser) header(line string, marker string) (string, bool) {
+	if !strings.HasPrefix(line, p.commentPrefix) {
+		return "", false
+	}
+
+	rest := strings.TrimLeft(line[len(p.commentPrefix):], " \t")
+	if len(rest) < len(marker) || !strings.EqualFold(rest[:len(marker)], marker) {
+		return "", false
+	}
+
+	return strings.TrimSpace(rest[len(marker):]), true
+}
+
+// byteOrderMark is the UTF-8 encoded byte order mark some editors add at the
+// beginning of a file.
+const byteOrderMark = "\ufeff"
+
+// normalizeDocument applies the WithNormalization rules to s.
+func normalizeDocument(s string) string {
+	s = strings.ToValidUTF8(s, "\ufffd")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	s = strings.TrimRight(s, " \t\n\x00\x1a")
+
+	if s == "" {
+		return s
+	}
+
+	return s + "\n"
+}
+
+// ParseMigrations takes a string that represents a text formatted set
+// of migrations and parse them for use. By default a migration starts with
+// a "-- Version:" line, optionally followed by a "-- Description:" line; use
+// the options to parse documents following a different convention.
+// A leading byte order mark is always ignored.
+func ParseMigrations(s string, opts ...ParseOption) []Migration {
+	p := newParser(opts)
+
+	s = strings.TrimPrefix(s, byteOrderMark)
+	if p.normalize {
+		s = normalizeDocument(s)
+	}
+
+	var migs []Migration
+
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	scanner.Split(bufio.ScanLines)
+
+	// A single line can be as long as the whole document, large INSERT
+	// statements would be silently truncated by the default buffer.
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), len(s)+1)
+
+	var mig Migration
+	var script string
+	for scanner.Scan() {
+		v := scanner.Text()
+
+		if value, ok := p.header(v, p.versionMarker); ok {
+			mig.Script = script
+			migs = append(migs, mig)
+
+			mig = Migration{}
+			script = ""
+
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil
+			}
+			mig.Version = f
+			continue
+		}
+
+		if value, ok := p.header(v, p.descriptionMarker); ok {
+			mig.Description = value
+			continue
+		}
+
+		script += v + "\n"
+	}
+
+	mig.Script = script
+	migs = append(migs, mig)
+
+	return migs[1:]
+}
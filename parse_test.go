@@ -0,0 +1,105 @@
+package darwin
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_ParseMigrations_custom_markers(t *testing.T) {
+	doc := `# migrate:version 1.0
+# migrate:description Create table users
+CREATE TABLE users (id INT);
+
+# MIGRATE:VERSION 1.1
+# migrate:description Create table products
+CREATE TABLE products (id INT);
+`
+
+	migs := ParseMigrations(doc,
+		WithCommentPrefix("#"),
+		WithVersionMarker("migrate:version"),
+		WithDescriptionMarker("migrate:description"),
+	)
+
+	if len(migs) != 2 {
+		t.Fatalf("len(migs) == %d, wants 2", len(migs))
+	}
+
+	expectations := []Migration{
+		{Version: 1.0, Description: "Create table users", Script: "CREATE TABLE users (id INT);\n\n"},
+		{Version: 1.1, Description: "Create table products", Script: "CREATE TABLE products (id INT);\n"},
+	}
+
+	for i, expected := range expectations {
+		if migs[i] != expected {
+			t.Errorf("Expected %#v, got %#v", expected, migs[i])
+		}
+	}
+}
+
+func Test_ParseMigrations_default_markers_without_space(t *testing.T) {
+	doc := "--version: 1\n--DESCRIPTION: Hello\nSELECT 1;\n"
+
+	migs := ParseMigrations(doc)
+
+	if len(migs) != 1 {
+		t.Fatalf("len(migs) == %d, wants 1", len(migs))
+	}
+
+	if migs[0].Version != 1 || migs[0].Description != "Hello" {
+		t.Errorf("Must parse headers without a space after the comment prefix, got %#v", migs[0])
+	}
+}
+
+func Test_ParseMigrations_invalid_version(t *testing.T) {
+	if migs := ParseMigrations("-- Version: one\nSELECT 1;\n"); migs != nil {
+		t.Errorf("Must not parse a document with an invalid version, got %#v", migs)
+	}
+}
+
+func Test_ParseMigrations_normalization(t *testing.T) {
+	unix := "-- Version: 1\n-- Description: Hello\nSELECT 1;\n\nSELECT 2;\n"
+	windows := "\ufeff-- Version: 1\r\n-- Description: Hello\r\nSELECT 1;\r\n\r\nSELECT 2;\r\n\r\n\x1a"
+	mac := "-- Version: 1\r-- Description: Hello\rSELECT 1;\r\rSELECT 2;\x00\x00"
+
+	expected := ParseMigrations(unix, WithNormalization())
+	if len(expected) != 1 {
+		t.Fatalf("len(migs) == %d, wants 1", len(expected))
+	}
+
+	for _, doc := range []string{windows, mac} {
+		migs := ParseMigrations(doc, WithNormalization())
+		if len(migs) != 1 {
+			t.Fatalf("len(migs) == %d, wants 1", len(migs))
+		}
+
+		if migs[0] != expected[0] {
+			t.Errorf("Expected %#v, got %#v", expected[0], migs[0])
+		}
+
+		if migs[0].Checksum() != expected[0].Checksum() {
+			t.Errorf("Must produce the same checksum regardless of the line endings")
+		}
+	}
+}
+
+func Test_ParseMigrations_byte_order_mark(t *testing.T) {
+	migs := ParseMigrations("\ufeff-- Version: 1\nSELECT 1;\n")
+
+	if len(migs) != 1 || migs[0].Version != 1 {
+		t.Errorf("Must ignore the byte order mark, got %#v", migs)
+	}
+}
+
+func Test_ParseMigrations_long_line(t *testing.T) {
+	long := strings.Repeat("x", 1<<20)
+	migs := ParseMigrations("-- Version: 1\n" + long + "\n-- Version: 2\nSELECT 1;\n")
+
+	if len(migs) != 2 {
+		t.Fatalf("len(migs) == %d, wants 2", len(migs))
+	}
+
+	if migs[0].Script != long+"\n" {
+		t.Errorf("Must not truncate long lines")
+	}
+}
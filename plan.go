@@ -0,0 +1,17 @@
+package darwin
+
+import "context"
+
+// PlanFunc receives the migrations about to be executed, in order. It is
+// called with the migration lock held, so the plan can't change before the
+// migrations run. Returning an error stops Migrate before any of them is
+// executed.
+type PlanFunc func(ctx context.Context, planned []Migration) error
+
+// WithPlan makes Migrate call f with the plan before executing it, to log
+// it, ask for an approval or report it.
+func WithPlan(f PlanFunc) Option {
+	return func(d *Darwin) {
+		d.plan = f
+	}
+}
@@ -0,0 +1,52 @@
+package darwin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func Test_WithPlan(t *testing.T) {
+	driver := &lockingDriver{dummyDriver: dummyDriver{records: []MigrationRecord{{Version: 1, Checksum: Migration{Script: "does not matter!"}.Checksum()}}}}
+
+	migrations := []Migration{
+		{Version: 1, Description: "First Migration", Script: "does not matter!"},
+		{Version: 2, Description: "Second Migration", Script: "does not matter!"},
+	}
+
+	var planned []Migration
+	plan := func(ctx context.Context, p []Migration) error {
+		driver.calls = append(driver.calls, "plan")
+		planned = p
+		return nil
+	}
+
+	if err := New(driver, migrations, WithPlan(plan)).Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(planned) != 1 || planned[0].Version != 2 {
+		t.Errorf("Must call the callback with the pending migrations, got %#v", planned)
+	}
+
+	if fmt.Sprint(driver.calls) != "[lock plan insert unlock]" {
+		t.Errorf("Must call the callback with the lock held, got %v", driver.calls)
+	}
+}
+
+func Test_WithPlan_rejected(t *testing.T) {
+	driver := &dummyDriver{}
+	rejected := errors.New("rejected")
+
+	migrations := []Migration{{Version: 1, Description: "First Migration", Script: "does not matter!"}}
+
+	err := New(driver, migrations, WithPlan(func(context.Context, []Migration) error { return rejected })).Migrate()
+	if err != rejected {
+		t.Errorf("Must return the error of the callback, got %v", err)
+	}
+
+	if all, _ := driver.All(); len(all) != 0 {
+		t.Errorf("Must not execute a rejected plan, got %v", all)
+	}
+}
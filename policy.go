@@ -0,0 +1,102 @@
+package darwin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Policy is a lint rule Migrate enforces on the plan in some environments,
+// like no destructive statement in production.
+type Policy struct {
+	Rule LintRule
+
+	// Environments are the environments given to WithEnvironment enforcing
+	// the rule, compared without case; all of them when empty.
+	Environments []string
+}
+
+// applies reports whether the policy is enforced in the environment.
+func (p Policy) applies(env string) bool {
+	if len(p.Environments) == 0 {
+		return true
+	}
+	for _, e := range p.Environments {
+		if strings.EqualFold(e, env) {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyError is used to report the migrations of the plan violating the
+// policies of the environment.
+type PolicyError struct {
+	Environment string
+	Findings    []Finding
+}
+
+func (p PolicyError) Error() string {
+	findings := make([]string, len(p.Findings))
+	for i, f := range p.Findings {
+		findings[i] = f.String()
+	}
+	if p.Environment == "" {
+		return fmt.Sprintf("darwin: the migrations violate the policies: %s", strings.Join(findings, "; "))
+	}
+	return fmt.Sprintf("darwin: the migrations violate the policies of %s: %s", p.Environment, strings.Join(findings, "; "))
+}
+
+// TicketReference returns the ticket rule: the description of the
+// migrations must reference a ticket, matching pattern, like
+// regexp.MustCompile(`\b[A-Z]+-\d+\b`).
+func TicketReference(pattern *regexp.Regexp) LintRule {
+	return LintRule{Name: "ticket", Check: func(m Migration) []Finding {
+		if !pattern.MatchString(m.Description) {
+			return []Finding{{Message: "the description references no ticket"}}
+		}
+		return nil
+	}}
+}
+
+// PolicyGate returns a PlanFunc refusing the plan with a PolicyError when
+// its migrations violate the policies enforced in the environment.
+func PolicyGate(env string, policies ...Policy) PlanFunc {
+	var rules []LintRule
+	for _, p := range policies {
+		if p.applies(env) {
+			rules = append(rules, p.Rule)
+		}
+	}
+
+	return func(ctx context.Context, planned []Migration) error {
+		if len(rules) == 0 {
+			return nil
+		}
+
+		migrations := make([]Migration, len(planned))
+		for i, m := range planned {
+			loaded, err := m.Load()
+			if err != nil {
+				return err
+			}
+			migrations[i] = loaded
+		}
+
+		if findings := Lint(migrations, rules...); len(findings) > 0 {
+			return PolicyError{Environment: env, Findings: findings}
+		}
+		return nil
+	}
+}
+
+// WithPolicy makes Migrate refuse to execute a plan violating the policies
+// enforced in the environment of WithEnvironment, with PolicyGate, so the
+// rules reviews rely on hold at deploy time. It is checked before the
+// function of WithPlan.
+func WithPolicy(policies ...Policy) Option {
+	return func(d *Darwin) {
+		d.policies = append(d.policies, policies...)
+	}
+}
@@ -0,0 +1,46 @@
+package darwin
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+)
+
+func Test_WithPolicy(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Description: "OPS-1 Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Cleanup", Script: "CREATE TABLE roles (id INT);\nDROP TABLE legacy;"},
+	}
+
+	policies := []Policy{
+		{Rule: DestructiveRule, Environments: []string{"prod"}},
+		{Rule: TicketReference(regexp.MustCompile(`\b[A-Z]+-\d+\b`))},
+	}
+
+	production := &dummyDriver{}
+	err := New(production, migrations, WithEnvironment("PROD"), WithPolicy(policies...)).Migrate()
+
+	var policy PolicyError
+	if !errors.As(err, &policy) || len(policy.Findings) != 2 {
+		t.Fatalf("Must refuse the migrations violating the policies, got %v", err)
+	}
+
+	expected := "darwin: the migrations violate the policies of PROD: version 2:0: ticket: the description references no ticket; version 2:2: destructive: DROP TABLE loses data"
+	if policy.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, policy.Error())
+	}
+
+	if all, _ := production.All(); len(all) != 0 {
+		t.Errorf("Must not execute any migration, got %#v", all)
+	}
+
+	migrations[1].Description = "OPS-2 Cleanup"
+	staging := &dummyDriver{}
+	if err := New(staging, migrations, WithEnvironment("staging"), WithPolicy(policies...)).Migrate(); err != nil {
+		t.Fatalf("Must not enforce the policies of other environments, got %s", err)
+	}
+
+	if all, _ := staging.All(); len(all) != 2 {
+		t.Errorf("Must execute the migrations, got %#v", all)
+	}
+}
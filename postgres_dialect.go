@@ -1,11 +1,17 @@
 package darwin
 
+import "fmt"
+
 // PostgresDialect a Dialect configured for PostgreSQL.
-type PostgresDialect struct{}
+type PostgresDialect struct {
+	// Table is the name of the history table, qualified by its schema or
+	// not, darwin_migrations by default.
+	Table string
+}
 
 // CreateTableSQL returns the SQL to create the schema table.
 func (p PostgresDialect) CreateTableSQL() string {
-	return `CREATE TABLE IF NOT EXISTS darwin_migrations
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
                 (
                     id             SERIAL                  NOT NULL,
                     version        REAL                    NOT NULL,
@@ -13,14 +19,19 @@ func (p PostgresDialect) CreateTableSQL() string {
                     checksum       CHARACTER VARYING (32)  NOT NULL,
                     applied_at     INTEGER                 NOT NULL,
                     execution_time REAL                    NOT NULL,
+                    applied_by     VARCHAR(255),
+                    hostname       VARCHAR(255),
+                    app_version    VARCHAR(255),
+                    source         VARCHAR(255),
+                    script         TEXT,
                     UNIQUE         (version),
                     PRIMARY KEY    (id)
-                );`
+                );`, p.table())
 }
 
 // InsertSQL returns the SQL to insert a new migration in the schema table.
 func (p PostgresDialect) InsertSQL() string {
-	return `INSERT INTO darwin_migrations
+	return fmt.Sprintf(`INSERT INTO %s
                 (
                     version,
                     description,
@@ -28,18 +39,132 @@ func (p PostgresDialect) InsertSQL() string {
                     applied_at,
                     execution_time
                 )
-            VALUES ($1, $2, $3, $4, $5);`
+            VALUES ($1, $2, $3, $4, $5);`, p.table())
 }
 
 // AllSQL returns a SQL to get all entries in the table.
 func (p PostgresDialect) AllSQL() string {
-	return `SELECT 
+	return fmt.Sprintf(`SELECT 
                 version,
                 description,
                 checksum,
                 applied_at,
                 execution_time
             FROM 
-                darwin_migrations
-            ORDER BY version ASC;`
+                %s
+            ORDER BY version ASC;`, p.table())
+}
+
+// DeleteSQL returns the SQL to delete the record of a migration.
+func (p PostgresDialect) DeleteSQL() string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE version = $1;`, p.table())
+}
+
+// InsertMetadataSQL returns the SQL to insert a new migration in the schema
+// table with its metadata.
+func (p PostgresDialect) InsertMetadataSQL() string {
+	return fmt.Sprintf(`INSERT INTO %s
+                (
+                    version,
+                    description,
+                    checksum,
+                    applied_at,
+                    execution_time,
+                    applied_by,
+                    hostname,
+                    app_version,
+                    source,
+                    script
+                )
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);`, p.table())
+}
+
+// AllMetadataSQL returns a SQL to get all entries in the table with their
+// metadata.
+func (p PostgresDialect) AllMetadataSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time,
+                applied_by,
+                hostname,
+                app_version,
+                source
+            FROM
+                %s
+            ORDER BY version ASC;`, p.table())
+}
+
+// AllSinceSQL returns a SQL to get the entries in the table from a version
+// on, with their metadata.
+func (p PostgresDialect) AllSinceSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time,
+                applied_by,
+                hostname,
+                app_version,
+                source
+            FROM
+                %s
+            WHERE
+                version >= $1
+            ORDER BY version ASC;`, p.table())
+}
+
+// LatestVersionSQL returns a SQL to get the greatest version in the table,
+// NULL when it is empty.
+func (p PostgresDialect) LatestVersionSQL() string {
+	return fmt.Sprintf("SELECT MAX(version) FROM %s;", p.table())
+}
+
+// HistoryUpgrades returns the upgrades of the schema table to its latest
+// layout.
+func (p PostgresDialect) HistoryUpgrades() []HistoryUpgrade {
+	return historyUpgrades(p.table(), "ADD COLUMN IF NOT EXISTS", "VARCHAR(255)", "TEXT", "1 = 0")
+}
+
+// ScriptSQL returns the SQL to get the script stored with a migration.
+func (p PostgresDialect) ScriptSQL() string {
+	return fmt.Sprintf(`SELECT script FROM %s WHERE version = $1;`, p.table())
+}
+
+// LockSQL returns the SQL to acquire the migration lock.
+func (p PostgresDialect) LockSQL() string {
+	return fmt.Sprintf(`SELECT pg_advisory_lock(hashtext(%s));`, lockKey(p.Table))
+}
+
+// UnlockSQL returns the SQL to release the migration lock.
+func (p PostgresDialect) UnlockSQL() string {
+	return fmt.Sprintf(`SELECT pg_advisory_unlock(hashtext(%s));`, lockKey(p.Table))
+}
+
+// SupportsTransactionalDDL returns true, Postgres rolls back schema changes.
+func (p PostgresDialect) SupportsTransactionalDDL() bool {
+	return true
+}
+
+// Syntax returns the syntax of the statements.
+func (p PostgresDialect) Syntax() Syntax {
+	return PostgresSyntax
+}
+
+// SetSQL returns the SQL to change a session setting.
+func (p PostgresDialect) SetSQL(name, value string) string {
+	return fmt.Sprintf("SET %s = %s", name, value)
+}
+
+// ResetSQL returns the SQL to restore a session setting.
+func (p PostgresDialect) ResetSQL(name string) string {
+	return fmt.Sprintf("RESET %s", name)
+}
+
+// IndexValidSQL returns the SQL telling if an index is valid.
+func (p PostgresDialect) IndexValidSQL() string {
+	return `SELECT indisvalid FROM pg_index WHERE indexrelid = to_regclass($1);`
 }
@@ -0,0 +1,85 @@
+package darwin
+
+import "context"
+
+// Event is an event of the progress of Migrate: PlanComputed,
+// MigrationStarted, StatementExecuted, MigrationFinished or ErrorEvent.
+type Event interface {
+	event()
+}
+
+// PlanComputed is sent with the migrations about to be executed.
+type PlanComputed struct {
+	Planned []Migration
+}
+
+// MigrationStarted is sent before a migration is executed. With
+// WithSingleTransaction the MigrationFinished events are sent once the
+// transaction is committed.
+type MigrationStarted struct {
+	Migration Migration
+}
+
+// StatementExecuted is sent for each statement of an executed migration,
+// when the driver is a StatementExecer: as it executes when the driver
+// calls ReportStatement, or else once the migration is executed.
+type StatementExecuted struct {
+	Migration Migration
+	Statement StatementResult
+}
+
+// MigrationFinished is sent once a migration is executed and recorded.
+type MigrationFinished struct {
+	Result Result
+}
+
+// ErrorEvent is sent when Migrate fails, as the last event.
+type ErrorEvent struct {
+	Err error
+}
+
+func (PlanComputed) event()      {}
+func (MigrationStarted) event()  {}
+func (StatementExecuted) event() {}
+func (MigrationFinished) event() {}
+func (ErrorEvent) event()        {}
+
+// WithProgress makes Migrate call f with the events of its progress.
+func WithProgress(f func(ctx context.Context, e Event)) Option {
+	return func(d *Darwin) {
+		d.progress = f
+	}
+}
+
+// MigrateWithProgress executes the missing migrations in database like
+// MigrateContext, in a goroutine, and streams the events of its progress.
+// The channel is closed once Migrate returns, a failure being reported by
+// an ErrorEvent; it must be drained unless ctx is canceled.
+func (d Darwin) MigrateWithProgress(ctx context.Context) <-chan Event {
+	events := make(chan Event, 16)
+
+	listener := d.progress
+	d.progress = func(ctx context.Context, e Event) {
+		if listener != nil {
+			listener(ctx, e)
+		}
+		select {
+		case events <- e:
+		case <-ctx.Done():
+		}
+	}
+
+	go func() {
+		defer close(events)
+		d.migrate(ctx)
+	}()
+
+	return events
+}
+
+// emit sends the event to the progress listener, if any.
+func (d Darwin) emit(ctx context.Context, e Event) {
+	if d.progress != nil {
+		d.progress(ctx, e)
+	}
+}
@@ -0,0 +1,101 @@
+package darwin
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_Darwin_MigrateWithProgress(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+
+	var events []string
+	for e := range New(&dummyDriver{}, migrations).MigrateWithProgress(context.Background()) {
+		switch e := e.(type) {
+		case PlanComputed:
+			events = append(events, fmt.Sprintf("plan %d", len(e.Planned)))
+		case MigrationStarted:
+			events = append(events, fmt.Sprintf("started %g", e.Migration.Version))
+		case MigrationFinished:
+			events = append(events, fmt.Sprintf("finished %g", e.Result.Migration.Version))
+		default:
+			events = append(events, fmt.Sprintf("%#v", e))
+		}
+	}
+
+	expected := []string{"plan 2", "started 1", "finished 1", "started 2", "finished 2"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected %q, got %q", expected, events)
+	}
+}
+
+func Test_Darwin_MigrateWithProgress_error(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+	}
+
+	var last Event
+	for e := range New(&dummyDriver{ExecError: true}, migrations).MigrateWithProgress(context.Background()) {
+		last = e
+	}
+
+	if e, ok := last.(ErrorEvent); !ok || e.Err == nil {
+		t.Errorf("Must end with an ErrorEvent, got %#v", last)
+	}
+}
+
+// statementTx is a fakeTx reporting its statements as they execute.
+type statementTx struct {
+	fakeTx
+}
+
+func (s *statementTx) ExecStatements(ctx context.Context, script string) ([]StatementResult, error) {
+	s.d.calls = append(s.d.calls, "tx.exec")
+	r := StatementResult{Statement: script, Duration: time.Millisecond}
+	ReportStatement(ctx, r)
+	return []StatementResult{r}, nil
+}
+
+// statementDriver is a txDriver whose transactions are statementTxs.
+type statementDriver struct {
+	txDriver
+}
+
+func (d *statementDriver) BeginTx(ctx context.Context) (Tx, error) {
+	d.calls = append(d.calls, "begin")
+	return &statementTx{fakeTx{d: &d.txDriver}}, nil
+}
+
+func Test_Darwin_MigrateWithProgress_single_transaction(t *testing.T) {
+	driver := &statementDriver{}
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+
+	var events []string
+	listener := func(ctx context.Context, e Event) {
+		switch e := e.(type) {
+		case MigrationStarted:
+			events = append(events, fmt.Sprintf("started %g", e.Migration.Version))
+		case StatementExecuted:
+			events = append(events, fmt.Sprintf("statement %g %d", e.Migration.Version, len(driver.calls)))
+		case MigrationFinished:
+			events = append(events, fmt.Sprintf("finished %g", e.Result.Migration.Version))
+		}
+	}
+
+	if err := New(driver, migrations, WithSingleTransaction(), WithProgress(listener)).Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := []string{"started 1", "statement 1 2", "started 2", "statement 2 4", "finished 1", "finished 2"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Must send the statements as they execute, expected %q, got %q", expected, events)
+	}
+}
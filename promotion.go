@@ -0,0 +1,61 @@
+package darwin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PromotionError is used to report migrations which can't be promoted to
+// the target database, not being applied to the source database first, or
+// applied with a different script.
+type PromotionError struct {
+	Versions []float64
+}
+
+func (p PromotionError) Error() string {
+	versions := make([]string, len(p.Versions))
+	for i, v := range p.Versions {
+		versions[i] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprintf("darwin: migrations %s must be applied to the source environment first", strings.Join(versions, ", "))
+}
+
+// PromotionGate returns a PlanFunc refusing the plan unless each of its
+// migrations was applied, with the same checksum, to the database of
+// source, like staging before production.
+func PromotionGate(source Driver) PlanFunc {
+	return func(ctx context.Context, planned []Migration) error {
+		records, err := source.All()
+		if err != nil {
+			return err
+		}
+
+		applied := map[float64]string{}
+		for _, r := range records {
+			applied[r.Version] = r.Checksum
+		}
+
+		var missing []float64
+		for _, m := range planned {
+			if checksum, ok := applied[m.Version]; !ok || checksum != m.Checksum() {
+				missing = append(missing, m.Version)
+			}
+		}
+
+		if len(missing) > 0 {
+			return PromotionError{Versions: missing}
+		}
+		return nil
+	}
+}
+
+// WithPromotionGate makes Migrate refuse to execute migrations which the
+// database of source didn't apply first, with PromotionGate. It is checked
+// before the function of WithPlan.
+func WithPromotionGate(source Driver) Option {
+	return func(d *Darwin) {
+		d.gates = append(d.gates, PromotionGate(source))
+	}
+}
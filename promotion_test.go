@@ -0,0 +1,36 @@
+package darwin
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_WithPromotionGate(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+		{Version: 3, Description: "Grants", Script: "CREATE TABLE grants (id INT);"},
+	}
+
+	staging := &dummyDriver{records: []MigrationRecord{
+		{Version: 1, Checksum: migrations[0].Checksum()},
+		{Version: 2, Checksum: "edited"},
+	}}
+
+	production := &dummyDriver{}
+	err := New(production, migrations, WithPromotionGate(staging)).Migrate()
+
+	var promotion PromotionError
+	if !errors.As(err, &promotion) || !reflect.DeepEqual(promotion.Versions, []float64{2, 3}) {
+		t.Fatalf("Must refuse the migrations not applied to staging, got %v", err)
+	}
+
+	if all, _ := production.All(); len(all) != 0 {
+		t.Errorf("Must not execute any migration, got %#v", all)
+	}
+
+	if err := New(production, migrations[:1], WithPromotionGate(staging)).Migrate(); err != nil {
+		t.Fatalf("Must promote the migrations applied to staging, got %s", err)
+	}
+}
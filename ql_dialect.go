@@ -1,25 +1,37 @@
 package darwin
 
+import "fmt"
+
 //QLDialect implements Dialect interface for ql database.
-type QLDialect struct{}
+type QLDialect struct {
+	// Table is the name of the history table, darwin_migrations by default.
+	// QL has no schemas nor quoted identifiers, the name must be an
+	// identifier.
+	Table string
+}
 
 // CreateTableSQL returns the SQL to create the schema table.
-func (QLDialect) CreateTableSQL() string {
-	return `
-CREATE TABLE IF NOT EXISTS darwin_migrations(
+func (q QLDialect) CreateTableSQL() string {
+	return fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s(
 	version float,
 	description string,
 	checksum string,
 	applied_at int64,
 	execution_time int64,
+	applied_by string,
+	hostname string,
+	app_version string,
+	source string,
+	script string,
 );
-CREATE UNIQUE INDEX IF NOT EXISTS idx_versions on darwin_migrations(version);
-	`
+CREATE UNIQUE INDEX IF NOT EXISTS %s on %s(version);
+	`, q.table(), q.index(), q.table())
 }
 
 // InsertSQL returns the SQL to insert a new migration in the schema table.
-func (QLDialect) InsertSQL() string {
-	return `INSERT INTO darwin_migrations
+func (q QLDialect) InsertSQL() string {
+	return fmt.Sprintf(`INSERT INTO %s
                 (
                     version,
                     description,
@@ -27,18 +39,102 @@ func (QLDialect) InsertSQL() string {
                     applied_at,
                     execution_time
                 )
-            VALUES ($1, $2, $3, $4, $5);`
+            VALUES ($1, $2, $3, $4, $5);`, q.table())
 }
 
 // AllSQL returns a SQL to get all entries in the table.
-func (QLDialect) AllSQL() string {
-	return `SELECT
+func (q QLDialect) AllSQL() string {
+	return fmt.Sprintf(`SELECT
                 version,
                 description,
                 checksum,
                 applied_at,
                 execution_time
             FROM 
-                darwin_migrations
-            ORDER BY version ASC;`
+                %s
+            ORDER BY version ASC;`, q.table())
+}
+
+// DeleteSQL returns the SQL to delete the record of a migration.
+func (q QLDialect) DeleteSQL() string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE version == $1;`, q.table())
+}
+
+// InsertMetadataSQL returns the SQL to insert a new migration in the schema
+// table with its metadata.
+func (q QLDialect) InsertMetadataSQL() string {
+	return fmt.Sprintf(`INSERT INTO %s
+                (
+                    version,
+                    description,
+                    checksum,
+                    applied_at,
+                    execution_time,
+                    applied_by,
+                    hostname,
+                    app_version,
+                    source,
+                    script
+                )
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);`, q.table())
+}
+
+// AllMetadataSQL returns a SQL to get all entries in the table with their
+// metadata.
+func (q QLDialect) AllMetadataSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time,
+                applied_by,
+                hostname,
+                app_version,
+                source
+            FROM
+                %s
+            ORDER BY version ASC;`, q.table())
+}
+
+// AllSinceSQL returns a SQL to get the entries in the table from a version
+// on, with their metadata.
+func (q QLDialect) AllSinceSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time,
+                applied_by,
+                hostname,
+                app_version,
+                source
+            FROM
+                %s
+            WHERE
+                version >= $1
+            ORDER BY version ASC;`, q.table())
+}
+
+// LatestVersionSQL returns a SQL to get the greatest version in the table,
+// NULL when it is empty.
+func (q QLDialect) LatestVersionSQL() string {
+	return fmt.Sprintf("SELECT max(version) FROM %s;", q.table())
+}
+
+// HistoryUpgrades returns the upgrades of the schema table to its latest
+// layout.
+func (q QLDialect) HistoryUpgrades() []HistoryUpgrade {
+	return historyUpgrades(q.table(), "ADD", "string", "string", "false")
+}
+
+// ScriptSQL returns the SQL to get the script stored with a migration.
+func (q QLDialect) ScriptSQL() string {
+	return fmt.Sprintf(`SELECT script FROM %s WHERE version == $1;`, q.table())
+}
+
+// SupportsTransactionalDDL returns true, QL rolls back schema changes.
+func (q QLDialect) SupportsTransactionalDDL() bool {
+	return true
 }
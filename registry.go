@@ -0,0 +1,69 @@
+package darwin
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	dialectsMu sync.RWMutex
+	dialects   = map[string]Dialect{
+		"mysql":    MySQLDialect{},
+		"postgres": PostgresDialect{},
+		"ql":       QLDialect{},
+		"sqlite3":  SqliteDialect{},
+	}
+)
+
+// RegisterDialect makes a dialect available by name to OpenDriver, like
+// sql.Register does for database drivers. It is meant to be called from the
+// init function of the package providing the dialect. RegisterDialect panics
+// if the dialect is nil or if a dialect is already registered with the name.
+func RegisterDialect(name string, d Dialect) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+
+	if d == nil {
+		panic("darwin: RegisterDialect dialect is nil")
+	}
+	if _, dup := dialects[name]; dup {
+		panic("darwin: RegisterDialect called twice for dialect " + name)
+	}
+	dialects[name] = d
+}
+
+// Dialects returns a sorted list of the names of the registered dialects.
+func Dialects() []string {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+
+	names := make([]string, 0, len(dialects))
+	for name := range dialects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupDialect returns the dialect registered with the name.
+func LookupDialect(name string) (Dialect, bool) {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+
+	d, ok := dialects[name]
+	return d, ok
+}
+
+// OpenDriver returns a GenericDriver for db using the dialect registered with
+// the name, which is usually the name of the database/sql driver:
+//
+//	driver, err := darwin.OpenDriver("postgres", db)
+func OpenDriver(name string, db *sql.DB) (*GenericDriver, error) {
+	d, ok := LookupDialect(name)
+	if !ok {
+		return nil, fmt.Errorf("darwin: unknown dialect %q (forgotten import?)", name)
+	}
+	return NewGenericDriver(db, d)
+}
@@ -0,0 +1,57 @@
+package darwin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func Test_OpenDriver(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, err := OpenDriver("postgres", db)
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if _, ok := d.Dialect.(PostgresDialect); !ok {
+		t.Errorf("Expected PostgresDialect, got %T", d.Dialect)
+	}
+
+	if _, err := OpenDriver("oracle", db); err == nil || !strings.Contains(err.Error(), "oracle") {
+		t.Errorf("Must return an error for unknown dialects, got %v", err)
+	}
+}
+
+func Test_RegisterDialect(t *testing.T) {
+	RegisterDialect("test_oracle", StandardDialect{Placeholder: PlaceholderColon})
+	defer func() {
+		dialectsMu.Lock()
+		delete(dialects, "test_oracle")
+		dialectsMu.Unlock()
+	}()
+
+	if _, ok := LookupDialect("test_oracle"); !ok {
+		t.Errorf("Must find the registered dialect")
+	}
+
+	found := false
+	for _, name := range Dialects() {
+		found = found || name == "test_oracle"
+	}
+	if !found {
+		t.Errorf("Dialects must list the registered dialect, got %v", Dialects())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Must panic when a dialect is registered twice")
+		}
+	}()
+	RegisterDialect("test_oracle", StandardDialect{})
+}
@@ -0,0 +1,60 @@
+package darwin
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// RenderInfoTable writes the status of the migrations to w as an aligned
+// text table, with when and how long the applied ones took.
+func RenderInfoTable(w io.Writer, infos []MigrationInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "VERSION\tDESCRIPTION\tSTATUS\tAPPLIED AT\tDURATION")
+	for _, i := range infos {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", infoColumns(i)...)
+	}
+
+	return tw.Flush()
+}
+
+// RenderInfoMarkdown writes the status of the migrations to w as a Markdown
+// table, for pull request comments.
+func RenderInfoMarkdown(w io.Writer, infos []MigrationInfo) error {
+	var b strings.Builder
+
+	b.WriteString("| Version | Description | Status | Applied at | Duration |\n")
+	b.WriteString("|--------:|-------------|--------|------------|---------:|\n")
+	for _, i := range infos {
+		columns := infoColumns(i)
+		for n, c := range columns {
+			columns[n] = strings.NewReplacer("|", `\|`, "\n", " ").Replace(c.(string))
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", columns...)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// infoColumns returns the columns of a migration in the tables, "-" when
+// not applied.
+func infoColumns(i MigrationInfo) []interface{} {
+	appliedAt, duration := "-", "-"
+	if !i.AppliedAt.IsZero() {
+		appliedAt = i.AppliedAt.UTC().Format(time.RFC3339)
+		duration = i.ExecutionTime.Round(time.Millisecond).String()
+	}
+
+	return []interface{}{
+		strconv.FormatFloat(i.Migration.Version, 'f', -1, 64),
+		i.Migration.Description,
+		i.Status.String(),
+		appliedAt,
+		duration,
+	}
+}
@@ -0,0 +1,51 @@
+package darwin
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+var renderedInfos = []MigrationInfo{
+	{
+		Status:        Applied,
+		Migration:     Migration{Version: 1, Description: "Create users"},
+		AppliedAt:     time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC),
+		ExecutionTime: 1234567 * time.Microsecond,
+	},
+	{
+		Status:    Pending,
+		Migration: Migration{Version: 1.1, Description: "Split a|b"},
+	},
+}
+
+func Test_RenderInfoTable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderInfoTable(&buf, renderedInfos); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := `VERSION  DESCRIPTION   STATUS   APPLIED AT            DURATION
+1        Create users  APPLIED  2021-03-04T05:06:07Z  1.235s
+1.1      Split a|b     PENDING  -                     -
+`
+	if buf.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func Test_RenderInfoMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderInfoMarkdown(&buf, renderedInfos); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := `| Version | Description | Status | Applied at | Duration |
+|--------:|-------------|--------|------------|---------:|
+| 1 | Create users | APPLIED | 2021-03-04T05:06:07Z | 1.235s |
+| 1.1 | Split a\|b | PENDING | - | - |
+`
+	if buf.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,176 @@
+package darwin
+
+import (
+	"context"
+	"time"
+)
+
+// StatementResult is the outcome of a statement of a migration.
+type StatementResult struct {
+	Statement    string
+	RowsAffected int64
+	Duration     time.Duration
+}
+
+// StatementExecer is implemented by drivers, and by the transactions of
+// Transactors, able to report the rows affected by each statement of a
+// script and how long it took. Migrate uses it instead of Exec when
+// available.
+//
+// A driver overriding the ExecContext method of an embedded driver
+// implementing StatementExecer must override ExecStatements as well.
+type StatementExecer interface {
+	ExecStatements(ctx context.Context, script string) ([]StatementResult, error)
+}
+
+// observerKey is the context key of the statementObserver of Migrate.
+type observerKey struct{}
+
+// statementObserver is told about the statements of a migration as they
+// execute.
+type statementObserver struct {
+	migration Migration
+	executing func(statement string)
+	executed  func(s StatementResult)
+}
+
+// ReportExecuting tells Migrate the statement of the script given to
+// ExecStatements with ctx is about to execute, for WithSQLEcho to log it
+// before it runs. StatementExecers call it before each statement.
+func ReportExecuting(ctx context.Context, statement string) {
+	if o, ok := ctx.Value(observerKey{}).(*statementObserver); ok && o.executing != nil {
+		o.executing(statement)
+	}
+}
+
+// ReportStatement tells Migrate the statement of the script given to
+// ExecStatements with ctx is executed, for its StatementExecuted event to
+// be sent live. StatementExecers call it after each statement; the
+// statements they don't report are sent once the migration is executed.
+func ReportStatement(ctx context.Context, s StatementResult) {
+	if o, ok := ctx.Value(observerKey{}).(*statementObserver); ok && o.executed != nil {
+		o.executed(s)
+	}
+}
+
+// ExecutingMigration returns the migration Migrate executes with ctx, for
+// the drivers keeping the progress of each migration, if any.
+func ExecutingMigration(ctx context.Context) (Migration, bool) {
+	o, ok := ctx.Value(observerKey{}).(*statementObserver)
+	if !ok {
+		return Migration{}, false
+	}
+	return o.migration, true
+}
+
+// observe returns ctx echoing the statements of m about to execute, and
+// reporting those executed to the progress listener, counting them in
+// reported, unless reported is nil.
+func (d Darwin) observe(ctx context.Context, m Migration, reported map[float64]int) context.Context {
+	o := &statementObserver{
+		migration: m,
+		executing: func(statement string) {
+			d.echoExecuting(m, statement)
+		},
+	}
+	if reported != nil {
+		o.executed = func(s StatementResult) {
+			reported[m.Version]++
+			d.emit(ctx, StatementExecuted{Migration: m, Statement: s})
+		}
+	}
+	return context.WithValue(ctx, observerKey{}, o)
+}
+
+// Result is the outcome of an executed migration. Statements is empty when
+// the driver can't report them.
+type Result struct {
+	Migration  Migration
+	Duration   time.Duration
+	Statements []StatementResult
+}
+
+// RowsAffected returns the number of rows affected by the statements of the
+// migration.
+func (r Result) RowsAffected() int64 {
+	var rows int64
+	for _, s := range r.Statements {
+		rows += s.RowsAffected
+	}
+	return rows
+}
+
+// Report is the outcome of Migrate, with the results of the executed
+// migrations in order, then those of the seeds of WithSeeds.
+type Report struct {
+	Results []Result
+	Seeds   []Result
+
+	// Skipped are the migrations of a single transaction skipped by
+	// WithSavepoints, left unrecorded.
+	Skipped []SkippedMigration
+}
+
+// RowsAffected returns the number of rows affected by all the migrations.
+func (r Report) RowsAffected() int64 {
+	var rows int64
+	for _, result := range r.Results {
+		rows += result.RowsAffected()
+	}
+	return rows
+}
+
+// StatementHook is called after each statement executed by Migrate, when
+// the driver is a StatementExecer.
+type StatementHook func(ctx context.Context, m Migration, s StatementResult)
+
+// WithStatementHook makes Migrate call h after each statement executed, for
+// example to warn about a migration rewriting more rows than expected.
+func WithStatementHook(h StatementHook) Option {
+	return func(d *Darwin) {
+		d.onStatement = h
+	}
+}
+
+// MigrateReport executes the missing migrations in database like
+// MigrateContext, and reports on each of them. The report holds the
+// migrations executed before a failure.
+func (d Darwin) MigrateReport(ctx context.Context) (Report, error) {
+	return d.migrate(ctx)
+}
+
+// executed records the result of a migration in the report, logs and
+// measures it, and calls the statement hook and the progress listener with
+// the statements not reported live.
+func (d Darwin) executed(ctx context.Context, report *Report, r Result, reported int) {
+	report.Results = append(report.Results, r)
+
+	d.echoResult(r)
+
+	d.logger().Info("darwin: migration finished",
+		"version", r.Migration.Version,
+		"description", r.Migration.Description,
+		"rows_affected", r.RowsAffected(),
+		"duration", r.Duration)
+	d.measures().MigrationApplied(r.Migration, r.Duration)
+
+	for i, s := range r.Statements {
+		if d.onStatement != nil {
+			d.onStatement(ctx, r.Migration, s)
+		}
+		if i >= reported {
+			d.emit(ctx, StatementExecuted{Migration: r.Migration, Statement: s})
+		}
+	}
+
+	d.emit(ctx, MigrationFinished{Result: r})
+}
+
+// sumDurations returns the total duration of the statements.
+func sumDurations(statements []StatementResult) time.Duration {
+	var total time.Duration
+	for _, s := range statements {
+		total += s.Duration
+	}
+	return total
+}
@@ -0,0 +1,135 @@
+package darwin
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func Test_Darwin_MigrateReport(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	dialect := PostgresDialect{}
+	d, _ := NewGenericDriver(db, dialect)
+
+	migrations := []Migration{
+		{Version: 1, Description: "Backfill", Script: "ALTER TABLE users ADD COLUMN active BOOL;\nUPDATE users SET active = true;"},
+	}
+
+	mock.ExpectExec(escapeQuery(dialect.LockSQL())).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectExec(escapeQuery(dialect.CreateTableSQL())).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(escapeQuery(latestProbe(dialect))).WillReturnRows(sqlmock.NewRows([]string{"script"}))
+	mock.ExpectQuery(escapeQuery(dialect.LatestVersionSQL())).WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+	mock.ExpectBegin()
+	mock.ExpectExec(escapeQuery("ALTER TABLE users ADD COLUMN active BOOL")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(escapeQuery("UPDATE users SET active = true")).WillReturnResult(sqlmock.NewResult(0, 80))
+	mock.ExpectExec(escapeQuery(dialect.InsertMetadataSQL())).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(escapeQuery(dialect.UnlockSQL())).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	var hooked []StatementResult
+	hook := func(ctx context.Context, m Migration, s StatementResult) {
+		hooked = append(hooked, s)
+	}
+
+	report, err := New(d, migrations, WithStatementHook(hook)).MigrateReport(context.Background())
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(report.Results) != 1 || len(report.Results[0].Statements) != 2 {
+		t.Fatalf("Must report each statement, got %#v", report)
+	}
+
+	if report.RowsAffected() != 80 || report.Results[0].Statements[1].Statement != "UPDATE users SET active = true" {
+		t.Errorf("Must report the rows affected, got %#v", report.Results[0].Statements)
+	}
+
+	if len(hooked) != 2 || hooked[1].RowsAffected != 80 {
+		t.Errorf("Must call the hook with each statement, got %#v", hooked)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_Darwin_MigrateReport_without_statements(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+
+	report, err := New(&dummyDriver{}, migrations).MigrateReport(context.Background())
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(report.Results) != 2 || report.Results[1].Migration.Version != 2 || len(report.Results[1].Statements) != 0 {
+		t.Errorf("Must report the migrations executed, got %#v", report)
+	}
+}
+
+func Test_Migrate_ExecutionError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	dialect := QLDialect{}
+	d, _ := NewGenericDriver(db, dialect)
+
+	migrations := []Migration{
+		{Version: 1.5, Description: "Backfill", Script: "CREATE TABLE users (id int);\nUPDATE users\n    SET id = id + 1, name = 'a rather long name making the statement longer than its excerpt';"},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(escapeQuery(dialect.CreateTableSQL())).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(escapeQuery(latestProbe(dialect))).WillReturnRows(sqlmock.NewRows([]string{"script"}))
+	mock.ExpectQuery(escapeQuery(dialect.LatestVersionSQL())).WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+	mock.ExpectBegin()
+	mock.ExpectExec(escapeQuery("CREATE TABLE users (id int)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE users").WillReturnError(errors.New("Generic Error"))
+	mock.ExpectRollback()
+
+	err = Migrate(d, migrations)
+
+	var execErr ExecutionError
+	if !errors.As(err, &execErr) {
+		t.Fatalf("Must return an ExecutionError, got %v", err)
+	}
+
+	if execErr.Version != 1.5 || execErr.Statement != 1 || !strings.HasPrefix(execErr.SQL, "UPDATE users") {
+		t.Errorf("Must report the failed statement, got %#v", execErr)
+	}
+
+	expected := "UPDATE users SET id = id + 1, name = 'a rather long name making the statement..."
+	if execErr.Excerpt() != expected {
+		t.Errorf("Expected %q, got %q", expected, execErr.Excerpt())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_ExecutionError_Excerpt_multibyte(t *testing.T) {
+	e := ExecutionError{SQL: "INSERT INTO greetings (text) VALUES ('" + strings.Repeat("é", 100) + "')"}
+
+	excerpt := e.Excerpt()
+	if !utf8.ValidString(excerpt) || utf8.RuneCountInString(excerpt) != excerptLength {
+		t.Errorf("Must truncate the statement by character, got %q", excerpt)
+	}
+}
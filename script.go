@@ -0,0 +1,152 @@
+package darwin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// gzipPrefix starts the compressed scripts stored in the history.
+const gzipPrefix = "gzip:"
+
+// defaultMaxScriptSize is the size of the largest script stored by default.
+const defaultMaxScriptSize = 1 << 20
+
+// ScriptStorage configures the scripts stored in the history by
+// WithStoredScripts.
+type ScriptStorage struct {
+	// Compress stores the scripts gzipped.
+	Compress bool
+
+	// MaxSize is the size in bytes of the largest script stored, once
+	// compressed, 1 MiB when zero. The larger scripts are recorded without
+	// their script, with a warning.
+	MaxSize int
+}
+
+// WithStoredScripts stores the script of each migration applied with its
+// record, so the exact SQL run in production is recoverable even if the
+// history of the repository is rewritten. It requires a driver storing the
+// scripts, like the generic driver with a MetadataDialect, Migrate returning
+// an error otherwise; AppliedScript reads them back.
+func WithStoredScripts(s ScriptStorage) Option {
+	return func(d *Darwin) {
+		d.scripts = &s
+	}
+}
+
+// ScriptReader is implemented by drivers reading the script stored with the
+// record of a migration.
+type ScriptReader interface {
+	StoredScript(version float64) (string, error)
+}
+
+// StoredScript returns the script stored with the migration version, as
+// stored, empty when none was.
+func (m *GenericDriver) StoredScript(version float64) (string, error) {
+	md, ok := m.Dialect.(MetadataDialect)
+	if !ok {
+		return "", errors.New("darwin: the dialect can't store scripts")
+	}
+
+	var script sql.NullString
+	err := m.DB.QueryRow(md.ScriptSQL(), version).Scan(&script)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return script.String, err
+}
+
+// AppliedScript returns the script applied with the migration version, as
+// stored by WithStoredScripts, empty when it wasn't.
+func (d Darwin) AppliedScript(version float64) (string, error) {
+	if d.err != nil {
+		return "", d.err
+	}
+
+	r, ok := d.driver.(ScriptReader)
+	if !ok {
+		return "", errors.New("darwin: the driver can't read the stored scripts")
+	}
+
+	stored, err := r.StoredScript(version)
+	if err != nil {
+		return "", err
+	}
+	return decodeScript(stored)
+}
+
+// record returns the record of the migration executed in the duration,
+// with its metadata and stored script.
+func (d Darwin) record(ctx context.Context, m Migration, duration time.Duration) MigrationRecord {
+	return MigrationRecord{
+		Version:        m.Version,
+		Description:    m.Description,
+		Checksum:       m.Checksum(),
+		AppliedAt:      d.appliedAt(),
+		ExecutionTime:  duration,
+		RecordMetadata: d.recordMetadata(ctx, m),
+		Script:         d.storedScript(m),
+	}
+}
+
+// storedScript returns the script of the migration as stored in the history,
+// empty unless WithStoredScripts is used and the script isn't too large.
+func (d Darwin) storedScript(m Migration) string {
+	if d.scripts == nil {
+		return ""
+	}
+
+	script := m.Script
+	if d.scripts.Compress {
+		script = encodeScript(script)
+	}
+
+	max := d.scripts.MaxSize
+	if max == 0 {
+		max = defaultMaxScriptSize
+	}
+
+	if len(script) > max {
+		d.logger().Info("darwin: script too large to store", "version", m.Version, "size", len(script), "max_size", max)
+		return ""
+	}
+	return script
+}
+
+// encodeScript returns the script gzipped and encoded in base64, after the
+// gzip prefix.
+func encodeScript(script string) string {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Write([]byte(script))
+	zw.Close()
+	return gzipPrefix + base64.StdEncoding.EncodeToString(b.Bytes())
+}
+
+// decodeScript returns the stored script, decompressed if it was.
+func decodeScript(stored string) (string, error) {
+	if !strings.HasPrefix(stored, gzipPrefix) {
+		return stored, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, gzipPrefix))
+	if err != nil {
+		return "", err
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+
+	script, err := ioutil.ReadAll(zr)
+	return string(script), err
+}
@@ -0,0 +1,60 @@
+package darwin
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func Test_WithStoredScripts(t *testing.T) {
+	db, err := sql.Open("ql-mem", "scripts.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	dv, _ := NewGenericDriver(db, QLDialect{})
+
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id int);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id int);\n-- " + strings.Repeat("padding ", 100)},
+	}
+
+	d := New(dv, migrations, WithStoredScripts(ScriptStorage{MaxSize: 100}))
+	if err := d.Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if script, err := d.AppliedScript(1); err != nil || script != migrations[0].Script {
+		t.Errorf("Must store the script, got %q %v", script, err)
+	}
+
+	if script, err := d.AppliedScript(2); err != nil || script != "" {
+		t.Errorf("Must not store the scripts larger than the maximum, got %q %v", script, err)
+	}
+
+	if script, err := d.AppliedScript(3); err != nil || script != "" {
+		t.Errorf("Must not find the script of a migration not applied, got %q %v", script, err)
+	}
+}
+
+func Test_WithStoredScripts_compressed(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Description: "Roles", Script: "CREATE TABLE roles (id int);\n-- " + strings.Repeat("padding ", 100)},
+	}
+
+	d := New(&dummyDriver{}, migrations, WithStoredScripts(ScriptStorage{Compress: true, MaxSize: 100}))
+	stored := d.storedScript(migrations[0])
+	if !strings.HasPrefix(stored, gzipPrefix) || len(stored) > 100 {
+		t.Fatalf("Must store the script compressed, got %q", stored)
+	}
+
+	script, err := decodeScript(stored)
+	if err != nil || script != migrations[0].Script {
+		t.Errorf("Must decompress the script, got %q %v", script, err)
+	}
+
+	if _, err := d.AppliedScript(1); err == nil {
+		t.Errorf("Must return an error when the driver can't read the scripts")
+	}
+}
@@ -0,0 +1,256 @@
+package darwin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// defaultSeedTable is the name of the history table of the seeds.
+const defaultSeedTable = "darwin_seeds"
+
+// Seed is a migration of data, like reference rows or the fixtures of a
+// development database, kept out of the versions of the schema migrations.
+// Seeds have their own versions and history.
+type Seed struct {
+	Migration
+
+	// Requires is the version of the schema migration the seed needs
+	// applied, none when zero.
+	Requires float64
+
+	// Environments are the environments given to WithEnvironment the seed
+	// is applied in, compared without case; all of them when empty.
+	Environments []string
+}
+
+// applies reports whether the seed is applied in the environment.
+func (s Seed) applies(env string) bool {
+	return Policy{Environments: s.Environments}.applies(env)
+}
+
+// WithSeeds makes Migrate apply the seeds after the schema migrations, in
+// the order of their versions, keeping their history in the darwin_seeds
+// table of the database. A seed is executed again when its script changes,
+// so seeds must be re-runnable, like INSERT ... ON CONFLICT DO NOTHING. A
+// seed requiring a schema version not applied yet stops the seeding, it and
+// the following seeds staying pending. The driver must be a TableCloner,
+// whose copy executes the seeds and keeps their history, in a transaction
+// when it is a Transactor as for Migrate. Executing a changed seed again
+// replaces its record in a transaction, which requires the copy to be a
+// HistoryTransactor or a Transactor whose transactions are TxDeleters.
+func WithSeeds(seeds ...Seed) Option {
+	return func(d *Darwin) {
+		d.seeds = append(d.seeds, seeds...)
+	}
+}
+
+// WithSeedTable keeps the history of the seeds in the table name,
+// qualified by its schema or not, instead of darwin_seeds.
+func WithSeedTable(name string) Option {
+	return func(d *Darwin) {
+		d.seedTable = name
+	}
+}
+
+// Seed applies the seeds of WithSeeds without migrating the schema, for
+// example to restore the data of a development database which was wiped.
+// Report.Seeds holds the seeds executed.
+func (d Darwin) Seed(ctx context.Context) (report Report, err error) {
+	_, release, err := d.acquire(ctx)
+	if err != nil {
+		return report, err
+	}
+	defer func() {
+		if uerr := release(); err == nil {
+			err = uerr
+		}
+	}()
+
+	err = d.seed(ctx, &report)
+	return report, err
+}
+
+// seedDriver returns the driver executing the seeds and keeping their
+// history, a copy of the driver of d using the seed table.
+func (d Darwin) seedDriver() (Driver, error) {
+	c, ok := d.driver.(TableCloner)
+	if !ok {
+		return nil, errors.New("darwin: the driver can't keep the history of the seeds")
+	}
+
+	table := d.seedTable
+	if table == "" {
+		table = defaultSeedTable
+	}
+	return c.CloneTable(table)
+}
+
+// seed executes the seeds of the environment which are new or changed,
+// adding their results to the report.
+func (d Darwin) seed(ctx context.Context, report *Report) error {
+	var seeds []Seed
+	for _, s := range d.seeds {
+		if s.applies(d.environment) {
+			seeds = append(seeds, s)
+		}
+	}
+	if len(seeds) == 0 {
+		return nil
+	}
+	sort.SliceStable(seeds, func(i, j int) bool { return seeds[i].Version < seeds[j].Version })
+
+	driver, err := d.seedDriver()
+	if err != nil {
+		return err
+	}
+	if err := driver.Create(); err != nil {
+		return err
+	}
+
+	records, err := driver.All()
+	if err != nil {
+		return err
+	}
+	applied := map[float64]string{}
+	for _, r := range records {
+		applied[r.Version] = r.Checksum
+	}
+
+	schema, ok, err := LatestApplied(d.driver)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range seeds {
+		checksum, seeded := applied[s.Version]
+		if seeded && checksum == s.Checksum() {
+			continue
+		}
+
+		if s.Requires > 0 && (!ok || schema.Version < s.Requires) {
+			d.logger().Info("darwin: seed pending", "version", s.Version, "description", s.Description, "requires", s.Requires)
+			return nil
+		}
+
+		r, err := d.applySeed(ctx, driver, s.Migration, seeded)
+		if err != nil {
+			return err
+		}
+
+		report.Seeds = append(report.Seeds, r)
+		d.logger().Info("darwin: seed finished", "version", s.Version, "description", s.Description, "rows_affected", r.RowsAffected(), "duration", r.Duration)
+	}
+
+	return nil
+}
+
+// applySeed executes the seed and records it, replacing its record when it
+// was executed before with another script, in a transaction when the driver
+// is a Transactor.
+func (d Darwin) applySeed(ctx context.Context, driver Driver, m Migration, seeded bool) (Result, error) {
+	if c, ok := driver.(DeleteChecker); ok && seeded && !c.CanDelete() {
+		return Result{}, errors.New("darwin: the dialect can't delete the former record of the seed")
+	}
+
+	loaded, err := loadScripts(driver, []Migration{m})
+	if err != nil {
+		return Result{}, err
+	}
+	m = loaded[0]
+
+	if transactional(m) {
+		r, err := seedInTx(ctx, driver, m, seeded, d.record)
+		if err != ErrTransactionUnsupported {
+			return r, err
+		}
+	}
+
+	r, err := execMigration(ctx, driver, m)
+	if err != nil {
+		return r, SeedError{Version: m.Version, Description: m.Description, Err: err}
+	}
+
+	record := d.record(ctx, m, r.Duration)
+	if !seeded {
+		return r, driver.Insert(record)
+	}
+	return r, replaceRecord(ctx, driver, record)
+}
+
+// seedInTx executes the seed and records it, deleting its former record
+// when seeded, in a transaction of d. It returns ErrTransactionUnsupported
+// when d can't.
+func seedInTx(ctx context.Context, d Driver, m Migration, seeded bool, record recordFunc) (Result, error) {
+	t, ok := d.(Transactor)
+	if !ok {
+		return Result{}, ErrTransactionUnsupported
+	}
+
+	tx, err := t.BeginTx(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+
+	deleter, ok := tx.(TxDeleter)
+	if seeded && !ok {
+		tx.Rollback()
+		return Result{}, ErrTransactionUnsupported
+	}
+
+	r, err := execTx(ctx, tx, m)
+	if err != nil {
+		tx.Rollback()
+		return r, SeedError{Version: m.Version, Description: m.Description, Err: err}
+	}
+
+	if seeded {
+		err = deleter.Delete(ctx, m.Version)
+	}
+	if err == nil {
+		err = tx.Insert(ctx, record(ctx, m, r.Duration))
+	}
+	if err != nil {
+		tx.Rollback()
+		return r, err
+	}
+
+	return r, tx.Commit()
+}
+
+// replaceRecord replaces the record of the same version as record with it,
+// in a transaction changing the history of d.
+func replaceRecord(ctx context.Context, d Driver, record MigrationRecord) error {
+	tx, err := historyTx(ctx, d)
+	if err != nil {
+		return err
+	}
+
+	err = tx.(TxDeleter).Delete(ctx, record.Version)
+	if err == nil {
+		err = tx.Insert(ctx, record)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SeedError is used to report when a seed fails.
+type SeedError struct {
+	Version     float64
+	Description string
+	Err         error
+}
+
+func (e SeedError) Error() string {
+	return fmt.Sprintf("darwin: seed %f: %s", e.Version, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e SeedError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,110 @@
+package darwin
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+)
+
+func Test_WithSeeds(t *testing.T) {
+	db, err := sql.Open("ql-mem", "seeds.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	driver, err := NewGenericDriver(db, QLDialect{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrations := []Migration{
+		{Version: 1, Description: "Roles", Script: "CREATE TABLE roles (name string);"},
+	}
+
+	seeds := []Seed{
+		{Migration: Migration{Version: 1, Description: "Admin role", Script: "INSERT INTO roles VALUES (\"admin\");"}, Requires: 1},
+		{Migration: Migration{Version: 2, Description: "Demo role", Script: "INSERT INTO roles VALUES (\"demo\");"}, Environments: []string{"dev"}},
+		{Migration: Migration{Version: 3, Description: "Grants", Script: "INSERT INTO grants VALUES (\"admin\");"}, Requires: 2},
+	}
+
+	report, err := New(driver, migrations, WithEnvironment("prod"), WithSeeds(seeds...)).migrate(context.Background())
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(report.Results) != 1 || len(report.Seeds) != 1 || report.Seeds[0].Migration.Version != 1 {
+		t.Fatalf("Must apply the seeds of the environment which the schema allows, got %#v", report)
+	}
+
+	if !hasTable(db, "darwin_seeds", t) || countRows(db, "roles", t) != 1 {
+		t.Errorf("Must keep the history of the seeds in their table")
+	}
+
+	if all, _ := driver.All(); len(all) != 1 {
+		t.Errorf("Must not record the seeds with the migrations, got %#v", all)
+	}
+
+	seeds[0].Script = "INSERT INTO roles VALUES (\"owner\");"
+	report, err = New(driver, migrations, WithEnvironment("prod"), WithSeeds(seeds...)).Seed(context.Background())
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(report.Seeds) != 1 || countRows(db, "roles", t) != 2 {
+		t.Errorf("Must execute the changed seeds again, got %#v", report.Seeds)
+	}
+
+	report, err = New(driver, migrations, WithEnvironment("prod"), WithSeeds(seeds...)).Seed(context.Background())
+	if err != nil || len(report.Seeds) != 0 {
+		t.Errorf("Must not execute the seeds applied, got %#v, %v", report.Seeds, err)
+	}
+}
+
+// cloneDriver keeps the history of the seeds in the seeds driver.
+type cloneDriver struct {
+	dummyDriver
+	seeds *txDriver
+}
+
+func (d *cloneDriver) CloneTable(name string) (Driver, error) {
+	return d.seeds, nil
+}
+
+func Test_WithSeeds_replace(t *testing.T) {
+	seed := Seed{Migration: Migration{Version: 1, Description: "Admin role", Script: "INSERT INTO roles VALUES ('admin');"}}
+
+	if _, err := New(&dummyDriver{}, nil, WithSeeds(seed)).Seed(context.Background()); err == nil {
+		t.Error("Must refuse a driver which can't keep the history of the seeds")
+	}
+
+	seeds := &txDriver{insertError: true}
+	seeds.records = []MigrationRecord{{Version: 1, Checksum: "former"}}
+	driver := &cloneDriver{seeds: seeds}
+
+	if _, err := New(driver, nil, WithSeeds(seed)).Seed(context.Background()); err == nil {
+		t.Fatal("Must return the error of Insert")
+	}
+
+	if fmt.Sprint(seeds.calls) != "[begin tx.exec tx.delete tx.insert rollback]" || len(seeds.records) != 1 || seeds.records[0].Checksum != "former" {
+		t.Errorf("Must roll the seed and its record back, got %v and %#v", seeds.calls, seeds.records)
+	}
+
+	seeds.insertError, seeds.calls = false, nil
+	if _, err := New(driver, nil, WithSeeds(seed)).Seed(context.Background()); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(seeds.records) != 1 || seeds.records[0].Checksum != seed.Checksum() {
+		t.Errorf("Must replace the record of the seed, got %#v", seeds.records)
+	}
+}
+
+func countRows(db *sql.DB, table string, t *testing.T) int {
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM " + table).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
@@ -0,0 +1,113 @@
+package darwin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// setDirective applies a session setting while the migration runs, to
+// protect production from long lock waits without editing every statement:
+//
+//	-- darwin:set lock_timeout = '5s'
+//	-- darwin:set statement_timeout = '1min'
+//	ALTER TABLE users ADD COLUMN active BOOL;
+const setDirective = "set"
+
+// Setting is a session setting of a set directive.
+type Setting struct {
+	Name  string
+	Value string
+}
+
+// Settings returns the settings of the set directives of the script, in
+// order.
+func Settings(script string) ([]Setting, error) {
+	var settings []Setting
+
+	for _, d := range Directives(script) {
+		if d.Name != setDirective {
+			continue
+		}
+
+		i := strings.IndexByte(d.Args, '=')
+		if i < 0 {
+			return nil, fmt.Errorf("darwin: line %d: invalid set directive %q, expected name = value", d.Line, d.Args)
+		}
+
+		s := Setting{Name: strings.TrimSpace(d.Args[:i]), Value: strings.TrimSpace(d.Args[i+1:])}
+		if !validSettingName(s.Name) || s.Value == "" {
+			return nil, fmt.Errorf("darwin: line %d: invalid set directive %q", d.Line, d.Args)
+		}
+
+		settings = append(settings, s)
+	}
+
+	return settings, nil
+}
+
+// validSettingName reports whether name can be a setting, like
+// lock_timeout or app.tenant.
+func validSettingName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isWordByte(name[i]) && name[i] != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// SessionDialect is implemented by the dialects of databases with session
+// settings, applied with SetSQL before a migration with set directives and
+// restored with ResetSQL after it.
+type SessionDialect interface {
+	SetSQL(name, value string) string
+	ResetSQL(name string) string
+}
+
+// execer is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// withSettings calls f with the settings of the script applied to the
+// session of e, and restores them after.
+func withSettings(ctx context.Context, e execer, d Dialect, script string, f func() error) (err error) {
+	settings, err := Settings(script)
+	if err != nil {
+		return err
+	}
+
+	if len(settings) == 0 {
+		return f()
+	}
+
+	sd, ok := d.(SessionDialect)
+	if !ok {
+		return errors.New("darwin: the dialect doesn't support the set directive")
+	}
+
+	applied := 0
+	defer func() {
+		for _, s := range settings[:applied] {
+			if _, rerr := e.ExecContext(ctx, sd.ResetSQL(s.Name)); err == nil {
+				err = rerr
+			}
+		}
+	}()
+
+	for _, s := range settings {
+		if _, err := e.ExecContext(ctx, sd.SetSQL(s.Name, s.Value)); err != nil {
+			return err
+		}
+		applied++
+	}
+
+	return f()
+}
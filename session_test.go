@@ -0,0 +1,75 @@
+package darwin
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func Test_Settings(t *testing.T) {
+	script := "-- darwin:set lock_timeout = '5s'\n-- darwin:set search_path=app, public\nSELECT 1;"
+
+	settings, err := Settings(script)
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := []Setting{{Name: "lock_timeout", Value: "'5s'"}, {Name: "search_path", Value: "app, public"}}
+	if !reflect.DeepEqual(settings, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, settings)
+	}
+
+	for _, script := range []string{"-- darwin:set lock_timeout", "-- darwin:set a; DROP TABLE x = 1", "-- darwin:set a ="} {
+		if _, err := Settings(script); err == nil {
+			t.Errorf("Must not accept %q", script)
+		}
+	}
+}
+
+func Test_GenericDriver_Exec_settings(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, _ := NewGenericDriver(db, PostgresDialect{})
+
+	script := "-- darwin:set lock_timeout = '5s'\nALTER TABLE users ADD COLUMN active BOOL;"
+
+	mock.ExpectBegin()
+	mock.ExpectExec(escapeQuery("SET lock_timeout = '5s'")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(escapeQuery(script)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(escapeQuery("RESET lock_timeout")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	if _, err := d.Exec(script); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_GenericDriver_Exec_settings_unsupported(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, _ := NewGenericDriver(db, QLDialect{})
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	if _, err := d.Exec("-- darwin:set lock_timeout = '5s'\nSELECT 1;"); err == nil {
+		t.Errorf("Must refuse settings the dialect can't apply")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
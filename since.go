@@ -0,0 +1,147 @@
+package darwin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+)
+
+// historyWindow is how many of the newest migrations applied Migrate
+// validates with a HistorySince driver.
+const historyWindow = 100
+
+// HistorySince is implemented by drivers able to read the records of the
+// newest migrations only, so planning a migration doesn't load a history of
+// tens of thousands of records. LatestVersion returns the greatest version
+// of the history, if any, and AllSince the records with a version greater
+// than or equal to version.
+type HistorySince interface {
+	LatestVersion() (float64, bool, error)
+	AllSince(version float64) ([]MigrationRecord, error)
+}
+
+// ErrSinceUnsupported is returned by the methods of HistorySince when the
+// driver can't read the history from a version. The planner then reads it
+// with All.
+var ErrSinceUnsupported = errors.New("darwin: the driver can't read the history from a version")
+
+// SinceDialect is implemented by the dialects with the SQL selecting the
+// records from a version on, its only argument, with the columns of
+// AllMetadataSQL, and the SQL selecting the greatest version.
+type SinceDialect interface {
+	AllSinceSQL() string
+	LatestVersionSQL() string
+}
+
+// LatestVersion returns the greatest version of the history, if any.
+func (m *GenericDriver) LatestVersion() (float64, bool, error) {
+	sd, ok := m.Dialect.(SinceDialect)
+	if !ok {
+		return 0, false, ErrSinceUnsupported
+	}
+
+	var version sql.NullFloat64
+	if err := m.DB.QueryRow(sd.LatestVersionSQL()).Scan(&version); err != nil {
+		return 0, false, err
+	}
+	return version.Float64, version.Valid, nil
+}
+
+// AllSince returns the records of the migrations from the version on, with
+// their metadata. The history table must have the metadata columns, which
+// Create adds.
+func (m *GenericDriver) AllSince(version float64) ([]MigrationRecord, error) {
+	sd, ok := m.Dialect.(SinceDialect)
+	if !ok {
+		return nil, ErrSinceUnsupported
+	}
+
+	rows, err := m.DB.Query(sd.AllSinceSQL(), version)
+	if err != nil {
+		return nil, err
+	}
+	return scanRecords(rows, true)
+}
+
+// since returns the records from the version on.
+func since(records []MigrationRecord, version float64) []MigrationRecord {
+	var kept []MigrationRecord
+	for _, r := range records {
+		if r.Version >= version {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// readHistory returns the records Migrate validates the sorted migrations
+// against and plans with. With a HistorySince driver, these are the records
+// from the historyWindow-th newest migration up to the latest version
+// applied, read with two queries whatever the length of the history, or a
+// single one when the history is empty. Other drivers read the whole
+// history.
+func readHistory(d Driver, migrations []Migration) ([]MigrationRecord, error) {
+	if s, ok := d.(HistorySince); ok {
+		latest, found, err := s.LatestVersion()
+		switch {
+		case err == ErrSinceUnsupported:
+		case err != nil:
+			return nil, err
+		case !found:
+			return nil, nil
+		default:
+			return s.AllSince(windowStart(migrations, latest))
+		}
+	}
+	return d.All()
+}
+
+// windowStart returns the version of the historyWindow-th newest of the
+// sorted migrations up to the latest version applied, the latest version
+// when none is.
+func windowStart(migrations []Migration, latest float64) float64 {
+	n := sort.Search(len(migrations), func(i int) bool {
+		return migrations[i].Version > latest
+	})
+	if n == 0 {
+		return latest
+	}
+	if n -= historyWindow; n < 0 {
+		n = 0
+	}
+	return migrations[n].Version
+}
+
+// lastRecord returns the record of the newest migration of the records.
+func lastRecord(records []MigrationRecord) (MigrationRecord, bool) {
+	if len(records) == 0 {
+		return MigrationRecord{}, false
+	}
+
+	last := records[0]
+	for _, r := range records[1:] {
+		if r.Version > last.Version {
+			last = r
+		}
+	}
+	return last, true
+}
+
+// validateHistory reads the records of readHistory and validates the sorted
+// migrations against them, in a span, returning the records to plan with.
+func (d Darwin) validateHistory(ctx context.Context, migrations []Migration) (records []MigrationRecord, err error) {
+	_, span := d.startSpan(ctx, "darwin.Validate")
+	defer func() { endSpan(span, err) }()
+
+	if err := checkVersions(migrations); err != nil {
+		return nil, err
+	}
+
+	records, err = readHistory(d.driver, migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	return records, validateRecords(records, migrations)
+}
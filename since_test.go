@@ -0,0 +1,126 @@
+package darwin
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+// plainDialect is a Dialect without optional capabilities.
+type plainDialect struct{}
+
+func (plainDialect) CreateTableSQL() string { return "" }
+func (plainDialect) InsertSQL() string      { return "" }
+func (plainDialect) AllSQL() string         { return "" }
+
+// sinceDriver is a HistorySince driver recording the versions read from.
+type sinceDriver struct {
+	dummyDriver
+	since  []float64
+	latest int
+	all    int
+}
+
+func (d *sinceDriver) All() ([]MigrationRecord, error) {
+	d.all++
+	return d.dummyDriver.All()
+}
+
+func (d *sinceDriver) LatestVersion() (float64, bool, error) {
+	d.latest++
+	last, ok := lastRecord(d.records)
+	return last.Version, ok, nil
+}
+
+func (d *sinceDriver) AllSince(version float64) ([]MigrationRecord, error) {
+	d.since = append(d.since, version)
+	return since(d.records, version), nil
+}
+
+func Test_planMigration_since(t *testing.T) {
+	var migrations []Migration
+	driver := &sinceDriver{}
+	for v := 1000; v >= 1; v-- {
+		migrations = append(migrations, Migration{Version: float64(v)})
+		if v <= 995 {
+			driver.records = append(driver.records, MigrationRecord{Version: float64(v)})
+		}
+	}
+
+	planned, err := planMigration(driver, migrations)
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(planned) != 5 || planned[0].Version != 996 {
+		t.Errorf("Must plan the pending migrations, got %d from %v", len(planned), planned[0].Version)
+	}
+
+	if !reflect.DeepEqual(driver.since, []float64{896}) || driver.latest != 1 || driver.all != 0 {
+		t.Errorf("Must read the newest records only, got %v, %d reads of the latest and %d of all", driver.since, driver.latest, driver.all)
+	}
+
+	driver = &sinceDriver{}
+	if planned, _ := planMigration(driver, migrations); len(planned) != 1000 || driver.latest != 1 || len(driver.since) != 0 || driver.all != 0 {
+		t.Errorf("Must plan every migration of an empty history with a single read, got %d", len(planned))
+	}
+}
+
+func Test_Darwin_Migrate_since(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+
+	driver := &sinceDriver{}
+	driver.records = []MigrationRecord{{Version: 1, Checksum: "edited"}}
+
+	if err := New(driver, migrations).Migrate(); err != (InvalidChecksumError{Version: 1}) {
+		t.Errorf("Must validate the records read, got %v", err)
+	}
+
+	if driver.latest != 1 || len(driver.since) != 1 || driver.all != 0 {
+		t.Errorf("Must validate and plan with a single read, got %d reads of the latest, %v and %d of all", driver.latest, driver.since, driver.all)
+	}
+}
+
+func Test_GenericDriver_AllSince(t *testing.T) {
+	db, err := sql.Open("ql-mem", "since.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	d, _ := NewGenericDriver(db, QLDialect{})
+	if err := d.Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []float64{1, 2, 3} {
+		if err := d.Insert(MigrationRecord{Version: v, RecordMetadata: RecordMetadata{AppliedBy: "deploy"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := d.AllSince(2)
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(records) != 2 || records[0].Version != 2 || records[1].Version != 3 || records[1].AppliedBy != "deploy" {
+		t.Errorf("Must return the records from the version on, got %#v", records)
+	}
+
+	if latest, ok, err := d.LatestVersion(); err != nil || !ok || latest != 3 {
+		t.Errorf("Must return the latest version, got %v %v %v", latest, ok, err)
+	}
+
+	missing, _ := NewGenericDriver(db, QLDialect{Table: "missing"})
+	if _, err := missing.AllSince(2); err == nil {
+		t.Errorf("Must return the error of the query")
+	}
+
+	if _, err := (&GenericDriver{Dialect: plainDialect{}}).AllSince(2); err != ErrSinceUnsupported {
+		t.Errorf("Must return ErrSinceUnsupported without a SinceDialect, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package darwin
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DownSuffix is the suffix of the files reverting a migration, next to the
+// file of the migration: 0003_users.down.sql reverts 0003_users.sql.
+const DownSuffix = ".down.sql"
+
+// Source provides the migrations of a project.
+type Source interface {
+	Migrations() ([]Migration, error)
+}
+
+// DownSource provides the down migrations of a project, reverting its
+// migrations, each with the version of the migration it reverts.
+type DownSource interface {
+	DownMigrations() ([]Migration, error)
+}
+
+// FSSource reads migrations from the .sql files of a directory in a file
+// system, like an embed.FS or os.DirFS. Files are parsed with ParseMigrations
+// in lexical order and data files attached to the migrations are resolved
+// relative to the directory. The .down.sql files, reverting the migration
+// of the .sql file of the same name, are read by DownMigrations.
+//
+// With Lazy, the migrations are returned with their checksums but without
+// their scripts, which are read again from the files when the migrations
+// are executed, keeping the memory of projects with thousands of
+// migrations low; their checksums are computed as the files are read, and
+// kept in Checksums when set. The scripts of the migrations with the stream directive
+// are always left out, and streamed to the drivers implementing ExecReader.
+type FSSource struct {
+	FS      fs.FS
+	Dir     string
+	Options []ParseOption
+	Lazy    bool
+
+	Checksums *ChecksumCache
+}
+
+// NewFSSource returns a FSSource reading the .sql files of dir in fsys.
+func NewFSSource(fsys fs.FS, dir string, opts ...ParseOption) FSSource {
+	return FSSource{FS: fsys, Dir: dir, Options: opts}
+}
+
+// Migrations implements the Source interface.
+func (s FSSource) Migrations() ([]Migration, error) {
+	files, names, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	for _, name := range names {
+		if strings.HasSuffix(name, DownSuffix) {
+			continue
+		}
+
+		if s.Lazy && !newParser(s.Options).normalize {
+			migs, err := s.checksums(files, name)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, m := range migs {
+				var loader ScriptLoader = &fileScript{source: s, files: files, name: name, version: m.version}
+				if m.stream {
+					loader = &fileStream{fileScript{source: s, files: files, name: name, version: m.version}}
+				}
+				migrations = append(migrations, Migration{Version: m.version, Description: m.description, Files: files, Loader: loader, ScriptChecksum: m.checksum})
+			}
+			continue
+		}
+
+		migs, err := s.parse(files, name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range migs {
+			m.Files = files
+			switch loader := (fileScript{source: s, files: files, name: name, version: m.Version}); {
+			case hasDirective(m.Script, streamDirective):
+				m.ScriptChecksum, m.Script, m.Loader = m.Checksum(), "", &fileStream{loader}
+			case s.Lazy:
+				m.ScriptChecksum, m.Script, m.Loader = m.Checksum(), "", &loader
+			}
+			migrations = append(migrations, m)
+		}
+	}
+
+	return migrations, nil
+}
+
+// fileScript loads the script of the migration version of a file.
+type fileScript struct {
+	source  FSSource
+	files   fs.FS
+	name    string
+	version float64
+}
+
+func (f *fileScript) LoadScript() (string, error) {
+	migs, err := f.source.parse(f.files, f.name)
+	if err != nil {
+		return "", err
+	}
+
+	for _, m := range migs {
+		if m.Version == f.version {
+			return m.Script, nil
+		}
+	}
+
+	return "", fmt.Errorf("darwin: %s no longer holds migration %f", f.source.path(f.name), f.version)
+}
+
+// DownMigrations returns the down migrations of the .down.sql files, with
+// the version of the migration they revert. A down file without version
+// headers reverts the single migration of its .sql file, one reverting the
+// migrations of a file holding several declares their versions with the
+// same headers.
+func (s FSSource) DownMigrations() ([]Migration, error) {
+	files, names, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+
+	var downs []Migration
+	for _, name := range names {
+		if !strings.HasSuffix(name, DownSuffix) {
+			continue
+		}
+
+		migs, err := s.parse(files, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(migs) == 0 {
+			up, err := s.parse(files, strings.TrimSuffix(name, DownSuffix)+".sql")
+			if err != nil {
+				return nil, err
+			}
+			if len(up) != 1 {
+				return nil, fmt.Errorf("darwin: %s reverts %d migrations, versions must be declared", s.path(name), len(up))
+			}
+
+			content, err := fs.ReadFile(files, name)
+			if err != nil {
+				return nil, err
+			}
+			migs = []Migration{{Version: up[0].Version, Description: up[0].Description, Script: string(content)}}
+		}
+
+		for _, m := range migs {
+			m.Files = files
+			downs = append(downs, m)
+		}
+	}
+
+	return downs, nil
+}
+
+// files returns the directory of the source and the names of its .sql
+// files, sorted.
+func (s FSSource) files() (fs.FS, []string, error) {
+	files, err := fs.Sub(s.FS, s.dir())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(names)
+
+	return files, names, nil
+}
+
+// parse parses the migrations of the file name.
+func (s FSSource) parse(files fs.FS, name string) ([]Migration, error) {
+	content, err := fs.ReadFile(files, name)
+	if err != nil {
+		return nil, err
+	}
+
+	migs := ParseMigrations(string(content), s.Options...)
+	if migs == nil {
+		return nil, fmt.Errorf("darwin: unable to parse migrations in %s", s.path(name))
+	}
+
+	return migs, nil
+}
+
+func (s FSSource) dir() string {
+	if s.Dir == "" {
+		return "."
+	}
+	return s.Dir
+}
+
+func (s FSSource) path(name string) string {
+	return path.Join(s.dir(), name)
+}
@@ -0,0 +1,348 @@
+package darwin
+
+import "strings"
+
+// Syntax describes the parts of the SQL of a database which decide where
+// its statements end. The zero value is the SQL standard: strings in single
+// quotes, identifiers in double quotes, -- and /* */ comments.
+type Syntax struct {
+	// DollarQuotes is set for databases quoting function bodies with $$ or
+	// $tag$, like Postgres.
+	DollarQuotes bool
+
+	// BackslashEscapes is set for databases escaping quotes in strings with
+	// a backslash, like MySQL. Postgres escape strings, E'...', always do.
+	BackslashEscapes bool
+
+	// Backticks is set for databases quoting identifiers with backticks.
+	Backticks bool
+
+	// Brackets is set for databases quoting identifiers with brackets, like
+	// SQLite.
+	Brackets bool
+
+	// HashComments is set for databases starting comments with #.
+	HashComments bool
+
+	// SlashComments is set for databases starting comments with // instead
+	// of --, like Cypher and AQL.
+	SlashComments bool
+
+	// DashAndSlashComments is set for databases starting comments with
+	// either -- or //, like CQL.
+	DashAndSlashComments bool
+
+	// NestedComments is set for databases where /* */ comments nest.
+	NestedComments bool
+
+	// Blocks is set for databases with BEGIN ... END bodies in CREATE
+	// FUNCTION, PROCEDURE and TRIGGER statements and DECLARE blocks, whose
+	// semicolons don't end the statement.
+	Blocks bool
+
+	// DelimiterCommand is set for databases whose scripts change the
+	// statement terminator with DELIMITER lines, like those of the mysql
+	// client.
+	DelimiterCommand bool
+}
+
+// delimiterDirective changes the statement terminator from the next line on,
+// in scripts of any dialect:
+//
+//	-- darwin:delimiter $$
+//	CREATE PROCEDURE fill() BEGIN INSERT INTO t VALUES (1); END$$
+//	-- darwin:delimiter ;
+const delimiterDirective = "delimiter"
+
+// Syntaxes of the databases with a dialect in this package.
+var (
+	StandardSyntax = Syntax{}
+	PostgresSyntax = Syntax{DollarQuotes: true, NestedComments: true, Blocks: true}
+	MySQLSyntax    = Syntax{BackslashEscapes: true, Backticks: true, HashComments: true, Blocks: true, DelimiterCommand: true}
+	SqliteSyntax   = Syntax{Backticks: true, Brackets: true, Blocks: true}
+)
+
+// SyntaxDialect is implemented by dialects whose SQL differs from the SQL
+// standard in a way changing where statements end. The generic driver
+// splits scripts with it when it executes their statements one at a time.
+type SyntaxDialect interface {
+	Syntax() Syntax
+}
+
+// syntaxOf returns the syntax of the dialect.
+func syntaxOf(d Dialect) Syntax {
+	if s, ok := d.(SyntaxDialect); ok {
+		return s.Syntax()
+	}
+	return StandardSyntax
+}
+
+// SplitStatements splits a script into its statements, on the semicolons
+// outside of quoted strings and identifiers, comments, dollar quoted bodies
+// and BEGIN ... END blocks, following the syntax. The statements are
+// returned without their trailing semicolon; statements holding only
+// comments are dropped.
+//
+// The terminator can be changed with the delimiter directive or, with the
+// DelimiterCommand syntax, a DELIMITER line. Semicolons are then ordinary
+// characters:
+//
+//	DELIMITER //
+//	CREATE TRIGGER touch BEFORE UPDATE ON users FOR EACH ROW
+//	SET NEW.updated_at = NOW();
+//	//
+//	DELIMITER ;
+//
+// A script defining a PL/pgSQL function is a single statement:
+//
+//	CREATE FUNCTION touch() RETURNS trigger AS $$
+//	BEGIN
+//		NEW.updated_at := now();
+//		RETURN NEW;
+//	END;
+//	$$ LANGUAGE plpgsql;
+func SplitStatements(script string, syntax Syntax) []string {
+	var (
+		statements []string
+		start      int
+		code       bool
+		first      string // first word of the statement
+		words      int    // words read in the statement
+		header     = true // no parenthesis was read in the statement
+		routine    bool   // the statement can hold blocks
+		depth      int    // blocks opened in the statement
+		delimiter  = ";"
+	)
+
+	end := func(i int) {
+		if code {
+			statements = append(statements, strings.TrimSpace(script[start:i]))
+		}
+		start, code, first, words, header, routine, depth = i, false, "", 0, true, false, 0
+	}
+
+	for i := 0; i < len(script); {
+		c := script[i]
+
+		if i == 0 || script[i-1] == '\n' {
+			line := script[i:skipTo(script, i, "\n")]
+			if d, ok := delimiterLine(line, syntax); ok {
+				end(i)
+				delimiter = d
+				i += len(line)
+				start = i
+				continue
+			}
+		}
+
+		if delimiter != ";" && strings.HasPrefix(script[i:], delimiter) {
+			end(i)
+			i += len(delimiter)
+			start = i
+			continue
+		}
+
+		switch {
+		case c == '\'':
+			escapes := syntax.BackslashEscapes || (syntax.DollarQuotes && escapeString(script, i))
+			i = skipQuoted(script, i, escapes)
+			code = true
+
+		case c == '"' || (c == '`' && syntax.Backticks):
+			i = skipQuoted(script, i, syntax.BackslashEscapes && c == '"')
+			code = true
+
+		case c == '[' && syntax.Brackets:
+			i = skipTo(script, i+1, "]")
+			code = true
+
+		case c == '$' && syntax.DollarQuotes && (i == 0 || !isWordByte(script[i-1])):
+			if tag, ok := dollarTag(script[i:]); ok {
+				i = skipTo(script, i+len(tag), tag)
+			} else {
+				i++
+			}
+			code = true
+
+		case c == '-' && !syntax.SlashComments && strings.HasPrefix(script[i:], "--"),
+			c == '/' && (syntax.SlashComments || syntax.DashAndSlashComments) && strings.HasPrefix(script[i:], "//"),
+			c == '#' && syntax.HashComments:
+			i = skipTo(script, i, "\n")
+
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			i = skipComment(script, i, syntax.NestedComments)
+
+		case c == ';' && delimiter == ";":
+			if depth == 0 {
+				end(i)
+				start = i + 1
+			}
+			i++
+
+		case isWordByte(c):
+			j := i
+			for j < len(script) && isWordByte(script[j]) {
+				j++
+			}
+			word := strings.ToUpper(script[i:j])
+			if words++; words == 1 {
+				first = word
+			}
+			code = true
+
+			if syntax.Blocks {
+				switch {
+				case first == "DECLARE", first == "CREATE" && header && routineKeyword(word):
+					routine = true
+				}
+
+				switch {
+				case !routine:
+				case word == "BEGIN" || word == "CASE":
+					depth++
+				case word == "END":
+					next, k := nextWord(script, j)
+					switch next {
+					case "IF", "LOOP", "WHILE", "REPEAT", "FOR":
+						// Closes a control statement, not a block.
+					default:
+						if depth > 0 {
+							depth--
+						}
+						if next == "CASE" {
+							j = k
+						}
+					}
+				}
+			}
+			i = j
+
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			if c == '(' {
+				header = false
+			}
+			code = true
+			i++
+
+		default:
+			i++
+		}
+	}
+
+	end(len(script))
+	return statements
+}
+
+// delimiterLine reports whether the line changes the statement terminator,
+// and returns the new one.
+func delimiterLine(line string, syntax Syntax) (string, bool) {
+	if d, ok := parseDirective(line); ok && d.Name == delimiterDirective && d.Args != "" {
+		return d.Args, true
+	}
+
+	fields := strings.Fields(line)
+	if syntax.DelimiterCommand && len(fields) == 2 && strings.EqualFold(fields[0], "DELIMITER") {
+		return fields[1], true
+	}
+
+	return "", false
+}
+
+// routineKeyword reports whether the word in the header of a CREATE
+// statement makes it a routine, whose body can hold blocks.
+func routineKeyword(word string) bool {
+	switch word {
+	case "FUNCTION", "PROCEDURE", "TRIGGER", "PACKAGE":
+		return true
+	}
+	return false
+}
+
+// nextWord returns the upper cased word following the spaces after i, and
+// the index following it.
+func nextWord(s string, i int) (string, int) {
+	for i < len(s) && strings.IndexByte(" \t\r\n", s[i]) >= 0 {
+		i++
+	}
+	j := i
+	for j < len(s) && isWordByte(s[j]) {
+		j++
+	}
+	return strings.ToUpper(s[i:j]), j
+}
+
+// escapeString reports whether the string starting at i is a Postgres
+// escape string, E'...'.
+func escapeString(s string, i int) bool {
+	return i > 0 && (s[i-1] == 'E' || s[i-1] == 'e') && (i == 1 || !isWordByte(s[i-2]))
+}
+
+// skipQuoted returns the index following the quoted text starting at i.
+// Quotes are escaped by doubling them or, with escapes, with a backslash.
+func skipQuoted(s string, i int, escapes bool) int {
+	quote := s[i]
+
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			if escapes {
+				j++
+			}
+		case quote:
+			if j+1 < len(s) && s[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+
+	return len(s)
+}
+
+// dollarTag returns the dollar quote opening s, like "$$" or "$body$".
+func dollarTag(s string) (string, bool) {
+	for j := 1; j < len(s); j++ {
+		c := s[j]
+		if c == '$' {
+			return s[:j+1], true
+		}
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || j > 1 && c >= '0' && c <= '9') {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// skipComment returns the index following the block comment starting at i.
+func skipComment(s string, i int, nested bool) int {
+	depth := 0
+
+	for j := i; j < len(s); j++ {
+		switch {
+		case strings.HasPrefix(s[j:], "/*") && (nested || depth == 0):
+			depth++
+			j++
+		case strings.HasPrefix(s[j:], "*/"):
+			depth--
+			j++
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+
+	return len(s)
+}
+
+// skipTo returns the index following the next occurrence of end.
+func skipTo(s string, i int, end string) int {
+	if j := strings.Index(s[i:], end); j >= 0 {
+		return i + j + len(end)
+	}
+	return len(s)
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,133 @@
+package darwin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_SplitStatements(t *testing.T) {
+	script := `-- comment; with a semicolon
+CREATE TABLE users (name VARCHAR(10) DEFAULT 'a;b');
+/* block; comment */
+INSERT INTO users VALUES ('it''s; here'), ("x"";y");
+UPDATE users SET name = 'a' WHERE name = $1`
+
+	expected := []string{
+		"-- comment; with a semicolon\nCREATE TABLE users (name VARCHAR(10) DEFAULT 'a;b')",
+		"/* block; comment */\nINSERT INTO users VALUES ('it''s; here'), (\"x\"\";y\")",
+		"UPDATE users SET name = 'a' WHERE name = $1",
+	}
+
+	if got := SplitStatements(script, StandardSyntax); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	if got := SplitStatements("-- only a comment;\n;", StandardSyntax); len(got) != 0 {
+		t.Errorf("Must drop statements without code, got %q", got)
+	}
+}
+
+func Test_SplitStatements_postgres(t *testing.T) {
+	script := `CREATE FUNCTION touch() RETURNS trigger AS $body$
+BEGIN
+	NEW.note := 'touched; $$ ok';
+	RETURN NEW;
+END;
+$body$ LANGUAGE plpgsql;
+CREATE FUNCTION one() RETURNS int LANGUAGE sql
+BEGIN ATOMIC
+	SELECT CASE WHEN true THEN 1 END;
+	SELECT 1;
+END;
+/* outer /* nested; */ still; a comment */
+SELECT E'it\'s; escaped';
+BEGIN;
+COMMIT`
+
+	expected := []string{
+		"CREATE FUNCTION touch() RETURNS trigger AS $body$\nBEGIN\n\tNEW.note := 'touched; $$ ok';\n\tRETURN NEW;\nEND;\n$body$ LANGUAGE plpgsql",
+		"CREATE FUNCTION one() RETURNS int LANGUAGE sql\nBEGIN ATOMIC\n\tSELECT CASE WHEN true THEN 1 END;\n\tSELECT 1;\nEND",
+		"/* outer /* nested; */ still; a comment */\nSELECT E'it\\'s; escaped'",
+		"BEGIN",
+		"COMMIT",
+	}
+
+	if got := SplitStatements(script, PostgresSyntax); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func Test_SplitStatements_mysql(t *testing.T) {
+	script := "# hash; comment\nINSERT INTO `odd;name` VALUES ('a\\';b');\n" + `CREATE PROCEDURE fill()
+BEGIN
+	IF (SELECT COUNT(*) FROM t) = 0 THEN
+		INSERT INTO t VALUES (1);
+	END IF;
+	CASE WHEN 1 THEN SELECT 1; END CASE;
+END;
+CREATE TABLE procedures (kind VARCHAR(10) DEFAULT 'case')`
+
+	expected := []string{
+		"# hash; comment\nINSERT INTO `odd;name` VALUES ('a\\';b')",
+		"CREATE PROCEDURE fill()\nBEGIN\n\tIF (SELECT COUNT(*) FROM t) = 0 THEN\n\t\tINSERT INTO t VALUES (1);\n\tEND IF;\n\tCASE WHEN 1 THEN SELECT 1; END CASE;\nEND",
+		"CREATE TABLE procedures (kind VARCHAR(10) DEFAULT 'case')",
+	}
+
+	if got := SplitStatements(script, MySQLSyntax); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func Test_SplitStatements_delimiter(t *testing.T) {
+	script := `DELIMITER //
+CREATE TRIGGER touch BEFORE UPDATE ON users FOR EACH ROW
+SET NEW.updated_at = NOW(); //
+delimiter ;
+INSERT INTO users VALUES (1);
+-- darwin:delimiter $$
+CREATE PROCEDURE fill() BEGIN INSERT INTO t VALUES ('$'); END$$
+-- darwin:delimiter ;
+SELECT 1`
+
+	expected := []string{
+		"CREATE TRIGGER touch BEFORE UPDATE ON users FOR EACH ROW\nSET NEW.updated_at = NOW();",
+		"INSERT INTO users VALUES (1)",
+		"CREATE PROCEDURE fill() BEGIN INSERT INTO t VALUES ('$'); END",
+		"SELECT 1",
+	}
+
+	if got := SplitStatements(script, MySQLSyntax); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	if got := SplitStatements("DELIMITER //\nSELECT 1;", StandardSyntax); len(got) != 1 {
+		t.Errorf("Must only honor DELIMITER lines with the DelimiterCommand syntax, got %q", got)
+	}
+}
+
+func Test_SplitStatements_brackets(t *testing.T) {
+	script := "INSERT INTO [weird;name] VALUES (1);\nSELECT 1;"
+
+	expected := []string{"INSERT INTO [weird;name] VALUES (1)", "SELECT 1"}
+	if got := SplitStatements(script, SqliteSyntax); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func Test_SplitStatements_slash_comments(t *testing.T) {
+	script := "// create; the index\nMATCH (a)-->(b) RETURN 'it\\'s; here';\nRETURN 1;"
+
+	expected := []string{"// create; the index\nMATCH (a)-->(b) RETURN 'it\\'s; here'", "RETURN 1"}
+	if got := SplitStatements(script, Syntax{BackslashEscapes: true, SlashComments: true}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func Test_SplitStatements_dash_and_slash_comments(t *testing.T) {
+	script := "-- first; comment\nSELECT 1;\n// second; comment\nSELECT 2;"
+
+	expected := []string{"-- first; comment\nSELECT 1", "// second; comment\nSELECT 2"}
+	if got := SplitStatements(script, Syntax{DashAndSlashComments: true}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
@@ -1,11 +1,17 @@
 package darwin
 
+import "fmt"
+
 // SqliteDialect a Dialect configured for Sqlite3.
-type SqliteDialect struct{}
+type SqliteDialect struct {
+	// Table is the name of the history table, qualified by the name of its
+	// database or not, darwin_migrations by default.
+	Table string
+}
 
 // CreateTableSQL returns the SQL to create the schema table.
 func (s SqliteDialect) CreateTableSQL() string {
-	return `CREATE TABLE IF NOT EXISTS darwin_migrations
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
                 (
                     id             INTEGER  PRIMARY KEY,
                     version        FLOAT    NOT NULL,
@@ -13,13 +19,18 @@ func (s SqliteDialect) CreateTableSQL() string {
                     checksum       TEXT     NOT NULL,
                     applied_at     DATETIME NOT NULL,
                     execution_time FLOAT    NOT NULL,
+                    applied_by     TEXT,
+                    hostname       TEXT,
+                    app_version    TEXT,
+                    source         TEXT,
+                    script         TEXT,
                     UNIQUE         (version)
-                );`
+                );`, s.table())
 }
 
 // InsertSQL returns the SQL to insert a new migration in the schema table.
 func (s SqliteDialect) InsertSQL() string {
-	return `INSERT INTO darwin_migrations
+	return fmt.Sprintf(`INSERT INTO %s
                 (
                     version,
                     description,
@@ -27,18 +38,107 @@ func (s SqliteDialect) InsertSQL() string {
                     applied_at,
                     execution_time
                 )
-            VALUES (?, ?, ?, ?, ?);`
+            VALUES (?, ?, ?, ?, ?);`, s.table())
 }
 
 // AllSQL returns a SQL to get all entries in the table.
 func (s SqliteDialect) AllSQL() string {
-	return `SELECT 
+	return fmt.Sprintf(`SELECT 
                 version,
                 description,
                 checksum,
                 applied_at,
                 execution_time
             FROM 
-                darwin_migrations
-            ORDER BY version ASC;`
+                %s
+            ORDER BY version ASC;`, s.table())
+}
+
+// DeleteSQL returns the SQL to delete the record of a migration.
+func (s SqliteDialect) DeleteSQL() string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE version = ?;`, s.table())
+}
+
+// InsertMetadataSQL returns the SQL to insert a new migration in the schema
+// table with its metadata.
+func (s SqliteDialect) InsertMetadataSQL() string {
+	return fmt.Sprintf(`INSERT INTO %s
+                (
+                    version,
+                    description,
+                    checksum,
+                    applied_at,
+                    execution_time,
+                    applied_by,
+                    hostname,
+                    app_version,
+                    source,
+                    script
+                )
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`, s.table())
+}
+
+// AllMetadataSQL returns a SQL to get all entries in the table with their
+// metadata.
+func (s SqliteDialect) AllMetadataSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time,
+                applied_by,
+                hostname,
+                app_version,
+                source
+            FROM
+                %s
+            ORDER BY version ASC;`, s.table())
+}
+
+// AllSinceSQL returns a SQL to get the entries in the table from a version
+// on, with their metadata.
+func (s SqliteDialect) AllSinceSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time,
+                applied_by,
+                hostname,
+                app_version,
+                source
+            FROM
+                %s
+            WHERE
+                version >= ?
+            ORDER BY version ASC;`, s.table())
+}
+
+// LatestVersionSQL returns a SQL to get the greatest version in the table,
+// NULL when it is empty.
+func (s SqliteDialect) LatestVersionSQL() string {
+	return fmt.Sprintf("SELECT MAX(version) FROM %s;", s.table())
+}
+
+// HistoryUpgrades returns the upgrades of the schema table to its latest
+// layout.
+func (s SqliteDialect) HistoryUpgrades() []HistoryUpgrade {
+	return historyUpgrades(s.table(), "ADD COLUMN", "TEXT", "TEXT", "1 = 0")
+}
+
+// ScriptSQL returns the SQL to get the script stored with a migration.
+func (s SqliteDialect) ScriptSQL() string {
+	return fmt.Sprintf(`SELECT script FROM %s WHERE version = ?;`, s.table())
+}
+
+// SupportsTransactionalDDL returns true, SQLite rolls back schema changes.
+func (s SqliteDialect) SupportsTransactionalDDL() bool {
+	return true
+}
+
+// Syntax returns the syntax of the statements.
+func (s SqliteDialect) Syntax() Syntax {
+	return SqliteSyntax
 }
@@ -0,0 +1,178 @@
+package darwin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Squash returns the baseline migration replacing the migrations up to the
+// version through: their scripts in order, each after a comment naming it,
+// as the single migration of version through. Projects with many
+// migrations squash the oldest ones to keep the bootstrap of a new database
+// short. The migrations must have the version through; a single migration
+// up to it, like a baseline, is returned as is.
+func Squash(migrations []Migration, through float64) (Migration, error) {
+	var squashed []Migration
+	for _, m := range migrations {
+		if m.Version <= through {
+			squashed = append(squashed, m)
+		}
+	}
+	sort.Sort(byMigrationVersion(squashed))
+
+	squashed, err := loadScripts(nil, squashed)
+	if err != nil {
+		return Migration{}, err
+	}
+
+	if len(squashed) == 0 || squashed[len(squashed)-1].Version != through {
+		return Migration{}, fmt.Errorf("darwin: no migration has the version %f", through)
+	}
+
+	if len(squashed) == 1 {
+		return squashed[0], nil
+	}
+
+	var script strings.Builder
+	for i, m := range squashed {
+		if i > 0 {
+			script.WriteString("\n")
+		}
+		fmt.Fprintf(&script, "-- Squashed version %s: %s\n", strconv.FormatFloat(m.Version, 'f', -1, 64), m.Description)
+		script.WriteString(strings.TrimRight(m.Script, "\n"))
+		script.WriteString("\n")
+	}
+
+	return Migration{
+		Version:     through,
+		Description: fmt.Sprintf("Baseline through version %s", strconv.FormatFloat(through, 'f', -1, 64)),
+		Script:      script.String(),
+		Files:       squashed[0].Files,
+	}, nil
+}
+
+// SquashHistory replaces the records of the migrations up to the version of
+// the baseline with the record of the baseline, so the database is valid
+// against the squashed migrations. A database where none of them is applied
+// is left as is, as is one already squashed. It fails when the migration
+// of the version of the baseline isn't applied. The records are deleted and
+// the baseline inserted in a transaction, so a failure leaves the history
+// as it was, which requires a HistoryTransactor or Transactor driver whose
+// transactions are TxDeleters.
+func SquashHistory(d Driver, baseline Migration) error {
+	return squashHistory(context.Background(), d, baseline)
+}
+
+// squashHistory is SquashHistory, with the transaction canceled with ctx.
+func squashHistory(ctx context.Context, d Driver, baseline Migration) (err error) {
+	records, err := d.All()
+	if err != nil {
+		return err
+	}
+
+	var squashed []MigrationRecord
+	latest := MigrationRecord{}
+	found := false
+	for _, r := range records {
+		if r.Version > baseline.Version {
+			continue
+		}
+		squashed = append(squashed, r)
+		if r.Version == baseline.Version {
+			latest, found = r, true
+		}
+	}
+
+	switch {
+	case len(squashed) == 0:
+		return nil
+	case !found:
+		return fmt.Errorf("darwin: migration %f isn't applied, the history can't be squashed", baseline.Version)
+	case len(squashed) == 1 && latest.Checksum == baseline.Checksum():
+		return nil
+	}
+
+	tx, err := historyTx(ctx, d)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	record := MigrationRecord{
+		Version:        baseline.Version,
+		Description:    baseline.Description,
+		Checksum:       baseline.Checksum(),
+		AppliedAt:      latest.AppliedAt,
+		RecordMetadata: latest.RecordMetadata,
+	}
+
+	for _, r := range squashed {
+		record.ExecutionTime += r.ExecutionTime
+		if r.AppliedAt.After(record.AppliedAt) {
+			record.AppliedAt = r.AppliedAt
+		}
+
+		if err := tx.(TxDeleter).Delete(ctx, r.Version); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Insert(ctx, record); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Squash returns the baseline migration of the migrations up to the version
+// through and replaces their records with its record, holding the migration
+// lock. The history must be valid, unless the migrations are already
+// squashed, as they are when the history of another database sharing them
+// was squashed first.
+func (d Darwin) Squash(ctx context.Context, through float64) (baseline Migration, err error) {
+	ctx, span := d.startSpan(ctx, "darwin.Squash")
+	defer func() { endSpan(span, err) }()
+
+	baseline, err = Squash(d.migrations, through)
+	if err != nil {
+		return baseline, err
+	}
+
+	_, release, err := d.acquire(ctx)
+	if err != nil {
+		return baseline, err
+	}
+	defer func() {
+		if rerr := release(); err == nil {
+			err = rerr
+		}
+	}()
+
+	squashed := 0
+	for _, m := range d.migrations {
+		if m.Version <= through {
+			squashed++
+		}
+	}
+
+	if squashed > 1 {
+		if err := d.validate(ctx); err != nil {
+			return baseline, err
+		}
+	}
+
+	start := time.Now()
+	if err := squashHistory(ctx, d.driver, baseline); err != nil {
+		return baseline, err
+	}
+
+	d.logger().Info("darwin: history squashed", "version", through, "duration", time.Since(start))
+	return baseline, nil
+}
@@ -0,0 +1,105 @@
+package darwin
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func squashMigrations() []Migration {
+	return []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);\n"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);\n"},
+		{Version: 3, Description: "Teams", Script: "CREATE TABLE teams (id INT);\n"},
+	}
+}
+
+func Test_Squash(t *testing.T) {
+	baseline, err := Squash(squashMigrations(), 2)
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	expected := "-- Squashed version 1: Users\nCREATE TABLE users (id INT);\n\n-- Squashed version 2: Roles\nCREATE TABLE roles (id INT);\n"
+	if baseline.Version != 2 || baseline.Description != "Baseline through version 2" || baseline.Script != expected {
+		t.Errorf("Must concatenate the squashed scripts, got %#v", baseline)
+	}
+
+	if again, _ := Squash([]Migration{baseline}, 2); again.Checksum() != baseline.Checksum() {
+		t.Errorf("Must return a baseline as is, got %#v", again)
+	}
+
+	if _, err := Squash(squashMigrations(), 2.5); err == nil {
+		t.Error("Must emit error when no migration has the version")
+	}
+}
+
+func Test_Darwin_Squash(t *testing.T) {
+	applied := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	driver := &txDriver{}
+	for _, m := range squashMigrations() {
+		driver.records = append(driver.records, MigrationRecord{
+			Version:       m.Version,
+			Description:   m.Description,
+			Checksum:      m.Checksum(),
+			AppliedAt:     applied.Add(time.Duration(m.Version) * time.Hour),
+			ExecutionTime: time.Second,
+		})
+	}
+
+	baseline, err := New(driver, squashMigrations()).Squash(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	records := driver.records
+	if len(records) != 2 || records[0].Version != 3 || records[1].Version != 2 {
+		t.Fatalf("Must replace the squashed records, got %#v", records)
+	}
+
+	if records[1].Checksum != baseline.Checksum() || records[1].ExecutionTime != 2*time.Second || !records[1].AppliedAt.Equal(applied.Add(2*time.Hour)) {
+		t.Errorf("Must record the baseline, got %#v", records[1])
+	}
+
+	migrations := []Migration{baseline, squashMigrations()[2]}
+	if err := Validate(driver, migrations); err != nil {
+		t.Errorf("Must be valid against the squashed migrations, got %s", err)
+	}
+
+	if _, err := New(driver, migrations).Squash(context.Background(), 2); err != nil || len(driver.records) != 2 {
+		t.Errorf("Must leave a squashed history as is, got %v %#v", err, driver.records)
+	}
+}
+
+func Test_SquashHistory_transaction(t *testing.T) {
+	baseline, _ := Squash(squashMigrations(), 2)
+	records := []MigrationRecord{{Version: 1}, {Version: 2}}
+
+	if err := SquashHistory(&downDriver{dummyDriver: dummyDriver{records: records}}, baseline); err == nil {
+		t.Error("Must refuse a driver without transactions")
+	}
+
+	driver := &txDriver{insertError: true}
+	driver.records = append([]MigrationRecord(nil), records...)
+	if err := SquashHistory(driver, baseline); err == nil {
+		t.Fatal("Must return the error of Insert")
+	}
+
+	if fmt.Sprint(driver.calls) != "[begin tx.delete tx.delete tx.insert rollback]" || len(driver.records) != 2 {
+		t.Errorf("Must roll the history back, got %v and %#v", driver.calls, driver.records)
+	}
+}
+
+func Test_SquashHistory_not_applied(t *testing.T) {
+	baseline, _ := Squash(squashMigrations(), 2)
+
+	if err := SquashHistory(&downDriver{}, baseline); err != nil {
+		t.Errorf("Must leave an empty history as is, got %s", err)
+	}
+
+	driver := &downDriver{dummyDriver: dummyDriver{records: []MigrationRecord{{Version: 1}}}}
+	if err := SquashHistory(driver, baseline); err == nil {
+		t.Error("Must emit error when the baseline version isn't applied")
+	}
+}
@@ -0,0 +1,99 @@
+package darwin
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+// RunSummary is the outcome of a run of Migrate, for debug endpoints.
+type RunSummary struct {
+	// LatestVersion is the latest migration applied, zero when none is.
+	LatestVersion float64
+
+	// Applied is the number of migrations executed by the run.
+	Applied int
+
+	// Pending is the number of migrations left to execute after the run.
+	Pending int
+
+	// Error is the error of a failed run, empty when it succeeded.
+	Error string
+
+	Time     time.Time
+	Duration time.Duration
+}
+
+// lastRun holds the summary of the last run, shared by the copies of a
+// Darwin.
+type lastRun struct {
+	mu      sync.Mutex
+	summary RunSummary
+	ok      bool
+}
+
+// LastRun returns the summary of the last run of Migrate, if it ran.
+func (d Darwin) LastRun() (RunSummary, bool) {
+	if d.last == nil {
+		return RunSummary{}, false
+	}
+
+	d.last.mu.Lock()
+	defer d.last.mu.Unlock()
+
+	return d.last.summary, d.last.ok
+}
+
+// Publish publishes the summary of the last run of Migrate as the expvar
+// variable name, shown by the /debug/vars endpoint. Like expvar.Publish, it
+// panics if the name is already used.
+func (d Darwin) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		s, _ := d.LastRun()
+		return s
+	}))
+}
+
+// recordRun records the summary of a run of Migrate started at start, which
+// planned the migrations.
+func (d Darwin) recordRun(planned []Migration, report Report, err error, start time.Time) {
+	if d.last == nil {
+		return
+	}
+
+	s := RunSummary{
+		Applied:  len(report.Results),
+		Time:     start,
+		Duration: time.Since(start),
+	}
+
+	if err != nil {
+		s.Error = err.Error()
+	}
+
+	// Once planned, the migrations which weren't planned were applied.
+	if planned != nil {
+		s.Pending = len(planned) - len(report.Results)
+
+		pending := map[float64]bool{}
+		for _, m := range planned {
+			pending[m.Version] = true
+		}
+		for _, m := range d.migrations {
+			if !pending[m.Version] && m.Version > s.LatestVersion {
+				s.LatestVersion = m.Version
+			}
+		}
+	}
+
+	for _, r := range report.Results {
+		if r.Migration.Version > s.LatestVersion {
+			s.LatestVersion = r.Migration.Version
+		}
+	}
+
+	d.last.mu.Lock()
+	defer d.last.mu.Unlock()
+
+	d.last.summary, d.last.ok = s, true
+}
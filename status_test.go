@@ -0,0 +1,53 @@
+package darwin
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+)
+
+func Test_Darwin_LastRun(t *testing.T) {
+	records := []MigrationRecord{{Version: 1, Checksum: Migration{Script: "CREATE TABLE users (id INT);"}.Checksum()}}
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+
+	d := New(&dummyDriver{records: records}, migrations)
+
+	if _, ok := d.LastRun(); ok {
+		t.Fatalf("Must not have a summary before Migrate")
+	}
+
+	if err := d.Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	s, ok := d.LastRun()
+	if !ok || s.LatestVersion != 2 || s.Applied != 1 || s.Pending != 0 || s.Error != "" || s.Time.IsZero() {
+		t.Errorf("Must summarize the run, got %#v", s)
+	}
+}
+
+func Test_Darwin_LastRun_failure(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+
+	d := New(&dummyDriver{ExecError: true}, migrations)
+	d.Publish("darwin_test_last_run")
+
+	if err := d.Migrate(); err == nil {
+		t.Fatalf("Must return the error of Exec")
+	}
+
+	var s RunSummary
+	if err := json.Unmarshal([]byte(expvar.Get("darwin_test_last_run").String()), &s); err != nil {
+		t.Fatalf("Must publish the summary as JSON, got %s", err)
+	}
+
+	if s.LatestVersion != 0 || s.Applied != 0 || s.Pending != 2 || s.Error == "" {
+		t.Errorf("Must summarize the failed run, got %#v", s)
+	}
+}
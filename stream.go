@@ -0,0 +1,248 @@
+package darwin
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// streamDirective makes FSSource stream the script of a migration to the
+// drivers implementing ExecReader, outside of any transaction, instead of
+// reading it in memory:
+//
+//	-- darwin:stream
+const streamDirective = "stream"
+
+// ScriptOpener is implemented by the loaders able to stream the script of
+// a migration. Drivers implementing ExecReader execute such scripts as they
+// read them, so a migration seeding hundreds of megabytes of data is never
+// held in memory.
+type ScriptOpener interface {
+	OpenScript() (io.ReadCloser, error)
+}
+
+// ExecReader is implemented by drivers able to execute a script read from
+// r, a few statements at a time.
+type ExecReader interface {
+	ExecReader(ctx context.Context, r io.Reader) (time.Duration, error)
+}
+
+// streamChunk is the size of the statements the generic driver reads
+// before executing them.
+const streamChunk = 64 << 10
+
+// ExecReader executes the statements of the script read from r one at a
+// time, without a transaction, reading them a chunk at a time.
+func (m *GenericDriver) ExecReader(ctx context.Context, r io.Reader) (time.Duration, error) {
+	start := time.Now()
+
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return time.Since(start), err
+	}
+	defer conn.Close()
+
+	sr := newStatementReader(r, syntaxOf(m.Dialect))
+	for i := 0; ; {
+		statements, err := sr.next()
+		if err == io.EOF {
+			return time.Since(start), nil
+		}
+		if err != nil {
+			return time.Since(start), err
+		}
+
+		for _, stmt := range statements {
+			if _, err := conn.ExecContext(ctx, stmt); err != nil {
+				return time.Since(start), statementError{index: i, statement: stmt, err: err}
+			}
+			i++
+		}
+	}
+}
+
+// streamed reports if the migration is executed as its script is read,
+// which requires an ExecReader driver.
+func streamed(d Driver, m Migration) bool {
+	if _, ok := d.(ExecReader); !ok {
+		return false
+	}
+	return streamable(m)
+}
+
+// streamable reports if the migration has a script to stream.
+func streamable(m Migration) bool {
+	_, ok := m.Loader.(ScriptOpener)
+	return m.Script == "" && ok
+}
+
+// execStreamed executes the streamed script of the migration.
+func execStreamed(ctx context.Context, d Driver, m Migration) (Result, error) {
+	result := Result{Migration: m}
+
+	rc, err := m.Loader.(ScriptOpener).OpenScript()
+	if err != nil {
+		return result, fmt.Errorf("darwin: unable to open migration %f: %w", m.Version, err)
+	}
+	defer rc.Close()
+
+	result.Duration, err = d.(ExecReader).ExecReader(ctx, rc)
+	return result, err
+}
+
+// streamChecksum returns the checksum of the streamed script.
+func streamChecksum(o ScriptOpener) (string, error) {
+	rc, err := o.OpenScript()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// streamSentinel is appended to the statements read to tell whether the
+// last of them is complete.
+const streamSentinel = "darwin_end_of_chunk"
+
+// statementReader reads the statements of a script a chunk at a time.
+type statementReader struct {
+	r      *bufio.Reader
+	syntax Syntax
+
+	// delimiter is the delimiter line in effect, read again before each
+	// chunk.
+	delimiter string
+}
+
+func newStatementReader(r io.Reader, syntax Syntax) *statementReader {
+	return &statementReader{r: bufio.NewReader(r), syntax: syntax}
+}
+
+// next returns the statements of the next chunk of the script, io.EOF once
+// they are all read. A chunk ends after a complete statement.
+func (s *statementReader) next() ([]string, error) {
+	var chunk strings.Builder
+	chunk.WriteString(s.delimiter)
+	delimiter := ";"
+	if d, ok := delimiterLine(strings.TrimSpace(s.delimiter), s.syntax); ok {
+		delimiter = d
+	}
+
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		chunk.WriteString(line)
+
+		trimmed := strings.TrimSpace(line)
+		if d, ok := delimiterLine(trimmed, s.syntax); ok {
+			delimiter, s.delimiter = d, trimmed+"\n"
+			if d == ";" {
+				s.delimiter = ""
+			}
+		}
+
+		if err == io.EOF {
+			statements := SplitStatements(chunk.String(), s.syntax)
+			if len(statements) == 0 {
+				return nil, io.EOF
+			}
+			return statements, nil
+		}
+
+		if chunk.Len() < streamChunk || !strings.HasSuffix(trimmed, delimiter) {
+			continue
+		}
+
+		statements := SplitStatements(chunk.String()+"\n"+streamSentinel, s.syntax)
+		if n := len(statements); n > 1 && statements[n-1] == streamSentinel {
+			return statements[:n-1], nil
+		}
+	}
+}
+
+// fileStream streams the script of the migration version of a file.
+type fileStream struct {
+	fileScript
+}
+
+// OpenScript implements the ScriptOpener interface. The script of a
+// normalized source is read in memory to be normalized.
+func (f *fileStream) OpenScript() (io.ReadCloser, error) {
+	p := newParser(f.source.Options)
+	if p.normalize {
+		script, err := f.LoadScript()
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(strings.NewReader(script)), nil
+	}
+
+	file, err := f.files.Open(f.name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &migrationReader{r: bufio.NewReader(file), closer: file, parser: p, version: f.version, first: true}, nil
+}
+
+// migrationReader reads the script of the migration version of a document
+// read from r, the way ParseMigrations does.
+type migrationReader struct {
+	r      *bufio.Reader
+	closer io.Closer
+	parser parser
+
+	version float64
+	inside  bool
+	first   bool
+	line    string
+}
+
+func (m *migrationReader) Read(p []byte) (int, error) {
+	for m.line == "" {
+		line, err := m.r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return 0, err
+		}
+
+		if m.first {
+			line, m.first = strings.TrimPrefix(line, byteOrderMark), false
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if value, ok := m.parser.header(line, m.parser.versionMarker); ok {
+			if m.inside {
+				return 0, io.EOF
+			}
+			v, err := strconv.ParseFloat(value, 64)
+			m.inside = err == nil && v == m.version
+			continue
+		}
+
+		if _, ok := m.parser.header(line, m.parser.descriptionMarker); ok || !m.inside {
+			continue
+		}
+
+		m.line = line + "\n"
+	}
+
+	n := copy(p, m.line)
+	m.line = m.line[n:]
+	return n, nil
+}
+
+func (m *migrationReader) Close() error {
+	return m.closer.Close()
+}
@@ -0,0 +1,116 @@
+package darwin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type streamDriver struct {
+	dummyDriver
+	streamed []string
+}
+
+func (d *streamDriver) ExecReader(ctx context.Context, r io.Reader) (time.Duration, error) {
+	b, err := io.ReadAll(r)
+	d.streamed = append(d.streamed, string(b))
+	return time.Millisecond, err
+}
+
+func Test_statementReader(t *testing.T) {
+	var script strings.Builder
+	script.WriteString("-- darwin:delimiter $$\nCREATE PROCEDURE fill() BEGIN INSERT INTO t VALUES (1); END$$\n-- darwin:delimiter ;\n")
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&script, "INSERT INTO t VALUES (%d, 'a;\nb');\n", i)
+	}
+	script.WriteString("-- darwin:delimiter $$\nCREATE PROCEDURE last() BEGIN SELECT 1; END$$\n")
+
+	sr := newStatementReader(strings.NewReader(script.String()), StandardSyntax)
+
+	var statements []string
+	chunks := 0
+	for {
+		s, err := sr.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Must not return error, got %s", err)
+		}
+		statements = append(statements, s...)
+		chunks++
+	}
+
+	if chunks < 2 {
+		t.Errorf("Must read the script a chunk at a time, got %d chunks", chunks)
+	}
+
+	if expected := SplitStatements(script.String(), StandardSyntax); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("Must read the statements of the script, got %d, expected %d", len(statements), len(expected))
+	}
+}
+
+func Test_FSSource_stream(t *testing.T) {
+	document := "\ufeff-- Version: 1\r\nCREATE TABLE t (id INT);\n-- Version: 2\n-- Description: Seed\n-- darwin:stream\nINSERT INTO t VALUES (1);\nINSERT INTO t VALUES (2);\n-- Version: 3\nSELECT 3;"
+	fsys := fstest.MapFS{"001_seed.sql": {Data: []byte(document)}}
+
+	eager := ParseMigrations(document)
+	migrations, err := NewFSSource(fsys, "").Migrations()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if migrations[0].Script != eager[0].Script || migrations[1].Script != "" || migrations[1].Checksum() != eager[1].Checksum() {
+		t.Errorf("Must leave the streamed script out with its checksum, got %#v", migrations)
+	}
+
+	driver := &streamDriver{}
+	if err := New(driver, migrations).Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(driver.streamed) != 1 || driver.streamed[0] != eager[1].Script {
+		t.Errorf("Must stream the script of the migration, got %#v", driver.streamed)
+	}
+
+	if len(driver.records) != 3 || driver.records[1].Checksum != eager[1].Checksum() {
+		t.Errorf("Must record the streamed migration with its checksum, got %#v", driver.records)
+	}
+
+	if planned, _ := planMigration(&dummyDriver{}, migrations); planned[1].Script != eager[1].Script {
+		t.Errorf("Must load the script for the drivers which can't stream it, got %q", planned[1].Script)
+	}
+}
+
+func Test_GenericDriver_ExecReader(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, _ := NewGenericDriver(db, PostgresDialect{})
+
+	mock.ExpectExec(escapeQuery("INSERT INTO t VALUES (1)")).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(escapeQuery("INSERT INTO t VALUES (2)")).WillReturnError(fmt.Errorf("duplicate"))
+
+	_, err = d.ExecReader(context.Background(), strings.NewReader("INSERT INTO t VALUES (1);\nINSERT INTO t VALUES (2);\n"))
+	se, ok := err.(statementError)
+	if !ok {
+		t.Fatalf("Must report the failed statement, got %v", err)
+	}
+	if i, stmt := se.FailedStatement(); i != 1 || stmt != "INSERT INTO t VALUES (2)" {
+		t.Errorf("Must report the failed statement, got %d %q", i, stmt)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
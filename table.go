@@ -0,0 +1,188 @@
+package darwin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// defaultTable is the name of the history table.
+const defaultTable = "darwin_migrations"
+
+// TableDialect is implemented by the dialects able to keep the history in
+// another table, as the dialects of this package do. WithTable returns the
+// dialect using the table name, qualified by its schema or not.
+type TableDialect interface {
+	WithTable(name string) Dialect
+}
+
+// TableNamer is implemented by drivers able to keep the history in another
+// table, which WithTableName requires. The built-in drivers all are.
+type TableNamer interface {
+	SetTableName(name string) error
+}
+
+// TableCloner is implemented by drivers able to return a copy of themselves
+// keeping another history in the table name, sharing their database, which
+// WithSeeds requires to keep the history of the seeds. The built-in drivers
+// all are.
+type TableCloner interface {
+	CloneTable(name string) (Driver, error)
+}
+
+// WithTableName makes the driver keep the history in the table name,
+// qualified by its schema or not, like "ops.schema_migrations", so several
+// applications can share a database. The driver quotes the name as its
+// dialect requires, and takes a lock of its own. When the driver isn't a
+// TableNamer able to use the name, the methods of Darwin using the driver
+// return the error, Migrate included.
+func WithTableName(name string) Option {
+	return func(d *Darwin) {
+		t, ok := d.driver.(TableNamer)
+		if !ok {
+			d.setErr(errors.New("darwin: WithTableName: the driver can't change the name of the history table"))
+			return
+		}
+		if err := t.SetTableName(name); err != nil {
+			d.setErr(fmt.Errorf("darwin: WithTableName: %w", err))
+		}
+	}
+}
+
+// SetTableName makes the driver keep the history in the table name, when
+// its dialect is a TableDialect.
+func (m *GenericDriver) SetTableName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("darwin: the table name is empty")
+	}
+
+	t, ok := m.Dialect.(TableDialect)
+	if !ok {
+		return errors.New("darwin: the dialect can't change the name of the history table")
+	}
+
+	m.Dialect = t.WithTable(name)
+	return nil
+}
+
+// WithTable returns a copy of the driver keeping the history in the table
+// name, sharing its database, for the drivers embedding a GenericDriver to
+// implement TableCloner.
+func (m *GenericDriver) WithTable(name string) (*GenericDriver, error) {
+	c := &GenericDriver{DB: m.DB, Dialect: m.Dialect, LockTimeout: m.LockTimeout, IndexTimeout: m.IndexTimeout}
+	if err := c.SetTableName(name); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// CloneTable returns a copy of the driver keeping the history in the table
+// name.
+func (m *GenericDriver) CloneTable(name string) (Driver, error) {
+	c, err := m.WithTable(name)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// QuoteTable returns the table name, darwin_migrations by default, with
+// each part of a name qualified by its schema between the quotes, doubled
+// within it. The TableDialects of the drivers quote their table with it.
+func QuoteTable(name string, quote string) string {
+	if name == "" {
+		return defaultTable
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quote + strings.Replace(part, quote, quote+quote, -1) + quote
+	}
+	return strings.Join(parts, ".")
+}
+
+// lockKey returns the name of the lock of the history table name, as a SQL
+// literal, so applications sharing a database don't wait for each other.
+func lockKey(name string) string {
+	if name == "" {
+		name = defaultTable
+	}
+	return quoteLiteral(name)
+}
+
+func (p PostgresDialect) table() string {
+	return QuoteTable(p.Table, `"`)
+}
+
+// WithTable returns the dialect using the table name.
+func (p PostgresDialect) WithTable(name string) Dialect {
+	p.Table = name
+	return p
+}
+
+func (m MySQLDialect) table() string {
+	return QuoteTable(m.Table, "`")
+}
+
+// statementsTable returns the name of the checkpoint table.
+func (m MySQLDialect) statementsTable() string {
+	if m.Table == "" {
+		return "darwin_migration_statements"
+	}
+	return QuoteTable(m.Table+"_statements", "`")
+}
+
+// WithTable returns the dialect using the table name.
+func (m MySQLDialect) WithTable(name string) Dialect {
+	m.Table = name
+	return m
+}
+
+func (s SqliteDialect) table() string {
+	return QuoteTable(s.Table, `"`)
+}
+
+// WithTable returns the dialect using the table name.
+func (s SqliteDialect) WithTable(name string) Dialect {
+	s.Table = name
+	return s
+}
+
+func (q QLDialect) table() string {
+	if q.Table == "" {
+		return defaultTable
+	}
+	return q.Table
+}
+
+// index returns the name of the index of the versions, with the characters
+// QL doesn't allow in identifiers, like the dot of a schema, replaced.
+func (q QLDialect) index() string {
+	if q.Table == "" {
+		return "idx_versions"
+	}
+	return "idx_" + identifier(q.Table) + "_versions"
+}
+
+// identifier returns the name with the characters other than the letters,
+// digits and underscores replaced by underscores.
+func identifier(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// WithTable returns the dialect using the table name.
+func (q QLDialect) WithTable(name string) Dialect {
+	q.Table = name
+	return q
+}
+
+// WithTable returns the dialect using the table name, which isn't quoted.
+func (s StandardDialect) WithTable(name string) Dialect {
+	s.Table = name
+	return s
+}
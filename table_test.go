@@ -0,0 +1,115 @@
+package darwin
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func Test_quoteTable(t *testing.T) {
+	tests := []struct {
+		name  string
+		quote string
+		want  string
+	}{
+		{"", `"`, "darwin_migrations"},
+		{"schema_migrations", `"`, `"schema_migrations"`},
+		{"ops.schema_migrations", `"`, `"ops"."schema_migrations"`},
+		{"ops.schema_migrations", "`", "`ops`.`schema_migrations`"},
+		{`we"ird`, `"`, `"we""ird"`},
+	}
+
+	for _, tt := range tests {
+		if got := QuoteTable(tt.name, tt.quote); got != tt.want {
+			t.Errorf("QuoteTable(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func Test_TableDialect_sql(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		table   string
+		lock    string
+	}{
+		{PostgresDialect{Table: "ops.schema_migrations"}, `"ops"."schema_migrations"`, "hashtext('ops.schema_migrations')"},
+		{MySQLDialect{Table: "ops.schema_migrations"}, "`ops`.`schema_migrations`", "GET_LOCK('ops.schema_migrations'"},
+		{SqliteDialect{Table: "ops.schema_migrations"}, `"ops"."schema_migrations"`, ""},
+		{QLDialect{Table: "schema_migrations"}, "schema_migrations", ""},
+	}
+
+	for _, tt := range tests {
+		for _, sql := range []string{tt.dialect.CreateTableSQL(), tt.dialect.InsertSQL(), tt.dialect.AllSQL(), tt.dialect.(DeleteDialect).DeleteSQL()} {
+			if !strings.Contains(sql, tt.table) || strings.Contains(sql, "darwin_migrations") {
+				t.Errorf("%T: expected the table %s, got %s", tt.dialect, tt.table, sql)
+			}
+		}
+
+		if ld, ok := tt.dialect.(LockDialect); ok && !strings.Contains(ld.LockSQL(), tt.lock) {
+			t.Errorf("%T: expected the lock %s, got %s", tt.dialect, tt.lock, ld.LockSQL())
+		}
+	}
+
+	checkpoints := MySQLDialect{Table: "ops.schema_migrations"}.CreateCheckpointTableSQL()
+	if !strings.Contains(checkpoints, "`ops`.`schema_migrations_statements`") {
+		t.Errorf("expected the checkpoint table to follow the history table, got %s", checkpoints)
+	}
+}
+
+func Test_WithTableName(t *testing.T) {
+	db, err := sql.Open("ql-mem", "table.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	dv, err := NewGenericDriver(db, QLDialect{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrations := []Migration{
+		{Version: 1, Description: "Creating table posts", Script: "CREATE TABLE posts (id int);"},
+	}
+
+	if err := New(dv, migrations, WithTableName("schema_migrations")).Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if !hasTable(db, "schema_migrations", t) {
+		t.Error("expected the table schema_migrations to exist")
+	}
+	if hasTable(db, "darwin_migrations", t) {
+		t.Error("expected the table darwin_migrations not to exist")
+	}
+
+	records, err := dv.All()
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+	if len(records) != 1 {
+		t.Errorf("expected 1 record, got %d", len(records))
+	}
+}
+
+func Test_WithTableName_unsupported(t *testing.T) {
+	d := New(&dummyDriver{}, nil, WithTableName("schema_migrations"))
+
+	if err := d.Migrate(); err == nil || !strings.Contains(err.Error(), "WithTableName") {
+		t.Errorf("Must return the error of WithTableName from Migrate, got %v", err)
+	}
+	if err := d.Validate(); err == nil {
+		t.Error("Must return the error of WithTableName from Validate")
+	}
+
+	dv := &GenericDriver{Dialect: QLDialect{}}
+	if err := New(dv, nil, WithTableName(" ")).Migrate(); err == nil {
+		t.Error("Must return the error of SetTableName")
+	}
+}
+
+func Test_QLDialect_index(t *testing.T) {
+	if got := (QLDialect{Table: "ops.schema_migrations"}).index(); got != "idx_ops_schema_migrations_versions" {
+		t.Errorf("Must replace the dot of the index name, got %s", got)
+	}
+}
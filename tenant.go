@@ -0,0 +1,135 @@
+package darwin
+
+import (
+	"context"
+	"strings"
+)
+
+// SchemaPlaceholder is replaced by the name of the schema in the scripts of
+// the migrations applied by Tenants.
+const SchemaPlaceholder = "{{schema}}"
+
+// Tenants applies the same migrations to many schemas of a database, one per
+// tenant or shard. Each schema has its own history, kept by the driver
+// returned by Driver, usually a table in the schema:
+//
+//	tenants := darwin.Tenants{
+//		Schemas:    []string{"tenant_a", "tenant_b"},
+//		Migrations: migrations, // CREATE TABLE {{schema}}.users (...)
+//		Driver: func(schema string) (darwin.Driver, error) {
+//			return darwin.NewGenericDriver(db, darwin.StandardDialect{
+//				Table:       schema + ".darwin_migrations",
+//				Placeholder: darwin.PlaceholderDollar,
+//			})
+//		},
+//	}
+//	report := tenants.Migrate(ctx)
+//
+// The report tells which tenants are behind, so a partially migrated tenant
+// is caught up by the next run.
+type Tenants struct {
+	Schemas    []string
+	Migrations []Migration
+	Driver     func(schema string) (Driver, error)
+
+	// Concurrency is how many schemas are migrated at the same time, all
+	// of them when zero.
+	Concurrency int
+
+	// Options configure the Darwin migrating each schema.
+	Options []Option
+}
+
+// Migrate executes the missing migrations in every schema. A schema failing
+// doesn't stop the others, the errors are in the report.
+func (t Tenants) Migrate(ctx context.Context) FleetReport {
+	fleet, failed := t.fleet()
+	report := fleet.Migrate(ctx)
+	return merge(report, failed, t.Schemas)
+}
+
+// Info returns the state of every schema without migrating them.
+func (t Tenants) Info(ctx context.Context) FleetReport {
+	fleet, failed := t.fleet()
+	report := fleet.Info(ctx)
+	return merge(report, failed, t.Schemas)
+}
+
+// TenantInfo returns the status of all migrations in the schema.
+func (t Tenants) TenantInfo(schema string) ([]MigrationInfo, error) {
+	d, err := t.Driver(schema)
+	if err != nil {
+		return nil, err
+	}
+	return Info(d, RenderSchema(t.Migrations, schema))
+}
+
+// fleet returns the Fleet of the schemas, and the results of the schemas
+// without driver.
+func (t Tenants) fleet() (Fleet, map[string]TargetResult) {
+	fleet := Fleet{Concurrency: t.Concurrency, Options: t.Options}
+	failed := map[string]TargetResult{}
+
+	for _, schema := range t.Schemas {
+		d, err := t.Driver(schema)
+		if err != nil {
+			failed[schema] = TargetResult{Name: schema, Err: err, Pending: len(t.Migrations)}
+			continue
+		}
+
+		fleet.Targets = append(fleet.Targets, Target{
+			Name:       schema,
+			Driver:     d,
+			Migrations: RenderSchema(t.Migrations, schema),
+		})
+	}
+
+	return fleet, failed
+}
+
+// merge returns the report with the results of the schemas without driver,
+// in the order of the schemas.
+func merge(report FleetReport, failed map[string]TargetResult, schemas []string) FleetReport {
+	if len(failed) == 0 {
+		return report
+	}
+
+	merged := FleetReport{Results: make([]TargetResult, 0, len(schemas))}
+	next := 0
+	for _, schema := range schemas {
+		if result, ok := failed[schema]; ok {
+			merged.Results = append(merged.Results, result)
+			continue
+		}
+		merged.Results = append(merged.Results, report.Results[next])
+		next++
+	}
+
+	return merged
+}
+
+// renderedScript loads a script with SchemaPlaceholder replaced by schema.
+type renderedScript struct {
+	loader ScriptLoader
+	schema string
+}
+
+func (r *renderedScript) LoadScript() (string, error) {
+	script, err := r.loader.LoadScript()
+	return strings.Replace(script, SchemaPlaceholder, r.schema, -1), err
+}
+
+// RenderSchema returns the migrations with SchemaPlaceholder replaced by
+// schema in their scripts.
+func RenderSchema(migrations []Migration, schema string) []Migration {
+	rendered := make([]Migration, len(migrations))
+	for i, m := range migrations {
+		m.Script = strings.Replace(m.Script, SchemaPlaceholder, schema, -1)
+		if m.Loader != nil {
+			m.Loader = &renderedScript{loader: m.Loader, schema: schema}
+			m.ScriptChecksum = ""
+		}
+		rendered[i] = m
+	}
+	return rendered
+}
@@ -0,0 +1,65 @@
+package darwin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// scriptDriver is a dummyDriver recording the scripts it executes.
+type scriptDriver struct {
+	dummyDriver
+	scripts []string
+}
+
+func (d *scriptDriver) Exec(script string) (time.Duration, error) {
+	d.scripts = append(d.scripts, script)
+	return d.dummyDriver.Exec(script)
+}
+
+func Test_Tenants_Migrate(t *testing.T) {
+	drivers := map[string]*scriptDriver{
+		"tenant_a": {},
+		"tenant_b": {dummyDriver: dummyDriver{ExecError: true}},
+	}
+
+	tenants := Tenants{
+		Schemas:    []string{"tenant_a", "missing", "tenant_b"},
+		Migrations: []Migration{{Version: 1, Description: "Users", Script: "CREATE TABLE {{schema}}.users (id INT);"}},
+		Driver: func(schema string) (Driver, error) {
+			d, ok := drivers[schema]
+			if !ok {
+				return nil, errors.New("unknown schema")
+			}
+			return d, nil
+		},
+	}
+
+	report := tenants.Migrate(context.Background())
+
+	if len(report.Results) != 3 {
+		t.Fatalf("Must report every schema, got %#v", report.Results)
+	}
+
+	if a := report.Results[0]; a.Name != "tenant_a" || a.Err != nil || a.Version != 1 {
+		t.Errorf("Must migrate tenant_a, got %#v", a)
+	}
+
+	if got := drivers["tenant_a"].scripts; len(got) != 1 || got[0] != "CREATE TABLE tenant_a.users (id INT);" {
+		t.Errorf("Must replace the schema placeholder, got %q", got)
+	}
+
+	if missing := report.Results[1]; missing.Name != "missing" || missing.Err == nil || missing.Pending != 1 {
+		t.Errorf("Must report schemas without driver, got %#v", missing)
+	}
+
+	if behind := report.Behind(); len(behind) != 2 || behind[1].Name != "tenant_b" {
+		t.Errorf("Must report the partially migrated tenants, got %#v", behind)
+	}
+
+	info, err := tenants.TenantInfo("tenant_a")
+	if err != nil || len(info) != 1 || info[0].Status != Applied {
+		t.Errorf("Must return the info of the tenant, got %#v, %v", info, err)
+	}
+}
@@ -0,0 +1,67 @@
+package darwin
+
+import "context"
+
+// Tracer starts the spans of Migrate, Validate and Info, with a child span
+// for each migration executed. The core doesn't depend on a tracing library;
+// with OpenTelemetry a Tracer is a few lines:
+//
+//	type tracer struct{ t trace.Tracer }
+//
+//	func (t tracer) Start(ctx context.Context, name string) (context.Context, darwin.Span) {
+//		ctx, s := t.t.Start(ctx, name)
+//		return ctx, span{s}
+//	}
+//
+//	type span struct{ s trace.Span }
+//
+//	func (s span) SetAttribute(key string, value interface{}) {
+//		s.s.SetAttributes(attribute.String(key, fmt.Sprint(value)))
+//	}
+//
+//	func (s span) RecordError(err error) {
+//		s.s.RecordError(err)
+//		s.s.SetStatus(codes.Error, err.Error())
+//	}
+//
+//	func (s span) End() { s.s.End() }
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer. The attributes set by darwin are
+// prefixed with "darwin.", like darwin.version and darwin.rows_affected.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// WithTracer makes Migrate, Validate and Info start spans with t.
+func WithTracer(t Tracer) Option {
+	return func(d *Darwin) {
+		d.tracer = t
+	}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttribute(key string, value interface{}) {}
+func (nopSpan) RecordError(err error)                      {}
+func (nopSpan) End()                                       {}
+
+// startSpan starts a span with the tracer of d, if any.
+func (d Darwin) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if d.tracer == nil {
+		return ctx, nopSpan{}
+	}
+	return d.tracer.Start(ctx, name)
+}
+
+// endSpan ends the span, recording err if the operation failed.
+func endSpan(s Span, err error) {
+	if err != nil {
+		s.RecordError(err)
+	}
+	s.End()
+}
@@ -0,0 +1,80 @@
+package darwin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+type recordTracer struct {
+	spans []*recordSpan
+}
+
+type recordSpan struct {
+	name       string
+	parent     string
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+type spanKey struct{}
+
+func (t *recordTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	s := &recordSpan{name: name, attributes: map[string]interface{}{}}
+	if parent, ok := ctx.Value(spanKey{}).(*recordSpan); ok {
+		s.parent = parent.name
+	}
+	t.spans = append(t.spans, s)
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func (s *recordSpan) SetAttribute(key string, value interface{}) { s.attributes[key] = value }
+func (s *recordSpan) RecordError(err error)                      { s.err = err }
+func (s *recordSpan) End()                                       { s.ended = true }
+
+func Test_WithTracer(t *testing.T) {
+	tracer := &recordTracer{}
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+
+	if err := New(&dummyDriver{}, migrations, WithTracer(tracer)).Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	var names []string
+	for _, s := range tracer.spans {
+		names = append(names, s.name+"<"+s.parent)
+		if !s.ended || s.err != nil {
+			t.Errorf("Must end the span %s without error, got %#v", s.name, s)
+		}
+	}
+
+	expected := []string{"darwin.Migrate<", "darwin.Validate<darwin.Migrate", "darwin.Migration<darwin.Migrate", "darwin.Migration<darwin.Migrate"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("Expected %q, got %q", expected, names)
+	}
+
+	if tracer.spans[0].attributes["darwin.applied"] != 2 || tracer.spans[3].attributes["darwin.version"] != 2.0 {
+		t.Errorf("Must set the attributes, got %v and %v", tracer.spans[0].attributes, tracer.spans[3].attributes)
+	}
+}
+
+func Test_WithTracer_failure(t *testing.T) {
+	tracer := &recordTracer{}
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+	}
+
+	if err := New(&dummyDriver{ExecError: true}, migrations, WithTracer(tracer)).Migrate(); err == nil {
+		t.Fatalf("Must return the error of Exec")
+	}
+
+	for _, s := range tracer.spans {
+		if s.name != "darwin.Validate" && s.err == nil {
+			t.Errorf("Must record the error in the span %s", s.name)
+		}
+	}
+}
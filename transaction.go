@@ -0,0 +1,450 @@
+package darwin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// noTransactionDirective runs a migration outside of any transaction, for
+// statements like CREATE INDEX CONCURRENTLY:
+//
+//	-- darwin:no-transaction
+const noTransactionDirective = "no-transaction"
+
+// ErrTransactionUnsupported is returned by BeginTx when the driver can't run
+// a migration and record it in a transaction. Migrate then calls Exec and
+// Insert.
+var ErrTransactionUnsupported = errors.New("darwin: the driver doesn't support migration transactions")
+
+// Tx is a transaction executing migrations and recording them.
+type Tx interface {
+	Exec(ctx context.Context, script string) (time.Duration, error)
+	Insert(ctx context.Context, e MigrationRecord) error
+	Commit() error
+	Rollback() error
+}
+
+// Transactor is implemented by drivers able to execute a migration and
+// record it in the same transaction, so a failure can't leave the database
+// changed but the migration unrecorded. Migrate uses it for the migrations
+// without the no-transaction directive and data files.
+//
+// A driver overriding the Exec method of an embedded GenericDriver must
+// override BeginTx as well, returning ErrTransactionUnsupported if needed.
+type Transactor interface {
+	BeginTx(ctx context.Context) (Tx, error)
+}
+
+// TxDeleter is implemented by the transactions able to delete the record of
+// a migration, as those of the generic driver are with a DeleteDialect.
+type TxDeleter interface {
+	Delete(ctx context.Context, version float64) error
+}
+
+// HistoryTransactor is implemented by drivers able to change the records of
+// the history in a transaction, even when their migrations can't run in
+// one, like the generic driver with MySQL. SquashHistory uses it before
+// Transactor.
+type HistoryTransactor interface {
+	BeginHistoryTx(ctx context.Context) (Tx, error)
+}
+
+// historyTx begins a transaction changing the records of the history of d,
+// able to delete them.
+func historyTx(ctx context.Context, d Driver) (Tx, error) {
+	if c, ok := d.(DeleteChecker); ok && !c.CanDelete() {
+		return nil, errors.New("darwin: the dialect can't delete migration records")
+	}
+
+	var (
+		tx  Tx
+		err error
+	)
+
+	switch t := d.(type) {
+	case HistoryTransactor:
+		tx, err = t.BeginHistoryTx(ctx)
+	case Transactor:
+		tx, err = t.BeginTx(ctx)
+	default:
+		return nil, errors.New("darwin: the driver can't change the history in a transaction")
+	}
+
+	if err == ErrTransactionUnsupported {
+		return nil, errors.New("darwin: the driver can't change the history in a transaction")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := tx.(TxDeleter); !ok {
+		tx.Rollback()
+		return nil, errors.New("darwin: the driver can't delete migration records in a transaction")
+	}
+	return tx, nil
+}
+
+// BeginHistoryTx starts a transaction changing the records of the history,
+// whatever the support of transactional DDL of the dialect.
+func (m *GenericDriver) BeginHistoryTx(ctx context.Context) (Tx, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return genericTx{tx: tx, dialect: m.Dialect}, nil
+}
+
+// BeginTx starts a migration transaction. It returns
+// ErrTransactionUnsupported when the dialect commits DDL implicitly.
+func (m *GenericDriver) BeginTx(ctx context.Context) (Tx, error) {
+	if !supportsTransactionalDDL(m.Dialect) {
+		return nil, ErrTransactionUnsupported
+	}
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return genericTx{tx: tx, dialect: m.Dialect}, nil
+}
+
+type genericTx struct {
+	tx      *sql.Tx
+	dialect Dialect
+}
+
+func (g genericTx) Exec(ctx context.Context, script string) (time.Duration, error) {
+	start := time.Now()
+	err := withSettings(ctx, g.tx, g.dialect, script, func() error {
+		_, err := g.tx.ExecContext(ctx, script)
+		return err
+	})
+	return time.Since(start), err
+}
+
+func (g genericTx) ExecStatements(ctx context.Context, script string) ([]StatementResult, error) {
+	var results []StatementResult
+
+	err := withSettings(ctx, g.tx, g.dialect, script, func() error {
+		var err error
+		results, err = ExecStatements(ctx, g.tx, script, syntaxOf(g.dialect))
+		return err
+	})
+
+	return results, err
+}
+
+// SQLExecer is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type SQLExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// ExecStatements executes the statements of the script, split following the
+// syntax, one at a time with e, reporting them to the observers of Migrate.
+// The error of a failed statement is a StatementFailure. It is meant for
+// the drivers executing migrations in transactions of their own.
+func ExecStatements(ctx context.Context, e SQLExecer, script string, syntax Syntax) ([]StatementResult, error) {
+	var results []StatementResult
+
+	for i, stmt := range SplitStatements(script, syntax) {
+		ReportExecuting(ctx, stmt)
+		start := time.Now()
+
+		var res sql.Result
+		err := execSavepointed(ctx, i, func() error {
+			var err error
+			res, err = e.ExecContext(ctx, stmt)
+			return err
+		})
+		if err != nil {
+			return results, statementError{index: i, statement: stmt, err: err}
+		}
+
+		// Drivers which can't count the rows, like for DDL, report none.
+		rows, _ := res.RowsAffected()
+		s := StatementResult{Statement: stmt, RowsAffected: rows, Duration: time.Since(start)}
+		results = append(results, s)
+		ReportStatement(ctx, s)
+	}
+
+	return results, nil
+}
+
+func (g genericTx) Insert(ctx context.Context, e MigrationRecord) error {
+	query, args := InsertArgs(g.dialect, e)
+	_, err := g.tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (g genericTx) Delete(ctx context.Context, version float64) error {
+	dd, ok := g.dialect.(DeleteDialect)
+	if !ok {
+		return errors.New("darwin: the dialect can't delete migration records")
+	}
+
+	_, err := g.tx.ExecContext(ctx, dd.DeleteSQL(), version)
+	return err
+}
+
+func (g genericTx) Savepoint(ctx context.Context, name string) error {
+	_, err := g.tx.ExecContext(ctx, "SAVEPOINT "+name)
+	return err
+}
+
+func (g genericTx) RollbackToSavepoint(ctx context.Context, name string) error {
+	_, err := g.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+	return err
+}
+
+func (g genericTx) ReleaseSavepoint(ctx context.Context, name string) error {
+	_, err := g.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+func (g genericTx) Commit() error {
+	return g.tx.Commit()
+}
+
+func (g genericTx) Rollback() error {
+	return g.tx.Rollback()
+}
+
+// transactional reports if the migration can run in a transaction, which
+// the streamed migrations can't.
+func transactional(m Migration) bool {
+	return !streamable(m) && !hasDirective(m.Script, noTransactionDirective) && !hasDirective(m.Script, copyDirective) && !nonTransactional(m.Script)
+}
+
+// WithSingleTransaction makes Migrate execute all the pending migrations in
+// one transaction, rolled back if any of them fails, for databases with
+// transactional DDL like Postgres. The driver must be a Transactor and the
+// migrations must not have the no-transaction directive or data files.
+func WithSingleTransaction() Option {
+	return func(d *Darwin) {
+		d.singleTransaction = true
+	}
+}
+
+// Savepointer is implemented by the transactions supporting savepoints.
+type Savepointer interface {
+	Savepoint(ctx context.Context, name string) error
+	RollbackToSavepoint(ctx context.Context, name string) error
+	ReleaseSavepoint(ctx context.Context, name string) error
+}
+
+// SkipFunc decides if a migration failing with err, an ExecutionError, in a
+// single transaction is skipped, or if the whole transaction is rolled back.
+type SkipFunc func(m Migration, err error) bool
+
+// WithSavepoints makes Migrate take a savepoint before each migration of a
+// single transaction, and before each of its statements when the
+// transactions are StatementExecers, so the failed statement is reported
+// in the ExecutionError. A migration failing is rolled back to its
+// savepoint and skipped, left unrecorded and reported in Report.Skipped,
+// when skip returns true; the transaction stays usable even on databases
+// like Postgres which abort it on error. The transactions of the driver
+// must implement Savepointer.
+func WithSavepoints(skip SkipFunc) Option {
+	return func(d *Darwin) {
+		d.skip = skip
+	}
+}
+
+// SkippedMigration is a migration of a single transaction which failed and
+// was skipped by the SkipFunc of WithSavepoints. It is left unrecorded, so
+// Migrate applies it again.
+type SkippedMigration struct {
+	Migration Migration
+
+	// Err is the ExecutionError of the migration.
+	Err error
+}
+
+// statementSavepointsKey is the context key of the statementSavepoints of
+// a migration.
+type statementSavepointsKey struct{}
+
+// statementSavepoints are the savepoints taken around the statements of a
+// migration, named with the prefix and the index of the statement.
+type statementSavepoints struct {
+	sp     Savepointer
+	prefix string
+}
+
+// execSavepointed calls f executing the ith statement of the script, after
+// taking a savepoint when ctx has statementSavepoints. The savepoint is
+// released when f succeeds, and rolled back to when it fails, so the
+// transaction stays usable.
+func execSavepointed(ctx context.Context, i int, f func() error) error {
+	s, ok := ctx.Value(statementSavepointsKey{}).(statementSavepoints)
+	if !ok {
+		return f()
+	}
+
+	name := fmt.Sprintf("%s_%d", s.prefix, i)
+	if err := s.sp.Savepoint(ctx, name); err != nil {
+		return err
+	}
+
+	if err := f(); err != nil {
+		if rerr := s.sp.RollbackToSavepoint(ctx, name); rerr != nil {
+			return fmt.Errorf("%w, and rolling back to the savepoint failed: %s", err, rerr)
+		}
+		return err
+	}
+
+	return s.sp.ReleaseSavepoint(ctx, name)
+}
+
+// recordFunc returns the record of a migration executed in the duration.
+type recordFunc func(ctx context.Context, m Migration, duration time.Duration) MigrationRecord
+
+// execAllInTx executes the migrations and records them in one transaction
+// of d, taking a savepoint before each migration and each of its
+// statements when skip isn't nil. The start function is called before each
+// migration, returning the context to execute it with, the executed
+// function with each migration executed, and the skipped function with each
+// migration skipped.
+func execAllInTx(ctx context.Context, d Driver, migrations []Migration, skip SkipFunc, record recordFunc, start func(context.Context, Migration) context.Context, executed func(Result), skipped func(SkippedMigration)) error {
+	t, ok := d.(Transactor)
+	if !ok {
+		return ErrTransactionUnsupported
+	}
+
+	for _, m := range migrations {
+		if !transactional(m) {
+			return fmt.Errorf("darwin: migration %f can't run in a transaction", m.Version)
+		}
+	}
+
+	tx, err := t.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	sp, ok := tx.(Savepointer)
+	if skip != nil && !ok {
+		tx.Rollback()
+		return errors.New("darwin: the driver transactions don't support savepoints")
+	}
+
+	for i, m := range migrations {
+		mctx := start(ctx, m)
+
+		if skip == nil {
+			r, err := execAndInsert(mctx, tx, m, record)
+			if err != nil {
+				tx.Rollback()
+				return executionError(m, err)
+			}
+			executed(r)
+			continue
+		}
+
+		name := fmt.Sprintf("darwin_%d", i)
+		if err := sp.Savepoint(ctx, name); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		mctx = context.WithValue(mctx, statementSavepointsKey{}, statementSavepoints{sp: sp, prefix: name})
+		r, err := execAndInsert(mctx, tx, m, record)
+		if err == nil {
+			executed(r)
+			err = sp.ReleaseSavepoint(ctx, name)
+		} else if err = executionError(m, err); skip(m, err) {
+			skipped(SkippedMigration{Migration: m, Err: err})
+			err = sp.RollbackToSavepoint(ctx, name)
+		}
+
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// execInTx executes the migration and records it in a transaction of d. It
+// returns ErrTransactionUnsupported when d can't.
+func execInTx(ctx context.Context, d Driver, m Migration, record recordFunc) (Result, error) {
+	t, ok := d.(Transactor)
+	if !ok {
+		return Result{}, ErrTransactionUnsupported
+	}
+
+	tx, err := t.BeginTx(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+
+	r, err := execAndInsert(ctx, tx, m, record)
+	if err != nil {
+		tx.Rollback()
+		return r, err
+	}
+
+	return r, tx.Commit()
+}
+
+// revertInTx executes the down migration and deletes the record of the
+// migration it reverts in a transaction, when d is a Transactor whose
+// transactions are TxDeleters.
+func revertInTx(ctx context.Context, d Driver, m Migration) (Result, error) {
+	t, ok := d.(Transactor)
+	if !ok {
+		return Result{}, ErrTransactionUnsupported
+	}
+
+	tx, err := t.BeginTx(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+
+	deleter, ok := tx.(TxDeleter)
+	if !ok {
+		tx.Rollback()
+		return Result{}, ErrTransactionUnsupported
+	}
+
+	r, err := execTx(ctx, tx, m)
+	if err == nil {
+		err = deleter.Delete(ctx, m.Version)
+	}
+	if err != nil {
+		tx.Rollback()
+		return r, err
+	}
+
+	return r, tx.Commit()
+}
+
+// execAndInsert executes the migration and records it in tx.
+func execAndInsert(ctx context.Context, tx Tx, m Migration, record recordFunc) (Result, error) {
+	r, err := execTx(ctx, tx, m)
+	if err != nil {
+		return r, err
+	}
+
+	return r, tx.Insert(ctx, record(ctx, m, r.Duration))
+}
+
+// execTx executes the migration in tx, with ExecStatements when tx is a
+// StatementExecer.
+func execTx(ctx context.Context, tx Tx, m Migration) (Result, error) {
+	r := Result{Migration: m}
+
+	var err error
+	if se, ok := tx.(StatementExecer); ok {
+		r.Statements, err = se.ExecStatements(ctx, m.Script)
+		r.Duration = sumDurations(r.Statements)
+	} else {
+		ReportExecuting(ctx, m.Script)
+		r.Duration, err = tx.Exec(ctx, m.Script)
+	}
+	return r, err
+}
@@ -0,0 +1,335 @@
+package darwin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// txDriver is a dummyDriver implementing Transactor.
+type txDriver struct {
+	dummyDriver
+	calls       []string
+	insertError bool
+}
+
+func (d *txDriver) BeginTx(ctx context.Context) (Tx, error) {
+	d.calls = append(d.calls, "begin")
+	return &fakeTx{d: d}, nil
+}
+
+func (d *txDriver) Exec(script string) (time.Duration, error) {
+	d.calls = append(d.calls, "exec")
+	return d.dummyDriver.Exec(script)
+}
+
+type fakeTx struct {
+	d       *txDriver
+	records []MigrationRecord
+	deleted []float64
+}
+
+func (f *fakeTx) Exec(ctx context.Context, script string) (time.Duration, error) {
+	f.d.calls = append(f.d.calls, "tx.exec")
+	return time.Millisecond, nil
+}
+
+// failingTx fails to record the migration 2.
+type failingTx struct {
+	fakeTx
+}
+
+func (f *failingTx) Insert(ctx context.Context, e MigrationRecord) error {
+	f.records = append(f.records, e)
+	if e.Version == 2 {
+		return errors.New("Error")
+	}
+	return nil
+}
+
+func (f *fakeTx) Insert(ctx context.Context, e MigrationRecord) error {
+	f.d.calls = append(f.d.calls, "tx.insert")
+	if f.d.insertError {
+		return errors.New("Error")
+	}
+	f.records = append(f.records, e)
+	return nil
+}
+
+func (f *fakeTx) Delete(ctx context.Context, version float64) error {
+	f.d.calls = append(f.d.calls, "tx.delete")
+	f.deleted = append(f.deleted, version)
+	return nil
+}
+
+func (f *fakeTx) Savepoint(ctx context.Context, name string) error {
+	f.d.calls = append(f.d.calls, "savepoint "+name)
+	return nil
+}
+
+func (f *fakeTx) RollbackToSavepoint(ctx context.Context, name string) error {
+	f.d.calls = append(f.d.calls, "rollback to "+name)
+	f.records = f.records[:len(f.records)-1]
+	return nil
+}
+
+func (f *fakeTx) ReleaseSavepoint(ctx context.Context, name string) error {
+	f.d.calls = append(f.d.calls, "release "+name)
+	return nil
+}
+
+func (f *fakeTx) Commit() error {
+	f.d.calls = append(f.d.calls, "commit")
+	for _, version := range f.deleted {
+		for i, r := range f.d.records {
+			if r.Version == version {
+				f.d.records = append(f.d.records[:i], f.d.records[i+1:]...)
+				break
+			}
+		}
+	}
+	f.d.records = append(f.d.records, f.records...)
+	return nil
+}
+
+func (f *fakeTx) Rollback() error {
+	f.d.calls = append(f.d.calls, "rollback")
+	return nil
+}
+
+func Test_Migrate_transaction(t *testing.T) {
+	driver := &txDriver{}
+
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Index", Script: "-- darwin:no-transaction\nCREATE INDEX CONCURRENTLY users_id ON users (id);"},
+	}
+
+	if err := Migrate(driver, migrations); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if fmt.Sprint(driver.calls) != "[begin tx.exec tx.insert commit exec]" {
+		t.Errorf("Must execute and record the migration in a transaction, got %v", driver.calls)
+	}
+
+	if all, _ := driver.All(); len(all) != 2 {
+		t.Errorf("Must record both migrations, got %#v", all)
+	}
+}
+
+func Test_Migrate_transaction_rollback(t *testing.T) {
+	driver := &txDriver{insertError: true}
+
+	if err := Migrate(driver, []Migration{{Version: 1, Script: "CREATE TABLE users (id INT);"}}); err == nil {
+		t.Fatalf("Must return the error of Insert")
+	}
+
+	if fmt.Sprint(driver.calls) != "[begin tx.exec tx.insert rollback]" {
+		t.Errorf("Must roll back the migration, got %v", driver.calls)
+	}
+}
+
+func Test_GenericDriver_Exec_no_transaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, _ := NewGenericDriver(db, PostgresDialect{})
+
+	script := "-- darwin:no-transaction\nCREATE INDEX CONCURRENTLY users_id ON users (id);\nCREATE INDEX CONCURRENTLY users_name ON users (name);"
+	mock.ExpectExec(escapeQuery("-- darwin:no-transaction\nCREATE INDEX CONCURRENTLY users_id ON users (id)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(escapeQuery(PostgresDialect{}.IndexValidSQL())).WithArgs("users_id").WillReturnRows(sqlmock.NewRows([]string{"indisvalid"}).AddRow(true))
+	mock.ExpectExec(escapeQuery("CREATE INDEX CONCURRENTLY users_name ON users (name)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(escapeQuery(PostgresDialect{}.IndexValidSQL())).WithArgs("users_name").WillReturnRows(sqlmock.NewRows([]string{"indisvalid"}).AddRow(true))
+
+	if _, err := d.Exec(script); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_WithSingleTransaction(t *testing.T) {
+	driver := &txDriver{}
+
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+	}
+
+	if err := New(driver, migrations, WithSingleTransaction()).Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if fmt.Sprint(driver.calls) != "[begin tx.exec tx.insert tx.exec tx.insert commit]" {
+		t.Errorf("Must execute all the migrations in one transaction, got %v", driver.calls)
+	}
+
+	driver = &txDriver{insertError: true}
+	if err := New(driver, migrations, WithSingleTransaction()).Migrate(); err == nil {
+		t.Fatalf("Must return the error of Insert")
+	}
+
+	if fmt.Sprint(driver.calls) != "[begin tx.exec tx.insert rollback]" {
+		t.Errorf("Must roll back all the migrations, got %v", driver.calls)
+	}
+}
+
+func Test_WithSingleTransaction_not_transactional(t *testing.T) {
+	driver := &txDriver{}
+
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Index", Script: "-- darwin:no-transaction\nCREATE INDEX CONCURRENTLY users_id ON users (id);"},
+	}
+
+	if err := New(driver, migrations, WithSingleTransaction()).Migrate(); err == nil {
+		t.Errorf("Must refuse migrations which can't run in a transaction")
+	}
+
+	if len(driver.calls) != 0 {
+		t.Errorf("Must not execute any migration, got %v", driver.calls)
+	}
+
+	if err := New(&dummyDriver{}, migrations[:1], WithSingleTransaction()).Migrate(); err != ErrTransactionUnsupported {
+		t.Errorf("Expected ErrTransactionUnsupported, got %v", err)
+	}
+}
+
+// savepointDriver is a txDriver whose transactions fail on the migration 2.
+type savepointDriver struct {
+	txDriver
+}
+
+func (d *savepointDriver) BeginTx(ctx context.Context) (Tx, error) {
+	d.calls = append(d.calls, "begin")
+	return &failingTx{fakeTx{d: &d.txDriver}}, nil
+}
+
+func Test_WithSavepoints(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);"},
+		{Version: 2, Description: "Roles", Script: "CREATE TABLE roles (id INT);"},
+		{Version: 3, Description: "Groups", Script: "CREATE TABLE groups (id INT);"},
+	}
+
+	driver := &savepointDriver{}
+	skip := func(m Migration, err error) bool { return m.Version == 2 }
+
+	report, err := New(driver, migrations, WithSingleTransaction(), WithSavepoints(skip)).MigrateReport(context.Background())
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if len(report.Skipped) != 1 || report.Skipped[0].Migration.Version != 2 || report.Skipped[0].Err == nil {
+		t.Errorf("Must report the skipped migration, got %#v", report.Skipped)
+	}
+
+	expected := "[begin savepoint darwin_0 tx.exec release darwin_0 savepoint darwin_1 tx.exec rollback to darwin_1 savepoint darwin_2 tx.exec release darwin_2 commit]"
+	if fmt.Sprint(driver.calls) != expected {
+		t.Errorf("Expected %s, got %v", expected, driver.calls)
+	}
+
+	if all, _ := driver.All(); len(all) != 2 || all[1].Version != 3 {
+		t.Errorf("Must skip the failed migration, got %#v", all)
+	}
+
+	driver = &savepointDriver{}
+	never := func(Migration, error) bool { return false }
+
+	err = New(driver, migrations, WithSingleTransaction(), WithSavepoints(never)).Migrate()
+	if err == nil || err.Error() != "darwin: migration 2.000000: Error" {
+		t.Errorf("Must report the failed migration, got %v", err)
+	}
+
+	if all, _ := driver.All(); len(all) != 0 {
+		t.Errorf("Must roll back the transaction, got %#v", all)
+	}
+}
+
+func Test_execAllInTx_statement_savepoints(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, _ := NewGenericDriver(db, PostgresDialect{})
+
+	migrations := []Migration{
+		{Version: 1, Description: "Users", Script: "CREATE TABLE users (id INT);\nCREATE INDEX users_name ON users (name);"},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT darwin_0").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT darwin_0_0").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE users").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("RELEASE SAVEPOINT darwin_0_0").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT darwin_0_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE INDEX users_name").WillReturnError(errors.New(`column "name" does not exist`))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT darwin_0_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT darwin_0").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	var skipped []SkippedMigration
+	err = execAllInTx(context.Background(), d, migrations,
+		func(Migration, error) bool { return true },
+		func(ctx context.Context, m Migration, duration time.Duration) MigrationRecord {
+			return MigrationRecord{}
+		},
+		func(ctx context.Context, m Migration) context.Context { return ctx },
+		func(Result) { t.Errorf("Must not execute the failed migration") },
+		func(s SkippedMigration) { skipped = append(skipped, s) })
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	var execErr ExecutionError
+	if len(skipped) != 1 || !errors.As(skipped[0].Err, &execErr) || execErr.Statement != 1 {
+		t.Errorf("Must skip the migration with its failed statement, got %#v", skipped)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
+
+func Test_genericTx_Savepoint(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New().error != nil, wants nil")
+	}
+	defer db.Close()
+
+	d, _ := NewGenericDriver(db, PostgresDialect{})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT darwin_0").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT darwin_0").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("RELEASE SAVEPOINT darwin_0").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	tx, err := d.BeginTx(context.Background())
+	if err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	sp := tx.(Savepointer)
+	sp.Savepoint(context.Background(), "darwin_0")
+	sp.RollbackToSavepoint(context.Background(), "darwin_0")
+	sp.ReleaseSavepoint(context.Background(), "darwin_0")
+	tx.Commit()
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expections: %s", err)
+	}
+}
@@ -0,0 +1,97 @@
+package darwin
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// HistoryUpgrade upgrades the history table to a newer layout, the
+// migrations of the migrations table. The layouts are numbered from 1, the
+// layout of the first history tables.
+type HistoryUpgrade struct {
+	// Layout is the layout the upgrade brings the table to.
+	Layout int
+
+	// Probe is a query without arguments nor rows failing on the tables of
+	// the older layouts, typically selecting the columns the upgrade adds.
+	Probe string
+
+	// Statements upgrade the table, executed in a transaction.
+	Statements []string
+}
+
+// UpgradeDialect is implemented by the dialects whose history table layout
+// changed over time, as the dialects of this package do with the metadata
+// columns. HistoryUpgrades returns the upgrades from layout 2 to the latest
+// layout, in order; the generic driver applies those missing when it
+// creates the table, so the tables of earlier versions of darwin keep
+// working without losing their history.
+type UpgradeDialect interface {
+	HistoryUpgrades() []HistoryUpgrade
+}
+
+// upgrade applies the upgrades the history table is missing. The layout of
+// the table is the layout of the newest upgrade whose probe succeeds, so an
+// up to date table costs a single query.
+func (m *GenericDriver) upgrade(upgrades []HistoryUpgrade) error {
+	missing := len(upgrades)
+	for missing > 0 && !m.probe(upgrades[missing-1].Probe) {
+		missing--
+	}
+
+	for _, u := range upgrades[missing:] {
+		err := transaction(m.DB, func(tx *sql.Tx) error {
+			for _, stmt := range u.Statements {
+				if _, err := tx.Exec(stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("darwin: unable to upgrade the history table to layout %d: %w", u.Layout, err)
+		}
+	}
+
+	m.metadata = true
+	return nil
+}
+
+// probe reports whether the query succeeds.
+func (m *GenericDriver) probe(query string) bool {
+	rows, err := m.DB.Query(query)
+	if err != nil {
+		return false
+	}
+	for rows.Next() {
+	}
+	return rows.Close() == nil
+}
+
+// historyUpgrades returns the upgrades of the history table of the dialects
+// of this package, adding the columns with the add clause, like "ADD
+// COLUMN", and probing them with the never condition, always false: layout 2 adds the
+// metadata columns of the string type, layout 3 the script of the text type.
+func historyUpgrades(table, add, stringType, textType, never string) []HistoryUpgrade {
+	columns := func(columnType string, names ...string) []string {
+		var statements []string
+		for _, name := range names {
+			// One column per statement, as SQLite requires.
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s %s %s %s;", table, add, name, columnType))
+		}
+		return statements
+	}
+
+	return []HistoryUpgrade{
+		{
+			Layout:     2,
+			Probe:      fmt.Sprintf("SELECT applied_by, hostname, app_version, source FROM %s WHERE %s", table, never),
+			Statements: columns(stringType, "applied_by", "hostname", "app_version", "source"),
+		},
+		{
+			Layout:     3,
+			Probe:      fmt.Sprintf("SELECT script FROM %s WHERE %s", table, never),
+			Statements: columns(textType, "script"),
+		},
+	}
+}
@@ -0,0 +1,56 @@
+package darwin
+
+import (
+	"database/sql"
+	"testing"
+)
+
+// latestProbe returns the probe of the latest layout of the history table
+// of the dialect, which Create queries.
+func latestProbe(d UpgradeDialect) string {
+	upgrades := d.HistoryUpgrades()
+	return upgrades[len(upgrades)-1].Probe
+}
+
+func Test_GenericDriver_Create_upgrade(t *testing.T) {
+	layouts := map[string]string{
+		"layout1": "CREATE TABLE darwin_migrations (version float, description string, checksum string, applied_at int64, execution_time int64);",
+		"layout2": "CREATE TABLE darwin_migrations (version float, description string, checksum string, applied_at int64, execution_time int64, applied_by string, hostname string, app_version string, source string);",
+	}
+
+	for name, table := range layouts {
+		db, err := sql.Open("ql-mem", name+".db")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx.Exec(table)
+		tx.Exec("INSERT INTO darwin_migrations (version, description, checksum, applied_at, execution_time) VALUES (1.0, \"Users\", \"a\", 1, 1);")
+		tx.Commit()
+
+		d, _ := NewGenericDriver(db, QLDialect{})
+		if err := d.Create(); err != nil {
+			t.Fatalf("%s: Must upgrade the table, got %s", name, err)
+		}
+
+		for _, u := range (QLDialect{}).HistoryUpgrades() {
+			if !d.probe(u.Probe) {
+				t.Errorf("%s: Must upgrade the table to layout %d", name, u.Layout)
+			}
+		}
+
+		records, err := d.All()
+		if err != nil || len(records) != 1 || records[0].Description != "Users" {
+			t.Errorf("%s: Must keep the history, got %#v %v", name, records, err)
+		}
+
+		if err := d.Create(); err != nil {
+			t.Errorf("%s: Must leave an up to date table as is, got %s", name, err)
+		}
+	}
+}
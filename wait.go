@@ -0,0 +1,52 @@
+package darwin
+
+import (
+	"context"
+	"time"
+)
+
+// maxWaitBackoff caps the wait between two attempts of WaitForDriver.
+const maxWaitBackoff = 30 * time.Second
+
+// WaitForDriver waits until the database of d accepts connections, for
+// applications starting before their database. It pings a ContextDriver and
+// creates the history table with other drivers, retrying after backoff,
+// doubled after every attempt up to 30s, until it succeeds or ctx is done.
+// The last error of the driver is returned when ctx is done.
+func WaitForDriver(ctx context.Context, d Driver, backoff time.Duration) error {
+	for {
+		err := ready(ctx, d)
+		if err == nil {
+			return nil
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		if backoff *= 2; backoff > maxWaitBackoff {
+			backoff = maxWaitBackoff
+		}
+	}
+}
+
+// ready checks once if the database of d accepts connections.
+func ready(ctx context.Context, d Driver) error {
+	if cd, ok := d.(ContextDriver); ok {
+		return cd.Ping(ctx)
+	}
+	return d.Create()
+}
+
+// WithWaitForDriver makes Migrate wait up to timeout for the database to
+// accept connections, see WaitForDriver.
+func WithWaitForDriver(timeout time.Duration, backoff time.Duration) Option {
+	return func(d *Darwin) {
+		d.waitTimeout = timeout
+		d.waitBackoff = backoff
+	}
+}
@@ -0,0 +1,60 @@
+package darwin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// startingDriver is a dummyDriver failing to connect a few times.
+type startingDriver struct {
+	dummyDriver
+	failures int
+	attempts int
+}
+
+func (d *startingDriver) Create() error {
+	d.attempts++
+	if d.attempts <= d.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func Test_WaitForDriver(t *testing.T) {
+	driver := &startingDriver{failures: 2}
+
+	if err := WaitForDriver(context.Background(), driver, time.Millisecond); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+
+	if driver.attempts != 3 {
+		t.Errorf("Must retry until the database is ready, got %d attempts", driver.attempts)
+	}
+}
+
+func Test_WaitForDriver_timeout(t *testing.T) {
+	driver := &startingDriver{failures: 1000}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := WaitForDriver(ctx, driver, time.Millisecond); err == nil || err.Error() != "connection refused" {
+		t.Errorf("Must return the error of the driver, got %v", err)
+	}
+}
+
+func Test_WithWaitForDriver(t *testing.T) {
+	driver := &startingDriver{failures: 1}
+	migrations := []Migration{{Version: 1, Description: "First Migration", Script: "does not matter!"}}
+
+	if err := New(driver, migrations).Migrate(); err == nil {
+		t.Fatalf("Must fail without waiting for the database")
+	}
+
+	driver.attempts = 0
+	if err := New(driver, migrations, WithWaitForDriver(time.Second, time.Millisecond)).Migrate(); err != nil {
+		t.Fatalf("Must not return error, got %s", err)
+	}
+}